@@ -0,0 +1,744 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/internal/api/grpc"
+	"github.com/branched-services/go-gas/internal/testnode"
+	"github.com/branched-services/go-gas/pkg/client"
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+// End-to-end tests: the service binary is built once and run against a
+// scripted node over real HTTP and WebSocket connections.
+
+const gwei = 1_000_000_000
+
+var (
+	binaryOnce sync.Once
+	binaryPath string
+	binaryErr  error
+)
+
+// buildBinary compiles the service into a temporary directory.
+func buildBinary(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("end-to-end test builds and runs the service binary")
+	}
+	binaryOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "go-gas-e2e")
+		if err != nil {
+			binaryErr = err
+			return
+		}
+		binaryPath = filepath.Join(dir, "estimator")
+		out, err := exec.Command("go", "build", "-o", binaryPath, ".").CombinedOutput()
+		if err != nil {
+			binaryErr = fmt.Errorf("go build: %v\n%s", err, out)
+		}
+	})
+	if binaryErr != nil {
+		t.Fatal(binaryErr)
+	}
+	return binaryPath
+}
+
+// service is a running service binary.
+type service struct {
+	apiURL    string
+	healthURL string
+	logs      *bytes.Buffer
+}
+
+// startService runs the binary against node with env added to the
+// required configuration, and waits until it is ready.
+func startService(t *testing.T, node *testnode.Node, env ...string) *service {
+	t.Helper()
+	return startProcess(t, nil, append([]string{
+		"GAS_NODE_HTTP_URL=" + node.HTTPURL(),
+		"GAS_NODE_WS_URL=" + node.WSURL(),
+	}, env...)...)
+}
+
+// startProcess runs the binary with args and waits until it is ready.
+func startProcess(t *testing.T, args []string, env ...string) *service {
+	t.Helper()
+	bin := buildBinary(t)
+
+	apiAddr, healthAddr := freeAddr(t), freeAddr(t)
+	cmd := exec.Command(bin, args...)
+	cmd.Env = append(os.Environ(),
+		"GAS_GRPC_ADDR="+apiAddr,
+		"GAS_HTTP_ADDR="+healthAddr,
+		"GAS_RECALC_INTERVAL=50ms",
+		"GAS_NODE_DEGRADED_POLL_INTERVAL=100ms",
+		"GAS_LOG_FORMAT=text",
+	)
+	cmd.Env = append(cmd.Env, env...)
+	logs := &bytes.Buffer{}
+	cmd.Stdout, cmd.Stderr = logs, logs
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Signal(os.Interrupt)
+		done := make(chan struct{})
+		go func() { cmd.Wait(); close(done) }()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			cmd.Process.Kill()
+			<-done
+		}
+		if t.Failed() {
+			t.Logf("service logs:\n%s", logs)
+		}
+	})
+
+	s := &service{apiURL: "http://" + apiAddr, healthURL: "http://" + healthAddr, logs: logs}
+	eventually(t, 10*time.Second, "service ready", func() bool {
+		resp, err := http.Get("http://" + healthAddr + "/readyz")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	})
+	return s
+}
+
+// estimate fetches /v1/gas/estimate.
+func (s *service) estimate(t *testing.T) grpc.GasEstimateResponse {
+	t.Helper()
+	return s.get(t, "/v1/gas/estimate")
+}
+
+// get fetches an estimate from path.
+func (s *service) get(t *testing.T, path string) grpc.GasEstimateResponse {
+	t.Helper()
+	resp, err := http.Get(s.apiURL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("estimate status = %d", resp.StatusCode)
+	}
+	var out grpc.GasEstimateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// getJSON fetches url, checks the status and decodes the body into out
+// unless it is nil.
+func getJSON(t *testing.T, url string, status int, out any) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != status {
+		t.Fatalf("GET %s status = %d, want %d", url, resp.StatusCode, status)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func eventually(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// newChain starts a node with history blocks paying 2 gwei tips.
+func newChain(t *testing.T, blocks int) *testnode.Node {
+	t.Helper()
+	node := testnode.New(1)
+	t.Cleanup(node.Close)
+	for i := 0; i < blocks; i++ {
+		node.Mine(10*gwei, 2*gwei, 2*gwei, 2*gwei)
+	}
+	return node
+}
+
+func TestE2E_FollowsNewHeads(t *testing.T) {
+	node := newChain(t, 25)
+	svc := startService(t, node)
+
+	est := svc.estimate(t)
+	if est.ChainID != 1 || est.BlockNumber != node.Head() {
+		t.Fatalf("estimate chain %d block %d, want chain 1 block %d", est.ChainID, est.BlockNumber, node.Head())
+	}
+	if est.ValidUntil == "" {
+		t.Error("valid_until not set")
+	}
+	baseFee, _ := uint256.FromDecimal(est.BaseFee)
+	tip, _ := uint256.FromDecimal(est.Estimates.Standard.MaxPriorityFeePerGas)
+	if want := new(uint256.Int).Add(baseFee, tip).Dec(); est.Estimates.Standard.GasPrice != want {
+		t.Errorf("standard gas_price = %s, want %s", est.Estimates.Standard.GasPrice, want)
+	}
+
+	// The Go client decodes the same estimate
+	typed, err := client.New(svc.apiURL).GetEstimate(context.Background())
+	if err != nil {
+		t.Fatalf("client GetEstimate: %v", err)
+	}
+	if typed.BlockNumber != est.BlockNumber || typed.BaseFee.Dec() != est.BaseFee || typed.Standard.GasPrice.Dec() != est.Estimates.Standard.GasPrice {
+		t.Errorf("client estimate = %+v, want block %d base fee %s", typed, est.BlockNumber, est.BaseFee)
+	}
+
+	// Display values in gwei alongside the exact wei
+	display := svc.get(t, "/v1/gas/estimate?unit=gwei&round=0.1")
+	if display.BaseFee != est.BaseFee || !regexp.MustCompile(`^\d+\.\d$`).MatchString(display.BaseFeeGwei) {
+		t.Errorf("base fee %s wei, %q gwei; want %s wei rounded to 0.1 gwei", display.BaseFee, display.BaseFeeGwei, est.BaseFee)
+	}
+	if display.Estimates.Standard.MaxPriorityFeePerGasGwei != "2.0" {
+		t.Errorf("standard tip = %q gwei, want 2.0", display.Estimates.Standard.MaxPriorityFeePerGasGwei)
+	}
+
+	// Totals at a tier in every unit
+	resp, err := http.Get(svc.apiURL + "/v1/gas/cost?gas=100000&tier=fast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cost grpc.CostResponse
+	json.NewDecoder(resp.Body).Decode(&cost)
+	resp.Body.Close()
+	maxFee, _ := uint256.FromDecimal(est.Estimates.Fast.MaxFeePerGas)
+	if want := new(uint256.Int).Mul(maxFee, uint256.NewInt(100000)); resp.StatusCode != http.StatusOK || cost.Max.Wei != want.Dec() {
+		t.Errorf("cost status %d, max %s wei; want %s", resp.StatusCode, cost.Max.Wei, want.Dec())
+	}
+	if cost.Estimated.Gwei == "" || !strings.HasPrefix(cost.Estimated.Ether, "0.00") {
+		t.Errorf("estimated cost %+v, want gwei and ETH amounts", cost.Estimated)
+	}
+
+	// Every recent block included 2 gwei tips, so any deadline gets 2 gwei
+	for path, blocks := range map[string]int{"/v1/gas/deadline?blocks=3&confidence=0.99": 3, "/v1/gas/deadline?deadline=30s": 2} {
+		resp, err := http.Get(svc.apiURL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var d grpc.DeadlineResponse
+		json.NewDecoder(resp.Body).Decode(&d)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || d.Blocks != blocks || d.MaxPriorityFeePerGas != "2000000000" || d.BlockNumber != est.BlockNumber {
+			t.Errorf("%s: status %d, %+v; want 2 gwei within %d blocks", path, resp.StatusCode, d, blocks)
+		}
+	}
+	resp, err = http.Get(svc.apiURL + "/v1/gas/deadline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("deadline without a wait: status %d, want 400", resp.StatusCode)
+	}
+
+	// Long polls time out without a new block and return once it is priced
+	resp, err = http.Get(fmt.Sprintf("%s/v1/gas/estimate/next?since_block=%d&timeout=100ms", svc.apiURL, est.BlockNumber))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("long poll without a new block: status %d, want 204", resp.StatusCode)
+	}
+	next := make(chan uint64, 1)
+	go func() {
+		defer close(next)
+		resp, err := http.Get(fmt.Sprintf("%s/v1/gas/estimate/next?since_block=%d&timeout=10s", svc.apiURL, est.BlockNumber))
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+		var out grpc.GasEstimateResponse
+		if resp.StatusCode == http.StatusOK && json.NewDecoder(resp.Body).Decode(&out) == nil {
+			next <- out.BlockNumber
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	b := node.Mine(12*gwei, 3*gwei)
+	eventually(t, 5*time.Second, "estimate for the new head", func() bool {
+		return svc.estimate(t).BlockNumber == b.Number
+	})
+	if got := <-next; got != b.Number {
+		t.Errorf("long poll returned block %d, want %d", got, b.Number)
+	}
+
+	// Chain lag is reported in the body and a header, update latency as a metric
+	resp, err = http.Get(svc.apiURL + "/v1/gas/estimate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lagged grpc.GasEstimateResponse
+	json.NewDecoder(resp.Body).Decode(&lagged)
+	resp.Body.Close()
+	if resp.Header.Get("X-Chain-Lag-Seconds") == "" || lagged.ChainLagSeconds == nil {
+		t.Errorf("chain lag header %q, field %v; want both set", resp.Header.Get("X-Chain-Lag-Seconds"), lagged.ChainLagSeconds)
+	}
+	resp, err = http.Get(svc.healthURL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !bytes.Contains(metrics, []byte("gas_block_update_latency_seconds_count 1")) {
+		t.Errorf("metrics missing the new block's update latency:\n%s", metrics)
+	}
+
+	// The previous head's quote stays available
+	if old := svc.get(t, fmt.Sprintf("/v1/gas/estimate?block=%d", est.BlockNumber)); old.BlockNumber != est.BlockNumber {
+		t.Errorf("snapshot block = %d, want %d", old.BlockNumber, est.BlockNumber)
+	}
+
+	// Follow-up calls can be pinned to the first estimate's generation
+	pinned := func(path, generation string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, svc.apiURL+path, nil)
+		req.Header.Set("If-Generation-Match", generation)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	resp = pinned("/v1/gas/cost?gas=21000", est.Generation)
+	json.NewDecoder(resp.Body).Decode(&cost)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || cost.BlockNumber != est.BlockNumber || cost.Generation != est.Generation {
+		t.Errorf("pinned cost: status %d, block %d, generation %q; want block %d, generation %q",
+			resp.StatusCode, cost.BlockNumber, cost.Generation, est.BlockNumber, est.Generation)
+	}
+	resp = pinned("/v1/gas/estimate", fmt.Sprintf("%d-999999", b.Number))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("unknown generation: status %d, want 412", resp.StatusCode)
+	}
+}
+
+func TestE2E_MempoolRaisesTips(t *testing.T) {
+	node := newChain(t, 25)
+	svc := startService(t, node, "GAS_HISTORY_HALF_LIFE=0")
+	eventually(t, 5*time.Second, "pending transaction subscription", func() bool {
+		return node.Subscriptions("newPendingTransactions") > 0
+	})
+
+	before := svc.estimate(t)
+	for i := 0; i < 200; i++ {
+		node.AddPendingTx(&eth.Transaction{
+			Hash:                 fmt.Sprintf("0x%064x", 1_000_000+i),
+			From:                 fmt.Sprintf("0x%040x", 1_000+i),
+			GasLimit:             21000,
+			MaxFeePerGas:         uint256.NewInt(100 * gwei),
+			MaxPriorityFeePerGas: uint256.NewInt(50 * gwei),
+			Type:                 2,
+		})
+	}
+
+	eventually(t, 5*time.Second, "tips to follow the mempool", func() bool {
+		tip, _ := uint256.FromDecimal(svc.estimate(t).Estimates.Standard.MaxPriorityFeePerGas)
+		prev, _ := uint256.FromDecimal(before.Estimates.Standard.MaxPriorityFeePerGas)
+		return tip != nil && prev != nil && tip.Gt(prev)
+	})
+}
+
+func TestE2E_RecoversFromDisconnect(t *testing.T) {
+	node := newChain(t, 25)
+	svc := startService(t, node)
+	eventually(t, 5*time.Second, "newHeads subscription", func() bool {
+		return node.Subscriptions("newHeads") > 0
+	})
+
+	node.DropConnections()
+
+	// Blocks mined while disconnected are picked up by polling or the new subscription
+	b := node.Mine(11*gwei, 2*gwei)
+	eventually(t, 10*time.Second, "estimate after reconnecting", func() bool {
+		return svc.estimate(t).BlockNumber == b.Number
+	})
+	eventually(t, 10*time.Second, "resubscription", func() bool {
+		return node.Subscriptions("newHeads") > 0
+	})
+
+	b = node.Mine(11*gwei, 2*gwei)
+	eventually(t, 5*time.Second, "estimate from the restored subscription", func() bool {
+		est := svc.estimate(t)
+		return est.BlockNumber == b.Number && !est.Degraded
+	})
+}
+
+func TestE2E_Reorg(t *testing.T) {
+	node := newChain(t, 25)
+	svc := startService(t, node)
+	eventually(t, 5*time.Second, "newHeads subscription", func() bool {
+		return node.Subscriptions("newHeads") > 0
+	})
+
+	head := node.Head()
+	node.Mine(10*gwei, 2*gwei)
+	eventually(t, 5*time.Second, "estimate for the replaced block", func() bool {
+		return svc.estimate(t).BlockNumber == head+1
+	})
+
+	// The replacement block reports a much higher base fee
+	b := node.MineReorg(head+1, 40*gwei, 2*gwei)
+	eventually(t, 5*time.Second, "estimate for the replacement block", func() bool {
+		est := svc.estimate(t)
+		baseFee, _ := uint256.FromDecimal(est.BaseFee)
+		return est.BlockNumber == b.Number && baseFee != nil && baseFee.Gt(uint256.NewInt(30*gwei))
+	})
+
+	resp, err := http.Get(svc.apiURL + "/v1/chain/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var st grpc.ChainStatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || !st.Healthy || st.Subscription != "subscribed" {
+		t.Errorf("chain status = %d %+v, want healthy and subscribed", resp.StatusCode, st)
+	}
+	if st.HeadBlock != b.Number || st.HeadHash != b.Hash || st.Reorgs != 1 || st.LastReorgDepth != 1 {
+		t.Errorf("chain status head = %d %s, %d reorgs (depth %d), want %d %s after 1 reorg of depth 1",
+			st.HeadBlock, st.HeadHash, st.Reorgs, st.LastReorgDepth, b.Number, b.Hash)
+	}
+	if st.Node == nil || !st.Node.Reachable || st.Node.Syncing {
+		t.Errorf("chain status node = %+v, want reachable and synced", st.Node)
+	}
+}
+
+func TestE2E_CrossCheck(t *testing.T) {
+	node := newChain(t, 25)
+	witnesses := []*testnode.Node{newChain(t, 25), newChain(t, 25)}
+	svc := startService(t, node, fmt.Sprintf("GAS_CROSSCHECK_URLS=%s, %s", witnesses[0].HTTPURL(), witnesses[1].HTTPURL()))
+	eventually(t, 5*time.Second, "newHeads subscription", func() bool {
+		return node.Subscriptions("newHeads") > 0
+	})
+
+	// The primary node reports a base fee both witnesses dispute
+	for _, w := range witnesses {
+		w.Mine(40*gwei, 2*gwei)
+	}
+	b := node.Mine(10*gwei, 2*gwei)
+	eventually(t, 5*time.Second, "estimate for the majority's block", func() bool {
+		est := svc.estimate(t)
+		return est.BlockNumber == b.Number && est.BaseFee == fmt.Sprint(40*gwei)
+	})
+
+	resp, err := http.Get(svc.apiURL + "/v1/chain/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var st grpc.ChainStatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Divergences != 1 {
+		t.Errorf("chain status divergences = %d, want 1", st.Divergences)
+	}
+}
+
+func TestE2E_CrossCheckAuth(t *testing.T) {
+	node := newChain(t, 25)
+	witness := newChain(t, 25)
+
+	// Record the credentials each witness request carries
+	var mu sync.Mutex
+	var auths []string
+	target, _ := url.Parse(witness.HTTPURL())
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		auths = append(auths, r.Header.Get("Authorization"))
+		mu.Unlock()
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	withCreds, _ := url.Parse(srv.URL)
+	withCreds.User = url.UserPassword("witness", "secret")
+
+	svc := startService(t, node,
+		"GAS_NODE_BEARER_TOKEN=primary-token",
+		"GAS_CROSSCHECK_URLS="+srv.URL+","+withCreds.String(),
+	)
+	eventually(t, 5*time.Second, "newHeads subscription", func() bool {
+		return node.Subscriptions("newHeads") > 0
+	})
+	witness.Mine(10*gwei, 2*gwei)
+	b := node.Mine(10*gwei, 2*gwei)
+	eventually(t, 5*time.Second, "estimate for the new block", func() bool {
+		return svc.estimate(t).BlockNumber == b.Number
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("witness:secret"))
+	var plain, own int
+	for _, auth := range auths {
+		switch auth {
+		case "":
+			plain++
+		case basic:
+			own++
+		default:
+			t.Errorf("witness request with Authorization %q", auth)
+		}
+	}
+	if plain == 0 || own == 0 {
+		t.Errorf("witnesses got %d requests without credentials and %d with their own, want both", plain, own)
+	}
+}
+
+func TestE2E_InputLogReplay(t *testing.T) {
+	node := newChain(t, 25)
+	path := filepath.Join(t.TempDir(), "inputs.jsonl")
+	svc := startService(t, node, "GAS_INPUT_LOG="+path)
+
+	b := node.Mine(12*gwei, 3*gwei, 4*gwei)
+	eventually(t, 5*time.Second, "estimate for the new block", func() bool {
+		return svc.estimate(t).BlockNumber == b.Number
+	})
+
+	cmd := exec.Command(buildBinary(t), "replay", path)
+	cmd.Env = append(os.Environ(), "GAS_NODE_HTTP_URL="+node.HTTPURL())
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	var replayed, matching int
+	var last replayLine
+	for _, line := range bytes.Split(bytes.TrimSpace(out), []byte("\n")) {
+		if err := json.Unmarshal(line, &last); err != nil {
+			t.Fatalf("replay output %q: %v", line, err)
+		}
+		replayed++
+		if last.Recorded == last.Replayed {
+			matching++
+		}
+	}
+	if replayed == 0 || matching != replayed || last.BlockNumber != b.Number {
+		t.Errorf("replayed %d estimates, %d matching the recorded ones, last for block %d; want all matching up to block %d",
+			replayed, matching, last.BlockNumber, b.Number)
+	}
+}
+
+func TestE2E_ReplaySandbox(t *testing.T) {
+	node := newChain(t, 25)
+	path := filepath.Join(t.TempDir(), "inputs.jsonl")
+	svc := startService(t, node, "GAS_INPUT_LOG="+path)
+
+	b := node.Mine(12*gwei, 3*gwei, 4*gwei)
+	var recorded grpc.GasEstimateResponse
+	eventually(t, 5*time.Second, "estimate for the new block", func() bool {
+		recorded = svc.estimate(t)
+		return recorded.BlockNumber == b.Number
+	})
+
+	// No node: estimates come from the log only
+	sandbox := startProcess(t, []string{"--replay", path, "--replay-speed", "0"})
+	var got grpc.GasEstimateResponse
+	eventually(t, 5*time.Second, "replayed estimate for the last block", func() bool {
+		got = sandbox.estimate(t)
+		return got.BlockNumber == b.Number
+	})
+	if got.BaseFee != recorded.BaseFee || got.Estimates.Standard.MaxPriorityFeePerGas != recorded.Estimates.Standard.MaxPriorityFeePerGas {
+		t.Errorf("replayed estimate = %+v, want %+v", got.Estimates.Standard, recorded.Estimates.Standard)
+	}
+}
+
+func TestE2E_FeeCurrency(t *testing.T) {
+	const cUSD = "0x765DE816845861e75A25fCA122bb6898B8B1282a"
+	node := newChain(t, 25)
+	node.SetGasPrice("", 10*gwei)
+	node.SetGasPrice(cUSD, 5*gwei) // 1 cUSD = 2 ETH
+	svc := startService(t, node, "GAS_NETWORK_FEE_CURRENCIES=cUSD:"+cUSD)
+
+	est := svc.estimate(t)
+	if est.FeeCurrency != (grpc.FeeCurrencyResponse{Symbol: "ETH", Decimals: 18}) {
+		t.Errorf("fee_currency = %+v, want ETH", est.FeeCurrency)
+	}
+	if len(est.Network.FeeCurrencies) != 1 || est.Network.FeeCurrencies[0].Symbol != "cUSD" {
+		t.Errorf("network fee_currencies = %+v, want cUSD", est.Network.FeeCurrencies)
+	}
+
+	converted := svc.get(t, "/v1/gas/estimate?fee_currency=cusd")
+	if converted.FeeCurrency.Address != cUSD {
+		t.Errorf("converted fee_currency = %+v, want cUSD", converted.FeeCurrency)
+	}
+	baseFee, _ := uint256.FromDecimal(est.BaseFee)
+	if want := new(uint256.Int).Div(baseFee, uint256.NewInt(2)).Dec(); converted.BaseFee != want {
+		t.Errorf("converted base fee = %s, want %s", converted.BaseFee, want)
+	}
+	if tip := converted.Estimates.Standard.MaxPriorityFeePerGas; tip != "1000000000" {
+		t.Errorf("converted standard tip = %s, want 1e9 (2 gwei at half the price)", tip)
+	}
+
+	resp, err := http.Get(svc.apiURL + "/v1/gas/estimate?fee_currency=USDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown fee_currency status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestE2E_StrategyOverrides(t *testing.T) {
+	node := newChain(t, 25)
+	svc := startService(t, node, "GAS_API_STRATEGY_OVERRIDES=true")
+
+	var strategy grpc.StrategyResponse
+	getJSON(t, svc.apiURL+"/v1/strategy", http.StatusOK, &strategy)
+	if strategy.Name != "hybrid" || strategy.Params["historical_weight"] != 0.3 {
+		t.Errorf("strategy = %+v, want hybrid with historical_weight 0.3", strategy)
+	}
+
+	var ev grpc.StrategyEvaluationResponse
+	getJSON(t, svc.apiURL+"/v1/strategy/evaluate?max_priority_fee=1500000000&smoothing_factor=0", http.StatusOK, &ev)
+	if ev.Published.Estimates.Standard.MaxPriorityFeePerGas != "2000000000" {
+		t.Errorf("published standard tip = %s, want 2 gwei", ev.Published.Estimates.Standard.MaxPriorityFeePerGas)
+	}
+	if ev.Evaluated.Estimates.Standard.MaxPriorityFeePerGas != "1500000000" || ev.Evaluated.BlockNumber != ev.Published.BlockNumber {
+		t.Errorf("evaluated standard tip = %s at block %d, want the 1.5 gwei ceiling at block %d",
+			ev.Evaluated.Estimates.Standard.MaxPriorityFeePerGas, ev.Evaluated.BlockNumber, ev.Published.BlockNumber)
+	}
+	if ev.Strategy.Params["max_priority_fee"] != "1500000000" {
+		t.Errorf("evaluated strategy params = %v", ev.Strategy.Params)
+	}
+	// Nothing is published
+	if est := svc.estimate(t); est.Estimates.Standard.MaxPriorityFeePerGas != "2000000000" {
+		t.Errorf("current standard tip = %s after evaluating, want 2 gwei", est.Estimates.Standard.MaxPriorityFeePerGas)
+	}
+
+	getJSON(t, svc.apiURL+"/v1/strategy/evaluate?historical_weight=2", http.StatusBadRequest, nil)
+}
+
+func TestE2E_Suggest(t *testing.T) {
+	node := newChain(t, 25)
+	from, token := "0x00000000000000000000000000000000000000aa", "0x00000000000000000000000000000000000000bb"
+	node.SetNonce(from, 7)
+	node.Revert("0x00000000000000000000000000000000000000cc")
+	pool, vault, counter := "0x00000000000000000000000000000000000000dd", "0x00000000000000000000000000000000000000ee", "0x00000000000000000000000000000000000000ff"
+	node.Storage(pool, vault, "0x01", "0x02", "0x03")
+	node.Storage(counter, counter, "0x01")
+	svc := startService(t, node, "GAS_API_SUGGEST=true")
+	est := svc.estimate(t)
+
+	suggest := func(body string) (*http.Response, grpc.SuggestResponse) {
+		t.Helper()
+		resp, err := http.Post(svc.apiURL+"/v1/gas/suggest", "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var out grpc.SuggestResponse
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+
+	resp, got := suggest(fmt.Sprintf(`{"from":%q,"to":%q,"data":"0xa9059cbb","tier":"fast"}`, from, token))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("suggest status = %d", resp.StatusCode)
+	}
+	if got.GasLimit != 21064 || !got.GasEstimated {
+		t.Errorf("gas_limit = %d (estimated %v), want 21064 from eth_estimateGas", got.GasLimit, got.GasEstimated)
+	}
+	if got.Nonce == nil || *got.Nonce != 7 {
+		t.Errorf("nonce = %v, want 7", got.Nonce)
+	}
+	if got.Tier != "fast" || got.MaxPriorityFeePerGas != est.Estimates.Fast.MaxPriorityFeePerGas || got.MaxFeePerGas != est.Estimates.Fast.MaxFeePerGas {
+		t.Errorf("suggestion %+v does not match the fast tier %+v", got, est.Estimates.Fast)
+	}
+	maxFee, _ := uint256.FromDecimal(got.MaxFeePerGas)
+	if want := new(uint256.Int).Mul(maxFee, uint256.NewInt(21064)).Dec(); got.Cost.MaxWei != want {
+		t.Errorf("cost max_wei = %s, want %s", got.Cost.MaxWei, want)
+	}
+
+	if resp, _ := suggest(`{"to":"0x00000000000000000000000000000000000000cc"}`); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("reverting call status = %d, want 422", resp.StatusCode)
+	}
+	if resp, _ := suggest(`{"to":"0x1234"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid address status = %d, want 400", resp.StatusCode)
+	}
+
+	// A deployment of 100 bytes of init code, which the node prices at
+	// 21000 + 32000 + 216*100 gas
+	initCode := "0x" + strings.Repeat("60", 100)
+	resp, got = suggest(fmt.Sprintf(`{"data":%q}`, initCode))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("deployment status = %d", resp.StatusCode)
+	}
+	if want := uint64(74600 * 110 / 100); !got.Deployment || got.GasLimit != want || got.IntrinsicGas != 53000+4*2+100*16 {
+		t.Errorf("deployment gas_limit = %d, intrinsic_gas = %d (deployment %v), want %d with a margin", got.GasLimit, got.IntrinsicGas, got.Deployment, want)
+	}
+	if resp, _ := suggest(fmt.Sprintf(`{"data":%q,"gas":54000}`, initCode)); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("gas below intrinsic status = %d, want 400", resp.StatusCode)
+	}
+	if resp, _ := suggest(fmt.Sprintf(`{"data":"0x%s"}`, strings.Repeat("00", 49153))); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("oversized init code status = %d, want 400", resp.StatusCode)
+	}
+
+	// Declaring another contract's slots saves 100 gas for the contract and
+	// each slot
+	resp, got = suggest(fmt.Sprintf(`{"to":%q,"access_list":true}`, pool))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("access list status = %d", resp.StatusCode)
+	}
+	if without := uint64(21000 + 2600 + 3*2100); got.GasWithoutAccessList != without || got.GasLimit != without-400 ||
+		len(got.AccessList) != 1 || got.AccessList[0].Address != vault || len(got.AccessList[0].StorageKeys) != 3 ||
+		got.IntrinsicGas != 21000+2400+3*1900 {
+		t.Errorf("access list suggestion = %+v", got)
+	}
+	// Declaring the called contract's own slot costs more than it saves
+	resp, got = suggest(fmt.Sprintf(`{"to":%q,"access_list":true}`, counter))
+	if resp.StatusCode != http.StatusOK || got.AccessList != nil || got.GasLimit != 21000+2100 {
+		t.Errorf("unprofitable access list: status %d, suggestion %+v", resp.StatusCode, got)
+	}
+	if resp, _ := suggest(fmt.Sprintf(`{"to":%q,"access_list":true,"gas":50000}`, pool)); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("access list with gas status = %d, want 400", resp.StatusCode)
+	}
+}
@@ -3,20 +3,33 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
+	"math"
+	"net/url"
 	"os"
 	"os/signal"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/branched-services/go-gas/internal/api/grpc"
 	"github.com/branched-services/go-gas/internal/config"
+	"github.com/branched-services/go-gas/internal/ha"
 	"github.com/branched-services/go-gas/internal/observability"
+	"github.com/branched-services/go-gas/internal/tenant"
 	"github.com/branched-services/go-gas/pkg/estimator"
 	"github.com/branched-services/go-gas/pkg/eth"
 	"github.com/branched-services/go-gas/pkg/health"
+	"github.com/branched-services/go-gas/pkg/pricefeed"
+	"github.com/branched-services/go-gas/pkg/webhook"
+	"github.com/holiman/uint256"
 )
 
 func main() {
@@ -24,8 +37,24 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
 	defer stop()
 
+	runCmd := run
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		runCmd = func(ctx context.Context) error { return runReplay(ctx, os.Args[2:], os.Stdout) }
+	} else {
+		// Flags mirror GAS_REPLAY and GAS_REPLAY_SPEED, which config reads
+		replay := flag.String("replay", "", "serve estimates replayed from this input log instead of a node (GAS_REPLAY)")
+		speed := flag.String("replay-speed", "", "speed-up of the replayed timing; 0 replays as fast as possible (GAS_REPLAY_SPEED, default 10)")
+		flag.Parse()
+		if *replay != "" {
+			os.Setenv("GAS_REPLAY", *replay)
+		}
+		if *speed != "" {
+			os.Setenv("GAS_REPLAY_SPEED", *speed)
+		}
+	}
+
 	code := 0
-	if err := run(ctx); err != nil {
+	if err := runCmd(ctx); err != nil {
 		slog.Error("fatal error", "error", err)
 		code = 1
 	}
@@ -45,53 +74,357 @@ func run(ctx context.Context) error {
 	slog.SetDefault(logger)
 
 	slog.Info("starting gas estimator",
+		"version", estimator.Version,
 		"grpc_addr", cfg.GRPCAddr,
 		"http_addr", cfg.HTTPAddr,
 		"history_blocks", cfg.HistoryBlocks,
+		"history_half_life", cfg.HistoryHalfLife,
 		"mempool_samples", cfg.MempoolSamples,
+		"mempool_sampling", cfg.MempoolSampling,
 		"recalc_interval", cfg.RecalcInterval,
+		"strategy", cfg.Strategy,
+		"chain_preset", cfg.ChainPreset,
 	)
 
+	if cfg.ReplayPath != "" {
+		return runSandbox(ctx, cfg, logger)
+	}
+
 	// Build dependency graph (dependency inversion)
 
+	auth, err := nodeAuth(cfg)
+	if err != nil {
+		return fmt.Errorf("node auth: %w", err)
+	}
+
+	transportOpts := []eth.ClientOption{
+		eth.WithDialTimeout(cfg.NodeDialTimeout),
+		eth.WithRequestTimeout(cfg.NodeRequestTimeout),
+		eth.WithHTTP2(cfg.NodeHTTP2),
+		eth.WithMaxConcurrentRequests(cfg.NodeMaxConcurrentRequests),
+	}
+	if cfg.NodeProxyURL != "" {
+		proxyURL, _ := url.Parse(cfg.NodeProxyURL) // validated by config
+		transportOpts = append(transportOpts, eth.WithProxy(proxyURL))
+	}
+	tlsConfig, otherTLS, err := nodeTLS(cfg)
+	if err != nil {
+		return fmt.Errorf("node tls: %w", err)
+	}
+	if tlsConfig != nil && tlsConfig.InsecureSkipVerify {
+		logger.Warn("node TLS certificates are not verified (GAS_NODE_TLS_INSECURE_SKIP_VERIFY)")
+	}
+
 	// 1. Eth client (HTTP for RPC calls)
-	ethClient := eth.NewClient(cfg.NodeHTTPURL)
+	ethClient := eth.NewClient(cfg.NodeHTTPURL, append([]eth.ClientOption{
+		eth.WithClientAuth(auth),
+		eth.WithTLSConfig(tlsConfig),
+	}, transportOpts...)...)
 	defer ethClient.Close()
 
-	// 2. WebSocket subscriber for real-time updates
-	subscriber := eth.NewWSSubscriber(cfg.NodeWSURL, logger)
-	defer subscriber.Close()
+	// Witness nodes that new heads are cross-checked against. They are
+	// usually run by other providers, so the primary's credentials aren't
+	// sent to them; a witness's own go in its URL.
+	var witnesses []estimator.Witness
+	for _, u := range cfg.CrossCheckEndpoints() {
+		client := eth.NewClient(u, append([]eth.ClientOption{eth.WithTLSConfig(otherTLS)}, transportOpts...)...)
+		defer client.Close()
+		witnesses = append(witnesses, estimator.Witness{Name: witnessName(u), Client: client})
+	}
+
+	// Input log for replaying estimates, shared by every leadership term
+	var inputLog *estimator.InputLog
+	if cfg.InputLogPath != "" {
+		f, err := os.OpenFile(cfg.InputLogPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			return fmt.Errorf("opening input log: %w", err)
+		}
+		defer f.Close()
+		inputLog = estimator.NewInputLog(f)
+		logger.Info("recording estimate inputs", "path", cfg.InputLogPath)
+	}
 
-	// 3. Provider (atomic estimate storage)
-	provider := estimator.NewProvider()
-
-	// 4. Strategy (estimation algorithm)
-	strategy := estimator.DefaultStrategy()
-
-	// 5. Estimator (orchestrates everything)
-	est := estimator.New(
-		ethClient,
-		ethClient, // also implements TransactionReader
-		subscriber,
-		provider,
-		estimator.WithHistorySize(cfg.HistoryBlocks),
-		estimator.WithMempoolSamples(cfg.MempoolSamples),
-		estimator.WithRecalcInterval(cfg.RecalcInterval),
-		estimator.WithStrategy(strategy),
-		estimator.WithLogger(logger),
+	// 2. Provider (atomic estimate storage)
+	providerOpts := []estimator.ProviderOption{
+		estimator.WithFeeCaps(estimator.FeeCaps{
+			MaxPriorityFeePerGas: gweiCap(cfg.MaxPriorityFeeCap),
+			MaxFeePerGas:         gweiCap(cfg.MaxFeeCap),
+		}),
+		estimator.WithRenderer(grpc.RenderEstimate),
+		estimator.WithHistoryCapacity(cfg.SnapshotBlocks),
+	}
+	if cfg.ExpireEstimates {
+		providerOpts = append(providerOpts, estimator.WithExpiry(cfg.ExpiryGrace))
+	}
+	if cfg.SigningKeyFile != "" {
+		signer, err := estimator.LoadSigner(cfg.SigningKeyFile)
+		if err != nil {
+			return err
+		}
+		logger.Info("signing estimates",
+			"algorithm", signer.Algorithm(),
+			"public_key", "0x"+hex.EncodeToString(signer.PublicKey()),
+		)
+		providerOpts = append(providerOpts, estimator.WithSigner(signer))
+	}
+	provider := estimator.NewProvider(providerOpts...)
+
+	// 3. Strategy (estimation algorithm)
+	strategy := newStrategy(cfg)
+
+	// Webhook deliveries, for consumer registrations and anomaly alerts
+	var dispatcher *webhook.Dispatcher
+	if cfg.WebhookToken != "" || cfg.AnomalyWebhookURL != "" {
+		dispatcher = webhook.NewDispatcher(provider, logger,
+			webhook.WithMaxWebhooks(cfg.WebhookMax),
+			webhook.WithMaxAttempts(cfg.WebhookMaxAttempts),
+		)
+	}
+	var (
+		onAnomaly    func(estimator.Anomaly)
+		onDivergence func(estimator.Divergence)
+		onRejection  func(estimator.Rejection)
 	)
+	if cfg.AnomalyWebhookURL != "" {
+		onAnomaly = func(a estimator.Anomaly) {
+			dispatcher.Send(ctx, cfg.AnomalyWebhookURL, cfg.AnomalyWebhookSecret, webhook.NewAnomalyPayload(a))
+		}
+		onDivergence = func(d estimator.Divergence) {
+			dispatcher.Send(ctx, cfg.AnomalyWebhookURL, cfg.AnomalyWebhookSecret, webhook.NewDivergencePayload(d))
+		}
+		onRejection = func(r estimator.Rejection) {
+			dispatcher.Send(ctx, cfg.AnomalyWebhookURL, cfg.AnomalyWebhookSecret, webhook.NewRejectionPayload(r))
+		}
+	}
+
+	// 4. Subscriber and estimator. In warm standby they are rebuilt for
+	// every leadership term, so followers hold no node subscriptions.
+	if cfg.NodeWSURL == "" {
+		logger.Info("no WebSocket endpoint configured, polling the node for blocks and pending transactions",
+			"poll_interval", cfg.NodePollInterval,
+		)
+	}
+	// Latency metrics, served on the health server's /metrics
+	metrics := observability.NewMetrics()
+	updateLatency := metrics.Histogram("gas_block_update_latency_seconds",
+		"Time from a new block's arrival to the estimate update.",
+		[]float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10})
+	chainLag := metrics.Histogram("gas_block_chain_lag_seconds",
+		"Time from a block's production to the estimate update.",
+		[]float64{0.25, 0.5, 1, 2, 4, 8, 16, 32, 64})
+	metrics.GaugeFunc("gas_chain_lag_seconds",
+		"Time since the current estimate's block was produced.",
+		func() float64 { return provider.ChainLag(time.Now()).Seconds() })
+	metrics.CounterFunc("gas_provider_update_conflicts_total",
+		"Estimates discarded because a newer calculation had already been published.",
+		func() float64 { return float64(provider.ConflictCount()) })
+	onBlockTiming := func(t estimator.BlockTiming) {
+		updateLatency.Observe(t.UpdateLatency().Seconds())
+		chainLag.Observe(t.ChainLag().Seconds())
+	}
+
+	// Node request budget, shared by every leadership term and the API
+	nodeLimiter := estimator.NewNodeLimiter(cfg.NodeRequestsPerSecond)
+
+	newEstimator := func() (*estimator.Estimator, eth.Subscriber) {
+		subscriber := newSubscriber(cfg, auth, tlsConfig, otherTLS, ethClient, logger)
+		opts := []estimator.Option{
+			estimator.WithHistorySize(cfg.HistoryBlocks),
+			estimator.WithHistorySource(estimator.HistorySource(cfg.HistoryBootstrap)),
+			estimator.WithBlockFeeSamples(cfg.BlockFeeSamples),
+			estimator.WithMempoolSamples(cfg.MempoolSamples),
+			estimator.WithMempoolMaxBytes(cfg.MempoolMaxBytes),
+			estimator.WithNodeBudget(estimator.NodeBudget{
+				Limiter:            nodeLimiter,
+				MaxInFlightBatches: cfg.PendingFetchBatches,
+				MaxBatchSize:       cfg.PendingFetchBatchSize,
+			}),
+			estimator.WithFetchTimeouts(estimator.FetchTimeouts{
+				Block: cfg.NodeBlockTimeout,
+				Txs:   cfg.NodeTxFetchTimeout,
+			}),
+			estimator.WithRecalcTriggers(estimator.RecalcTriggers{
+				Blocks:     cfg.RecalcOnBlock,
+				PendingTxs: cfg.RecalcPendingTxs,
+				Interval:   cfg.RecalcInterval,
+			}),
+			estimator.WithFullPendingTxs(cfg.FullPendingTxs),
+			estimator.WithNonceFiltering(cfg.NonceFilter),
+			estimator.WithPendingBlock(cfg.PendingBlock),
+			estimator.WithReceipts(cfg.HistoryReceipts),
+			estimator.WithExplain(cfg.Explain),
+			estimator.WithAutoDetect(cfg.NodeAutoDetect),
+			estimator.WithDevChain(estimator.DevChainMode(cfg.NodeDevChain)),
+			estimator.WithDegradedMode(cfg.NodeDegradedPollInterval),
+			estimator.WithHaltThreshold(cfg.ChainHaltThreshold),
+			estimator.WithResyncGuard(cfg.ResyncPollInterval, uint64(cfg.ResyncMaxRewind)),
+			estimator.WithNetwork(network(cfg)),
+			estimator.WithSamplingPolicy(estimator.SamplingPolicy(cfg.MempoolSampling), cfg.MempoolSamplingWindow),
+			estimator.WithStrategy(strategy),
+			estimator.WithBlockTiming(onBlockTiming),
+			estimator.WithLogger(logger),
+		}
+		if cfg.AnomalyDetection {
+			opts = append(opts, estimator.WithAnomalyDetection(anomalyThresholds(cfg), onAnomaly))
+		}
+		if len(witnesses) > 0 {
+			opts = append(opts, estimator.WithCrossCheck(witnesses, onDivergence))
+		}
+		if cfg.SanityGuard {
+			opts = append(opts, estimator.WithSanityGuard(estimator.SanityLimits{
+				MaxBaseFeeChange: cfg.SanityMaxBaseFeeChange,
+				CongestedMempool: cfg.SanityCongestedMempool,
+			}, onRejection))
+		}
+		if inputLog != nil {
+			opts = append(opts, estimator.WithInputLog(inputLog))
+		}
+		est := estimator.New(
+			ethClient,
+			ethClient, // also implements TransactionReader
+			subscriber,
+			provider,
+			opts...,
+		)
+		return est, subscriber
+	}
+	est, subscriber := newEstimator()
+	defer subscriber.Close()
+	active := &activeEstimator{}
+	active.Store(est)
+
+	// Memory gauges and eviction counters follow the active estimator; the
+	// counters restart with each leadership term
+	memory := func(field func(estimator.MemoryStats) float64) func() float64 {
+		return func() float64 { return field(active.Load().MemoryStats()) }
+	}
+	metrics.GaugeFunc("gas_history_fee_samples",
+		"Priority fees retained in the block history.",
+		memory(func(m estimator.MemoryStats) float64 { return float64(m.HistoryFeeSamples) }))
+	metrics.GaugeFunc("gas_mempool_sample_bytes",
+		"Approximate memory held by the mempool sample.",
+		memory(func(m estimator.MemoryStats) float64 { return float64(m.MempoolBytes) }))
+	metrics.CounterFunc("gas_history_fee_samples_evicted_total",
+		"Priority fees dropped by thinning history blocks to GAS_BLOCK_FEE_SAMPLES.",
+		memory(func(m estimator.MemoryStats) float64 { return float64(m.HistoryFeesEvicted) }))
+	metrics.CounterFunc("gas_mempool_samples_evicted_total",
+		"Sampled pending transactions dropped to make room for newer ones.",
+		memory(func(m estimator.MemoryStats) float64 { return float64(m.MempoolEvicted) }))
+
+	// Head age and reorgs, also served by /v1/chain/status
+	metrics.GaugeFunc("gas_chain_head_age_seconds",
+		"Time since the newest processed block was produced.",
+		func() float64 { return active.Load().ChainStatus().HeadAge.Seconds() })
+	metrics.CounterFunc("gas_chain_reorgs_total",
+		"New heads that replaced or did not build on the previous head.",
+		func() float64 { return float64(active.Load().ChainStatus().Reorgs) })
+	metrics.CounterFunc("gas_node_divergences_total",
+		"New heads on which GAS_CROSSCHECK_URLS nodes disagreed with the primary node.",
+		func() float64 { return float64(active.Load().ChainStatus().Divergences) })
+	metrics.GaugeFunc("gas_node_resyncing",
+		"1 while estimates are held back because the node re-syncs, 0 otherwise.",
+		func() float64 {
+			if active.Load().ChainStatus().Resyncing {
+				return 1
+			}
+			return 0
+		})
+	metrics.CounterFunc("gas_node_resyncs_total",
+		"Node re-syncs detected by eth_syncing or a head rewinding more than GAS_RESYNC_MAX_REWIND blocks.",
+		func() float64 { return float64(active.Load().ChainStatus().Resyncs) })
+	metrics.CounterFunc("gas_estimates_rejected_total",
+		"Estimates the sanity guard refused to publish, keeping the previous one.",
+		func() float64 { return float64(active.Load().EstimatesRejected()) })
+
+	// Transaction type counters reveal new types the estimator predates
+	for _, name := range eth.TxTypeNames() {
+		metrics.CounterFunc("gas_transactions_"+name+"_total",
+			"Transactions of type "+name+" ingested from blocks and the mempool.",
+			func() float64 { return float64(active.Load().TxTypeCounts()[name]) })
+	}
+
+	// 5. Leader election (warm standby only)
+	var lease ha.Lease
+	switch cfg.HAMode {
+	case "redis":
+		lease = ha.NewRedisLease(cfg.HARedisAddr, cfg.HARedisPassword, cfg.HALeaseName)
+	case "kubernetes":
+		lease, err = ha.NewInClusterLease(cfg.HANamespace, cfg.HALeaseName)
+		if err != nil {
+			return fmt.Errorf("kubernetes lease: %w", err)
+		}
+	}
 
 	// 6. API server
-	apiServer := grpc.NewServer(cfg.GRPCAddr, provider, logger)
+	apiOpts := []grpc.Option{
+		grpc.WithNode(ethClient),
+		grpc.WithChainStatus(active),
+		grpc.WithStrategy(active, cfg.APIStrategyOverrides),
+		grpc.WithTimeouts(grpc.Timeouts{
+			Read:  cfg.APIReadTimeout,
+			Node:  cfg.APINodeTimeout,
+			Write: cfg.APIWriteTimeout,
+		}),
+	}
+	apiOpts = append(apiOpts, grpc.WithAccessLogLevel(observability.ParseLevel(cfg.APIAccessLogLevel)))
+	if cfg.APISuggest {
+		apiOpts = append(apiOpts, grpc.WithSuggest(nodeLimiter))
+	}
+	if feed := newPriceFeed(cfg, ethClient); feed != nil {
+		apiOpts = append(apiOpts, grpc.WithPriceFeed(feed))
+	}
+	if cfg.APIDocs {
+		apiOpts = append(apiOpts, grpc.WithDocs())
+	}
+	if !cfg.APICompression {
+		apiOpts = append(apiOpts, grpc.WithCompression(false))
+	}
+	if cfg.DebugToken != "" {
+		apiOpts = append(apiOpts, grpc.WithDebug(active, cfg.DebugToken))
+	}
+	if cfg.AdminToken != "" {
+		apiOpts = append(apiOpts, grpc.WithAdmin(active, cfg.AdminToken))
+	}
+	if cfg.WebhookToken != "" {
+		apiOpts = append(apiOpts, grpc.WithWebhooks(dispatcher, cfg.WebhookToken))
+	}
+	if cfg.TenantsFile != "" {
+		tenants, err := tenant.Open(cfg.TenantsFile)
+		if err != nil {
+			return fmt.Errorf("tenants file: %w", err)
+		}
+		logger.Info("multi-tenant mode enabled", "tenants", len(tenants.List(time.Now())))
+		apiOpts = append(apiOpts, grpc.WithTenants(tenants, cfg.TenantAdminToken))
+	}
+	windows, _ := config.ParseDurations(cfg.AccuracyWindows) // validated by config
+	apiOpts = append(apiOpts, grpc.WithAccuracyWindows(windows))
+	version, _ := grpc.ParseAPIVersion(cfg.APIDefaultVersion) // validated by config
+	apiOpts = append(apiOpts, grpc.WithDefaultVersion(version))
+	if cfg.APIV1Deprecation != "" {
+		var d grpc.Deprecation
+		d.Since, _ = time.Parse(time.RFC3339, cfg.APIV1Deprecation) // validated by config
+		if cfg.APIV1Sunset != "" {
+			d.Sunset, _ = time.Parse(time.RFC3339, cfg.APIV1Sunset)
+		}
+		apiOpts = append(apiOpts, grpc.WithDeprecation(grpc.APIVersion1, d))
+	}
+	apiServer := grpc.NewServer(cfg.GRPCAddr, provider, logger, apiOpts...)
 
 	// 7. Health server
-	healthServer := health.NewServer(cfg.HTTPAddr, provider, logger)
+	healthServer := health.NewServer(cfg.HTTPAddr, readiness{provider, active}, logger)
+	healthServer.Handle("/metrics", metrics)
 
 	// Run all components concurrently
 	errCh := make(chan error, 3)
 
 	go func() {
-		if err := est.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		run := est.Run
+		if lease != nil {
+			run = func(ctx context.Context) error {
+				return runStandby(ctx, cfg, lease, provider, active, newEstimator, logger)
+			}
+		}
+		if err := run(ctx); err != nil && !errors.Is(err, context.Canceled) {
 			errCh <- fmt.Errorf("estimator: %w", err)
 		}
 	}()
@@ -102,6 +435,10 @@ func run(ctx context.Context) error {
 		}
 	}()
 
+	if dispatcher != nil {
+		go dispatcher.Run(ctx)
+	}
+
 	go func() {
 		if err := healthServer.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
 			errCh <- fmt.Errorf("health server: %w", err)
@@ -134,3 +471,323 @@ func run(ctx context.Context) error {
 	slog.Info("shutdown complete")
 	return nil
 }
+
+// activeEstimator serves debug snapshots and admin actions from the
+// estimator of the current leadership term.
+type activeEstimator struct {
+	atomic.Pointer[estimator.Estimator]
+}
+
+func (a *activeEstimator) DebugSnapshot() estimator.DebugSnapshot {
+	return a.Load().DebugSnapshot()
+}
+
+func (a *activeEstimator) ChainStatus() estimator.ChainStatus {
+	return a.Load().ChainStatus()
+}
+
+func (a *activeEstimator) Strategy() estimator.Strategy {
+	return a.Load().Strategy()
+}
+
+func (a *activeEstimator) Evaluate(ctx context.Context, strategy estimator.Strategy) (estimator.Evaluation, error) {
+	return a.Load().Evaluate(ctx, strategy)
+}
+
+func (a *activeEstimator) Pause()       { a.Load().Pause() }
+func (a *activeEstimator) Paused() bool { return a.Load().Paused() }
+
+// Resume and Recalculate refuse on standby followers, where the last
+// term's estimator would overwrite the mirrored estimates.
+func (a *activeEstimator) Resume(ctx context.Context) error {
+	est := a.Load()
+	if !est.Running() {
+		return estimator.ErrNotRunning
+	}
+	return est.Resume(ctx)
+}
+
+func (a *activeEstimator) Recalculate(ctx context.Context) error {
+	est := a.Load()
+	if !est.Running() {
+		return estimator.ErrNotRunning
+	}
+	return est.Recalculate(ctx)
+}
+
+func (a *activeEstimator) Resubscribe(ctx context.Context) error {
+	return a.Load().Resubscribe(ctx)
+}
+
+// readiness is ready once an estimate has been published, except while
+// the running estimator's chain head is halted or its node re-syncs.
+// Standby followers mirror the leader and ignore their idle estimator's
+// head.
+type readiness struct {
+	provider *estimator.Provider
+	active   *activeEstimator
+}
+
+func (r readiness) Ready() bool {
+	if !r.provider.Ready() {
+		return false
+	}
+	st := r.active.ChainStatus()
+	return !st.Running || (!st.Halted && !st.Resyncing)
+}
+
+// runStandby campaigns for leadership until ctx is canceled. The leader runs
+// a fresh estimator for its term; followers mirror the leader's estimates.
+func runStandby(
+	ctx context.Context,
+	cfg *config.Config,
+	lease ha.Lease,
+	provider *estimator.Provider,
+	active *activeEstimator,
+	newEstimator func() (*estimator.Estimator, eth.Subscriber),
+	logger *slog.Logger,
+) error {
+	elector := ha.NewElector(lease, cfg.HAIdentity, cfg.HALeaseTTL, logger)
+	follower := ha.NewFollower(provider, cfg.HAPollInterval, logger, ha.WithAPIKey(cfg.HAAPIKey))
+
+	lead := func(ctx context.Context) error {
+		est, subscriber := newEstimator()
+		defer subscriber.Close()
+		active.Store(est)
+		return est.Run(ctx)
+	}
+	return elector.Run(ctx, lead, follower.Run)
+}
+
+// newSubscriber builds the node subscriber: WebSocket (or IPC) when an
+// endpoint is configured, otherwise polling over the HTTP client. Shards on
+// GAS_WS_SHARD_URLS use shardTLS rather than the primary node's tlsConfig.
+func newSubscriber(cfg *config.Config, auth eth.Auth, tlsConfig, shardTLS *tls.Config, client *eth.Client, logger *slog.Logger) eth.Subscriber {
+	if cfg.NodeWSURL == "" {
+		return eth.NewPollingSubscriber(client, logger, eth.WithPollInterval(cfg.NodePollInterval))
+	}
+	endpoints := cfg.WSEndpoints()
+	if cfg.WSConnections == 1 && len(endpoints) == 1 {
+		return newWSSubscriber(cfg, endpoints[0], auth, tlsConfig, logger)
+	}
+	// Spread the connections round-robin, at least one per endpoint
+	shards := make([]eth.Subscriber, max(cfg.WSConnections, len(endpoints)))
+	for i := range shards {
+		endpointAuth, endpointTLS := auth, tlsConfig
+		if i%len(endpoints) > 0 {
+			// The node credentials are for GAS_NODE_WS_URL only; shard
+			// endpoints carry their own in the URL
+			endpointAuth, endpointTLS = eth.Auth{}, shardTLS
+		}
+		shards[i] = newWSSubscriber(cfg, endpoints[i%len(endpoints)], endpointAuth, endpointTLS, logger.With("shard", i))
+	}
+	return eth.NewShardedSubscriber(logger, shards...)
+}
+
+// newWSSubscriber creates a WebSocket subscriber to endpoint.
+func newWSSubscriber(cfg *config.Config, endpoint string, auth eth.Auth, tlsConfig *tls.Config, logger *slog.Logger) *eth.WSSubscriber {
+	opts := []eth.SubscriberOption{
+		eth.WithSubscriberAuth(auth),
+		eth.WithPing(cfg.NodeWSPingInterval, cfg.NodeWSPongTimeout),
+	}
+	if cfg.NodeProxyURL != "" {
+		proxyURL, _ := url.Parse(cfg.NodeProxyURL) // validated by config
+		opts = append(opts, eth.WithSubscriberProxy(proxyURL))
+	}
+	if tlsConfig != nil {
+		opts = append(opts, eth.WithSubscriberTLSConfig(tlsConfig))
+	}
+	return eth.NewWSSubscriber(endpoint, logger, opts...)
+}
+
+// nodeAuth builds node credentials from configuration.
+func nodeAuth(cfg *config.Config) (eth.Auth, error) {
+	var auth eth.Auth
+
+	if cfg.NodeAuthHeaders != "" {
+		headers, err := eth.ParseHeaders(cfg.NodeAuthHeaders)
+		if err != nil {
+			return auth, fmt.Errorf("GAS_NODE_AUTH_HEADERS: %w", err)
+		}
+		auth.Headers = headers
+	}
+
+	auth.BearerToken = cfg.NodeBearerToken
+	if cfg.NodeBasicAuth != "" {
+		auth.Username, auth.Password, _ = strings.Cut(cfg.NodeBasicAuth, ":")
+	}
+
+	if cfg.NodeJWTSecretFile != "" {
+		data, err := os.ReadFile(cfg.NodeJWTSecretFile)
+		if err != nil {
+			return auth, fmt.Errorf("reading JWT secret: %w", err)
+		}
+		secret, err := eth.ParseJWTSecret(string(data))
+		if err != nil {
+			return auth, err
+		}
+		auth.JWTSecret = secret
+	}
+
+	return auth, nil
+}
+
+// nodeTLS builds the TLS configurations for node connections from
+// configuration: primary for GAS_NODE_HTTP_URL and GAS_NODE_WS_URL, and
+// other for witnesses and WebSocket shards. The server name override, client
+// certificate and skipped verification are meant for the primary node, so
+// other only trusts the CA bundle. Either is nil when no setting applies.
+func nodeTLS(cfg *config.Config) (primary, other *tls.Config, err error) {
+	var roots *x509.CertPool
+	if cfg.NodeTLSCAFile != "" {
+		data, err := os.ReadFile(cfg.NodeTLSCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading CA bundle: %w", err)
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(data) {
+			return nil, nil, fmt.Errorf("no PEM certificates in %s", cfg.NodeTLSCAFile)
+		}
+		other = &tls.Config{RootCAs: roots}
+	}
+
+	if roots == nil && cfg.NodeTLSCertFile == "" && cfg.NodeTLSServerName == "" && !cfg.NodeTLSInsecureSkipVerify {
+		return nil, nil, nil
+	}
+	primary = &tls.Config{
+		RootCAs:            roots,
+		ServerName:         cfg.NodeTLSServerName,
+		InsecureSkipVerify: cfg.NodeTLSInsecureSkipVerify,
+	}
+	if cfg.NodeTLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.NodeTLSCertFile, cfg.NodeTLSKeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		primary.Certificates = []tls.Certificate{cert}
+	}
+	return primary, other, nil
+}
+
+// witnessName labels a cross-check endpoint in logs and alerts by its host,
+// leaving out API keys carried in the path or query.
+func witnessName(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return endpoint
+}
+
+// network returns the configured network labels; zero fields are filled in
+// from the built-in metadata for the chain.
+func network(cfg *config.Config) estimator.Network {
+	n := estimator.Network{
+		Name:           cfg.NetworkName,
+		CurrencySymbol: cfg.NetworkCurrency,
+		BlockTime:      cfg.NetworkBlockTime,
+	}
+	if cfg.NetworkFeeCurrencies != "" {
+		currencies, _ := config.ParseFeeCurrencies(cfg.NetworkFeeCurrencies) // validated by config
+		for _, c := range currencies {
+			n.FeeCurrencies = append(n.FeeCurrencies, estimator.FeeCurrency(c))
+		}
+	}
+	return n
+}
+
+// newPriceFeed builds the configured fiat price feed, or nil if none is configured.
+func newPriceFeed(cfg *config.Config, client *eth.Client) pricefeed.Feed {
+	var feed pricefeed.Feed
+	switch {
+	case cfg.PriceFeedURL != "":
+		feed = pricefeed.NewHTTPFeed(cfg.PriceFeedURL, cfg.PriceFeedPath)
+	case cfg.PriceFeedChainlinkAddress != "":
+		feed = pricefeed.NewChainlinkFeed(client, cfg.PriceFeedChainlinkAddress)
+	default:
+		return nil
+	}
+	// Serve stale prices for up to 10 TTLs if the source is temporarily down
+	return pricefeed.NewCached(feed, cfg.PriceFeedTTL, 10*cfg.PriceFeedTTL)
+}
+
+// anomalyThresholds builds anomaly detection thresholds from configuration.
+func anomalyThresholds(cfg *config.Config) estimator.AnomalyThresholds {
+	th := estimator.DefaultAnomalyThresholds()
+	th.BaseFeeRatio = cfg.AnomalyBaseFeeRatio
+	th.BaseFeeWindow = cfg.AnomalyBaseFeeBlocks
+	th.MempoolRatio = cfg.AnomalyMempoolRatio
+	th.PriorityFeeRatio = cfg.AnomalyPriorityFeeRatio
+	return th
+}
+
+// newStrategy builds the configured estimation strategy.
+func newStrategy(cfg *config.Config) estimator.Strategy {
+	hybrid := estimator.DefaultStrategy()
+	hybrid.ElasticityMultiplier = cfg.ElasticityMultiplier
+	hybrid.BaseFeeChangeDenominator = cfg.BaseFeeChangeDenominator
+	hybrid.HistoricalHalfLife = float64(cfg.HistoryHalfLife)
+	hybrid.GasWeighted = cfg.HistoryGasWeighted
+	hybrid.PendingBlockWeight = cfg.PendingBlockWeight
+	hybrid.BaseFeeHorizon = cfg.BaseFeeHorizon
+	hybrid.BaseFeeMultiplier = cfg.BaseFeeMultiplier
+	outlierMethod, _ := estimator.ParseOutlierMethod(cfg.OutlierFilter) // validated by config
+	outliers := estimator.OutlierFilter{Method: outlierMethod, Threshold: cfg.OutlierThreshold}
+	hybrid.MempoolOutliers = outliers
+	percentiles, _ := estimator.ParsePercentileMethod(cfg.PercentileMethod) // validated by config
+	hybrid.Percentiles = percentiles
+	tiers, _ := config.ParseTierPercentiles(cfg.TierPercentiles) // validated by config
+	minTip, maxTip := gweiFee(cfg.MinPriorityFee), gweiFee(cfg.MaxPriorityFee)
+	hybrid.Tiers = tiers
+	hybrid.MinPriorityFee, hybrid.MaxPriorityFee = minTip, maxTip
+	if cfg.Strategy != "ensemble" {
+		return hybrid
+	}
+
+	feeHistory := estimator.DefaultFeeHistoryStrategy()
+	feeHistory.ElasticityMultiplier = cfg.ElasticityMultiplier
+	feeHistory.BaseFeeChangeDenominator = cfg.BaseFeeChangeDenominator
+	feeHistory.Percentiles = percentiles
+	feeHistory.Tiers = tiers
+	feeHistory.MinPriorityFee, feeHistory.MaxPriorityFee = minTip, maxTip
+
+	mempool := estimator.DefaultMempoolStrategy()
+	mempool.ElasticityMultiplier = cfg.ElasticityMultiplier
+	mempool.BaseFeeChangeDenominator = cfg.BaseFeeChangeDenominator
+	mempool.Outliers = outliers
+	mempool.Percentiles = percentiles
+	mempool.Tiers = tiers
+	mempool.MinPriorityFee, mempool.MaxPriorityFee = minTip, maxTip
+
+	if cfg.BaseFeeMultiplier > 0 {
+		feeHistory.BaseFeeMultiplier = cfg.BaseFeeMultiplier
+		mempool.BaseFeeMultiplier = cfg.BaseFeeMultiplier
+	}
+
+	byName := map[string]estimator.Strategy{
+		"hybrid":      hybrid,
+		"fee_history": feeHistory,
+		"mempool":     mempool,
+	}
+	weights, _ := config.ParseEnsembleWeights(cfg.EnsembleWeights) // validated by config
+	ensemble := &estimator.EnsembleStrategy{Combine: estimator.CombineMethod(cfg.EnsembleCombine)}
+	for _, w := range weights {
+		ensemble.Members = append(ensemble.Members, estimator.EnsembleMember{
+			Strategy: byName[w.Name],
+			Weight:   w.Weight,
+		})
+	}
+	return ensemble
+}
+
+// gweiFee converts a fee in gwei to wei, rounded to the nearest wei.
+func gweiFee(gwei float64) *uint256.Int {
+	return uint256.NewInt(uint64(math.Round(gwei * 1e9)))
+}
+
+// gweiCap converts a fee cap in gwei to wei; nil (no cap) for zero.
+func gweiCap(gwei float64) *uint256.Int {
+	if gwei <= 0 {
+		return nil
+	}
+	return gweiFee(gwei)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/internal/config"
+	"github.com/branched-services/go-gas/pkg/eth"
+)
+
+// writeCert writes a self-signed certificate and its key as PEM files.
+func writeCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "node.internal"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestNodeTLS(t *testing.T) {
+	certFile, keyFile := writeCert(t)
+
+	t.Run("unset", func(t *testing.T) {
+		primary, other, err := nodeTLS(&config.Config{})
+		if err != nil || primary != nil || other != nil {
+			t.Errorf("nodeTLS = %v, %v, %v; want nil configs", primary, other, err)
+		}
+	})
+
+	t.Run("primary settings only", func(t *testing.T) {
+		primary, other, err := nodeTLS(&config.Config{
+			NodeTLSCertFile:           certFile,
+			NodeTLSKeyFile:            keyFile,
+			NodeTLSServerName:         "node.internal",
+			NodeTLSInsecureSkipVerify: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if primary == nil || primary.ServerName != "node.internal" || !primary.InsecureSkipVerify || len(primary.Certificates) != 1 {
+			t.Errorf("primary = %+v, want the server name, skipped verification and client certificate", primary)
+		}
+		if other != nil {
+			t.Errorf("other = %+v, want nil (system roots)", other)
+		}
+	})
+
+	t.Run("all settings", func(t *testing.T) {
+		primary, other, err := nodeTLS(&config.Config{
+			NodeTLSCAFile:             certFile,
+			NodeTLSCertFile:           certFile,
+			NodeTLSKeyFile:            keyFile,
+			NodeTLSServerName:         "node.internal",
+			NodeTLSInsecureSkipVerify: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if primary.RootCAs == nil || primary.ServerName != "node.internal" || len(primary.Certificates) != 1 {
+			t.Errorf("primary = %+v, want the CA bundle, server name and client certificate", primary)
+		}
+		if other == nil || !other.RootCAs.Equal(primary.RootCAs) {
+			t.Fatalf("other = %+v, want the CA bundle", other)
+		}
+		if other.ServerName != "" || other.InsecureSkipVerify || len(other.Certificates) != 0 {
+			t.Errorf("other = %+v, want only the CA bundle", other)
+		}
+	})
+
+	t.Run("invalid CA bundle", func(t *testing.T) {
+		if _, _, err := nodeTLS(&config.Config{NodeTLSCAFile: keyFile}); err == nil {
+			t.Error("nodeTLS accepted a CA bundle without certificates")
+		}
+	})
+}
+
+func TestNewSubscriber_ShardAuth(t *testing.T) {
+	// Record the Authorization header of each WebSocket handshake, by host
+	var mu sync.Mutex
+	auths := make(map[string]string)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		auths[r.Host] = r.Header.Get("Authorization")
+		mu.Unlock()
+		http.Error(w, "no upgrade", http.StatusForbidden)
+	})
+	primary := httptest.NewServer(handler)
+	t.Cleanup(primary.Close)
+	plain := httptest.NewServer(handler)
+	t.Cleanup(plain.Close)
+	withCreds := httptest.NewServer(handler)
+	t.Cleanup(withCreds.Close)
+
+	wsURL := func(srv *httptest.Server) *url.URL {
+		u, _ := url.Parse(strings.Replace(srv.URL, "http", "ws", 1))
+		return u
+	}
+	credsURL := wsURL(withCreds)
+	credsURL.User = url.UserPassword("shard", "secret")
+
+	cfg := &config.Config{
+		NodeWSURL:     wsURL(primary).String(),
+		WSShardURLs:   wsURL(plain).String() + "," + credsURL.String(),
+		WSConnections: 3,
+	}
+	sub := newSubscriber(cfg, eth.Auth{BearerToken: "primary-token"}, nil, nil, nil,
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(func() { sub.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, _ = sub.SubscribeNewHeads(ctx) // every handshake is refused
+	eventually(t, 5*time.Second, "a handshake on every endpoint", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(auths) == 3
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]string{
+		wsURL(primary).Host: "Bearer primary-token",
+		wsURL(plain).Host:   "",
+		credsURL.Host:       "Basic " + base64.StdEncoding.EncodeToString([]byte("shard:secret")),
+	}
+	for host, auth := range want {
+		if auths[host] != auth {
+			t.Errorf("handshake to %s with Authorization %q, want %q", host, auths[host], auth)
+		}
+	}
+}
+
+func TestGweiCap(t *testing.T) {
+	tests := []struct {
+		gwei float64
+		want uint64
+	}{
+		{0.3, 300_000_000},
+		{1.1, 1_100_000_000},
+		{500, 500_000_000_000},
+	}
+	for _, tt := range tests {
+		if got := gweiCap(tt.gwei); got == nil || got.Uint64() != tt.want {
+			t.Errorf("gweiCap(%v) = %v, want %d", tt.gwei, got, tt.want)
+		}
+	}
+	if got := gweiCap(0); got != nil {
+		t.Errorf("gweiCap(0) = %v, want nil (no cap)", got)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/branched-services/go-gas/internal/config"
+	"github.com/branched-services/go-gas/pkg/estimator"
+)
+
+// replayLine is one rebuilt estimate as printed by the replay command:
+// base fee and tips in wei as published then and as computed now.
+type replayLine struct {
+	Time        time.Time   `json:"time"`
+	BlockNumber uint64      `json:"block_number"`
+	PendingTxs  int         `json:"pending_txs"`
+	Recorded    replayTiers `json:"recorded"`
+	Replayed    replayTiers `json:"replayed"`
+}
+
+type replayTiers struct {
+	BaseFee  string `json:"base_fee"`
+	Urgent   string `json:"urgent"`
+	Fast     string `json:"fast"`
+	Standard string `json:"standard"`
+	Slow     string `json:"slow"`
+}
+
+// runReplay implements "gas-estimator replay [-from t] [-to t] <input log>":
+// it rebuilds every estimate recorded in a GAS_INPUT_LOG file with the
+// strategy the GAS_ configuration selects and prints them as JSON lines.
+func runReplay(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	from := fs.String("from", "", "skip estimates calculated before this RFC 3339 time")
+	to := fs.String("to", "", "stop at estimates calculated after this RFC 3339 time")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: gas-estimator replay [-from t] [-to t] <input log>")
+	}
+	var start, end time.Time
+	var err error
+	if *from != "" {
+		if start, err = time.Parse(time.RFC3339, *from); err != nil {
+			return fmt.Errorf("invalid -from: %w", err)
+		}
+	}
+	if *to != "" {
+		if end, err = time.Parse(time.RFC3339, *to); err != nil {
+			return fmt.Errorf("invalid -to: %w", err)
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(out)
+	return estimator.Replay(ctx, f, newStrategy(cfg), func(r estimator.ReplayedEstimate) error {
+		if r.Input.Now.Before(start) || (!end.IsZero() && r.Input.Now.After(end)) {
+			return nil
+		}
+		return enc.Encode(replayLine{
+			Time:        r.Input.Now,
+			BlockNumber: r.Input.CurrentBlock.Number,
+			PendingTxs:  len(r.Input.PendingTxs),
+			Recorded:    newReplayTiers(r.Recorded),
+			Replayed:    newReplayTiers(r.Replayed),
+		})
+	})
+}
+
+func newReplayTiers(est *estimator.GasEstimate) replayTiers {
+	return replayTiers{
+		BaseFee:  est.BaseFee.Dec(),
+		Urgent:   est.Urgent.MaxPriorityFeePerGas.Dec(),
+		Fast:     est.Fast.MaxPriorityFeePerGas.Dec(),
+		Standard: est.Standard.MaxPriorityFeePerGas.Dec(),
+		Slow:     est.Slow.MaxPriorityFeePerGas.Dec(),
+	}
+}
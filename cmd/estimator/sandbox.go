@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/branched-services/go-gas/internal/api/grpc"
+	"github.com/branched-services/go-gas/internal/config"
+	"github.com/branched-services/go-gas/pkg/estimator"
+	"github.com/branched-services/go-gas/pkg/health"
+)
+
+// runSandbox serves the API from the estimates rebuilt from the input log
+// at cfg.ReplayPath instead of a node, for demos, tests and strategy
+// development. Endpoints that need a node, such as /v1/gas/suggest, are
+// not served. It keeps serving the last estimate once the log is played.
+func runSandbox(ctx context.Context, cfg *config.Config, logger *slog.Logger) error {
+	f, err := os.Open(cfg.ReplayPath)
+	if err != nil {
+		return fmt.Errorf("opening replay log: %w", err)
+	}
+	defer f.Close()
+
+	provider := estimator.NewProvider(
+		estimator.WithFeeCaps(estimator.FeeCaps{
+			MaxPriorityFeePerGas: gweiCap(cfg.MaxPriorityFeeCap),
+			MaxFeePerGas:         gweiCap(cfg.MaxFeeCap),
+		}),
+		estimator.WithRenderer(grpc.RenderEstimate),
+		estimator.WithHistoryCapacity(cfg.SnapshotBlocks),
+	)
+
+	apiOpts := []grpc.Option{
+		grpc.WithTimeouts(grpc.Timeouts{
+			Read:  cfg.APIReadTimeout,
+			Write: cfg.APIWriteTimeout,
+		}),
+	}
+	if cfg.APIDocs {
+		apiOpts = append(apiOpts, grpc.WithDocs())
+	}
+	if !cfg.APICompression {
+		apiOpts = append(apiOpts, grpc.WithCompression(false))
+	}
+	windows, _ := config.ParseDurations(cfg.AccuracyWindows) // validated by config
+	apiOpts = append(apiOpts, grpc.WithAccuracyWindows(windows))
+	apiServer := grpc.NewServer(cfg.GRPCAddr, provider, logger, apiOpts...)
+	healthServer := health.NewServer(cfg.HTTPAddr, provider, logger)
+
+	logger.Info("sandbox mode: replaying recorded estimates instead of connecting to a node",
+		"path", cfg.ReplayPath,
+		"speed", cfg.ReplaySpeed,
+	)
+
+	errCh := make(chan error, 3)
+	go func() {
+		err := estimator.Playback(ctx, f, newStrategy(cfg), provider, cfg.ReplaySpeed)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			errCh <- fmt.Errorf("replay: %w", err)
+			return
+		}
+		if err == nil {
+			logger.Info("replay finished, serving the last estimate", "updates", provider.UpdateCount())
+		}
+	}()
+	go func() {
+		if err := apiServer.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			errCh <- fmt.Errorf("api server: %w", err)
+		}
+	}()
+	go func() {
+		if err := healthServer.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			errCh <- fmt.Errorf("health server: %w", err)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		slog.Info("received shutdown signal")
+	case err := <-errCh:
+		slog.Error("component failed", "error", err)
+		return err
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := apiServer.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("api server shutdown error", "error", err)
+	}
+	if err := healthServer.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("health server shutdown error", "error", err)
+	}
+	return nil
+}
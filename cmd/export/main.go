@@ -0,0 +1,315 @@
+// Package main implements export, which dumps the estimates retained by a
+// running gas estimator to CSV or JSON Lines for offline analysis (pandas,
+// DuckDB, spreadsheets).
+//
+// Usage:
+//
+//	export [--addr http://localhost:9090] [--from 1h] [--to now]
+//	       [--format csv|json] [--out estimates.csv] [--debug-token TOKEN]
+//
+// Each row is the final estimate published for one block. Fee amounts are
+// written as exact decimal wei strings; load them as strings or decimals,
+// not floats, to keep full precision.
+//
+// The estimator keeps the last ~1024 blocks of estimates, so the time range
+// can only reach that far back. With --debug-token (the estimator's
+// GAS_DEBUG_TOKEN), rows for blocks still in the estimator's block history
+// also carry the block's own fee statistics: its base fee, gas used, gas
+// limit, utilization and number of fee-paying transactions. Older rows leave
+// those columns empty.
+//
+// With --format json, each row is a JSON object on its own line with the CSV
+// columns as string fields; empty columns are left out.
+//
+// Parquet is not written directly. To get it, convert with DuckDB:
+//
+//	COPY (SELECT * FROM read_csv('estimates.csv', all_varchar=true)) TO 'estimates.parquet';
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/branched-services/go-gas/internal/api/grpc"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "export:", err)
+		os.Exit(1)
+	}
+}
+
+// csvHeader lists the exported columns. Columns prefixed "block_" describe
+// the head block the estimate was computed at and need --debug-token.
+var csvHeader = []string{
+	"chain_id",
+	"block_number",
+	"timestamp",
+	"base_fee",
+	"urgent_max_priority_fee_per_gas",
+	"urgent_max_fee_per_gas",
+	"fast_max_priority_fee_per_gas",
+	"fast_max_fee_per_gas",
+	"standard_max_priority_fee_per_gas",
+	"standard_max_fee_per_gas",
+	"slow_max_priority_fee_per_gas",
+	"slow_max_fee_per_gas",
+	"block_base_fee",
+	"block_gas_used",
+	"block_gas_limit",
+	"block_utilization",
+	"block_priority_fees",
+}
+
+func run(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	addr := fs.String("addr", envOrDefault("GAS_API_URL", "http://localhost:9090"), "estimator API base URL")
+	fromFlag := fs.String("from", "1h", "start of the range: RFC 3339 time or duration before now")
+	toFlag := fs.String("to", "", "end of the range: RFC 3339 time or duration before now (default now)")
+	format := fs.String("format", "csv", "output format: csv or json (JSON Lines)")
+	outPath := fs.String("out", "", "output file (default stdout)")
+	debugToken := fs.String("debug-token", os.Getenv("GAS_DEBUG_TOKEN"), "debug token, to include per-block statistics")
+	timeout := fs.Duration("timeout", 30*time.Second, "request timeout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch *format {
+	case "csv", "json":
+	case "parquet":
+		return errors.New("parquet output is not supported; export CSV and convert it (see the package documentation)")
+	default:
+		return fmt.Errorf("invalid --format %q (want csv or json)", *format)
+	}
+
+	now := time.Now()
+	from, err := parseTime(*fromFlag, now)
+	if err != nil {
+		return fmt.Errorf("invalid --from: %w", err)
+	}
+	to := now
+	if *toFlag != "" {
+		if to, err = parseTime(*toFlag, now); err != nil {
+			return fmt.Errorf("invalid --to: %w", err)
+		}
+	}
+	if !to.After(from) {
+		return errors.New("--to must be after --from")
+	}
+
+	c := &client{
+		addr:    strings.TrimRight(*addr, "/"),
+		timeout: *timeout,
+		http:    &http.Client{},
+	}
+
+	query := url.Values{}
+	query.Set("since", now.Sub(from).String())
+	var history grpc.GasHistoryResponse
+	if err := c.getJSON(ctx, "/v1/gas/history", query, "", &history); err != nil {
+		return fmt.Errorf("fetching history: %w", err)
+	}
+
+	blocks := make(map[uint64]grpc.DebugBlock)
+	if *debugToken != "" {
+		// Only the block history is needed; the full DebugResponse can't be
+		// decoded generically (StrategyConfig is an interface)
+		var debug struct {
+			History []grpc.DebugBlock `json:"history"`
+		}
+		if err := c.getJSON(ctx, "/debug/estimator", nil, *debugToken, &debug); err != nil {
+			return fmt.Errorf("fetching block statistics: %w", err)
+		}
+		for _, b := range debug.History {
+			blocks[b.Number] = b
+		}
+	}
+
+	out := stdout
+	var file *os.File
+	if *outPath != "" {
+		if file, err = os.Create(*outPath); err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	var records [][]string
+	for _, est := range history.Estimates {
+		ts, err := time.Parse(time.RFC3339Nano, est.Timestamp)
+		if err != nil {
+			return fmt.Errorf("block %d: invalid timestamp %q", est.BlockNumber, est.Timestamp)
+		}
+		if ts.Before(from) || ts.After(to) {
+			continue
+		}
+		records = append(records, csvRecord(est, blocks))
+	}
+
+	if *format == "json" {
+		err = writeJSONLines(out, records)
+	} else {
+		err = writeCSV(out, records)
+	}
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", *format, err)
+	}
+	if file != nil {
+		return file.Close()
+	}
+	return nil
+}
+
+// csvRecord formats one estimate, with its block's statistics if known, in
+// csvHeader order.
+func csvRecord(est grpc.GasEstimateResponse, blocks map[uint64]grpc.DebugBlock) []string {
+	record := []string{
+		strconv.FormatUint(est.ChainID, 10),
+		strconv.FormatUint(est.BlockNumber, 10),
+		est.Timestamp,
+		est.BaseFee,
+	}
+	for _, level := range []grpc.EstimateLevel{
+		est.Estimates.Urgent,
+		est.Estimates.Fast,
+		est.Estimates.Standard,
+		est.Estimates.Slow,
+	} {
+		record = append(record, level.MaxPriorityFeePerGas, level.MaxFeePerGas)
+	}
+
+	b, ok := blocks[est.BlockNumber]
+	if !ok {
+		return append(record, "", "", "", "", "")
+	}
+	return append(record,
+		b.BaseFee,
+		strconv.FormatUint(b.GasUsed, 10),
+		strconv.FormatUint(b.GasLimit, 10),
+		strconv.FormatFloat(b.Utilization, 'f', -1, 64),
+		strconv.Itoa(b.PriorityFees),
+	)
+}
+
+// writeCSV writes the header row and records.
+func writeCSV(out io.Writer, records [][]string) error {
+	w := csv.NewWriter(out)
+	w.Write(csvHeader)
+	w.WriteAll(records)
+	return w.Error()
+}
+
+// writeJSONLines writes each record as a JSON object keyed by the CSV
+// columns, in csvHeader order, leaving out empty columns.
+func writeJSONLines(out io.Writer, records [][]string) error {
+	w := bufio.NewWriter(out)
+	for _, record := range records {
+		w.WriteByte('{')
+		first := true
+		for i, v := range record {
+			if v == "" {
+				continue
+			}
+			if !first {
+				w.WriteByte(',')
+			}
+			first = false
+			// Strings always encode
+			key, _ := json.Marshal(csvHeader[i])
+			val, _ := json.Marshal(v)
+			w.Write(key)
+			w.WriteByte(':')
+			w.Write(val)
+		}
+		w.WriteString("}\n")
+	}
+	return w.Flush()
+}
+
+// parseTime parses an RFC 3339 time or a duration before now.
+func parseTime(v string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 time nor a duration", v)
+	}
+	return now.Add(-d), nil
+}
+
+// client fetches JSON from the estimator API.
+type client struct {
+	addr    string
+	timeout time.Duration
+	http    *http.Client
+}
+
+func (c *client) getJSON(ctx context.Context, path string, query url.Values, token string, v any) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	u := c.addr + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return decodeError(resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// decodeError turns a non-200 API response into an error.
+func decodeError(resp *http.Response) error {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body.Error)
+}
+
+func envOrDefault(key, defaultVal string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return defaultVal
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/internal/api/grpc"
+)
+
+// newAPI serves history estimates for blocks 100-102, published 90, 30 and
+// 5 minutes ago, and debug statistics for block 102.
+func newAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	now := time.Now()
+	estimate := func(block uint64, age time.Duration) grpc.GasEstimateResponse {
+		level := grpc.EstimateLevel{MaxPriorityFeePerGas: "2000000000", MaxFeePerGas: "22000000000"}
+		return grpc.GasEstimateResponse{
+			ChainID:     1,
+			BlockNumber: block,
+			Timestamp:   now.Add(-age).UTC().Format(time.RFC3339Nano),
+			BaseFee:     "10000000000",
+			Estimates:   grpc.EstimatesBundle{Urgent: level, Fast: level, Standard: level, Slow: level},
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/gas/history", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(grpc.GasHistoryResponse{Estimates: []grpc.GasEstimateResponse{
+			estimate(100, 90*time.Minute),
+			estimate(101, 30*time.Minute),
+			estimate(102, 5*time.Minute),
+		}})
+	})
+	mux.HandleFunc("/debug/estimator", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer debug-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"history": []grpc.DebugBlock{{
+			Number: 102, BaseFee: "9000000000", GasUsed: 15_000_000, GasLimit: 30_000_000, Utilization: 0.5, PriorityFees: 120,
+		}}})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRun_InvalidRange(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--from", "yesterday"}, "invalid --from"},
+		{[]string{"--from", "-1h"}, "invalid --from"},
+		{[]string{"--to", "soon"}, "invalid --to"},
+		{[]string{"--from", "10m", "--to", "1h"}, "--to must be after --from"},
+		{[]string{"--from", "1h", "--to", "1h"}, "--to must be after --from"},
+		{[]string{"--from", "2024-06-01T13:00:00Z", "--to", "2024-06-01T12:00:00Z"}, "--to must be after --from"},
+		{[]string{"--format", "xml"}, "invalid --format"},
+		{[]string{"--format", "parquet"}, "parquet output is not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			// Invalid arguments fail before any request is made
+			args := append([]string{"--addr", "http://127.0.0.1:0"}, tt.args...)
+			err := run(context.Background(), args, &bytes.Buffer{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("run error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_CSV(t *testing.T) {
+	srv := newAPI(t)
+	var out bytes.Buffer
+	if err := run(context.Background(), []string{"--addr", srv.URL, "--from", "1h", "--debug-token", "debug-token"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want the header and blocks 101 and 102: %q", len(rows), rows)
+	}
+	if strings.Join(rows[0], ",") != strings.Join(csvHeader, ",") {
+		t.Errorf("header = %q, want %q", rows[0], csvHeader)
+	}
+	want := [][]string{
+		{"1", "101", rows[1][2], "10000000000", "2000000000", "22000000000", "2000000000", "22000000000",
+			"2000000000", "22000000000", "2000000000", "22000000000", "", "", "", "", ""},
+		{"1", "102", rows[2][2], "10000000000", "2000000000", "22000000000", "2000000000", "22000000000",
+			"2000000000", "22000000000", "2000000000", "22000000000", "9000000000", "15000000", "30000000", "0.5", "120"},
+	}
+	for i, row := range rows[1:] {
+		if strings.Join(row, ",") != strings.Join(want[i], ",") {
+			t.Errorf("row %d = %q, want %q", i+1, row, want[i])
+		}
+	}
+}
+
+func TestRun_JSON(t *testing.T) {
+	srv := newAPI(t)
+	var out bytes.Buffer
+	if err := run(context.Background(), []string{"--addr", srv.URL, "--from", "1h", "--format", "json", "--debug-token", "debug-token"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want blocks 101 and 102:\n%s", len(lines), out.String())
+	}
+	var rows []map[string]string
+	for _, line := range lines {
+		var row map[string]string
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		rows = append(rows, row)
+	}
+	if rows[0]["block_number"] != "101" || rows[0]["base_fee"] != "10000000000" || rows[0]["slow_max_fee_per_gas"] != "22000000000" {
+		t.Errorf("first row = %v, want block 101's estimate", rows[0])
+	}
+	if _, ok := rows[0]["block_base_fee"]; ok {
+		t.Errorf("first row = %v, want no block statistics", rows[0])
+	}
+	if rows[1]["block_number"] != "102" || rows[1]["block_gas_used"] != "15000000" || rows[1]["block_utilization"] != "0.5" {
+		t.Errorf("second row = %v, want block 102 with its statistics", rows[1])
+	}
+
+	// Columns keep the CSV order
+	if !strings.HasPrefix(lines[0], `{"chain_id":"1","block_number":"101","timestamp":`) {
+		t.Errorf("first line = %s, want the columns in CSV order", lines[0])
+	}
+}
+
+func TestRun_EmptyRange(t *testing.T) {
+	srv := newAPI(t)
+	args := []string{"--addr", srv.URL, "--from", "3h", "--to", "2h"}
+
+	var out bytes.Buffer
+	if err := run(context.Background(), args, &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Join(csvHeader, ",") + "\n"; out.String() != want {
+		t.Errorf("csv = %q, want only the header", out.String())
+	}
+
+	out.Reset()
+	if err := run(context.Background(), append(args, "--format", "json"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("json = %q, want nothing", out.String())
+	}
+}
+
+func TestRun_DebugTokenRejected(t *testing.T) {
+	srv := newAPI(t)
+	err := run(context.Background(), []string{"--addr", srv.URL, "--debug-token", "wrong"}, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Errorf("run error = %v, want the API's error", err)
+	}
+}
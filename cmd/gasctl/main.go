@@ -0,0 +1,400 @@
+// Package main implements gasctl, a command-line client for a running gas estimator.
+//
+// Usage:
+//
+//	gasctl [global flags] estimate [--tier fast] [--chain 1]
+//	gasctl [global flags] stream [--min-change-pct 5] [--min-base-fee-delta 1000000000]
+//	gasctl [global flags] history [--since 1h]
+//	gasctl [global flags] cost --gas 120000 [--tier fast]
+//	gasctl schema [--format json|ts]
+//
+// schema prints the API's JSON Schema or TypeScript declarations, as served
+// at /v1/schema and /v1/schema.d.ts, without contacting an estimator, so
+// front-end builds can generate them from a checkout:
+//
+//	go run ./cmd/gasctl schema --format ts > src/gas-api.d.ts
+//
+// Global flags:
+//
+//	--addr     estimator API base URL (default $GAS_API_URL or http://localhost:9090)
+//	--output   output format: table or json (default table)
+//	--timeout  request timeout for non-streaming commands (default 5s)
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/branched-services/go-gas/internal/api/grpc"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "gasctl:", err)
+		os.Exit(1)
+	}
+}
+
+// client holds global options shared by all subcommands.
+type client struct {
+	addr    string
+	output  string
+	timeout time.Duration
+	http    *http.Client
+}
+
+func run(ctx context.Context, args []string, out io.Writer) error {
+	global := flag.NewFlagSet("gasctl", flag.ContinueOnError)
+	addr := global.String("addr", envOrDefault("GAS_API_URL", "http://localhost:9090"), "estimator API base URL")
+	output := global.String("output", "table", "output format: table or json")
+	timeout := global.Duration("timeout", 5*time.Second, "request timeout")
+	global.Usage = func() {
+		fmt.Fprintln(global.Output(), "usage: gasctl [flags] <estimate|stream|history|cost|schema> [command flags]")
+		global.PrintDefaults()
+	}
+
+	if err := global.Parse(args); err != nil {
+		return err
+	}
+	if *output != "table" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (want table or json)", *output)
+	}
+
+	c := &client{
+		addr:    strings.TrimRight(*addr, "/"),
+		output:  *output,
+		timeout: *timeout,
+		http:    &http.Client{},
+	}
+
+	rest := global.Args()
+	if len(rest) == 0 {
+		global.Usage()
+		return errors.New("missing command")
+	}
+
+	switch rest[0] {
+	case "estimate":
+		return c.estimate(ctx, rest[1:], out)
+	case "stream":
+		return c.stream(ctx, rest[1:], out)
+	case "history":
+		return c.history(ctx, rest[1:], out)
+	case "cost":
+		return c.cost(ctx, rest[1:], out)
+	case "schema":
+		return schema(rest[1:], out)
+	default:
+		return fmt.Errorf("unknown command %q", rest[0])
+	}
+}
+
+// estimate prints the current estimate, optionally a single tier.
+func (c *client) estimate(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("estimate", flag.ContinueOnError)
+	tier := fs.String("tier", "", "only show one tier: urgent, fast, standard, slow")
+	chain := fs.Uint64("chain", 0, "expected chain ID; fails if the estimator serves a different chain")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var resp grpc.GasEstimateResponse
+	if err := c.getJSON(ctx, "/v1/gas/estimate", nil, &resp); err != nil {
+		return err
+	}
+
+	if *chain != 0 && resp.ChainID != *chain {
+		return fmt.Errorf("estimator serves chain %d, not %d", resp.ChainID, *chain)
+	}
+
+	if *tier != "" {
+		level, ok := tierOf(resp.Estimates, *tier)
+		if !ok {
+			return fmt.Errorf("unknown tier %q", *tier)
+		}
+		if c.output == "json" {
+			return writeJSON(out, level)
+		}
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "BLOCK\tBASE FEE\tTIER\tPRIORITY FEE\tMAX FEE\tGAS PRICE")
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", resp.BlockNumber, resp.BaseFee, *tier,
+			level.MaxPriorityFeePerGas, level.MaxFeePerGas, level.GasPrice)
+		return tw.Flush()
+	}
+
+	if c.output == "json" {
+		return writeJSON(out, resp)
+	}
+	return writeTable(out, []grpc.GasEstimateResponse{resp})
+}
+
+// stream prints estimate events as they arrive until interrupted.
+func (c *client) stream(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("stream", flag.ContinueOnError)
+	minChange := fs.String("min-change-pct", "", "only emit when a tier moves more than this percent")
+	minBaseFee := fs.String("min-base-fee-delta", "", "only emit when base fee moves more than this many wei")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	if *minChange != "" {
+		query.Set("min_change_pct", *minChange)
+	}
+	if *minBaseFee != "" {
+		query.Set("min_base_fee_delta", *minBaseFee)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url("/v1/gas/estimate/stream", query), nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("connecting: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return decodeError(resp)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	if c.output == "table" {
+		fmt.Fprintln(tw, "BLOCK\tBASE FEE\tURGENT\tFAST\tSTANDARD\tSLOW")
+		tw.Flush()
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	event := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			event = name
+			continue
+		}
+		if line == "" {
+			event = ""
+			continue
+		}
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+
+		if event == "shutdown" {
+			fmt.Fprintln(os.Stderr, "server shutting down, stream closed")
+			return nil
+		}
+
+		if c.output == "json" {
+			fmt.Fprintln(out, data)
+			continue
+		}
+
+		var ev grpc.StreamUpdate
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return fmt.Errorf("decoding event: %w", err)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			ev.BlockNumber, ev.BaseFee, ev.Urgent, ev.Fast, ev.Standard, ev.Slow)
+		tw.Flush()
+	}
+
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("reading stream: %w", err)
+	}
+	return nil
+}
+
+// history prints the per-block estimates published in the lookback window.
+func (c *client) history(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	since := fs.Duration("since", time.Hour, "lookback window")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("since", since.String())
+
+	var resp grpc.GasHistoryResponse
+	if err := c.getJSON(ctx, "/v1/gas/history", query, &resp); err != nil {
+		return err
+	}
+
+	if c.output == "json" {
+		return writeJSON(out, resp)
+	}
+	return writeTable(out, resp.Estimates)
+}
+
+// cost prints the total cost of a transaction using --gas gas at a tier.
+func (c *client) cost(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("cost", flag.ContinueOnError)
+	gas := fs.Uint64("gas", 0, "gas used by the transaction (required)")
+	tier := fs.String("tier", "standard", "tier: urgent, fast, standard, slow")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *gas == 0 {
+		return errors.New("--gas is required")
+	}
+
+	query := url.Values{}
+	query.Set("gas", fmt.Sprint(*gas))
+	query.Set("tier", strings.ToLower(*tier))
+
+	var resp grpc.CostResponse
+	if err := c.getJSON(ctx, "/v1/gas/cost", query, &resp); err != nil {
+		return err
+	}
+
+	if c.output == "json" {
+		return writeJSON(out, resp)
+	}
+	symbol := resp.CurrencySymbol
+	if symbol == "" {
+		symbol = "NATIVE"
+	}
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "BLOCK\tTIER\tGAS\tCOST\tWEI\tGWEI\t%s\tUSD\n", symbol)
+	for _, row := range []struct {
+		name   string
+		amount grpc.CostAmount
+	}{{"estimated", resp.Estimated}, {"max", resp.Max}} {
+		usd := "-"
+		if row.amount.USD != nil {
+			usd = fmt.Sprintf("%.2f", *row.amount.USD)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n", resp.BlockNumber, resp.Tier, resp.Gas,
+			row.name, row.amount.Wei, row.amount.Gwei, row.amount.Ether, usd)
+	}
+	return tw.Flush()
+}
+
+// schema prints the API's generated JSON Schema or TypeScript declarations.
+func schema(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	format := fs.String("format", "json", "json (JSON Schema) or ts (TypeScript declarations)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var doc []byte
+	switch *format {
+	case "json":
+		doc = append(grpc.JSONSchema(), '\n')
+	case "ts":
+		doc = grpc.TypeScript()
+	default:
+		return fmt.Errorf("invalid --format %q (want json or ts)", *format)
+	}
+	_, err := out.Write(doc)
+	return err
+}
+
+func (c *client) url(path string, query url.Values) string {
+	u := c.addr + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+func (c *client) getJSON(ctx context.Context, path string, query url.Values, v any) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url(path, query), nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return decodeError(resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// decodeError turns a non-200 API response into an error.
+func decodeError(resp *http.Response) error {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body.Error)
+}
+
+func tierOf(b grpc.EstimatesBundle, tier string) (grpc.EstimateLevel, bool) {
+	switch strings.ToLower(tier) {
+	case "urgent":
+		return b.Urgent, true
+	case "fast":
+		return b.Fast, true
+	case "standard":
+		return b.Standard, true
+	case "slow":
+		return b.Slow, true
+	default:
+		return grpc.EstimateLevel{}, false
+	}
+}
+
+func writeTable(out io.Writer, ests []grpc.GasEstimateResponse) error {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHAIN\tBLOCK\tTIMESTAMP\tBASE FEE\tURGENT\tFAST\tSTANDARD\tSLOW")
+	for _, e := range ests {
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.ChainID, e.BlockNumber, e.Timestamp, e.BaseFee,
+			e.Estimates.Urgent.MaxPriorityFeePerGas,
+			e.Estimates.Fast.MaxPriorityFeePerGas,
+			e.Estimates.Standard.MaxPriorityFeePerGas,
+			e.Estimates.Slow.MaxPriorityFeePerGas,
+		)
+	}
+	return tw.Flush()
+}
+
+func writeJSON(out io.Writer, v any) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func envOrDefault(key, defaultVal string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return defaultVal
+}
@@ -0,0 +1,329 @@
+// Package main implements loadgen, a load generator for the gas estimator API.
+//
+// It issues concurrent requests against an estimate endpoint, optionally
+// holds open SSE streams at the same time, and reports throughput, latency
+// percentiles and error rates.
+//
+// Usage:
+//
+//	loadgen [--addr http://localhost:9090] [--concurrency 50] [--duration 30s]
+//	        [--rate 0] [--path /v1/gas/estimate] [--etag] [--streams 0]
+//	        [--max-error-rate 0.01]
+//
+// Exits non-zero if the request error rate exceeds --max-error-rate.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "loadgen:", err)
+		os.Exit(1)
+	}
+}
+
+// config holds the parsed command-line flags.
+type config struct {
+	addr         string
+	path         string
+	concurrency  int
+	duration     time.Duration
+	rate         int
+	timeout      time.Duration
+	etag         bool
+	streams      int
+	maxErrorRate float64
+}
+
+func run(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	cfg := config{}
+	fs.StringVar(&cfg.addr, "addr", envOrDefault("GAS_API_URL", "http://localhost:9090"), "estimator API base URL")
+	fs.StringVar(&cfg.path, "path", "/v1/gas/estimate", "request path and query")
+	fs.IntVar(&cfg.concurrency, "concurrency", 50, "concurrent request workers")
+	fs.DurationVar(&cfg.duration, "duration", 30*time.Second, "test duration")
+	fs.IntVar(&cfg.rate, "rate", 0, "target total requests per second (0 = as fast as possible)")
+	fs.DurationVar(&cfg.timeout, "timeout", 5*time.Second, "per-request timeout")
+	fs.BoolVar(&cfg.etag, "etag", false, "send If-None-Match with the last ETag seen, like a caching poller")
+	fs.IntVar(&cfg.streams, "streams", 0, "SSE streams to hold open during the test")
+	fs.Float64Var(&cfg.maxErrorRate, "max-error-rate", 0.01, "fail if the request error rate exceeds this fraction")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if cfg.concurrency < 1 {
+		return errors.New("--concurrency must be at least 1")
+	}
+	if cfg.duration <= 0 {
+		return errors.New("--duration must be positive")
+	}
+	cfg.addr = strings.TrimRight(cfg.addr, "/")
+
+	client := &http.Client{
+		Timeout: cfg.timeout,
+		Transport: &http.Transport{
+			MaxIdleConns:        cfg.concurrency + cfg.streams,
+			MaxIdleConnsPerHost: cfg.concurrency + cfg.streams,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+	// Streams must not be cut off by the request timeout
+	streamClient := &http.Client{Transport: client.Transport}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.duration)
+	defer cancel()
+
+	fmt.Fprintf(out, "loadgen: %s%s, %d workers, %d streams, %s\n",
+		cfg.addr, cfg.path, cfg.concurrency, cfg.streams, cfg.duration)
+
+	var streamStats streamStats
+	var streamWG sync.WaitGroup
+	for i := 0; i < cfg.streams; i++ {
+		streamWG.Add(1)
+		go func() {
+			defer streamWG.Done()
+			streamStats.run(ctx, streamClient, cfg.addr+"/v1/gas/estimate/stream")
+		}()
+	}
+
+	// Optional global rate limit shared by all workers
+	var tokens <-chan time.Time
+	if cfg.rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(cfg.rate))
+		defer ticker.Stop()
+		tokens = ticker.C
+	}
+
+	start := time.Now()
+	results := make([]*workerStats, cfg.concurrency)
+	var wg sync.WaitGroup
+	for i := range results {
+		results[i] = &workerStats{statuses: make(map[int]int)}
+		wg.Add(1)
+		go func(ws *workerStats) {
+			defer wg.Done()
+			ws.run(ctx, client, cfg.addr+cfg.path, cfg.etag, tokens)
+		}(results[i])
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	cancel()
+	streamWG.Wait()
+
+	total := merge(results)
+	report(out, total, &streamStats, elapsed)
+
+	if n := total.requests(); n > 0 {
+		if rate := float64(total.errors) / float64(n); rate > cfg.maxErrorRate {
+			return fmt.Errorf("error rate %.2f%% exceeds %.2f%%", rate*100, cfg.maxErrorRate*100)
+		}
+	}
+	return nil
+}
+
+// workerStats accumulates results for one worker; merged after the run.
+type workerStats struct {
+	latencies []time.Duration
+	statuses  map[int]int
+	errors    int // transport errors and non-2xx/304 responses
+}
+
+// requests is the number of requests issued; status 0 counts those that got
+// no HTTP response.
+func (w *workerStats) requests() int {
+	n := 0
+	for _, c := range w.statuses {
+		n += c
+	}
+	return n
+}
+
+func (w *workerStats) run(ctx context.Context, client *http.Client, url string, useETag bool, tokens <-chan time.Time) {
+	etag := ""
+	for {
+		if tokens != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tokens:
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			w.errors++
+			w.statuses[0]++
+			return
+		}
+		if useETag && etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		start := time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				// Cut off by the end of the test, not a failure
+				return
+			}
+			w.errors++
+			w.statuses[0]++
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		w.latencies = append(w.latencies, time.Since(start))
+		w.statuses[resp.StatusCode]++
+
+		switch {
+		case resp.StatusCode == http.StatusNotModified:
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			etag = resp.Header.Get("ETag")
+		default:
+			w.errors++
+		}
+	}
+}
+
+func merge(all []*workerStats) *workerStats {
+	total := &workerStats{statuses: make(map[int]int)}
+	for _, w := range all {
+		total.latencies = append(total.latencies, w.latencies...)
+		for code, n := range w.statuses {
+			total.statuses[code] += n
+		}
+		total.errors += w.errors
+	}
+	slices.Sort(total.latencies)
+	return total
+}
+
+// streamStats aggregates SSE stream results across goroutines.
+type streamStats struct {
+	opened     atomic.Int64
+	failed     atomic.Int64
+	dropped    atomic.Int64 // closed by the server before the test ended
+	events     atomic.Int64
+	firstEvent atomic.Int64 // sum of time-to-first-event, in microseconds
+	firstCount atomic.Int64
+}
+
+func (s *streamStats) run(ctx context.Context, client *http.Client, url string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		s.failed.Add(1)
+		return
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.failed.Add(1)
+		}
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		s.failed.Add(1)
+		return
+	}
+	s.opened.Add(1)
+
+	first := true
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if !strings.HasPrefix(scanner.Text(), "data: ") {
+			continue
+		}
+		if first {
+			s.firstEvent.Add(time.Since(start).Microseconds())
+			s.firstCount.Add(1)
+			first = false
+		}
+		s.events.Add(1)
+	}
+	if ctx.Err() == nil {
+		s.dropped.Add(1)
+	}
+}
+
+func report(out io.Writer, total *workerStats, streams *streamStats, elapsed time.Duration) {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	defer tw.Flush()
+
+	n := total.requests()
+	fmt.Fprintf(tw, "\nrequests\t%d\n", n)
+	fmt.Fprintf(tw, "throughput\t%.1f req/s\n", float64(n)/elapsed.Seconds())
+	if n > 0 {
+		fmt.Fprintf(tw, "errors\t%d (%.2f%%)\n", total.errors, 100*float64(total.errors)/float64(n))
+	}
+
+	codes := make([]int, 0, len(total.statuses))
+	for code := range total.statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		label := fmt.Sprintf("status %d", code)
+		if code == 0 {
+			label = "transport errors"
+		}
+		fmt.Fprintf(tw, "%s\t%d\n", label, total.statuses[code])
+	}
+
+	if len(total.latencies) > 0 {
+		fmt.Fprintf(tw, "latency p50\t%s\n", quantile(total.latencies, 0.50))
+		fmt.Fprintf(tw, "latency p90\t%s\n", quantile(total.latencies, 0.90))
+		fmt.Fprintf(tw, "latency p99\t%s\n", quantile(total.latencies, 0.99))
+		fmt.Fprintf(tw, "latency max\t%s\n", total.latencies[len(total.latencies)-1])
+	}
+
+	if opened, failed := streams.opened.Load(), streams.failed.Load(); opened+failed > 0 {
+		fmt.Fprintf(tw, "streams opened\t%d\n", opened)
+		fmt.Fprintf(tw, "streams failed\t%d\n", failed)
+		fmt.Fprintf(tw, "streams dropped\t%d\n", streams.dropped.Load())
+		fmt.Fprintf(tw, "stream events\t%d\n", streams.events.Load())
+		if c := streams.firstCount.Load(); c > 0 {
+			avg := time.Duration(streams.firstEvent.Load()/c) * time.Microsecond
+			fmt.Fprintf(tw, "time to first event\t%s (avg)\n", avg)
+		}
+	}
+}
+
+// quantile returns the q-th quantile of sorted durations.
+func quantile(sorted []time.Duration, q float64) time.Duration {
+	idx := int(float64(len(sorted)-1) * q)
+	return sorted[idx].Round(time.Microsecond)
+}
+
+func envOrDefault(key, defaultVal string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return defaultVal
+}
@@ -0,0 +1,78 @@
+// Package gas is the quickest way to get a gas estimate from Go. It wraps
+// pkg/eth and pkg/estimator for the common cases:
+//
+//	est, err := gas.EstimateOnce(ctx, "https://eth.example.com")
+//	if err != nil { ... }
+//	fmt.Println(est.Standard.MaxFeePerGas, est.Standard.MaxPriorityFeePerGas)
+//
+// For a continuously updated estimate, NewService follows the chain over a
+// WebSocket subscription:
+//
+//	svc, err := gas.NewService(gas.Options{NodeHTTPURL: httpURL, NodeWSURL: wsURL})
+//	if err != nil { ... }
+//	if err := svc.Start(ctx); err != nil { ... }
+//	defer svc.Stop(context.Background())
+//	est, err := svc.Current(ctx)
+//
+// The types are aliases, so values pass freely to and from pkg/estimator
+// when finer control is needed.
+package gas
+
+import (
+	"context"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+	"github.com/branched-services/go-gas/pkg/eth"
+)
+
+type (
+	// Estimate is a gas estimate for the next block, with four tiers.
+	Estimate = estimator.GasEstimate
+
+	// Tier is one confidence level of an Estimate.
+	Tier = estimator.PriorityEstimate
+
+	// Service keeps an Estimate current in the background.
+	Service = estimator.Service
+
+	// Options configures a Service.
+	Options = estimator.Options
+
+	// Option tunes EstimateOnce.
+	Option = estimator.Option
+
+	// FeeCaps bounds published fees.
+	FeeCaps = estimator.FeeCaps
+
+	// Auth holds node credentials (see Options.NodeAuth).
+	Auth = eth.Auth
+)
+
+var (
+	// ErrNotReady is returned by Service.Current before the first estimate.
+	ErrNotReady = estimator.ErrNotReady
+
+	// ErrExpired is returned for estimates past their validity window.
+	ErrExpired = estimator.ErrExpired
+)
+
+// Commonly used options for EstimateOnce.
+var (
+	WithHistorySize    = estimator.WithHistorySize
+	WithMempoolSamples = estimator.WithMempoolSamples
+	WithStrategy       = estimator.WithStrategy
+	WithLogger         = estimator.WithLogger
+)
+
+// NewService builds a Service from opts. Nothing connects until Start.
+func NewService(opts Options) (*Service, error) {
+	return estimator.NewService(opts)
+}
+
+// EstimateOnce connects to the JSON-RPC endpoint rpcURL, computes a single
+// estimate from recent blocks and the node's mempool, and disconnects.
+func EstimateOnce(ctx context.Context, rpcURL string, opts ...Option) (*Estimate, error) {
+	client := eth.NewClient(rpcURL)
+	defer client.Close()
+	return estimator.EstimateOnce(ctx, client, opts...)
+}
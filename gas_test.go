@@ -0,0 +1,34 @@
+package gas_test
+
+import (
+	"context"
+	"testing"
+
+	gas "github.com/branched-services/go-gas"
+	"github.com/branched-services/go-gas/internal/testnode"
+)
+
+func TestEstimateOnce(t *testing.T) {
+	node := testnode.New(1)
+	defer node.Close()
+	for i := 0; i < 10; i++ {
+		node.Mine(10e9, 2e9, 2e9, 2e9)
+	}
+
+	est, err := gas.EstimateOnce(context.Background(), node.HTTPURL(), gas.WithHistorySize(10))
+	if err != nil {
+		t.Fatalf("EstimateOnce() error = %v", err)
+	}
+	if est.ChainID != 1 || est.BlockNumber != node.Head() {
+		t.Errorf("estimate chain %d block %d, want chain 1 block %d", est.ChainID, est.BlockNumber, node.Head())
+	}
+	if est.Standard.MaxPriorityFeePerGas.Uint64() != 2e9 {
+		t.Errorf("standard tip = %v, want 2 gwei", est.Standard.MaxPriorityFeePerGas)
+	}
+}
+
+func TestNewService_RequiresEndpoints(t *testing.T) {
+	if _, err := gas.NewService(gas.Options{}); err == nil {
+		t.Error("NewService() without endpoints succeeded")
+	}
+}
@@ -0,0 +1,104 @@
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+)
+
+// defaultAccuracyWindows are reported by /v1/gas/accuracy unless configured
+// with WithAccuracyWindows or overridden per request.
+var defaultAccuracyWindows = []time.Duration{time.Hour, 24 * time.Hour}
+
+// WithAccuracyWindows sets the windows /v1/gas/accuracy reports by default.
+func WithAccuracyWindows(windows []time.Duration) Option {
+	return func(s *Server) {
+		if len(windows) > 0 {
+			s.accuracyWindows = windows
+		}
+	}
+}
+
+// AccuracyResponse is the /v1/gas/accuracy response format.
+type AccuracyResponse struct {
+	Windows []AccuracyWindow `json:"windows"`
+}
+
+// AccuracyWindow is the inclusion rate of each tier over the blocks scored
+// within a look-back window.
+type AccuracyWindow struct {
+	Window string        `json:"window"`
+	Blocks int           `json:"blocks"`
+	Tiers  AccuracyTiers `json:"tiers"`
+}
+
+// AccuracyTiers holds per-tier accuracy.
+type AccuracyTiers struct {
+	Urgent   TierAccuracy `json:"urgent"`
+	Fast     TierAccuracy `json:"fast"`
+	Standard TierAccuracy `json:"standard"`
+	Slow     TierAccuracy `json:"slow"`
+}
+
+// TierAccuracy counts blocks whose cheapest included priority fee was at or
+// below the tier's recommendation.
+type TierAccuracy struct {
+	Included      int     `json:"included"`
+	InclusionRate float64 `json:"inclusion_rate"`
+}
+
+// handleAccuracy reports how often each tier would have been included.
+// Query parameter "windows" is a comma-separated list of durations
+// (e.g. "1h,24h"); default: the configured windows.
+func (s *Server) handleAccuracy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	reader, ok := s.provider.(estimator.AccuracyReader)
+	if !ok {
+		s.writeError(w, http.StatusNotImplemented, "accuracy not available")
+		return
+	}
+
+	windows := s.accuracyWindows
+	if v := r.URL.Query().Get("windows"); v != "" {
+		windows = nil
+		for _, part := range strings.Split(v, ",") {
+			d, err := time.ParseDuration(strings.TrimSpace(part))
+			if err != nil || d <= 0 {
+				s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid windows: %q", v))
+				return
+			}
+			windows = append(windows, d)
+		}
+	}
+
+	resp := AccuracyResponse{Windows: make([]AccuracyWindow, len(windows))}
+	for i, window := range windows {
+		acc := reader.Accuracy(window)
+		resp.Windows[i] = AccuracyWindow{
+			Window: window.String(),
+			Blocks: acc.Blocks,
+			Tiers: AccuracyTiers{
+				Urgent:   toTierAccuracy(acc.Urgent),
+				Fast:     toTierAccuracy(acc.Fast),
+				Standard: toTierAccuracy(acc.Standard),
+				Slow:     toTierAccuracy(acc.Slow),
+			},
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func toTierAccuracy(t estimator.TierAccuracy) TierAccuracy {
+	return TierAccuracy{Included: t.Hits, InclusionRate: t.Rate}
+}
@@ -0,0 +1,75 @@
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+)
+
+// WithAdmin enables the operational control endpoints POST /admin/pause,
+// /admin/resume, /admin/recalculate and /admin/resubscribe. Requests must
+// carry "Authorization: Bearer <token>"; an empty token leaves the
+// endpoints disabled.
+func WithAdmin(ctrl estimator.Controller, token string) Option {
+	return func(s *Server) {
+		if token == "" {
+			return
+		}
+		s.control = ctrl
+		s.adminToken = token
+	}
+}
+
+// AdminResponse is the response of the control endpoints: the estimator's
+// state after the action.
+type AdminResponse struct {
+	Paused bool `json:"paused"`
+}
+
+// handleAdmin returns a handler for a control action, which runs on
+// authorized POST requests.
+func (s *Server) handleAdmin(name string, action func(r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		if !s.authorizeBearer(w, r, s.adminToken) {
+			return
+		}
+
+		if err := action(r); err != nil {
+			s.logger.Warn("admin action failed", "action", name, "error", err)
+			if errors.Is(err, estimator.ErrNotRunning) {
+				// e.g. a warm standby follower, which mirrors the leader
+				s.writeProblem(w, http.StatusConflict, CodeFailedPrecondition, err.Error())
+				return
+			}
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.logger.Info("admin action", "action", name)
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(AdminResponse{Paused: s.control.Paused()})
+	}
+}
+
+// registerAdmin adds the control endpoints to mux.
+func (s *Server) registerAdmin(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/pause", s.handleAdmin("pause", func(r *http.Request) error {
+		s.control.Pause()
+		return nil
+	}))
+	mux.HandleFunc("/admin/resume", s.handleAdmin("resume", func(r *http.Request) error {
+		return s.control.Resume(r.Context())
+	}))
+	mux.HandleFunc("/admin/recalculate", s.handleAdmin("recalculate", func(r *http.Request) error {
+		return s.control.Recalculate(r.Context())
+	}))
+	mux.HandleFunc("/admin/resubscribe", s.handleAdmin("resubscribe", func(r *http.Request) error {
+		return s.control.Resubscribe(r.Context())
+	}))
+}
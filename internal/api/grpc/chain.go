@@ -0,0 +1,154 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+	"github.com/branched-services/go-gas/pkg/eth"
+)
+
+// StatusNode reports the node's sync progress and peers. A Node that also
+// implements it adds node health to GET /v1/chain/status. *eth.Client
+// implements it.
+type StatusNode interface {
+	Syncing(ctx context.Context) (eth.SyncStatus, error)
+	PeerCount(ctx context.Context) (uint64, error)
+}
+
+// WithChainStatus enables GET /v1/chain/status, which reports reader's
+// view of the chain head and, when the node given to WithNode is a
+// StatusNode, the node's health.
+func WithChainStatus(reader estimator.ChainStatusReader) Option {
+	return func(s *Server) {
+		s.chain = reader
+	}
+}
+
+// ChainStatusResponse is the /v1/chain/status response format. Healthy
+// is false when the head is halted, the subscription is down, estimates
+// are held back for a node re-sync or the node is unreachable or syncing.
+type ChainStatusResponse struct {
+	Healthy bool `json:"healthy"`
+
+	HeadBlock     uint64  `json:"head_block"`
+	HeadHash      string  `json:"head_hash,omitempty"`
+	HeadTimestamp string  `json:"head_timestamp,omitempty" format:"date-time"`
+	HeadSeen      string  `json:"head_seen,omitempty" format:"date-time"`
+	HeadAgeSecs   float64 `json:"head_age_seconds"`
+
+	// Halted is set when the head is older than halt_threshold_seconds
+	Halted        bool    `json:"halted"`
+	HaltThreshold float64 `json:"halt_threshold_seconds"`
+
+	Reorgs         uint64 `json:"reorgs"`
+	LastReorg      string `json:"last_reorg,omitempty" format:"date-time"`
+	LastReorgDepth uint64 `json:"last_reorg_depth,omitempty"`
+
+	// Divergences counts heads on which cross-check nodes disagreed with
+	// the primary node
+	Divergences uint64 `json:"divergences"`
+
+	// Resyncing is set while estimates are held back because the node
+	// re-syncs; Resyncs counts the re-syncs detected
+	Resyncing      bool   `json:"resyncing"`
+	ResyncingSince string `json:"resyncing_since,omitempty" format:"date-time"`
+	Resyncs        uint64 `json:"resyncs"`
+
+	// Subscription is "subscribed", "polling" (degraded mode after the
+	// WebSocket subscription was lost), "paused" or "stopped" (e.g. on a
+	// standby follower).
+	Subscription string `json:"subscription"`
+
+	// Node is omitted when the node cannot report its health
+	Node *NodeHealth `json:"node,omitempty"`
+}
+
+// NodeHealth is the node's answer to eth_syncing and net_peerCount. Error
+// is set when eth_syncing failed; Peers is omitted when the provider hides
+// the net namespace.
+type NodeHealth struct {
+	Reachable    bool    `json:"reachable"`
+	Syncing      bool    `json:"syncing"`
+	CurrentBlock uint64  `json:"current_block,omitempty"`
+	HighestBlock uint64  `json:"highest_block,omitempty"`
+	Peers        *uint64 `json:"peers,omitempty"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// handleChainStatus reports whether the service's view of the chain is
+// healthy: 200 if so, 503 with the same body otherwise.
+func (s *Server) handleChainStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	st := s.chain.ChainStatus()
+	resp := ChainStatusResponse{
+		HeadBlock:      st.HeadNumber,
+		HeadHash:       st.HeadHash,
+		HeadTimestamp:  formatTime(st.HeadTimestamp),
+		HeadSeen:       formatTime(st.HeadSeen),
+		HeadAgeSecs:    st.HeadAge.Seconds(),
+		Halted:         st.Halted,
+		HaltThreshold:  st.HaltThreshold.Seconds(),
+		Reorgs:         st.Reorgs,
+		LastReorg:      formatTime(st.LastReorg),
+		LastReorgDepth: st.LastReorgDepth,
+		Divergences:    st.Divergences,
+		Resyncing:      st.Resyncing,
+		ResyncingSince: formatTime(st.ResyncingSince),
+		Resyncs:        st.Resyncs,
+	}
+	switch {
+	case !st.Running:
+		resp.Subscription = "stopped"
+	case st.Paused:
+		resp.Subscription = "paused"
+	case st.Degraded:
+		resp.Subscription = "polling"
+	default:
+		resp.Subscription = "subscribed"
+	}
+	if node, ok := s.node.(StatusNode); ok {
+		resp.Node = s.nodeHealth(r.Context(), node)
+	}
+
+	// Standby followers serve the leader's estimates, so only a running
+	// estimator's head and subscription count against health
+	resp.Healthy = !st.Running || (!st.HeadSeen.IsZero() && !st.Halted && !st.Degraded && !st.Resyncing)
+	if resp.Node != nil && (!resp.Node.Reachable || resp.Node.Syncing) {
+		resp.Healthy = false
+	}
+
+	status := http.StatusOK
+	if !resp.Healthy {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// nodeHealth asks node for its sync progress and peer count.
+func (s *Server) nodeHealth(ctx context.Context, node StatusNode) *NodeHealth {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Node)
+	defer cancel()
+
+	sync, err := node.Syncing(ctx)
+	if err != nil {
+		return &NodeHealth{Error: err.Error()}
+	}
+	health := &NodeHealth{
+		Reachable:    true,
+		Syncing:      sync.Syncing,
+		CurrentBlock: sync.CurrentBlock,
+		HighestBlock: sync.HighestBlock,
+	}
+	if peers, err := node.PeerCount(ctx); err == nil {
+		health.Peers = &peers
+	}
+	return health
+}
@@ -0,0 +1,213 @@
+package grpc
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// compressMinSize is the smallest body worth compressing. Plain estimate
+// responses stay below it and are served uncompressed from the pre-rendered
+// body; distribution, history, forecast and OpenAPI responses exceed it.
+const compressMinSize = 1400
+
+// WithCompression enables or disables gzip/deflate compression of estimate,
+// history, forecast and OpenAPI responses for clients that accept it. Enabled by
+// default.
+func WithCompression(enabled bool) Option {
+	return func(s *Server) {
+		s.compress = enabled
+	}
+}
+
+// Compressors are reused across responses; each holds sizable buffers.
+var (
+	gzipWriters = sync.Pool{
+		New: func() any { return gzip.NewWriter(nil) },
+	}
+	zlibWriters = sync.Pool{
+		New: func() any { return zlib.NewWriter(nil) },
+	}
+)
+
+// compressed wraps h to compress responses of at least compressMinSize
+// bytes with the best encoding the client accepts ("deflate" being zlib, per
+// RFC 9110). Only 200 responses are compressed; their ETag is weakened, as
+// is that of a 304 answering the weakened tag, since the bytes differ from
+// the identity encoding.
+func (s *Server) compressed(h http.HandlerFunc) http.HandlerFunc {
+	if !s.compress {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			h(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.close()
+		h(cw, r)
+	}
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header,
+// preferring gzip; empty if neither is acceptable.
+func negotiateEncoding(header string) string {
+	var gzipOK, deflateOK, anyOK bool
+	gzipSet, deflateSet := false, false
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		accepted := true
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			v, err := strconv.ParseFloat(q, 64)
+			accepted = err == nil && v > 0
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "gzip", "x-gzip":
+			gzipOK, gzipSet = accepted, true
+		case "deflate":
+			deflateOK, deflateSet = accepted, true
+		case "*":
+			anyOK = accepted
+		}
+	}
+	switch {
+	case gzipOK || (!gzipSet && anyOK):
+		return "gzip"
+	case deflateOK || (!deflateSet && anyOK):
+		return "deflate"
+	}
+	return ""
+}
+
+// compressWriter buffers the start of a 200 response until it reaches
+// compressMinSize, then switches to compressing. Shorter bodies, other
+// statuses and event streams are written as is.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+
+	buf         []byte
+	enc         io.WriteCloser
+	passthrough bool // the response is written as is
+	deferred    bool // WriteHeader(200) was called; sent once the encoding is known
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.deferred || w.passthrough || w.enc != nil {
+		return
+	}
+	if status != http.StatusOK || streaming(w.Header()) {
+		if status == http.StatusNotModified {
+			weakenETag(w.Header())
+		}
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.deferred = true
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.deferred && !w.passthrough && w.enc == nil && streaming(w.Header()) {
+		w.WriteHeader(http.StatusOK)
+	}
+	switch {
+	case w.passthrough:
+		return w.ResponseWriter.Write(b)
+	case w.enc != nil:
+		return w.enc.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) < compressMinSize {
+		return len(b), nil
+	}
+
+	h := w.Header()
+	h.Set("Content-Encoding", w.encoding)
+	h.Del("Content-Length")
+	weakenETag(h)
+	w.ResponseWriter.WriteHeader(http.StatusOK)
+
+	if w.encoding == "gzip" {
+		gz := gzipWriters.Get().(*gzip.Writer)
+		gz.Reset(w.ResponseWriter)
+		w.enc = gz
+	} else {
+		zw := zlibWriters.Get().(*zlib.Writer)
+		zw.Reset(w.ResponseWriter)
+		w.enc = zw
+	}
+	buf := w.buf
+	w.buf = nil
+	if _, err := w.enc.Write(buf); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// close finishes the response: it flushes the compressor, or writes a
+// body too short to compress.
+func (w *compressWriter) close() {
+	switch {
+	case w.enc != nil:
+		w.enc.Close()
+		switch enc := w.enc.(type) {
+		case *gzip.Writer:
+			gzipWriters.Put(enc)
+		case *zlib.Writer:
+			zlibWriters.Put(enc)
+		}
+	case w.passthrough:
+	default:
+		if w.deferred {
+			w.ResponseWriter.WriteHeader(http.StatusOK)
+		}
+		if len(w.buf) > 0 {
+			w.ResponseWriter.Write(w.buf)
+		}
+	}
+}
+
+// Flush sends what was written so far: compressed if compression has
+// started, otherwise as is, giving up on compressing the response.
+func (w *compressWriter) Flush() {
+	switch {
+	case w.enc != nil:
+		if f, ok := w.enc.(interface{ Flush() error }); ok {
+			f.Flush()
+		}
+	case !w.passthrough:
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(http.StatusOK)
+		w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// streaming reports whether h starts an event stream, whose events must
+// reach the client as they are written.
+func streaming(h http.Header) bool {
+	return strings.HasPrefix(h.Get("Content-Type"), "text/event-stream")
+}
+
+// weakenETag marks a strong ETag in h as weak.
+func weakenETag(h http.Header) {
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		h.Set("ETag", "W/"+etag)
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,167 @@
+package grpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"x-gzip", "gzip"},
+		{"GZIP", "gzip"},
+		{"deflate", "deflate"},
+		{"deflate, gzip", "gzip"},
+		{"br", ""},
+		{"br, deflate", "deflate"},
+		{"*", "gzip"},
+		{"gzip;q=0.5", "gzip"},
+		{"gzip;q=0", ""},
+		{"gzip;q=0.0, deflate", "deflate"},
+		{"gzip;q=0, deflate;q=0", ""},
+		{"gzip;q=0, *", "deflate"},
+		{"*;q=0", ""},
+		{"*;q=0, deflate", "deflate"},
+		{"gzip;q=bogus", ""},
+		{"identity", ""},
+		{"identity;q=0", ""},
+		{"identity;q=0, gzip", "gzip"},
+		{"identity;q=0, *", "gzip"},
+		{" gzip ; q=1 ", "gzip"},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+// compressTest runs handler behind the compression wrapper for a client
+// sending acceptEncoding.
+func compressTest(t *testing.T, acceptEncoding string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	s := &Server{compress: true}
+	req := httptest.NewRequest(http.MethodGet, "/v1/gas/history", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	s.compressed(handler)(rec, req)
+	return rec
+}
+
+func gunzip(t *testing.T, body []byte) string {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestCompressed(t *testing.T) {
+	large := strings.Repeat("x", compressMinSize)
+	small := strings.Repeat("x", compressMinSize-1)
+
+	tests := []struct {
+		name     string
+		accept   string
+		status   int
+		body     string
+		encoding string
+		etag     string
+	}{
+		{name: "large body", accept: "gzip", status: http.StatusOK, body: large, encoding: "gzip", etag: `W/"1-1"`},
+		{name: "large body in writes", accept: "deflate", status: http.StatusOK, body: small + "xx", encoding: "deflate", etag: `W/"1-1"`},
+		{name: "below minimum size", accept: "gzip", status: http.StatusOK, body: small, etag: `"1-1"`},
+		{name: "not accepted", accept: "", status: http.StatusOK, body: large, etag: `"1-1"`},
+		{name: "refused", accept: "gzip;q=0", status: http.StatusOK, body: large, etag: `"1-1"`},
+		{name: "not modified", accept: "gzip", status: http.StatusNotModified, etag: `W/"1-1"`},
+		{name: "not modified uncompressed", accept: "", status: http.StatusNotModified, etag: `"1-1"`},
+		{name: "no content", accept: "gzip", status: http.StatusNoContent, etag: `"1-1"`},
+		{name: "error", accept: "gzip", status: http.StatusInternalServerError, body: large, etag: `"1-1"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := compressTest(t, tt.accept, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"1-1"`)
+				w.WriteHeader(tt.status)
+				// Written in two parts to exercise buffering
+				half := len(tt.body) / 2
+				io.WriteString(w, tt.body[:half])
+				io.WriteString(w, tt.body[half:])
+			})
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.encoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.encoding)
+			}
+			if got := rec.Header().Get("ETag"); got != tt.etag {
+				t.Errorf("ETag = %s, want %s", got, tt.etag)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", got)
+			}
+
+			body := rec.Body.String()
+			switch tt.encoding {
+			case "gzip":
+				body = gunzip(t, rec.Body.Bytes())
+			case "deflate":
+				if body == tt.body {
+					t.Error("deflate body not compressed")
+				}
+				return
+			}
+			if body != tt.body {
+				t.Errorf("body has %d bytes, want %d", len(body), len(tt.body))
+			}
+		})
+	}
+}
+
+func TestCompressed_Stream(t *testing.T) {
+	// Events reach the client as written, uncompressed
+	var flushed []string
+	rec := compressTest(t, "gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			io.WriteString(w, "data: {}\n\n")
+			http.NewResponseController(w).Flush()
+			flushed = append(flushed, w.(*compressWriter).ResponseWriter.(*httptest.ResponseRecorder).Body.String())
+		}
+	})
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q, want none", got)
+	}
+	for i, body := range flushed {
+		if want := strings.Repeat("data: {}\n\n", i+1); body != want {
+			t.Errorf("after event %d the client has %q, want %q", i+1, body, want)
+		}
+	}
+
+	// Flushing a short response sends it as is
+	rec = compressTest(t, "gzip", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "partial")
+		http.NewResponseController(w).Flush()
+		io.WriteString(w, strings.Repeat("x", compressMinSize))
+	})
+	if got := rec.Header().Get("Content-Encoding"); got != "" || !strings.HasPrefix(rec.Body.String(), "partial") || !rec.Flushed {
+		t.Errorf("flushed response: Content-Encoding %q, flushed %v, body starts %q", got, rec.Flushed, rec.Body.String()[:7])
+	}
+}
@@ -0,0 +1,135 @@
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+	"github.com/holiman/uint256"
+)
+
+// etherDecimals is the number of decimal places in one ether (or the
+// chain's native token) worth of wei.
+const etherDecimals = 18
+
+// CostResponse is the cost of a transaction using Gas gas at one tier:
+// Estimated assumes the predicted base fee, Max is the worst case at
+// max_fee_per_gas.
+type CostResponse struct {
+	ChainID     uint64 `json:"chain_id"`
+	BlockNumber uint64 `json:"block_number"`
+	Tier        string `json:"tier"`
+	Gas         uint64 `json:"gas"`
+
+	// Generation identifies the estimate the cost was computed from; send
+	// it as If-Generation-Match to price follow-up calls from the same one.
+	Generation string `json:"generation"`
+
+	MaxFeePerGas         string `json:"max_fee_per_gas"`
+	MaxPriorityFeePerGas string `json:"max_priority_fee_per_gas"`
+
+	Estimated CostAmount `json:"estimated"`
+	Max       CostAmount `json:"max"`
+
+	// CurrencySymbol is the native token Ether amounts are in, e.g. "ETH";
+	// empty for chains without known metadata.
+	CurrencySymbol string `json:"currency_symbol,omitempty"`
+
+	// NativeTokenUSD is the price used for USD amounts; set only when a
+	// price feed is configured and available.
+	NativeTokenUSD *float64 `json:"native_token_usd,omitempty"`
+}
+
+// CostAmount is one amount in wei, gwei and whole native tokens, and in
+// USD when a price feed is configured and available.
+type CostAmount struct {
+	Wei   string   `json:"wei"`
+	Gwei  string   `json:"gwei"`
+	Ether string   `json:"eth"`
+	USD   *float64 `json:"usd,omitempty"`
+}
+
+// handleCost returns the cost of a transaction at a tier. Query parameter
+// "gas" is the gas used (required) and "tier" one of urgent, fast, standard
+// or slow (default standard). Honors If-Generation-Match.
+func (s *Server) handleCost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	q := r.URL.Query()
+	gas, err := strconv.ParseUint(q.Get("gas"), 10, 64)
+	if err != nil || gas == 0 {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid gas: %q", q.Get("gas")))
+		return
+	}
+	tier := q.Get("tier")
+	if tier == "" {
+		tier = "standard"
+	}
+
+	est := s.requestedEstimate(w, r)
+	if est == nil {
+		return
+	}
+	p, ok := est.Tier(tier)
+	if !ok {
+		s.writeError(w, http.StatusBadRequest,
+			fmt.Sprintf("invalid tier: %q (one of %s)", tier, strings.Join(estimator.TierNames[:], ", ")))
+		return
+	}
+
+	quote := s.quote(r.Context())
+	cost := txCost(est.BaseFee, p, gas, quote)
+	resp := CostResponse{
+		ChainID:              est.ChainID,
+		BlockNumber:          est.BlockNumber,
+		Tier:                 tier,
+		Gas:                  gas,
+		Generation:           generationToken(est),
+		MaxFeePerGas:         p.MaxFeePerGas.Dec(),
+		MaxPriorityFeePerGas: p.MaxPriorityFeePerGas.Dec(),
+		Estimated:            costAmount(cost.EstimatedWei, cost.EstimatedUSD),
+		Max:                  costAmount(cost.MaxWei, cost.MaxUSD),
+		CurrencySymbol:       est.Network.CurrencySymbol,
+	}
+	if quote != nil {
+		resp.NativeTokenUSD = &quote.USD
+	}
+
+	w.Header().Set(generationHeader, resp.Generation)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// costAmount renders an amount of wei in every unit.
+func costAmount(wei string, usd *float64) CostAmount {
+	v := parseWei(wei)
+	return CostAmount{
+		Wei:   wei,
+		Gwei:  formatUnits(v, gweiDecimals),
+		Ether: formatUnits(v, etherDecimals),
+		USD:   usd,
+	}
+}
+
+// formatUnits renders wei exactly as a decimal number of units of
+// 10^decimals wei, without trailing zeros.
+func formatUnits(wei *uint256.Int, decimals int) string {
+	if wei == nil {
+		return ""
+	}
+	digits := wei.Dec()
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	whole, frac := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if frac == "" {
+		return whole
+	}
+	return whole + "." + frac
+}
@@ -0,0 +1,118 @@
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+)
+
+// DeadlineResponse is a single fee recommendation for inclusion within a
+// deadline, in blocks, with the given confidence. Percentile is the
+// per-block inclusion probability the priority fee was read at from recent
+// blocks' cheapest included tips.
+type DeadlineResponse struct {
+	ChainID     uint64 `json:"chain_id"`
+	BlockNumber uint64 `json:"block_number"`
+	Generation  string `json:"generation"`
+	BaseFee     string `json:"base_fee"`
+
+	// Deadline echoes the requested wait, when given as a duration
+	Deadline   string  `json:"deadline,omitempty"`
+	Blocks     int     `json:"blocks"`
+	Confidence float64 `json:"confidence"`
+	Percentile float64 `json:"percentile"`
+
+	MaxPriorityFeePerGas string `json:"max_priority_fee_per_gas"`
+	MaxFeePerGas         string `json:"max_fee_per_gas"`
+	GasPrice             string `json:"gas_price"`
+
+	ValidUntil string `json:"valid_until,omitempty" format:"date-time"`
+}
+
+// handleDeadline returns the fee for inclusion within a deadline, given as
+// query parameter "deadline" (a Go duration) or "blocks"; "confidence" is
+// the targeted probability of inclusion (default 0.9). Honors
+// If-Generation-Match.
+func (s *Server) handleDeadline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	q := r.URL.Query()
+	deadline, blocksParam := q.Get("deadline"), q.Get("blocks")
+	if (deadline == "") == (blocksParam == "") {
+		s.writeError(w, http.StatusBadRequest, "exactly one of deadline or blocks is required")
+		return
+	}
+	var (
+		wait   time.Duration
+		blocks int
+		err    error
+	)
+	if deadline != "" {
+		if wait, err = time.ParseDuration(deadline); err != nil || wait <= 0 {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid deadline: %q", deadline))
+			return
+		}
+	} else if blocks, err = strconv.Atoi(blocksParam); err != nil || blocks < 1 {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid blocks: %q", blocksParam))
+		return
+	}
+	confidence := estimator.DefaultDeadlineConfidence
+	if v := q.Get("confidence"); v != "" {
+		if confidence, err = strconv.ParseFloat(v, 64); err != nil || confidence <= 0 || confidence >= 1 {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid confidence: %q (must be between 0 and 1)", v))
+			return
+		}
+	}
+
+	est := s.requestedEstimate(w, r)
+	if est == nil {
+		return
+	}
+	if wait > 0 {
+		if blocks, err = est.BlocksWithin(wait); err != nil {
+			s.writeError(w, http.StatusBadRequest, "deadline: block time unknown for this chain; use blocks")
+			return
+		}
+	}
+	d, err := est.ForDeadline(blocks, confidence)
+	if errors.Is(err, estimator.ErrInclusionUnknown) {
+		s.writeError(w, http.StatusServiceUnavailable, "no recent blocks with fee-paying transactions")
+		return
+	}
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if enforcer, ok := s.provider.(estimator.FeeCapEnforcer); ok {
+		capped := &estimator.GasEstimate{Urgent: d.PriorityEstimate}
+		enforcer.EnforceCaps(capped)
+		d.PriorityEstimate = capped.Urgent
+	}
+
+	resp := DeadlineResponse{
+		ChainID:              est.ChainID,
+		BlockNumber:          est.BlockNumber,
+		Generation:           generationToken(est),
+		BaseFee:              est.BaseFee.Dec(),
+		Deadline:             deadline,
+		Blocks:               d.Blocks,
+		Confidence:           d.Confidence,
+		Percentile:           d.Percentile,
+		MaxPriorityFeePerGas: d.MaxPriorityFeePerGas.Dec(),
+		MaxFeePerGas:         d.MaxFeePerGas.Dec(),
+		GasPrice:             d.GasPrice(est.BaseFee).Dec(),
+		ValidUntil:           formatTime(est.ValidUntil),
+	}
+
+	w.Header().Set(generationHeader, resp.Generation)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,327 @@
+package grpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+	"github.com/holiman/uint256"
+)
+
+// WithDebug enables /debug/estimator, which dumps estimator internals.
+// Requests must carry "Authorization: Bearer <token>"; an empty token
+// leaves the endpoint disabled.
+func WithDebug(reader estimator.DebugReader, token string) Option {
+	return func(s *Server) {
+		if token == "" {
+			return
+		}
+		s.debug = reader
+		s.debugToken = token
+	}
+}
+
+// DebugResponse is the /debug/estimator response format.
+// It is an operator tool and not part of the versioned API.
+type DebugResponse struct {
+	ChainID            uint64              `json:"chain_id"`
+	Strategy           string              `json:"strategy"`
+	StrategyConfig     estimator.Strategy  `json:"strategy_config"`
+	Config             DebugConfigResponse `json:"config"`
+	HistoryCapacity    int                 `json:"history_capacity"`
+	History            []DebugBlock        `json:"history"`
+	Mempool            DebugMempool        `json:"mempool"`
+	Memory             DebugMemory         `json:"memory"`
+	DataSources        DebugDataSources    `json:"data_sources"`
+	OutliersRejected   uint64              `json:"mempool_outliers_rejected_total"`
+	FeeCapHits         uint64              `json:"fee_cap_hits_total"`
+	UpdateConflicts    uint64              `json:"update_conflicts_total"`
+	EstimatesRejected  uint64              `json:"estimates_rejected_total"`
+	Anomalies          map[string]uint64   `json:"anomalies_total,omitempty"`
+	NonceTracked       int                 `json:"nonce_senders_tracked"`
+	NonceExcluded      int                 `json:"nonce_gap_excluded"`
+	LastRecalc         string              `json:"last_recalc,omitempty"`
+	LastRecalcDuration string              `json:"last_recalc_duration"`
+	LastRecalcError    string              `json:"last_recalc_error,omitempty"`
+	Subscriptions      map[string]string   `json:"subscriptions"`
+	Paused             bool                `json:"paused,omitempty"`
+	Connection         *DebugConnection    `json:"websocket,omitempty"`
+
+	// Explanation is how the current estimate's tiers were derived; set
+	// only in explain mode (GAS_EXPLAIN).
+	Explanation *DebugExplanation `json:"explanation,omitempty"`
+
+	// StrategyOutputs are the ensemble members' estimates behind the
+	// current estimate; empty for single strategies.
+	StrategyOutputs []DebugStrategyOutput `json:"strategy_outputs,omitempty"`
+}
+
+// DebugStrategyOutput is one ensemble member's estimate or error.
+type DebugStrategyOutput struct {
+	Strategy string               `json:"strategy"`
+	Weight   float64              `json:"weight"`
+	Estimate *GasEstimateResponse `json:"estimate,omitempty"`
+	Error    string               `json:"error,omitempty"`
+}
+
+// DebugExplanation is how each tier of the current estimate was derived.
+type DebugExplanation struct {
+	PredictedBaseFee    string               `json:"predicted_base_fee"`
+	BaseFeeMultiplier   float64              `json:"base_fee_multiplier"`
+	HistoricalSamples   int                  `json:"historical_samples"`
+	MempoolSamples      int                  `json:"mempool_samples"`
+	PendingBlockSamples int                  `json:"pending_block_samples"`
+	Urgent              DebugTierExplanation `json:"urgent"`
+	Fast                DebugTierExplanation `json:"fast"`
+	Standard            DebugTierExplanation `json:"standard"`
+	Slow                DebugTierExplanation `json:"slow"`
+}
+
+// DebugTierExplanation is the derivation of one tier's priority fee, in wei.
+// Source percentiles are empty when the source had no samples; mempool is
+// before the pending block is blended in. SmoothingDelta is final minus
+// unsmoothed and may be negative.
+type DebugTierExplanation struct {
+	Percentile       float64 `json:"percentile"`
+	Historical       string  `json:"historical,omitempty"`
+	Mempool          string  `json:"mempool,omitempty"`
+	PendingBlock     string  `json:"pending_block,omitempty"`
+	HistoricalWeight float64 `json:"historical_weight"`
+	Default          bool    `json:"default,omitempty"`
+	Blended          string  `json:"blended"`
+	Clamped          string  `json:"clamped,omitempty"`
+	Unsmoothed       string  `json:"unsmoothed"`
+	SmoothingDelta   string  `json:"smoothing_delta"`
+	Final            string  `json:"final"`
+}
+
+// DebugMemory is the approximate memory held by the history and mempool
+// sample, and the data dropped to bound it.
+type DebugMemory struct {
+	HistoryFeeSamples  int    `json:"history_fee_samples"`
+	HistoryBytes       int    `json:"history_bytes"`
+	HistoryFeesEvicted uint64 `json:"history_fees_evicted_total"`
+	MempoolSamples     int    `json:"mempool_samples"`
+	MempoolBytes       int    `json:"mempool_bytes"`
+	MempoolEvicted     uint64 `json:"mempool_evicted_total"`
+}
+
+// DebugConnection counts WebSocket connections to the node and how they
+// ended. Normal closes are server closures with code 1000 or 1001.
+type DebugConnection struct {
+	Connects        uint64 `json:"connects"`
+	NormalCloses    uint64 `json:"normal_closes"`
+	AbnormalCloses  uint64 `json:"abnormal_closes"`
+	LastCloseCode   int    `json:"last_close_code,omitempty"`
+	LastCloseReason string `json:"last_close_reason,omitempty"`
+}
+
+// DebugDataSources is the data source plan chosen for the node and the
+// capabilities it was based on.
+type DebugDataSources struct {
+	Mempool             string `json:"mempool"`
+	History             string `json:"history"`
+	ClientVersion       string `json:"client_version,omitempty"`
+	TxPool              bool   `json:"txpool"`
+	FeeHistory          bool   `json:"fee_history"`
+	Erigon              bool   `json:"erigon"`
+	PendingSubscription bool   `json:"pending_subscription"`
+	DevChain            bool   `json:"dev_chain"`
+}
+
+// DebugConfigResponse is the estimator configuration in effect.
+type DebugConfigResponse struct {
+	HistorySize      int             `json:"history_size"`
+	MempoolSamples   int             `json:"mempool_samples"`
+	RecalcInterval   string          `json:"recalc_interval"`
+	RecalcOnBlock    bool            `json:"recalc_on_block"`
+	RecalcPendingTxs int             `json:"recalc_pending_txs"`
+	FullPendingTxs   bool            `json:"full_pending_txs"`
+	NonceFiltering   bool            `json:"nonce_filtering"`
+	SamplingPolicy   string          `json:"sampling_policy"`
+	SamplingWindow   string          `json:"sampling_window"`
+	Network          NetworkResponse `json:"network"`
+}
+
+// DebugBlock summarizes one block in the estimator's history.
+type DebugBlock struct {
+	Number       uint64  `json:"number"`
+	BaseFee      string  `json:"base_fee"`
+	GasUsed      uint64  `json:"gas_used"`
+	GasLimit     uint64  `json:"gas_limit"`
+	Utilization  float64 `json:"utilization"`
+	PriorityFees int     `json:"priority_fees"`
+}
+
+// DebugMempool summarizes the current mempool sample.
+type DebugMempool struct {
+	Samples int `json:"samples"`
+	EIP1559 int `json:"eip1559"`
+	Legacy  int `json:"legacy"`
+
+	// Includable transactions can pay the predicted base fee; only they
+	// inform estimates.
+	Includable      int     `json:"includable"`
+	IncludableRatio float64 `json:"includable_ratio"`
+
+	MinPriorityFee string `json:"min_priority_fee,omitempty"`
+	P50PriorityFee string `json:"p50_priority_fee,omitempty"`
+	MaxPriorityFee string `json:"max_priority_fee,omitempty"`
+}
+
+// handleDebug returns a snapshot of estimator internals.
+func (s *Server) handleDebug(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if !s.authorizeBearer(w, r, s.debugToken) {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(toDebugResponse(s.debug.DebugSnapshot()))
+}
+
+func toDebugResponse(snap estimator.DebugSnapshot) DebugResponse {
+	resp := DebugResponse{
+		ChainID:        snap.ChainID,
+		Strategy:       snap.Strategy.Name(),
+		StrategyConfig: snap.Strategy,
+		Config: DebugConfigResponse{
+			HistorySize:      snap.Config.HistorySize,
+			MempoolSamples:   snap.Config.MempoolSamples,
+			RecalcInterval:   snap.Config.RecalcInterval.String(),
+			RecalcOnBlock:    snap.Config.RecalcOnBlock,
+			RecalcPendingTxs: snap.Config.RecalcPendingTxs,
+			FullPendingTxs:   snap.Config.FullPendingTxs,
+			NonceFiltering:   snap.Config.NonceFiltering,
+			SamplingPolicy:   string(snap.Config.SamplingPolicy),
+			SamplingWindow:   snap.Config.SamplingWindow.String(),
+			Network: NetworkResponse{
+				Name:           snap.Config.Network.Name,
+				CurrencySymbol: snap.Config.Network.CurrencySymbol,
+				BlockTimeMs:    snap.Config.Network.BlockTime.Milliseconds(),
+			},
+		},
+		HistoryCapacity:    snap.HistoryCapacity,
+		History:            make([]DebugBlock, len(snap.History)),
+		LastRecalcDuration: snap.LastRecalcDuration.String(),
+		LastRecalcError:    snap.LastRecalcError,
+		OutliersRejected:   snap.MempoolOutliersRejected,
+		FeeCapHits:         snap.FeeCapHits,
+		UpdateConflicts:    snap.UpdateConflicts,
+		EstimatesRejected:  snap.EstimatesRejected,
+		NonceTracked:       snap.NonceTracked,
+		NonceExcluded:      snap.NonceExcluded,
+		Subscriptions:      snap.Subscriptions,
+		Paused:             snap.Paused,
+		DataSources: DebugDataSources{
+			Mempool:             string(snap.DataPlan.Mempool),
+			History:             string(snap.DataPlan.History),
+			ClientVersion:       snap.DataPlan.Capabilities.ClientVersion,
+			TxPool:              snap.DataPlan.Capabilities.TxPool,
+			FeeHistory:          snap.DataPlan.Capabilities.FeeHistory,
+			Erigon:              snap.DataPlan.Capabilities.Erigon,
+			PendingSubscription: snap.DataPlan.Capabilities.PendingSubscription,
+			DevChain:            snap.DataPlan.DevChain,
+		},
+		Memory: DebugMemory(snap.Memory),
+		Mempool: DebugMempool{
+			Samples:        snap.Mempool.Samples,
+			EIP1559:        snap.Mempool.EIP1559,
+			Legacy:         snap.Mempool.Legacy,
+			Includable:     snap.Mempool.Includable,
+			MinPriorityFee: decOrEmpty(snap.Mempool.MinPriorityFee),
+			P50PriorityFee: decOrEmpty(snap.Mempool.P50PriorityFee),
+			MaxPriorityFee: decOrEmpty(snap.Mempool.MaxPriorityFee),
+		},
+	}
+	if len(snap.Anomalies) > 0 {
+		resp.Anomalies = make(map[string]uint64, len(snap.Anomalies))
+		for kind, n := range snap.Anomalies {
+			resp.Anomalies[string(kind)] = n
+		}
+	}
+	if c := snap.Connection; c != nil {
+		resp.Connection = &DebugConnection{
+			Connects:       c.Connects,
+			NormalCloses:   c.NormalCloses,
+			AbnormalCloses: c.AbnormalCloses,
+		}
+		if c.LastClose != nil {
+			resp.Connection.LastCloseCode = c.LastClose.Code
+			resp.Connection.LastCloseReason = c.LastClose.Reason
+		}
+	}
+	if snap.Mempool.Samples > 0 {
+		resp.Mempool.IncludableRatio = float64(snap.Mempool.Includable) / float64(snap.Mempool.Samples)
+	}
+	if !snap.LastRecalc.IsZero() {
+		resp.LastRecalc = snap.LastRecalc.UTC().Format(time.RFC3339Nano)
+	}
+	for _, c := range snap.Components {
+		out := DebugStrategyOutput{Strategy: c.Strategy, Weight: c.Weight, Error: c.Err}
+		if c.Estimate != nil {
+			est := toResponse(c.Estimate)
+			out.Estimate = &est
+		}
+		resp.StrategyOutputs = append(resp.StrategyOutputs, out)
+	}
+	if x := snap.Explanation; x != nil {
+		resp.Explanation = &DebugExplanation{
+			PredictedBaseFee:    decOrEmpty(x.PredictedBaseFee),
+			BaseFeeMultiplier:   x.BaseFeeMultiplier,
+			HistoricalSamples:   x.HistoricalSamples,
+			MempoolSamples:      x.MempoolSamples,
+			PendingBlockSamples: x.PendingBlockSamples,
+			Urgent:              toDebugTierExplanation(x.Urgent),
+			Fast:                toDebugTierExplanation(x.Fast),
+			Standard:            toDebugTierExplanation(x.Standard),
+			Slow:                toDebugTierExplanation(x.Slow),
+		}
+	}
+	for i, b := range snap.History {
+		resp.History[i] = DebugBlock{
+			Number:       b.Number,
+			BaseFee:      decOrEmpty(b.BaseFee),
+			GasUsed:      b.GasUsed,
+			GasLimit:     b.GasLimit,
+			Utilization:  b.Utilization,
+			PriorityFees: b.PriorityFees,
+		}
+	}
+	return resp
+}
+
+func toDebugTierExplanation(t estimator.TierExplanation) DebugTierExplanation {
+	out := DebugTierExplanation{
+		Percentile:       t.Percentile,
+		Historical:       decOrEmpty(t.Historical),
+		Mempool:          decOrEmpty(t.Mempool),
+		PendingBlock:     decOrEmpty(t.PendingBlock),
+		HistoricalWeight: t.HistoricalWeight,
+		Default:          t.Default,
+		Blended:          decOrEmpty(t.Blended),
+		Clamped:          t.Clamped,
+		Unsmoothed:       decOrEmpty(t.Unsmoothed),
+		Final:            decOrEmpty(t.Final),
+	}
+	if t.Final != nil && t.Unsmoothed != nil {
+		if t.Final.Lt(t.Unsmoothed) {
+			out.SmoothingDelta = "-" + new(uint256.Int).Sub(t.Unsmoothed, t.Final).Dec()
+		} else {
+			out.SmoothingDelta = new(uint256.Int).Sub(t.Final, t.Unsmoothed).Dec()
+		}
+	}
+	return out
+}
+
+func decOrEmpty(v *uint256.Int) string {
+	if v == nil {
+		return ""
+	}
+	return v.Dec()
+}
@@ -0,0 +1,132 @@
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+)
+
+// Errors are RFC 7807 problem details. Besides the HTTP status, each
+// carries a canonical code named after the gRPC status code for the same
+// failure and, when retrying later can succeed, a retry hint.
+
+// problemContentType is the media type of error responses.
+const problemContentType = "application/problem+json"
+
+// estimateRetryAfter is the retry hint for requests that failed for want
+// of a current estimate; the next block brings one.
+const estimateRetryAfter = time.Second
+
+// Code is a canonical error code.
+type Code string
+
+const (
+	CodeInvalidArgument    Code = "INVALID_ARGUMENT"
+	CodeUnauthenticated    Code = "UNAUTHENTICATED"
+	CodePermissionDenied   Code = "PERMISSION_DENIED"
+	CodeNotFound           Code = "NOT_FOUND"
+	CodeAlreadyExists      Code = "ALREADY_EXISTS"
+	CodeFailedPrecondition Code = "FAILED_PRECONDITION"
+	CodeResourceExhausted  Code = "RESOURCE_EXHAUSTED"
+	CodeUnimplemented      Code = "UNIMPLEMENTED"
+	CodeInternal           Code = "INTERNAL"
+	CodeUnavailable        Code = "UNAVAILABLE"
+)
+
+// ErrorResponse is the body of every non-2xx JSON response: an RFC 7807
+// problem details object, served as application/problem+json. Error
+// repeats Detail for clients that predate problem details.
+type ErrorResponse struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+
+	// Code is the canonical error code, e.g. UNAVAILABLE before the first
+	// estimate, FAILED_PRECONDITION for an expired one and
+	// RESOURCE_EXHAUSTED over a tenant's quota
+	Code Code `json:"code"`
+
+	// RetryAfterSecs is set, like the Retry-After header, when the request
+	// may succeed if retried after that long
+	RetryAfterSecs int `json:"retry_after_seconds,omitempty"`
+
+	Error string `json:"error"`
+}
+
+// codeFor returns the code of errors answered with status, unless the
+// handler gives a more specific one.
+func codeFor(status int) Code {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return CodeInvalidArgument
+	case http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case http.StatusForbidden:
+		return CodePermissionDenied
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeAlreadyExists
+	case http.StatusPreconditionFailed:
+		return CodeFailedPrecondition
+	case http.StatusTooManyRequests:
+		return CodeResourceExhausted
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return CodeUnimplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return CodeUnavailable
+	default:
+		return CodeInternal
+	}
+}
+
+// writeError writes an error response with message as its detail and the
+// code for status.
+func (s *Server) writeError(w http.ResponseWriter, status int, message string) {
+	s.writeProblem(w, status, codeFor(status), message)
+}
+
+// writeProblem writes an error response with the given code. A Retry-After
+// header set by the caller becomes the body's retry hint.
+func (s *Server) writeProblem(w http.ResponseWriter, status int, code Code, message string) {
+	resp := ErrorResponse{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: message,
+		Code:   code,
+		Error:  message,
+	}
+	if secs, err := strconv.Atoi(w.Header().Get("Retry-After")); err == nil && secs > 0 {
+		resp.RetryAfterSecs = secs
+	}
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// writeEstimateError reports a failure to read the current estimate: 503
+// UNAVAILABLE before the first estimate and 503 FAILED_PRECONDITION once it
+// expired, both with a retry hint.
+func (s *Server) writeEstimateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, estimator.ErrNotReady):
+		setRetryAfter(w, estimateRetryAfter)
+		s.writeProblem(w, http.StatusServiceUnavailable, CodeUnavailable, "estimator not ready")
+	case errors.Is(err, estimator.ErrExpired):
+		setRetryAfter(w, estimateRetryAfter)
+		s.writeProblem(w, http.StatusServiceUnavailable, CodeFailedPrecondition, "estimate expired")
+	default:
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// setRetryAfter sets the Retry-After header to d, rounded up to a second.
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int((d+time.Second-1)/time.Second)))
+}
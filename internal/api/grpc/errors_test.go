@@ -0,0 +1,88 @@
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+)
+
+func TestCodeFor(t *testing.T) {
+	tests := []struct {
+		status int
+		want   Code
+	}{
+		{http.StatusBadRequest, CodeInvalidArgument},
+		{http.StatusUnprocessableEntity, CodeInvalidArgument},
+		{http.StatusUnauthorized, CodeUnauthenticated},
+		{http.StatusForbidden, CodePermissionDenied},
+		{http.StatusNotFound, CodeNotFound},
+		{http.StatusConflict, CodeAlreadyExists},
+		{http.StatusPreconditionFailed, CodeFailedPrecondition},
+		{http.StatusTooManyRequests, CodeResourceExhausted},
+		{http.StatusMethodNotAllowed, CodeUnimplemented},
+		{http.StatusNotImplemented, CodeUnimplemented},
+		{http.StatusBadGateway, CodeUnavailable},
+		{http.StatusServiceUnavailable, CodeUnavailable},
+		{http.StatusGatewayTimeout, CodeUnavailable},
+		{http.StatusInternalServerError, CodeInternal},
+		{http.StatusTeapot, CodeInternal},
+	}
+	for _, tt := range tests {
+		if got := codeFor(tt.status); got != tt.want {
+			t.Errorf("codeFor(%d) = %s, want %s", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestWriteEstimateError(t *testing.T) {
+	s, _ := newTestServer(t)
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		code       Code
+		retryAfter int
+	}{
+		{name: "not ready", err: estimator.ErrNotReady, status: http.StatusServiceUnavailable, code: CodeUnavailable, retryAfter: 1},
+		{name: "expired", err: estimator.ErrExpired, status: http.StatusServiceUnavailable, code: CodeFailedPrecondition, retryAfter: 1},
+		{name: "wrapped expired", err: fmt.Errorf("reading estimate: %w", estimator.ErrExpired), status: http.StatusServiceUnavailable, code: CodeFailedPrecondition, retryAfter: 1},
+		{name: "other", err: errors.New("boom"), status: http.StatusInternalServerError, code: CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.writeEstimateError(rec, tt.err)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != problemContentType {
+				t.Errorf("Content-Type = %q, want %q", ct, problemContentType)
+			}
+			var resp ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Code != tt.code || resp.Status != tt.status {
+				t.Errorf("code = %s, status = %d; want %s, %d", resp.Code, resp.Status, tt.code, tt.status)
+			}
+			if resp.RetryAfterSecs != tt.retryAfter {
+				t.Errorf("retry_after_seconds = %d, want %d", resp.RetryAfterSecs, tt.retryAfter)
+			}
+			header := rec.Header().Get("Retry-After")
+			if tt.retryAfter == 0 {
+				if header != "" {
+					t.Errorf("Retry-After = %q, want none", header)
+				}
+			} else if header != strconv.Itoa(resp.RetryAfterSecs) {
+				t.Errorf("Retry-After = %q, want %d like retry_after_seconds", header, resp.RetryAfterSecs)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package grpc
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+)
+
+// defaultV2ForecastHours is the forecast horizon of /v2/gas/estimate
+// unless a request sets "forecast_hours".
+const defaultV2ForecastHours = 6
+
+// GasEstimateV2Response is the /v2/gas/estimate response format. Unlike
+// v1 it keeps what to pay apart from how the estimate was produced, which
+// moves to Metadata, and includes the fee forecast for the coming hours.
+type GasEstimateV2Response struct {
+	BlockNumber uint64              `json:"block_number"`
+	BaseFee     string              `json:"base_fee"`
+	FeeCurrency FeeCurrencyResponse `json:"fee_currency"`
+	Estimates   EstimatesBundle     `json:"estimates"`
+
+	// BaseFeeGwei is BaseFee in gwei, set only when the request includes
+	// unit=gwei.
+	BaseFeeGwei string `json:"base_fee_gwei,omitempty"`
+
+	// GasAmount echoes the gas_amount the tiers were priced for, if any.
+	GasAmount uint64 `json:"gas_amount,omitempty"`
+
+	// NativeTokenUSD is the price used for USD costs; set only when
+	// gas_limit was requested and a price feed is configured.
+	NativeTokenUSD *float64 `json:"native_token_usd,omitempty"`
+
+	// Forecast predicts the base and standard priority fee for each of the
+	// coming forecast_hours hours; omitted when the service cannot forecast.
+	Forecast []ForecastWindow `json:"forecast,omitempty"`
+
+	// Distribution is set only when the request includes include=distribution.
+	Distribution *DistributionResponse `json:"distribution,omitempty"`
+
+	Metadata EstimateMetadata `json:"metadata"`
+
+	// Signature is set when the server signs estimates.
+	Signature *SignatureResponse `json:"signature,omitempty"`
+}
+
+// EstimateMetadata describes how and when an estimate was produced.
+type EstimateMetadata struct {
+	ChainID          uint64          `json:"chain_id"`
+	Network          NetworkResponse `json:"network"`
+	Strategy         string          `json:"strategy,omitempty"`
+	EstimatorVersion string          `json:"estimator_version"`
+
+	// Generation identifies this exact estimate for If-Generation-Match.
+	Generation string `json:"generation"`
+
+	// CalculatedAt is when the estimate was calculated, PublishedAt when it
+	// was published and ValidUntil when the next block is expected.
+	CalculatedAt string `json:"calculated_at" format:"date-time"`
+	PublishedAt  string `json:"published_at,omitempty" format:"date-time"`
+	ValidUntil   string `json:"valid_until,omitempty" format:"date-time"`
+
+	// EstimateAgeMs is how long ago the estimate was published and
+	// ChainLagSeconds how long ago its block was produced.
+	EstimateAgeMs   *int64   `json:"estimate_age_ms,omitempty"`
+	ChainLagSeconds *float64 `json:"chain_lag_seconds,omitempty"`
+
+	BaseFeeMultiplier float64             `json:"base_fee_multiplier,omitempty"`
+	Degraded          bool                `json:"degraded"`
+	Samples           SampleSizesResponse `json:"samples"`
+}
+
+// toV2 reshapes a v1 estimate response.
+func toV2(resp GasEstimateResponse) GasEstimateV2Response {
+	return GasEstimateV2Response{
+		BlockNumber:    resp.BlockNumber,
+		BaseFee:        resp.BaseFee,
+		FeeCurrency:    resp.FeeCurrency,
+		Estimates:      resp.Estimates,
+		BaseFeeGwei:    resp.BaseFeeGwei,
+		GasAmount:      resp.GasAmount,
+		NativeTokenUSD: resp.NativeTokenUSD,
+		Distribution:   resp.Distribution,
+		Metadata: EstimateMetadata{
+			ChainID:           resp.ChainID,
+			Network:           resp.Network,
+			Strategy:          resp.Strategy,
+			EstimatorVersion:  resp.EstimatorVersion,
+			Generation:        resp.Generation,
+			CalculatedAt:      resp.Timestamp,
+			PublishedAt:       resp.LastUpdate,
+			ValidUntil:        resp.ValidUntil,
+			EstimateAgeMs:     resp.EstimateAgeMs,
+			ChainLagSeconds:   resp.ChainLagSeconds,
+			BaseFeeMultiplier: resp.BaseFeeMultiplier,
+			Degraded:          resp.Degraded,
+			Samples:           resp.Samples,
+		},
+		Signature: resp.Signature,
+	}
+}
+
+// handleEstimateV2 returns the requested estimate in the v2 format. It
+// takes the same parameters as /v1/gas/estimate plus "forecast_hours"
+// (0-168, default 6; 0 omits the forecast).
+func (s *Server) handleEstimateV2(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	hours := defaultV2ForecastHours
+	if v := r.URL.Query().Get("forecast_hours"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > estimator.MaxForecastHours {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid forecast_hours: %q", v))
+			return
+		}
+		hours = n
+	}
+
+	est, _, ok := s.requestedSnapshot(w, r)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	setEstimateHeaders(w, est, now)
+	v1, etag, ok := s.estimateResponse(w, r, est, now)
+	if !ok {
+		return
+	}
+	resp := toV2(v1)
+	etag = strings.TrimSuffix(etag, `"`) + `-v2`
+
+	// The forecast moves on with the hour even while the estimate doesn't
+	if reader, ok := s.provider.(estimator.ForecastReader); ok && hours > 0 {
+		if f, err := reader.Forecast(hours); err == nil {
+			resp.Forecast = make([]ForecastWindow, len(f.Hours))
+			for i, h := range f.Hours {
+				resp.Forecast[i] = toForecastWindow(h)
+			}
+			etag = fmt.Sprintf("%s-f%d-%d", etag, hours, f.Hours[0].Start.Unix())
+		}
+	}
+	writeCacheable(w, r, etag+`"`, resp)
+}
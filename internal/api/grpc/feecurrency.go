@@ -0,0 +1,105 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+	"github.com/holiman/uint256"
+)
+
+// FeeCurrencyNode is a Node that quotes gas prices in the ERC-20 tokens a
+// chain accepts fees in, enabling fee_currency on /v1/gas/estimate.
+// *eth.Client implements it.
+type FeeCurrencyNode interface {
+	Node
+	GasPrice(ctx context.Context, feeCurrency string) (*uint256.Int, error)
+}
+
+// FeeCurrencyResponse is the token fee amounts are denominated in, in
+// units of 10^-Decimals tokens (wei for the native token). Address is empty
+// for the chain's native token.
+type FeeCurrencyResponse struct {
+	Symbol   string `json:"symbol,omitempty"`
+	Address  string `json:"address,omitempty"`
+	Decimals uint8  `json:"decimals"`
+}
+
+// feeRate is the price of a fee currency in wei of the native token at a
+// block: Token units are worth Native wei.
+type feeRate struct {
+	block         uint64
+	token, native *uint256.Int
+}
+
+// nativeFeeCurrency describes the native token of network.
+func nativeFeeCurrency(network estimator.Network) FeeCurrencyResponse {
+	return FeeCurrencyResponse{Symbol: network.CurrencySymbol, Decimals: etherDecimals}
+}
+
+func toFeeCurrency(c estimator.FeeCurrency) FeeCurrencyResponse {
+	return FeeCurrencyResponse{Symbol: c.Symbol, Address: c.Address, Decimals: c.Decimals}
+}
+
+func toFeeCurrencies(network estimator.Network) []FeeCurrencyResponse {
+	var out []FeeCurrencyResponse
+	for _, c := range network.FeeCurrencies {
+		out = append(out, toFeeCurrency(c))
+	}
+	return out
+}
+
+// inFeeCurrency returns a copy of est with the base fee and tier fees
+// converted to currency at the node's current rate, which is fetched once
+// per block. The copy is unsigned; signatures cover native amounts.
+func (s *Server) inFeeCurrency(ctx context.Context, est *estimator.GasEstimate, currency estimator.FeeCurrency) (*estimator.GasEstimate, error) {
+	node, ok := s.node.(FeeCurrencyNode)
+	if !ok {
+		return nil, errors.New("fee currency conversion not available")
+	}
+	key := strings.ToLower(currency.Address)
+
+	s.feeRatesMu.Lock()
+	rate, ok := s.feeRates[key]
+	s.feeRatesMu.Unlock()
+	if !ok || rate.block != est.BlockNumber {
+		ctx, cancel := context.WithTimeout(ctx, s.timeouts.Node)
+		defer cancel()
+		native, err := node.GasPrice(ctx, "")
+		if err != nil {
+			return nil, fmt.Errorf("native gas price: %w", err)
+		}
+		token, err := node.GasPrice(ctx, currency.Address)
+		if err != nil {
+			return nil, fmt.Errorf("%s gas price: %w", currency.Symbol, err)
+		}
+		if native.IsZero() {
+			return nil, errors.New("node quotes a zero gas price")
+		}
+		rate = feeRate{block: est.BlockNumber, token: token, native: native}
+		s.feeRatesMu.Lock()
+		if s.feeRates == nil {
+			s.feeRates = make(map[string]feeRate)
+		}
+		s.feeRates[key] = rate
+		s.feeRatesMu.Unlock()
+	}
+
+	convert := func(wei *uint256.Int) *uint256.Int {
+		if wei == nil {
+			return nil
+		}
+		v := new(uint256.Int).Mul(wei, rate.token)
+		return v.Div(v, rate.native)
+	}
+	out := est.Clone()
+	out.Signature = nil
+	out.BaseFee = convert(est.BaseFee)
+	for _, tier := range []*estimator.PriorityEstimate{&out.Urgent, &out.Fast, &out.Standard, &out.Slow} {
+		tier.MaxPriorityFeePerGas = convert(tier.MaxPriorityFeePerGas)
+		tier.MaxFeePerGas = convert(tier.MaxFeePerGas)
+	}
+	return out, nil
+}
@@ -0,0 +1,101 @@
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+)
+
+// defaultForecastHours is the forecast horizon unless a request sets "hours".
+const defaultForecastHours = 24
+
+// ForecastResponse is the /v1/gas/forecast response format.
+type ForecastResponse struct {
+	GeneratedAt string           `json:"generated_at" format:"date-time"`
+	Hours       []ForecastWindow `json:"hours"`
+	Cheapest    ForecastCheapest `json:"cheapest"`
+}
+
+// ForecastWindow is the predicted base fee and standard priority fee for
+// one hour. Samples counts the blocks behind the hour's seasonal level; 0
+// means no history covers that hour yet and the current level is repeated.
+type ForecastWindow struct {
+	Start       string `json:"start" format:"date-time"`
+	End         string `json:"end" format:"date-time"`
+	BaseFee     string `json:"base_fee"`
+	PriorityFee string `json:"priority_fee"`
+	TotalFee    string `json:"total_fee"`
+	Samples     int    `json:"samples"`
+}
+
+// ForecastCheapest recommends the hour with the lowest predicted total fee.
+// SavingsPercent compares it with the current hour.
+type ForecastCheapest struct {
+	Window         ForecastWindow `json:"window"`
+	SavingsPercent float64        `json:"savings_percent"`
+}
+
+// handleForecast predicts fee levels over the coming hours from seasonal
+// history. Query parameter "hours" sets the horizon (1-168, default 24).
+func (s *Server) handleForecast(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	reader, ok := s.provider.(estimator.ForecastReader)
+	if !ok {
+		s.writeError(w, http.StatusNotImplemented, "forecast not available")
+		return
+	}
+
+	hours := defaultForecastHours
+	if v := r.URL.Query().Get("hours"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > estimator.MaxForecastHours {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid hours: %q", v))
+			return
+		}
+		hours = n
+	}
+
+	f, err := reader.Forecast(hours)
+	if err != nil {
+		s.writeEstimateError(w, err)
+		return
+	}
+
+	resp := ForecastResponse{
+		GeneratedAt: f.Generated.UTC().Format(time.RFC3339),
+		Hours:       make([]ForecastWindow, len(f.Hours)),
+	}
+	for i, h := range f.Hours {
+		resp.Hours[i] = toForecastWindow(h)
+	}
+
+	cheapest := f.Hours[f.Cheapest]
+	resp.Cheapest.Window = resp.Hours[f.Cheapest]
+	if now := f.Hours[0].TotalFee(); !now.IsZero() {
+		saved := now.Float64() - cheapest.TotalFee().Float64()
+		resp.Cheapest.SavingsPercent = saved / now.Float64() * 100
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func toForecastWindow(h estimator.ForecastHour) ForecastWindow {
+	return ForecastWindow{
+		Start:       h.Start.UTC().Format(time.RFC3339),
+		End:         h.Start.Add(time.Hour).UTC().Format(time.RFC3339),
+		BaseFee:     h.BaseFee.Dec(),
+		PriorityFee: h.PriorityFee.Dec(),
+		TotalFee:    h.TotalFee().Dec(),
+		Samples:     h.Samples,
+	}
+}
@@ -0,0 +1,79 @@
+package grpc
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+)
+
+const (
+	// generationHeader carries the generation token of the estimate a
+	// response was computed from.
+	generationHeader = "X-Estimate-Generation"
+
+	// ifGenerationMatchHeader pins a request to the estimate with the given
+	// generation token, so that several calls see the same snapshot.
+	ifGenerationMatchHeader = "If-Generation-Match"
+)
+
+// generationToken identifies one published estimate. Clients must treat it
+// as opaque.
+func generationToken(est *estimator.GasEstimate) string {
+	return fmt.Sprintf("%d-%d", est.BlockNumber, est.Version)
+}
+
+// parseGenerationToken is the inverse of generationToken.
+func parseGenerationToken(token string) (number, version uint64, err error) {
+	block, ver, ok := strings.Cut(strings.Trim(strings.TrimSpace(token), `"`), "-")
+	if ok {
+		if number, err = strconv.ParseUint(block, 10, 64); err == nil {
+			version, err = strconv.ParseUint(ver, 10, 64)
+		}
+	}
+	if !ok || err != nil {
+		return 0, 0, fmt.Errorf("invalid %s: %q", ifGenerationMatchHeader, token)
+	}
+	return number, version, nil
+}
+
+// requestedEstimate returns the estimate pinned by the request's
+// If-Generation-Match header, or the current estimate if there is none.
+// On failure it writes the error response and returns nil.
+func (s *Server) requestedEstimate(w http.ResponseWriter, r *http.Request) *estimator.GasEstimate {
+	if token := r.Header.Get(ifGenerationMatchHeader); token != "" {
+		return s.atGeneration(w, token)
+	}
+
+	est, _, err := s.current(r.Context())
+	if err != nil {
+		s.writeEstimateError(w, err)
+		return nil
+	}
+	return est
+}
+
+// atGeneration returns the estimate with generation token token. It
+// responds 412 Precondition Failed once that estimate is no longer
+// retained, so the client can start over from a fresh snapshot. On failure
+// it writes the error response and returns nil.
+func (s *Server) atGeneration(w http.ResponseWriter, token string) *estimator.GasEstimate {
+	number, version, err := parseGenerationToken(token)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return nil
+	}
+	versions, ok := s.provider.(estimator.VersionReader)
+	if !ok {
+		s.writeError(w, http.StatusNotImplemented, "estimate generations not available")
+		return nil
+	}
+	est, ok := versions.AtVersion(number, version)
+	if !ok {
+		s.writeError(w, http.StatusPreconditionFailed, fmt.Sprintf("estimate generation %s no longer available", token))
+		return nil
+	}
+	return est
+}
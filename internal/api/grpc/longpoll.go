@@ -0,0 +1,105 @@
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+)
+
+const (
+	// defaultLongPollWait and maxLongPollWait bound how long
+	// /v1/gas/estimate/next holds a request open.
+	defaultLongPollWait = 30 * time.Second
+	maxLongPollWait     = 60 * time.Second
+
+	// longPollWriteSlack is added to the wait for the response write deadline.
+	longPollWriteSlack = 5 * time.Second
+)
+
+// handleNext long-polls for an estimate newer than the since_block query
+// parameter: it responds as soon as one is published, or with 204 No
+// Content once the "timeout" parameter (a Go duration, default 30s, at
+// most 60s) passes without one. For clients that can't hold a stream open.
+func (s *Server) handleNext(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	notifier, ok := s.provider.(estimator.ChangeNotifier)
+	if !ok {
+		s.writeError(w, http.StatusNotImplemented, "change notifications not available")
+		return
+	}
+
+	q := r.URL.Query()
+	since, err := strconv.ParseUint(q.Get("since_block"), 10, 64)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid since_block: %q", q.Get("since_block")))
+		return
+	}
+	wait := defaultLongPollWait
+	if v := q.Get("timeout"); v != "" {
+		wait, err = time.ParseDuration(v)
+		if err != nil || wait <= 0 || wait > maxLongPollWait {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid timeout: %q (at most %s)", v, maxLongPollWait))
+			return
+		}
+	}
+
+	// The server's write timeout is shorter than a long poll
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + longPollWriteSlack))
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		changed := notifier.Changed()
+		est, _, err := s.current(r.Context())
+		switch {
+		case err == nil && est.BlockNumber > since:
+			s.writeNext(w, est)
+			return
+		case err != nil && !errors.Is(err, estimator.ErrNotReady) && !errors.Is(err, estimator.ErrExpired):
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case <-s.draining:
+			s.writeError(w, http.StatusServiceUnavailable, "server shutting down")
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeNext writes est as a /v1/gas/estimate response.
+func (s *Server) writeNext(w http.ResponseWriter, est *estimator.GasEstimate) {
+	resp := toResponse(est)
+	now := time.Now()
+	if !est.UpdatedAt.IsZero() {
+		age := now.Sub(est.UpdatedAt).Milliseconds()
+		resp.EstimateAgeMs = &age
+	}
+	if !est.BlockTimestamp.IsZero() {
+		lag := est.ChainLag(now).Seconds()
+		resp.ChainLagSeconds = &lag
+	}
+
+	w.Header().Set("ETag", estimateETag(est))
+	w.Header().Set(generationHeader, resp.Generation)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
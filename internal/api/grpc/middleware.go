@@ -0,0 +1,158 @@
+package grpc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/branched-services/go-gas/internal/observability"
+)
+
+// requestIDHeader carries the request ID in both directions.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds client-supplied request IDs so they can't bloat logs.
+const maxRequestIDLen = 128
+
+// withMiddleware wraps the handler with common middleware.
+//
+// Every request gets a request ID, taken from X-Request-ID when the client
+// supplies a usable one and generated otherwise. The ID is stored in the
+// request context under observability.RequestIDKey, echoed in the response,
+// and attached to the access log emitted when the request completes, at
+// info level unless set with WithAccessLogLevel.
+//
+// When tenants are configured (see WithTenants), metered endpoints also
+// require an API key within quota; the tenant is added to the access log.
+func (s *Server) withMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		reqID := r.Header.Get(requestIDHeader)
+		if !validRequestID(reqID) {
+			reqID = newRequestID()
+		}
+		r = r.WithContext(context.WithValue(r.Context(), observability.RequestIDKey, reqID))
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		w = rec
+
+		// Set common headers
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(requestIDHeader, reqID)
+
+		// CORS for development
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, "+apiKeyHeader+", "+ifGenerationMatchHeader)
+		w.Header().Set("Access-Control-Expose-Headers", requestIDHeader+", ETag, Retry-After, "+generationHeader+", "+apiVersionHeader+", Deprecation, Sunset, Link")
+
+		var tenantID string
+		switch {
+		case r.Method == "OPTIONS":
+			w.WriteHeader(http.StatusOK)
+		case s.tenants != nil && metered(r.URL.Path):
+			var ok bool
+			if tenantID, ok = s.authorizeTenant(w, r); ok {
+				next.ServeHTTP(w, r)
+			}
+		default:
+			next.ServeHTTP(w, r)
+		}
+
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"duration_us", time.Since(start).Microseconds(),
+			"remote_addr", r.RemoteAddr,
+			"user_agent", r.UserAgent(),
+		}
+		if tenantID != "" {
+			attrs = append(attrs, "tenant", tenantID)
+		}
+		observability.WithContext(r.Context(), s.logger).Log(r.Context(), s.accessLogLevel, "request completed", attrs...)
+	})
+}
+
+// WithAccessLogLevel sets the level of the access log line written for
+// every request. Default info.
+func WithAccessLogLevel(level slog.Level) Option {
+	return func(s *Server) {
+		s.accessLogLevel = level
+	}
+}
+
+// authorizeBearer checks for "Authorization: Bearer <token>", writing a 401
+// if it is missing or wrong. Authorized responses are marked uncacheable.
+func (s *Server) authorizeBearer(w http.ResponseWriter, r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		s.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return false
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	return true
+}
+
+// validRequestID accepts non-empty IDs of printable ASCII within the length limit.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random 128-bit hex identifier.
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// responseRecorder captures the status code and body size for access logs.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher so streaming handlers keep working.
+func (r *responseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
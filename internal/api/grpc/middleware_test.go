@@ -0,0 +1,114 @@
+package grpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/branched-services/go-gas/internal/observability"
+)
+
+func TestMiddleware_RequestID(t *testing.T) {
+	generated := regexp.MustCompile(`^[0-9a-f]{32}$`)
+	tests := []struct {
+		name   string
+		header string
+		want   string // empty: a generated ID
+	}{
+		{name: "none", header: ""},
+		{name: "client ID", header: "req-42.abc_DEF", want: "req-42.abc_DEF"},
+		{name: "longest client ID", header: strings.Repeat("a", maxRequestIDLen), want: strings.Repeat("a", maxRequestIDLen)},
+		{name: "too long", header: strings.Repeat("a", maxRequestIDLen+1)},
+		{name: "space", header: "two words"},
+		{name: "control character", header: "id\x01"},
+		{name: "non-ASCII", header: "idé"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			var ctxID any
+			h := s.withMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxID = r.Context().Value(observability.RequestIDKey)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/gas/estimate", nil)
+			if tt.header != "" {
+				req.Header.Set(requestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			got := rec.Header().Get(requestIDHeader)
+			if tt.want != "" && got != tt.want {
+				t.Errorf("X-Request-ID = %q, want the client's %q", got, tt.want)
+			}
+			if tt.want == "" && !generated.MatchString(got) {
+				t.Errorf("X-Request-ID = %q, want a generated 128-bit hex ID", got)
+			}
+			if ctxID != got {
+				t.Errorf("request context ID = %v, want %q", ctxID, got)
+			}
+		})
+	}
+
+	// Generated IDs differ per request
+	s, _ := newTestServer(t)
+	a := serve(s, http.MethodGet, "/v1/gas/estimate", "", nil).Header().Get(requestIDHeader)
+	b := serve(s, http.MethodGet, "/v1/gas/estimate", "", nil).Header().Get(requestIDHeader)
+	if a == b {
+		t.Errorf("two requests got the same ID %q", a)
+	}
+}
+
+func TestMiddleware_AccessLog(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want bool
+	}{
+		{name: "default info", want: true},
+		{name: "debug", opts: []Option{WithAccessLogLevel(slog.LevelDebug)}, want: false},
+		{name: "warn", opts: []Option{WithAccessLogLevel(slog.LevelWarn)}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t, tt.opts...)
+			var buf bytes.Buffer
+			s.logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+			rec := serve(s, http.MethodGet, "/v1/gas/history?blocks=x", "", map[string]string{requestIDHeader: "req-1"})
+			if !tt.want {
+				if buf.Len() != 0 {
+					t.Errorf("access log written below the logger's level: %s", buf.String())
+				}
+				return
+			}
+
+			var entry map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("access log %q: %v", buf.String(), err)
+			}
+			want := map[string]any{
+				"msg":        "request completed",
+				"method":     "GET",
+				"path":       "/v1/gas/history",
+				"status":     float64(rec.Code),
+				"bytes":      float64(rec.Body.Len()),
+				"request_id": "req-1",
+			}
+			for k, v := range want {
+				if entry[k] != v {
+					t.Errorf("access log %s = %v, want %v", k, entry[k], v)
+				}
+			}
+			if _, ok := entry["duration_us"]; !ok {
+				t.Error("access log lacks duration_us")
+			}
+		})
+	}
+}
@@ -0,0 +1,567 @@
+package grpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+)
+
+// The OpenAPI document is generated from the response types in this package
+// by reflection, so the spec cannot drift from what handlers actually encode.
+// Only the operations themselves (paths, parameters, status codes) are
+// described by hand below.
+
+// handleOpenAPI serves the OpenAPI 3 description of this API.
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(s.openAPI)
+}
+
+// handleDocs serves a Swagger UI page for the OpenAPI document.
+// The UI assets are loaded from a public CDN.
+func (s *Server) handleDocs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(swaggerUIPage))
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Gas Estimator API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/v1/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// OpenAPISpec returns the OpenAPI 3 document for the API as JSON.
+func OpenAPISpec() []byte {
+	// The document only contains maps, slices and strings; encoding can't fail
+	b, _ := json.MarshalIndent(openAPIDocument(), "", "  ")
+	return b
+}
+
+// openAPIDocument builds the OpenAPI document as a generic JSON value.
+func openAPIDocument() map[string]any {
+	g := newSchemaGen("#/components/schemas/")
+
+	errorResponse := func(desc string) map[string]any {
+		return map[string]any{
+			"description": desc,
+			"content": map[string]any{
+				problemContentType: map[string]any{"schema": g.schema(reflect.TypeOf(ErrorResponse{}))},
+			},
+		}
+	}
+	query := func(name, typ, desc string) map[string]any {
+		return map[string]any{
+			"name":        name,
+			"in":          "query",
+			"description": desc,
+			"schema":      map[string]any{"type": typ},
+		}
+	}
+
+	estimate := g.schema(reflect.TypeOf(GasEstimateResponse{}))
+	estimateV2 := g.schema(reflect.TypeOf(GasEstimateV2Response{}))
+	streamUpdate := g.schema(reflect.TypeOf(StreamUpdate{}))
+	history := g.schema(reflect.TypeOf(GasHistoryResponse{}))
+	accuracy := g.schema(reflect.TypeOf(AccuracyResponse{}))
+	forecast := g.schema(reflect.TypeOf(ForecastResponse{}))
+	cost := g.schema(reflect.TypeOf(CostResponse{}))
+	deadline := g.schema(reflect.TypeOf(DeadlineResponse{}))
+	suggestReq := g.schema(reflect.TypeOf(SuggestRequest{}))
+	suggestResp := g.schema(reflect.TypeOf(SuggestResponse{}))
+	chainStatus := g.schema(reflect.TypeOf(ChainStatusResponse{}))
+	strategy := g.schema(reflect.TypeOf(StrategyResponse{}))
+	strategyEvaluation := g.schema(reflect.TypeOf(StrategyEvaluationResponse{}))
+	webhookReq := g.schema(reflect.TypeOf(WebhookRequest{}))
+	webhookResp := g.schema(reflect.TypeOf(WebhookResponse{}))
+	webhookList := g.schema(reflect.TypeOf(WebhookListResponse{}))
+	webhookID := map[string]any{
+		"name":     "id",
+		"in":       "path",
+		"required": true,
+		"schema":   map[string]any{"type": "string"},
+	}
+
+	ifGenerationMatch := map[string]any{
+		"name":        "If-Generation-Match",
+		"in":          "header",
+		"description": "Generation token of a previously fetched estimate; the response is computed from that exact estimate instead of the current one.",
+		"schema":      map[string]any{"type": "string"},
+	}
+	generationFailed := errorResponse("The estimate named by If-Generation-Match is no longer retained; fetch a fresh one and start over.")
+
+	unit := query("unit", "string", "\"gwei\" adds *_gwei decimal strings next to the exact wei values.")
+	round := query("round", "string", "With unit=gwei, round gwei values to this precision, e.g. \"0.1\". Default exact.")
+
+	estimateParams := []any{
+		query("block", "integer", "Return the final estimate published for this recent block instead of the current one."),
+		query("gas_amount", "integer", "Price tiers for a transaction using this much gas, accounting for the pending demand it must outbid to fit in a block."),
+		query("gas_limit", "integer", "Include per-tier transaction costs for this gas limit."),
+		query("include", "string", "Comma-separated optional sections; \"distribution\" adds the raw percentile curves."),
+		query("fee_currency", "string", "Symbol or address of one of network.fee_currencies to convert the base fee, tiers and costs to, at the node's current rate. Distribution curves stay in wei. Default the native token."),
+		unit,
+		round,
+		map[string]any{
+			"name":        "If-None-Match",
+			"in":          "header",
+			"description": "ETag of a previously fetched estimate.",
+			"schema":      map[string]any{"type": "string"},
+		},
+		ifGenerationMatch,
+	}
+
+	paths := map[string]any{
+		"/v1/gas/estimate": map[string]any{
+			"get": map[string]any{
+				"operationId": "getEstimate",
+				"summary":     "Current gas estimate",
+				"parameters":  estimateParams,
+				"responses": map[string]any{
+					"200": jsonResponse("The latest estimate.", estimate),
+					"304": map[string]any{"description": "The estimate has not changed since the given ETag."},
+					"400": errorResponse("Invalid query parameter."),
+					"404": errorResponse("The requested block is older than the retained estimates or was never priced."),
+					"412": generationFailed,
+					"503": errorResponse("No estimate has been computed yet, or the server refuses estimates past valid_until and the current one is."),
+				},
+			},
+		},
+		"/v2/gas/estimate": map[string]any{
+			"get": map[string]any{
+				"operationId": "getEstimateV2",
+				"summary":     "Current gas estimate with forecast and metadata",
+				"description": "The estimate of /v1/gas/estimate, with the fields describing how it was produced grouped under metadata and the fee forecast for the coming hours.",
+				"parameters": append([]any{
+					query("forecast_hours", "integer", "Hours of fee forecast to include, 0-168; 0 omits it. Default 6."),
+				}, estimateParams...),
+				"responses": map[string]any{
+					"200": jsonResponse("The latest estimate.", estimateV2),
+					"304": map[string]any{"description": "The estimate has not changed since the given ETag."},
+					"400": errorResponse("Invalid query parameter."),
+					"404": errorResponse("The requested block is older than the retained estimates or was never priced."),
+					"412": generationFailed,
+					"503": errorResponse("No estimate has been computed yet, or the server refuses estimates past valid_until and the current one is."),
+				},
+			},
+		},
+		"/v1/gas/estimate/stream": map[string]any{
+			"get": map[string]any{
+				"operationId": "streamEstimates",
+				"summary":     "Server-sent events of estimate updates",
+				"description": "Each \"data:\" line carries a StreamUpdate. A final \"shutdown\" event is sent before the server closes the stream.",
+				"parameters": []any{
+					query("min_change_pct", "number", "Only send events when a tier's priority fee moves by at least this percentage, a finite non-negative number (resolution 0.000001). Without min_base_fee_delta, base fee changes alone send no event."),
+					query("min_base_fee_delta", "string", "Only send events when the base fee moves by at least this many wei. Without min_change_pct, priority fee changes alone send no event."),
+				},
+				"responses": map[string]any{
+					"200": map[string]any{
+						"description": "An event stream of estimates.",
+						"content": map[string]any{
+							"text/event-stream": map[string]any{"schema": streamUpdate},
+						},
+					},
+					"400": errorResponse("Invalid query parameter."),
+					"503": errorResponse("The server is shutting down."),
+				},
+			},
+		},
+		"/v1/gas/estimate/next": map[string]any{
+			"get": map[string]any{
+				"operationId": "waitForEstimate",
+				"summary":     "Long-poll for an estimate newer than a block",
+				"description": "Responds as soon as an estimate for a block after since_block is published (immediately if one already is), or with 204 when the timeout passes first. Loop with the returned block_number for near-push freshness without a stream.",
+				"parameters": []any{
+					map[string]any{
+						"name":        "since_block",
+						"in":          "query",
+						"required":    true,
+						"description": "Block number of the last estimate the client has.",
+						"schema":      map[string]any{"type": "integer"},
+					},
+					query("timeout", "string", "How long to wait, as a Go duration up to \"60s\". Default 30s."),
+				},
+				"responses": map[string]any{
+					"200": jsonResponse("The newer estimate.", estimate),
+					"204": map[string]any{"description": "No newer estimate was published before the timeout."},
+					"400": errorResponse("Invalid query parameter."),
+					"501": errorResponse("The estimate provider does not signal updates."),
+					"503": errorResponse("The server is shutting down."),
+				},
+			},
+		},
+		"/v1/gas/history": map[string]any{
+			"get": map[string]any{
+				"operationId": "getHistory",
+				"summary":     "Final estimate of each recent block",
+				"parameters": []any{
+					query("since", "string", "Look-back window as a Go duration, e.g. \"1h\". Default 1h."),
+					unit,
+					round,
+				},
+				"responses": map[string]any{
+					"200": jsonResponse("Recent estimates, oldest first.", history),
+					"400": errorResponse("Invalid query parameter."),
+					"501": errorResponse("The estimate provider does not keep history."),
+				},
+			},
+		},
+		"/v1/gas/accuracy": map[string]any{
+			"get": map[string]any{
+				"operationId": "getAccuracy",
+				"summary":     "How often each tier would have been included",
+				"description": "Each new block is scored against the estimate served when it arrived. A tier counts as included when its max_priority_fee_per_gas is at least the smallest nonzero priority fee in the block.",
+				"parameters": []any{
+					query("windows", "string", "Comma-separated look-back windows as Go durations, e.g. \"1h,24h\". Defaults to the configured windows."),
+				},
+				"responses": map[string]any{
+					"200": jsonResponse("Per-tier inclusion rates for each window.", accuracy),
+					"400": errorResponse("Invalid query parameter."),
+					"501": errorResponse("The estimate provider does not track accuracy."),
+				},
+			},
+		},
+		"/v1/gas/forecast": map[string]any{
+			"get": map[string]any{
+				"operationId": "getForecast",
+				"summary":     "Hourly fee forecast and the cheapest upcoming hour",
+				"description": "Predictions combine the current estimate with the usual fee level of each hour of the week (UTC), learned from the final estimate of every block since the service started. The current deviation from the usual level fades with a 2h half-life. Hours not yet covered by history repeat the current level and report zero samples.",
+				"parameters": []any{
+					query("hours", "integer", "Forecast horizon in hours, starting with the current hour (1-168). Default 24."),
+				},
+				"responses": map[string]any{
+					"200": jsonResponse("Hourly predictions and the cheapest hour.", forecast),
+					"400": errorResponse("Invalid query parameter."),
+					"501": errorResponse("The estimate provider does not forecast."),
+					"503": errorResponse("No estimate has been computed yet."),
+				},
+			},
+		},
+		"/v1/gas/cost": map[string]any{
+			"get": map[string]any{
+				"operationId": "getCost",
+				"summary":     "Total cost of a transaction at a tier",
+				"description": "Multiplies the tier's current fees by the gas used. estimated assumes the predicted base fee and max the worst case at max_fee_per_gas; each is given in wei, gwei, whole native tokens and, with a price feed, USD.",
+				"parameters": []any{
+					map[string]any{
+						"name":        "gas",
+						"in":          "query",
+						"required":    true,
+						"description": "Gas used by the transaction.",
+						"schema":      map[string]any{"type": "integer"},
+					},
+					query("tier", "string", "One of urgent, fast, standard or slow. Default standard."),
+					ifGenerationMatch,
+				},
+				"responses": map[string]any{
+					"200": jsonResponse("The transaction's cost.", cost),
+					"400": errorResponse("Invalid query parameter."),
+					"412": generationFailed,
+					"503": errorResponse("No estimate has been computed yet, or the current one is expired."),
+				},
+			},
+		},
+		"/v1/gas/deadline": map[string]any{
+			"get": map[string]any{
+				"operationId": "getDeadlineFee",
+				"summary":     "Fee for inclusion within a deadline",
+				"description": "Returns one recommendation for inclusion within deadline (or blocks) with the given confidence. Treating blocks as independent, the deadline and confidence map to the per-block inclusion probability p = 1-(1-confidence)^(1/blocks), and the priority fee is the p-th percentile of the cheapest tips included in recent blocks.",
+				"parameters": []any{
+					query("deadline", "string", "Wait as a Go duration, e.g. 30s; converted to blocks with the chain's block time. Exclusive with blocks."),
+					query("blocks", "integer", "Wait in blocks, at least 1. Exclusive with deadline."),
+					query("confidence", "number", "Targeted probability of inclusion by the deadline, between 0 and 1. Default 0.9."),
+					ifGenerationMatch,
+				},
+				"responses": map[string]any{
+					"200": jsonResponse("The recommendation.", deadline),
+					"400": errorResponse("Invalid query parameter, or deadline given for a chain with no known block time."),
+					"412": generationFailed,
+					"503": errorResponse("No estimate has been computed yet, the current one is expired, or no recent block had fee-paying transactions."),
+				},
+			},
+		},
+		"/v1/gas/suggest": map[string]any{
+			"post": map[string]any{
+				"operationId": "suggestFees",
+				"summary":     "Fee suggestion for an unsigned transaction",
+				"description": "Available when GAS_API_SUGGEST is set. Estimates the transaction's gas with eth_estimateGas unless gas is given, prices the requested tier for that much gas (as gas_amount does on /v1/gas/estimate) and, when from is set, returns the sender's next nonce. " +
+					"data is 0x-prefixed hex and value a decimal amount of wei. Name a tier (default \"standard\") or give within, a Go duration such as \"30s\", to get the cheapest tier expected to be included in time. " +
+					"Omit to for a contract deployment, with the init code as data (at most 49152 bytes); estimated deployment gas includes a 10% margin. " +
+					"Set access_list to also generate an EIP-2930 access list with eth_createAccessList; it is returned, and the gas priced with it, only if it lowers the estimate.",
+				"parameters": []any{ifGenerationMatch},
+				"requestBody": map[string]any{
+					"required": true,
+					"content": map[string]any{
+						"application/json": map[string]any{"schema": suggestReq},
+					},
+				},
+				"responses": map[string]any{
+					"200": jsonResponse("Fields to sign the transaction with, and its cost.", suggestResp),
+					"400": errorResponse("Invalid transaction, tier or within, init code over the size limit, or gas below the intrinsic gas or above the block gas limit."),
+					"412": generationFailed,
+					"422": errorResponse("The node rejected the transaction, e.g. because it reverts."),
+					"501": errorResponse("access_list was set but the node cannot create access lists."),
+					"502": errorResponse("The node could not be reached."),
+					"503": errorResponse("No estimate has been computed yet, the current one is expired, or the node request budget was exhausted."),
+				},
+			},
+		},
+		"/v1/chain/status": map[string]any{
+			"get": map[string]any{
+				"operationId": "getChainStatus",
+				"summary":     "Health of the service's view of the chain",
+				"description": "Reports the newest block processed, its age, how often the chain reorganized, the head subscription's state and, when the node supports it, eth_syncing progress and net_peerCount. " +
+					"The head is halted when it is older than the halt threshold: GAS_CHAIN_HALT_THRESHOLD, or by default ten block times (two minutes when the block time is unknown); dev chains never halt. While the node re-syncs (eth_syncing reports progress or the head rewound more than GAS_RESYNC_MAX_REWIND blocks), estimates are held back and resyncing is set. Standby followers report subscription \"stopped\" and are healthy unless their node is.",
+				"responses": map[string]any{
+					"200": jsonResponse("The chain view is healthy.", chainStatus),
+					"503": jsonResponse("The head is halted or not yet known, the subscription fell back to polling, estimates are held back for a node re-sync, or the node is unreachable or syncing.", chainStatus),
+				},
+			},
+		},
+		"/v1/strategy": map[string]any{
+			"get": map[string]any{
+				"operationId": "getStrategy",
+				"summary":     "Active estimation strategy and its parameters",
+				"description": "Parameters are listed by the names /v1/strategy/evaluate accepts as overrides. An ensemble lists its combine method and each member's weight and parameters.",
+				"responses": map[string]any{
+					"200": jsonResponse("The strategy.", strategy),
+				},
+			},
+		},
+		"/v1/strategy/evaluate": map[string]any{
+			"get": map[string]any{
+				"operationId": "evaluateStrategy",
+				"summary":     "Try strategy parameters against the latest input",
+				"description": "Available when GAS_API_STRATEGY_OVERRIDES is set. Every query parameter except api_key overrides the strategy parameter of that name, e.g. ?historical_weight=0.7&tiers=95,80,50,20. " +
+					"The estimate is recalculated from the history and mempool sample the latest published estimate was calculated from, and returned next to it; nothing is published. For an ensemble, parameters are set on every member that has them.",
+				"responses": map[string]any{
+					"200": jsonResponse("The published and evaluated estimates.", strategyEvaluation),
+					"400": errorResponse("An unknown parameter or invalid value."),
+					"501": errorResponse("The strategy has no tunable parameters."),
+					"503": errorResponse("No estimate has been published yet."),
+				},
+			},
+		},
+		"/v1/webhooks": map[string]any{
+			"get": map[string]any{
+				"operationId": "listWebhooks",
+				"summary":     "Registered fee webhooks",
+				"description": "Available when webhooks are enabled; requires \"Authorization: Bearer <token>\".",
+				"responses": map[string]any{
+					"200": jsonResponse("Registered webhooks, oldest first.", webhookList),
+					"401": errorResponse("Missing or invalid token."),
+				},
+			},
+			"post": map[string]any{
+				"operationId": "registerWebhook",
+				"summary":     "Register a webhook for a fee threshold",
+				"description": "The URL receives a signed POST when the tier's fee (max_fee_per_gas unless field says otherwise) crosses the threshold in wei in the given direction, \"below\" or \"above\". " +
+					"Each delivery carries X-Gas-Timestamp and X-Gas-Signature: \"sha256=\" and the hex HMAC-SHA256 of \"<timestamp>.<body>\" keyed with the returned secret. " +
+					"Failed deliveries are retried with exponential backoff. Requires \"Authorization: Bearer <token>\".",
+				"requestBody": map[string]any{
+					"required": true,
+					"content": map[string]any{
+						"application/json": map[string]any{"schema": webhookReq},
+					},
+				},
+				"responses": map[string]any{
+					"201": jsonResponse("The registered webhook, including its signing secret.", webhookResp),
+					"400": errorResponse("Invalid URL or condition."),
+					"401": errorResponse("Missing or invalid token."),
+					"409": errorResponse("The webhook limit has been reached."),
+				},
+			},
+		},
+		"/v1/webhooks/{id}": map[string]any{
+			"get": map[string]any{
+				"operationId": "getWebhook",
+				"summary":     "A registered webhook",
+				"parameters":  []any{webhookID},
+				"responses": map[string]any{
+					"200": jsonResponse("The webhook.", webhookResp),
+					"401": errorResponse("Missing or invalid token."),
+					"404": errorResponse("No webhook with this ID."),
+				},
+			},
+			"delete": map[string]any{
+				"operationId": "deleteWebhook",
+				"summary":     "Remove a webhook",
+				"parameters":  []any{webhookID},
+				"responses": map[string]any{
+					"204": map[string]any{"description": "The webhook was removed."},
+					"401": errorResponse("Missing or invalid token."),
+					"404": errorResponse("No webhook with this ID."),
+				},
+			},
+		},
+		"/v1/openapi.json": map[string]any{
+			"get": map[string]any{
+				"operationId": "getOpenAPI",
+				"summary":     "This document",
+				"responses": map[string]any{
+					"200": map[string]any{
+						"description": "The OpenAPI document.",
+						"content":     map[string]any{"application/json": map[string]any{}},
+					},
+				},
+			},
+		},
+		"/v1/schema": map[string]any{
+			"get": map[string]any{
+				"operationId": "getJSONSchema",
+				"summary":     "JSON Schema of the request and response bodies",
+				"description": "A JSON Schema (draft 2020-12) document with one $defs entry per body type, named as in this document.",
+				"responses": map[string]any{
+					"200": map[string]any{
+						"description": "The JSON Schema document.",
+						"content":     map[string]any{"application/schema+json": map[string]any{}},
+					},
+				},
+			},
+		},
+		"/v1/schema.d.ts": map[string]any{
+			"get": map[string]any{
+				"operationId": "getTypeScriptTypes",
+				"summary":     "TypeScript declarations of the request and response bodies",
+				"responses": map[string]any{
+					"200": map[string]any{
+						"description": "One exported interface per body type.",
+						"content":     map[string]any{"application/typescript": map[string]any{"schema": map[string]any{"type": "string"}}},
+					},
+				},
+			},
+		},
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Gas Estimator API",
+			"version": estimator.Version,
+			"description": "Paths are prefixed with the API version. Published versions are frozen: responses may gain optional fields but never change shape. " +
+				"Every response names its version in the API-Version header; the default version is also served without a prefix. " +
+				"Responses of a deprecated version carry Deprecation and Sunset headers and a Link to the successor version of the endpoint.",
+		},
+		"paths": paths,
+		// The API key is only required in multi-tenant mode
+		"security": []any{map[string]any{}, map[string]any{"apiKey": []any{}}},
+		"components": map[string]any{
+			"schemas": g.components,
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        apiKeyHeader,
+					"description": "Tenant API key, required on /v1/gas/ endpoints when the deployment runs in multi-tenant mode. Requests outside the tenant's chains get 403, and over its quota 429 with Retry-After.",
+				},
+			},
+		},
+	}
+}
+
+func jsonResponse(desc string, schema map[string]any) map[string]any {
+	return map[string]any{
+		"description": desc,
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": schema},
+		},
+	}
+}
+
+// schemaGen converts Go types to OpenAPI schemas. Named structs become
+// components referenced by $ref.
+type schemaGen struct {
+	components map[string]any
+	refPrefix  string // where components live in the document
+}
+
+func newSchemaGen(refPrefix string) *schemaGen {
+	return &schemaGen{components: make(map[string]any), refPrefix: refPrefix}
+}
+
+func (g *schemaGen) schema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := g.components[name]; !ok {
+			// Reserve the name first so recursive types terminate
+			g.components[name] = nil
+			g.components[name] = g.object(t)
+		}
+		return map[string]any{"$ref": g.refPrefix + name}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	default:
+		return map[string]any{}
+	}
+}
+
+// object describes a struct from its exported fields and json tags.
+// Fields without omitempty are always encoded and so are required. A
+// "format" tag overrides the schema format, e.g. format:"date-time".
+func (g *schemaGen) object(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop := g.schema(f.Type)
+		if format := f.Tag.Get("format"); format != "" {
+			prop["format"] = format
+		}
+		props[name] = prop
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	obj := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		obj["required"] = required
+	}
+	return obj
+}
@@ -0,0 +1,75 @@
+package grpc
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+	"github.com/branched-services/go-gas/pkg/webhook"
+)
+
+func TestOpenAPI_Routes(t *testing.T) {
+	// Enable every optional route; the handlers are never called
+	var est *estimator.Estimator
+	dispatcher := webhook.NewDispatcher(estimator.NewProvider(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s, _ := newTestServer(t,
+		WithNode(&fakeNode{}),
+		WithSuggest(nil),
+		WithChainStatus(est),
+		WithStrategy(est, true),
+		WithWebhooks(dispatcher, "webhook-token"),
+	)
+
+	rec := serve(s, "GET", "/v1/openapi.json", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var spec struct {
+		OpenAPI string                     `json:"openapi"`
+		Paths   map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("spec is not valid JSON: %v", err)
+	}
+	if spec.OpenAPI == "" {
+		t.Error("spec has no openapi version")
+	}
+
+	// The spec lists a path served the same way by every version once,
+	// under the oldest; a path only some versions serve under each of them
+	registered := make(map[string]bool)
+	for v, paths := range s.routes {
+		for path := range paths {
+			registered["/"+string(v)+path] = true
+		}
+	}
+	for v, paths := range s.routes {
+		for path := range paths {
+			want := "/" + string(v) + path
+			if allVersions(s, path) {
+				want = "/" + string(apiVersions[0]) + path
+			}
+			if _, ok := spec.Paths[want]; !ok {
+				t.Errorf("route %s is missing from the spec as %s", "/"+string(v)+path, want)
+			}
+		}
+	}
+	for path := range spec.Paths {
+		if !registered[path] {
+			t.Errorf("spec lists %s, which is not registered", path)
+		}
+	}
+}
+
+// allVersions reports whether every API version serves path.
+func allVersions(s *Server, path string) bool {
+	for _, v := range apiVersions {
+		if !s.routes[v][path] {
+			return false
+		}
+	}
+	return true
+}
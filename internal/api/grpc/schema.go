@@ -0,0 +1,163 @@
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+)
+
+// Like the OpenAPI document, the JSON Schema and TypeScript declarations
+// are generated from the Go types by reflection, so front-end consumers
+// pick up new response fields as soon as the server encodes them.
+
+// schemaTypes are the request and response bodies of the public API, in
+// the order their declarations are emitted.
+var schemaTypes = []reflect.Type{
+	reflect.TypeOf(GasEstimateResponse{}),
+	reflect.TypeOf(GasEstimateV2Response{}),
+	reflect.TypeOf(StreamUpdate{}),
+	reflect.TypeOf(GasHistoryResponse{}),
+	reflect.TypeOf(AccuracyResponse{}),
+	reflect.TypeOf(ForecastResponse{}),
+	reflect.TypeOf(CostResponse{}),
+	reflect.TypeOf(DeadlineResponse{}),
+	reflect.TypeOf(SuggestRequest{}),
+	reflect.TypeOf(SuggestResponse{}),
+	reflect.TypeOf(ChainStatusResponse{}),
+	reflect.TypeOf(StrategyResponse{}),
+	reflect.TypeOf(StrategyEvaluationResponse{}),
+	reflect.TypeOf(WebhookRequest{}),
+	reflect.TypeOf(WebhookResponse{}),
+	reflect.TypeOf(WebhookListResponse{}),
+	reflect.TypeOf(ErrorResponse{}),
+}
+
+// handleSchema serves the API's JSON Schema.
+func (s *Server) handleSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(s.schema)
+}
+
+// handleTypeScript serves TypeScript declarations for the API's bodies.
+func (s *Server) handleTypeScript(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/typescript; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(s.tsTypes)
+}
+
+// JSONSchema returns a JSON Schema (draft 2020-12) document whose $defs
+// describe every request and response body of the API, named after the Go
+// types.
+func JSONSchema() []byte {
+	g := newSchemaGen("#/$defs/")
+	for _, t := range schemaTypes {
+		g.schema(t)
+	}
+	doc := map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     "/v1/schema",
+		"title":   "Gas Estimator API " + estimator.Version,
+		"$defs":   g.components,
+	}
+	// The document only contains maps, slices and strings; encoding can't fail
+	b, _ := json.MarshalIndent(doc, "", "  ")
+	return b
+}
+
+// TypeScript returns TypeScript declarations of every request and response
+// body of the API, one exported interface per Go type.
+func TypeScript() []byte {
+	g := &tsGen{seen: make(map[string]bool)}
+	for _, t := range schemaTypes {
+		g.declare(t)
+	}
+	return []byte("// Gas Estimator API " + estimator.Version + " types, generated from the server.\n" +
+		"// Fees are decimal wei strings; optional fields are omitted when empty.\n" + g.out.String())
+}
+
+// tsGen writes TypeScript interfaces for Go structs, in dependency order
+// after the types that use them.
+type tsGen struct {
+	out  strings.Builder
+	seen map[string]bool
+}
+
+// declare emits an interface for the struct t and the structs it uses.
+func (g *tsGen) declare(t reflect.Type) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || g.seen[t.Name()] {
+		return
+	}
+	g.seen[t.Name()] = true
+
+	var nested []reflect.Type
+	fmt.Fprintf(&g.out, "\nexport interface %s {\n", t.Name())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		optional := ""
+		if strings.Contains(opts, "omitempty") {
+			optional = "?"
+		}
+		fmt.Fprintf(&g.out, "  %s%s: %s;\n", name, optional, g.typeName(f.Type, &nested))
+	}
+	g.out.WriteString("}\n")
+
+	for _, n := range nested {
+		g.declare(n)
+	}
+}
+
+// typeName returns the TypeScript type for t, adding named structs it
+// refers to to nested.
+func (g *tsGen) typeName(t reflect.Type, nested *[]reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.typeName(t.Elem(), nested)
+	case reflect.Struct:
+		*nested = append(*nested, t)
+		return t.Name()
+	case reflect.Slice, reflect.Array:
+		elem := g.typeName(t.Elem(), nested)
+		if strings.ContainsAny(elem, " |") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case reflect.Map:
+		return "Record<string, " + g.typeName(t.Elem(), nested) + ">"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return "unknown"
+	}
+}
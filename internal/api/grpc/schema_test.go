@@ -0,0 +1,125 @@
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestJSONSchema_MatchesResponses(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := serve(s, "GET", "/v1/schema", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("schema status = %d, want 200", rec.Code)
+	}
+	var doc struct {
+		Defs map[string]any `json:"$defs"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		def  string
+	}{
+		{"/v1/gas/estimate", "GasEstimateResponse"},
+		{"/v1/gas/estimate?include=distribution&gas_limit=21000", "GasEstimateResponse"},
+		{"/v2/gas/estimate", "GasEstimateV2Response"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := serve(s, "GET", tt.path, "", nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+			}
+			var body any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			for _, err := range checkSchema(doc.Defs, map[string]any{"$ref": "#/$defs/" + tt.def}, body, "$") {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// checkSchema validates v against the subset of JSON Schema the generator
+// emits. Properties the schema doesn't declare are errors too, so a field
+// added without a schema shows up.
+func checkSchema(defs map[string]any, schema map[string]any, v any, at string) []error {
+	if ref, ok := schema["$ref"].(string); ok {
+		def, ok := defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+		if !ok {
+			return []error{fmt.Errorf("%s: unresolved $ref %s", at, ref)}
+		}
+		return checkSchema(defs, def, v, at)
+	}
+
+	var errs []error
+	switch typ, _ := schema["type"].(string); typ {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return []error{fmt.Errorf("%s: %v is not an object", at, v)}
+		}
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				errs = append(errs, fmt.Errorf("%s: required property %s is missing", at, name))
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		extra, _ := schema["additionalProperties"].(map[string]any)
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := props[name].(map[string]any)
+			if !ok {
+				prop = extra
+			}
+			if prop == nil {
+				errs = append(errs, fmt.Errorf("%s: property %s is not in the schema", at, name))
+				continue
+			}
+			errs = append(errs, checkSchema(defs, prop, obj[name], at+"."+name)...)
+		}
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return []error{fmt.Errorf("%s: %v is not an array", at, v)}
+		}
+		for i, item := range items {
+			errs = append(errs, checkSchema(defs, schema["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			errs = append(errs, fmt.Errorf("%s: %v is not a string", at, v))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			errs = append(errs, fmt.Errorf("%s: %v is not a boolean", at, v))
+		}
+	case "number", "integer":
+		n, ok := v.(float64)
+		if !ok || typ == "integer" && n != math.Trunc(n) {
+			return []error{fmt.Errorf("%s: %v is not an %s", at, v, typ)}
+		}
+		if min, ok := schema["minimum"].(float64); ok && n < min {
+			errs = append(errs, fmt.Errorf("%s: %v is below the minimum %v", at, n, min))
+		}
+	case "":
+		// Any value
+	default:
+		errs = append(errs, fmt.Errorf("%s: unexpected schema type %q", at, typ))
+	}
+	return errs
+}
@@ -3,14 +3,25 @@ package grpc
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/branched-services/go-gas/internal/observability"
+	"github.com/branched-services/go-gas/internal/tenant"
 	"github.com/branched-services/go-gas/pkg/estimator"
+	"github.com/branched-services/go-gas/pkg/pricefeed"
+	"github.com/branched-services/go-gas/pkg/webhook"
+	"github.com/holiman/uint256"
 )
 
 // Note: This is a simplified HTTP/JSON implementation.
@@ -19,29 +30,149 @@ import (
 
 // Server provides the gas estimation API.
 type Server struct {
-	addr     string
-	provider estimator.EstimateReader
-	logger   *slog.Logger
-	server   *http.Server
+	addr      string
+	provider  estimator.EstimateReader
+	priceFeed pricefeed.Feed
+	node      Node
+	logger    *slog.Logger
+	server    *http.Server
+	openAPI   []byte
+	schema    []byte
+	tsTypes   []byte
+	docs      bool
+	compress  bool
+
+	accessLogLevel slog.Level // zero is info
+
+	debug      estimator.DebugReader
+	debugToken string
+
+	webhooks     *webhook.Dispatcher
+	webhookToken string
+
+	tenants          *tenant.Registry
+	tenantAdminToken string
+
+	control    estimator.Controller
+	adminToken string
+
+	chain estimator.ChainStatusReader
+
+	strategy          estimator.StrategyEvaluator
+	strategyOverrides bool
+
+	feeRatesMu sync.Mutex
+	feeRates   map[string]feeRate // by lowercase fee currency address
+
+	suggest    bool
+	nodeBudget *estimator.NodeLimiter
+
+	accuracyWindows []time.Duration
+
+	timeouts Timeouts
+
+	defaultVersion APIVersion
+	deprecations   map[APIVersion]Deprecation
+	routes         map[APIVersion]map[string]bool // paths served, by version
+
+	// draining is closed when Shutdown begins so open streams can say goodbye
+	draining  chan struct{}
+	drainOnce sync.Once
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithPriceFeed enables fiat (USD) cost quotes in estimate responses.
+func WithPriceFeed(feed pricefeed.Feed) Option {
+	return func(s *Server) {
+		s.priceFeed = feed
+	}
+}
+
+// WithDocs serves a Swagger UI page for the OpenAPI document at /docs.
+func WithDocs() Option {
+	return func(s *Server) {
+		s.docs = true
+	}
 }
 
 // NewServer creates a new gRPC server.
-func NewServer(addr string, provider estimator.EstimateReader, logger *slog.Logger) *Server {
+func NewServer(addr string, provider estimator.EstimateReader, logger *slog.Logger, opts ...Option) *Server {
 	s := &Server{
 		addr:     addr,
 		provider: provider,
 		logger:   logger.With("component", "grpc"),
+		draining: make(chan struct{}),
+		openAPI:  OpenAPISpec(),
+		schema:   JSONSchema(),
+		tsTypes:  TypeScript(),
+		compress: true,
+
+		accuracyWindows: defaultAccuracyWindows,
+		timeouts:        defaultTimeouts,
+		defaultVersion:  APIVersion1,
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/v1/gas/estimate", s.handleEstimate)
-	mux.HandleFunc("/v1/gas/estimate/stream", s.handleStream)
+	// Responses that embed a v1 estimate are v1 only until v2 reshapes them
+	v1 := func(h http.HandlerFunc) map[APIVersion]http.HandlerFunc {
+		return map[APIVersion]http.HandlerFunc{APIVersion1: h}
+	}
+	s.handleVersions(mux, "/gas/estimate", map[APIVersion]http.HandlerFunc{
+		APIVersion1: s.compressed(s.handleEstimate),
+		APIVersion2: s.compressed(s.handleEstimateV2),
+	})
+	s.handleVersions(mux, "/gas/estimate/stream", v1(s.handleStream))
+	s.handleVersions(mux, "/gas/estimate/next", v1(s.handleNext))
+	s.handleVersions(mux, "/gas/history", v1(s.compressed(s.handleHistory)))
+	s.handle(mux, "/gas/accuracy", s.handleAccuracy)
+	s.handle(mux, "/gas/forecast", s.compressed(s.handleForecast))
+	s.handle(mux, "/gas/cost", s.handleCost)
+	s.handle(mux, "/gas/deadline", s.handleDeadline)
+	if s.node != nil && s.suggest {
+		s.handle(mux, "/gas/suggest", s.handleSuggest)
+	}
+	if s.chain != nil {
+		s.handle(mux, "/chain/status", s.handleChainStatus)
+	}
+	if s.strategy != nil {
+		s.handle(mux, "/strategy", s.handleStrategy)
+		if s.strategyOverrides {
+			s.handleVersions(mux, "/strategy/evaluate", v1(s.handleStrategyEvaluate))
+		}
+	}
+	s.handle(mux, "/openapi.json", s.compressed(s.handleOpenAPI))
+	s.handle(mux, "/schema", s.compressed(s.handleSchema))
+	s.handle(mux, "/schema.d.ts", s.compressed(s.handleTypeScript))
+	if s.docs {
+		mux.HandleFunc("/docs", s.handleDocs)
+	}
+	if s.debug != nil {
+		mux.HandleFunc("/debug/estimator", s.handleDebug)
+	}
+	if s.webhooks != nil {
+		s.handle(mux, "/webhooks", s.handleWebhooks)
+		s.handle(mux, "/webhooks/{id}", s.handleWebhook)
+	}
+	if s.control != nil {
+		s.registerAdmin(mux)
+	}
+	if s.tenants != nil && s.tenantAdminToken != "" {
+		mux.HandleFunc("/admin/tenants", s.handleTenants)
+		mux.HandleFunc("/admin/tenants/{id}", s.handleTenant)
+		mux.HandleFunc("/admin/tenants/{id}/key", s.handleTenantKey)
+	}
 
 	s.server = &http.Server{
 		Addr:         addr,
 		Handler:      s.withMiddleware(mux),
 		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		WriteTimeout: s.timeouts.Write,
 		IdleTimeout:  120 * time.Second,
 	}
 
@@ -73,45 +204,125 @@ func (s *Server) Run(ctx context.Context) error {
 }
 
 // Shutdown gracefully stops the server.
+//
+// New connections are refused immediately. Open streams receive a final
+// "shutdown" event and are closed by the server; Shutdown then waits for
+// in-flight requests until ctx expires, after which remaining connections
+// are closed forcibly.
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("API server shutting down")
-	return s.server.Shutdown(ctx)
-}
-
-// withMiddleware wraps the handler with common middleware.
-func (s *Server) withMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-
-		// Set common headers
-		w.Header().Set("Content-Type", "application/json")
+	s.drainOnce.Do(func() { close(s.draining) })
 
-		// CORS for development
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-
-		s.logger.Debug("request completed",
-			"method", r.Method,
-			"path", r.URL.Path,
-			"duration_us", time.Since(start).Microseconds(),
-		)
-	})
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.logger.Warn("connections did not drain before deadline, closing", "error", err)
+		s.server.Close()
+		return err
+	}
+	return nil
 }
 
 // GasEstimateResponse is the API response format.
 type GasEstimateResponse struct {
 	ChainID     uint64          `json:"chain_id"`
 	BlockNumber uint64          `json:"block_number"`
-	Timestamp   string          `json:"timestamp"`
+	Timestamp   string          `json:"timestamp" format:"date-time"`
 	BaseFee     string          `json:"base_fee"`
 	Estimates   EstimatesBundle `json:"estimates"`
+
+	// FeeCurrency is the token BaseFee, Estimates and costs are in: the
+	// native token unless the request converts them with fee_currency.
+	FeeCurrency FeeCurrencyResponse `json:"fee_currency"`
+
+	// LastUpdate is when the estimate was published, unlike Timestamp, which
+	// is when it was calculated. Clients can use it and EstimateAgeMs to
+	// reject stale data.
+	LastUpdate string `json:"last_update,omitempty" format:"date-time"`
+
+	// Generation identifies this exact estimate. Send it back as the
+	// If-Generation-Match header so follow-up calls (distribution, cost,
+	// suggest) are computed from the same snapshot.
+	Generation string `json:"generation"`
+
+	// ValidUntil is when the next block is expected; the estimate should
+	// not be used for transactions signed after it. Empty when the chain's
+	// block cadence is unknown.
+	ValidUntil string `json:"valid_until,omitempty" format:"date-time"`
+
+	// BaseFeeMultiplier is the base fee buffer used in max_fee_per_gas.
+	BaseFeeMultiplier float64 `json:"base_fee_multiplier,omitempty"`
+
+	// Labels identifying the deployment that produced the estimate.
+	Network          NetworkResponse `json:"network"`
+	Strategy         string          `json:"strategy,omitempty"`
+	EstimatorVersion string          `json:"estimator_version"`
+
+	// Degraded is set while the estimator polls for blocks after losing its
+	// node subscription; estimates may lag the chain.
+	Degraded bool `json:"degraded,omitempty"`
+
+	// Samples is how much data the tiers were derived from.
+	Samples SampleSizesResponse `json:"samples"`
+
+	// BaseFeeGwei is BaseFee in gwei, set only when the request includes
+	// unit=gwei (optionally with round=<gwei>, e.g. round=0.1).
+	BaseFeeGwei string `json:"base_fee_gwei,omitempty"`
+
+	// GasAmount echoes the gas_amount the tiers were priced for, if any.
+	GasAmount uint64 `json:"gas_amount,omitempty"`
+
+	// NativeTokenUSD is the price used for USD costs; set only when gas_limit
+	// was requested and a price feed is configured.
+	NativeTokenUSD *float64 `json:"native_token_usd,omitempty"`
+
+	// Distribution is set only when the request includes include=distribution.
+	Distribution *DistributionResponse `json:"distribution,omitempty"`
+
+	// Signature is set when the server signs estimates (GAS_SIGNING_KEY_FILE).
+	// Responses to gas_amount requests whose tiers were raised are unsigned.
+	Signature *SignatureResponse `json:"signature,omitempty"`
+
+	// EstimateAgeMs is how long ago the estimate was published, and
+	// ChainLagSeconds how long ago its block was produced, when the response
+	// was written (estimate endpoint only). They are the last fields so they
+	// can be appended to a pre-rendered body (see RenderEstimate).
+	EstimateAgeMs   *int64   `json:"estimate_age_ms,omitempty"`
+	ChainLagSeconds *float64 `json:"chain_lag_seconds,omitempty"`
+}
+
+// NetworkResponse describes the chain an estimate was produced for.
+// Fields are empty for chains without built-in or configured metadata.
+type NetworkResponse struct {
+	Name           string `json:"name,omitempty"`
+	CurrencySymbol string `json:"currency_symbol,omitempty"`
+	BlockTimeMs    int64  `json:"block_time_ms,omitempty"`
+
+	// FeeCurrencies are the other tokens the chain accepts fees in; pass
+	// one's symbol or address as fee_currency to get estimates in it.
+	FeeCurrencies []FeeCurrencyResponse `json:"fee_currencies,omitempty"`
+}
+
+// SignatureResponse is the publisher's signature over the estimate's chain
+// ID, block number, timestamp, base fee and tiers, hex encoded. Message is
+// the exact signed bytes (see estimator.SigningMessage); verifiers should
+// check that it matches the response fields and that PublicKey is the key
+// they trust.
+type SignatureResponse struct {
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"`
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+}
+
+// DistributionResponse is the raw priority fee percentile curve per data source.
+type DistributionResponse struct {
+	Historical []PercentilePoint `json:"historical"`
+	Mempool    []PercentilePoint `json:"mempool"`
+}
+
+// PercentilePoint is one point on a percentile curve.
+type PercentilePoint struct {
+	Percentile     float64 `json:"percentile"`
+	MaxPriorityFee string  `json:"max_priority_fee_per_gas"`
 }
 
 // EstimatesBundle contains all priority level estimates.
@@ -127,62 +338,682 @@ type EstimateLevel struct {
 	MaxPriorityFeePerGas string  `json:"max_priority_fee_per_gas"`
 	MaxFeePerGas         string  `json:"max_fee_per_gas"`
 	Confidence           float64 `json:"confidence"`
+
+	// Sources are where the priority fee came from: history, mempool,
+	// pending_block or, when none had samples, default.
+	Sources []string `json:"sources,omitempty"`
+
+	// GasPrice is the equivalent gas price for legacy (type-0 and type-1)
+	// transactions: the predicted base fee plus the priority fee.
+	GasPrice string `json:"gas_price"`
+
+	// The fees above in gwei, set only when the request includes unit=gwei.
+	MaxPriorityFeePerGasGwei string `json:"max_priority_fee_per_gas_gwei,omitempty"`
+	MaxFeePerGasGwei         string `json:"max_fee_per_gas_gwei,omitempty"`
+	GasPriceGwei             string `json:"gas_price_gwei,omitempty"`
+
+	// Cost is set only when the request includes gas_limit.
+	Cost *TxCost `json:"cost,omitempty"`
+}
+
+// SampleSizesResponse counts the data behind an estimate: the recent blocks
+// that contributed priority fees and the fees sampled from each source.
+type SampleSizesResponse struct {
+	Blocks       int `json:"blocks"`
+	Historical   int `json:"historical"`
+	Mempool      int `json:"mempool"`
+	PendingBlock int `json:"pending_block"`
+}
+
+// TxCost is the cost of a transaction with a given gas limit at one tier.
+// Estimated assumes the predicted base fee; Max is the worst case at MaxFeePerGas.
+type TxCost struct {
+	GasLimit     uint64   `json:"gas_limit"`
+	EstimatedWei string   `json:"estimated_wei"`
+	MaxWei       string   `json:"max_wei"`
+	EstimatedUSD *float64 `json:"estimated_usd,omitempty"`
+	MaxUSD       *float64 `json:"max_usd,omitempty"`
 }
 
-// handleEstimate returns the current gas estimate.
+// handleEstimate returns the current gas estimate, or the one named by the
+// "block" query parameter or the If-Generation-Match header.
 func (s *Server) handleEstimate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 100*time.Millisecond)
+	est, body, ok := s.requestedSnapshot(w, r)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	setEstimateHeaders(w, est, now)
+
+	// Plain requests are served from the body rendered at publish time
+	if body != nil && r.URL.RawQuery == "" {
+		etag := estimateETag(est)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		writeRendered(w, body, est)
+		return
+	}
+
+	resp, etag, ok := s.estimateResponse(w, r, est, now)
+	if !ok {
+		return
+	}
+	writeCacheable(w, r, etag, resp)
+}
+
+// requestedSnapshot returns the estimate an estimate request asks for: the
+// one named by the "block" query parameter or the If-Generation-Match
+// header, or the current one with its pre-rendered body, if any. On
+// failure it writes the error response and reports false.
+func (s *Server) requestedSnapshot(w http.ResponseWriter, r *http.Request) (*estimator.GasEstimate, []byte, bool) {
+	block, pinned := r.URL.Query().Get("block"), r.Header.Get(ifGenerationMatchHeader)
+	switch {
+	case block != "" && pinned != "":
+		s.writeError(w, http.StatusBadRequest, "block and "+ifGenerationMatchHeader+" are mutually exclusive")
+		return nil, nil, false
+	case pinned != "":
+		est := s.atGeneration(w, pinned)
+		return est, nil, est != nil
+	case block != "":
+		// The quote that was live at a recent block, for reconciliation
+		number, err := strconv.ParseUint(block, 10, 64)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid block: %q", block))
+			return nil, nil, false
+		}
+		snapshots, ok := s.provider.(estimator.SnapshotReader)
+		if !ok {
+			s.writeError(w, http.StatusNotImplemented, "estimate snapshots not available")
+			return nil, nil, false
+		}
+		est, ok := snapshots.AtBlock(number)
+		if !ok {
+			s.writeError(w, http.StatusNotFound, fmt.Sprintf("no estimate for block %d", number))
+			return nil, nil, false
+		}
+		return est, nil, true
+	}
+	est, body, err := s.current(r.Context())
+	if err != nil {
+		s.writeEstimateError(w, err)
+		return nil, nil, false
+	}
+	return est, body, true
+}
+
+// setEstimateHeaders sets the headers every estimate response carries.
+func setEstimateHeaders(w http.ResponseWriter, est *estimator.GasEstimate, now time.Time) {
+	w.Header().Set(generationHeader, generationToken(est))
+	if !est.BlockTimestamp.IsZero() {
+		// Lets load balancers route away from replicas that fall behind
+		w.Header().Set("X-Chain-Lag-Seconds", formatSeconds(est.ChainLag(now)))
+	}
+}
+
+// estimateResponse builds the /v1/gas/estimate response for est with the
+// request's query options applied, and its ETag. On failure it writes the
+// error response and reports false.
+func (s *Server) estimateResponse(w http.ResponseWriter, r *http.Request, est *estimator.GasEstimate, now time.Time) (GasEstimateResponse, string, bool) {
+	etag := estimateETag(est)
+
+	// Optional gwei rendering for display
+	units, err := parseUnits(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return GasEstimateResponse{}, "", false
+	}
+
+	// Optional size-aware pricing for a transaction using gas_amount gas
+	var gasAmount uint64
+	if v := r.URL.Query().Get("gas_amount"); v != "" {
+		gasAmount, err = strconv.ParseUint(v, 10, 64)
+		if err != nil || gasAmount == 0 {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid gas_amount: %q", v))
+			return GasEstimateResponse{}, "", false
+		}
+		est, err = est.ForGasAmount(gasAmount)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err.Error())
+			return GasEstimateResponse{}, "", false
+		}
+		// Raised tips must stay within the configured caps
+		if enforcer, ok := s.provider.(estimator.FeeCapEnforcer); ok {
+			enforcer.EnforceCaps(est)
+		}
+		etag = fmt.Sprintf(`%s-g%d"`, strings.TrimSuffix(etag, `"`), gasAmount)
+	}
+
+	// Optional conversion to another token the chain accepts fees in
+	var currency estimator.FeeCurrency
+	if v := r.URL.Query().Get("fee_currency"); v != "" {
+		var ok bool
+		if currency, ok = est.Network.LookupFeeCurrency(v); !ok {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown fee_currency: %q", v))
+			return GasEstimateResponse{}, "", false
+		}
+		if units != nil && currency.Decimals != etherDecimals {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("unit=gwei is not supported for %s (%d decimals)", currency.Symbol, currency.Decimals))
+			return GasEstimateResponse{}, "", false
+		}
+		if est, err = s.inFeeCurrency(r.Context(), est, currency); err != nil {
+			s.writeError(w, http.StatusBadGateway, err.Error())
+			return GasEstimateResponse{}, "", false
+		}
+		etag = fmt.Sprintf(`%s-%s"`, strings.TrimSuffix(etag, `"`), strings.ToLower(currency.Address))
+	}
+
+	resp := toResponse(est)
+	resp.GasAmount = gasAmount
+	if currency.Address != "" {
+		resp.FeeCurrency = toFeeCurrency(currency)
+	}
+	if units != nil {
+		units.apply(&resp)
+		etag = fmt.Sprintf(`%s-gwei%s"`, strings.TrimSuffix(etag, `"`), r.URL.Query().Get("round"))
+	}
+	if !est.UpdatedAt.IsZero() {
+		age := now.Sub(est.UpdatedAt).Milliseconds()
+		resp.EstimateAgeMs = &age
+	}
+	if !est.BlockTimestamp.IsZero() {
+		lag := est.ChainLag(now).Seconds()
+		resp.ChainLagSeconds = &lag
+	}
+
+	// Optional cost quote for a given gas limit
+	if v := r.URL.Query().Get("gas_limit"); v != "" {
+		gasLimit, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || gasLimit == 0 {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid gas_limit: %q", v))
+			return GasEstimateResponse{}, "", false
+		}
+		s.addCosts(r.Context(), &resp, est, gasLimit)
+	}
+
+	if includes(r, "distribution") && est.Distribution != nil {
+		resp.Distribution = &DistributionResponse{
+			Historical: toCurve(est.Distribution.Historical),
+			Mempool:    toCurve(est.Distribution.Mempool),
+		}
+	}
+
+	// USD costs can change with the price feed while the estimate doesn't
+	if resp.NativeTokenUSD != nil {
+		etag = fmt.Sprintf(`%s-%s"`, strings.TrimSuffix(etag, `"`),
+			strconv.FormatFloat(*resp.NativeTokenUSD, 'g', -1, 64))
+	}
+	return resp, etag, true
+}
+
+// writeCacheable writes resp with etag, or 304 Not Modified if the
+// request's If-None-Match has it. Estimates change at most once per
+// recalculation, so clients must revalidate.
+func writeCacheable(w http.ResponseWriter, r *http.Request, etag string, resp any) {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// current returns the provider's current estimate, with its pre-rendered
+// response body when the provider keeps one, waiting at most the read
+// timeout.
+func (s *Server) current(ctx context.Context) (*estimator.GasEstimate, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Read)
 	defer cancel()
 
+	if reader, ok := s.provider.(estimator.RenderedReader); ok {
+		return reader.Rendered(ctx)
+	}
 	est, err := s.provider.Current(ctx)
+	return est, nil, err
+}
+
+// RenderEstimate encodes est as the /v1/gas/estimate response to a request
+// without query parameters, minus estimate_age_ms and chain_lag_seconds.
+// Pass it to estimator.WithRenderer so the encoding happens once per update.
+func RenderEstimate(est *estimator.GasEstimate) []byte {
+	body, err := json.Marshal(toResponse(est))
+	if err != nil {
+		return nil
+	}
+	return append(body, '\n')
+}
+
+// writeRendered writes a body from RenderEstimate for est, splicing in the
+// estimate age and chain lag as its last fields.
+func writeRendered(w http.ResponseWriter, body []byte, est *estimator.GasEstimate) {
+	if est.UpdatedAt.IsZero() && est.BlockTimestamp.IsZero() {
+		w.Write(body)
+		return
+	}
+	bufp := renderBufs.Get().(*[]byte)
+	defer renderBufs.Put(bufp)
+
+	// Replace the closing "}\n"
+	now := time.Now()
+	buf := append((*bufp)[:0], body[:len(body)-2]...)
+	if !est.UpdatedAt.IsZero() {
+		buf = append(buf, `,"estimate_age_ms":`...)
+		buf = strconv.AppendInt(buf, now.Sub(est.UpdatedAt).Milliseconds(), 10)
+	}
+	if !est.BlockTimestamp.IsZero() {
+		buf = append(buf, `,"chain_lag_seconds":`...)
+		buf = strconv.AppendFloat(buf, est.ChainLag(now).Seconds(), 'f', -1, 64)
+	}
+	buf = append(buf, "}\n"...)
+	w.Write(buf)
+	*bufp = buf
+}
+
+// formatSeconds formats d in seconds with millisecond precision.
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// renderBufs holds buffers for writeRendered.
+var renderBufs = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, 2048)
+		return &buf
+	},
+}
+
+// addCosts fills per-tier transaction costs for gasLimit, including USD
+// values when a price feed is configured and currently available.
+func (s *Server) addCosts(ctx context.Context, resp *GasEstimateResponse, est *estimator.GasEstimate, gasLimit uint64) {
+	var quote *pricefeed.Quote
+	if resp.FeeCurrency.Address == "" {
+		// The price feed quotes the native token only
+		quote = s.quote(ctx)
+	}
+	if quote != nil {
+		resp.NativeTokenUSD = &quote.USD
+	}
+	resp.Estimates.Urgent.Cost = txCost(est.BaseFee, est.Urgent, gasLimit, quote)
+	resp.Estimates.Fast.Cost = txCost(est.BaseFee, est.Fast, gasLimit, quote)
+	resp.Estimates.Standard.Cost = txCost(est.BaseFee, est.Standard, gasLimit, quote)
+	resp.Estimates.Slow.Cost = txCost(est.BaseFee, est.Slow, gasLimit, quote)
+}
+
+// quote returns the native token price, or nil when no price feed is
+// configured or it is unavailable.
+func (s *Server) quote(ctx context.Context) *pricefeed.Quote {
+	if s.priceFeed == nil {
+		return nil
+	}
+	q, err := s.priceFeed.Price(ctx)
 	if err != nil {
-		if err == estimator.ErrNotReady {
-			s.writeError(w, http.StatusServiceUnavailable, "estimator not ready")
+		observability.WithContext(ctx, s.logger).Warn("price feed unavailable", "error", err)
+		return nil
+	}
+	return &q
+}
+
+// txCost is the cost of a transaction with gasLimit at tier p, with USD
+// values if quote is set.
+func txCost(baseFee *uint256.Int, p estimator.PriorityEstimate, gasLimit uint64, quote *pricefeed.Quote) *TxCost {
+	gas := uint256.NewInt(gasLimit)
+	estimated := new(uint256.Int).Add(baseFee, p.MaxPriorityFeePerGas)
+	estimated.Mul(estimated, gas)
+	max := new(uint256.Int).Mul(p.MaxFeePerGas, gas)
+
+	c := &TxCost{
+		GasLimit:     gasLimit,
+		EstimatedWei: estimated.Dec(),
+		MaxWei:       max.Dec(),
+	}
+	if quote != nil {
+		estimatedUSD, maxUSD := quote.ValueOf(estimated), quote.ValueOf(max)
+		c.EstimatedUSD, c.MaxUSD = &estimatedUSD, &maxUSD
+	}
+	return c
+}
+
+// GasHistoryResponse is the API response format for estimate history.
+type GasHistoryResponse struct {
+	Since     string                `json:"since" format:"date-time"`
+	Estimates []GasEstimateResponse `json:"estimates"`
+}
+
+// handleHistory returns the final estimate of each recent block.
+// Query parameter "since" is a duration (e.g. "1h") looking back from now; default 1h.
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	history, ok := s.provider.(estimator.HistoryReader)
+	if !ok {
+		s.writeError(w, http.StatusNotImplemented, "history not available")
+		return
+	}
+
+	window := time.Hour
+	if v := r.URL.Query().Get("since"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid since: %q", v))
 			return
 		}
-		s.writeError(w, http.StatusInternalServerError, err.Error())
+		window = d
+	}
+
+	units, err := parseUnits(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	resp := GasEstimateResponse{
-		ChainID:     est.ChainID,
-		BlockNumber: est.BlockNumber,
-		Timestamp:   est.Timestamp.UTC().Format(time.RFC3339Nano),
-		BaseFee:     est.BaseFee.String(),
-		Estimates: EstimatesBundle{
-			Urgent: EstimateLevel{
-				MaxPriorityFeePerGas: est.Urgent.MaxPriorityFeePerGas.String(),
-				MaxFeePerGas:         est.Urgent.MaxFeePerGas.String(),
-				Confidence:           est.Urgent.Confidence,
-			},
-			Fast: EstimateLevel{
-				MaxPriorityFeePerGas: est.Fast.MaxPriorityFeePerGas.String(),
-				MaxFeePerGas:         est.Fast.MaxFeePerGas.String(),
-				Confidence:           est.Fast.Confidence,
-			},
-			Standard: EstimateLevel{
-				MaxPriorityFeePerGas: est.Standard.MaxPriorityFeePerGas.String(),
-				MaxFeePerGas:         est.Standard.MaxFeePerGas.String(),
-				Confidence:           est.Standard.Confidence,
-			},
-			Slow: EstimateLevel{
-				MaxPriorityFeePerGas: est.Slow.MaxPriorityFeePerGas.String(),
-				MaxFeePerGas:         est.Slow.MaxFeePerGas.String(),
-				Confidence:           est.Slow.Confidence,
-			},
-		},
+	since := time.Now().Add(-window)
+	ests := history.Recent(since)
+
+	resp := GasHistoryResponse{
+		Since:     since.UTC().Format(time.RFC3339Nano),
+		Estimates: make([]GasEstimateResponse, len(ests)),
+	}
+	for i, est := range ests {
+		resp.Estimates[i] = toResponse(est)
+		if units != nil {
+			units.apply(&resp.Estimates[i])
+		}
 	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(resp)
 }
 
+// estimateETag identifies an estimate by block number and provider version.
+func estimateETag(est *estimator.GasEstimate) string {
+	return fmt.Sprintf(`"%d-%d"`, est.BlockNumber, est.Version)
+}
+
+// etagMatches implements If-None-Match comparison (weak, per RFC 9110).
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// includes reports whether the comma-separated "include" query parameter lists field.
+func includes(r *http.Request, field string) bool {
+	for _, v := range r.URL.Query()["include"] {
+		for _, f := range strings.Split(v, ",") {
+			if strings.TrimSpace(f) == field {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func toCurve(values []*uint256.Int) []PercentilePoint {
+	points := make([]PercentilePoint, len(values))
+	steps := len(values) - 1
+	for i, v := range values {
+		points[i] = PercentilePoint{
+			Percentile:     float64(i) / float64(steps),
+			MaxPriorityFee: v.Dec(),
+		}
+	}
+	return points
+}
+
+// toResponse converts an estimate to its API representation.
+func toResponse(est *estimator.GasEstimate) GasEstimateResponse {
+	return GasEstimateResponse{
+		ChainID:           est.ChainID,
+		BlockNumber:       est.BlockNumber,
+		Timestamp:         est.Timestamp.UTC().Format(time.RFC3339Nano),
+		LastUpdate:        formatTime(est.UpdatedAt),
+		Generation:        generationToken(est),
+		ValidUntil:        formatTime(est.ValidUntil),
+		BaseFee:           est.BaseFee.String(),
+		BaseFeeMultiplier: est.BaseFeeMultiplier,
+		Network: NetworkResponse{
+			Name:           est.Network.Name,
+			CurrencySymbol: est.Network.CurrencySymbol,
+			BlockTimeMs:    est.Network.BlockTime.Milliseconds(),
+			FeeCurrencies:  toFeeCurrencies(est.Network),
+		},
+		FeeCurrency:      nativeFeeCurrency(est.Network),
+		Strategy:         est.Strategy,
+		EstimatorVersion: estimator.Version,
+		Degraded:         est.Degraded,
+		Samples: SampleSizesResponse{
+			Blocks:       est.Samples.Blocks,
+			Historical:   est.Samples.Historical,
+			Mempool:      est.Samples.Mempool,
+			PendingBlock: est.Samples.PendingBlock,
+		},
+		Estimates: EstimatesBundle{
+			Urgent:   toLevel(est.Urgent, est.BaseFee),
+			Fast:     toLevel(est.Fast, est.BaseFee),
+			Standard: toLevel(est.Standard, est.BaseFee),
+			Slow:     toLevel(est.Slow, est.BaseFee),
+		},
+		Signature: toSignature(est),
+	}
+}
+
+func toSignature(est *estimator.GasEstimate) *SignatureResponse {
+	if est.Signature == nil {
+		return nil
+	}
+	return &SignatureResponse{
+		Algorithm: est.Signature.Algorithm,
+		PublicKey: hexBytes(est.Signature.PublicKey),
+		Message:   hexBytes(estimator.SigningMessage(est)),
+		Signature: hexBytes(est.Signature.Value),
+	}
+}
+
+// hexBytes encodes b as 0x-prefixed hex.
+func hexBytes(b []byte) string {
+	return "0x" + hex.EncodeToString(b)
+}
+
+// formatTime formats t as RFC 3339 in UTC; empty for the zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// Estimate converts an API response back into an estimate, e.g. to mirror
+// another replica; Version and UpdatedAt are those the replica published it
+// with. Costs, Components and Demand are not part of the response and are
+// left empty.
+func (r *GasEstimateResponse) Estimate() (*estimator.GasEstimate, error) {
+	if r.FeeCurrency.Address != "" {
+		return nil, fmt.Errorf("estimate is in fee currency %s, not the native token", r.FeeCurrency.Symbol)
+	}
+	ts, err := time.Parse(time.RFC3339Nano, r.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("timestamp: %w", err)
+	}
+	baseFee, err := uint256.FromDecimal(r.BaseFee)
+	if err != nil {
+		return nil, fmt.Errorf("base_fee: %w", err)
+	}
+
+	var validUntil, updatedAt time.Time
+	if r.ValidUntil != "" {
+		if validUntil, err = time.Parse(time.RFC3339Nano, r.ValidUntil); err != nil {
+			return nil, fmt.Errorf("valid_until: %w", err)
+		}
+	}
+	if r.LastUpdate != "" {
+		if updatedAt, err = time.Parse(time.RFC3339Nano, r.LastUpdate); err != nil {
+			return nil, fmt.Errorf("last_update: %w", err)
+		}
+	}
+	var version uint64
+	if r.Generation != "" {
+		if _, version, err = parseGenerationToken(r.Generation); err != nil {
+			return nil, fmt.Errorf("generation: %w", err)
+		}
+	}
+
+	est := &estimator.GasEstimate{
+		ChainID:           r.ChainID,
+		BlockNumber:       r.BlockNumber,
+		Timestamp:         ts,
+		BaseFee:           baseFee,
+		BaseFeeMultiplier: r.BaseFeeMultiplier,
+		ValidUntil:        validUntil,
+		Version:           version,
+		UpdatedAt:         updatedAt,
+		Network: estimator.Network{
+			Name:           r.Network.Name,
+			CurrencySymbol: r.Network.CurrencySymbol,
+			BlockTime:      time.Duration(r.Network.BlockTimeMs) * time.Millisecond,
+		},
+		Strategy: r.Strategy,
+		Degraded: r.Degraded,
+		Samples: estimator.SampleSizes{
+			Blocks:       r.Samples.Blocks,
+			Historical:   r.Samples.Historical,
+			Mempool:      r.Samples.Mempool,
+			PendingBlock: r.Samples.PendingBlock,
+		},
+	}
+	for _, c := range r.Network.FeeCurrencies {
+		est.Network.FeeCurrencies = append(est.Network.FeeCurrencies, estimator.FeeCurrency{
+			Symbol:   c.Symbol,
+			Address:  c.Address,
+			Decimals: c.Decimals,
+		})
+	}
+	levels := []struct {
+		name string
+		in   EstimateLevel
+		out  *estimator.PriorityEstimate
+	}{
+		{"urgent", r.Estimates.Urgent, &est.Urgent},
+		{"fast", r.Estimates.Fast, &est.Fast},
+		{"standard", r.Estimates.Standard, &est.Standard},
+		{"slow", r.Estimates.Slow, &est.Slow},
+	}
+	for _, l := range levels {
+		p, err := l.in.priority()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", l.name, err)
+		}
+		*l.out = p
+	}
+
+	if r.Signature != nil {
+		// Passed through as is; VerifyEstimate checks it against the fields
+		pub, err1 := hex.DecodeString(strings.TrimPrefix(r.Signature.PublicKey, "0x"))
+		sig, err2 := hex.DecodeString(strings.TrimPrefix(r.Signature.Signature, "0x"))
+		if err := errors.Join(err1, err2); err != nil {
+			return nil, fmt.Errorf("signature: %w", err)
+		}
+		est.Signature = &estimator.Signature{
+			Algorithm: r.Signature.Algorithm,
+			PublicKey: pub,
+			Value:     sig,
+		}
+	}
+
+	if r.Distribution != nil {
+		est.Distribution = &estimator.FeeDistribution{}
+		if est.Distribution.Historical, err = fromCurve(r.Distribution.Historical); err != nil {
+			return nil, fmt.Errorf("distribution: %w", err)
+		}
+		if est.Distribution.Mempool, err = fromCurve(r.Distribution.Mempool); err != nil {
+			return nil, fmt.Errorf("distribution: %w", err)
+		}
+	}
+	return est, nil
+}
+
+func (l EstimateLevel) priority() (estimator.PriorityEstimate, error) {
+	tip, err := uint256.FromDecimal(l.MaxPriorityFeePerGas)
+	if err != nil {
+		return estimator.PriorityEstimate{}, fmt.Errorf("max_priority_fee_per_gas: %w", err)
+	}
+	maxFee, err := uint256.FromDecimal(l.MaxFeePerGas)
+	if err != nil {
+		return estimator.PriorityEstimate{}, fmt.Errorf("max_fee_per_gas: %w", err)
+	}
+	p := estimator.PriorityEstimate{
+		MaxPriorityFeePerGas: tip,
+		MaxFeePerGas:         maxFee,
+		Confidence:           l.Confidence,
+	}
+	for _, source := range l.Sources {
+		p.Sources = append(p.Sources, estimator.FeeSource(source))
+	}
+	return p, nil
+}
+
+func fromCurve(points []PercentilePoint) ([]*uint256.Int, error) {
+	if len(points) == 0 {
+		return nil, nil
+	}
+	values := make([]*uint256.Int, len(points))
+	for i, p := range points {
+		v, err := uint256.FromDecimal(p.MaxPriorityFee)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func toLevel(p estimator.PriorityEstimate, baseFee *uint256.Int) EstimateLevel {
+	level := EstimateLevel{
+		MaxPriorityFeePerGas: p.MaxPriorityFeePerGas.String(),
+		MaxFeePerGas:         p.MaxFeePerGas.String(),
+		Confidence:           p.Confidence,
+		GasPrice:             p.GasPrice(baseFee).String(),
+	}
+	for _, source := range p.Sources {
+		level.Sources = append(level.Sources, string(source))
+	}
+	return level
+}
+
 // handleStream provides server-sent events for estimate updates.
+//
+// By default an event is sent whenever the block number changes. Consumers that
+// cache estimates can instead request changed-only delivery with thresholds:
+//
+//	min_change_pct      relative change in any tier's priority fee (e.g. 5 = 5%)
+//	min_base_fee_delta  absolute change in base fee, in wei
+//
+// When either parameter is set, events are sent only when the estimate moves
+// beyond the threshold relative to the last event delivered on this stream.
+//
+// When the server shuts down, a final "shutdown" event is sent before the
+// stream is closed so clients can reconnect elsewhere instead of treating the
+// disconnect as an error.
 func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -190,39 +1021,63 @@ func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	select {
+	case <-s.draining:
+		s.writeError(w, http.StatusServiceUnavailable, "server shutting down")
+		return
+	default:
+	}
+
+	threshold, thresholdSet, err := parseChangeThreshold(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 
+	// Streams outlive the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
 	ctx := r.Context()
 	ticker := time.NewTicker(200 * time.Millisecond)
 	defer ticker.Stop()
 
-	var lastBlock uint64
+	var last *estimator.GasEstimate
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-s.draining:
+			fmt.Fprint(w, "event: shutdown\ndata: {\"reason\":\"server shutting down\"}\n\n")
+			flusher.Flush()
+			return
 		case <-ticker.C:
-			est, err := s.provider.Current(ctx)
+			est, _, err := s.current(ctx)
 			if err != nil {
 				continue
 			}
 
-			// Only send if block changed
-			if est.BlockNumber == lastBlock {
+			if thresholdSet {
+				if !estimator.Changed(last, est, threshold) {
+					continue
+				}
+			} else if last != nil && est.BlockNumber == last.BlockNumber {
+				// Only send if block changed
 				continue
 			}
-			lastBlock = est.BlockNumber
-
-			data, _ := json.Marshal(map[string]any{
-				"block_number": est.BlockNumber,
-				"base_fee":     est.BaseFee.String(),
-				"urgent":       est.Urgent.MaxPriorityFeePerGas.String(),
-				"fast":         est.Fast.MaxPriorityFeePerGas.String(),
-				"standard":     est.Standard.MaxPriorityFeePerGas.String(),
-				"slow":         est.Slow.MaxPriorityFeePerGas.String(),
+			last = est
+
+			data, _ := json.Marshal(StreamUpdate{
+				BlockNumber: est.BlockNumber,
+				BaseFee:     est.BaseFee.String(),
+				Urgent:      est.Urgent.MaxPriorityFeePerGas.String(),
+				Fast:        est.Fast.MaxPriorityFeePerGas.String(),
+				Standard:    est.Standard.MaxPriorityFeePerGas.String(),
+				Slow:        est.Slow.MaxPriorityFeePerGas.String(),
 			})
 
 			fmt.Fprintf(w, "data: %s\n\n", data)
@@ -231,9 +1086,47 @@ func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (s *Server) writeError(w http.ResponseWriter, status int, message string) {
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]string{
-		"error": message,
-	})
+// StreamUpdate is the data of each estimate stream event: the base fee and
+// each tier's priority fee, in wei.
+type StreamUpdate struct {
+	BlockNumber uint64 `json:"block_number"`
+	BaseFee     string `json:"base_fee"`
+	Urgent      string `json:"urgent"`
+	Fast        string `json:"fast"`
+	Standard    string `json:"standard"`
+	Slow        string `json:"slow"`
+}
+
+// parseChangeThreshold reads changed-only streaming thresholds from the query string.
+// The boolean result reports whether any threshold was supplied; a fee without
+// one is ignored.
+func parseChangeThreshold(r *http.Request) (estimator.ChangeThreshold, bool, error) {
+	var th estimator.ChangeThreshold
+	q := r.URL.Query()
+	set := false
+
+	if v := q.Get("min_change_pct"); v != "" {
+		pct, err := strconv.ParseFloat(v, 64)
+		if err != nil || pct < 0 || math.IsNaN(pct) || math.IsInf(pct, 0) {
+			return th, false, fmt.Errorf("invalid min_change_pct: %q", v)
+		}
+		th.PriorityFeePercent = pct
+		set = true
+	}
+
+	if v := q.Get("min_base_fee_delta"); v != "" {
+		delta, err := uint256.FromDecimal(v)
+		if err != nil {
+			return th, false, fmt.Errorf("invalid min_base_fee_delta: %q", v)
+		}
+		th.BaseFeeDelta = delta
+		set = true
+	}
+
+	// A threshold for only one of the fees leaves the other out; the base
+	// fee alone moves on nearly every block
+	th.IgnoreBaseFee = q.Get("min_base_fee_delta") == ""
+	th.IgnorePriorityFee = q.Get("min_change_pct") == ""
+
+	return th, set, nil
 }
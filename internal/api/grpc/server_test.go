@@ -0,0 +1,148 @@
+package grpc
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+	"github.com/holiman/uint256"
+)
+
+// newTestServer returns a server publishing one estimate for block 100 on
+// chain 1.
+func newTestServer(t *testing.T, opts ...Option) (*Server, *estimator.Provider) {
+	t.Helper()
+	provider := estimator.NewProvider(estimator.WithRenderer(RenderEstimate))
+	tier := func(tip uint64) estimator.PriorityEstimate {
+		return estimator.PriorityEstimate{
+			MaxPriorityFeePerGas: uint256.NewInt(tip),
+			MaxFeePerGas:         uint256.NewInt(2e10 + tip),
+			Confidence:           0.5,
+		}
+	}
+	provider.Update(&estimator.GasEstimate{
+		ChainID:     1,
+		BlockNumber: 100,
+		Timestamp:   time.Now(),
+		BaseFee:     uint256.NewInt(1e10),
+		Urgent:      tier(4e9),
+		Fast:        tier(3e9),
+		Standard:    tier(2e9),
+		Slow:        tier(1e9),
+	})
+	return NewServer(":0", provider, slog.New(slog.NewTextHandler(io.Discard, nil)), opts...), provider
+}
+
+// serve sends a request through the server's full handler chain.
+func serve(s *Server, method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestParseChangeThreshold(t *testing.T) {
+	tests := []struct {
+		query                   string
+		set                     bool
+		ignoreBase, ignoreTiers bool
+	}{
+		{query: "", set: false, ignoreBase: true, ignoreTiers: true},
+		{query: "min_change_pct=5", set: true, ignoreBase: true},
+		{query: "min_base_fee_delta=1000", set: true, ignoreTiers: true},
+		{query: "min_change_pct=5&min_base_fee_delta=1000", set: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			th, set, err := parseChangeThreshold(httptest.NewRequest("GET", "/v1/gas/estimate/stream?"+tt.query, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if set != tt.set || th.IgnoreBaseFee != tt.ignoreBase || th.IgnorePriorityFee != tt.ignoreTiers {
+				t.Errorf("set = %v, IgnoreBaseFee = %v, IgnorePriorityFee = %v; want %v, %v, %v",
+					set, th.IgnoreBaseFee, th.IgnorePriorityFee, tt.set, tt.ignoreBase, tt.ignoreTiers)
+			}
+		})
+	}
+}
+
+func TestParseChangeThreshold_Invalid(t *testing.T) {
+	s, _ := newTestServer(t)
+	for _, query := range []string{
+		"min_change_pct=abc",
+		"min_change_pct=-1",
+		"min_change_pct=NaN",
+		"min_change_pct=Inf",
+		"min_change_pct=-Inf",
+		"min_base_fee_delta=-5",
+	} {
+		t.Run(query, func(t *testing.T) {
+			if _, _, err := parseChangeThreshold(httptest.NewRequest("GET", "/v1/gas/estimate/stream?"+query, nil)); err == nil {
+				t.Error("expected an error")
+			}
+			if rec := serve(s, "GET", "/v1/gas/estimate/stream?"+query, "", nil); rec.Code != 400 {
+				t.Errorf("status = %d, want 400", rec.Code)
+			}
+		})
+	}
+}
+
+func TestStream_Shutdown(t *testing.T) {
+	s, _ := newTestServer(t)
+	srv := httptest.NewServer(s.server.Handler)
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Get(srv.URL + "/v1/gas/estimate/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	// readEvent returns the next event's lines, without the blank line
+	lines := bufio.NewScanner(resp.Body)
+	readEvent := func() []string {
+		var event []string
+		for lines.Scan() {
+			if lines.Text() == "" {
+				return event
+			}
+			event = append(event, lines.Text())
+		}
+		return event
+	}
+	if event := readEvent(); len(event) != 1 || !strings.HasPrefix(event[0], "data: ") {
+		t.Fatalf("first event = %q, want an estimate", event)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"event: shutdown", `data: {"reason":"server shutting down"}`}
+	if event := readEvent(); strings.Join(event, "\n") != strings.Join(want, "\n") {
+		t.Errorf("event after Shutdown = %q, want %q", event, want)
+	}
+	if lines.Scan() {
+		t.Errorf("stream continued after the shutdown event: %q", lines.Text())
+	}
+
+	// New streams are refused while draining
+	if rec := serve(s, "GET", "/v1/gas/estimate/stream", "", nil); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("new stream status = %d, want 503", rec.Code)
+	}
+}
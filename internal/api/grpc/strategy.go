@@ -0,0 +1,96 @@
+package grpc
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+)
+
+// WithStrategy enables GET /v1/strategy, which reports evaluator's
+// strategy and its parameters. With overrides, GET
+// /v1/strategy/evaluate also recalculates the latest estimate's input with
+// the parameters given in the query, e.g. ?historical_weight=0.7, without
+// changing what is published.
+func WithStrategy(evaluator estimator.StrategyEvaluator, overrides bool) Option {
+	return func(s *Server) {
+		s.strategy = evaluator
+		s.strategyOverrides = overrides
+	}
+}
+
+// StrategyResponse is the /v1/strategy response format. Params is empty
+// for custom strategies that don't list their parameters.
+type StrategyResponse struct {
+	Name   string         `json:"name"`
+	Params map[string]any `json:"params,omitempty"`
+}
+
+// StrategyEvaluationResponse is the /v1/strategy/evaluate response format:
+// the estimate the strategy with Overrides applied calculates from the
+// same input as Published, the latest published estimate.
+type StrategyEvaluationResponse struct {
+	Strategy  StrategyResponse    `json:"strategy"`
+	Overrides map[string]string   `json:"overrides"`
+	Published GasEstimateResponse `json:"published"`
+	Evaluated GasEstimateResponse `json:"evaluated"`
+}
+
+func toStrategy(strategy estimator.Strategy) StrategyResponse {
+	resp := StrategyResponse{Name: strategy.Name()}
+	if t, ok := strategy.(estimator.TunableStrategy); ok {
+		resp.Params = t.Params()
+	}
+	return resp
+}
+
+func (s *Server) handleStrategy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(toStrategy(s.strategy.Strategy()))
+}
+
+// handleStrategyEvaluate evaluates the strategy with the query parameters
+// as overrides; api_key is reserved for tenant authentication.
+func (s *Server) handleStrategyEvaluate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	overrides := make(map[string]string)
+	for name, values := range r.URL.Query() {
+		if name != "api_key" {
+			overrides[name] = values[len(values)-1]
+		}
+	}
+	strategy := s.strategy.Strategy()
+	if len(overrides) > 0 {
+		tunable, ok := strategy.(estimator.TunableStrategy)
+		if !ok {
+			s.writeError(w, http.StatusNotImplemented, "strategy "+strategy.Name()+" has no tunable parameters")
+			return
+		}
+		var err error
+		if strategy, err = tunable.WithParams(overrides); err != nil {
+			s.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	ev, err := s.strategy.Evaluate(r.Context(), strategy)
+	if err != nil {
+		s.writeEstimateError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(StrategyEvaluationResponse{
+		Strategy:  toStrategy(strategy),
+		Overrides: overrides,
+		Published: toResponse(ev.Published),
+		Evaluated: toResponse(ev.Evaluated),
+	})
+}
@@ -0,0 +1,313 @@
+package grpc
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/branched-services/go-gas/internal/observability"
+	"github.com/branched-services/go-gas/pkg/estimator"
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+// Node simulates transactions for POST /v1/gas/suggest. *eth.Client
+// implements it.
+type Node interface {
+	EstimateGas(ctx context.Context, msg eth.CallMsg) (uint64, error)
+	PendingNonce(ctx context.Context, address string) (uint64, error)
+}
+
+// AccessListNode is a Node that also builds access lists, enabling
+// access_list on POST /v1/gas/suggest. *eth.Client implements it.
+type AccessListNode interface {
+	Node
+	CreateAccessList(ctx context.Context, msg eth.CallMsg) (eth.AccessList, uint64, error)
+}
+
+// WithNode sets the node used for POST /v1/gas/suggest, fee currency
+// rates and the node health in /v1/chain/status.
+func WithNode(node Node) Option {
+	return func(s *Server) {
+		s.node = node
+	}
+}
+
+// WithSuggest enables POST /v1/gas/suggest, which prices a full unsigned
+// transaction using the node given to WithNode. Each request makes up to
+// three node calls, which spend from budget (nil is unlimited).
+func WithSuggest(budget *estimator.NodeLimiter) Option {
+	return func(s *Server) {
+		s.suggest = true
+		s.nodeBudget = budget
+	}
+}
+
+// SuggestRequest is an unsigned transaction to price. To is empty for
+// contract creation, with the init code in Data. Gas skips eth_estimateGas
+// when set. Tier selects the tier to price at (default "standard");
+// alternatively, Within picks the cheapest tier expected to be included
+// within that wait, as a Go duration. AccessList asks for an EIP-2930
+// access list to be generated (eth_createAccessList) and used if it lowers
+// the estimated gas; it needs Gas unset.
+type SuggestRequest struct {
+	From       string `json:"from,omitempty"`
+	To         string `json:"to,omitempty"`
+	Data       string `json:"data,omitempty"`
+	Value      string `json:"value,omitempty"`
+	Gas        uint64 `json:"gas,omitempty"`
+	Tier       string `json:"tier,omitempty"`
+	Within     string `json:"within,omitempty"`
+	AccessList bool   `json:"access_list,omitempty"`
+}
+
+// SuggestResponse is a fee suggestion for a transaction: the fields to
+// sign it with, and its cost. Nonce is set when the request has a from
+// address. GasEstimated reports whether GasLimit came from eth_estimateGas
+// rather than the request; estimates for contract creation (Deployment)
+// include a safety margin. IntrinsicGas is the gas charged before
+// execution, the least gas limit the transaction can be sent with.
+// AccessList is set when the request asked for one and it lowers the gas,
+// to GasWithoutAccessList from GasLimit. Generation identifies the
+// estimate it was priced from (see If-Generation-Match).
+type SuggestResponse struct {
+	ChainID              uint64         `json:"chain_id"`
+	BlockNumber          uint64         `json:"block_number"`
+	Generation           string         `json:"generation"`
+	Tier                 string         `json:"tier"`
+	GasLimit             uint64         `json:"gas_limit"`
+	GasEstimated         bool           `json:"gas_estimated"`
+	IntrinsicGas         uint64         `json:"intrinsic_gas"`
+	Deployment           bool           `json:"deployment,omitempty"`
+	AccessList           eth.AccessList `json:"access_list,omitempty"`
+	GasWithoutAccessList uint64         `json:"gas_without_access_list,omitempty"`
+	MaxFeePerGas         string         `json:"max_fee_per_gas"`
+	MaxPriorityFeePerGas string         `json:"max_priority_fee_per_gas"`
+	Nonce                *uint64        `json:"nonce,omitempty"`
+	Cost                 TxCost         `json:"cost"`
+	ValidUntil           string         `json:"valid_until,omitempty" format:"date-time"`
+}
+
+// handleSuggest prices the transaction in the request body: it estimates
+// its gas, picks a tier, sizes the tier's fees for that much gas and looks
+// up the sender's next nonce. Honors If-Generation-Match.
+func (s *Server) handleSuggest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req SuggestRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	msg, err := req.callMsg()
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var lister AccessListNode
+	if req.AccessList {
+		var ok bool
+		if lister, ok = s.node.(AccessListNode); !ok {
+			s.writeError(w, http.StatusNotImplemented, "access lists are not supported by the node")
+			return
+		}
+	}
+
+	est := s.requestedEstimate(w, r)
+	if est == nil {
+		return
+	}
+	tier, err := req.tier(est)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeouts.Node)
+	defer cancel()
+
+	resp := SuggestResponse{
+		ChainID:      est.ChainID,
+		BlockNumber:  est.BlockNumber,
+		Generation:   generationToken(est),
+		Tier:         tier,
+		GasLimit:     req.Gas,
+		IntrinsicGas: eth.IntrinsicGas(msg.Data, msg.To == ""),
+		Deployment:   msg.To == "",
+		ValidUntil:   formatTime(est.ValidUntil),
+	}
+	if resp.GasLimit != 0 && resp.GasLimit < resp.IntrinsicGas {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("gas %d is below the intrinsic gas %d", resp.GasLimit, resp.IntrinsicGas))
+		return
+	}
+	if resp.GasLimit == 0 {
+		if !s.spendNodeBudget(ctx, w) {
+			return
+		}
+		if resp.GasLimit, err = s.node.EstimateGas(ctx, msg); err != nil {
+			s.writeNodeError(w, r, "eth_estimateGas", err)
+			return
+		}
+		resp.GasEstimated = true
+		if lister != nil {
+			if !s.spendNodeBudget(ctx, w) {
+				return
+			}
+			list, _, err := lister.CreateAccessList(ctx, msg)
+			if err != nil {
+				s.writeNodeError(w, r, "eth_createAccessList", err)
+				return
+			}
+			msg.AccessList = list
+			if !s.spendNodeBudget(ctx, w) {
+				return
+			}
+			gas, err := s.node.EstimateGas(ctx, msg)
+			if err != nil {
+				s.writeNodeError(w, r, "eth_estimateGas", err)
+				return
+			}
+			// A list can cost more than it saves, e.g. for slots read once
+			if gas < resp.GasLimit {
+				resp.AccessList, resp.GasWithoutAccessList, resp.GasLimit = list, resp.GasLimit, gas
+				resp.IntrinsicGas += list.IntrinsicGas()
+			}
+		}
+		if resp.Deployment {
+			resp.GasLimit += resp.GasLimit * deploymentGasMargin / 100
+		}
+	}
+	if req.From != "" {
+		if !s.spendNodeBudget(ctx, w) {
+			return
+		}
+		nonce, err := s.node.PendingNonce(ctx, req.From)
+		if err != nil {
+			s.writeNodeError(w, r, "eth_getTransactionCount", err)
+			return
+		}
+		resp.Nonce = &nonce
+	}
+
+	// Larger transactions must outbid more of the pending demand
+	sized, err := est.ForGasAmount(resp.GasLimit)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if enforcer, ok := s.provider.(estimator.FeeCapEnforcer); ok {
+		enforcer.EnforceCaps(sized)
+	}
+	p, _ := sized.Tier(tier)
+	resp.MaxFeePerGas = p.MaxFeePerGas.String()
+	resp.MaxPriorityFeePerGas = p.MaxPriorityFeePerGas.String()
+	resp.Cost = *txCost(sized.BaseFee, p, resp.GasLimit, s.quote(r.Context()))
+
+	w.Header().Set(generationHeader, resp.Generation)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// spendNodeBudget waits for budget for one node call, writing a 503 if
+// the request's node deadline passes first.
+func (s *Server) spendNodeBudget(ctx context.Context, w http.ResponseWriter) bool {
+	if err := s.nodeBudget.Wait(ctx, 1); err != nil {
+		w.Header().Set("Retry-After", "1")
+		s.writeError(w, http.StatusServiceUnavailable, "node request budget exhausted")
+		return false
+	}
+	return true
+}
+
+// writeNodeError reports a failed node call: 422 when the node rejected the
+// transaction, e.g. because it reverts, and 502 when the node is unreachable.
+func (s *Server) writeNodeError(w http.ResponseWriter, r *http.Request, method string, err error) {
+	if eth.IsNodeError(err) {
+		s.writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("%s: %v", method, err))
+		return
+	}
+	observability.WithContext(r.Context(), s.logger).Warn("node call failed", "method", method, "error", err)
+	s.writeError(w, http.StatusBadGateway, "node unavailable")
+}
+
+// deploymentGasMargin is the percentage added to eth_estimateGas results
+// for contract creation. A constructor's gas depends on the state it runs
+// against, which can change between estimation and inclusion, and an
+// out-of-gas deployment still pays for all of its gas.
+const deploymentGasMargin = 10
+
+// callMsg validates the transaction fields of req.
+func (req *SuggestRequest) callMsg() (eth.CallMsg, error) {
+	msg := eth.CallMsg{From: req.From, To: req.To, Gas: req.Gas}
+	for _, addr := range []struct{ name, value string }{{"from", req.From}, {"to", req.To}} {
+		if addr.value != "" && !isAddress(addr.value) {
+			return msg, fmt.Errorf("invalid %s: %q", addr.name, addr.value)
+		}
+	}
+	if req.Data != "" {
+		data, err := hex.DecodeString(strings.TrimPrefix(req.Data, "0x"))
+		if err != nil || !strings.HasPrefix(req.Data, "0x") {
+			return msg, errors.New("invalid data: must be 0x-prefixed hex")
+		}
+		msg.Data = data
+	}
+	if req.To == "" && len(msg.Data) == 0 {
+		return msg, errors.New("to or data is required")
+	}
+	if req.To == "" && len(msg.Data) > eth.MaxInitCodeSize {
+		return msg, fmt.Errorf("init code is %d bytes, over the %d byte limit", len(msg.Data), eth.MaxInitCodeSize)
+	}
+	if req.AccessList && req.Gas != 0 {
+		return msg, errors.New("access_list and gas are mutually exclusive")
+	}
+	if req.Value != "" {
+		value, err := uint256.FromDecimal(req.Value)
+		if err != nil {
+			return msg, errors.New("invalid value: must be a decimal amount of wei")
+		}
+		msg.Value = value
+	}
+	return msg, nil
+}
+
+// tier resolves the tier requested by name or by wait.
+func (req *SuggestRequest) tier(est *estimator.GasEstimate) (string, error) {
+	switch {
+	case req.Tier != "" && req.Within != "":
+		return "", errors.New("tier and within are mutually exclusive")
+	case req.Within != "":
+		wait, err := time.ParseDuration(req.Within)
+		if err != nil || wait <= 0 {
+			return "", fmt.Errorf("invalid within: %q", req.Within)
+		}
+		tier, err := est.TierWithin(wait)
+		if err != nil {
+			return "", fmt.Errorf("within: %w", err)
+		}
+		return tier, nil
+	case req.Tier == "":
+		return "standard", nil
+	}
+	if _, ok := est.Tier(req.Tier); !ok {
+		return "", fmt.Errorf("invalid tier: %q (one of %s)", req.Tier, strings.Join(estimator.TierNames[:], ", "))
+	}
+	return req.Tier, nil
+}
+
+// isAddress reports whether s is a 0x-prefixed 20-byte hex address.
+func isAddress(s string) bool {
+	if len(s) != 42 || !strings.HasPrefix(s, "0x") {
+		return false
+	}
+	_, err := hex.DecodeString(s[2:])
+	return err == nil
+}
@@ -0,0 +1,98 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+	"github.com/branched-services/go-gas/pkg/eth"
+)
+
+// fakeNode answers every call with fixed values and counts the calls.
+type fakeNode struct {
+	calls atomic.Int32
+}
+
+func (n *fakeNode) EstimateGas(ctx context.Context, msg eth.CallMsg) (uint64, error) {
+	n.calls.Add(1)
+	return 50000, nil
+}
+
+func (n *fakeNode) PendingNonce(ctx context.Context, address string) (uint64, error) {
+	n.calls.Add(1)
+	return 7, nil
+}
+
+const suggestBody = `{"from":"0x00000000000000000000000000000000000000aa","to":"0x00000000000000000000000000000000000000bb"}`
+
+func TestSuggest_Disabled(t *testing.T) {
+	node := &fakeNode{}
+	s, _ := newTestServer(t, WithNode(node))
+	if rec := serve(s, http.MethodPost, "/v1/gas/suggest", suggestBody, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 without WithSuggest", rec.Code)
+	}
+	if n := node.calls.Load(); n != 0 {
+		t.Errorf("node got %d calls", n)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	node := &fakeNode{}
+	s, _ := newTestServer(t, WithNode(node), WithSuggest(nil))
+	rec := serve(s, http.MethodPost, "/v1/gas/suggest", suggestBody, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var resp SuggestResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.GasLimit != 50000 || !resp.GasEstimated || resp.Nonce == nil || *resp.Nonce != 7 {
+		t.Errorf("suggestion = gas %d (estimated %v), nonce %v", resp.GasLimit, resp.GasEstimated, resp.Nonce)
+	}
+}
+
+func TestSuggest_NodeBudget(t *testing.T) {
+	node := &fakeNode{}
+	// A burst of one call; another waits a second, beyond the node timeout
+	budget := estimator.NewNodeLimiter(1)
+	s, _ := newTestServer(t, WithNode(node), WithSuggest(budget), WithTimeouts(Timeouts{Node: 20 * time.Millisecond}))
+
+	rec := serve(s, http.MethodPost, "/v1/gas/suggest", suggestBody, nil)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("503 lacks Retry-After")
+	}
+	if n := node.calls.Load(); n != 1 {
+		t.Errorf("node got %d calls, want the 1 within budget", n)
+	}
+
+	// Requests that need no node calls don't wait for budget
+	rec = serve(s, http.MethodPost, "/v1/gas/suggest", `{"to":"0x00000000000000000000000000000000000000bb","gas":21000}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Errorf("status without node calls = %d, want 200", rec.Code)
+	}
+}
+
+func TestSuggest_SharedNodeBudget(t *testing.T) {
+	node := &fakeNode{}
+	budget := estimator.NewNodeLimiter(1)
+	s, _ := newTestServer(t, WithNode(node), WithSuggest(budget), WithTimeouts(Timeouts{Node: 20 * time.Millisecond}))
+
+	// The estimator spends the budget first
+	if err := budget.Wait(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if rec := serve(s, http.MethodPost, "/v1/gas/suggest", suggestBody, nil); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+	if n := node.calls.Load(); n != 0 {
+		t.Errorf("node got %d calls, want none", n)
+	}
+}
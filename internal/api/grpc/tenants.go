@@ -0,0 +1,272 @@
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/branched-services/go-gas/internal/tenant"
+)
+
+// apiKeyHeader carries a tenant's API key. Clients that can't set headers,
+// such as browser EventSource, may use the api_key query parameter instead.
+const apiKeyHeader = "X-API-Key"
+
+// WithTenants requires an API key on /v1/gas/ endpoints and enforces each
+// tenant's chain access and quotas. A non-empty adminToken also enables
+// /admin/tenants for managing tenants, with "Authorization: Bearer <token>".
+func WithTenants(registry *tenant.Registry, adminToken string) Option {
+	return func(s *Server) {
+		s.tenants = registry
+		s.tenantAdminToken = adminToken
+	}
+}
+
+// TenantRequest is the request body for creating or updating a tenant.
+type TenantRequest struct {
+	ID          string                  `json:"id"`
+	Name        string                  `json:"name,omitempty"`
+	Chains      []uint64                `json:"chains,omitempty"`
+	Quota       tenant.Quota            `json:"quota"`
+	ChainQuotas map[uint64]tenant.Quota `json:"chain_quotas,omitempty"`
+	Disabled    bool                    `json:"disabled,omitempty"`
+}
+
+// TenantResponse describes a tenant and its usage. APIKey is only returned
+// when a key is issued.
+type TenantResponse struct {
+	TenantRequest
+	APIKey string      `json:"api_key,omitempty"`
+	Usage  TenantUsage `json:"usage"`
+}
+
+// TenantUsage is a tenant's request counts since the server started.
+// MinuteUsed and DayUsed are the requests counted against the current quota
+// windows.
+type TenantUsage struct {
+	Requests        uint64            `json:"requests_total"`
+	QuotaRejected   uint64            `json:"quota_rejected_total"`
+	ChainRejected   uint64            `json:"chain_rejected_total"`
+	RequestsByChain map[uint64]uint64 `json:"requests_by_chain,omitempty"`
+	LastRequest     string            `json:"last_request,omitempty" format:"date-time"`
+	MinuteUsed      int               `json:"minute_used"`
+	DayUsed         int               `json:"day_used"`
+}
+
+// TenantListResponse is the response for listing tenants.
+type TenantListResponse struct {
+	Tenants []TenantResponse `json:"tenants"`
+}
+
+func toTenantResponse(st tenant.Status) TenantResponse {
+	t := st.Tenant
+	return TenantResponse{
+		TenantRequest: TenantRequest{
+			ID:          t.ID,
+			Name:        t.Name,
+			Chains:      t.Chains,
+			Quota:       t.Quota,
+			ChainQuotas: t.ChainQuotas,
+			Disabled:    t.Disabled,
+		},
+		Usage: TenantUsage{
+			Requests:        st.Usage.Requests,
+			QuotaRejected:   st.Usage.QuotaRejected,
+			ChainRejected:   st.Usage.ChainRejected,
+			RequestsByChain: st.Usage.RequestsByChain,
+			LastRequest:     formatTime(st.Usage.LastRequest),
+			MinuteUsed:      st.Usage.MinuteUsed,
+			DayUsed:         st.Usage.DayUsed,
+		},
+	}
+}
+
+func (req TenantRequest) tenant() tenant.Tenant {
+	return tenant.Tenant{
+		ID:          req.ID,
+		Name:        req.Name,
+		Chains:      req.Chains,
+		Quota:       req.Quota,
+		ChainQuotas: req.ChainQuotas,
+		Disabled:    req.Disabled,
+	}
+}
+
+// metered reports whether requests to path need an API key.
+func metered(path string) bool {
+	return strings.HasPrefix(unversioned(path), "/gas/")
+}
+
+// authorizeTenant checks the request's API key and counts it against the
+// tenant's quota, writing a 401, 403 or 429 if it is refused. It returns the
+// tenant ID.
+func (s *Server) authorizeTenant(w http.ResponseWriter, r *http.Request) (string, bool) {
+	key := r.Header.Get(apiKeyHeader)
+	if key == "" {
+		key = r.URL.Query().Get("api_key")
+	}
+	id, ok := s.tenants.Authenticate(key)
+	if !ok {
+		s.writeError(w, http.StatusUnauthorized, "missing or invalid API key")
+		return "", false
+	}
+
+	// The chain is unknown (0) until the first estimate; the endpoint then
+	// answers 503 regardless
+	var chainID uint64
+	if est, _, err := s.current(r.Context()); err == nil {
+		chainID = est.ChainID
+	}
+
+	wait, err := s.tenants.Allow(id, chainID, time.Now())
+	switch {
+	case errors.Is(err, tenant.ErrChainNotAllowed):
+		s.writeError(w, http.StatusForbidden, err.Error())
+		return id, false
+	case errors.Is(err, tenant.ErrQuotaExceeded):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		s.writeError(w, http.StatusTooManyRequests, err.Error())
+		return id, false
+	case err != nil:
+		// Deleted between Authenticate and Allow
+		s.writeError(w, http.StatusUnauthorized, "missing or invalid API key")
+		return id, false
+	}
+	return id, true
+}
+
+// handleTenants lists tenants with their usage (GET) or creates one (POST).
+// The response to POST carries the tenant's API key.
+func (s *Server) handleTenants(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.authorizeBearer(w, r, s.tenantAdminToken) {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		resp := TenantListResponse{Tenants: []TenantResponse{}}
+		for _, st := range s.tenants.List(time.Now()) {
+			resp.Tenants = append(resp.Tenants, toTenantResponse(st))
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	var req TenantRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16384)).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	t := req.tenant()
+	if err := t.Validate(); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	key, err := s.tenants.Create(t)
+	if err != nil {
+		s.writeTenantError(w, err)
+		return
+	}
+
+	st, _ := s.tenants.Get(req.ID, time.Now())
+	resp := toTenantResponse(st)
+	resp.APIKey = key
+	w.Header().Set("Location", "/admin/tenants/"+req.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleTenant returns (GET), updates (PUT) or deletes (DELETE) a tenant.
+// Updates keep the tenant's API keys and usage.
+func (s *Server) handleTenant(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.authorizeBearer(w, r, s.tenantAdminToken) {
+		return
+	}
+
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodDelete:
+		if err := s.tenants.Delete(id); err != nil {
+			s.writeTenantError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+
+	case http.MethodPut:
+		var req TenantRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16384)).Decode(&req); err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		req.ID = id
+		t := req.tenant()
+		if err := t.Validate(); err != nil {
+			s.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := s.tenants.Update(t); err != nil {
+			s.writeTenantError(w, err)
+			return
+		}
+	}
+
+	st, ok := s.tenants.Get(id, time.Now())
+	if !ok {
+		s.writeError(w, http.StatusNotFound, tenant.ErrNotFound.Error())
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(toTenantResponse(st))
+}
+
+// handleTenantKey issues a new API key for a tenant (POST), revoking its
+// previous keys.
+func (s *Server) handleTenantKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.authorizeBearer(w, r, s.tenantAdminToken) {
+		return
+	}
+
+	id := r.PathValue("id")
+	key, err := s.tenants.IssueKey(id)
+	if err != nil {
+		s.writeTenantError(w, err)
+		return
+	}
+
+	st, _ := s.tenants.Get(id, time.Now())
+	resp := toTenantResponse(st)
+	resp.APIKey = key
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// writeTenantError maps registry errors to HTTP statuses. Tenants are
+// validated before reaching the registry, so anything else failed to save.
+func (s *Server) writeTenantError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, tenant.ErrNotFound):
+		s.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, tenant.ErrExists):
+		s.writeError(w, http.StatusConflict, err.Error())
+	default:
+		s.logger.Error("updating tenants", "error", err)
+		s.writeError(w, http.StatusInternalServerError, "saving tenants failed")
+	}
+}
@@ -0,0 +1,81 @@
+package grpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/branched-services/go-gas/internal/tenant"
+)
+
+func TestTenantAdmin(t *testing.T) {
+	registry, _ := tenant.Open("")
+	s, _ := newTestServer(t, WithTenants(registry, "admin-token"))
+	admin := map[string]string{"Authorization": "Bearer admin-token"}
+
+	if rec := serve(s, http.MethodGet, "/admin/tenants", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("list without the admin token: status %d, want 401", rec.Code)
+	}
+
+	// Create issues a key that unlocks the metered endpoints
+	rec := serve(s, http.MethodPost, "/admin/tenants", `{"id":"acme","quota":{"requests_per_minute":1}}`, admin)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Location") != "/admin/tenants/acme" {
+		t.Fatalf("create: status %d, Location %q", rec.Code, rec.Header().Get("Location"))
+	}
+	var created TenantResponse
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if created.APIKey == "" || created.Quota.RequestsPerMinute != 1 {
+		t.Fatalf("create response = %+v", created)
+	}
+	if rec := serve(s, http.MethodPost, "/admin/tenants", `{"id":"acme"}`, admin); rec.Code != http.StatusConflict {
+		t.Errorf("create duplicate: status %d, want 409", rec.Code)
+	}
+	if rec := serve(s, http.MethodPost, "/admin/tenants", `{"id":"Not Valid"}`, admin); rec.Code != http.StatusBadRequest {
+		t.Errorf("create invalid: status %d, want 400", rec.Code)
+	}
+
+	if rec := serve(s, http.MethodGet, "/v1/gas/estimate", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("estimate without a key: status %d, want 401", rec.Code)
+	}
+	key := map[string]string{apiKeyHeader: created.APIKey}
+	if rec := serve(s, http.MethodGet, "/v1/gas/estimate", "", key); rec.Code != http.StatusOK {
+		t.Fatalf("estimate with the key: status %d", rec.Code)
+	}
+	rec = serve(s, http.MethodGet, "/v2/gas/estimate", "", key)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("estimate over quota: status %d, Retry-After %q; want 429 with a wait", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// List reports usage
+	rec = serve(s, http.MethodGet, "/admin/tenants", "", admin)
+	var list TenantListResponse
+	json.Unmarshal(rec.Body.Bytes(), &list)
+	if len(list.Tenants) != 1 || list.Tenants[0].ID != "acme" || list.Tenants[0].APIKey != "" {
+		t.Fatalf("list = %+v, want acme without its key", list)
+	}
+	if u := list.Tenants[0].Usage; u.Requests != 1 || u.QuotaRejected != 1 || u.MinuteUsed != 1 {
+		t.Errorf("usage = %+v, want 1 request, 1 rejected", u)
+	}
+
+	// Issuing a new key revokes the old one
+	rec = serve(s, http.MethodPost, "/admin/tenants/acme/key", "", admin)
+	var rotated TenantResponse
+	json.Unmarshal(rec.Body.Bytes(), &rotated)
+	if rec.Code != http.StatusOK || rotated.APIKey == "" || rotated.APIKey == created.APIKey {
+		t.Fatalf("new key: status %d, key %q", rec.Code, rotated.APIKey)
+	}
+	if rec := serve(s, http.MethodGet, "/v1/gas/estimate", "", key); rec.Code != http.StatusUnauthorized {
+		t.Errorf("estimate with the revoked key: status %d, want 401", rec.Code)
+	}
+
+	// Deleting the tenant revokes its keys
+	if rec := serve(s, http.MethodDelete, "/admin/tenants/acme", "", admin); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status %d", rec.Code)
+	}
+	if rec := serve(s, http.MethodGet, "/v1/gas/estimate", "", map[string]string{apiKeyHeader: rotated.APIKey}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("estimate after delete: status %d, want 401", rec.Code)
+	}
+	if rec := serve(s, http.MethodGet, "/admin/tenants/acme", "", admin); rec.Code != http.StatusNotFound {
+		t.Errorf("get deleted: status %d, want 404", rec.Code)
+	}
+}
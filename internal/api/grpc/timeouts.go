@@ -0,0 +1,41 @@
+package grpc
+
+import "time"
+
+// Timeouts bounds how long API requests wait on their dependencies. Zero
+// fields keep their defaults.
+type Timeouts struct {
+	// Read bounds reading the current estimate from the provider, which is
+	// an in-memory load unless the provider is remote. Default 100ms.
+	Read time.Duration
+
+	// Node bounds the node calls made for one request, e.g. gas estimation
+	// and nonce lookups for POST /v1/gas/suggest. Default 5s.
+	Node time.Duration
+
+	// Write bounds writing a response. Streams and long polls are exempt.
+	// Default 10s.
+	Write time.Duration
+}
+
+// defaultTimeouts are the Timeouts used for fields left zero.
+var defaultTimeouts = Timeouts{
+	Read:  100 * time.Millisecond,
+	Node:  5 * time.Second,
+	Write: 10 * time.Second,
+}
+
+// WithTimeouts sets the API's request timeouts.
+func WithTimeouts(t Timeouts) Option {
+	return func(s *Server) {
+		if t.Read > 0 {
+			s.timeouts.Read = t.Read
+		}
+		if t.Node > 0 {
+			s.timeouts.Node = t.Node
+		}
+		if t.Write > 0 {
+			s.timeouts.Write = t.Write
+		}
+	}
+}
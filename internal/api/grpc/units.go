@@ -0,0 +1,97 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/holiman/uint256"
+)
+
+// gweiDecimals is the number of decimal places in one gwei worth of wei.
+const gweiDecimals = 9
+
+// gweiFormat renders wei amounts as decimal gwei strings, rounded to the
+// nearest multiple of step wei and shown with decimals fractional digits.
+type gweiFormat struct {
+	step     *uint256.Int
+	decimals int
+	trim     bool // drop trailing zeros (no rounding requested)
+}
+
+// parseUnits reads the "unit" and "round" query parameters. unit=gwei adds
+// gwei strings next to the wei values; round is the precision in gwei
+// (e.g. 0.1), exact by default. It returns nil for plain wei responses.
+func parseUnits(r *http.Request) (*gweiFormat, error) {
+	q := r.URL.Query()
+	unit, round := q.Get("unit"), q.Get("round")
+	switch unit {
+	case "", "wei":
+		if round != "" {
+			return nil, errors.New("round requires unit=gwei")
+		}
+		return nil, nil
+	case "gwei":
+	default:
+		return nil, fmt.Errorf("invalid unit: %q (one of wei, gwei)", unit)
+	}
+
+	if round == "" {
+		return &gweiFormat{step: uint256.NewInt(1), decimals: gweiDecimals, trim: true}, nil
+	}
+	whole, frac, _ := strings.Cut(round, ".")
+	if len(frac) > gweiDecimals {
+		return nil, fmt.Errorf("invalid round: %q (at most %d decimal places)", round, gweiDecimals)
+	}
+	step, err := uint256.FromDecimal(whole + frac + strings.Repeat("0", gweiDecimals-len(frac)))
+	if err != nil || step.IsZero() || strings.HasPrefix(round, "+") {
+		return nil, fmt.Errorf("invalid round: %q", round)
+	}
+	return &gweiFormat{step: step, decimals: len(frac)}, nil
+}
+
+// format renders wei in gwei, rounding half up.
+func (f *gweiFormat) format(wei *uint256.Int) string {
+	if wei == nil {
+		return ""
+	}
+	half := new(uint256.Int).Rsh(f.step, 1)
+	v := new(uint256.Int).Add(wei, half)
+	v.Div(v, f.step).Mul(v, f.step)
+
+	gwei := uint256.NewInt(1e9)
+	whole, frac := new(uint256.Int).DivMod(v, gwei, new(uint256.Int))
+	digits := fmt.Sprintf("%09d", frac.Uint64())[:f.decimals]
+	if f.trim {
+		digits = strings.TrimRight(digits, "0")
+	}
+	if digits == "" {
+		return whole.Dec()
+	}
+	return whole.Dec() + "." + digits
+}
+
+// apply sets the gwei fields of resp.
+func (f *gweiFormat) apply(resp *GasEstimateResponse) {
+	resp.BaseFeeGwei = f.format(parseWei(resp.BaseFee))
+	for _, level := range []*EstimateLevel{
+		&resp.Estimates.Urgent,
+		&resp.Estimates.Fast,
+		&resp.Estimates.Standard,
+		&resp.Estimates.Slow,
+	} {
+		level.MaxPriorityFeePerGasGwei = f.format(parseWei(level.MaxPriorityFeePerGas))
+		level.MaxFeePerGasGwei = f.format(parseWei(level.MaxFeePerGas))
+		level.GasPriceGwei = f.format(parseWei(level.GasPrice))
+	}
+}
+
+// parseWei parses a decimal wei string from a response; nil if malformed.
+func parseWei(s string) *uint256.Int {
+	v, err := uint256.FromDecimal(s)
+	if err != nil {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,134 @@
+package grpc
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The HTTP API is versioned by path prefix. A published version is frozen:
+// its responses may gain optional fields but never change shape, so
+// reshaping a response means adding it to a new version. Endpoints whose
+// response is the same in every version are served under each prefix;
+// the default version's are also served without one, e.g. /gas/estimate.
+
+// APIVersion is a major version of the HTTP API.
+type APIVersion string
+
+const (
+	APIVersion1 APIVersion = "v1"
+	APIVersion2 APIVersion = "v2"
+)
+
+// apiVersions lists the versions oldest first; each one's successor is the
+// next.
+var apiVersions = []APIVersion{APIVersion1, APIVersion2}
+
+// apiVersionHeader names the version that served a response.
+const apiVersionHeader = "API-Version"
+
+// ParseAPIVersion returns the API version named s, e.g. "v2".
+func ParseAPIVersion(s string) (APIVersion, error) {
+	for _, v := range apiVersions {
+		if string(v) == s {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("unknown API version %q", s)
+}
+
+// successor returns the version that replaces v, if any.
+func (v APIVersion) successor() (APIVersion, bool) {
+	for i, known := range apiVersions[:len(apiVersions)-1] {
+		if known == v {
+			return apiVersions[i+1], true
+		}
+	}
+	return "", false
+}
+
+// Deprecation announces that an API version is being retired. Its
+// responses carry a Deprecation header (RFC 9745) with Since, a Sunset
+// header (RFC 8594) with Sunset unless it is zero, and a Link to the same
+// endpoint in the successor version when it has one.
+type Deprecation struct {
+	Since  time.Time
+	Sunset time.Time
+}
+
+// WithDefaultVersion also serves version v's endpoints without a version
+// prefix. Default: v1.
+func WithDefaultVersion(v APIVersion) Option {
+	return func(s *Server) {
+		s.defaultVersion = v
+	}
+}
+
+// WithDeprecation marks version v deprecated (see Deprecation).
+func WithDeprecation(v APIVersion, d Deprecation) Option {
+	return func(s *Server) {
+		if s.deprecations == nil {
+			s.deprecations = make(map[APIVersion]Deprecation)
+		}
+		s.deprecations[v] = d
+	}
+}
+
+// handle registers handler for path, relative to the version prefix, in
+// every API version.
+func (s *Server) handle(mux *http.ServeMux, path string, handler http.HandlerFunc) {
+	handlers := make(map[APIVersion]http.HandlerFunc, len(apiVersions))
+	for _, v := range apiVersions {
+		handlers[v] = handler
+	}
+	s.handleVersions(mux, path, handlers)
+}
+
+// handleVersions registers each version's handler for path, relative to
+// the version prefix. Versions without a handler don't serve path.
+func (s *Server) handleVersions(mux *http.ServeMux, path string, handlers map[APIVersion]http.HandlerFunc) {
+	if s.routes == nil {
+		s.routes = make(map[APIVersion]map[string]bool)
+	}
+	for v, handler := range handlers {
+		if s.routes[v] == nil {
+			s.routes[v] = make(map[string]bool)
+		}
+		s.routes[v][path] = true
+		mux.HandleFunc("/"+string(v)+path, s.versioned(v, path, handler))
+		if v == s.defaultVersion {
+			mux.HandleFunc(path, s.versioned(v, path, handler))
+		}
+	}
+}
+
+// versioned wraps handler, which serves path in version v, to label its
+// responses with the version and announce its deprecation.
+func (s *Server) versioned(v APIVersion, path string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(apiVersionHeader, string(v))
+		if d, ok := s.deprecations[v]; ok {
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+			if !d.Sunset.IsZero() {
+				w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if next, ok := v.successor(); ok && s.routes[next][path] {
+				link := "/" + string(next) + unversioned(r.URL.Path)
+				w.Header().Set("Link", "<"+link+`>; rel="successor-version"`)
+			}
+		}
+		handler(w, r)
+	}
+}
+
+// unversioned strips the API version prefix from path, if it has one.
+func unversioned(path string) string {
+	for _, v := range apiVersions {
+		if rest, ok := strings.CutPrefix(path, "/"+string(v)+"/"); ok {
+			return "/" + rest
+		}
+	}
+	return path
+}
@@ -0,0 +1,203 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAPIVersion(t *testing.T) {
+	for _, v := range []APIVersion{APIVersion1, APIVersion2} {
+		if got, err := ParseAPIVersion(string(v)); err != nil || got != v {
+			t.Errorf("ParseAPIVersion(%q) = %q, %v", v, got, err)
+		}
+	}
+	for _, s := range []string{"", "v3", "V1", "1"} {
+		if _, err := ParseAPIVersion(s); err == nil {
+			t.Errorf("ParseAPIVersion(%q) succeeded", s)
+		}
+	}
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	sunset := time.Date(2027, 5, 1, 0, 0, 0, 0, time.UTC)
+	deprecated := WithDeprecation(APIVersion1, Deprecation{Since: time.Unix(1793491200, 0), Sunset: sunset})
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		path       string
+		status     int
+		version    APIVersion
+		deprecated bool
+		link       string
+	}{
+		{name: "v1 estimate", path: "/v1/gas/estimate", status: 200, version: APIVersion1},
+		{name: "v2 estimate", path: "/v2/gas/estimate", status: 200, version: APIVersion2},
+		{name: "shared endpoint in v1", path: "/v1/gas/forecast", status: 200, version: APIVersion1},
+		{name: "shared endpoint in v2", path: "/v2/gas/forecast", status: 200, version: APIVersion2},
+		{name: "v1-only endpoint", path: "/v1/gas/history", status: 200, version: APIVersion1},
+		{name: "v1-only endpoint in v2", path: "/v2/gas/history", status: 404},
+		{name: "unprefixed defaults to v1", path: "/gas/estimate", status: 200, version: APIVersion1},
+		{name: "unprefixed v1-only endpoint", path: "/gas/history", status: 200, version: APIVersion1},
+		{
+			name: "unprefixed with default v2", opts: []Option{WithDefaultVersion(APIVersion2)},
+			path: "/gas/estimate", status: 200, version: APIVersion2,
+		},
+		{
+			name: "v1-only endpoint unprefixed with default v2", opts: []Option{WithDefaultVersion(APIVersion2)},
+			path: "/gas/history", status: 404,
+		},
+		{
+			name: "deprecated estimate", opts: []Option{deprecated},
+			path: "/v1/gas/estimate?unit=gwei", status: 200, version: APIVersion1, deprecated: true,
+			link: `</v2/gas/estimate>; rel="successor-version"`,
+		},
+		{
+			name: "deprecated unprefixed", opts: []Option{deprecated},
+			path: "/gas/forecast", status: 200, version: APIVersion1, deprecated: true,
+			link: `</v2/gas/forecast>; rel="successor-version"`,
+		},
+		{
+			name: "deprecated without a successor endpoint", opts: []Option{deprecated},
+			path: "/v1/gas/history", status: 200, version: APIVersion1, deprecated: true,
+		},
+		{
+			name: "successor not deprecated", opts: []Option{deprecated},
+			path: "/v2/gas/estimate", status: 200, version: APIVersion2,
+		},
+		{
+			name: "errors are labeled", opts: []Option{deprecated},
+			path: "/v2/gas/estimate?forecast_hours=-1", status: 400, version: APIVersion2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t, tt.opts...)
+			rec := serve(s, http.MethodGet, tt.path, "", nil)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			h := rec.Header()
+			if got := h.Get(apiVersionHeader); got != string(tt.version) {
+				t.Errorf("API-Version = %q, want %q", got, tt.version)
+			}
+			if tt.deprecated {
+				if got := h.Get("Deprecation"); got != "@1793491200" {
+					t.Errorf("Deprecation = %q, want @1793491200", got)
+				}
+				if got := h.Get("Sunset"); got != "Sat, 01 May 2027 00:00:00 GMT" {
+					t.Errorf("Sunset = %q", got)
+				}
+			} else if h.Get("Deprecation") != "" || h.Get("Sunset") != "" {
+				t.Errorf("Deprecation, Sunset = %q, %q on a current version", h.Get("Deprecation"), h.Get("Sunset"))
+			}
+			if got := h.Get("Link"); got != tt.link {
+				t.Errorf("Link = %q, want %q", got, tt.link)
+			}
+		})
+	}
+}
+
+func TestDeprecation_NoSunset(t *testing.T) {
+	s, _ := newTestServer(t, WithDeprecation(APIVersion1, Deprecation{Since: time.Unix(1793491200, 0)}))
+	rec := serve(s, http.MethodGet, "/v1/gas/estimate", "", nil)
+	if rec.Header().Get("Deprecation") == "" || rec.Header().Get("Sunset") != "" {
+		t.Errorf("Deprecation, Sunset = %q, %q; want only Deprecation", rec.Header().Get("Deprecation"), rec.Header().Get("Sunset"))
+	}
+}
+
+func TestEstimateV2(t *testing.T) {
+	s, _ := newTestServer(t)
+	rec := serve(s, http.MethodGet, "/v2/gas/estimate?forecast_hours=3", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"block_number", "base_fee", "fee_currency", "estimates", "forecast", "metadata"} {
+		if _, ok := raw[field]; !ok {
+			t.Errorf("v2 response lacks %q", field)
+		}
+	}
+	// Production details moved under metadata
+	for _, field := range []string{"chain_id", "timestamp", "generation", "samples", "estimator_version"} {
+		if _, ok := raw[field]; ok {
+			t.Errorf("v2 response has top-level %q", field)
+		}
+	}
+
+	var resp GasEstimateV2Response
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.BlockNumber != 100 || resp.Estimates.Standard.MaxPriorityFeePerGas != "2000000000" {
+		t.Errorf("estimate = block %d, standard tip %s", resp.BlockNumber, resp.Estimates.Standard.MaxPriorityFeePerGas)
+	}
+	m := resp.Metadata
+	if m.ChainID != 1 || m.Generation == "" || m.CalculatedAt == "" || m.PublishedAt == "" {
+		t.Errorf("metadata = %+v", m)
+	}
+	if len(resp.Forecast) != 3 {
+		t.Errorf("forecast has %d hours, want 3", len(resp.Forecast))
+	}
+
+	// forecast_hours=0 omits the forecast; the default is 6 hours
+	rec = serve(s, http.MethodGet, "/v2/gas/estimate?forecast_hours=0", "", nil)
+	if strings.Contains(rec.Body.String(), `"forecast"`) {
+		t.Error("forecast_hours=0 response has a forecast")
+	}
+	rec = serve(s, http.MethodGet, "/v2/gas/estimate", "", nil)
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if len(resp.Forecast) != defaultV2ForecastHours {
+		t.Errorf("default forecast has %d hours, want %d", len(resp.Forecast), defaultV2ForecastHours)
+	}
+
+	for _, bad := range []string{"-1", "169", "x"} {
+		if rec := serve(s, http.MethodGet, "/v2/gas/estimate?forecast_hours="+bad, "", nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("forecast_hours=%s: status %d, want 400", bad, rec.Code)
+		}
+	}
+}
+
+func TestEstimateV2_ETag(t *testing.T) {
+	s, provider := newTestServer(t, WithCompression(false))
+
+	v1 := serve(s, http.MethodGet, "/v1/gas/estimate", "", nil).Header().Get("ETag")
+	rec := serve(s, http.MethodGet, "/v2/gas/estimate", "", nil)
+	etag := rec.Header().Get("ETag")
+	if etag == "" || etag == v1 {
+		t.Fatalf("v2 ETag %q, want one distinct from v1's %q", etag, v1)
+	}
+
+	// The ETag depends on the forecast horizon
+	if other := serve(s, http.MethodGet, "/v2/gas/estimate?forecast_hours=2", "", nil).Header().Get("ETag"); other == etag {
+		t.Errorf("ETag %q is the same for different forecast horizons", other)
+	}
+
+	rec = serve(s, http.MethodGet, "/v2/gas/estimate", "", map[string]string{"If-None-Match": etag})
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("revalidation: status %d with %d bytes, want an empty 304", rec.Code, rec.Body.Len())
+	}
+	if got := rec.Header().Get(apiVersionHeader); got != "v2" {
+		t.Errorf("304 API-Version = %q, want v2", got)
+	}
+
+	// A v1 ETag doesn't validate a v2 response
+	if rec := serve(s, http.MethodGet, "/v2/gas/estimate", "", map[string]string{"If-None-Match": v1}); rec.Code != http.StatusOK {
+		t.Errorf("v2 request with the v1 ETag: status %d, want 200", rec.Code)
+	}
+
+	// A new estimate changes the ETag
+	est, _ := provider.Current(context.Background())
+	next := *est
+	next.BlockNumber++
+	provider.Update(&next)
+	if rec := serve(s, http.MethodGet, "/v2/gas/estimate", "", map[string]string{"If-None-Match": etag}); rec.Code != http.StatusOK {
+		t.Errorf("revalidation after a new estimate: status %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,146 @@
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/webhook"
+	"github.com/holiman/uint256"
+)
+
+// WithWebhooks enables /v1/webhooks, where consumers register URLs to be
+// notified when a tier's fee crosses a threshold. Requests must carry
+// "Authorization: Bearer <token>"; an empty token leaves the endpoints
+// disabled.
+func WithWebhooks(dispatcher *webhook.Dispatcher, token string) Option {
+	return func(s *Server) {
+		if token == "" {
+			return
+		}
+		s.webhooks = dispatcher
+		s.webhookToken = token
+	}
+}
+
+// WebhookRequest is the request body for registering a webhook.
+type WebhookRequest struct {
+	URL       string `json:"url"`
+	Tier      string `json:"tier"`
+	Field     string `json:"field,omitempty"`
+	Direction string `json:"direction"`
+	Threshold string `json:"threshold"`
+}
+
+// WebhookResponse describes a registered webhook. Secret, the key for
+// verifying delivery signatures, is only returned on registration.
+type WebhookResponse struct {
+	ID        string `json:"id"`
+	URL       string `json:"url"`
+	Tier      string `json:"tier"`
+	Field     string `json:"field"`
+	Direction string `json:"direction"`
+	Threshold string `json:"threshold"`
+	Secret    string `json:"secret,omitempty"`
+	CreatedAt string `json:"created_at" format:"date-time"`
+}
+
+// WebhookListResponse is the response for listing webhooks.
+type WebhookListResponse struct {
+	Webhooks []WebhookResponse `json:"webhooks"`
+}
+
+func toWebhookResponse(hook webhook.Webhook) WebhookResponse {
+	return WebhookResponse{
+		ID:        hook.ID,
+		URL:       hook.URL,
+		Tier:      hook.Condition.Tier,
+		Field:     string(hook.Condition.Field),
+		Direction: string(hook.Condition.Direction),
+		Threshold: hook.Condition.Threshold.Dec(),
+		CreatedAt: hook.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// handleWebhooks lists webhooks (GET) or registers one (POST).
+func (s *Server) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.authorizeBearer(w, r, s.webhookToken) {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		resp := WebhookListResponse{Webhooks: []WebhookResponse{}}
+		for _, hook := range s.webhooks.List() {
+			resp.Webhooks = append(resp.Webhooks, toWebhookResponse(hook))
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	var req WebhookRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	threshold, err := uint256.FromDecimal(req.Threshold)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid threshold: must be a decimal amount of wei")
+		return
+	}
+
+	hook, err := s.webhooks.Register(req.URL, webhook.Condition{
+		Tier:      req.Tier,
+		Field:     webhook.Field(req.Field),
+		Direction: webhook.Direction(req.Direction),
+		Threshold: threshold,
+	})
+	switch {
+	case errors.Is(err, webhook.ErrLimitReached):
+		s.writeProblem(w, http.StatusConflict, CodeResourceExhausted, err.Error())
+		return
+	case err != nil:
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp := toWebhookResponse(hook)
+	resp.Secret = hook.Secret
+	w.Header().Set("Location", "/v1/webhooks/"+hook.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleWebhook returns (GET) or deletes (DELETE) a single webhook.
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.authorizeBearer(w, r, s.webhookToken) {
+		return
+	}
+
+	id := r.PathValue("id")
+	if r.Method == http.MethodDelete {
+		if !s.webhooks.Remove(id) {
+			s.writeError(w, http.StatusNotFound, "webhook not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	hook, ok := s.webhooks.Get(id)
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "webhook not found")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(toWebhookResponse(hook))
+}
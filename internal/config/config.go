@@ -8,46 +8,434 @@ import (
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/branched-services/go-gas/pkg/webhook"
 )
 
 // Config holds all service configuration.
 // All fields are loaded from environment variables with the GAS_ prefix.
 type Config struct {
-	// Node connection (Factor IV: Backing Services)
+	// Node connection (Factor IV: Backing Services). Either URL may be an
+	// IPC endpoint; NodeIPCPath is the default for both. Without a WebSocket
+	// URL, subscriptions are emulated by polling NodeHTTPURL.
 	NodeWSURL   string
 	NodeHTTPURL string
+	NodeIPCPath string
+
+	// Node authentication (optional; applied to both HTTP and WebSocket)
+	NodeAuthHeaders   string
+	NodeBearerToken   string
+	NodeBasicAuth     string
+	NodeJWTSecretFile string
+
+	// Node HTTP client tuning
+	NodeProxyURL              string
+	NodeDialTimeout           time.Duration
+	NodeRequestTimeout        time.Duration
+	NodeBlockTimeout          time.Duration
+	NodeTxFetchTimeout        time.Duration
+	NodeHTTP2                 bool
+	NodeMaxConcurrentRequests int
+
+	// TLS for https and wss node endpoints: a CA bundle to trust instead of
+	// the system roots, a client certificate, a server name overriding the
+	// URL's host, and opt-in skipping of certificate verification. Witnesses
+	// and WebSocket shards only get the CA bundle
+	NodeTLSCAFile             string
+	NodeTLSCertFile           string
+	NodeTLSKeyFile            string
+	NodeTLSServerName         string
+	NodeTLSInsecureSkipVerify bool
+
+	// Estimator node budget: request rate, concurrent pending transaction
+	// batches and their largest size
+	NodeRequestsPerSecond float64
+	PendingFetchBatches   int
+	PendingFetchBatchSize int
+
+	// NodeAutoDetect probes the node for optional RPCs at startup and picks
+	// mempool and history sources accordingly
+	NodeAutoDetect bool
+
+	// NodeDevChain selects the dev-chain profile for local chains such as
+	// Anvil and Hardhat: auto, on or off
+	NodeDevChain string
+
+	// NodeDegradedPollInterval is how often the latest block is polled over
+	// HTTP after the WebSocket subscription is lost (0 = exit instead)
+	NodeDegradedPollInterval time.Duration
+
+	// NodePollInterval is how often the node is polled for new blocks and
+	// pending transactions when NodeWSURL is empty
+	NodePollInterval time.Duration
+
+	// ChainHaltThreshold is how old the head block may get before the
+	// chain counts as halted and the service stops being ready (0 = ten
+	// block times, or 2m when the block time is unknown)
+	ChainHaltThreshold time.Duration
+
+	// Resync guard: estimates are held back and the service stops being
+	// ready while the node reports syncing, polled every
+	// ResyncPollInterval, or after its head rewinds more than
+	// ResyncMaxRewind blocks (0 disables either)
+	ResyncPollInterval time.Duration
+	ResyncMaxRewind    int
+
+	// Node WebSocket keepalive
+	NodeWSPingInterval time.Duration
+	NodeWSPongTimeout  time.Duration
+
+	// WSConnections subscriptions are opened in parallel and merged, spread
+	// round-robin over NodeWSURL and WSShardURLs, so pending transaction
+	// bursts are not dropped by a single connection. The node credentials
+	// apply to NodeWSURL only; WSShardURLs carry their own in the URL
+	WSConnections int
+	WSShardURLs   string
+
+	// InputLogPath is a file the input of every published estimate is
+	// appended to, for rebuilding estimates with "gas-estimator replay"
+	// (empty = disabled)
+	InputLogPath string
+
+	// ReplayPath is an input log (see InputLogPath) to serve estimates
+	// from instead of connecting to a node, for demos and strategy
+	// development; ReplaySpeed accelerates its recorded timing
+	// (0 = as fast as possible)
+	ReplayPath  string
+	ReplaySpeed float64
+
+	// CrossCheckURLs are comma-separated HTTP endpoints of other nodes
+	// that every new head is compared against; the majority's version of a
+	// disputed block is used (empty = disabled). They don't get the node
+	// credentials; a witness's own go in its URL
+	CrossCheckURLs string
 
 	// Server addresses
 	GRPCAddr string
 	HTTPAddr string
+	APIDocs  bool
+
+	// APICompression gzips large estimate, history, forecast and OpenAPI
+	// responses for clients that accept it
+	APICompression bool
+
+	// APIStrategyOverrides enables GET /v1/strategy/evaluate, which
+	// recalculates the latest estimate with strategy parameters given per
+	// request
+	APIStrategyOverrides bool
+
+	// APISuggest enables POST /v1/gas/suggest, which simulates transactions
+	// on the node within the node request budget
+	APISuggest bool
+
+	// API timeouts: reading the current estimate, node calls made for a
+	// request, and writing a response
+	APIReadTimeout  time.Duration
+	APINodeTimeout  time.Duration
+	APIWriteTimeout time.Duration
+
+	// APIDefaultVersion is the API version also served without a version
+	// prefix ("v1" or "v2"). APIV1Deprecation and APIV1Sunset, RFC 3339
+	// times, announce v1's retirement in its Deprecation and Sunset headers
+	// (empty = not deprecated)
+	APIDefaultVersion string
+	APIV1Deprecation  string
+	APIV1Sunset       string
+
+	// DebugToken enables the /debug/estimator endpoint when set.
+	DebugToken string
+
+	// AdminToken enables the /admin/pause, /admin/resume, /admin/recalculate
+	// and /admin/resubscribe control endpoints when set.
+	AdminToken string
+
+	// SigningKeyFile is a PEM Ed25519 or P-256 private key; when set, every
+	// estimate is signed and responses carry the signature
+	SigningKeyFile string
+
+	// Explain logs how every estimate's tiers were derived and shows it in
+	// /debug/estimator.
+	Explain bool
+
+	// WebhookToken enables /v1/webhooks when set.
+	WebhookToken       string
+	WebhookMax         int
+	WebhookMaxAttempts int
+
+	// Multi-tenant mode: API keys with chain access and quotas, kept in
+	// TenantsFile; TenantAdminToken enables /admin/tenants
+	TenantsFile      string
+	TenantAdminToken string
+
+	// Anomaly detection; alerts are also POSTed to AnomalyWebhookURL if set
+	AnomalyDetection        bool
+	AnomalyBaseFeeRatio     float64
+	AnomalyBaseFeeBlocks    int
+	AnomalyMempoolRatio     float64
+	AnomalyPriorityFeeRatio float64
+	AnomalyWebhookURL       string
+	AnomalyWebhookSecret    string
+
+	// Sanity guard: estimates breaking invariants are not published and are
+	// reported to AnomalyWebhookURL if set
+	SanityGuard            bool
+	SanityMaxBaseFeeChange float64
+	SanityCongestedMempool int
+
+	// ExpireEstimates refuses to serve estimates more than ExpiryGrace past
+	// their valid_until (the next expected block)
+	ExpireEstimates bool
+	ExpiryGrace     time.Duration
+
+	// SnapshotBlocks is how many blocks' final estimates are kept for
+	// /v1/gas/history and /v1/gas/estimate?block=N
+	SnapshotBlocks int
+
+	// AccuracyWindows lists the look-back windows /v1/gas/accuracy reports
+	// by default, e.g. "1h,24h"
+	AccuracyWindows string
 
 	// Estimator tuning
-	HistoryBlocks  int
-	MempoolSamples int
-	RecalcInterval time.Duration
+	HistoryBlocks         int
+	HistoryBootstrap      string
+	BlockFeeSamples       int
+	HistoryHalfLife       int
+	HistoryGasWeighted    bool
+	HistoryReceipts       bool
+	MempoolSamples        int
+	MempoolMaxBytes       int
+	RecalcInterval        time.Duration
+	RecalcOnBlock         bool
+	RecalcPendingTxs      int
+	FullPendingTxs        bool
+	MempoolSampling       string
+	MempoolSamplingWindow time.Duration
+	NonceFilter           bool
+	PendingBlock          bool
+	PendingBlockWeight    float64
+
+	// Estimation strategy: "hybrid" or "ensemble"
+	Strategy        string
+	EnsembleCombine string
+	EnsembleWeights string
+
+	// Mempool fee outlier rejection: "none", "iqr" or "mad"
+	OutlierFilter    string
+	OutlierThreshold float64
+
+	// PercentileMethod is how tier percentiles are read from fee samples:
+	// "linear" or "nearest_rank"
+	PercentileMethod string
+
+	// ChainPreset names the built-in preset whose settings are used for
+	// unset variables (see ChainPresets); empty for none
+	ChainPreset string
+
+	// TierPercentiles are the urgent, fast, standard and slow tier
+	// percentiles, e.g. "99,90,50,25"
+	TierPercentiles string
+
+	// Bounds on recommended priority fees in gwei
+	MinPriorityFee float64
+	MaxPriorityFee float64
+
+	// EIP-1559 parameters of the target chain
+	ElasticityMultiplier     uint64
+	BaseFeeChangeDenominator uint64
+
+	// maxFeePerGas buffer: trend projection horizon, or a fixed multiplier
+	BaseFeeHorizon    int
+	BaseFeeMultiplier float64
+
+	// Hard caps on recommended fees in gwei (0 = no cap)
+	MaxPriorityFeeCap float64
+	MaxFeeCap         float64
+
+	// Network labels (optional; override built-in metadata for the chain)
+	NetworkName      string
+	NetworkCurrency  string
+	NetworkBlockTime time.Duration
+
+	// NetworkFeeCurrencies lists the ERC-20 tokens the chain accepts fees
+	// in as comma-separated "symbol:address[:decimals]" entries (decimals
+	// default to 18), replacing the built-in list (empty = built-in)
+	NetworkFeeCurrencies string
+
+	// Fiat price feed (optional; at most one source)
+	PriceFeedURL              string
+	PriceFeedPath             string
+	PriceFeedChainlinkAddress string
+	PriceFeedTTL              time.Duration
+
+	// Warm standby (optional): leader election backend "redis" or
+	// "kubernetes"; empty runs a single active replica
+	HAMode          string
+	HAIdentity      string
+	HALeaseName     string
+	HALeaseTTL      time.Duration
+	HAPollInterval  time.Duration
+	HAAPIKey        string
+	HARedisAddr     string
+	HARedisPassword string
+	HANamespace     string
 
 	// Observability
 	LogLevel  string
 	LogFormat string
+
+	// APIAccessLogLevel is the level of the per-request access log
+	APIAccessLogLevel string
 }
 
 // Load reads configuration from environment variables.
 // All variables are prefixed with GAS_ (e.g., GAS_NODE_WS_URL).
 func Load() (*Config, error) {
+	// Preset values stand in for unset variables while loading
+	preset := os.Getenv("GAS_CHAIN_PRESET")
+	if _, ok := chainPresets[preset]; preset != "" && !ok {
+		return nil, fmt.Errorf("GAS_CHAIN_PRESET must be one of %s", strings.Join(ChainPresets(), ", "))
+	}
+	activePreset = chainPresets[preset]
+	defer func() { activePreset = nil }()
+
+	ipcPath := os.Getenv("GAS_NODE_IPC_PATH")
 	cfg := &Config{
-		// Required fields have no defaults
-		NodeWSURL:   os.Getenv("GAS_NODE_WS_URL"),
-		NodeHTTPURL: os.Getenv("GAS_NODE_HTTP_URL"),
+		// The HTTP URL is required unless an IPC path is given; the
+		// WebSocket URL is optional
+		NodeWSURL:   envOrDefault("GAS_NODE_WS_URL", ipcPath),
+		NodeHTTPURL: envOrDefault("GAS_NODE_HTTP_URL", ipcPath),
+		NodeIPCPath: ipcPath,
+
+		NodeAuthHeaders:   os.Getenv("GAS_NODE_AUTH_HEADERS"),
+		NodeBearerToken:   os.Getenv("GAS_NODE_BEARER_TOKEN"),
+		NodeBasicAuth:     os.Getenv("GAS_NODE_BASIC_AUTH"),
+		NodeJWTSecretFile: os.Getenv("GAS_NODE_JWT_SECRET_FILE"),
+
+		NodeProxyURL:              os.Getenv("GAS_NODE_PROXY_URL"),
+		NodeDialTimeout:           envDurationOrDefault("GAS_NODE_DIAL_TIMEOUT", 10*time.Second),
+		NodeRequestTimeout:        envDurationOrDefault("GAS_NODE_REQUEST_TIMEOUT", 30*time.Second),
+		NodeBlockTimeout:          envDurationOrDefault("GAS_NODE_BLOCK_TIMEOUT", 5*time.Second),
+		NodeTxFetchTimeout:        envDurationOrDefault("GAS_NODE_TX_FETCH_TIMEOUT", 2*time.Second),
+		NodeHTTP2:                 envBoolOrDefault("GAS_NODE_HTTP2", true),
+		NodeMaxConcurrentRequests: envIntOrDefault("GAS_NODE_MAX_CONCURRENT_REQUESTS", 0),
+		NodeTLSCAFile:             os.Getenv("GAS_NODE_TLS_CA_FILE"),
+		NodeTLSCertFile:           os.Getenv("GAS_NODE_TLS_CERT_FILE"),
+		NodeTLSKeyFile:            os.Getenv("GAS_NODE_TLS_KEY_FILE"),
+		NodeTLSServerName:         os.Getenv("GAS_NODE_TLS_SERVER_NAME"),
+		NodeTLSInsecureSkipVerify: envBoolOrDefault("GAS_NODE_TLS_INSECURE_SKIP_VERIFY", false),
+		NodeRequestsPerSecond:     envFloatOrDefault("GAS_NODE_REQUESTS_PER_SECOND", 0),
+		PendingFetchBatches:       envIntOrDefault("GAS_PENDING_FETCH_BATCHES", 1),
+		PendingFetchBatchSize:     envIntOrDefault("GAS_PENDING_FETCH_BATCH_SIZE", 100),
+
+		NodeAutoDetect:           envBoolOrDefault("GAS_NODE_AUTODETECT", true),
+		NodeDevChain:             envOrDefault("GAS_NODE_DEV_CHAIN", "auto"),
+		NodeDegradedPollInterval: envDurationOrDefault("GAS_NODE_DEGRADED_POLL_INTERVAL", 2*time.Second),
+		NodePollInterval:         envDurationOrDefault("GAS_NODE_POLL_INTERVAL", time.Second),
+		ChainHaltThreshold:       envDurationOrDefault("GAS_CHAIN_HALT_THRESHOLD", 0),
+		ResyncPollInterval:       envDurationOrDefault("GAS_RESYNC_POLL_INTERVAL", 15*time.Second),
+		ResyncMaxRewind:          envIntOrDefault("GAS_RESYNC_MAX_REWIND", 64),
+
+		NodeWSPingInterval: envDurationOrDefault("GAS_NODE_WS_PING_INTERVAL", 15*time.Second),
+		NodeWSPongTimeout:  envDurationOrDefault("GAS_NODE_WS_PONG_TIMEOUT", 10*time.Second),
+		WSConnections:      envIntOrDefault("GAS_WS_CONNECTIONS", 1),
+		WSShardURLs:        os.Getenv("GAS_WS_SHARD_URLS"),
+		CrossCheckURLs:     os.Getenv("GAS_CROSSCHECK_URLS"),
+		InputLogPath:       os.Getenv("GAS_INPUT_LOG"),
+		ReplayPath:         os.Getenv("GAS_REPLAY"),
+		ReplaySpeed:        envFloatOrDefault("GAS_REPLAY_SPEED", 10),
 
 		// Optional fields with defaults
-		GRPCAddr:       envOrDefault("GAS_GRPC_ADDR", ":9090"),
-		HTTPAddr:       envOrDefault("GAS_HTTP_ADDR", ":8080"),
-		HistoryBlocks:  envIntOrDefault("GAS_HISTORY_BLOCKS", 20),
-		MempoolSamples: envIntOrDefault("GAS_MEMPOOL_SAMPLES", 500),
-		RecalcInterval: envDurationOrDefault("GAS_RECALC_INTERVAL", 200*time.Millisecond),
-		LogLevel:       envOrDefault("GAS_LOG_LEVEL", "info"),
-		LogFormat:      envOrDefault("GAS_LOG_FORMAT", "json"),
+		GRPCAddr:                  envOrDefault("GAS_GRPC_ADDR", ":9090"),
+		HTTPAddr:                  envOrDefault("GAS_HTTP_ADDR", ":8080"),
+		APIDocs:                   envBoolOrDefault("GAS_API_DOCS", false),
+		APICompression:            envBoolOrDefault("GAS_API_COMPRESSION", true),
+		APIStrategyOverrides:      envBoolOrDefault("GAS_API_STRATEGY_OVERRIDES", false),
+		APISuggest:                envBoolOrDefault("GAS_API_SUGGEST", false),
+		APIReadTimeout:            envDurationOrDefault("GAS_API_READ_TIMEOUT", 100*time.Millisecond),
+		APINodeTimeout:            envDurationOrDefault("GAS_API_NODE_TIMEOUT", 5*time.Second),
+		APIWriteTimeout:           envDurationOrDefault("GAS_API_WRITE_TIMEOUT", 10*time.Second),
+		APIDefaultVersion:         envOrDefault("GAS_API_DEFAULT_VERSION", "v1"),
+		APIV1Deprecation:          os.Getenv("GAS_API_V1_DEPRECATION"),
+		APIV1Sunset:               os.Getenv("GAS_API_V1_SUNSET"),
+		DebugToken:                os.Getenv("GAS_DEBUG_TOKEN"),
+		AdminToken:                os.Getenv("GAS_ADMIN_TOKEN"),
+		SigningKeyFile:            os.Getenv("GAS_SIGNING_KEY_FILE"),
+		Explain:                   envBoolOrDefault("GAS_EXPLAIN", false),
+		WebhookToken:              os.Getenv("GAS_WEBHOOK_TOKEN"),
+		WebhookMax:                envIntOrDefault("GAS_WEBHOOK_MAX", 100),
+		WebhookMaxAttempts:        envIntOrDefault("GAS_WEBHOOK_MAX_ATTEMPTS", 5),
+		AnomalyDetection:          envBoolOrDefault("GAS_ANOMALY_DETECTION", false),
+		AnomalyBaseFeeRatio:       envFloatOrDefault("GAS_ANOMALY_BASE_FEE_RATIO", 2),
+		AnomalyBaseFeeBlocks:      envIntOrDefault("GAS_ANOMALY_BASE_FEE_BLOCKS", 5),
+		AnomalyMempoolRatio:       envFloatOrDefault("GAS_ANOMALY_MEMPOOL_RATIO", 5),
+		AnomalyPriorityFeeRatio:   envFloatOrDefault("GAS_ANOMALY_PRIORITY_FEE_RATIO", 3),
+		TenantsFile:               os.Getenv("GAS_TENANTS_FILE"),
+		TenantAdminToken:          os.Getenv("GAS_TENANT_ADMIN_TOKEN"),
+		AnomalyWebhookURL:         os.Getenv("GAS_ANOMALY_WEBHOOK_URL"),
+		AnomalyWebhookSecret:      os.Getenv("GAS_ANOMALY_WEBHOOK_SECRET"),
+		SanityGuard:               envBoolOrDefault("GAS_SANITY_GUARD", true),
+		SanityMaxBaseFeeChange:    envFloatOrDefault("GAS_SANITY_MAX_BASE_FEE_CHANGE", 0.125),
+		SanityCongestedMempool:    envIntOrDefault("GAS_SANITY_CONGESTED_MEMPOOL", 100),
+		AccuracyWindows:           envOrDefault("GAS_ACCURACY_WINDOWS", "1h,24h"),
+		ExpireEstimates:           envBoolOrDefault("GAS_EXPIRE_ESTIMATES", false),
+		ExpiryGrace:               envDurationOrDefault("GAS_EXPIRY_GRACE", 0),
+		SnapshotBlocks:            envIntOrDefault("GAS_SNAPSHOT_BLOCKS", 1024),
+		HistoryBlocks:             envIntOrDefault("GAS_HISTORY_BLOCKS", 20),
+		HistoryBootstrap:          envOrDefault("GAS_HISTORY_BOOTSTRAP", "fee_history"),
+		BlockFeeSamples:           envIntOrDefault("GAS_BLOCK_FEE_SAMPLES", 0),
+		Strategy:                  envOrDefault("GAS_STRATEGY", "hybrid"),
+		EnsembleCombine:           envOrDefault("GAS_ENSEMBLE_COMBINE", "median"),
+		OutlierFilter:             envOrDefault("GAS_OUTLIER_FILTER", "none"),
+		OutlierThreshold:          envFloatOrDefault("GAS_OUTLIER_THRESHOLD", 0),
+		PercentileMethod:          envOrDefault("GAS_PERCENTILE_METHOD", "linear"),
+		ChainPreset:               preset,
+		TierPercentiles:           envOrDefault("GAS_TIER_PERCENTILES", "99,90,50,25"),
+		MinPriorityFee:            envFloatOrDefault("GAS_MIN_PRIORITY_FEE", 1),
+		MaxPriorityFee:            envFloatOrDefault("GAS_MAX_PRIORITY_FEE", 500),
+		EnsembleWeights:           envOrDefault("GAS_ENSEMBLE_WEIGHTS", "hybrid=0.5,fee_history=0.25,mempool=0.25"),
+		HistoryHalfLife:           envIntOrDefault("GAS_HISTORY_HALF_LIFE", 5),
+		HistoryGasWeighted:        envBoolOrDefault("GAS_HISTORY_GAS_WEIGHTED", true),
+		HistoryReceipts:           envBoolOrDefault("GAS_HISTORY_RECEIPTS", false),
+		MempoolSamples:            envIntOrDefault("GAS_MEMPOOL_SAMPLES", 500),
+		MempoolMaxBytes:           envIntOrDefault("GAS_MEMPOOL_MAX_BYTES", 0),
+		RecalcInterval:            envDurationOrDefault("GAS_RECALC_INTERVAL", 200*time.Millisecond),
+		RecalcOnBlock:             envBoolOrDefault("GAS_RECALC_ON_BLOCK", true),
+		RecalcPendingTxs:          envIntOrDefault("GAS_RECALC_PENDING_TXS", 0),
+		MempoolSampling:           envOrDefault("GAS_MEMPOOL_SAMPLING", "recent"),
+		MempoolSamplingWindow:     envDurationOrDefault("GAS_MEMPOOL_SAMPLING_WINDOW", 30*time.Second),
+		FullPendingTxs:            envBoolOrDefault("GAS_FULL_PENDING_TXS", true),
+		NonceFilter:               envBoolOrDefault("GAS_NONCE_FILTER", false),
+		PendingBlock:              envBoolOrDefault("GAS_PENDING_BLOCK", false),
+		PendingBlockWeight:        envFloatOrDefault("GAS_PENDING_BLOCK_WEIGHT", 0.5),
+		ElasticityMultiplier:      uint64(envIntOrDefault("GAS_ELASTICITY_MULTIPLIER", 2)),
+		BaseFeeChangeDenominator:  uint64(envIntOrDefault("GAS_BASE_FEE_CHANGE_DENOMINATOR", 8)),
+		BaseFeeHorizon:            envIntOrDefault("GAS_BASE_FEE_HORIZON", 6),
+		BaseFeeMultiplier:         envFloatOrDefault("GAS_BASE_FEE_MULTIPLIER", 0),
+		MaxPriorityFeeCap:         envFloatOrDefault("GAS_MAX_PRIORITY_FEE_CAP", 0),
+		MaxFeeCap:                 envFloatOrDefault("GAS_MAX_FEE_CAP", 0),
+		NetworkName:               os.Getenv("GAS_NETWORK_NAME"),
+		NetworkCurrency:           os.Getenv("GAS_NETWORK_CURRENCY"),
+		NetworkBlockTime:          envDurationOrDefault("GAS_NETWORK_BLOCK_TIME", 0),
+		NetworkFeeCurrencies:      os.Getenv("GAS_NETWORK_FEE_CURRENCIES"),
+		PriceFeedURL:              os.Getenv("GAS_PRICE_FEED_URL"),
+		PriceFeedPath:             os.Getenv("GAS_PRICE_FEED_PATH"),
+		PriceFeedChainlinkAddress: os.Getenv("GAS_PRICE_FEED_CHAINLINK_ADDRESS"),
+		PriceFeedTTL:              envDurationOrDefault("GAS_PRICE_FEED_TTL", 30*time.Second),
+		HAMode:                    os.Getenv("GAS_HA_MODE"),
+		HAIdentity:                os.Getenv("GAS_HA_IDENTITY"),
+		HALeaseName:               envOrDefault("GAS_HA_LEASE_NAME", "go-gas-leader"),
+		HALeaseTTL:                envDurationOrDefault("GAS_HA_LEASE_TTL", 10*time.Second),
+		HAPollInterval:            envDurationOrDefault("GAS_HA_POLL_INTERVAL", time.Second),
+		HARedisAddr:               os.Getenv("GAS_HA_REDIS_ADDR"),
+		HARedisPassword:           os.Getenv("GAS_HA_REDIS_PASSWORD"),
+		HAAPIKey:                  os.Getenv("GAS_HA_API_KEY"),
+		HANamespace:               os.Getenv("GAS_HA_NAMESPACE"),
+		LogLevel:                  envOrDefault("GAS_LOG_LEVEL", "info"),
+		LogFormat:                 envOrDefault("GAS_LOG_FORMAT", "json"),
+		APIAccessLogLevel:         envOrDefault("GAS_API_ACCESS_LOG_LEVEL", "info"),
 	}
 
 	if err := cfg.validate(); err != nil {
@@ -58,44 +446,495 @@ func Load() (*Config, error) {
 }
 
 func (c *Config) validate() error {
-	if c.NodeWSURL == "" {
-		return errors.New("GAS_NODE_WS_URL is required")
+	if c.NodeIPCPath != "" && !eth.IsIPCEndpoint(c.NodeIPCPath) {
+		return errors.New(`GAS_NODE_IPC_PATH must end in .ipc or be a \\.\pipe\ named pipe`)
 	}
-	if _, err := url.Parse(c.NodeWSURL); err != nil {
+
+	if _, err := url.Parse(c.NodeWSURL); err != nil && !eth.IsIPCEndpoint(c.NodeWSURL) {
 		return fmt.Errorf("invalid GAS_NODE_WS_URL: %w", err)
 	}
+	if c.WSConnections < 1 {
+		return errors.New("GAS_WS_CONNECTIONS must be at least 1")
+	}
+	if c.WSShardURLs != "" {
+		if c.NodeWSURL == "" {
+			return errors.New("GAS_WS_SHARD_URLS requires GAS_NODE_WS_URL")
+		}
+		for _, u := range c.WSEndpoints()[1:] {
+			if _, err := url.Parse(u); err != nil && !eth.IsIPCEndpoint(u) {
+				return fmt.Errorf("invalid GAS_WS_SHARD_URLS entry: %w", err)
+			}
+		}
+	}
 
-	if c.NodeHTTPURL == "" {
-		return errors.New("GAS_NODE_HTTP_URL is required")
+	for _, u := range c.CrossCheckEndpoints() {
+		if _, err := url.Parse(u); err != nil && !eth.IsIPCEndpoint(u) {
+			return fmt.Errorf("invalid GAS_CROSSCHECK_URLS entry: %w", err)
+		}
+	}
+
+	if c.ReplaySpeed < 0 {
+		return errors.New("GAS_REPLAY_SPEED must not be negative")
+	}
+	if c.NodeHTTPURL == "" && c.ReplayPath == "" {
+		return errors.New("GAS_NODE_HTTP_URL or GAS_NODE_IPC_PATH is required")
 	}
-	if _, err := url.Parse(c.NodeHTTPURL); err != nil {
+	if _, err := url.Parse(c.NodeHTTPURL); err != nil && !eth.IsIPCEndpoint(c.NodeHTTPURL) {
 		return fmt.Errorf("invalid GAS_NODE_HTTP_URL: %w", err)
 	}
 
+	authMethods := 0
+	for _, v := range []string{c.NodeBearerToken, c.NodeBasicAuth, c.NodeJWTSecretFile} {
+		if v != "" {
+			authMethods++
+		}
+	}
+	if authMethods > 1 {
+		return errors.New("GAS_NODE_BEARER_TOKEN, GAS_NODE_BASIC_AUTH and GAS_NODE_JWT_SECRET_FILE are mutually exclusive")
+	}
+	if c.NodeBasicAuth != "" && !strings.Contains(c.NodeBasicAuth, ":") {
+		return errors.New("GAS_NODE_BASIC_AUTH must be in user:password form")
+	}
+
+	if c.NodeProxyURL != "" {
+		if _, err := url.Parse(c.NodeProxyURL); err != nil {
+			return fmt.Errorf("invalid GAS_NODE_PROXY_URL: %w", err)
+		}
+	}
+	if (c.NodeTLSCertFile == "") != (c.NodeTLSKeyFile == "") {
+		return errors.New("GAS_NODE_TLS_CERT_FILE and GAS_NODE_TLS_KEY_FILE must be set together")
+	}
+	if c.NodeDialTimeout <= 0 {
+		return errors.New("GAS_NODE_DIAL_TIMEOUT must be positive")
+	}
+	if c.NodeRequestTimeout <= 0 {
+		return errors.New("GAS_NODE_REQUEST_TIMEOUT must be positive")
+	}
+	if c.NodeBlockTimeout <= 0 {
+		return errors.New("GAS_NODE_BLOCK_TIMEOUT must be positive")
+	}
+	if c.NodeTxFetchTimeout <= 0 {
+		return errors.New("GAS_NODE_TX_FETCH_TIMEOUT must be positive")
+	}
+	if c.APIReadTimeout <= 0 {
+		return errors.New("GAS_API_READ_TIMEOUT must be positive")
+	}
+	if c.APINodeTimeout <= 0 {
+		return errors.New("GAS_API_NODE_TIMEOUT must be positive")
+	}
+	if c.APIWriteTimeout <= 0 {
+		return errors.New("GAS_API_WRITE_TIMEOUT must be positive")
+	}
+	if c.APIDefaultVersion != "v1" && c.APIDefaultVersion != "v2" {
+		return errors.New("GAS_API_DEFAULT_VERSION must be v1 or v2")
+	}
+	if c.APIV1Deprecation != "" {
+		if _, err := time.Parse(time.RFC3339, c.APIV1Deprecation); err != nil {
+			return errors.New("GAS_API_V1_DEPRECATION must be an RFC 3339 time, e.g. 2026-01-01T00:00:00Z")
+		}
+	}
+	if c.APIV1Sunset != "" {
+		if c.APIV1Deprecation == "" {
+			return errors.New("GAS_API_V1_SUNSET requires GAS_API_V1_DEPRECATION")
+		}
+		if _, err := time.Parse(time.RFC3339, c.APIV1Sunset); err != nil {
+			return errors.New("GAS_API_V1_SUNSET must be an RFC 3339 time, e.g. 2027-01-01T00:00:00Z")
+		}
+	}
+	if c.NodeMaxConcurrentRequests < 0 {
+		return errors.New("GAS_NODE_MAX_CONCURRENT_REQUESTS must not be negative")
+	}
+	if c.NodeRequestsPerSecond < 0 {
+		return errors.New("GAS_NODE_REQUESTS_PER_SECOND must not be negative")
+	}
+	if c.PendingFetchBatches < 1 {
+		return errors.New("GAS_PENDING_FETCH_BATCHES must be at least 1")
+	}
+	if c.PendingFetchBatchSize < 1 {
+		return errors.New("GAS_PENDING_FETCH_BATCH_SIZE must be at least 1")
+	}
+	if c.NodeDegradedPollInterval < 0 {
+		return errors.New("GAS_NODE_DEGRADED_POLL_INTERVAL must not be negative")
+	}
+	if c.NodePollInterval <= 0 {
+		return errors.New("GAS_NODE_POLL_INTERVAL must be positive")
+	}
+	if c.ChainHaltThreshold < 0 {
+		return errors.New("GAS_CHAIN_HALT_THRESHOLD must not be negative")
+	}
+	if c.ResyncPollInterval < 0 {
+		return errors.New("GAS_RESYNC_POLL_INTERVAL must not be negative")
+	}
+	if c.ResyncMaxRewind < 0 {
+		return errors.New("GAS_RESYNC_MAX_REWIND must not be negative")
+	}
+
+	if c.NodeWSPingInterval < 0 {
+		return errors.New("GAS_NODE_WS_PING_INTERVAL must not be negative")
+	}
+	if c.NodeWSPingInterval > 0 && c.NodeWSPongTimeout <= 0 {
+		return errors.New("GAS_NODE_WS_PONG_TIMEOUT must be positive")
+	}
+
 	if c.HistoryBlocks < 1 || c.HistoryBlocks > 1000 {
 		return errors.New("GAS_HISTORY_BLOCKS must be between 1 and 1000")
 	}
 
+	switch c.NodeDevChain {
+	case "auto", "on", "off":
+	default:
+		return errors.New("GAS_NODE_DEV_CHAIN must be one of auto, on, off")
+	}
+
+	switch strings.ToLower(c.APIAccessLogLevel) {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		return errors.New("GAS_API_ACCESS_LOG_LEVEL must be one of debug, info, warn, error")
+	}
+	switch c.HistoryBootstrap {
+	case "fee_history", "blocks":
+	default:
+		return errors.New("GAS_HISTORY_BOOTSTRAP must be one of fee_history, blocks")
+	}
+
+	if c.BlockFeeSamples < 0 || c.BlockFeeSamples > 10000 {
+		return errors.New("GAS_BLOCK_FEE_SAMPLES must be between 0 and 10000")
+	}
+
+	if c.HistoryHalfLife < 0 || c.HistoryHalfLife > 1000 {
+		return errors.New("GAS_HISTORY_HALF_LIFE must be between 0 and 1000")
+	}
+
 	if c.MempoolSamples < 0 || c.MempoolSamples > 10000 {
 		return errors.New("GAS_MEMPOOL_SAMPLES must be between 0 and 10000")
 	}
 
-	if c.RecalcInterval < 10*time.Millisecond {
-		return errors.New("GAS_RECALC_INTERVAL must be at least 10ms")
+	if c.MempoolMaxBytes < 0 {
+		return errors.New("GAS_MEMPOOL_MAX_BYTES must not be negative")
+	}
+
+	switch c.MempoolSampling {
+	case "recent", "reservoir", "stratified":
+	default:
+		return errors.New("GAS_MEMPOOL_SAMPLING must be one of recent, reservoir, stratified")
+	}
+
+	if c.MempoolSamplingWindow < time.Second {
+		return errors.New("GAS_MEMPOOL_SAMPLING_WINDOW must be at least 1s")
+	}
+
+	if c.WebhookMax < 1 || c.WebhookMax > 10000 {
+		return errors.New("GAS_WEBHOOK_MAX must be between 1 and 10000")
+	}
+
+	if c.WebhookMaxAttempts < 1 || c.WebhookMaxAttempts > 20 {
+		return errors.New("GAS_WEBHOOK_MAX_ATTEMPTS must be between 1 and 20")
+	}
+
+	if c.TenantAdminToken != "" && c.TenantsFile == "" {
+		return errors.New("GAS_TENANT_ADMIN_TOKEN requires GAS_TENANTS_FILE")
+	}
+
+	if c.AnomalyBaseFeeRatio < 0 || c.AnomalyMempoolRatio < 0 || c.AnomalyPriorityFeeRatio < 0 {
+		return errors.New("GAS_ANOMALY_*_RATIO must not be negative")
+	}
+
+	if c.AnomalyBaseFeeBlocks < 1 || c.AnomalyBaseFeeBlocks > 1000 {
+		return errors.New("GAS_ANOMALY_BASE_FEE_BLOCKS must be between 1 and 1000")
+	}
+
+	if c.AnomalyWebhookURL != "" {
+		if err := webhook.ValidateURL(c.AnomalyWebhookURL); err != nil {
+			return fmt.Errorf("invalid GAS_ANOMALY_WEBHOOK_URL: %w", err)
+		}
+	}
+
+	if c.SanityMaxBaseFeeChange < 0 {
+		return errors.New("GAS_SANITY_MAX_BASE_FEE_CHANGE must not be negative")
+	}
+
+	if c.SanityCongestedMempool < 0 {
+		return errors.New("GAS_SANITY_CONGESTED_MEMPOOL must not be negative")
+	}
+
+	if c.PendingBlockWeight < 0 || c.PendingBlockWeight > 1 {
+		return errors.New("GAS_PENDING_BLOCK_WEIGHT must be between 0 and 1")
+	}
+
+	if c.RecalcInterval != 0 && c.RecalcInterval < 10*time.Millisecond {
+		return errors.New("GAS_RECALC_INTERVAL must be 0 or at least 10ms")
+	}
+
+	if c.RecalcPendingTxs < 0 {
+		return errors.New("GAS_RECALC_PENDING_TXS must not be negative")
+	}
+
+	if !c.RecalcOnBlock && c.RecalcPendingTxs == 0 && c.RecalcInterval == 0 {
+		return errors.New("at least one of GAS_RECALC_ON_BLOCK, GAS_RECALC_PENDING_TXS or GAS_RECALC_INTERVAL must be enabled")
+	}
+
+	switch c.Strategy {
+	case "hybrid", "ensemble":
+	default:
+		return errors.New("GAS_STRATEGY must be one of hybrid, ensemble")
+	}
+	if c.Strategy == "ensemble" {
+		switch c.EnsembleCombine {
+		case "median", "weighted_mean":
+		default:
+			return errors.New("GAS_ENSEMBLE_COMBINE must be one of median, weighted_mean")
+		}
+		if _, err := ParseEnsembleWeights(c.EnsembleWeights); err != nil {
+			return fmt.Errorf("invalid GAS_ENSEMBLE_WEIGHTS: %w", err)
+		}
+	}
+
+	if _, err := ParseDurations(c.AccuracyWindows); err != nil {
+		return fmt.Errorf("invalid GAS_ACCURACY_WINDOWS: %w", err)
+	}
+
+	if c.ExpiryGrace < 0 {
+		return errors.New("GAS_EXPIRY_GRACE must not be negative")
+	}
+
+	if c.SnapshotBlocks < 0 || c.SnapshotBlocks > 1_000_000 {
+		return errors.New("GAS_SNAPSHOT_BLOCKS must be between 0 and 1000000")
+	}
+
+	switch c.OutlierFilter {
+	case "none", "iqr", "mad":
+	default:
+		return errors.New("GAS_OUTLIER_FILTER must be one of none, iqr, mad")
+	}
+	if c.OutlierThreshold < 0 || c.OutlierThreshold > 100 {
+		return errors.New("GAS_OUTLIER_THRESHOLD must be between 0 and 100")
+	}
+
+	switch c.PercentileMethod {
+	case "linear", "nearest_rank":
+	default:
+		return errors.New("GAS_PERCENTILE_METHOD must be one of linear, nearest_rank")
+	}
+
+	if _, err := ParseTierPercentiles(c.TierPercentiles); err != nil {
+		return fmt.Errorf("invalid GAS_TIER_PERCENTILES: %w", err)
+	}
+
+	if c.MinPriorityFee < 0 || c.MaxPriorityFee <= 0 {
+		return errors.New("GAS_MIN_PRIORITY_FEE must not be negative and GAS_MAX_PRIORITY_FEE must be positive")
+	}
+	if c.MinPriorityFee > c.MaxPriorityFee {
+		return errors.New("GAS_MIN_PRIORITY_FEE must not exceed GAS_MAX_PRIORITY_FEE")
+	}
+
+	if c.ElasticityMultiplier < 1 || c.ElasticityMultiplier > 1000 {
+		return errors.New("GAS_ELASTICITY_MULTIPLIER must be between 1 and 1000")
+	}
+
+	if c.BaseFeeChangeDenominator < 1 || c.BaseFeeChangeDenominator > 100000 {
+		return errors.New("GAS_BASE_FEE_CHANGE_DENOMINATOR must be between 1 and 100000")
+	}
+
+	if c.BaseFeeHorizon < 0 || c.BaseFeeHorizon > 100 {
+		return errors.New("GAS_BASE_FEE_HORIZON must be between 0 and 100")
+	}
+
+	if c.BaseFeeMultiplier < 0 || c.BaseFeeMultiplier > 10 {
+		return errors.New("GAS_BASE_FEE_MULTIPLIER must be between 0 and 10")
+	}
+
+	if c.MaxPriorityFeeCap < 0 {
+		return errors.New("GAS_MAX_PRIORITY_FEE_CAP must not be negative")
+	}
+	if c.MaxFeeCap < 0 {
+		return errors.New("GAS_MAX_FEE_CAP must not be negative")
+	}
+	if c.MaxFeeCap > 0 && c.MaxPriorityFeeCap > c.MaxFeeCap {
+		return errors.New("GAS_MAX_PRIORITY_FEE_CAP must not exceed GAS_MAX_FEE_CAP")
+	}
+
+	if c.NetworkBlockTime < 0 {
+		return errors.New("GAS_NETWORK_BLOCK_TIME must not be negative")
+	}
+	if c.NetworkFeeCurrencies != "" {
+		if _, err := ParseFeeCurrencies(c.NetworkFeeCurrencies); err != nil {
+			return fmt.Errorf("invalid GAS_NETWORK_FEE_CURRENCIES: %w", err)
+		}
+	}
+
+	if c.PriceFeedURL != "" && c.PriceFeedChainlinkAddress != "" {
+		return errors.New("GAS_PRICE_FEED_URL and GAS_PRICE_FEED_CHAINLINK_ADDRESS are mutually exclusive")
+	}
+	if c.PriceFeedURL != "" {
+		if _, err := url.Parse(c.PriceFeedURL); err != nil {
+			return fmt.Errorf("invalid GAS_PRICE_FEED_URL: %w", err)
+		}
+	}
+	if c.PriceFeedTTL <= 0 {
+		return errors.New("GAS_PRICE_FEED_TTL must be positive")
+	}
+
+	switch c.HAMode {
+	case "":
+	case "redis", "kubernetes":
+		if c.HAIdentity == "" {
+			return errors.New("GAS_HA_IDENTITY is required when GAS_HA_MODE is set")
+		}
+		if u, err := url.Parse(c.HAIdentity); err != nil || u.Host == "" {
+			return errors.New("GAS_HA_IDENTITY must be the replica's API base URL, e.g. http://10.0.0.5:9090")
+		}
+		if c.HAMode == "redis" && c.HARedisAddr == "" {
+			return errors.New("GAS_HA_REDIS_ADDR is required when GAS_HA_MODE is redis")
+		}
+		if c.HALeaseTTL < 3*time.Second {
+			return errors.New("GAS_HA_LEASE_TTL must be at least 3s")
+		}
+		if c.HAPollInterval <= 0 {
+			return errors.New("GAS_HA_POLL_INTERVAL must be positive")
+		}
+	default:
+		return errors.New("GAS_HA_MODE must be one of redis, kubernetes")
 	}
 
 	return nil
 }
 
+// ParseEnsembleWeights parses "name=weight" pairs separated by commas,
+// e.g. "hybrid=0.5,fee_history=0.25,mempool=0.25". Members are returned in
+// the order given. Known names are hybrid, fee_history and mempool.
+func ParseEnsembleWeights(s string) ([]EnsembleWeight, error) {
+	var out []EnsembleWeight
+	seen := make(map[string]bool)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, val, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not name=weight", pair)
+		}
+		name = strings.TrimSpace(name)
+		switch name {
+		case "hybrid", "fee_history", "mempool":
+		default:
+			return nil, fmt.Errorf("unknown strategy %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate strategy %q", name)
+		}
+		seen[name] = true
+		w, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight for %s: %q", name, val)
+		}
+		out = append(out, EnsembleWeight{Name: name, Weight: w})
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no strategies listed")
+	}
+	return out, nil
+}
+
+// ParseFeeCurrencies parses "symbol:address[:decimals]" entries separated
+// by commas, e.g. "cUSD:0x765D...282a,USDC:0x2F25...602B:6". Decimals
+// default to 18.
+func ParseFeeCurrencies(s string) ([]FeeCurrency, error) {
+	var out []FeeCurrency
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+			return nil, fmt.Errorf("%q is not symbol:address[:decimals]", entry)
+		}
+		c := FeeCurrency{Symbol: parts[0], Address: parts[1], Decimals: 18}
+		if !strings.HasPrefix(c.Address, "0x") || len(c.Address) != 42 {
+			return nil, fmt.Errorf("invalid address for %s: %q", c.Symbol, c.Address)
+		}
+		if len(parts) == 3 {
+			d, err := strconv.ParseUint(parts[2], 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid decimals for %s: %q", c.Symbol, parts[2])
+			}
+			c.Decimals = uint8(d)
+		}
+		out = append(out, c)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no fee currencies listed")
+	}
+	return out, nil
+}
+
+// ParseDurations parses a comma-separated list of positive durations,
+// e.g. "1h,24h".
+func ParseDurations(s string) ([]time.Duration, error) {
+	var out []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := time.ParseDuration(part)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid duration %q", part)
+		}
+		out = append(out, d)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no durations listed")
+	}
+	return out, nil
+}
+
+// WSEndpoints returns the WebSocket endpoints subscriptions are spread
+// over: NodeWSURL followed by the WSShardURLs entries.
+func (c *Config) WSEndpoints() []string {
+	endpoints := []string{c.NodeWSURL}
+	for _, u := range strings.Split(c.WSShardURLs, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			endpoints = append(endpoints, u)
+		}
+	}
+	return endpoints
+}
+
+// CrossCheckEndpoints returns the GAS_CROSSCHECK_URLS entries.
+func (c *Config) CrossCheckEndpoints() []string {
+	var endpoints []string
+	for _, u := range strings.Split(c.CrossCheckURLs, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			endpoints = append(endpoints, u)
+		}
+	}
+	return endpoints
+}
+
+// FeeCurrency is one parsed GAS_NETWORK_FEE_CURRENCIES entry.
+type FeeCurrency struct {
+	Symbol   string
+	Address  string
+	Decimals uint8
+}
+
+// EnsembleWeight is one parsed GAS_ENSEMBLE_WEIGHTS entry.
+type EnsembleWeight struct {
+	Name   string
+	Weight float64
+}
+
 func envOrDefault(key, defaultVal string) string {
-	if val := os.Getenv(key); val != "" {
+	if val := getenv(key); val != "" {
 		return val
 	}
 	return defaultVal
 }
 
 func envIntOrDefault(key string, defaultVal int) int {
-	if val := os.Getenv(key); val != "" {
+	if val := getenv(key); val != "" {
 		if i, err := strconv.Atoi(val); err == nil {
 			return i
 		}
@@ -103,8 +942,26 @@ func envIntOrDefault(key string, defaultVal int) int {
 	return defaultVal
 }
 
+func envFloatOrDefault(key string, defaultVal float64) float64 {
+	if val := getenv(key); val != "" {
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return f
+		}
+	}
+	return defaultVal
+}
+
+func envBoolOrDefault(key string, defaultVal bool) bool {
+	if val := getenv(key); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+	return defaultVal
+}
+
 func envDurationOrDefault(key string, defaultVal time.Duration) time.Duration {
-	if val := os.Getenv(key); val != "" {
+	if val := getenv(key); val != "" {
 		if d, err := time.ParseDuration(val); err == nil {
 			return d
 		}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// chainPresets are tuned settings for well-known chains, selected with
+// GAS_CHAIN_PRESET. Each maps environment variables to the value used
+// when the variable itself is unset, so explicit settings always win.
+var chainPresets = map[string]map[string]string{
+	"mainnet": {
+		"GAS_HISTORY_BLOCKS":              "20",
+		"GAS_HISTORY_HALF_LIFE":           "5",
+		"GAS_RECALC_INTERVAL":             "200ms",
+		"GAS_TIER_PERCENTILES":            "99,90,50,25",
+		"GAS_MIN_PRIORITY_FEE":            "0.05",
+		"GAS_MAX_PRIORITY_FEE":            "500",
+		"GAS_ELASTICITY_MULTIPLIER":       "2",
+		"GAS_BASE_FEE_CHANGE_DENOMINATOR": "8",
+	},
+	"sepolia": {
+		"GAS_HISTORY_BLOCKS":              "20",
+		"GAS_HISTORY_HALF_LIFE":           "5",
+		"GAS_RECALC_INTERVAL":             "500ms",
+		"GAS_TIER_PERCENTILES":            "95,80,50,25",
+		"GAS_MIN_PRIORITY_FEE":            "0.01",
+		"GAS_MAX_PRIORITY_FEE":            "50",
+		"GAS_ELASTICITY_MULTIPLIER":       "2",
+		"GAS_BASE_FEE_CHANGE_DENOMINATOR": "8",
+	},
+	// OP Stack: 2s blocks, so a longer history covers the same time span
+	"base": {
+		"GAS_HISTORY_BLOCKS":              "60",
+		"GAS_HISTORY_HALF_LIFE":           "15",
+		"GAS_RECALC_INTERVAL":             "100ms",
+		"GAS_TIER_PERCENTILES":            "99,90,50,25",
+		"GAS_MIN_PRIORITY_FEE":            "0.001",
+		"GAS_MAX_PRIORITY_FEE":            "5",
+		"GAS_ELASTICITY_MULTIPLIER":       "6",
+		"GAS_BASE_FEE_CHANGE_DENOMINATOR": "250",
+	},
+	// The sequencer orders by arrival and ignores tips, so they stay near zero
+	"arbitrum": {
+		"GAS_HISTORY_BLOCKS":              "240",
+		"GAS_HISTORY_HALF_LIFE":           "60",
+		"GAS_RECALC_INTERVAL":             "100ms",
+		"GAS_TIER_PERCENTILES":            "99,90,50,25",
+		"GAS_MIN_PRIORITY_FEE":            "0",
+		"GAS_MAX_PRIORITY_FEE":            "0.01",
+		"GAS_ELASTICITY_MULTIPLIER":       "2",
+		"GAS_BASE_FEE_CHANGE_DENOMINATOR": "8",
+	},
+	// Validators reject tips below 25 gwei
+	"polygon": {
+		"GAS_HISTORY_BLOCKS":              "60",
+		"GAS_HISTORY_HALF_LIFE":           "15",
+		"GAS_RECALC_INTERVAL":             "200ms",
+		"GAS_TIER_PERCENTILES":            "99,90,50,25",
+		"GAS_MIN_PRIORITY_FEE":            "25",
+		"GAS_MAX_PRIORITY_FEE":            "3000",
+		"GAS_ELASTICITY_MULTIPLIER":       "2",
+		"GAS_BASE_FEE_CHANGE_DENOMINATOR": "16",
+	},
+	// No base fee burn in practice; the gas price is all tip
+	"bsc": {
+		"GAS_HISTORY_BLOCKS":              "40",
+		"GAS_HISTORY_HALF_LIFE":           "10",
+		"GAS_RECALC_INTERVAL":             "200ms",
+		"GAS_TIER_PERCENTILES":            "95,80,50,25",
+		"GAS_MIN_PRIORITY_FEE":            "0.1",
+		"GAS_MAX_PRIORITY_FEE":            "100",
+		"GAS_ELASTICITY_MULTIPLIER":       "2",
+		"GAS_BASE_FEE_CHANGE_DENOMINATOR": "8",
+	},
+}
+
+// ChainPresets returns the names of the built-in chain presets, sorted.
+func ChainPresets() []string {
+	names := make([]string, 0, len(chainPresets))
+	for name := range chainPresets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// activePreset is the preset selected for the Load in progress; getenv
+// falls back to it.
+var activePreset map[string]string
+
+// getenv returns the environment variable key, or the active preset's
+// value for it when unset.
+func getenv(key string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return activePreset[key]
+}
+
+// ParseTierPercentiles parses four comma-separated percentiles (0-100] for
+// the Urgent, Fast, Standard and Slow tiers, e.g. "99,90,50,25", into
+// fractions. They must not increase from one tier to the next.
+func ParseTierPercentiles(s string) ([4]float64, error) {
+	var out [4]float64
+	parts := strings.Split(s, ",")
+	if len(parts) != len(out) {
+		return out, errors.New("want 4 comma-separated percentiles (urgent, fast, standard, slow)")
+	}
+	for i, part := range parts {
+		p, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || p <= 0 || p > 100 {
+			return out, fmt.Errorf("invalid percentile %q", part)
+		}
+		if i > 0 && p > out[i-1]*100 {
+			return out, fmt.Errorf("percentile %q is above the previous tier's", part)
+		}
+		out[i] = p / 100
+	}
+	return out, nil
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// presetFields renders the settings chain presets tune, by variable, as
+// the preset would spell them.
+func presetFields(cfg *Config) map[string]string {
+	return map[string]string{
+		"GAS_HISTORY_BLOCKS":              fmt.Sprint(cfg.HistoryBlocks),
+		"GAS_HISTORY_HALF_LIFE":           fmt.Sprint(cfg.HistoryHalfLife),
+		"GAS_RECALC_INTERVAL":             cfg.RecalcInterval.String(),
+		"GAS_TIER_PERCENTILES":            cfg.TierPercentiles,
+		"GAS_MIN_PRIORITY_FEE":            fmt.Sprint(cfg.MinPriorityFee),
+		"GAS_MAX_PRIORITY_FEE":            fmt.Sprint(cfg.MaxPriorityFee),
+		"GAS_ELASTICITY_MULTIPLIER":       fmt.Sprint(cfg.ElasticityMultiplier),
+		"GAS_BASE_FEE_CHANGE_DENOMINATOR": fmt.Sprint(cfg.BaseFeeChangeDenominator),
+	}
+}
+
+func TestLoad_ChainPresets(t *testing.T) {
+	for _, name := range ChainPresets() {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("GAS_NODE_HTTP_URL", "http://localhost:8545")
+			t.Setenv("GAS_CHAIN_PRESET", name)
+			cfg, err := Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.ChainPreset != name {
+				t.Errorf("ChainPreset = %q, want %q", cfg.ChainPreset, name)
+			}
+			got := presetFields(cfg)
+			for key, want := range chainPresets[name] {
+				field, ok := got[key]
+				if !ok {
+					t.Errorf("preset sets %s, which the test doesn't check", key)
+					continue
+				}
+				if field != want {
+					t.Errorf("%s = %s, want the preset's %s", key, field, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_UnknownChainPreset(t *testing.T) {
+	t.Setenv("GAS_NODE_HTTP_URL", "http://localhost:8545")
+	t.Setenv("GAS_CHAIN_PRESET", "goerli")
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "GAS_CHAIN_PRESET") {
+		t.Errorf("Load() error = %v, want one naming GAS_CHAIN_PRESET", err)
+	}
+}
+
+func TestLoad_ChainPresetOverrides(t *testing.T) {
+	t.Setenv("GAS_NODE_HTTP_URL", "http://localhost:8545")
+	t.Setenv("GAS_CHAIN_PRESET", "base")
+	t.Setenv("GAS_HISTORY_BLOCKS", "30")
+	t.Setenv("GAS_MIN_PRIORITY_FEE", "0.5")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := presetFields(cfg)
+	want := map[string]string{"GAS_HISTORY_BLOCKS": "30", "GAS_MIN_PRIORITY_FEE": "0.5"}
+	for key, val := range chainPresets["base"] {
+		if _, ok := want[key]; !ok {
+			want[key] = val
+		}
+	}
+	for key, val := range want {
+		if got[key] != val {
+			t.Errorf("%s = %s, want %s", key, got[key], val)
+		}
+	}
+
+	// The preset is only consulted while loading
+	if activePreset != nil {
+		t.Error("preset still active after Load")
+	}
+}
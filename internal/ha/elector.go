@@ -0,0 +1,172 @@
+// Package ha provides leader election for running estimator replicas in
+// warm standby: the leader subscribes to the node and publishes estimates,
+// followers mirror the leader's estimates and take over when its lease
+// expires.
+package ha
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Lease is a shared, expiring lock held by at most one replica.
+type Lease interface {
+	// Acquire takes the lease for id, or extends it if id already holds it.
+	// It returns false without error when another replica holds the lease.
+	Acquire(ctx context.Context, id string, ttl time.Duration) (bool, error)
+
+	// Holder returns the identity of the current holder, or "" if the
+	// lease is free or expired.
+	Holder(ctx context.Context) (string, error)
+
+	// Release gives up the lease if id holds it.
+	Release(ctx context.Context, id string) error
+}
+
+// Elector campaigns for a Lease and runs the leader or follower role.
+type Elector struct {
+	lease  Lease
+	id     string
+	ttl    time.Duration
+	renew  time.Duration
+	logger *slog.Logger
+}
+
+// NewElector creates an Elector campaigning as id. The lease is renewed
+// every ttl/3, so a failed leader is replaced within about ttl.
+func NewElector(lease Lease, id string, ttl time.Duration, logger *slog.Logger) *Elector {
+	return &Elector{
+		lease:  lease,
+		id:     id,
+		ttl:    ttl,
+		renew:  ttl / 3,
+		logger: logger.With("component", "elector", "id", id),
+	}
+}
+
+// Run campaigns until ctx is canceled.
+//
+// While this replica holds the lease, lead runs with a context that is
+// canceled when the lease is lost. If lead returns, the lease is released
+// and the replica sits out one TTL so another replica can take over.
+//
+// While another replica leads, follow runs with that replica's identity and
+// a context that is canceled when the leader changes or this replica takes
+// over.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context) error, follow func(ctx context.Context, leader string)) error {
+	var (
+		leader, follower    *role
+		following           string
+		lastRenew, cooldown time.Time
+	)
+	unfollow := func() {
+		if follower != nil {
+			follower.stop()
+			follower, following = nil, ""
+		}
+	}
+	stepDown := func() {
+		leader.stop()
+		leader = nil
+		releaseCtx, cancel := context.WithTimeout(context.Background(), e.renew)
+		defer cancel()
+		if err := e.lease.Release(releaseCtx, e.id); err != nil {
+			e.logger.Warn("releasing lease", "error", err)
+		}
+	}
+	defer func() {
+		unfollow()
+		if leader != nil {
+			stepDown()
+		}
+	}()
+
+	ticker := time.NewTicker(e.renew)
+	defer ticker.Stop()
+
+	for {
+		if leader == nil && time.Now().After(cooldown) {
+			ok, err := e.lease.Acquire(ctx, e.id, e.ttl)
+			if err != nil {
+				e.logger.Warn("acquiring lease", "error", err)
+			}
+			if ok {
+				unfollow()
+				e.logger.Info("became leader")
+				leader, lastRenew = startRole(ctx, lead), time.Now()
+			}
+		}
+
+		if leader == nil {
+			holder, err := e.lease.Holder(ctx)
+			if err != nil {
+				e.logger.Warn("reading lease holder", "error", err)
+			} else if holder != following {
+				unfollow()
+				if holder != "" && holder != e.id {
+					e.logger.Info("following leader", "leader", holder)
+					follower, following = startRole(ctx, func(ctx context.Context) error {
+						follow(ctx, holder)
+						return nil
+					}), holder
+				}
+			}
+		}
+
+		var leaderDone <-chan struct{}
+		if leader != nil {
+			leaderDone = leader.done
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-leaderDone:
+			e.logger.Warn("leader stopped, releasing lease", "error", leader.err)
+			stepDown()
+			cooldown = time.Now().Add(e.ttl)
+
+		case <-ticker.C:
+			if leader == nil {
+				continue
+			}
+			ok, err := e.lease.Acquire(ctx, e.id, e.ttl)
+			switch {
+			case ok:
+				lastRenew = time.Now()
+			case err != nil && time.Since(lastRenew) < e.ttl-e.renew:
+				// Keep leading through transient errors while the lease
+				// can't have expired yet
+				e.logger.Warn("renewing lease", "error", err)
+			default:
+				e.logger.Warn("lost leadership", "error", err)
+				stepDown()
+			}
+		}
+	}
+}
+
+// role is a running lead or follow callback.
+type role struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+func startRole(ctx context.Context, fn func(ctx context.Context) error) *role {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &role{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(r.done)
+		r.err = fn(ctx)
+	}()
+	return r
+}
+
+// stop cancels the callback and waits for it to return.
+func (r *role) stop() {
+	r.cancel()
+	<-r.done
+}
@@ -0,0 +1,144 @@
+package ha
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memLease is an in-process Lease.
+type memLease struct {
+	mu      sync.Mutex
+	holder  string
+	expires time.Time
+}
+
+func (l *memLease) Acquire(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.holder != "" && l.holder != id && time.Now().Before(l.expires) {
+		return false, nil
+	}
+	l.holder, l.expires = id, time.Now().Add(ttl)
+	return true, nil
+}
+
+func (l *memLease) Holder(ctx context.Context) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if time.Now().After(l.expires) {
+		return "", nil
+	}
+	return l.holder, nil
+}
+
+func (l *memLease) Release(ctx context.Context, id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.holder == id {
+		l.holder = ""
+	}
+	return nil
+}
+
+// steal hands the lease to id, as if this replica's renewals had failed.
+func (l *memLease) steal(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.holder, l.expires = id, time.Now().Add(time.Hour)
+}
+
+func waitFor[T any](t *testing.T, ch <-chan T, what string) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+		panic("unreachable")
+	}
+}
+
+func TestElector(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	lease := &memLease{}
+	const ttl = 60 * time.Millisecond
+
+	leading := make(chan string, 2)
+	deposed := make(chan string, 2)
+	following := make(chan string, 4)
+	run := func(id string) {
+		e := NewElector(lease, id, ttl, logger)
+		e.Run(ctx, func(ctx context.Context) error {
+			leading <- id
+			<-ctx.Done()
+			deposed <- id
+			return nil
+		}, func(ctx context.Context, leader string) {
+			following <- id + "->" + leader
+			<-ctx.Done()
+		})
+	}
+
+	go run("a")
+	if got := waitFor(t, leading, "a to lead"); got != "a" {
+		t.Fatalf("%s leads, want a", got)
+	}
+	go run("b")
+	if got := waitFor(t, following, "b to follow"); got != "b->a" {
+		t.Fatalf("follower %s, want b->a", got)
+	}
+
+	// a loses the lease: it stops leading and follows the new holder
+	lease.steal("c")
+	if got := waitFor(t, deposed, "a to step down"); got != "a" {
+		t.Fatalf("%s stepped down, want a", got)
+	}
+	for want := map[string]bool{"a->c": true, "b->c": true}; len(want) > 0; {
+		got := waitFor(t, following, "replicas to follow c")
+		if !want[got] {
+			t.Fatalf("unexpected follower %s", got)
+		}
+		delete(want, got)
+	}
+
+	// Once c's lease lapses, a standby takes over
+	lease.mu.Lock()
+	lease.expires = time.Now()
+	lease.mu.Unlock()
+	if got := waitFor(t, leading, "a standby to lead"); got != "a" && got != "b" {
+		t.Fatalf("%s leads, want a or b", got)
+	}
+}
+
+func TestElector_LeaderStops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lease := &memLease{}
+	e := NewElector(lease, "a", 60*time.Millisecond, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// A leader that fails releases the lease for another replica to take
+	done := make(chan struct{})
+	go e.Run(ctx, func(ctx context.Context) error {
+		close(done)
+		return io.ErrUnexpectedEOF
+	}, func(ctx context.Context, leader string) {})
+
+	waitFor(t, done, "a to lead")
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		if holder, _ := lease.Holder(ctx); holder == "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("lease not released after the leader stopped")
+		}
+	}
+	if ok, _ := lease.Acquire(ctx, "b", time.Second); !ok {
+		t.Error("another replica could not take over during the cooldown")
+	}
+}
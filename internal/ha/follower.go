@@ -0,0 +1,117 @@
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/branched-services/go-gas/internal/api/grpc"
+	"github.com/branched-services/go-gas/pkg/estimator"
+)
+
+// Follower mirrors the leader's estimates into a local Provider so a standby
+// replica serves the same values, ETags and publish times without talking to
+// the node.
+type Follower struct {
+	provider *estimator.Provider
+	interval time.Duration
+	client   *http.Client
+	logger   *slog.Logger
+	apiKey   string
+}
+
+// FollowerOption configures a Follower.
+type FollowerOption func(*Follower)
+
+// WithAPIKey sends key as the tenant API key, for leaders that require one
+// (multi-tenant mode).
+func WithAPIKey(key string) FollowerOption {
+	return func(f *Follower) {
+		f.apiKey = key
+	}
+}
+
+// NewFollower creates a Follower that polls the leader every interval.
+func NewFollower(provider *estimator.Provider, interval time.Duration, logger *slog.Logger, opts ...FollowerOption) *Follower {
+	f := &Follower{
+		provider: provider,
+		interval: interval,
+		client:   &http.Client{Timeout: 2 * time.Second},
+		logger:   logger.With("component", "follower"),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Run polls leaderURL, the API base URL the leader advertises as its lease
+// identity, until ctx is canceled.
+func (f *Follower) Run(ctx context.Context, leaderURL string) {
+	url := strings.TrimRight(leaderURL, "/") + "/v1/gas/estimate?include=distribution"
+	logger := f.logger.With("leader", leaderURL)
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	var etag string
+	for {
+		next, err := f.poll(ctx, url, etag)
+		if err != nil && ctx.Err() == nil {
+			logger.Warn("polling leader", "error", err)
+		} else {
+			etag = next
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll fetches the leader's estimate unless it still matches etag, publishes
+// it, and returns the new ETag.
+func (f *Follower) poll(ctx context.Context, url, etag string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return etag, err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if f.apiKey != "" {
+		req.Header.Set("X-API-Key", f.apiKey)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return etag, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified, http.StatusServiceUnavailable:
+		// Unchanged, or the leader is still bootstrapping
+		return etag, nil
+	case http.StatusOK:
+	default:
+		return etag, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var body grpc.GasEstimateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return etag, fmt.Errorf("decoding estimate: %w", err)
+	}
+	est, err := body.Estimate()
+	if err != nil {
+		return etag, err
+	}
+	f.provider.Mirror(est)
+	return resp.Header.Get("ETag"), nil
+}
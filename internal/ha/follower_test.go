@@ -0,0 +1,99 @@
+package ha
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+)
+
+const leaderEstimate = `{
+  "chain_id": 1,
+  "block_number": 7,
+  "timestamp": "2026-01-02T03:04:04Z",
+  "last_update": "2026-01-02T03:04:05Z",
+  "generation": "7-42",
+  "base_fee": "1000000000",
+  "fee_currency": {"decimals": 18},
+  "estimates": {
+    "urgent": {"max_priority_fee_per_gas": "4", "max_fee_per_gas": "2000000004", "confidence": 0.99},
+    "fast": {"max_priority_fee_per_gas": "3", "max_fee_per_gas": "2000000003", "confidence": 0.9},
+    "standard": {"max_priority_fee_per_gas": "2", "max_fee_per_gas": "2000000002", "confidence": 0.5},
+    "slow": {"max_priority_fee_per_gas": "1", "max_fee_per_gas": "2000000001", "confidence": 0.25}
+  },
+  "network": {},
+  "estimator_version": "test",
+  "samples": {}
+}`
+
+func TestFollower_Poll(t *testing.T) {
+	requests := 0
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-API-Key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"7-42"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"7-42"`)
+		io.WriteString(w, leaderEstimate)
+	}))
+	defer leader.Close()
+
+	ctx := context.Background()
+	provider := estimator.NewProvider()
+	f := NewFollower(provider, time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)), WithAPIKey("key"))
+
+	etag, err := f.poll(ctx, leader.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if etag != `"7-42"` {
+		t.Errorf("ETag = %s, want the leader's", etag)
+	}
+
+	// The mirror keeps the leader's version and publish time, so it serves
+	// the same ETags and last_update
+	est, err := provider.Current(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.BlockNumber != 7 || est.Version != 42 {
+		t.Errorf("mirrored block %d version %d, want 7 and 42", est.BlockNumber, est.Version)
+	}
+	if want := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC); !est.UpdatedAt.Equal(want) {
+		t.Errorf("mirrored UpdatedAt = %v, want %v", est.UpdatedAt, want)
+	}
+	if est.Standard.MaxPriorityFeePerGas.Uint64() != 2 {
+		t.Errorf("mirrored standard tip = %v, want 2", est.Standard.MaxPriorityFeePerGas)
+	}
+
+	// An unchanged estimate is not fetched again
+	updates := provider.UpdateCount()
+	if etag, err = f.poll(ctx, leader.URL, etag); err != nil || etag != `"7-42"` {
+		t.Fatalf("poll() of an unchanged estimate = %s, %v", etag, err)
+	}
+	if provider.UpdateCount() != updates || requests != 2 {
+		t.Errorf("unchanged estimate republished")
+	}
+}
+
+func TestFollower_PollError(t *testing.T) {
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer leader.Close()
+
+	f := NewFollower(estimator.NewProvider(), time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if etag, err := f.poll(context.Background(), leader.URL, `"1-1"`); err == nil || etag != `"1-1"` {
+		t.Errorf("poll() = %s, %v; want the previous ETag and an error", etag, err)
+	}
+}
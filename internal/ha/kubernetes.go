@@ -0,0 +1,259 @@
+package ha
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// In-cluster service account files.
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	tokenFile         = serviceAccountDir + "/token"
+	caFile            = serviceAccountDir + "/ca.crt"
+	namespaceFile     = serviceAccountDir + "/namespace"
+)
+
+// microTime is the Kubernetes MicroTime wire format.
+const microTime = "2006-01-02T15:04:05.000000Z07:00"
+
+// errConflict reports a write rejected because the lease changed meanwhile.
+var errConflict = errors.New("lease modified concurrently")
+
+// KubernetesLease is a Lease backed by a coordination.k8s.io/v1 Lease object,
+// accessed with the pod's service account. The account needs get, create and
+// update on leases in the namespace.
+type KubernetesLease struct {
+	collection string // leases URL of the namespace
+	name       string
+	tokenFile  string
+	client     *http.Client
+}
+
+// NewInClusterLease creates a lease named name in namespace, using the
+// in-cluster API server address and service account. An empty namespace
+// selects the pod's own.
+func NewInClusterLease(namespace, name string) (*KubernetesLease, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a Kubernetes cluster")
+	}
+
+	if namespace == "" {
+		ns, err := os.ReadFile(namespaceFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading namespace: %w", err)
+		}
+		namespace = strings.TrimSpace(string(ns))
+	}
+
+	ca, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("invalid CA certificate")
+	}
+
+	return &KubernetesLease{
+		collection: fmt.Sprintf("https://%s/apis/coordination.k8s.io/v1/namespaces/%s/leases",
+			net.JoinHostPort(host, port), namespace),
+		name:      name,
+		tokenFile: tokenFile,
+		client: &http.Client{
+			Timeout:   5 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+	}, nil
+}
+
+func (l *KubernetesLease) url() string {
+	return l.collection + "/" + l.name
+}
+
+// leaseObject is the subset of the Lease resource used here.
+type leaseObject struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   leaseMetadata `json:"metadata"`
+	Spec       leaseSpec     `json:"spec"`
+}
+
+type leaseMetadata struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace,omitempty"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type leaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int    `json:"leaseTransitions,omitempty"`
+}
+
+// expired reports whether the holder failed to renew within the duration.
+func (s leaseSpec) expired(now time.Time) bool {
+	if s.HolderIdentity == "" {
+		return true
+	}
+	renewed, err := time.Parse(microTime, s.RenewTime)
+	if err != nil {
+		return true
+	}
+	return now.After(renewed.Add(time.Duration(s.LeaseDurationSeconds) * time.Second))
+}
+
+// Acquire takes the lease if it is free, expired or already held by id.
+// Concurrent writers are serialized by the object's resourceVersion.
+func (l *KubernetesLease) Acquire(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	seconds := max(int(ttl.Round(time.Second)/time.Second), 1)
+
+	obj, err := l.get(ctx)
+	if err != nil {
+		return false, err
+	}
+	if obj == nil {
+		obj = &leaseObject{
+			APIVersion: "coordination.k8s.io/v1",
+			Kind:       "Lease",
+			Metadata:   leaseMetadata{Name: l.name},
+			Spec: leaseSpec{
+				HolderIdentity:       id,
+				LeaseDurationSeconds: seconds,
+				AcquireTime:          now.UTC().Format(microTime),
+				RenewTime:            now.UTC().Format(microTime),
+			},
+		}
+		err := l.write(ctx, http.MethodPost, l.collection, obj)
+		if errors.Is(err, errConflict) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	if obj.Spec.HolderIdentity != id {
+		if !obj.Spec.expired(now) {
+			return false, nil
+		}
+		obj.Spec.HolderIdentity = id
+		obj.Spec.AcquireTime = now.UTC().Format(microTime)
+		obj.Spec.LeaseTransitions++
+	}
+	obj.Spec.LeaseDurationSeconds = seconds
+	obj.Spec.RenewTime = now.UTC().Format(microTime)
+
+	err = l.write(ctx, http.MethodPut, l.url(), obj)
+	if errors.Is(err, errConflict) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Holder returns the holder identity unless the lease has expired.
+func (l *KubernetesLease) Holder(ctx context.Context) (string, error) {
+	obj, err := l.get(ctx)
+	if err != nil || obj == nil || obj.Spec.expired(time.Now()) {
+		return "", err
+	}
+	return obj.Spec.HolderIdentity, nil
+}
+
+// Release clears the holder if id holds the lease, letting another replica
+// take over without waiting for expiry.
+func (l *KubernetesLease) Release(ctx context.Context, id string) error {
+	obj, err := l.get(ctx)
+	if err != nil || obj == nil || obj.Spec.HolderIdentity != id {
+		return err
+	}
+	obj.Spec.HolderIdentity = ""
+	obj.Spec.LeaseDurationSeconds = 1
+	err = l.write(ctx, http.MethodPut, l.url(), obj)
+	if errors.Is(err, errConflict) {
+		return nil
+	}
+	return err
+}
+
+// get fetches the lease object; nil if it doesn't exist.
+func (l *KubernetesLease) get(ctx context.Context) (*leaseObject, error) {
+	resp, err := l.request(ctx, http.MethodGet, l.url(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+	var obj leaseObject
+	if err := json.NewDecoder(resp.Body).Decode(&obj); err != nil {
+		return nil, fmt.Errorf("decoding lease: %w", err)
+	}
+	return &obj, nil
+}
+
+// write creates or updates the lease object.
+func (l *KubernetesLease) write(ctx context.Context, method, url string, obj *leaseObject) error {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	resp, err := l.request(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return nil
+	case http.StatusConflict:
+		return errConflict
+	default:
+		return statusError(resp)
+	}
+}
+
+func (l *KubernetesLease) request(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	// Projected tokens rotate, so read it for every request
+	token, err := os.ReadFile(l.tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading service account token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return l.client.Do(req)
+}
+
+func statusError(resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("kubernetes API: %s: %s", resp.Status, bytes.TrimSpace(msg))
+}
+
+// Verify interface compliance at compile time.
+var _ Lease = (*KubernetesLease)(nil)
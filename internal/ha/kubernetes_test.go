@@ -0,0 +1,194 @@
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLeaseAPI serves one Lease object, rejecting writes made from a stale
+// resourceVersion like the Kubernetes API server does.
+type fakeLeaseAPI struct {
+	mu      sync.Mutex
+	obj     *leaseObject
+	version int
+}
+
+func (f *fakeLeaseAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		if f.obj == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(f.obj)
+	case http.MethodPost, http.MethodPut:
+		var obj leaseObject
+		if err := json.NewDecoder(r.Body).Decode(&obj); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		exists := f.obj != nil
+		if (r.Method == http.MethodPost && exists) ||
+			(r.Method == http.MethodPut && (!exists || obj.Metadata.ResourceVersion != f.obj.Metadata.ResourceVersion)) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.version++
+		obj.Metadata.ResourceVersion = strconv.Itoa(f.version)
+		f.obj = &obj
+		json.NewEncoder(w).Encode(f.obj)
+	}
+}
+
+// set replaces the stored lease spec, as another replica would.
+func (f *fakeLeaseAPI) set(spec leaseSpec) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.version++
+	f.obj.Spec = spec
+	f.obj.Metadata.ResourceVersion = strconv.Itoa(f.version)
+}
+
+func (f *fakeLeaseAPI) spec() leaseSpec {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.obj.Spec
+}
+
+func newTestKubernetesLease(t *testing.T, api http.Handler) *KubernetesLease {
+	t.Helper()
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	token := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(token, []byte("test-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return &KubernetesLease{
+		collection: srv.URL + "/apis/coordination.k8s.io/v1/namespaces/default/leases",
+		name:       "gas-estimator",
+		tokenFile:  token,
+		client:     srv.Client(),
+	}
+}
+
+func TestKubernetesLease(t *testing.T) {
+	ctx := context.Background()
+	api := &fakeLeaseAPI{}
+	lease := newTestKubernetesLease(t, api)
+
+	// Acquire creates the lease
+	if ok, err := lease.Acquire(ctx, "a", 10*time.Second); !ok || err != nil {
+		t.Fatalf("Acquire(a) on a free lease = %v, %v; want true", ok, err)
+	}
+	if holder, err := lease.Holder(ctx); holder != "a" || err != nil {
+		t.Fatalf("Holder() = %q, %v; want a", holder, err)
+	}
+	if s := api.spec(); s.LeaseDurationSeconds != 10 || s.AcquireTime == "" {
+		t.Errorf("created spec = %+v", s)
+	}
+
+	// Renewing moves the renew time but not the acquire time
+	acquired := api.spec().AcquireTime
+	api.set(leaseSpec{HolderIdentity: "a", LeaseDurationSeconds: 10, AcquireTime: acquired,
+		RenewTime: time.Now().Add(-5 * time.Second).UTC().Format(microTime)})
+	if ok, err := lease.Acquire(ctx, "a", 10*time.Second); !ok || err != nil {
+		t.Fatalf("renewing Acquire(a) = %v, %v; want true", ok, err)
+	}
+	if s := api.spec(); s.AcquireTime != acquired || s.LeaseTransitions != 0 {
+		t.Errorf("spec after renewal = %+v, want acquire time kept", s)
+	}
+	renewed, _ := time.Parse(microTime, api.spec().RenewTime)
+	if time.Since(renewed) > time.Second {
+		t.Errorf("renew time %v not updated", renewed)
+	}
+
+	// Others can't take a live lease
+	if ok, err := lease.Acquire(ctx, "b", 10*time.Second); ok || err != nil {
+		t.Fatalf("Acquire(b) while a holds the lease = %v, %v; want false", ok, err)
+	}
+
+	// a loses the lease once it stops renewing
+	api.set(leaseSpec{HolderIdentity: "a", LeaseDurationSeconds: 10, AcquireTime: acquired,
+		RenewTime: time.Now().Add(-11 * time.Second).UTC().Format(microTime)})
+	if holder, err := lease.Holder(ctx); holder != "" || err != nil {
+		t.Fatalf("Holder() of an expired lease = %q, %v; want none", holder, err)
+	}
+	if ok, err := lease.Acquire(ctx, "b", 10*time.Second); !ok || err != nil {
+		t.Fatalf("Acquire(b) of an expired lease = %v, %v; want true", ok, err)
+	}
+	if s := api.spec(); s.HolderIdentity != "b" || s.LeaseTransitions != 1 {
+		t.Errorf("spec after takeover = %+v, want b holding after 1 transition", s)
+	}
+	if ok, err := lease.Acquire(ctx, "a", 10*time.Second); ok || err != nil {
+		t.Fatalf("Acquire(a) after losing the lease = %v, %v; want false", ok, err)
+	}
+
+	// Release only clears the lease for its holder
+	if err := lease.Release(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if holder, _ := lease.Holder(ctx); holder != "b" {
+		t.Fatalf("Holder() after Release(a) = %q, want b", holder)
+	}
+	if err := lease.Release(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	if holder, _ := lease.Holder(ctx); holder != "" {
+		t.Fatalf("Holder() after Release(b) = %q, want none", holder)
+	}
+	if ok, err := lease.Acquire(ctx, "a", 10*time.Second); !ok || err != nil {
+		t.Fatalf("Acquire(a) of a released lease = %v, %v; want true", ok, err)
+	}
+}
+
+func TestKubernetesLease_Conflict(t *testing.T) {
+	ctx := context.Background()
+	api := &fakeLeaseAPI{}
+	lease := newTestKubernetesLease(t, api)
+
+	// Another replica writes between our read and write
+	raced := false
+	racing := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && !raced {
+			raced = true
+			api.set(leaseSpec{HolderIdentity: "b", LeaseDurationSeconds: 10,
+				RenewTime: time.Now().UTC().Format(microTime)})
+		}
+		api.ServeHTTP(w, r)
+	})
+	racingLease := newTestKubernetesLease(t, racing)
+
+	if ok, err := lease.Acquire(ctx, "a", 10*time.Second); !ok || err != nil {
+		t.Fatalf("Acquire(a) = %v, %v", ok, err)
+	}
+	if ok, err := racingLease.Acquire(ctx, "a", 10*time.Second); ok || err != nil {
+		t.Fatalf("Acquire(a) losing a write race = %v, %v; want false without error", ok, err)
+	}
+	if holder, _ := lease.Holder(ctx); holder != "b" {
+		t.Errorf("Holder() = %q, want b, who won the race", holder)
+	}
+}
+
+func TestKubernetesLease_APIError(t *testing.T) {
+	lease := newTestKubernetesLease(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "leases is forbidden", http.StatusForbidden)
+	}))
+	if ok, err := lease.Acquire(context.Background(), "a", 10*time.Second); ok || err == nil {
+		t.Fatalf("Acquire() = %v, %v; want the API error", ok, err)
+	}
+}
@@ -0,0 +1,153 @@
+package ha
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lua scripts keep check-and-set atomic on the Redis server.
+const (
+	redisAcquireScript = `local v = redis.call('GET', KEYS[1])
+if v == false or v == ARGV[1] then
+  redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+  return 1
+end
+return 0`
+
+	redisReleaseScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0`
+)
+
+// RedisLease is a Lease stored as a Redis key with a TTL.
+//
+// It speaks plain RESP over TCP and opens a connection per command, which is
+// fine at lease renewal rates. TLS is not supported.
+type RedisLease struct {
+	addr     string
+	password string
+	key      string
+	timeout  time.Duration
+}
+
+// NewRedisLease creates a lease stored under key on the Redis server at
+// addr (host:port). password may be empty.
+func NewRedisLease(addr, password, key string) *RedisLease {
+	return &RedisLease{
+		addr:     addr,
+		password: password,
+		key:      key,
+		timeout:  2 * time.Second,
+	}
+}
+
+// Acquire takes or extends the lease for id.
+func (l *RedisLease) Acquire(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	reply, err := l.do(ctx, "EVAL", redisAcquireScript, "1", l.key, id, strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, err
+	}
+	return reply == int64(1), nil
+}
+
+// Holder returns the identity stored in the lease key.
+func (l *RedisLease) Holder(ctx context.Context) (string, error) {
+	reply, err := l.do(ctx, "GET", l.key)
+	if err != nil {
+		return "", err
+	}
+	holder, _ := reply.(string) // nil when the key doesn't exist
+	return holder, nil
+}
+
+// Release deletes the lease key if id holds it.
+func (l *RedisLease) Release(ctx context.Context, id string) error {
+	_, err := l.do(ctx, "EVAL", redisReleaseScript, "1", l.key, id)
+	return err
+}
+
+// do sends one command on a fresh connection, authenticating first if a
+// password is set, and returns the parsed reply.
+func (l *RedisLease) do(ctx context.Context, args ...string) (any, error) {
+	dialer := net.Dialer{Timeout: l.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", l.addr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to redis: %w", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(l.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	r := bufio.NewReader(conn)
+	if l.password != "" {
+		if _, err := redisCommand(conn, r, "AUTH", l.password); err != nil {
+			return nil, fmt.Errorf("redis auth: %w", err)
+		}
+	}
+	return redisCommand(conn, r, args...)
+}
+
+// redisCommand writes args as a RESP array and reads the reply.
+func redisCommand(w io.Writer, r *bufio.Reader, args ...string) (any, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return nil, err
+	}
+	return readRESP(r)
+}
+
+// readRESP parses one reply: simple strings and bulk strings become string,
+// integers int64, nil bulk strings nil, and error replies an error.
+func readRESP(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("redis: %s", line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	default:
+		return nil, fmt.Errorf("unsupported redis reply %q", line)
+	}
+}
+
+// Verify interface compliance at compile time.
+var _ Lease = (*RedisLease)(nil)
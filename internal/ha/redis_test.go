@@ -0,0 +1,64 @@
+package ha
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadRESP(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    any
+		wantErr string
+	}{
+		{name: "simple string", reply: "+OK\r\n", want: "OK"},
+		{name: "integer", reply: ":1\r\n", want: int64(1)},
+		{name: "negative integer", reply: ":-2\r\n", want: int64(-2)},
+		{name: "bulk string", reply: "$7\r\nleader1\r\n", want: "leader1"},
+		{name: "bulk string with CRLF", reply: "$4\r\na\r\nb\r\n", want: "a\r\nb"},
+		{name: "empty bulk string", reply: "$0\r\n\r\n", want: ""},
+		{name: "nil bulk string", reply: "$-1\r\n", want: nil},
+		{name: "error", reply: "-NOAUTH Authentication required.\r\n", wantErr: "redis: NOAUTH Authentication required."},
+		{name: "empty line", reply: "\r\n", wantErr: "empty redis reply"},
+		{name: "unsupported type", reply: "*1\r\n", wantErr: "unsupported redis reply"},
+		{name: "bad integer", reply: ":x\r\n", wantErr: "invalid syntax"},
+		{name: "bad bulk length", reply: "$x\r\n", wantErr: "invalid syntax"},
+		{name: "truncated bulk string", reply: "$10\r\nabc\r\n", wantErr: "EOF"},
+		{name: "truncated line", reply: "+OK", wantErr: "EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readRESP(bufio.NewReader(strings.NewReader(tt.reply)))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readRESP() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readRESP() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedisCommand(t *testing.T) {
+	var sent bytes.Buffer
+	reply, err := redisCommand(&sent, bufio.NewReader(strings.NewReader(":1\r\n")), "GET", "gas:leader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "*2\r\n$3\r\nGET\r\n$10\r\ngas:leader\r\n"; sent.String() != want {
+		t.Errorf("sent %q, want %q", sent.String(), want)
+	}
+	if reply != int64(1) {
+		t.Errorf("reply = %#v, want 1", reply)
+	}
+}
@@ -20,7 +20,7 @@ const (
 // NewLogger creates a configured slog.Logger.
 // Output is always stdout (12-factor compliant).
 func NewLogger(level, format string) *slog.Logger {
-	lvl := parseLevel(level)
+	lvl := ParseLevel(level)
 	opts := &slog.HandlerOptions{
 		Level:     lvl,
 		AddSource: lvl == slog.LevelDebug,
@@ -36,7 +36,9 @@ func NewLogger(level, format string) *slog.Logger {
 	return slog.New(handler)
 }
 
-func parseLevel(level string) slog.Level {
+// ParseLevel maps debug, info, warn (or warning) and error to a level;
+// anything else is info.
+func ParseLevel(level string) slog.Level {
 	switch strings.ToLower(level) {
 	case "debug":
 		return slog.LevelDebug
@@ -0,0 +1,113 @@
+package observability
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Metrics is a minimal registry of histograms, gauges and counters served
+// in the Prometheus text exposition format, so the service can be scraped
+// without pulling in a client library.
+type Metrics struct {
+	mu         sync.Mutex
+	histograms []*Histogram
+	gauges     []gauge
+}
+
+type gauge struct {
+	name, help string
+	kind       string // "gauge" or "counter"
+	value      func() float64
+}
+
+// NewMetrics creates an empty registry.
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+// Histogram is a cumulative histogram with fixed bucket upper bounds.
+// Observe is safe for concurrent use.
+type Histogram struct {
+	name, help string
+	bounds     []float64
+	counts     []atomic.Uint64 // per bucket, plus +Inf
+	sum        atomic.Uint64   // float64 bits
+}
+
+// Histogram registers a histogram with the given bucket upper bounds, which
+// are sorted if needed.
+func (m *Metrics) Histogram(name, help string, bounds []float64) *Histogram {
+	bounds = append([]float64(nil), bounds...)
+	sort.Float64s(bounds)
+	h := &Histogram{
+		name:   name,
+		help:   help,
+		bounds: bounds,
+		counts: make([]atomic.Uint64, len(bounds)+1),
+	}
+	m.mu.Lock()
+	m.histograms = append(m.histograms, h)
+	m.mu.Unlock()
+	return h
+}
+
+// GaugeFunc registers a gauge whose value is read from fn at scrape time.
+func (m *Metrics) GaugeFunc(name, help string, fn func() float64) {
+	m.mu.Lock()
+	m.gauges = append(m.gauges, gauge{name: name, help: help, kind: "gauge", value: fn})
+	m.mu.Unlock()
+}
+
+// CounterFunc registers a counter whose running total is read from fn at
+// scrape time.
+func (m *Metrics) CounterFunc(name, help string, fn func() float64) {
+	m.mu.Lock()
+	m.gauges = append(m.gauges, gauge{name: name, help: help, kind: "counter", value: fn})
+	m.mu.Unlock()
+}
+
+// Observe records v.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.counts[i].Add(1)
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// ServeHTTP writes all metrics in the text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	histograms, gauges := m.histograms, m.gauges
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, g := range gauges {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n",
+			g.name, g.help, g.name, g.kind, g.name, formatFloat(g.value()))
+	}
+	for _, h := range histograms {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+		var cumulative uint64
+		for i, bound := range h.bounds {
+			cumulative += h.counts[i].Load()
+			fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", h.name, formatFloat(bound), cumulative)
+		}
+		cumulative += h.counts[len(h.bounds)].Load()
+		fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.name, cumulative)
+		fmt.Fprintf(w, "%s_sum %s\n", h.name, formatFloat(math.Float64frombits(h.sum.Load())))
+		fmt.Fprintf(w, "%s_count %d\n", h.name, cumulative)
+	}
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
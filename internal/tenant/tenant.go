@@ -0,0 +1,469 @@
+// Package tenant implements API keys, chain access and request quotas for
+// hosted deployments serving several customers.
+//
+// Tenants are kept in a JSON file (see Open) that operators may edit by hand
+// or through the API server's admin endpoints. API keys are stored only as
+// SHA-256 hashes; a key is shown once, when it is issued.
+//
+// Quotas are fixed windows: requests per UTC minute and per UTC day. A
+// tenant's default quota can be overridden per chain, so one tenants file
+// can be shared by estimators for different chains.
+package tenant
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrChainNotAllowed is returned by Allow when the tenant may not query
+	// the chain.
+	ErrChainNotAllowed = errors.New("chain not allowed for this API key")
+
+	// ErrQuotaExceeded is returned by Allow when a quota window is used up.
+	ErrQuotaExceeded = errors.New("request quota exceeded")
+
+	// ErrNotFound is returned for unknown tenant IDs.
+	ErrNotFound = errors.New("tenant not found")
+
+	// ErrExists is returned by Create for a tenant ID already in use.
+	ErrExists = errors.New("tenant already exists")
+)
+
+// validID restricts tenant IDs to URL- and log-friendly names.
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Quota limits a tenant's requests. A zero limit is unlimited.
+type Quota struct {
+	RequestsPerMinute int `json:"requests_per_minute,omitempty"`
+	RequestsPerDay    int `json:"requests_per_day,omitempty"`
+}
+
+// Tenant is a customer of a hosted deployment.
+type Tenant struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+
+	// KeyHashes are the hex SHA-256 hashes of the tenant's API keys.
+	KeyHashes []string `json:"key_hashes,omitempty"`
+
+	// Chains lists the chain IDs the tenant may query; empty allows all.
+	Chains []uint64 `json:"chains,omitempty"`
+
+	// Quota applies on every chain without an entry in ChainQuotas.
+	Quota       Quota            `json:"quota"`
+	ChainQuotas map[uint64]Quota `json:"chain_quotas,omitempty"`
+
+	// Disabled rejects the tenant's keys without deleting the tenant.
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// Validate reports whether the tenant is well-formed.
+func (t Tenant) Validate() error {
+	if !validID.MatchString(t.ID) {
+		return fmt.Errorf("invalid tenant id %q: use 1-64 lowercase letters, digits, '-' or '_'", t.ID)
+	}
+	quotas := []Quota{t.Quota}
+	for _, q := range t.ChainQuotas {
+		quotas = append(quotas, q)
+	}
+	for _, q := range quotas {
+		if q.RequestsPerMinute < 0 || q.RequestsPerDay < 0 {
+			return errors.New("quotas must be non-negative")
+		}
+	}
+	return nil
+}
+
+// quota returns the tenant's quota on chainID.
+func (t Tenant) quota(chainID uint64) Quota {
+	if q, ok := t.ChainQuotas[chainID]; ok {
+		return q
+	}
+	return t.Quota
+}
+
+// allows reports whether the tenant may query chainID. Chain 0 (not yet
+// known) is always allowed.
+func (t Tenant) allows(chainID uint64) bool {
+	return chainID == 0 || len(t.Chains) == 0 || slices.Contains(t.Chains, chainID)
+}
+
+// Usage is a tenant's request counts since the process started.
+type Usage struct {
+	Requests        uint64
+	QuotaRejected   uint64
+	ChainRejected   uint64
+	RequestsByChain map[uint64]uint64
+	LastRequest     time.Time
+
+	// Requests counted against the current quota windows.
+	MinuteUsed int
+	DayUsed    int
+}
+
+// Status is a tenant with its usage.
+type Status struct {
+	Tenant Tenant
+	Usage  Usage
+}
+
+// window counts requests in a fixed time window.
+type window struct {
+	start time.Time
+	count int
+}
+
+// take counts a request at now if fewer than limit were counted in the
+// window of length size containing it, returning the wait until the next
+// window otherwise. A limit of 0 always succeeds.
+func (w *window) take(now time.Time, size time.Duration, limit int) (time.Duration, bool) {
+	start := now.Truncate(size)
+	if !start.Equal(w.start) {
+		w.start = start
+		w.count = 0
+	}
+	if limit > 0 && w.count >= limit {
+		return start.Add(size).Sub(now), false
+	}
+	w.count++
+	return 0, true
+}
+
+// used returns the requests counted in the window containing now.
+func (w *window) used(now time.Time, size time.Duration) int {
+	if !now.Truncate(size).Equal(w.start) {
+		return 0
+	}
+	return w.count
+}
+
+type entry struct {
+	tenant Tenant
+	minute window
+	day    window
+	usage  Usage
+}
+
+// Registry holds the tenants and enforces their quotas.
+//
+// Thread safety: All methods are safe for concurrent use.
+type Registry struct {
+	path string
+
+	mu      sync.Mutex
+	tenants map[string]*entry
+	keys    map[string]string // key hash -> tenant ID
+}
+
+// file is the tenants file format.
+type file struct {
+	Tenants []Tenant `json:"tenants"`
+}
+
+// Open loads the tenants in the JSON file at path. A missing file is
+// created on the first change; an empty path keeps tenants in memory only.
+func Open(path string) (*Registry, error) {
+	r := &Registry{
+		path:    path,
+		tenants: make(map[string]*entry),
+		keys:    make(map[string]string),
+	}
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading tenants: %w", err)
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing tenants: %w", err)
+	}
+	for _, t := range f.Tenants {
+		if err := t.Validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := r.tenants[t.ID]; ok {
+			return nil, fmt.Errorf("duplicate tenant id %q", t.ID)
+		}
+		r.tenants[t.ID] = &entry{tenant: t}
+		for _, h := range t.KeyHashes {
+			if owner, ok := r.keys[h]; ok {
+				return nil, fmt.Errorf("tenants %q and %q share an API key", owner, t.ID)
+			}
+			r.keys[h] = t.ID
+		}
+	}
+	return r, nil
+}
+
+// Authenticate returns the ID of the enabled tenant owning key.
+func (r *Registry) Authenticate(key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id, ok := r.keys[HashKey(key)]
+	if !ok || r.tenants[id].tenant.Disabled {
+		return "", false
+	}
+	return id, true
+}
+
+// Allow counts a request by tenant id on chainID at now. It returns
+// ErrChainNotAllowed, or ErrQuotaExceeded with the time until the exhausted
+// window resets.
+func (r *Registry) Allow(id string, chainID uint64, now time.Time) (time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.tenants[id]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	if !e.tenant.allows(chainID) {
+		e.usage.ChainRejected++
+		return 0, ErrChainNotAllowed
+	}
+
+	// Check both windows before counting, so a request rejected by the
+	// daily quota doesn't use up the minute
+	q := e.tenant.quota(chainID)
+	minute, day := e.minute, e.day
+	if wait, ok := minute.take(now, time.Minute, q.RequestsPerMinute); !ok {
+		e.usage.QuotaRejected++
+		return wait, ErrQuotaExceeded
+	}
+	if wait, ok := day.take(now.UTC(), 24*time.Hour, q.RequestsPerDay); !ok {
+		e.usage.QuotaRejected++
+		return wait, ErrQuotaExceeded
+	}
+	e.minute, e.day = minute, day
+
+	e.usage.Requests++
+	if e.usage.RequestsByChain == nil {
+		e.usage.RequestsByChain = make(map[uint64]uint64)
+	}
+	e.usage.RequestsByChain[chainID]++
+	e.usage.LastRequest = now
+	return 0, nil
+}
+
+// List returns every tenant with its usage, ordered by ID.
+func (r *Registry) List(now time.Time) []Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]Status, 0, len(r.tenants))
+	for _, e := range r.tenants {
+		out = append(out, e.status(now))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Tenant.ID < out[j].Tenant.ID })
+	return out
+}
+
+// Get returns a tenant with its usage.
+func (r *Registry) Get(id string, now time.Time) (Status, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.tenants[id]
+	if !ok {
+		return Status{}, false
+	}
+	return e.status(now), true
+}
+
+func (e *entry) status(now time.Time) Status {
+	u := e.usage
+	u.RequestsByChain = maps.Clone(e.usage.RequestsByChain)
+	u.MinuteUsed = e.minute.used(now, time.Minute)
+	u.DayUsed = e.day.used(now.UTC(), 24*time.Hour)
+	return Status{Tenant: e.tenant.clone(), Usage: u}
+}
+
+// Create adds a tenant and issues its first API key. Key hashes in t are
+// ignored.
+func (r *Registry) Create(t Tenant) (key string, err error) {
+	if err := t.Validate(); err != nil {
+		return "", err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tenants[t.ID]; ok {
+		return "", ErrExists
+	}
+	key = newKey()
+	t = t.clone()
+	t.KeyHashes = []string{HashKey(key)}
+	r.tenants[t.ID] = &entry{tenant: t}
+	r.keys[t.KeyHashes[0]] = t.ID
+
+	if err := r.save(); err != nil {
+		r.remove(t.ID)
+		return "", err
+	}
+	return key, nil
+}
+
+// Update replaces a tenant's settings, keeping its API keys and usage.
+func (r *Registry) Update(t Tenant) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.tenants[t.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	prev := e.tenant
+	t = t.clone()
+	t.KeyHashes = prev.KeyHashes
+	e.tenant = t
+
+	if err := r.save(); err != nil {
+		e.tenant = prev
+		return err
+	}
+	return nil
+}
+
+// IssueKey replaces a tenant's API keys with a new one.
+func (r *Registry) IssueKey(id string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.tenants[id]
+	if !ok {
+		return "", ErrNotFound
+	}
+	prev := e.tenant.KeyHashes
+	key := newKey()
+	r.setKeys(e, []string{HashKey(key)})
+
+	if err := r.save(); err != nil {
+		r.setKeys(e, prev)
+		return "", err
+	}
+	return key, nil
+}
+
+// Delete removes a tenant, revoking its API keys.
+func (r *Registry) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.tenants[id]
+	if !ok {
+		return ErrNotFound
+	}
+	r.remove(id)
+
+	if err := r.save(); err != nil {
+		r.tenants[id] = e
+		for _, h := range e.tenant.KeyHashes {
+			r.keys[h] = id
+		}
+		return err
+	}
+	return nil
+}
+
+// setKeys replaces e's key hashes. Callers must hold mu.
+func (r *Registry) setKeys(e *entry, hashes []string) {
+	for _, h := range e.tenant.KeyHashes {
+		delete(r.keys, h)
+	}
+	e.tenant.KeyHashes = hashes
+	for _, h := range hashes {
+		r.keys[h] = e.tenant.ID
+	}
+}
+
+// remove drops a tenant and its keys. Callers must hold mu.
+func (r *Registry) remove(id string) {
+	if e, ok := r.tenants[id]; ok {
+		for _, h := range e.tenant.KeyHashes {
+			delete(r.keys, h)
+		}
+		delete(r.tenants, id)
+	}
+}
+
+// save writes the tenants file, replacing it atomically. Callers must hold mu.
+func (r *Registry) save() error {
+	if r.path == "" {
+		return nil
+	}
+
+	var f file
+	for _, e := range r.tenants {
+		f.Tenants = append(f.Tenants, e.tenant)
+	}
+	sort.Slice(f.Tenants, func(i, j int) bool { return f.Tenants[i].ID < f.Tenants[j].ID })
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding tenants: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), ".tenants-*")
+	if err != nil {
+		return fmt.Errorf("saving tenants: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("saving tenants: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("saving tenants: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("saving tenants: %w", err)
+	}
+	return nil
+}
+
+func (t Tenant) clone() Tenant {
+	t.KeyHashes = slices.Clone(t.KeyHashes)
+	t.Chains = slices.Clone(t.Chains)
+	t.ChainQuotas = maps.Clone(t.ChainQuotas)
+	return t
+}
+
+// HashKey returns the hash under which an API key is stored: hex SHA-256,
+// the same as `printf %s "$KEY" | sha256sum`.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// newKey returns a random API key.
+func newKey() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return "gas_" + hex.EncodeToString(b)
+}
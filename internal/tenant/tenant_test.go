@@ -0,0 +1,263 @@
+package tenant
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistry_Quota(t *testing.T) {
+	r, _ := Open("")
+	if _, err := r.Create(Tenant{ID: "acme", Quota: Quota{RequestsPerMinute: 2, RequestsPerDay: 3}}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 1, 2, 23, 58, 10, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		if _, err := r.Allow("acme", 1, now); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	wait, err := r.Allow("acme", 1, now)
+	if !errors.Is(err, ErrQuotaExceeded) || wait != 50*time.Second {
+		t.Fatalf("third request in a minute = %v, %v; want ErrQuotaExceeded for 50s", wait, err)
+	}
+
+	// The next minute starts afresh; the day does not
+	if _, err := r.Allow("acme", 1, now.Add(time.Minute)); err != nil {
+		t.Fatalf("request in the next minute: %v", err)
+	}
+	wait, err = r.Allow("acme", 1, now.Add(time.Minute))
+	if !errors.Is(err, ErrQuotaExceeded) || wait != 50*time.Second {
+		t.Fatalf("fourth request in a day = %v, %v; want ErrQuotaExceeded until midnight", wait, err)
+	}
+
+	// A request refused for the day doesn't use up the minute
+	st, _ := r.Get("acme", now.Add(time.Minute))
+	if st.Usage.MinuteUsed != 1 || st.Usage.DayUsed != 3 || st.Usage.Requests != 3 || st.Usage.QuotaRejected != 2 {
+		t.Errorf("usage = %+v, want 1 this minute, 3 today, 2 rejected", st.Usage)
+	}
+
+	// The next UTC day resets the daily quota
+	if _, err := r.Allow("acme", 1, now.Add(2*time.Minute)); err != nil {
+		t.Fatalf("request the next day: %v", err)
+	}
+	if st, _ := r.Get("acme", now.Add(2*time.Minute)); st.Usage.DayUsed != 1 {
+		t.Errorf("DayUsed after midnight = %d, want 1", st.Usage.DayUsed)
+	}
+}
+
+func TestRegistry_ChainQuotas(t *testing.T) {
+	r, _ := Open("")
+	if _, err := r.Create(Tenant{
+		ID:          "acme",
+		Chains:      []uint64{1, 10},
+		Quota:       Quota{RequestsPerMinute: 1},
+		ChainQuotas: map[uint64]Quota{10: {}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1000, 0)
+
+	if _, err := r.Allow("acme", 137, now); !errors.Is(err, ErrChainNotAllowed) {
+		t.Errorf("Allow() on another chain error = %v, want ErrChainNotAllowed", err)
+	}
+	if _, err := r.Allow("acme", 0, now); err != nil {
+		t.Errorf("Allow() before the chain is known error = %v", err)
+	}
+
+	// Chain 10 overrides the default quota with an unlimited one
+	for i := 0; i < 5; i++ {
+		if _, err := r.Allow("acme", 10, now); err != nil {
+			t.Fatalf("unlimited chain request %d: %v", i+1, err)
+		}
+	}
+	if _, err := r.Allow("acme", 1, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Allow("acme", 1, now.Add(time.Minute)); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("second chain 1 request error = %v, want ErrQuotaExceeded", err)
+	}
+
+	if _, err := r.Allow("nobody", 1, now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Allow() for an unknown tenant error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRegistry_Keys(t *testing.T) {
+	r, _ := Open("")
+	key, err := r.Create(Tenant{ID: "acme", KeyHashes: []string{HashKey("chosen")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, "gas_") {
+		t.Errorf("issued key %q, want a gas_ key", key)
+	}
+
+	// Only the hash is kept, and requested hashes are ignored
+	st, _ := r.Get("acme", time.Now())
+	if len(st.Tenant.KeyHashes) != 1 || st.Tenant.KeyHashes[0] != HashKey(key) {
+		t.Errorf("KeyHashes = %v, want the issued key's hash", st.Tenant.KeyHashes)
+	}
+	if id, ok := r.Authenticate(key); !ok || id != "acme" {
+		t.Errorf("Authenticate(issued key) = %q, %v", id, ok)
+	}
+	for _, bad := range []string{"", "chosen", HashKey(key), key + "x"} {
+		if _, ok := r.Authenticate(bad); ok {
+			t.Errorf("Authenticate(%q) succeeded", bad)
+		}
+	}
+
+	// Issuing a key revokes the previous ones
+	next, err := r.IssueKey("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Authenticate(key); ok {
+		t.Error("revoked key still authenticates")
+	}
+	if id, ok := r.Authenticate(next); !ok || id != "acme" {
+		t.Errorf("Authenticate(new key) = %q, %v", id, ok)
+	}
+
+	// Disabled tenants keep their keys but can't use them
+	if err := r.Update(Tenant{ID: "acme", Disabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Authenticate(next); ok {
+		t.Error("disabled tenant authenticated")
+	}
+	if err := r.Update(Tenant{ID: "acme"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Authenticate(next); !ok {
+		t.Error("re-enabled tenant's key lost")
+	}
+}
+
+func TestHashKey(t *testing.T) {
+	// printf %s abc | sha256sum
+	if got := HashKey("abc"); got != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Errorf("HashKey(abc) = %s", got)
+	}
+}
+
+func TestRegistry_Admin(t *testing.T) {
+	r, _ := Open("")
+	for _, id := range []string{"zeta", "acme"} {
+		if _, err := r.Create(Tenant{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.Create(Tenant{ID: "acme"}); !errors.Is(err, ErrExists) {
+		t.Errorf("Create(duplicate) error = %v, want ErrExists", err)
+	}
+	if _, err := r.Create(Tenant{ID: "Bad ID"}); err == nil {
+		t.Error("Create() accepted an invalid ID")
+	}
+	if _, err := r.Create(Tenant{ID: "neg", Quota: Quota{RequestsPerDay: -1}}); err == nil {
+		t.Error("Create() accepted a negative quota")
+	}
+
+	list := r.List(time.Now())
+	if len(list) != 2 || list[0].Tenant.ID != "acme" || list[1].Tenant.ID != "zeta" {
+		t.Fatalf("List() = %+v, want acme and zeta in order", list)
+	}
+
+	key, _ := r.IssueKey("zeta")
+	if err := r.Delete("zeta"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Authenticate(key); ok {
+		t.Error("deleted tenant's key still authenticates")
+	}
+	if _, ok := r.Get("zeta", time.Now()); ok {
+		t.Error("deleted tenant still listed")
+	}
+	if err := r.Delete("zeta"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete(unknown) error = %v, want ErrNotFound", err)
+	}
+	if _, err := r.IssueKey("zeta"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("IssueKey(unknown) error = %v, want ErrNotFound", err)
+	}
+	if err := r.Update(Tenant{ID: "zeta"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update(unknown) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRegistry_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Tenant{
+		ID:          "acme",
+		Name:        "Acme Corp",
+		Chains:      []uint64{1, 10},
+		Quota:       Quota{RequestsPerMinute: 60, RequestsPerDay: 10000},
+		ChainQuotas: map[uint64]Quota{10: {RequestsPerMinute: 5}},
+	}
+	key, err := r.Create(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Create(Tenant{ID: "other", Disabled: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saved atomically: no temporary files left behind
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("directory has %d files after saving, want only the tenants file", len(entries))
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), key) {
+		t.Error("tenants file contains the plain API key")
+	}
+
+	loaded, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, ok := loaded.Get("acme", time.Now())
+	if !ok {
+		t.Fatal("tenant not loaded")
+	}
+	got := st.Tenant
+	if got.Name != want.Name || len(got.Chains) != 2 || got.Quota != want.Quota || got.ChainQuotas[10] != want.ChainQuotas[10] {
+		t.Errorf("loaded %+v, want %+v", got, want)
+	}
+	if id, ok := loaded.Authenticate(key); !ok || id != "acme" {
+		t.Errorf("Authenticate() after reload = %q, %v", id, ok)
+	}
+	if st, _ := loaded.Get("other", time.Now()); !st.Tenant.Disabled {
+		t.Error("disabled flag not saved")
+	}
+}
+
+func TestOpen_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"syntax":       `{"tenants": [`,
+		"invalid id":   `{"tenants": [{"id": "Not Valid"}]}`,
+		"duplicate id": `{"tenants": [{"id": "a"}, {"id": "a"}]}`,
+		"shared key":   `{"tenants": [{"id": "a", "key_hashes": ["h"]}, {"id": "b", "key_hashes": ["h"]}]}`,
+	}
+	for name, content := range tests {
+		path := filepath.Join(dir, strings.ReplaceAll(name, " ", "_")+".json")
+		os.WriteFile(path, []byte(content), 0o600)
+		if _, err := Open(path); err == nil {
+			t.Errorf("Open(%s) succeeded", name)
+		}
+	}
+
+	// A missing file is created on the first change
+	r, err := Open(filepath.Join(dir, "new.json"))
+	if err != nil || len(r.List(time.Now())) != 0 {
+		t.Fatalf("Open(missing) = %v, %v", r, err)
+	}
+}
@@ -0,0 +1,787 @@
+// Package testnode provides a scripted Ethereum node that speaks JSON-RPC
+// over HTTP and WebSocket, for end-to-end tests of the service's wire path.
+//
+// Tests add blocks and pending transactions, reorganize the chain and drop
+// connections; the node answers the RPC methods the estimator uses and
+// pushes eth_subscribe notifications to connected clients. Methods it does
+// not implement answer with JSON-RPC error -32601, as a node with the
+// namespace disabled would.
+package testnode
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+// Subscription events accepted by eth_subscribe.
+const (
+	eventNewHeads   = "newHeads"
+	eventPendingTxs = "newPendingTransactions"
+)
+
+// Node is a scripted Ethereum node served over HTTP and WebSocket on the
+// same address. All methods are safe for concurrent use.
+type Node struct {
+	srv *httptest.Server
+
+	mu      sync.Mutex
+	chainID uint64
+	blocks  map[uint64]*eth.Block
+	head    uint64
+	txs     map[string]*eth.Transaction
+	conns   map[*wsConn]struct{}
+	calls   map[string]int
+	nextSub uint64
+	mined   uint64
+	nonces  map[string]uint64
+	reverts map[string]bool
+	storage map[string]storageAccess
+	prices  map[string]uint64
+}
+
+// storageAccess is the storage a call reads.
+type storageAccess struct {
+	contract string
+	slots    []string
+}
+
+// New starts a node for chainID. It is closed by Close.
+func New(chainID uint64) *Node {
+	n := &Node{
+		chainID: chainID,
+		blocks:  make(map[uint64]*eth.Block),
+		txs:     make(map[string]*eth.Transaction),
+		conns:   make(map[*wsConn]struct{}),
+		calls:   make(map[string]int),
+		nonces:  make(map[string]uint64),
+		reverts: make(map[string]bool),
+		storage: make(map[string]storageAccess),
+		prices:  make(map[string]uint64),
+	}
+	n.srv = httptest.NewServer(http.HandlerFunc(n.serveHTTP))
+	return n
+}
+
+// HTTPURL returns the JSON-RPC endpoint.
+func (n *Node) HTTPURL() string {
+	return n.srv.URL
+}
+
+// WSURL returns the WebSocket endpoint.
+func (n *Node) WSURL() string {
+	return "ws" + strings.TrimPrefix(n.srv.URL, "http")
+}
+
+// Close drops all connections and stops the server.
+func (n *Node) Close() {
+	n.DropConnections()
+	n.srv.Close()
+}
+
+// AddBlock stores b, advances the head if b is newer and sends its header
+// to newHeads subscribers.
+func (n *Node) AddBlock(b *eth.Block) {
+	n.mu.Lock()
+	n.blocks[b.Number] = b
+	if b.Number > n.head {
+		n.head = b.Number
+	}
+	for _, tx := range b.Transactions {
+		delete(n.txs, tx.Hash)
+	}
+	conns := n.connsLocked()
+	n.mu.Unlock()
+
+	header := encodeBlock(b, false)
+	for _, c := range conns {
+		c.notify(eventNewHeads, func(full bool) any { return header })
+	}
+}
+
+// GasLimit is the gas limit of blocks built by Mine.
+const GasLimit = 30_000_000
+
+// Mine builds the block after the head, stamped with the current time and
+// half full, with one EIP-1559 transaction per priority fee, and adds it
+// like AddBlock. Fees are in wei.
+func (n *Node) Mine(baseFee uint64, priorityFees ...uint64) *eth.Block {
+	n.mu.Lock()
+	number := n.head + 1
+	if len(n.blocks) == 0 {
+		number = 0
+	}
+	b := n.buildLocked(number, baseFee, priorityFees)
+	n.mu.Unlock()
+
+	n.AddBlock(b)
+	return b
+}
+
+// MineReorg builds a replacement for block number, like Mine, and
+// reorganizes the chain onto it (see Reorg).
+func (n *Node) MineReorg(number, baseFee uint64, priorityFees ...uint64) *eth.Block {
+	n.mu.Lock()
+	b := n.buildLocked(number, baseFee, priorityFees)
+	n.mu.Unlock()
+
+	n.Reorg(b)
+	return b
+}
+
+func (n *Node) buildLocked(number, baseFee uint64, priorityFees []uint64) *eth.Block {
+	n.mined++
+	b := &eth.Block{
+		Number:    number,
+		Hash:      fmt.Sprintf("0x%032x%032x", number, n.mined),
+		Timestamp: time.Now().Truncate(time.Second),
+		BaseFee:   uint256.NewInt(baseFee),
+		GasUsed:   GasLimit / 2,
+		GasLimit:  GasLimit,
+	}
+	if parent, ok := n.blocks[number-1]; ok && number > 0 {
+		b.ParentHash = parent.Hash
+	}
+	for i, fee := range priorityFees {
+		b.Transactions = append(b.Transactions, eth.Transaction{
+			Hash:                 fmt.Sprintf("0x%032x%016x%016x", number, n.mined, i),
+			From:                 fmt.Sprintf("0x%040x", i+1),
+			GasLimit:             21000,
+			MaxFeePerGas:         uint256.NewInt(2*baseFee + fee),
+			MaxPriorityFeePerGas: uint256.NewInt(fee),
+			Type:                 2,
+		})
+	}
+	return b
+}
+
+// Reorg replaces the chain from b.Number on with b, which becomes the new
+// head, and sends its header to newHeads subscribers.
+func (n *Node) Reorg(b *eth.Block) {
+	n.mu.Lock()
+	for num := range n.blocks {
+		if num >= b.Number {
+			delete(n.blocks, num)
+		}
+	}
+	n.head = b.Number
+	n.mu.Unlock()
+
+	n.AddBlock(b)
+}
+
+// AddPendingTx adds tx to the mempool and announces it to
+// newPendingTransactions subscribers, as a hash or a full body depending on
+// how they subscribed.
+func (n *Node) AddPendingTx(tx *eth.Transaction) {
+	n.mu.Lock()
+	n.txs[tx.Hash] = tx
+	conns := n.connsLocked()
+	n.mu.Unlock()
+
+	body := encodeTx(tx)
+	for _, c := range conns {
+		c.notify(eventPendingTxs, func(full bool) any {
+			if full {
+				return body
+			}
+			return tx.Hash
+		})
+	}
+}
+
+// SetNonce sets the transaction count eth_getTransactionCount reports for
+// address.
+func (n *Node) SetNonce(address string, nonce uint64) {
+	n.mu.Lock()
+	n.nonces[strings.ToLower(address)] = nonce
+	n.mu.Unlock()
+}
+
+// Revert makes eth_estimateGas fail with "execution reverted" for calls to
+// address. Other calls use 21000 gas plus 16 per byte of data; contract
+// creations (no to address) add 32000 plus 200 per byte of init code.
+func (n *Node) Revert(address string) {
+	n.mu.Lock()
+	n.reverts[strings.ToLower(address)] = true
+	n.mu.Unlock()
+}
+
+// Storage makes calls to address read the given storage slots of contract,
+// which may be address itself. The contract and each slot cost a cold
+// access unless the call's access list declares them, and
+// eth_createAccessList returns them.
+func (n *Node) Storage(address, contract string, slots ...string) {
+	n.mu.Lock()
+	n.storage[strings.ToLower(address)] = storageAccess{contract: contract, slots: slots}
+	n.mu.Unlock()
+}
+
+// SetGasPrice sets what eth_gasPrice answers when asked for a price in
+// feeCurrency, a token contract address, or in wei for "" (1 gwei unless
+// set). Prices in other fee currencies fail.
+func (n *Node) SetGasPrice(feeCurrency string, price uint64) {
+	n.mu.Lock()
+	n.prices[strings.ToLower(feeCurrency)] = price
+	n.mu.Unlock()
+}
+
+// Head returns the number of the newest block.
+func (n *Node) Head() uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.head
+}
+
+// Calls returns how many times method was called, over HTTP or WebSocket.
+func (n *Node) Calls(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.calls[method]
+}
+
+// Subscriptions returns the number of open subscriptions to event
+// ("newHeads" or "newPendingTransactions").
+func (n *Node) Subscriptions(event string) int {
+	count := 0
+	for _, c := range n.connections() {
+		c.mu.Lock()
+		for _, s := range c.subs {
+			if s.event == event {
+				count++
+			}
+		}
+		c.mu.Unlock()
+	}
+	return count
+}
+
+// DropConnections closes every WebSocket connection without a close
+// handshake, as when a provider's load balancer goes away.
+func (n *Node) DropConnections() {
+	for _, c := range n.connections() {
+		c.conn.Close()
+	}
+}
+
+func (n *Node) connections() []*wsConn {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.connsLocked()
+}
+
+func (n *Node) connsLocked() []*wsConn {
+	conns := make([]*wsConn, 0, len(n.conns))
+	for c := range n.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// JSON-RPC messages.
+
+type request struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+var errMethodNotFound = &rpcError{Code: -32601, Message: "the method does not exist/is not available"}
+
+func (n *Node) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		n.serveWS(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	out, err := n.handleMessage(nil, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// handleMessage answers a single request or a batch. conn is nil for HTTP,
+// where subscriptions are not available.
+func (n *Node) handleMessage(conn *wsConn, body []byte) ([]byte, error) {
+	if trimmed := strings.TrimSpace(string(body)); strings.HasPrefix(trimmed, "[") {
+		var reqs []request
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			return nil, err
+		}
+		resps := make([]response, len(reqs))
+		for i, req := range reqs {
+			resps[i] = n.handle(conn, req)
+		}
+		return json.Marshal(resps)
+	}
+
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	return json.Marshal(n.handle(conn, req))
+}
+
+func (n *Node) handle(conn *wsConn, req request) response {
+	n.mu.Lock()
+	n.calls[req.Method]++
+	n.mu.Unlock()
+
+	resp := response{JSONRPC: "2.0", ID: req.ID}
+	result, err := n.dispatch(conn, req)
+	if err != nil {
+		var rerr *rpcError
+		if !errors.As(err, &rerr) {
+			rerr = &rpcError{Code: -32602, Message: err.Error()}
+		}
+		resp.Error = rerr
+		return resp
+	}
+	resp.Result = result
+	return resp
+}
+
+func (rerr *rpcError) Error() string {
+	return rerr.Message
+}
+
+func (n *Node) dispatch(conn *wsConn, req request) (any, error) {
+	switch req.Method {
+	case "eth_chainId":
+		return hexUint(n.chainID), nil
+
+	case "eth_blockNumber":
+		return hexUint(n.Head()), nil
+
+	case "eth_syncing":
+		return false, nil
+
+	case "net_peerCount":
+		return hexUint(1), nil
+
+	case "eth_getBlockByNumber":
+		var tag string
+		var full bool
+		if len(req.Params) > 0 {
+			json.Unmarshal(req.Params[0], &tag)
+		}
+		if len(req.Params) > 1 {
+			json.Unmarshal(req.Params[1], &full)
+		}
+		b, err := n.blockByTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if b == nil {
+			return nil, nil
+		}
+		return encodeBlock(b, full), nil
+
+	case "eth_getTransactionByHash":
+		var hash string
+		if len(req.Params) > 0 {
+			json.Unmarshal(req.Params[0], &hash)
+		}
+		n.mu.Lock()
+		tx, ok := n.txs[hash]
+		n.mu.Unlock()
+		if !ok {
+			return nil, nil
+		}
+		return encodeTx(tx), nil
+
+	case "eth_estimateGas", "eth_createAccessList":
+		var call struct {
+			To         string `json:"to"`
+			Data       string `json:"data"`
+			AccessList []struct {
+				Address     string   `json:"address"`
+				StorageKeys []string `json:"storageKeys"`
+			} `json:"accessList"`
+		}
+		if len(req.Params) > 0 {
+			json.Unmarshal(req.Params[0], &call)
+		}
+		n.mu.Lock()
+		reverts := n.reverts[strings.ToLower(call.To)]
+		access := n.storage[strings.ToLower(call.To)]
+		n.mu.Unlock()
+		if reverts {
+			return nil, &rpcError{Code: 3, Message: "execution reverted"}
+		}
+		size := uint64(len(strings.TrimPrefix(call.Data, "0x")) / 2)
+		gas := 21000 + 16*size
+		if call.To == "" {
+			gas += 32000 + 200*size
+		}
+
+		// Cold accesses cost 2600 per account and 2100 per slot, warm ones
+		// 100, after paying 2400 per address and 1900 per slot to declare
+		// them in the list. The called address is always warm.
+		listed, declared := false, 0
+		for _, t := range call.AccessList {
+			gas += 2400 + 1900*uint64(len(t.StorageKeys))
+			if access.contract != "" && strings.EqualFold(t.Address, access.contract) {
+				listed, declared = true, min(len(t.StorageKeys), len(access.slots))
+			}
+		}
+		if access.contract != "" && !strings.EqualFold(access.contract, call.To) {
+			if listed {
+				gas += 100
+			} else {
+				gas += 2600
+			}
+		}
+		gas += 100*uint64(declared) + 2100*uint64(len(access.slots)-declared)
+
+		if req.Method == "eth_createAccessList" {
+			list := []map[string]any{}
+			if access.contract != "" {
+				list = append(list, map[string]any{"address": access.contract, "storageKeys": access.slots})
+			}
+			return map[string]any{"accessList": list, "gasUsed": hexUint(gas)}, nil
+		}
+		return hexUint(gas), nil
+
+	case "eth_gasPrice":
+		var currency string
+		if len(req.Params) > 0 {
+			json.Unmarshal(req.Params[0], &currency)
+		}
+		n.mu.Lock()
+		price, ok := n.prices[strings.ToLower(currency)]
+		n.mu.Unlock()
+		switch {
+		case ok:
+			return hexUint(price), nil
+		case currency == "":
+			return hexUint(1e9), nil
+		}
+		return nil, &rpcError{Code: -32000, Message: "unsupported fee currency"}
+
+	case "eth_getTransactionCount":
+		var address string
+		if len(req.Params) > 0 {
+			json.Unmarshal(req.Params[0], &address)
+		}
+		n.mu.Lock()
+		nonce := n.nonces[strings.ToLower(address)]
+		n.mu.Unlock()
+		return hexUint(nonce), nil
+
+	case "eth_subscribe":
+		if conn == nil {
+			return nil, &rpcError{Code: -32601, Message: "notifications not supported"}
+		}
+		var event string
+		var full bool
+		if len(req.Params) > 0 {
+			json.Unmarshal(req.Params[0], &event)
+		}
+		if len(req.Params) > 1 {
+			json.Unmarshal(req.Params[1], &full)
+		}
+		if event != eventNewHeads && event != eventPendingTxs {
+			return nil, fmt.Errorf("unsupported subscription %q", event)
+		}
+		n.mu.Lock()
+		n.nextSub++
+		id := hexUint(n.nextSub)
+		n.mu.Unlock()
+		conn.mu.Lock()
+		conn.subs[id] = subscription{event: event, full: full}
+		conn.mu.Unlock()
+		return id, nil
+
+	case "eth_unsubscribe":
+		if conn == nil {
+			return false, nil
+		}
+		var id string
+		if len(req.Params) > 0 {
+			json.Unmarshal(req.Params[0], &id)
+		}
+		conn.mu.Lock()
+		_, ok := conn.subs[id]
+		delete(conn.subs, id)
+		conn.mu.Unlock()
+		return ok, nil
+
+	default:
+		return nil, errMethodNotFound
+	}
+}
+
+// blockByTag resolves "latest", "pending" (the head, as nodes without a
+// pending block report it) or a hex number. Unknown blocks are nil.
+func (n *Node) blockByTag(tag string) (*eth.Block, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var num uint64
+	switch tag {
+	case "latest", "pending", "":
+		num = n.head
+	default:
+		v, err := strconv.ParseUint(strings.TrimPrefix(tag, "0x"), 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block number %q", tag)
+		}
+		num = v
+	}
+	return n.blocks[num], nil
+}
+
+// Wire encoding.
+
+func hexUint(v uint64) string {
+	return "0x" + strconv.FormatUint(v, 16)
+}
+
+func hexInt(v *uint256.Int) *string {
+	if v == nil {
+		return nil
+	}
+	s := v.Hex()
+	return &s
+}
+
+func encodeBlock(b *eth.Block, full bool) map[string]any {
+	out := map[string]any{
+		"number":     hexUint(b.Number),
+		"hash":       b.Hash,
+		"parentHash": b.ParentHash,
+		"timestamp":  hexUint(uint64(b.Timestamp.Unix())),
+		"gasUsed":    hexUint(b.GasUsed),
+		"gasLimit":   hexUint(b.GasLimit),
+	}
+	if b.BaseFee != nil {
+		out["baseFeePerGas"] = hexInt(b.BaseFee)
+	}
+	if full {
+		txs := make([]any, len(b.Transactions))
+		for i := range b.Transactions {
+			txs[i] = encodeTx(&b.Transactions[i])
+		}
+		out["transactions"] = txs
+	} else {
+		hashes := make([]string, len(b.Transactions))
+		for i, tx := range b.Transactions {
+			hashes[i] = tx.Hash
+		}
+		out["transactions"] = hashes
+	}
+	return out
+}
+
+func encodeTx(tx *eth.Transaction) map[string]any {
+	out := map[string]any{
+		"hash":  tx.Hash,
+		"from":  tx.From,
+		"nonce": hexUint(tx.Nonce),
+		"gas":   hexUint(tx.GasLimit),
+		"type":  hexUint(uint64(tx.Type)),
+	}
+	if tx.To != "" {
+		out["to"] = tx.To
+	}
+	if tx.GasPrice != nil {
+		out["gasPrice"] = hexInt(tx.GasPrice)
+	}
+	if tx.MaxFeePerGas != nil {
+		out["maxFeePerGas"] = hexInt(tx.MaxFeePerGas)
+	}
+	if tx.MaxPriorityFeePerGas != nil {
+		out["maxPriorityFeePerGas"] = hexInt(tx.MaxPriorityFeePerGas)
+	}
+	return out
+}
+
+// WebSocket transport (RFC 6455), just enough for JSON-RPC text messages.
+
+type subscription struct {
+	event string
+	full  bool
+}
+
+type wsConn struct {
+	conn net.Conn
+
+	writeMu sync.Mutex
+
+	mu   sync.Mutex
+	subs map[string]subscription
+}
+
+func (n *Node) serveWS(w http.ResponseWriter, r *http.Request) {
+	h := sha1.New()
+	h.Write([]byte(r.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	w.Header().Set("Upgrade", "websocket")
+	w.Header().Set("Connection", "Upgrade")
+	w.Header().Set("Sec-WebSocket-Accept", base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	w.WriteHeader(http.StatusSwitchingProtocols)
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return
+	}
+	c := &wsConn{conn: conn, subs: make(map[string]subscription)}
+
+	n.mu.Lock()
+	n.conns[c] = struct{}{}
+	n.mu.Unlock()
+	defer func() {
+		n.mu.Lock()
+		delete(n.conns, c)
+		n.mu.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		op, payload, err := readFrame(rw.Reader)
+		if err != nil {
+			return
+		}
+		switch op {
+		case 0x1, 0x2: // text, binary
+			out, err := n.handleMessage(c, payload)
+			if err != nil {
+				continue
+			}
+			c.write(0x1, out)
+		case 0x8: // close
+			c.write(0x8, payload)
+			return
+		case 0x9: // ping
+			c.write(0xA, payload)
+		}
+	}
+}
+
+// notify sends an eth_subscription message for every subscription to
+// event; payload builds the result for full or hash-only subscriptions.
+func (c *wsConn) notify(event string, payload func(full bool) any) {
+	c.mu.Lock()
+	type target struct {
+		id   string
+		full bool
+	}
+	var targets []target
+	for id, s := range c.subs {
+		if s.event == event {
+			targets = append(targets, target{id, s.full})
+		}
+	}
+	c.mu.Unlock()
+
+	for _, t := range targets {
+		msg, _ := json.Marshal(map[string]any{
+			"jsonrpc": "2.0",
+			"method":  "eth_subscription",
+			"params": map[string]any{
+				"subscription": t.id,
+				"result":       payload(t.full),
+			},
+		})
+		c.write(0x1, msg)
+	}
+}
+
+// write sends one unmasked frame; errors surface on the next read.
+func (c *wsConn) write(opcode byte, payload []byte) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	c.conn.Write(append(header, payload...))
+}
+
+// readFrame reads one masked client frame. Fragmented messages are not
+// supported; the subscriber never sends them.
+func readFrame(r *bufio.Reader) (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	size := uint64(header[1] & 0x7F)
+	switch size {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return 0, nil, err
+		}
+		size = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(ext)
+	}
+	if size > eth.DefaultMaxMessageSize {
+		return 0, nil, errors.New("frame too large")
+	}
+	var mask [4]byte
+	if header[1]&0x80 != 0 {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return header[0] & 0x0F, payload, nil
+}
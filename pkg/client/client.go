@@ -0,0 +1,281 @@
+// Package client is a Go client for the gas estimation service's HTTP API.
+//
+// It decodes responses into typed estimates, retries transient failures,
+// rejects stale estimates and can fall back to the last good estimate while
+// the service is unreachable:
+//
+//	c := client.New("http://gas-estimator:9090", client.WithCacheFallback(30*time.Second))
+//	est, err := c.GetEstimate(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	tx.GasTipCap, tx.GasFeeCap = est.Fast.MaxPriorityFeePerGas, est.Fast.MaxFeePerGas
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// ErrStale indicates the service returned an estimate older than the
+// client's maximum age, e.g. because the estimator lost its node.
+var ErrStale = errors.New("estimate is stale")
+
+// APIError is an error response from the service.
+type APIError struct {
+	StatusCode int
+	Message    string
+
+	// Code is the service's canonical error code, e.g. UNAVAILABLE or
+	// RESOURCE_EXHAUSTED; empty for services predating error codes.
+	Code string
+
+	// RetryAfter is the wait the service asked for, if any (429 and 503).
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("gas API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// temporary reports whether the request may succeed if retried.
+func (e *APIError) temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests ||
+		(e.StatusCode >= 500 && e.StatusCode != http.StatusNotImplemented)
+}
+
+// Default client settings.
+const (
+	DefaultMaxAge       = 30 * time.Second
+	DefaultRetries      = 2
+	DefaultRetryBackoff = 100 * time.Millisecond
+)
+
+// maxRetryBackoff caps the wait between retries, including waits asked
+// for with Retry-After.
+const maxRetryBackoff = 5 * time.Second
+
+// Client calls the gas estimation API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	apiKey     string
+
+	maxAge        time.Duration
+	retries       int
+	retryBackoff  time.Duration
+	cacheFallback time.Duration
+	now           func() time.Time
+
+	mu        sync.Mutex
+	cached    *Estimate
+	fetchedAt time.Time
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests. Streams use it
+// too, so it should not set a Timeout; GetEstimate attempts are bounded by
+// their context, or 10s without a deadline. Default: a zero http.Client.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithAPIKey sends key in the X-API-Key header, for services running in
+// multi-tenant mode.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithMaxAge sets how long after publication an estimate is considered
+// stale. GetEstimate rejects stale estimates with ErrStale. Zero accepts
+// estimates of any age. Default: DefaultMaxAge.
+func WithMaxAge(d time.Duration) Option {
+	return func(c *Client) {
+		c.maxAge = d
+	}
+}
+
+// WithRetries sets how many times a failed request is retried, waiting
+// backoff before the first retry and doubling it for each further one.
+// Network errors, 429 and 5xx responses and stale estimates are retried.
+// Default: DefaultRetries and DefaultRetryBackoff.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = n
+		c.retryBackoff = backoff
+	}
+}
+
+// WithCacheFallback makes GetEstimate return the last good estimate, marked
+// Stale, when the service fails or returns a stale estimate, for up to
+// maxAge after it was fetched. The cached estimate may be for an older
+// block than the chain's head; callers signing transactions should prefer
+// the faster tiers when Stale is set. Disabled by default.
+func WithCacheFallback(maxAge time.Duration) Option {
+	return func(c *Client) {
+		c.cacheFallback = maxAge
+	}
+}
+
+// New creates a client for the service at baseURL, e.g.
+// "http://gas-estimator:9090".
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		httpClient:   &http.Client{},
+		maxAge:       DefaultMaxAge,
+		retries:      DefaultRetries,
+		retryBackoff: DefaultRetryBackoff,
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// requestTimeout bounds one GetEstimate attempt whose context has no
+// deadline.
+const requestTimeout = 10 * time.Second
+
+// GetEstimate returns the service's current estimate, retrying transient
+// failures. With WithCacheFallback it returns the last good estimate,
+// marked Stale, if every attempt fails.
+func (c *Client) GetEstimate(ctx context.Context) (*Estimate, error) {
+	var err error
+	backoff := c.retryBackoff
+	for attempt := 0; ; attempt++ {
+		var est *Estimate
+		est, err = c.fetchEstimate(ctx)
+		if err == nil {
+			c.mu.Lock()
+			c.cached, c.fetchedAt = est, c.now()
+			c.mu.Unlock()
+			return est, nil
+		}
+
+		var apiErr *APIError
+		retryable := errors.Is(err, ErrStale) || !errors.As(err, &apiErr) || apiErr.temporary()
+		if attempt >= c.retries || !retryable || ctx.Err() != nil {
+			break
+		}
+
+		wait := backoff
+		if apiErr != nil && apiErr.RetryAfter > wait {
+			wait = apiErr.RetryAfter
+		}
+		backoff *= 2
+		timer := time.NewTimer(min(wait, maxRetryBackoff))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return c.fallback(err)
+		case <-timer.C:
+		}
+	}
+	return c.fallback(err)
+}
+
+// fallback returns the cached estimate in place of err, if caching allows.
+func (c *Client) fallback(err error) (*Estimate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cacheFallback <= 0 || c.cached == nil || c.now().Sub(c.fetchedAt) > c.cacheFallback {
+		return nil, err
+	}
+	est := *c.cached
+	est.Stale = true
+	return &est, nil
+}
+
+// fetchEstimate makes one GetEstimate attempt.
+func (c *Client) fetchEstimate(ctx context.Context) (*Estimate, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+		defer cancel()
+	}
+
+	resp, err := c.do(ctx, "/v1/gas/estimate")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var wire wireEstimate
+	if err := json.NewDecoder(resp.Body).Decode(&wire); err != nil {
+		return nil, fmt.Errorf("decoding estimate: %w", err)
+	}
+	est, err := wire.estimate()
+	if err != nil {
+		return nil, err
+	}
+	if c.maxAge > 0 && est.Age(c.now()) > c.maxAge {
+		return nil, fmt.Errorf("%w: published %s ago", ErrStale, est.Age(c.now()).Round(time.Millisecond))
+	}
+	return est, nil
+}
+
+// do sends a GET request for path, returning the response if it is 200 OK
+// and an *APIError otherwise.
+func (c *Client) do(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending request: %w", err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	// Errors are RFC 7807 problem details; older services only set error
+	var body struct {
+		Detail         string `json:"detail"`
+		Error          string `json:"error"`
+		Code           string `json:"code"`
+		RetryAfterSecs int    `json:"retry_after_seconds"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	switch {
+	case json.Unmarshal(data, &body) != nil:
+		apiErr.Message = strings.TrimSpace(string(data))
+	case body.Detail != "":
+		apiErr.Message = body.Detail
+	case body.Error != "":
+		apiErr.Message = body.Error
+	default:
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	apiErr.Code = body.Code
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		apiErr.RetryAfter = time.Duration(secs) * time.Second
+	} else if body.RetryAfterSecs > 0 {
+		apiErr.RetryAfter = time.Duration(body.RetryAfterSecs) * time.Second
+	}
+	return nil, apiErr
+}
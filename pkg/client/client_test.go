@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// estimateJSON renders a GET /v1/gas/estimate response for block, published
+// at updated.
+func estimateJSON(block uint64, updated time.Time) string {
+	tier := `{"max_priority_fee_per_gas":"%d","max_fee_per_gas":"%d","confidence":0.9,"gas_price":"%d","sources":["history","mempool"]}`
+	return fmt.Sprintf(`{"chain_id":1,"block_number":%d,"timestamp":%q,"last_update":%q,"base_fee":"1000000000",
+		"generation":"%d-1","samples":{"blocks":20,"historical":900,"mempool":40,"pending_block":0},"estimates":{"urgent":`+tier+`,"fast":`+tier+`,"standard":`+tier+`,"slow":`+tier+`}}`,
+		block, updated.Format(time.RFC3339Nano), updated.Format(time.RFC3339Nano), block,
+		4, 2000000004, 1000000004, 3, 2000000003, 1000000003, 2, 2000000002, 1000000002, 1, 2000000001, 1000000001)
+}
+
+func TestClient_GetEstimate(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/gas/estimate" || r.Header.Get("X-API-Key") != "key" {
+			t.Errorf("request %s with key %q", r.URL.Path, r.Header.Get("X-API-Key"))
+		}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"error":"estimator not ready"}`)
+			return
+		}
+		fmt.Fprint(w, estimateJSON(100, time.Now()))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL+"/", WithAPIKey("key"), WithRetries(1, time.Millisecond))
+	est, err := c.GetEstimate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want a retry after 503", calls.Load())
+	}
+	if est.BlockNumber != 100 || est.BaseFee.Uint64() != 1e9 || est.Generation != "100-1" || est.Stale {
+		t.Errorf("estimate = %+v", est)
+	}
+	if est.Fast.MaxPriorityFeePerGas.Uint64() != 3 || est.Fast.MaxFeePerGas.Uint64() != 2000000003 || est.Slow.GasPrice.Uint64() != 1000000001 {
+		t.Errorf("fast = %+v, slow = %+v", est.Fast, est.Slow)
+	}
+	if est.Samples.Blocks != 20 || est.Samples.Historical != 900 || len(est.Urgent.Sources) != 2 {
+		t.Errorf("samples = %+v, urgent sources = %v", est.Samples, est.Urgent.Sources)
+	}
+}
+
+func TestClient_GetEstimateErrors(t *testing.T) {
+	var (
+		calls   atomic.Int32
+		status  atomic.Int32
+		updated atomic.Pointer[time.Time]
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if s := int(status.Load()); s != http.StatusOK {
+			w.WriteHeader(s)
+			fmt.Fprint(w, `{"error":"boom"}`)
+			return
+		}
+		fmt.Fprint(w, estimateJSON(100, *updated.Load()))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithRetries(2, time.Millisecond), WithMaxAge(time.Minute))
+	get := func() (*Estimate, error) {
+		calls.Store(0)
+		return c.GetEstimate(context.Background())
+	}
+
+	// Client errors are not retried
+	status.Store(http.StatusNotFound)
+	_, err := get()
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "boom" || calls.Load() != 1 {
+		t.Errorf("404: err = %v after %d calls, want one APIError", err, calls.Load())
+	}
+
+	// Stale estimates are retried, then rejected
+	status.Store(http.StatusOK)
+	old := time.Now().Add(-time.Hour)
+	updated.Store(&old)
+	if _, err := get(); !errors.Is(err, ErrStale) || calls.Load() != 3 {
+		t.Errorf("stale: err = %v after %d calls, want ErrStale after 3", err, calls.Load())
+	}
+
+	// With a cache, the last good estimate stands in
+	c = New(srv.URL, WithRetries(0, 0), WithCacheFallback(time.Minute))
+	now := time.Now()
+	updated.Store(&now)
+	if _, err := get(); err != nil {
+		t.Fatal(err)
+	}
+	status.Store(http.StatusInternalServerError)
+	est, err := get()
+	if err != nil || !est.Stale || est.BlockNumber != 100 {
+		t.Errorf("fallback = %+v, %v; want the cached estimate marked stale", est, err)
+	}
+
+	// Until it is too old
+	c.now = func() time.Time { return now.Add(2 * time.Minute) }
+	if _, err := get(); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expired fallback: err = %v, want the 500", err)
+	}
+}
+
+func TestClient_ProblemDetails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"type":"about:blank","title":"Service Unavailable","status":503,"detail":"estimator not ready","code":"UNAVAILABLE","retry_after_seconds":1,"error":"estimator not ready"}`)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithRetries(0, 0))
+	_, err := c.GetEstimate(context.Background())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want an APIError", err)
+	}
+	if apiErr.Message != "estimator not ready" || apiErr.Code != "UNAVAILABLE" || apiErr.RetryAfter != time.Second {
+		t.Errorf("APIError = %+v, want the problem's detail, code and retry hint", apiErr)
+	}
+}
+
+func TestClient_StreamEstimates(t *testing.T) {
+	var conns atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		event := `data: {"block_number":%d,"base_fee":"100","urgent":"4","fast":"3","standard":"2","slow":"1"}` + "\n\n"
+		switch conns.Add(1) {
+		case 1:
+			fmt.Fprintf(w, event, 1)
+			fmt.Fprintf(w, event, 2)
+			fmt.Fprint(w, "event: shutdown\ndata: {\"reason\":\"server shutting down\"}\n\n")
+		default:
+			// The new server repeats block 2 before moving on
+			fmt.Fprintf(w, event, 2)
+			fmt.Fprintf(w, event, 3)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := New(srv.URL, WithRetries(0, time.Millisecond))
+	updates, err := c.StreamEstimates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var blocks []uint64
+	for len(blocks) < 3 {
+		select {
+		case u := <-updates:
+			if u.Fast.Uint64() != 3 || u.BaseFee.Uint64() != 100 {
+				t.Errorf("update = %+v", u)
+			}
+			blocks = append(blocks, u.BlockNumber)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("received blocks %v, want 1, 2, 3", blocks)
+		}
+	}
+	if blocks[0] != 1 || blocks[1] != 2 || blocks[2] != 3 {
+		t.Errorf("blocks = %v, want [1 2 3]", blocks)
+	}
+
+	cancel()
+	for range updates {
+	}
+}
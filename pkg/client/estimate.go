@@ -0,0 +1,177 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/holiman/uint256"
+)
+
+// Estimate is a gas price estimate from GET /v1/gas/estimate. Fees are in
+// wei.
+type Estimate struct {
+	ChainID     uint64
+	BlockNumber uint64
+	BaseFee     *uint256.Int
+
+	Urgent   Tier
+	Fast     Tier
+	Standard Tier
+	Slow     Tier
+
+	// Timestamp is when the estimate was calculated and LastUpdate when it
+	// was published. ValidUntil is when the next block is expected; zero
+	// when the chain's block cadence is unknown.
+	Timestamp  time.Time
+	LastUpdate time.Time
+	ValidUntil time.Time
+
+	// Generation identifies this exact estimate on the service.
+	Generation string
+
+	Strategy         string
+	EstimatorVersion string
+
+	// Degraded is set while the service polls for blocks after losing its
+	// node subscription; the estimate may lag the chain.
+	Degraded bool
+
+	// Samples is how much data the tiers were derived from.
+	Samples Samples
+
+	// Stale is set when the estimate was served from the client's cache
+	// because the service failed (see WithCacheFallback).
+	Stale bool
+}
+
+// Tier is the estimate for one priority level.
+type Tier struct {
+	MaxPriorityFeePerGas *uint256.Int
+	MaxFeePerGas         *uint256.Int
+
+	// GasPrice is the equivalent price for legacy transactions.
+	GasPrice *uint256.Int
+
+	Confidence float64
+
+	// Sources are where the priority fee came from: "history", "mempool",
+	// "pending_block" or, when none had samples, "default".
+	Sources []string
+}
+
+// Samples counts the data behind an estimate: the recent blocks that
+// contributed priority fees and the fees sampled from each source.
+type Samples struct {
+	Blocks       int `json:"blocks"`
+	Historical   int `json:"historical"`
+	Mempool      int `json:"mempool"`
+	PendingBlock int `json:"pending_block"`
+}
+
+// Age returns how long before now the estimate was published.
+func (e *Estimate) Age(now time.Time) time.Duration {
+	published := e.LastUpdate
+	if published.IsZero() {
+		published = e.Timestamp
+	}
+	return now.Sub(published)
+}
+
+// wireEstimate is the JSON shape of GET /v1/gas/estimate, limited to the
+// fields Estimate carries.
+type wireEstimate struct {
+	ChainID     uint64 `json:"chain_id"`
+	BlockNumber uint64 `json:"block_number"`
+	Timestamp   string `json:"timestamp"`
+	BaseFee     string `json:"base_fee"`
+	Estimates   struct {
+		Urgent   wireTier `json:"urgent"`
+		Fast     wireTier `json:"fast"`
+		Standard wireTier `json:"standard"`
+		Slow     wireTier `json:"slow"`
+	} `json:"estimates"`
+	LastUpdate       string  `json:"last_update"`
+	Generation       string  `json:"generation"`
+	ValidUntil       string  `json:"valid_until"`
+	Strategy         string  `json:"strategy"`
+	EstimatorVersion string  `json:"estimator_version"`
+	Degraded         bool    `json:"degraded"`
+	Samples          Samples `json:"samples"`
+}
+
+type wireTier struct {
+	MaxPriorityFeePerGas string   `json:"max_priority_fee_per_gas"`
+	MaxFeePerGas         string   `json:"max_fee_per_gas"`
+	GasPrice             string   `json:"gas_price"`
+	Confidence           float64  `json:"confidence"`
+	Sources              []string `json:"sources"`
+}
+
+// estimate converts the response, failing on malformed fields.
+func (w *wireEstimate) estimate() (*Estimate, error) {
+	est := &Estimate{
+		ChainID:          w.ChainID,
+		BlockNumber:      w.BlockNumber,
+		Generation:       w.Generation,
+		Strategy:         w.Strategy,
+		EstimatorVersion: w.EstimatorVersion,
+		Degraded:         w.Degraded,
+		Samples:          w.Samples,
+	}
+
+	var err error
+	if est.BaseFee, err = parseWei("base_fee", w.BaseFee); err != nil {
+		return nil, err
+	}
+	for _, t := range []struct {
+		name string
+		wire wireTier
+		dst  *Tier
+	}{
+		{"urgent", w.Estimates.Urgent, &est.Urgent},
+		{"fast", w.Estimates.Fast, &est.Fast},
+		{"standard", w.Estimates.Standard, &est.Standard},
+		{"slow", w.Estimates.Slow, &est.Slow},
+	} {
+		if t.dst.MaxPriorityFeePerGas, err = parseWei(t.name+".max_priority_fee_per_gas", t.wire.MaxPriorityFeePerGas); err != nil {
+			return nil, err
+		}
+		if t.dst.MaxFeePerGas, err = parseWei(t.name+".max_fee_per_gas", t.wire.MaxFeePerGas); err != nil {
+			return nil, err
+		}
+		if t.wire.GasPrice != "" {
+			if t.dst.GasPrice, err = parseWei(t.name+".gas_price", t.wire.GasPrice); err != nil {
+				return nil, err
+			}
+		}
+		t.dst.Confidence = t.wire.Confidence
+		t.dst.Sources = t.wire.Sources
+	}
+
+	for _, ts := range []struct {
+		name  string
+		value string
+		dst   *time.Time
+	}{
+		{"timestamp", w.Timestamp, &est.Timestamp},
+		{"last_update", w.LastUpdate, &est.LastUpdate},
+		{"valid_until", w.ValidUntil, &est.ValidUntil},
+	} {
+		if ts.value == "" {
+			continue
+		}
+		if *ts.dst, err = time.Parse(time.RFC3339Nano, ts.value); err != nil {
+			return nil, fmt.Errorf("invalid %s %q", ts.name, ts.value)
+		}
+	}
+	return est, nil
+}
+
+// parseWei parses a decimal wei amount.
+func parseWei(field, s string) (*uint256.Int, error) {
+	v, err := uint256.FromDecimal(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q", field, s)
+	}
+	return v, nil
+}
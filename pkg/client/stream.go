@@ -0,0 +1,168 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/holiman/uint256"
+)
+
+// maxStreamBackoff caps the wait between stream reconnects.
+const maxStreamBackoff = 30 * time.Second
+
+// Update is one event from GET /v1/gas/estimate/stream: the base fee and
+// each tier's priority fee, in wei.
+type Update struct {
+	BlockNumber uint64
+	BaseFee     *uint256.Int
+
+	Urgent   *uint256.Int
+	Fast     *uint256.Int
+	Standard *uint256.Int
+	Slow     *uint256.Int
+}
+
+// StreamEstimates streams an update for every new block until ctx ends,
+// when the returned channel is closed. Dropped connections, including the
+// service shutting down, are reconnected with backoff, so the stream
+// survives rolling deploys; updates for blocks already delivered are
+// skipped. The error is from the first connection attempt only.
+func (c *Client) StreamEstimates(ctx context.Context) (<-chan Update, error) {
+	stream, err := c.openStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(chan Update, 16)
+	go func() {
+		defer close(updates)
+
+		var last uint64
+		backoff := c.retryBackoff
+		for {
+			if stream != nil {
+				if c.readStream(ctx, stream, updates, &last) {
+					backoff = c.retryBackoff
+				}
+				stream.Close()
+				stream = nil
+			}
+
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			backoff = min(max(backoff*2, time.Millisecond), maxStreamBackoff)
+
+			stream, _ = c.openStream(ctx)
+		}
+	}()
+	return updates, nil
+}
+
+// openStream connects to the stream endpoint.
+func (c *Client) openStream(ctx context.Context) (*sseStream, error) {
+	resp, err := c.do(ctx, "/v1/gas/estimate/stream")
+	if err != nil {
+		return nil, err
+	}
+	return &sseStream{body: resp.Body, scanner: bufio.NewScanner(resp.Body)}, nil
+}
+
+// readStream forwards updates newer than *last until the stream ends,
+// reporting whether any arrived.
+func (c *Client) readStream(ctx context.Context, stream *sseStream, updates chan<- Update, last *uint64) bool {
+	received := false
+	for {
+		event, data, ok := stream.next()
+		if !ok || event == "shutdown" {
+			return received
+		}
+		received = true
+		if event != "" && event != "message" {
+			continue
+		}
+		u, err := parseUpdate(data)
+		if err != nil || u.BlockNumber <= *last {
+			continue
+		}
+		*last = u.BlockNumber
+		select {
+		case updates <- u:
+		case <-ctx.Done():
+			return received
+		}
+	}
+}
+
+// parseUpdate decodes one stream event's data.
+func parseUpdate(data string) (Update, error) {
+	var wire struct {
+		BlockNumber uint64 `json:"block_number"`
+		BaseFee     string `json:"base_fee"`
+		Urgent      string `json:"urgent"`
+		Fast        string `json:"fast"`
+		Standard    string `json:"standard"`
+		Slow        string `json:"slow"`
+	}
+	if err := json.Unmarshal([]byte(data), &wire); err != nil {
+		return Update{}, fmt.Errorf("decoding update: %w", err)
+	}
+
+	u := Update{BlockNumber: wire.BlockNumber}
+	var err error
+	for _, f := range []struct {
+		name, value string
+		dst         **uint256.Int
+	}{
+		{"base_fee", wire.BaseFee, &u.BaseFee},
+		{"urgent", wire.Urgent, &u.Urgent},
+		{"fast", wire.Fast, &u.Fast},
+		{"standard", wire.Standard, &u.Standard},
+		{"slow", wire.Slow, &u.Slow},
+	} {
+		if *f.dst, err = parseWei(f.name, f.value); err != nil {
+			return Update{}, err
+		}
+	}
+	return u, nil
+}
+
+// sseStream reads server-sent events.
+type sseStream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+}
+
+// next returns the next event's type (empty if unnamed) and data, or false
+// once the stream ends.
+func (s *sseStream) next() (event, data string, ok bool) {
+	var lines []string
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		switch {
+		case line == "":
+			if event != "" || lines != nil {
+				return event, strings.Join(lines, "\n"), true
+			}
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			lines = append(lines, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	return "", "", false
+}
+
+// Close closes the underlying connection.
+func (s *sseStream) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,137 @@
+package estimator
+
+import (
+	"sync"
+	"time"
+
+	"github.com/holiman/uint256"
+)
+
+// AccuracyReader reports how often published recommendations would have
+// been included. Implemented by Provider; used by the accuracy API.
+type AccuracyReader interface {
+	Accuracy(window time.Duration) Accuracy
+}
+
+// Accuracy summarizes the blocks scored within a window.
+//
+// Each new block is scored against the estimate being served when it
+// arrived, which was computed for an earlier block. A tier hits when its
+// MaxPriorityFeePerGas is at least the smallest nonzero priority fee
+// included in the block, i.e. a transaction at that tier would have
+// outbid someone who made it in. Blocks without fee-paying transactions
+// are not scored.
+type Accuracy struct {
+	Window time.Duration
+	Blocks int
+
+	Urgent   TierAccuracy
+	Fast     TierAccuracy
+	Standard TierAccuracy
+	Slow     TierAccuracy
+}
+
+// TierAccuracy is one tier's hit count over a window.
+type TierAccuracy struct {
+	Hits int
+
+	// Rate is Hits divided by the window's scored blocks; 0 if none were.
+	Rate float64
+}
+
+// defaultAccuracyCapacity is the number of scored blocks retained (~27h on mainnet).
+const defaultAccuracyCapacity = 8192
+
+// blockScore is the outcome of one scored block.
+type blockScore struct {
+	number uint64
+	at     time.Time
+	hits   [4]bool // urgent, fast, standard, slow
+}
+
+// accuracyLog is a ring buffer of block scores.
+type accuracyLog struct {
+	mu     sync.RWMutex
+	scores []blockScore
+	head   int
+	count  int
+}
+
+func newAccuracyLog(capacity int) *accuracyLog {
+	return &accuracyLog{scores: make([]blockScore, capacity)}
+}
+
+// score records how est's tiers fared against block, dated now if the block
+// has no timestamp. It does nothing if est was computed at or after block,
+// or block has no priority fees.
+func (l *accuracyLog) score(est *GasEstimate, block *BlockData, now time.Time) {
+	if est == nil || est.BlockNumber >= block.Number || len(block.PriorityFees) == 0 || len(l.scores) == 0 {
+		return
+	}
+
+	minFee := minPriorityFee(block.PriorityFees)
+	s := blockScore{number: block.Number, at: block.Timestamp}
+	if s.at.IsZero() {
+		s.at = now
+	}
+	for i, tier := range []PriorityEstimate{est.Urgent, est.Fast, est.Standard, est.Slow} {
+		s.hits[i] = tier.MaxPriorityFeePerGas != nil && !tier.MaxPriorityFeePerGas.Lt(minFee)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// A reorg or replayed block replaces the previous score
+	if l.count > 0 {
+		last := (l.head - 1 + len(l.scores)) % len(l.scores)
+		if l.scores[last].number == s.number {
+			l.scores[last] = s
+			return
+		}
+	}
+	l.scores[l.head] = s
+	l.head = (l.head + 1) % len(l.scores)
+	if l.count < len(l.scores) {
+		l.count++
+	}
+}
+
+// summarize aggregates the scores of blocks at or after now-window.
+func (l *accuracyLog) summarize(window time.Duration, now time.Time) Accuracy {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	acc := Accuracy{Window: window}
+	tiers := []*TierAccuracy{&acc.Urgent, &acc.Fast, &acc.Standard, &acc.Slow}
+	since := now.Add(-window)
+	size := len(l.scores)
+	for i := 0; i < l.count; i++ {
+		s := l.scores[(l.head-1-i+size)%size]
+		if s.at.Before(since) {
+			continue
+		}
+		acc.Blocks++
+		for t, hit := range s.hits {
+			if hit {
+				tiers[t].Hits++
+			}
+		}
+	}
+	if acc.Blocks > 0 {
+		for _, t := range tiers {
+			t.Rate = float64(t.Hits) / float64(acc.Blocks)
+		}
+	}
+	return acc
+}
+
+// minPriorityFee returns the smallest of fees, which must not be empty.
+func minPriorityFee(fees []*uint256.Int) *uint256.Int {
+	minFee := fees[0]
+	for _, fee := range fees[1:] {
+		if fee.Lt(minFee) {
+			minFee = fee
+		}
+	}
+	return minFee
+}
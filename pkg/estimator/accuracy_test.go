@@ -0,0 +1,74 @@
+package estimator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+)
+
+func TestAccuracyLog(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	tip := func(urgent, fast, standard, slow uint64) *GasEstimate {
+		return &GasEstimate{
+			BlockNumber: 100,
+			Urgent:      PriorityEstimate{MaxPriorityFeePerGas: uint256.NewInt(urgent)},
+			Fast:        PriorityEstimate{MaxPriorityFeePerGas: uint256.NewInt(fast)},
+			Standard:    PriorityEstimate{MaxPriorityFeePerGas: uint256.NewInt(standard)},
+			Slow:        PriorityEstimate{MaxPriorityFeePerGas: uint256.NewInt(slow)},
+		}
+	}
+	block := func(n uint64, age time.Duration, fees ...uint64) *BlockData {
+		b := &BlockData{Number: n, Timestamp: now.Add(-age)}
+		for _, f := range fees {
+			b.PriorityFees = append(b.PriorityFees, uint256.NewInt(f))
+		}
+		return b
+	}
+
+	l := newAccuracyLog(4)
+	est := tip(40, 30, 20, 10)
+
+	l.score(est, block(101, 2*time.Hour, 5, 50), now)  // every tier hits (min 5)
+	l.score(est, block(102, 30*time.Minute, 25), now)  // urgent, fast
+	l.score(est, block(103, 10*time.Minute, 35), now)  // urgent
+	l.score(est, block(103, 10*time.Minute, 15), now)  // replaces 103: urgent, fast, standard
+	l.score(est, block(104, time.Minute), now)         // no fees: skipped
+	l.score(est, block(100, time.Minute, 1), now)      // not after the estimate: skipped
+	l.score(nil, block(105, time.Minute, 1), now)      // no estimate: skipped
+	l.score(tip(0, 0, 0, 0), block(106, 0, 1, 2), now) // nothing hits
+
+	tests := []struct {
+		window time.Duration
+		blocks int
+		hits   [4]int
+	}{
+		{window: 3 * time.Hour, blocks: 4, hits: [4]int{3, 3, 2, 1}},
+		{window: time.Hour, blocks: 3, hits: [4]int{2, 2, 1, 0}},
+		{window: time.Second, blocks: 1, hits: [4]int{0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		acc := l.summarize(tt.window, now)
+		if acc.Window != tt.window || acc.Blocks != tt.blocks {
+			t.Fatalf("summarize(%v): window=%v blocks=%d, want %v, %d", tt.window, acc.Window, acc.Blocks, tt.window, tt.blocks)
+		}
+		for i, tier := range []TierAccuracy{acc.Urgent, acc.Fast, acc.Standard, acc.Slow} {
+			if tier.Hits != tt.hits[i] {
+				t.Errorf("summarize(%v) tier %d hits = %d, want %d", tt.window, i, tier.Hits, tt.hits[i])
+			}
+			if want := float64(tt.hits[i]) / float64(tt.blocks); tier.Rate != want {
+				t.Errorf("summarize(%v) tier %d rate = %v, want %v", tt.window, i, tier.Rate, want)
+			}
+		}
+	}
+
+	// The ring keeps only the newest scores
+	l.score(est, block(107, 0, 1), now)
+	if acc := l.summarize(3*time.Hour, now); acc.Blocks != 4 || acc.Slow.Hits != 1 {
+		t.Errorf("after wrap: blocks=%d slow hits=%d, want 4, 1", acc.Blocks, acc.Slow.Hits)
+	}
+
+	if acc := newAccuracyLog(4).summarize(time.Hour, now); acc.Blocks != 0 || acc.Urgent.Rate != 0 {
+		t.Errorf("empty log: %+v", acc)
+	}
+}
@@ -0,0 +1,194 @@
+package estimator
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// AnomalyKind identifies a class of abnormal fee conditions.
+type AnomalyKind string
+
+const (
+	// AnomalyBaseFeeSpike: the base fee rose by AnomalyThresholds.BaseFeeRatio
+	// within BaseFeeWindow blocks.
+	AnomalyBaseFeeSpike AnomalyKind = "base_fee_spike"
+
+	// AnomalyMempoolSurge: pending transactions arrived at
+	// AnomalyThresholds.MempoolRatio times the usual rate since the last block.
+	AnomalyMempoolSurge AnomalyKind = "mempool_surge"
+
+	// AnomalyPriorityFeeSpike: a block's median priority fee reached
+	// AnomalyThresholds.PriorityFeeRatio times the usual median.
+	AnomalyPriorityFeeSpike AnomalyKind = "priority_fee_spike"
+)
+
+// Anomaly is an abnormal condition detected when a block arrived.
+type Anomaly struct {
+	Kind        AnomalyKind
+	ChainID     uint64
+	BlockNumber uint64
+	DetectedAt  time.Time
+
+	// Value is the observed level and Baseline the level it is compared
+	// with: wei for fee spikes, pending transactions seen per block for
+	// mempool surges. Ratio is Value / Baseline.
+	Value    float64
+	Baseline float64
+	Ratio    float64
+}
+
+// AnomalyThresholds configures anomaly detection. A zero ratio disables
+// that check.
+type AnomalyThresholds struct {
+	// BaseFeeRatio flags a base fee at least this multiple of the lowest
+	// base fee in the previous BaseFeeWindow blocks.
+	// Default: 2 within 5 blocks
+	BaseFeeRatio  float64
+	BaseFeeWindow int
+
+	// MempoolRatio flags a block interval in which at least this multiple
+	// of the average pending transaction arrivals over the previous
+	// BaselineWindow blocks was seen.
+	// Default: 5
+	MempoolRatio float64
+
+	// PriorityFeeRatio flags a block whose median priority fee is at least
+	// this multiple of the median of the previous BaselineWindow blocks'
+	// medians.
+	// Default: 3
+	PriorityFeeRatio float64
+
+	// BaselineWindow is the number of blocks behind mempool and priority
+	// fee baselines.
+	// Default: 20
+	BaselineWindow int
+}
+
+// DefaultAnomalyThresholds returns the default anomaly thresholds.
+func DefaultAnomalyThresholds() AnomalyThresholds {
+	return AnomalyThresholds{
+		BaseFeeRatio:     2,
+		BaseFeeWindow:    5,
+		MempoolRatio:     5,
+		PriorityFeeRatio: 3,
+		BaselineWindow:   20,
+	}
+}
+
+const (
+	// minBaselineBlocks is the number of earlier blocks needed before a
+	// block is checked.
+	minBaselineBlocks = 3
+
+	// minSurgeArrivals ignores mempool surges smaller than this many
+	// transactions, which are noise on quiet chains.
+	minSurgeArrivals = 100
+)
+
+// blockStats is what the detector remembers about each block.
+type blockStats struct {
+	number    uint64
+	baseFee   float64
+	medianTip float64
+	arrivals  uint64
+}
+
+// anomalyDetector checks each new block against the recent ones. Each kind
+// is reported once when its condition starts to hold and re-armed when it
+// stops.
+type anomalyDetector struct {
+	th AnomalyThresholds
+
+	mu     sync.Mutex
+	recent []blockStats // oldest first
+	active map[AnomalyKind]bool
+	counts map[AnomalyKind]uint64
+}
+
+func newAnomalyDetector(th AnomalyThresholds) *anomalyDetector {
+	return &anomalyDetector{
+		th:     th,
+		active: make(map[AnomalyKind]bool),
+		counts: make(map[AnomalyKind]uint64),
+	}
+}
+
+// observe records block, with arrivals pending transactions seen since the
+// previous block, and returns the anomalies it starts.
+func (d *anomalyDetector) observe(block *BlockData, arrivals uint64) []Anomaly {
+	cur := blockStats{
+		number:    block.Number,
+		baseFee:   weiToFloat(block.BaseFee),
+		medianTip: weiToFloat(newFeeSample(block.PriorityFees, nil).at(0.5)),
+		arrivals:  arrivals,
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Replayed or reorged heights don't count as new blocks
+	if n := len(d.recent); n > 0 && cur.number <= d.recent[n-1].number {
+		return nil
+	}
+
+	var found []Anomaly
+	check := func(kind AnomalyKind, value, baseline, ratio float64) {
+		met := ratio > 0 && baseline > 0 && value >= ratio*baseline
+		if met && !d.active[kind] {
+			d.counts[kind]++
+			found = append(found, Anomaly{
+				Kind:        kind,
+				BlockNumber: cur.number,
+				Value:       value,
+				Baseline:    baseline,
+				Ratio:       value / baseline,
+			})
+		}
+		d.active[kind] = met
+	}
+
+	if len(d.recent) >= minBaselineBlocks {
+		window := d.recent[max(len(d.recent)-max(d.th.BaseFeeWindow, 1), 0):]
+		lowest := window[0].baseFee
+		for _, b := range window[1:] {
+			lowest = min(lowest, b.baseFee)
+		}
+		check(AnomalyBaseFeeSpike, cur.baseFee, lowest, d.th.BaseFeeRatio)
+
+		baseline := d.recent[max(len(d.recent)-max(d.th.BaselineWindow, 1), 0):]
+		var total float64
+		tips := make([]float64, 0, len(baseline))
+		for _, b := range baseline {
+			total += float64(b.arrivals)
+			if b.medianTip > 0 {
+				tips = append(tips, b.medianTip)
+			}
+		}
+		if cur.arrivals >= minSurgeArrivals {
+			check(AnomalyMempoolSurge, float64(cur.arrivals), total/float64(len(baseline)), d.th.MempoolRatio)
+		} else {
+			d.active[AnomalyMempoolSurge] = false
+		}
+		var tipBaseline float64
+		if len(tips) > 0 {
+			slices.Sort(tips)
+			tipBaseline = tips[len(tips)/2]
+		}
+		check(AnomalyPriorityFeeSpike, cur.medianTip, tipBaseline, d.th.PriorityFeeRatio)
+	}
+
+	d.recent = append(d.recent, cur)
+	if keep := max(d.th.BaseFeeWindow, d.th.BaselineWindow, minBaselineBlocks); len(d.recent) > keep {
+		d.recent = slices.Delete(d.recent, 0, len(d.recent)-keep)
+	}
+	return found
+}
+
+// totals returns the number of anomalies reported per kind.
+func (d *anomalyDetector) totals() map[AnomalyKind]uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return maps.Clone(d.counts)
+}
@@ -0,0 +1,100 @@
+package estimator
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestAnomalyDetector(t *testing.T) {
+	gwei := func(v uint64) *uint256.Int { return uint256.NewInt(v * 1e9) }
+	block := func(n, baseFee, tip uint64) *BlockData {
+		return &BlockData{Number: n, BaseFee: gwei(baseFee), PriorityFees: []*uint256.Int{gwei(tip)}}
+	}
+
+	type step struct {
+		block    *BlockData
+		arrivals uint64
+		want     []AnomalyKind
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "steady",
+			steps: []step{
+				{block(1, 10, 1), 50, nil},
+				{block(2, 11, 1), 60, nil},
+				{block(3, 10, 1), 50, nil},
+				{block(4, 12, 1), 70, nil},
+			},
+		},
+		{
+			name: "base fee doubles, reported once until it recovers",
+			steps: []step{
+				{block(1, 10, 1), 0, nil},
+				{block(2, 10, 1), 0, nil},
+				{block(3, 10, 1), 0, nil},
+				{block(4, 20, 1), 0, []AnomalyKind{AnomalyBaseFeeSpike}},
+				{block(5, 22, 1), 0, nil},
+				{block(6, 10, 1), 0, nil},
+				{block(7, 10, 1), 0, nil},
+				{block(8, 20, 1), 0, []AnomalyKind{AnomalyBaseFeeSpike}},
+			},
+		},
+		{
+			name: "mempool surge",
+			steps: []step{
+				{block(1, 10, 1), 40, nil},
+				{block(2, 10, 1), 40, nil},
+				{block(3, 10, 1), 40, nil},
+				{block(4, 10, 1), 400, []AnomalyKind{AnomalyMempoolSurge}},
+			},
+		},
+		{
+			name: "small surge on a quiet chain is ignored",
+			steps: []step{
+				{block(1, 10, 1), 2, nil},
+				{block(2, 10, 1), 2, nil},
+				{block(3, 10, 1), 2, nil},
+				{block(4, 10, 1), 50, nil},
+			},
+		},
+		{
+			name: "priority fee spike",
+			steps: []step{
+				{block(1, 10, 1), 0, nil},
+				{block(2, 10, 2), 0, nil},
+				{block(3, 10, 1), 0, nil},
+				{block(4, 10, 5), 0, []AnomalyKind{AnomalyPriorityFeeSpike}},
+			},
+		},
+		{
+			name: "replayed height is skipped",
+			steps: []step{
+				{block(1, 10, 1), 0, nil},
+				{block(2, 10, 1), 0, nil},
+				{block(3, 10, 1), 0, nil},
+				{block(3, 30, 1), 0, nil},
+				{block(4, 10, 1), 0, nil},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newAnomalyDetector(DefaultAnomalyThresholds())
+			for _, s := range tt.steps {
+				got := d.observe(s.block, s.arrivals)
+				if len(got) != len(s.want) {
+					t.Fatalf("block %d: anomalies = %+v, want %v", s.block.Number, got, s.want)
+				}
+				for i, a := range got {
+					if a.Kind != s.want[i] || a.BlockNumber != s.block.Number || a.Ratio < 1 {
+						t.Errorf("block %d: anomaly = %+v, want %s", s.block.Number, a, s.want[i])
+					}
+				}
+			}
+		})
+	}
+}
@@ -79,6 +79,7 @@ func BenchmarkStrategy_Calculate(b *testing.B) {
 		PendingTxs:   txs,
 	}
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		_, _ = strategy.Calculate(ctx, input)
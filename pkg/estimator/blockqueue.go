@@ -0,0 +1,134 @@
+package estimator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+)
+
+const (
+	// blockQueueSize bounds the new head notifications waiting to be
+	// processed. Heads arrive far slower than they are processed except in
+	// reorg storms and on very fast chains, where newer heads supersede
+	// waiting ones anyway.
+	blockQueueSize = 16
+
+	// blockSeenSize is how many recent block hashes are remembered for
+	// deduplication.
+	blockSeenSize = 256
+)
+
+// blockQueue hands new head notifications to a single worker in arrival
+// order, so at most one full block is fetched at a time and the history is
+// pushed in order. Heads whose hash was already queued are dropped, and
+// when the queue is full the oldest waiting head is dropped.
+type blockQueue struct {
+	mu      sync.Mutex
+	pending []queuedHead
+	seen    map[string]struct{}
+	order   []string // seen hashes, oldest first
+	ready   chan struct{}
+}
+
+// queuedHead is a new head notification and when it arrived. full marks
+// a block fetched with its transactions, such as a polled one.
+type queuedHead struct {
+	block   *eth.Block
+	arrived time.Time
+	full    bool
+}
+
+func newBlockQueue() *blockQueue {
+	return &blockQueue{
+		seen:  make(map[string]struct{}, blockSeenSize),
+		ready: make(chan struct{}, 1),
+	}
+}
+
+// push queues block, which arrived at the given time, unless its hash was
+// seen before; full marks a block that already has its transactions. It
+// reports whether block was queued, and the waiting head it displaced, if
+// any.
+func (q *blockQueue) push(block *eth.Block, arrived time.Time, full bool) (queued bool, displaced *eth.Block) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if block.Hash != "" {
+		if _, ok := q.seen[block.Hash]; ok {
+			return false, nil
+		}
+		if len(q.order) == blockSeenSize {
+			delete(q.seen, q.order[0])
+			q.order = q.order[1:]
+		}
+		q.seen[block.Hash] = struct{}{}
+		q.order = append(q.order, block.Hash)
+	}
+
+	if len(q.pending) == blockQueueSize {
+		displaced = q.pending[0].block
+		q.pending = q.pending[1:]
+	}
+	q.pending = append(q.pending, queuedHead{block: block, arrived: arrived, full: full})
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return true, displaced
+}
+
+// next waits for the oldest queued head; ok is false once ctx is done.
+func (q *blockQueue) next(ctx context.Context) (head queuedHead, ok bool) {
+	for {
+		q.mu.Lock()
+		if len(q.pending) > 0 {
+			head = q.pending[0]
+			q.pending[0] = queuedHead{}
+			q.pending = q.pending[1:]
+			q.mu.Unlock()
+			return head, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return queuedHead{}, false
+		case <-q.ready:
+		}
+	}
+}
+
+// queueBlock hands a new head notification to Run's block worker; full
+// marks a block that needn't be fetched again.
+func (e *Estimator) queueBlock(block *eth.Block, full bool) {
+	queued, displaced := e.blocks.push(block, e.clock.Now(), full)
+	if !queued {
+		e.logger.Debug("duplicate block notification", "block", block.Number, "hash", block.Hash)
+		return
+	}
+	if displaced != nil {
+		e.logger.Warn("block queue full, skipping superseded head",
+			"block", displaced.Number,
+			"hash", displaced.Hash,
+		)
+	}
+}
+
+// processBlocks is Run's block worker: it fetches and processes queued
+// heads one at a time until ctx is done.
+func (e *Estimator) processBlocks(ctx context.Context) {
+	for {
+		head, ok := e.blocks.next(ctx)
+		if !ok {
+			return
+		}
+		if head.full {
+			e.processBlock(ctx, head.block, head.arrived)
+			continue
+		}
+		e.handleNewBlock(ctx, head.block, head.arrived)
+	}
+}
@@ -0,0 +1,54 @@
+package estimator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+)
+
+func TestBlockQueue(t *testing.T) {
+	q := newBlockQueue()
+	head := func(n uint64) *eth.Block { return &eth.Block{Number: n, Hash: fmt.Sprintf("0x%x", n)} }
+
+	if queued, _ := q.push(head(1), time.Time{}, false); !queued {
+		t.Fatal("first head not queued")
+	}
+	if queued, _ := q.push(head(1), time.Time{}, false); queued {
+		t.Error("duplicate head queued")
+	}
+	q.push(&eth.Block{Number: 1, Hash: "0xreorg"}, time.Time{}, false)
+
+	ctx := context.Background()
+	if h, _ := q.next(ctx); h.block.Hash != "0x1" {
+		t.Errorf("next = %s, want 0x1", h.block.Hash)
+	}
+	if h, _ := q.next(ctx); h.block.Hash != "0xreorg" {
+		t.Errorf("next = %s, want the replacement head", h.block.Hash)
+	}
+
+	// A full queue drops its oldest head
+	for n := uint64(10); n < 10+blockQueueSize; n++ {
+		q.push(head(n), time.Time{}, false)
+	}
+	_, displaced := q.push(head(100), time.Time{}, false)
+	if displaced == nil || displaced.Number != 10 {
+		t.Fatalf("displaced = %v, want block 10", displaced)
+	}
+	if h, _ := q.next(ctx); h.block.Number != 11 {
+		t.Errorf("next = %d, want 11", h.block.Number)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	for {
+		if _, ok := q.next(canceled); !ok {
+			break
+		}
+	}
+	if h, ok := q.next(canceled); ok {
+		t.Errorf("next on an empty queue after cancel = %v, want none", h.block)
+	}
+}
@@ -0,0 +1,180 @@
+package estimator
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// minTxBatchSize is the smallest pending transaction batch the adaptive
+// batch size shrinks to, and the step it grows back by.
+const minTxBatchSize = 10
+
+// NodeBudget limits the load the estimator puts on its node, for
+// rate-limited providers. Zero fields keep their defaults.
+type NodeBudget struct {
+	// RequestsPerSecond caps the estimator's node calls: block (including
+	// those polled while degraded), receipt, pending transaction, nonce and
+	// pending block fetches. Each call in a
+	// batch counts, so a batch of 100 transaction lookups spends 100.
+	// Calls wait for budget rather than fail. Default 0, unlimited.
+	RequestsPerSecond float64
+
+	// Limiter, if set, replaces RequestsPerSecond with a budget shared with
+	// the node's other users, such as the API's transaction simulation.
+	Limiter *NodeLimiter
+
+	// MaxInFlightBatches bounds the pending transaction batches fetched
+	// concurrently. Default 1.
+	MaxInFlightBatches int
+
+	// MaxBatchSize is the largest pending transaction batch. The batch
+	// size halves after a failed fetch, e.g. one refused with 429, and
+	// grows back after successful ones. Default 100.
+	MaxBatchSize int
+}
+
+// WithNodeBudget sets the estimator's node request budget.
+func WithNodeBudget(b NodeBudget) Option {
+	return func(e *Estimator) {
+		if b.RequestsPerSecond > 0 {
+			e.budget.rate = b.RequestsPerSecond
+		}
+		if b.Limiter != nil {
+			e.budget.limiter = b.Limiter
+		}
+		if b.MaxInFlightBatches > 0 {
+			e.budget.inFlight = b.MaxInFlightBatches
+		}
+		if b.MaxBatchSize > 0 {
+			e.budget.maxBatch = b.MaxBatchSize
+		}
+	}
+}
+
+// NodeLimiter is a requests-per-second budget for node calls, which can
+// be shared by several users of a node. A nil *NodeLimiter is unlimited.
+type NodeLimiter struct {
+	rate  float64 // requests per second; 0 is unlimited
+	clock Clock
+
+	mu     sync.Mutex
+	tokens float64 // negative while callers wait for budget
+	last   time.Time
+}
+
+// NewNodeLimiter returns a budget of requestsPerSecond node calls; 0 is
+// unlimited.
+func NewNodeLimiter(requestsPerSecond float64) *NodeLimiter {
+	return newNodeLimiter(requestsPerSecond, SystemClock())
+}
+
+func newNodeLimiter(rate float64, clock Clock) *NodeLimiter {
+	l := &NodeLimiter{rate: rate, clock: clock, last: clock.Now()}
+	l.tokens = l.burst()
+	return l
+}
+
+// burst is the budget that can be spent at once after an idle second.
+func (l *NodeLimiter) burst() float64 {
+	return max(l.rate, 1)
+}
+
+// Wait spends n requests of budget, waiting until the budget allows them
+// or ctx ends.
+func (l *NodeLimiter) Wait(ctx context.Context, n int) error {
+	if l == nil || l.rate <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := l.clock.Now()
+	l.tokens = min(l.burst(), l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := l.clock.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		// Return the unspent budget
+		l.mu.Lock()
+		l.tokens += float64(n)
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// nodeBudget schedules the estimator's node calls within a NodeBudget.
+type nodeBudget struct {
+	rate     float64 // requests per second; 0 is unlimited
+	limiter  *NodeLimiter
+	inFlight int
+	maxBatch int
+
+	batches   chan struct{} // in-flight batch slots
+	batchSize atomic.Int64
+}
+
+// defaultNodeBudget returns an unlimited budget with one batch of 100 in
+// flight; start must be called once options are applied.
+func defaultNodeBudget() *nodeBudget {
+	return &nodeBudget{inFlight: 1, maxBatch: 100}
+}
+
+// start readies the budget for use.
+func (b *nodeBudget) start(clock Clock) {
+	if b.limiter == nil {
+		b.limiter = newNodeLimiter(b.rate, clock)
+	}
+	b.batches = make(chan struct{}, b.inFlight)
+	b.batchSize.Store(int64(b.maxBatch))
+}
+
+// wait spends n requests of budget, waiting until the budget allows them
+// or ctx ends.
+func (b *nodeBudget) wait(ctx context.Context, n int) error {
+	return b.limiter.Wait(ctx, n)
+}
+
+// acquireBatch waits for an in-flight batch slot, reporting false if ctx
+// ended first. The slot is returned with releaseBatch.
+func (b *nodeBudget) acquireBatch(ctx context.Context) bool {
+	select {
+	case b.batches <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (b *nodeBudget) releaseBatch() {
+	<-b.batches
+}
+
+// batchLimit returns the current pending transaction batch size.
+func (b *nodeBudget) batchLimit() int {
+	return int(b.batchSize.Load())
+}
+
+// batchDone adapts the batch size to a fetch's outcome: halved after a
+// failure, grown by minTxBatchSize after a success.
+func (b *nodeBudget) batchDone(err error) {
+	for {
+		old := b.batchSize.Load()
+		next := min(old+minTxBatchSize, int64(b.maxBatch))
+		if err != nil {
+			next = max(old/2, int64(min(minTxBatchSize, b.maxBatch)))
+		}
+		if b.batchSize.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
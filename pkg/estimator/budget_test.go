@@ -0,0 +1,158 @@
+package estimator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+)
+
+func TestNodeBudget_Wait(t *testing.T) {
+	clock := &manualClock{now: time.Unix(1000, 0)}
+	b := defaultNodeBudget()
+	b.rate = 10
+	b.start(clock)
+
+	if err := b.wait(context.Background(), 10); err != nil {
+		t.Fatalf("wait within burst: %v", err)
+	}
+
+	// Over budget: waits about 100ms, longer than the caller will
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.wait(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("wait over budget = %v, want deadline exceeded", err)
+	}
+
+	// A second refills the budget; the canceled wait spent nothing
+	clock.now = clock.now.Add(time.Second)
+	start := time.Now()
+	if err := b.wait(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("wait after refill took %v, want no wait", elapsed)
+	}
+
+	unlimited := defaultNodeBudget()
+	unlimited.start(clock)
+	if err := unlimited.wait(ctx, 1e6); err != nil {
+		t.Errorf("unlimited wait = %v", err)
+	}
+}
+
+func TestNodeBudget_SharedLimiter(t *testing.T) {
+	clock := &manualClock{now: time.Unix(1000, 0)}
+	limiter := newNodeLimiter(10, clock)
+	e := &Estimator{budget: defaultNodeBudget()}
+	WithNodeBudget(NodeBudget{RequestsPerSecond: 1000, Limiter: limiter})(e)
+	e.budget.start(clock)
+
+	// Another user spends the whole burst; the estimator has to wait
+	if err := limiter.Wait(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := e.budget.wait(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait on a spent shared budget = %v, want deadline exceeded", err)
+	}
+
+	var unlimited *NodeLimiter
+	if err := unlimited.Wait(ctx, 1e6); err != nil {
+		t.Errorf("nil limiter wait = %v", err)
+	}
+}
+
+func TestNodeBudget_BatchSize(t *testing.T) {
+	b := defaultNodeBudget()
+	b.start(SystemClock())
+
+	var got []int
+	for _, err := range []error{errors.New("429"), errors.New("429"), errors.New("429"), errors.New("429"), nil, nil} {
+		b.batchDone(err)
+		got = append(got, b.batchLimit())
+	}
+	if want := []int{50, 25, 12, 10, 20, 30}; !slices.Equal(got, want) {
+		t.Errorf("batch sizes = %v, want %v", got, want)
+	}
+	for range 20 {
+		b.batchDone(nil)
+	}
+	if n := b.batchLimit(); n != 100 {
+		t.Errorf("batch size after successes = %d, want 100", n)
+	}
+}
+
+func TestEstimator_PendingTxBatches(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		sizes    []int
+		inFlight atomic.Int32
+		peak     atomic.Int32
+		release  = make(chan struct{})
+	)
+	txReader := &batchTxReader{fn: func(ctx context.Context, hashes []string) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		mu.Lock()
+		sizes = append(sizes, len(hashes))
+		mu.Unlock()
+		<-release
+	}}
+	e := New(&mockBlockReader{}, txReader, nil, NewProvider(),
+		WithNodeBudget(NodeBudget{MaxInFlightBatches: 2, MaxBatchSize: 3}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		e.processPendingTxs(ctx, ch)
+		close(done)
+	}()
+	for _, h := range []string{"0x1", "0x2", "0x3", "0x4", "0x5", "0x6"} {
+		ch <- h
+	}
+
+	// Both batches are fetched at once
+	deadline := time.After(time.Second)
+	for peak.Load() < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("peak in-flight batches = %d, want 2", peak.Load())
+		case <-time.After(time.Millisecond):
+		}
+	}
+	close(release)
+	cancel()
+	<-done
+
+	if peak.Load() != 2 || !slices.Equal(sizes, []int{3, 3}) {
+		t.Errorf("batches %v with peak %d in flight, want [3 3] with 2", sizes, peak.Load())
+	}
+}
+
+// batchTxReader calls fn for every batch fetched.
+type batchTxReader struct {
+	fn func(ctx context.Context, hashes []string)
+}
+
+func (r *batchTxReader) TransactionByHash(ctx context.Context, hash string) (*eth.Transaction, error) {
+	return nil, nil
+}
+
+func (r *batchTxReader) TransactionsByHashes(ctx context.Context, hashes []string) ([]*eth.Transaction, error) {
+	r.fn(ctx, hashes)
+	return nil, nil
+}
@@ -2,7 +2,7 @@ package estimator
 
 import (
 	"context"
-	"slices"
+	"math"
 	"time"
 
 	"github.com/holiman/uint256"
@@ -26,19 +26,101 @@ type HybridStrategy struct {
 	// Default: 0.3 (favor mempool for responsiveness)
 	HistoricalWeight float64
 
+	// PendingBlockWeight determines the blend between the node's pending
+	// block and the mempool sample, when a pending block is available.
+	// Transactions in the pending block were already selected by the node,
+	// so their fees are a strong signal of what the next block accepts.
+	// 0.0 = mempool only, 1.0 = pending block only
+	// Default: 0.5
+	PendingBlockWeight float64
+
 	// SmoothingFactor for exponential moving average with previous estimate
 	// 0.0 = no smoothing, 1.0 = ignore new data
 	// Default: 0.1
 	SmoothingFactor float64
+
+	// HistoricalHalfLife is the age, in blocks, at which a block's priority
+	// fees count half as much as the newest block's. Older fees decay
+	// exponentially so historical percentiles follow regime changes quickly.
+	// 0 weighs every block in the history window equally.
+	// Default: 5
+	HistoricalHalfLife float64
+
+	// GasWeighted weighs each historical priority fee by the gas of the
+	// transaction that paid it, so percentiles reflect blockspace rather
+	// than transaction counts: a 2M-gas transaction tipping 1 gwei outweighs
+	// a transfer tipping 100 gwei. Blocks without per-transaction gas
+	// (BlockData.FeeGas) split their gas used evenly across their fees.
+	// Default: true
+	GasWeighted bool
+
+	// BaseFeeHorizon is how many blocks ahead the base fee is projected when
+	// sizing maxFeePerGas. The buffer is the worst-case growth over the
+	// horizon given the recent utilization trend (see baseFeeMultiplier);
+	// with full blocks and 6 blocks this matches the classic baseFee*2 rule.
+	// 0 falls back to the fixed 2x buffer.
+	// Default: 6
+	BaseFeeHorizon int
+
+	// TrendWindow is the number of recent blocks whose gas utilization is
+	// averaged to estimate the base fee trend.
+	// Default: 6
+	TrendWindow int
+
+	// BaseFeeMultiplier, when positive, replaces the trend-adjusted buffer
+	// with a fixed multiplier (2 reproduces the classic baseFee*2 rule).
+	BaseFeeMultiplier float64
+
+	// Tiers are the confidence levels of the Urgent, Fast, Standard and
+	// Slow tiers.
+	// Default: DefaultTierPercentiles
+	Tiers TierPercentiles
+
+	// Percentiles selects how tier percentiles are read from fee samples.
+	// Nearest rank makes tiers jump between sample values when history is
+	// thin; linear interpolation moves them smoothly.
+	// Default: PercentileLinear
+	Percentiles PercentileMethod
+
+	// MempoolOutliers rejects outlying pending transaction fees before
+	// percentiles are computed. The rejected count is reported in
+	// GasEstimate.MempoolOutliers.
+	// Default: disabled
+	MempoolOutliers OutlierFilter
+
+	// ElasticityMultiplier is the EIP-1559 ELASTICITY_MULTIPLIER:
+	// gas target = gas limit / ElasticityMultiplier.
+	// Default: 2 (Ethereum mainnet). OP Stack chains use 6.
+	ElasticityMultiplier uint64
+
+	// BaseFeeChangeDenominator is the EIP-1559 BASE_FEE_MAX_CHANGE_DENOMINATOR:
+	// the base fee moves by at most 1/BaseFeeChangeDenominator per block.
+	// Default: 8 (Ethereum mainnet). OP Stack chains use 250.
+	BaseFeeChangeDenominator uint64
 }
 
+// EIP-1559 parameters used by Ethereum mainnet.
+const (
+	DefaultElasticityMultiplier     = 2
+	DefaultBaseFeeChangeDenominator = 8
+)
+
 // DefaultStrategy returns a HybridStrategy with sensible defaults.
 func DefaultStrategy() *HybridStrategy {
 	return &HybridStrategy{
-		MinPriorityFee:   uint256.NewInt(1e9),   // 1 gwei
-		MaxPriorityFee:   uint256.NewInt(500e9), // 500 gwei
-		HistoricalWeight: 0.3,
-		SmoothingFactor:  0.1,
+		MinPriorityFee:     uint256.NewInt(1e9),   // 1 gwei
+		MaxPriorityFee:     uint256.NewInt(500e9), // 500 gwei
+		HistoricalWeight:   0.3,
+		PendingBlockWeight: 0.5,
+		SmoothingFactor:    0.1,
+		HistoricalHalfLife: 5,
+		GasWeighted:        true,
+		BaseFeeHorizon:     6,
+		TrendWindow:        6,
+		Percentiles:        PercentileLinear,
+
+		ElasticityMultiplier:     DefaultElasticityMultiplier,
+		BaseFeeChangeDenominator: DefaultBaseFeeChangeDenominator,
 	}
 }
 
@@ -56,49 +138,98 @@ func (s *HybridStrategy) Calculate(ctx context.Context, input *CalculatorInput)
 	// Predict next block's base fee
 	predictedBaseFee := s.predictBaseFee(input.CurrentBlock)
 
-	// Collect priority fees from historical blocks
-	var historicalFees []*uint256.Int
+	// Collect priority fees from historical blocks, weighted by block age
+	var n int
 	for _, block := range input.RecentBlocks {
-		historicalFees = append(historicalFees, block.PriorityFees...)
+		n += len(block.PriorityFees)
 	}
-	slices.SortFunc(historicalFees, func(a, b *uint256.Int) int {
-		if a.Lt(b) {
-			return -1
+	fees := make([]*uint256.Int, 0, n)
+	weights := make([]float64, 0, n)
+	head := input.CurrentBlock.Number
+	blocks := 0
+	for _, block := range input.RecentBlocks {
+		if len(block.PriorityFees) > 0 {
+			blocks++
+		}
+		var age uint64
+		if head > block.Number {
+			age = head - block.Number
 		}
-		if b.Lt(a) {
-			return 1
+		w := decayWeight(age, s.HistoricalHalfLife)
+		for i, fee := range block.PriorityFees {
+			fees = append(fees, fee)
+			weights = append(weights, w*s.feeGas(block, i))
 		}
-		return 0
-	})
+	}
+	if s.HistoricalHalfLife <= 0 && !s.GasWeighted {
+		weights = nil
+	}
+	historicalFees := newFeeSample(fees, weights)
 
-	// Collect priority fees from pending transactions
-	var mempoolFees []*uint256.Int
+	// Collect priority fees from pending transactions that can pay the
+	// predicted base fee; the rest won't be mined soon whatever their tip
+	pending := make([]*uint256.Int, 0, len(input.PendingTxs))
+	arena := newFeeArena(len(input.PendingTxs))
+	includable := 0
 	for _, tx := range input.PendingTxs {
-		fee := tx.EffectivePriorityFee(predictedBaseFee)
+		if !tx.Includable(predictedBaseFee) {
+			continue
+		}
+		includable++
+		fee := tx.effectivePriorityFee(arena.next(), predictedBaseFee)
 		if !fee.IsZero() {
-			mempoolFees = append(mempoolFees, fee)
+			pending = append(pending, fee)
 		}
 	}
-	slices.SortFunc(mempoolFees, func(a, b *uint256.Int) int {
-		if a.Lt(b) {
-			return -1
-		}
-		if b.Lt(a) {
-			return 1
-		}
-		return 0
-	})
+	pending, rejected := s.MempoolOutliers.apply(pending)
+	mempoolFees := newFeeSample(pending, nil)
+
+	// Fees from the node's pending block, if it builds on the current head
+	var pendingBlockFees feeSample
+	if pb := input.PendingBlock; pb != nil && pb.Number == head+1 {
+		pendingBlockFees = newFeeSample(pb.PriorityFees, nil)
+	}
+
+	now := input.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	// Size the maxFeePerGas buffer from the base fee trend
+	multiplier := s.baseFeeMultiplier(input.RecentBlocks)
+	bufferedBaseFee := scaleFee(predictedBaseFee, multiplier)
 
 	// Compute estimates at each confidence level
+	tiers := s.Tiers.orDefault()
+	urgent, urgentWhy := s.computeEstimate(bufferedBaseFee, historicalFees, mempoolFees, pendingBlockFees, tiers[0])
+	fast, fastWhy := s.computeEstimate(bufferedBaseFee, historicalFees, mempoolFees, pendingBlockFees, tiers[1])
+	standard, standardWhy := s.computeEstimate(bufferedBaseFee, historicalFees, mempoolFees, pendingBlockFees, tiers[2])
+	slow, slowWhy := s.computeEstimate(bufferedBaseFee, historicalFees, mempoolFees, pendingBlockFees, tiers[3])
 	estimate := &GasEstimate{
-		ChainID:     input.ChainID,
-		BlockNumber: input.CurrentBlock.Number,
-		Timestamp:   time.Now(),
-		BaseFee:     predictedBaseFee,
-		Urgent:      s.computeEstimate(predictedBaseFee, historicalFees, mempoolFees, 0.99),
-		Fast:        s.computeEstimate(predictedBaseFee, historicalFees, mempoolFees, 0.90),
-		Standard:    s.computeEstimate(predictedBaseFee, historicalFees, mempoolFees, 0.50),
-		Slow:        s.computeEstimate(predictedBaseFee, historicalFees, mempoolFees, 0.25),
+		ChainID:           input.ChainID,
+		BlockNumber:       input.CurrentBlock.Number,
+		Timestamp:         now,
+		BaseFee:           predictedBaseFee,
+		BaseFeeMultiplier: multiplier,
+		MempoolSampled:    len(input.PendingTxs),
+		MempoolIncludable: includable,
+		MempoolOutliers:   rejected,
+		Samples: SampleSizes{
+			Blocks:       blocks,
+			Historical:   historicalFees.len(),
+			Mempool:      mempoolFees.len(),
+			PendingBlock: pendingBlockFees.len(),
+		},
+		BlockGasLimit: input.CurrentBlock.GasLimit,
+		Demand:        demandCurve(input.PendingTxs, predictedBaseFee),
+		Urgent:        urgent,
+		Fast:          fast,
+		Standard:      standard,
+		Slow:          slow,
+		Distribution: &FeeDistribution{
+			Historical: curve(historicalFees),
+			Mempool:    curve(mempoolFees),
+		},
 	}
 
 	// Apply smoothing if we have a previous estimate
@@ -106,33 +237,80 @@ func (s *HybridStrategy) Calculate(ctx context.Context, input *CalculatorInput)
 		estimate = s.smooth(estimate, input.PreviousEstimate)
 	}
 
+	if input.Explain {
+		urgentWhy.Final = estimate.Urgent.MaxPriorityFeePerGas
+		fastWhy.Final = estimate.Fast.MaxPriorityFeePerGas
+		standardWhy.Final = estimate.Standard.MaxPriorityFeePerGas
+		slowWhy.Final = estimate.Slow.MaxPriorityFeePerGas
+		estimate.Explanation = &Explanation{
+			PredictedBaseFee:    predictedBaseFee,
+			BaseFeeMultiplier:   multiplier,
+			HistoricalSamples:   historicalFees.len(),
+			MempoolSamples:      mempoolFees.len(),
+			PendingBlockSamples: pendingBlockFees.len(),
+			Urgent:              urgentWhy,
+			Fast:                fastWhy,
+			Standard:            standardWhy,
+			Slow:                slowWhy,
+		}
+	}
+
 	return estimate, nil
 }
 
+// feeGas returns the weight of block's i-th priority fee under GasWeighted:
+// the gas of its transaction, or the block's average gas per fee when
+// unknown. It is 1 when GasWeighted is off.
+func (s *HybridStrategy) feeGas(block *BlockData, i int) float64 {
+	if !s.GasWeighted {
+		return 1
+	}
+	if len(block.FeeGas) == len(block.PriorityFees) && block.FeeGas[i] > 0 {
+		return float64(block.FeeGas[i])
+	}
+	if block.GasUsed == 0 {
+		return 1
+	}
+	return float64(block.GasUsed) / float64(len(block.PriorityFees))
+}
+
 // predictBaseFee predicts the base fee for the next block using EIP-1559 formula.
 func (s *HybridStrategy) predictBaseFee(block *BlockData) *uint256.Int {
+	return nextBaseFee(block, s.ElasticityMultiplier, s.BaseFeeChangeDenominator)
+}
+
+// nextBaseFee applies the EIP-1559 base fee update rule to block.
+// Zero parameters select the mainnet defaults.
+func nextBaseFee(block *BlockData, elasticity, denominator uint64) *uint256.Int {
 	if block.BaseFee == nil {
 		return uint256.NewInt(1e9) // 1 gwei default for non-EIP-1559
 	}
 
+	if elasticity == 0 {
+		elasticity = DefaultElasticityMultiplier
+	}
+	if denominator == 0 {
+		denominator = DefaultBaseFeeChangeDenominator
+	}
+
 	baseFee := new(uint256.Int).Set(block.BaseFee)
-	gasTarget := block.GasLimit / 2
+	gasTarget := block.GasLimit / elasticity
 
-	if block.GasUsed == gasTarget {
+	if block.GasUsed == gasTarget || gasTarget == 0 {
 		return baseFee
 	}
 
 	if block.GasUsed > gasTarget {
-		// Block was more than 50% full - base fee increases
+		// Block was above target - base fee increases
 		delta := new(uint256.Int).Mul(baseFee, uint256.NewInt(block.GasUsed-gasTarget))
 		delta.Div(delta, uint256.NewInt(gasTarget))
-		delta.Div(delta, uint256.NewInt(8)) // max 12.5% change
+		delta.Div(delta, uint256.NewInt(denominator)) // max 1/denominator change (12.5% on mainnet)
 		baseFee.Add(baseFee, delta)
 	} else {
-		// Block was less than 50% full - base fee decreases
+		// Block was below target - base fee decreases
 		delta := new(uint256.Int).Mul(baseFee, uint256.NewInt(gasTarget-block.GasUsed))
 		delta.Div(delta, uint256.NewInt(gasTarget))
-		delta.Div(delta, uint256.NewInt(8))
+		delta.Div(delta, uint256.NewInt(denominator))
 		// Check for underflow
 		if baseFee.Lt(delta) {
 			baseFee.SetUint64(0)
@@ -144,56 +322,165 @@ func (s *HybridStrategy) predictBaseFee(block *BlockData) *uint256.Int {
 	return baseFee
 }
 
-// computeEstimate calculates priority fee at a given percentile.
+// baseFeeMultiplier returns the factor applied to the predicted base fee in
+// maxFeePerGas.
+//
+// Each recent block's utilization implies a base fee step of
+// (gasUsed/gasTarget - 1) / denominator. The average step over TrendWindow
+// blocks, plus half the maximum step as headroom for surprises, is clamped to
+// [0, 1/denominator] and compounded over BaseFeeHorizon blocks. A sustained
+// run of full blocks yields (1+1/8)^6 ≈ 2.03 on mainnet; flat demand ≈ 1.44;
+// a steady decline 1.0.
+func (s *HybridStrategy) baseFeeMultiplier(blocks []*BlockData) float64 {
+	if s.BaseFeeMultiplier > 0 {
+		return s.BaseFeeMultiplier
+	}
+	if s.BaseFeeHorizon <= 0 {
+		return 2
+	}
+
+	elasticity := s.ElasticityMultiplier
+	if elasticity == 0 {
+		elasticity = DefaultElasticityMultiplier
+	}
+	denominator := s.BaseFeeChangeDenominator
+	if denominator == 0 {
+		denominator = DefaultBaseFeeChangeDenominator
+	}
+	maxStep := 1 / float64(denominator)
+
+	window := min(max(s.TrendWindow, 1), len(blocks))
+	trend := 0.0
+	n := 0
+	for _, b := range blocks[:window] {
+		target := b.GasLimit / elasticity
+		if target == 0 {
+			continue
+		}
+		step := (float64(b.GasUsed)/float64(target) - 1) * maxStep
+		trend += min(max(step, -maxStep), maxStep)
+		n++
+	}
+	if n > 0 {
+		trend /= float64(n)
+	}
+
+	growth := min(max(trend+maxStep/2, 0), maxStep)
+	return math.Pow(1+growth, float64(s.BaseFeeHorizon))
+}
+
+// scaleFee returns fee * multiplier, rounded to basis-point precision.
+func scaleFee(fee *uint256.Int, multiplier float64) *uint256.Int {
+	bps := uint256.NewInt(uint64(math.Round(multiplier * 10000)))
+	out := new(uint256.Int).Mul(fee, bps)
+	return out.Div(out, uint256.NewInt(10000))
+}
+
+// computeEstimate calculates priority fee at a given percentile, and how it
+// was derived.
+// bufferedBaseFee is the predicted base fee already scaled by the buffer multiplier.
+// The pending block, when non-empty, is blended into the mempool percentile
+// by PendingBlockWeight.
 func (s *HybridStrategy) computeEstimate(
-	baseFee *uint256.Int,
-	historical []*uint256.Int,
-	mempool []*uint256.Int,
+	bufferedBaseFee *uint256.Int,
+	historical feeSample,
+	mempool feeSample,
+	pendingBlock feeSample,
 	percentile float64,
-) PriorityEstimate {
+) (PriorityEstimate, TierExplanation) {
 	var priorityFee *uint256.Int
 
 	histP := s.percentile(historical, percentile)
 	mempP := s.percentile(mempool, percentile)
+	blockP := s.percentile(pendingBlock, percentile)
+	why := TierExplanation{
+		Percentile:   percentile,
+		Historical:   histP,
+		Mempool:      mempP,
+		PendingBlock: blockP,
+	}
+	var sources []FeeSource
+	if histP != nil {
+		sources = append(sources, FeeSourceHistory)
+	}
+	if mempP != nil {
+		sources = append(sources, FeeSourceMempool)
+	}
+	if blockP != nil {
+		sources = append(sources, FeeSourcePendingBlock)
+	}
+	if blockP != nil {
+		if mempP != nil {
+			mempP = s.blend(blockP, mempP, s.PendingBlockWeight)
+		} else {
+			mempP = blockP
+		}
+	}
 
 	if histP != nil && mempP != nil {
 		// Blend historical and mempool estimates
 		weighted := s.blend(histP, mempP, s.HistoricalWeight)
 		priorityFee = weighted
+		why.HistoricalWeight = s.HistoricalWeight
 	} else if mempP != nil {
 		priorityFee = mempP
 	} else if histP != nil {
 		priorityFee = histP
+		why.HistoricalWeight = 1
 	} else {
 		// No data available - use reasonable default based on percentile
 		priorityFee = s.defaultPriorityFee(percentile)
+		why.Default = true
+		sources = []FeeSource{FeeSourceDefault}
 	}
+	why.Blended = priorityFee
 
 	// Clamp to min/max
 	priorityFee = s.clamp(priorityFee)
+	switch {
+	case priorityFee.Lt(why.Blended):
+		why.Clamped = ClampedMax
+	case priorityFee.Gt(why.Blended):
+		why.Clamped = ClampedMin
+	}
+	why.Unsmoothed = priorityFee
 
-	// Calculate maxFeePerGas: baseFee * 2 + priorityFee
-	// The 2x buffer handles up to ~6 consecutive full blocks
-	maxFee := new(uint256.Int).Mul(baseFee, uint256.NewInt(2))
-	maxFee.Add(maxFee, priorityFee)
+	// Calculate maxFeePerGas: baseFee * multiplier + priorityFee
+	maxFee := new(uint256.Int).Add(bufferedBaseFee, priorityFee)
 
 	return PriorityEstimate{
 		MaxPriorityFeePerGas: priorityFee,
 		MaxFeePerGas:         maxFee,
 		Confidence:           percentile,
-	}
+		Sources:              sources,
+	}, why
 }
 
 // percentile calculates the value at the given percentile (0.0 to 1.0).
-// Assumes values is already sorted.
-func (s *HybridStrategy) percentile(values []*uint256.Int, p float64) *uint256.Int {
-	if len(values) == 0 {
+func (s *HybridStrategy) percentile(values feeSample, p float64) *uint256.Int {
+	if s.Percentiles == PercentileLinear {
+		return values.interpolate(p)
+	}
+	v := values.at(p)
+	if v == nil {
 		return nil
 	}
+	return new(uint256.Int).Set(v)
+}
 
-	// Calculate index
-	idx := int(float64(len(values)-1) * p)
-	return new(uint256.Int).Set(values[idx])
+// curve samples values at every DistributionStep percentile.
+// Returned elements alias values and must not be modified.
+func curve(values feeSample) []*uint256.Int {
+	if values.len() == 0 {
+		return nil
+	}
+
+	steps := int(math.Round(1 / DistributionStep))
+	out := make([]*uint256.Int, steps+1)
+	for i := range out {
+		out[i] = values.at(float64(i) / float64(steps))
+	}
+	return out
 }
 
 // blend computes a weighted average of two uint256.Int values.
@@ -228,11 +515,16 @@ func (s *HybridStrategy) defaultPriorityFee(percentile float64) *uint256.Int {
 
 // clamp ensures the priority fee is within bounds.
 func (s *HybridStrategy) clamp(fee *uint256.Int) *uint256.Int {
-	if fee.Lt(s.MinPriorityFee) {
-		return new(uint256.Int).Set(s.MinPriorityFee)
+	return clampFee(fee, s.MinPriorityFee, s.MaxPriorityFee)
+}
+
+// clampFee bounds fee to [lo, hi]; nil bounds are ignored.
+func clampFee(fee, lo, hi *uint256.Int) *uint256.Int {
+	if lo != nil && fee.Lt(lo) {
+		return new(uint256.Int).Set(lo)
 	}
-	if fee.Gt(s.MaxPriorityFee) {
-		return new(uint256.Int).Set(s.MaxPriorityFee)
+	if hi != nil && fee.Gt(hi) {
+		return new(uint256.Int).Set(hi)
 	}
 	return fee
 }
@@ -241,16 +533,13 @@ func (s *HybridStrategy) clamp(fee *uint256.Int) *uint256.Int {
 func (s *HybridStrategy) smooth(current, previous *GasEstimate) *GasEstimate {
 	factor := s.SmoothingFactor
 
-	return &GasEstimate{
-		ChainID:     current.ChainID,
-		BlockNumber: current.BlockNumber,
-		Timestamp:   current.Timestamp,
-		BaseFee:     current.BaseFee, // Don't smooth base fee
-		Urgent:      s.smoothEstimate(current.Urgent, previous.Urgent, factor),
-		Fast:        s.smoothEstimate(current.Fast, previous.Fast, factor),
-		Standard:    s.smoothEstimate(current.Standard, previous.Standard, factor),
-		Slow:        s.smoothEstimate(current.Slow, previous.Slow, factor),
-	}
+	// Copy all fields (base fee and distribution are not smoothed)
+	smoothed := *current
+	smoothed.Urgent = s.smoothEstimate(current.Urgent, previous.Urgent, factor)
+	smoothed.Fast = s.smoothEstimate(current.Fast, previous.Fast, factor)
+	smoothed.Standard = s.smoothEstimate(current.Standard, previous.Standard, factor)
+	smoothed.Slow = s.smoothEstimate(current.Slow, previous.Slow, factor)
+	return &smoothed
 }
 
 func (s *HybridStrategy) smoothEstimate(current, previous PriorityEstimate, factor float64) PriorityEstimate {
@@ -262,6 +551,7 @@ func (s *HybridStrategy) smoothEstimate(current, previous PriorityEstimate, fact
 		MaxPriorityFeePerGas: smoothedPriority,
 		MaxFeePerGas:         smoothedMax,
 		Confidence:           current.Confidence,
+		Sources:              current.Sources,
 	}
 }
 
@@ -2,6 +2,8 @@ package estimator
 
 import (
 	"context"
+	"math"
+	"slices"
 	"testing"
 	"time"
 
@@ -32,6 +34,11 @@ func TestHybridStrategy_Calculate(t *testing.T) {
 
 	defaultStrategy := DefaultStrategy()
 
+	// OP Stack style parameters: target = limit/6, max change 1/250
+	opStrategy := DefaultStrategy()
+	opStrategy.ElasticityMultiplier = 6
+	opStrategy.BaseFeeChangeDenominator = 250
+
 	tests := []struct {
 		name        string
 		strategy    *HybridStrategy
@@ -77,6 +84,26 @@ func TestHybridStrategy_Calculate(t *testing.T) {
 			// New BaseFee = 1000000000 - 125000000 = 875000000
 			wantBaseFee: u256(875000000),
 		},
+		{
+			name:     "Base fee prediction - custom elasticity at target",
+			strategy: opStrategy,
+			input: &CalculatorInput{
+				ChainID:      10,
+				CurrentBlock: makeBlock(100, 1000000000, 5000000, 30000000, nil), // 1/6 usage
+			},
+			wantBaseFee: u256(1000000000), // Should stay same
+		},
+		{
+			name:     "Base fee prediction - custom elasticity full block",
+			strategy: opStrategy,
+			input: &CalculatorInput{
+				ChainID:      10,
+				CurrentBlock: makeBlock(100, 1000000000, 30000000, 30000000, nil), // 100% usage
+			},
+			// Delta = 1000000000 * (30000000 - 5000000) / 5000000 / 250 = 20000000
+			// New BaseFee = 1000000000 + 20000000 = 1020000000
+			wantBaseFee: u256(1020000000),
+		},
 		{
 			name:     "No data - defaults",
 			strategy: defaultStrategy,
@@ -168,3 +195,528 @@ func TestHybridStrategy_Blend(t *testing.T) {
 		})
 	}
 }
+
+func TestHybridStrategy_Distribution(t *testing.T) {
+	fees := make([]*uint256.Int, 101)
+	for i := range fees {
+		fees[i] = uint256.NewInt(uint64(i) * 1e9)
+	}
+	input := &CalculatorInput{
+		ChainID: 1,
+		CurrentBlock: &BlockData{
+			Number:       100,
+			BaseFee:      uint256.NewInt(1e9),
+			GasUsed:      15000000,
+			GasLimit:     30000000,
+			PriorityFees: fees,
+		},
+	}
+	input.RecentBlocks = []*BlockData{input.CurrentBlock}
+
+	got, err := DefaultStrategy().Calculate(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+	if got.Distribution == nil {
+		t.Fatal("Distribution = nil")
+	}
+
+	hist := got.Distribution.Historical
+	if len(hist) != 21 {
+		t.Fatalf("Historical len = %d, want 21", len(hist))
+	}
+	// 0..100 gwei in 1 gwei steps: the p-th percentile is p gwei
+	for i, v := range hist {
+		if want := uint64(i*5) * 1e9; v.Uint64() != want {
+			t.Errorf("Historical[%d] = %d, want %d", i, v.Uint64(), want)
+		}
+	}
+	if len(got.Distribution.Mempool) != 0 {
+		t.Errorf("Mempool len = %d, want 0 (no pending txs)", len(got.Distribution.Mempool))
+	}
+}
+
+func TestHybridStrategy_HistoricalHalfLife(t *testing.T) {
+	// Fees jumped from 1 to 50 gwei two blocks ago
+	var blocks []*BlockData
+	for n := uint64(100); n > 90; n-- {
+		fee := uint64(1e9)
+		if n > 98 {
+			fee = 50e9
+		}
+		blocks = append(blocks, &BlockData{
+			Number:       n,
+			BaseFee:      uint256.NewInt(1e9),
+			GasUsed:      15000000,
+			GasLimit:     30000000,
+			PriorityFees: []*uint256.Int{uint256.NewInt(fee), uint256.NewInt(fee)},
+		})
+	}
+	input := &CalculatorInput{ChainID: 1, CurrentBlock: blocks[0], RecentBlocks: blocks}
+
+	tests := []struct {
+		name     string
+		halfLife float64
+		want     uint64
+	}{
+		{name: "equal weights", halfLife: 0, want: 1e9},
+		{name: "one block half-life", halfLife: 1, want: 50e9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := DefaultStrategy()
+			s.HistoricalHalfLife = tt.halfLife
+
+			got, err := s.Calculate(context.Background(), input)
+			if err != nil {
+				t.Fatalf("Calculate() error = %v", err)
+			}
+			if fee := got.Standard.MaxPriorityFeePerGas.Uint64(); fee != tt.want {
+				t.Errorf("Standard priority fee = %d, want %d", fee, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeeSample_EqualWeightsMatchUnweighted(t *testing.T) {
+	fees := make([]*uint256.Int, 37)
+	weights := make([]float64, len(fees))
+	for i := range fees {
+		fees[i] = uint256.NewInt(uint64((i * 7919) % 101))
+		weights[i] = 0.3
+	}
+	plain := newFeeSample(fees, nil)
+	weighted := newFeeSample(fees, weights)
+
+	for p := 0.0; p <= 1.0; p += 0.01 {
+		if a, b := plain.at(p), weighted.at(p); !a.Eq(b) {
+			t.Errorf("at(%.2f): unweighted %s, weighted %s", p, a, b)
+		}
+	}
+}
+
+func TestFeeSample_Interpolate(t *testing.T) {
+	fees := []*uint256.Int{uint256.NewInt(40), uint256.NewInt(10), uint256.NewInt(30), uint256.NewInt(20)}
+	tests := []struct {
+		p       float64
+		weights []float64
+		want    uint64
+	}{
+		{0, nil, 10},
+		{0.5, nil, 25},
+		{0.9, nil, 37},
+		{1, nil, 40},
+		// Weights follow fees: 40 and 10 count double, so sorted fees sit
+		// at 0, 2, 3 and 4
+		{0.5, []float64{2, 2, 1, 1}, 20},
+		{0.25, []float64{2, 2, 1, 1}, 15},
+	}
+	for _, tt := range tests {
+		got := newFeeSample(fees, tt.weights).interpolate(tt.p)
+		if !got.Eq(uint256.NewInt(tt.want)) {
+			t.Errorf("interpolate(%.2f, %v) = %s, want %d", tt.p, tt.weights, got, tt.want)
+		}
+	}
+
+	if got := newFeeSample(fees[:1], nil).interpolate(0.5); !got.Eq(fees[0]) {
+		t.Errorf("single value interpolate = %s, want %s", got, fees[0])
+	}
+	if newFeeSample(nil, nil).interpolate(0.5) != nil {
+		t.Error("empty interpolate != nil")
+	}
+}
+
+func TestHybridStrategy_Percentiles(t *testing.T) {
+	// Two history fees: nearest rank sits on the lower one for every tier
+	// below 100%, interpolation spreads tiers between them
+	block := &BlockData{
+		Number:       100,
+		BaseFee:      uint256.NewInt(10e9),
+		GasUsed:      15_000_000,
+		GasLimit:     30_000_000,
+		PriorityFees: []*uint256.Int{uint256.NewInt(2e9), uint256.NewInt(4e9)},
+	}
+	input := &CalculatorInput{CurrentBlock: block, RecentBlocks: []*BlockData{block}}
+
+	s := DefaultStrategy()
+	s.SmoothingFactor = 0
+	s.Percentiles = PercentileNearestRank
+	est, err := s.Calculate(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !est.Standard.MaxPriorityFeePerGas.Eq(uint256.NewInt(2e9)) || !est.Fast.MaxPriorityFeePerGas.Eq(uint256.NewInt(2e9)) {
+		t.Errorf("nearest rank standard/fast = %s/%s, want 2 gwei", est.Standard.MaxPriorityFeePerGas, est.Fast.MaxPriorityFeePerGas)
+	}
+
+	s.Percentiles = PercentileLinear
+	est, err = s.Calculate(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !est.Standard.MaxPriorityFeePerGas.Eq(uint256.NewInt(3e9)) || !est.Fast.MaxPriorityFeePerGas.Eq(uint256.NewInt(3.8e9)) {
+		t.Errorf("linear standard/fast = %s/%s, want 3/3.8 gwei", est.Standard.MaxPriorityFeePerGas, est.Fast.MaxPriorityFeePerGas)
+	}
+}
+
+func TestHybridStrategy_GasWeighted(t *testing.T) {
+	// One 2M-gas swap tipping 1 gwei against ten transfers tipping 100 gwei
+	block := &BlockData{
+		Number:       100,
+		BaseFee:      uint256.NewInt(10e9),
+		GasUsed:      15_000_000,
+		GasLimit:     30_000_000,
+		PriorityFees: []*uint256.Int{uint256.NewInt(1e9)},
+		FeeGas:       []uint64{2_000_000},
+	}
+	for i := 0; i < 10; i++ {
+		block.PriorityFees = append(block.PriorityFees, uint256.NewInt(100e9))
+		block.FeeGas = append(block.FeeGas, 21_000)
+	}
+	input := &CalculatorInput{CurrentBlock: block, RecentBlocks: []*BlockData{block}}
+
+	s := DefaultStrategy()
+	s.SmoothingFactor = 0
+	s.Percentiles = PercentileNearestRank
+	est, err := s.Calculate(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !est.Standard.MaxPriorityFeePerGas.Eq(uint256.NewInt(1e9)) {
+		t.Errorf("gas weighted standard = %s, want 1 gwei", est.Standard.MaxPriorityFeePerGas)
+	}
+
+	s.GasWeighted = false
+	est, err = s.Calculate(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !est.Standard.MaxPriorityFeePerGas.Eq(uint256.NewInt(100e9)) {
+		t.Errorf("count weighted standard = %s, want 100 gwei", est.Standard.MaxPriorityFeePerGas)
+	}
+}
+
+func TestThinFees_Gas(t *testing.T) {
+	fees := make([]*uint256.Int, 10)
+	gas := make([]uint64, len(fees))
+	var total uint64
+	for i := range fees {
+		fees[i] = uint256.NewInt(uint64(10 - i))
+		gas[i] = uint64(1000 * (i + 1))
+		total += gas[i]
+	}
+
+	got, gotGas := thinFees(fees, gas, 3)
+	if len(got) != 3 || len(gotGas) != 3 {
+		t.Fatalf("thinFees() = %v, %v, want 3 fees and gas", got, gotGas)
+	}
+	var sum uint64
+	for _, g := range gotGas {
+		sum += g
+	}
+	if sum != total {
+		t.Errorf("thinned gas total = %d, want %d", sum, total)
+	}
+	// The lowest fee, 1, was paid by the largest transaction
+	if got[0].Uint64() != 1 || gotGas[0] < 10_000 {
+		t.Errorf("lowest kept fee = %d with %d gas, want 1 with at least 10000", got[0].Uint64(), gotGas[0])
+	}
+}
+
+func TestThinFees(t *testing.T) {
+	fees := make([]*uint256.Int, 301)
+	for i := range fees {
+		fees[i] = uint256.NewInt(uint64((i * 7919) % 301)) // a permutation of 0..300
+	}
+
+	tests := []struct {
+		n    int
+		want []uint64
+	}{
+		{n: 0, want: nil},
+		{n: 400, want: nil},
+		{n: 1, want: []uint64{150}},
+		{n: 5, want: []uint64{0, 75, 150, 225, 300}},
+	}
+	for _, tt := range tests {
+		got, _ := thinFees(fees, nil, tt.n)
+		if tt.want == nil {
+			if len(got) != len(fees) {
+				t.Errorf("thinFees(n=%d) kept %d fees, want all %d", tt.n, len(got), len(fees))
+			}
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("thinFees(n=%d) = %v, want %v", tt.n, got, tt.want)
+		}
+		for i, fee := range got {
+			if fee.Uint64() != tt.want[i] {
+				t.Errorf("thinFees(n=%d)[%d] = %d, want %d", tt.n, i, fee.Uint64(), tt.want[i])
+			}
+		}
+	}
+}
+
+func TestHybridStrategy_BaseFeeMultiplier(t *testing.T) {
+	blocks := func(gasUsed uint64) []*BlockData {
+		var out []*BlockData
+		for n := uint64(100); n > 90; n-- {
+			out = append(out, &BlockData{Number: n, BaseFee: uint256.NewInt(1e9), GasUsed: gasUsed, GasLimit: 30000000})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		fixed  float64
+		blocks []*BlockData
+		want   float64
+	}{
+		{name: "full blocks", blocks: blocks(30000000), want: 2.0273}, // 1.125^6
+		{name: "at target", blocks: blocks(15000000), want: 1.4387},   // 1.0625^6
+		{name: "empty blocks", blocks: blocks(0), want: 1},
+		{name: "fixed multiplier", fixed: 2, blocks: blocks(0), want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := DefaultStrategy()
+			s.BaseFeeMultiplier = tt.fixed
+
+			input := &CalculatorInput{ChainID: 1, CurrentBlock: tt.blocks[0], RecentBlocks: tt.blocks}
+			got, err := s.Calculate(context.Background(), input)
+			if err != nil {
+				t.Fatalf("Calculate() error = %v", err)
+			}
+			if math.Abs(got.BaseFeeMultiplier-tt.want) > 1e-4 {
+				t.Errorf("BaseFeeMultiplier = %.4f, want %.4f", got.BaseFeeMultiplier, tt.want)
+			}
+
+			// maxFee = baseFee * multiplier + priority
+			want := scaleFee(got.BaseFee, got.BaseFeeMultiplier)
+			want.Add(want, got.Standard.MaxPriorityFeePerGas)
+			if !got.Standard.MaxFeePerGas.Eq(want) {
+				t.Errorf("Standard MaxFeePerGas = %s, want %s", got.Standard.MaxFeePerGas, want)
+			}
+		})
+	}
+}
+
+func TestHybridStrategy_IncludableOnly(t *testing.T) {
+	block := &BlockData{Number: 1, BaseFee: uint256.NewInt(10e9), GasUsed: 15e6, GasLimit: 30e6}
+	tx := func(maxFee, tip uint64) *TxData {
+		return &TxData{IsEIP1559: true, MaxFeePerGas: uint256.NewInt(maxFee), MaxPriorityFeePerGas: uint256.NewInt(tip)}
+	}
+	input := &CalculatorInput{
+		CurrentBlock: block,
+		RecentBlocks: []*BlockData{block},
+		PendingTxs: []*TxData{
+			tx(20e9, 2e9),
+			tx(30e9, 3e9),
+			tx(5e9, 5e9), // fee cap below the base fee
+			{GasPrice: uint256.NewInt(1e9)},
+		},
+	}
+
+	est, err := DefaultStrategy().Calculate(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+	if est.MempoolSampled != 4 || est.MempoolIncludable != 2 {
+		t.Errorf("sampled/includable = %d/%d, want 4/2", est.MempoolSampled, est.MempoolIncludable)
+	}
+	if got := est.Distribution.Mempool[len(est.Distribution.Mempool)-1]; got.Uint64() != 3e9 {
+		t.Errorf("max mempool tip = %v, want 3 gwei from includable transactions", got)
+	}
+}
+
+func TestHybridStrategy_EstimatesIndependent(t *testing.T) {
+	block := &BlockData{Number: 1, BaseFee: uint256.NewInt(10e9), GasUsed: 15e6, GasLimit: 30e6}
+	input := func(tip uint64) *CalculatorInput {
+		return &CalculatorInput{
+			CurrentBlock: block,
+			RecentBlocks: []*BlockData{block},
+			PendingTxs: []*TxData{
+				{IsEIP1559: true, Gas: 21000, MaxFeePerGas: uint256.NewInt(100e9), MaxPriorityFeePerGas: uint256.NewInt(tip)},
+			},
+		}
+	}
+
+	s := DefaultStrategy()
+	first, err := s.Calculate(context.Background(), input(2e9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Calculate(context.Background(), input(7e9)); err != nil {
+		t.Fatal(err)
+	}
+	// Fees computed for the first estimate must not be reused by the second
+	if got := first.Distribution.Mempool[0]; got.Uint64() != 2e9 {
+		t.Errorf("first mempool tip = %v, want 2 gwei", got)
+	}
+	if got := first.Demand[0].Tip; got.Uint64() != 2e9 {
+		t.Errorf("first demand tip = %v, want 2 gwei", got)
+	}
+}
+
+func TestHybridStrategy_PendingBlock(t *testing.T) {
+	head := &BlockData{Number: 1, BaseFee: uint256.NewInt(10e9), GasUsed: 15e6, GasLimit: 30e6}
+	mempool := []*TxData{
+		{IsEIP1559: true, MaxFeePerGas: uint256.NewInt(50e9), MaxPriorityFeePerGas: uint256.NewInt(2e9)},
+	}
+	pendingBlock := func(number uint64) *BlockData {
+		return &BlockData{Number: number, PriorityFees: []*uint256.Int{uint256.NewInt(10e9)}}
+	}
+
+	tests := []struct {
+		name    string
+		pending []*TxData
+		block   *BlockData
+		want    uint64
+		sources []FeeSource
+	}{
+		{"mempool only", mempool, nil, 2e9, []FeeSource{FeeSourceMempool}},
+		{"blended", mempool, pendingBlock(2), 6e9, []FeeSource{FeeSourceMempool, FeeSourcePendingBlock}},
+		{"pending block only", nil, pendingBlock(2), 10e9, []FeeSource{FeeSourcePendingBlock}},
+		{"stale pending block", mempool, pendingBlock(1), 2e9, []FeeSource{FeeSourceMempool}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			est, err := DefaultStrategy().Calculate(context.Background(), &CalculatorInput{
+				CurrentBlock: head,
+				RecentBlocks: []*BlockData{head},
+				PendingTxs:   tt.pending,
+				PendingBlock: tt.block,
+			})
+			if err != nil {
+				t.Fatalf("Calculate() error = %v", err)
+			}
+			if got := est.Standard.MaxPriorityFeePerGas.Uint64(); got != tt.want {
+				t.Errorf("Standard priority fee = %d, want %d", got, tt.want)
+			}
+			if !slices.Equal(est.Standard.Sources, tt.sources) {
+				t.Errorf("Standard sources = %v, want %v", est.Standard.Sources, tt.sources)
+			}
+		})
+	}
+}
+
+func TestHybridStrategy_Samples(t *testing.T) {
+	fees := func(n int) []*uint256.Int {
+		out := make([]*uint256.Int, n)
+		for i := range out {
+			out[i] = uint256.NewInt(uint64(i+1) * 1e9)
+		}
+		return out
+	}
+	head := &BlockData{Number: 3, BaseFee: uint256.NewInt(10e9), GasUsed: 15e6, GasLimit: 30e6, PriorityFees: fees(3)}
+	input := &CalculatorInput{
+		CurrentBlock: head,
+		RecentBlocks: []*BlockData{head, {Number: 2}, {Number: 1, PriorityFees: fees(2)}},
+		PendingTxs: []*TxData{
+			{IsEIP1559: true, MaxFeePerGas: uint256.NewInt(50e9), MaxPriorityFeePerGas: uint256.NewInt(2e9)},
+			{IsEIP1559: true, MaxFeePerGas: uint256.NewInt(1e9), MaxPriorityFeePerGas: uint256.NewInt(1e9)}, // can't pay the base fee
+		},
+	}
+	est, err := DefaultStrategy().Calculate(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (SampleSizes{Blocks: 2, Historical: 5, Mempool: 1}); est.Samples != want {
+		t.Errorf("Samples = %+v, want %+v", est.Samples, want)
+	}
+	if want := []FeeSource{FeeSourceHistory, FeeSourceMempool}; !slices.Equal(est.Urgent.Sources, want) {
+		t.Errorf("Urgent sources = %v, want %v", est.Urgent.Sources, want)
+	}
+
+	est, err = DefaultStrategy().Calculate(context.Background(), &CalculatorInput{CurrentBlock: &BlockData{Number: 1, BaseFee: uint256.NewInt(10e9)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.Samples != (SampleSizes{}) || !slices.Equal(est.Slow.Sources, []FeeSource{FeeSourceDefault}) {
+		t.Errorf("without data: samples %+v, sources %v", est.Samples, est.Slow.Sources)
+	}
+}
+
+func TestHybridStrategy_Explain(t *testing.T) {
+	head := &BlockData{Number: 1, BaseFee: uint256.NewInt(10e9), GasUsed: 15e6, GasLimit: 30e6,
+		PriorityFees: []*uint256.Int{uint256.NewInt(2e9)}}
+	input := &CalculatorInput{
+		CurrentBlock: head,
+		RecentBlocks: []*BlockData{head},
+		PendingTxs: []*TxData{
+			{IsEIP1559: true, MaxFeePerGas: uint256.NewInt(50e9), MaxPriorityFeePerGas: uint256.NewInt(4e9)},
+		},
+		PreviousEstimate: &GasEstimate{
+			Urgent:   PriorityEstimate{MaxPriorityFeePerGas: uint256.NewInt(1e9), MaxFeePerGas: uint256.NewInt(21e9)},
+			Fast:     PriorityEstimate{MaxPriorityFeePerGas: uint256.NewInt(1e9), MaxFeePerGas: uint256.NewInt(21e9)},
+			Standard: PriorityEstimate{MaxPriorityFeePerGas: uint256.NewInt(1e9), MaxFeePerGas: uint256.NewInt(21e9)},
+			Slow:     PriorityEstimate{MaxPriorityFeePerGas: uint256.NewInt(1e9), MaxFeePerGas: uint256.NewInt(21e9)},
+		},
+	}
+	s := DefaultStrategy()
+	s.MaxPriorityFee = uint256.NewInt(3e9)
+
+	est, err := s.Calculate(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+	if est.Explanation != nil {
+		t.Fatal("Explanation set without Explain")
+	}
+
+	input.Explain = true
+	est, err = s.Calculate(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+	x := est.Explanation
+	if x == nil {
+		t.Fatal("Explanation not set")
+	}
+	if x.HistoricalSamples != 1 || x.MempoolSamples != 1 || x.PendingBlockSamples != 0 {
+		t.Errorf("samples = %d/%d/%d, want 1/1/0", x.HistoricalSamples, x.MempoolSamples, x.PendingBlockSamples)
+	}
+
+	// 0.3 * 2 gwei + 0.7 * 4 gwei = 3.4 gwei, clamped to 3 gwei, then
+	// smoothed towards the previous 1 gwei
+	std := x.Standard
+	if std.Historical.Uint64() != 2e9 || std.Mempool.Uint64() != 4e9 || std.PendingBlock != nil {
+		t.Errorf("percentiles = %v/%v/%v", std.Historical, std.Mempool, std.PendingBlock)
+	}
+	if std.HistoricalWeight != 0.3 || std.Default {
+		t.Errorf("HistoricalWeight = %v, Default = %v", std.HistoricalWeight, std.Default)
+	}
+	if std.Blended.Uint64() != 3.4e9 || std.Clamped != ClampedMax || std.Unsmoothed.Uint64() != 3e9 {
+		t.Errorf("Blended = %v, Clamped = %q, Unsmoothed = %v", std.Blended, std.Clamped, std.Unsmoothed)
+	}
+	if !std.Final.Eq(est.Standard.MaxPriorityFeePerGas) || std.SmoothingDelta() >= 0 {
+		t.Errorf("Final = %v, SmoothingDelta = %v", std.Final, std.SmoothingDelta())
+	}
+}
+
+func TestPriorityEstimate_GasPrice(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseFee *uint256.Int
+		tip     uint64
+		maxFee  uint64
+		want    uint64
+	}{
+		{"base fee plus tip", uint256.NewInt(30e9), 2e9, 62e9, 32e9},
+		{"capped at max fee", uint256.NewInt(30e9), 2e9, 31e9, 31e9},
+		{"no base fee", nil, 2e9, 2e9, 2e9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := PriorityEstimate{
+				MaxPriorityFeePerGas: uint256.NewInt(tt.tip),
+				MaxFeePerGas:         uint256.NewInt(tt.maxFee),
+			}
+			if got := p.GasPrice(tt.baseFee); got.Uint64() != tt.want {
+				t.Errorf("GasPrice() = %v, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package estimator
+
+import "github.com/holiman/uint256"
+
+// FeeCaps are operator-configured upper bounds on recommended fees, applied
+// to every tier whatever the strategy produced. Nil fields are unbounded.
+type FeeCaps struct {
+	MaxPriorityFeePerGas *uint256.Int
+	MaxFeePerGas         *uint256.Int
+}
+
+// FeeCapEnforcer applies fee caps to estimates derived after publication,
+// e.g. by ForGasAmount. Implemented by Provider.
+type FeeCapEnforcer interface {
+	EnforceCaps(est *GasEstimate)
+}
+
+// enabled reports whether any cap is set.
+func (c FeeCaps) enabled() bool {
+	return c.MaxPriorityFeePerGas != nil || c.MaxFeePerGas != nil
+}
+
+// apply lowers tier fees above the caps and returns how many values were
+// capped. The tip never exceeds the max fee after capping. Capped values are
+// replaced rather than modified, since they may be shared with other
+// estimates.
+func (c FeeCaps) apply(est *GasEstimate) int {
+	hits := 0
+	for _, tier := range []*PriorityEstimate{&est.Urgent, &est.Fast, &est.Standard, &est.Slow} {
+		if capped, ok := capInt(tier.MaxFeePerGas, c.MaxFeePerGas); ok {
+			tier.MaxFeePerGas = capped
+			hits++
+		}
+		if capped, ok := capInt(tier.MaxPriorityFeePerGas, c.MaxPriorityFeePerGas); ok {
+			tier.MaxPriorityFeePerGas = capped
+			hits++
+		}
+		if capped, ok := capInt(tier.MaxPriorityFeePerGas, tier.MaxFeePerGas); ok {
+			tier.MaxPriorityFeePerGas = capped
+		}
+	}
+	return hits
+}
+
+// capInt returns a copy of limit if v exceeds it.
+func capInt(v, limit *uint256.Int) (*uint256.Int, bool) {
+	if v == nil || limit == nil || !v.Gt(limit) {
+		return nil, false
+	}
+	return new(uint256.Int).Set(limit), true
+}
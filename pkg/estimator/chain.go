@@ -0,0 +1,152 @@
+package estimator
+
+import (
+	"sync"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+)
+
+// ChainStatusReader reports the estimator's view of the chain head.
+// Implemented by Estimator; used by the chain status API.
+type ChainStatusReader interface {
+	ChainStatus() ChainStatus
+}
+
+// Halt detection defaults: the head is considered halted after
+// haltBlocks block times, or DefaultHaltThreshold when the block time is
+// unknown.
+const (
+	DefaultHaltThreshold = 2 * time.Minute
+	haltBlocks           = 10
+)
+
+// ChainStatus is the estimator's view of the chain: the newest block it
+// processed, how old that block is and how often the chain reorganized.
+type ChainStatus struct {
+	// Running is false before Run starts and after it returns, e.g. on
+	// standby followers; the other fields then describe the last run.
+	Running    bool
+	Paused     bool
+	Degraded   bool
+	Subscribed bool
+
+	// Head is the newest block processed; zero before the first.
+	HeadNumber    uint64
+	HeadHash      string
+	HeadTimestamp time.Time
+	HeadSeen      time.Time
+
+	// HeadAge is how long before now the head block was produced. Halted
+	// is set when it exceeds HaltThreshold; dev chains, which mine on
+	// demand, never halt.
+	HeadAge       time.Duration
+	HaltThreshold time.Duration
+	Halted        bool
+
+	// Reorgs counts heads that replaced or did not build on the previous
+	// head; LastReorgDepth is the number of blocks the last one replaced.
+	Reorgs         uint64
+	LastReorg      time.Time
+	LastReorgDepth uint64
+
+	// Divergences counts new heads on which witness nodes disagreed with
+	// the primary node (see WithCrossCheck).
+	Divergences uint64
+
+	// Resyncing is set while estimates are held back because the node
+	// re-syncs, since ResyncingSince; Resyncs counts re-syncs detected
+	// (see WithResyncGuard).
+	Resyncing      bool
+	ResyncingSince time.Time
+	Resyncs        uint64
+}
+
+// WithHaltThreshold sets how old the head block may get before
+// ChainStatus reports the chain halted. Zero derives it from the block
+// time: haltBlocks blocks, or DefaultHaltThreshold when the block time is
+// unknown. Default: zero.
+func WithHaltThreshold(d time.Duration) Option {
+	return func(e *Estimator) {
+		e.haltThreshold = d
+	}
+}
+
+// chainHead tracks the newest processed block and counts reorgs.
+type chainHead struct {
+	mu        sync.Mutex
+	number    uint64
+	hash      string
+	timestamp time.Time
+	seen      time.Time
+
+	reorgs         uint64
+	lastReorg      time.Time
+	lastReorgDepth uint64
+}
+
+// observe records block as the new head, first seen at seen, and returns
+// how many blocks it replaced: the heights at or above it for a block no
+// newer than the head, or the head itself for a child of another block.
+// Zero means it extended the chain or repeated the head.
+func (h *chainHead) observe(block *eth.Block, seen time.Time) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var depth uint64
+	switch {
+	case h.seen.IsZero():
+	case block.Number == h.number && block.Hash == h.hash:
+		return 0
+	case block.Number <= h.number:
+		depth = h.number - block.Number + 1
+	case block.Number == h.number+1 && block.ParentHash != "" && h.hash != "" && block.ParentHash != h.hash:
+		depth = 1
+	}
+	if depth > 0 {
+		h.reorgs++
+		h.lastReorg = seen
+		h.lastReorgDepth = depth
+	}
+	h.number, h.hash, h.timestamp, h.seen = block.Number, block.Hash, block.Timestamp, seen
+	return depth
+}
+
+// ChainStatus returns the estimator's view of the chain head as of now.
+func (e *Estimator) ChainStatus() ChainStatus {
+	e.mu.Lock()
+	running, network := e.running, e.network
+	e.mu.Unlock()
+
+	e.head.mu.Lock()
+	st := ChainStatus{
+		Running:        running,
+		Paused:         e.paused.Load(),
+		Degraded:       e.degraded.Load(),
+		HeadNumber:     e.head.number,
+		HeadHash:       e.head.hash,
+		HeadTimestamp:  e.head.timestamp,
+		HeadSeen:       e.head.seen,
+		Reorgs:         e.head.reorgs,
+		LastReorg:      e.head.lastReorg,
+		LastReorgDepth: e.head.lastReorgDepth,
+	}
+	e.head.mu.Unlock()
+
+	st.Divergences = e.divergences.Load()
+	st.Resyncing, st.ResyncingSince = e.resync.status()
+	st.Resyncs = e.resync.count.Load()
+	st.Subscribed = running && !st.Degraded
+	st.HaltThreshold = e.haltThreshold
+	if st.HaltThreshold <= 0 {
+		st.HaltThreshold = DefaultHaltThreshold
+		if network.BlockTime > 0 {
+			st.HaltThreshold = haltBlocks * network.BlockTime
+		}
+	}
+	if !st.HeadSeen.IsZero() {
+		st.HeadAge = max(e.clock.Now().Sub(st.HeadTimestamp), 0)
+		st.Halted = st.HeadAge > st.HaltThreshold && !e.dataPlan().DevChain
+	}
+	return st
+}
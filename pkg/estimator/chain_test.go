@@ -0,0 +1,80 @@
+package estimator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+func TestEstimator_ChainStatus(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	clock := &manualClock{now: now}
+	block := func(number uint64, fork, parentFork int) *eth.Block {
+		b := &eth.Block{
+			Number:    number,
+			Hash:      fmt.Sprintf("0x%x-%d", number, fork),
+			Timestamp: now,
+			BaseFee:   uint256.NewInt(1e9),
+		}
+		if number > 0 {
+			b.ParentHash = fmt.Sprintf("0x%x-%d", number-1, parentFork)
+		}
+		return b
+	}
+	client := &mockBlockReader{
+		chainIDFunc: func(ctx context.Context) (uint64, error) { return 1, nil },
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) {
+			return block(100, 0, 0), nil
+		},
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			return block(number.Uint64(), 0, 0), nil
+		},
+	}
+	e := New(client, nil, nil, NewProvider(), WithHistorySize(5), WithClock(clock), WithHaltThreshold(time.Minute))
+	if st := e.ChainStatus(); st.HeadNumber != 0 || st.Halted {
+		t.Errorf("status before bootstrap = %+v, want no head", st)
+	}
+	if err := e.Bootstrap(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if st := e.ChainStatus(); st.HeadNumber != 100 || st.HeadHash != "0x64-0" || st.Reorgs != 0 {
+		t.Errorf("status after bootstrap = %+v, want head 100", st)
+	}
+
+	steps := []struct {
+		name      string
+		block     *eth.Block
+		reorgs    uint64
+		lastDepth uint64
+	}{
+		{"child", block(101, 0, 0), 0, 0},
+		{"repeated head", block(101, 0, 0), 0, 0},
+		{"replaced head", block(101, 1, 0), 1, 1},
+		{"child of the old head", block(102, 0, 0), 2, 1},
+		{"replaced two blocks", block(101, 2, 0), 3, 2},
+		{"child", block(102, 2, 2), 3, 2},
+	}
+	for _, step := range steps {
+		e.IngestBlock(context.Background(), step.block)
+		st := e.ChainStatus()
+		if st.HeadNumber != step.block.Number || st.HeadHash != step.block.Hash {
+			t.Errorf("%s: head = %d %s, want %d %s", step.name, st.HeadNumber, st.HeadHash, step.block.Number, step.block.Hash)
+		}
+		if st.Reorgs != step.reorgs || st.LastReorgDepth != step.lastDepth {
+			t.Errorf("%s: reorgs = %d (depth %d), want %d (depth %d)", step.name, st.Reorgs, st.LastReorgDepth, step.reorgs, step.lastDepth)
+		}
+	}
+
+	clock.now = now.Add(time.Minute)
+	if st := e.ChainStatus(); st.Halted || st.HeadAge != time.Minute {
+		t.Errorf("status at threshold = age %v halted %v, want age 1m not halted", st.HeadAge, st.Halted)
+	}
+	clock.now = now.Add(time.Minute + time.Second)
+	if st := e.ChainStatus(); !st.Halted || st.HaltThreshold != time.Minute {
+		t.Errorf("status past threshold = %+v, want halted", st)
+	}
+}
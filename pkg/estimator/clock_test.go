@@ -0,0 +1,158 @@
+package estimator_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+	"github.com/branched-services/go-gas/pkg/estimator/estimatortest"
+)
+
+// waitFor polls cond while background goroutines catch up with the fake clock.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestEstimator_RunWithFakeClock(t *testing.T) {
+	node := estimatortest.NewNode(estimatortest.ChainID)
+	for n := uint64(1); n <= 10; n++ {
+		node.AddBlock(estimatortest.EthBlock(n, 10*estimatortest.Gwei, 0.5, 2*estimatortest.Gwei))
+	}
+
+	clock := estimatortest.NewClock(estimatortest.BlockTime(10).Add(time.Second))
+	provider := estimator.NewProvider(estimator.WithProviderClock(clock))
+	e := estimator.New(node, node, node, provider,
+		estimator.WithClock(clock),
+		estimator.WithHistorySize(5),
+		estimator.WithRecalcInterval(time.Second),
+		estimator.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- e.Run(ctx) }()
+
+	// Recalc ticker and pending-tx batch timer are both armed once the loop is running
+	clock.BlockUntil(2)
+
+	est, err := provider.Current(ctx)
+	if err != nil {
+		t.Fatalf("Current() after bootstrap error = %v", err)
+	}
+	if est.BlockNumber != 10 {
+		t.Errorf("BlockNumber = %d, want 10", est.BlockNumber)
+	}
+	if !est.Timestamp.Equal(clock.Now()) {
+		t.Errorf("Timestamp = %v, want %v", est.Timestamp, clock.Now())
+	}
+	if !est.UpdatedAt.Equal(clock.Now()) {
+		t.Errorf("UpdatedAt = %v, want %v", est.UpdatedAt, clock.Now())
+	}
+
+	// A tick triggers exactly one recalculation, stamped with fake time
+	updates := provider.UpdateCount()
+	clock.Advance(time.Second)
+	waitFor(t, "ticker recalculation", func() bool { return provider.UpdateCount() > updates })
+
+	est, _ = provider.Current(ctx)
+	if !est.Timestamp.Equal(clock.Now()) {
+		t.Errorf("Timestamp after tick = %v, want %v", est.Timestamp, clock.Now())
+	}
+
+	// A new head is picked up without advancing time
+	node.AddBlock(estimatortest.EthBlock(11, 10*estimatortest.Gwei, 0.5, 2*estimatortest.Gwei))
+	waitFor(t, "new block", func() bool {
+		est, _ := provider.Current(ctx)
+		return est.BlockNumber == 11
+	})
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
+
+func TestEstimator_DegradedMode(t *testing.T) {
+	node := estimatortest.NewNode(estimatortest.ChainID)
+	for n := uint64(1); n <= 10; n++ {
+		node.AddBlock(estimatortest.EthBlock(n, 10*estimatortest.Gwei, 0.5, 2*estimatortest.Gwei))
+	}
+
+	clock := estimatortest.NewClock(estimatortest.BlockTime(10).Add(time.Second))
+	provider := estimator.NewProvider()
+	e := estimator.New(node, node, node, provider,
+		estimator.WithClock(clock),
+		estimator.WithHistorySize(5),
+		estimator.WithRecalcInterval(time.Second),
+		estimator.WithDegradedMode(500*time.Millisecond),
+		estimator.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- e.Run(ctx) }()
+	clock.BlockUntil(2)
+
+	// Losing the subscription switches to polling instead of stopping Run
+	node.DropSubscriptions()
+	waitFor(t, "degraded mode", e.Degraded)
+	clock.BlockUntil(3) // recalc and poll tickers, resubscribe timer
+
+	node.AddBlock(estimatortest.EthBlock(11, 10*estimatortest.Gwei, 0.5, 2*estimatortest.Gwei))
+	clock.Advance(500 * time.Millisecond)
+	waitFor(t, "polled block", func() bool {
+		est, _ := provider.Current(ctx)
+		return est.BlockNumber == 11
+	})
+	if est, _ := provider.Current(ctx); !est.Degraded {
+		t.Error("estimate from polled block not flagged degraded")
+	}
+
+	// The resubscribe backoff elapses and blocks stream again
+	clock.Advance(time.Second)
+	waitFor(t, "resubscription", func() bool { return !e.Degraded() })
+
+	node.AddBlock(estimatortest.EthBlock(12, 10*estimatortest.Gwei, 0.5, 2*estimatortest.Gwei))
+	waitFor(t, "streamed block", func() bool {
+		est, _ := provider.Current(ctx)
+		return est.BlockNumber == 12 && !est.Degraded
+	})
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
+
+func TestFixtures_Strategy(t *testing.T) {
+	blocks := estimatortest.Blocks(100, 5, 10*estimatortest.Gwei, 0.5, 1*estimatortest.Gwei, 3*estimatortest.Gwei)
+	input := estimatortest.Input(blocks,
+		estimatortest.EIP1559Tx(30*estimatortest.Gwei, 2*estimatortest.Gwei),
+		estimatortest.LegacyTx(15*estimatortest.Gwei),
+	)
+
+	est, err := estimator.DefaultStrategy().Calculate(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+	if est.BlockNumber != 100 {
+		t.Errorf("BlockNumber = %d, want 100", est.BlockNumber)
+	}
+	if !est.Timestamp.Equal(input.Now) {
+		t.Errorf("Timestamp = %v, want %v", est.Timestamp, input.Now)
+	}
+}
@@ -0,0 +1,128 @@
+package estimator
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+)
+
+// ErrNotRunning is returned by Resubscribe when Run is not active.
+var ErrNotRunning = errors.New("estimator not running")
+
+// releaseTimeout bounds how long Resubscribe waits for a canceled
+// subscription to close its channel.
+const releaseTimeout = 5 * time.Second
+
+// Controller provides operational control over a running estimator, e.g.
+// to hold estimates steady during node maintenance without restarting and
+// losing the block history. Implemented by Estimator; used by the admin API.
+type Controller interface {
+	// Pause stops ingesting blocks and pending transactions and stops
+	// recalculating; the last estimate keeps being served.
+	Pause()
+
+	// Resume undoes Pause and recalculates right away.
+	Resume(ctx context.Context) error
+
+	// Paused reports whether the estimator is paused.
+	Paused() bool
+
+	// Recalculate computes and publishes an estimate now.
+	Recalculate(ctx context.Context) error
+
+	// Resubscribe replaces the node subscriptions with new ones.
+	Resubscribe(ctx context.Context) error
+}
+
+// Pause stops Run from processing new blocks, pending transactions and
+// periodic recalculations until Resume. Subscriptions stay open and their
+// events are dropped, so blocks seen while paused are missing from the
+// history.
+func (e *Estimator) Pause() {
+	if !e.paused.Swap(true) {
+		e.logger.Warn("estimation paused")
+	}
+}
+
+// Resume restarts processing after Pause and publishes a fresh estimate.
+func (e *Estimator) Resume(ctx context.Context) error {
+	if e.paused.Swap(false) {
+		e.logger.Info("estimation resumed")
+	}
+	return e.Recalculate(ctx)
+}
+
+// Paused reports whether Pause is in effect.
+func (e *Estimator) Paused() bool {
+	return e.paused.Load()
+}
+
+// Running reports whether Run is active.
+func (e *Estimator) Running() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.running
+}
+
+// Resubscribe makes Run drop its new heads and pending transaction
+// subscriptions and open new ones, e.g. after the node behind the endpoint
+// was restarted and stopped sending notifications. If subscribing fails,
+// Run retries in the background (polling meanwhile if degraded mode is
+// enabled) and the error is returned.
+func (e *Estimator) Resubscribe(ctx context.Context) error {
+	e.mu.Lock()
+	reqs := e.resubReqs
+	e.mu.Unlock()
+	if reqs == nil {
+		return ErrNotRunning
+	}
+
+	done := make(chan error, 1)
+	select {
+	case reqs <- done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// awaitClosed drains a canceled new heads subscription until its channel
+// closes, which for eth.WSSubscriber means the node subscription was
+// released. A nil channel returns immediately.
+func (e *Estimator) awaitClosed(ch <-chan *eth.Block) {
+	if ch == nil {
+		return
+	}
+	timer := e.clock.NewTimer(releaseTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timer.C():
+			return
+		}
+	}
+}
+
+// addPendingTx adds a pending transaction from one of Run's mempool sources
+// unless paused.
+func (e *Estimator) addPendingTx(tx *eth.Transaction) {
+	if tx != nil && !e.paused.Load() {
+		e.countTxType(tx.Type)
+		e.state.addTx(tx)
+		e.events.MempoolSampled.publish(MempoolSampled{Tx: tx})
+	}
+}
+
+// Verify interface compliance at compile time.
+var _ Controller = (*Estimator)(nil)
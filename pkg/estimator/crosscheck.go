@@ -0,0 +1,148 @@
+package estimator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+// Witness is an additional node that new heads are cross-checked against.
+type Witness struct {
+	Name   string
+	Client eth.BlockReader
+}
+
+// HeadView is one node's version of a block: its hash and base fee in wei,
+// or Err when the node failed or did not have the block yet.
+type HeadView struct {
+	Node    string
+	Hash    string
+	BaseFee string
+	Err     string
+}
+
+// Divergence is a new head on which the primary node and its witnesses
+// disagreed. Views holds every node's version, the primary's first.
+// Replaced is set when the majority outvoted the primary and its block
+// was processed instead.
+type Divergence struct {
+	ChainID     uint64
+	BlockNumber uint64
+	DetectedAt  time.Time
+	Views       []HeadView
+	Majority    HeadView
+	Replaced    bool
+}
+
+// primaryNode names the estimator's own client in HeadViews.
+const primaryNode = "primary"
+
+// WithCrossCheck fetches every new head from each witness as well and
+// compares block hashes and base fees, catching a node that serves a
+// forked or stale view. Witnesses that fail or don't have the block yet
+// abstain. On disagreement the divergence is logged, counted in
+// ChainStatus and passed to handler, if not nil; when a strict majority of
+// the nodes that answered agrees on another version than the primary's,
+// that version is processed instead. handler runs on the block processing
+// path and must not block.
+// Disabled by default.
+func WithCrossCheck(witnesses []Witness, handler func(Divergence)) Option {
+	return func(e *Estimator) {
+		e.witnesses = witnesses
+		e.onDivergence = handler
+	}
+}
+
+// crossCheck returns the block to process for a head the primary node
+// returned as block: block itself, or the majority's version of it.
+func (e *Estimator) crossCheck(ctx context.Context, block *eth.Block) *eth.Block {
+	if len(e.witnesses) == 0 {
+		return block
+	}
+
+	views := make([]HeadView, len(e.witnesses)+1)
+	blocks := make([]*eth.Block, len(e.witnesses)+1)
+	views[0], blocks[0] = headView(primaryNode, block, nil), block
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeouts.Block)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i, w := range e.witnesses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b, err := w.Client.BlockByNumber(ctx, uint256.NewInt(block.Number))
+			views[i+1], blocks[i+1] = headView(w.Name, b, err), b
+		}()
+	}
+	wg.Wait()
+
+	// Tally the versions of the nodes that answered
+	votes := make(map[HeadView]int)
+	answered := 0
+	for _, v := range views {
+		if v.Err == "" {
+			votes[HeadView{Hash: v.Hash, BaseFee: v.BaseFee}]++
+			answered++
+		}
+	}
+	if len(votes) == 1 {
+		return block
+	}
+
+	primary := HeadView{Hash: views[0].Hash, BaseFee: views[0].BaseFee}
+	majority := primary
+	for version, n := range votes {
+		if 2*n > answered {
+			majority = version
+		}
+	}
+	e.mu.Lock()
+	chainID := e.chainID
+	e.mu.Unlock()
+	d := Divergence{
+		ChainID:     chainID,
+		BlockNumber: block.Number,
+		DetectedAt:  e.clock.Now(),
+		Views:       views,
+		Replaced:    majority != primary,
+	}
+	chosen := block
+	for i, v := range views {
+		if v.Hash == majority.Hash && v.BaseFee == majority.BaseFee {
+			d.Majority, chosen = v, blocks[i]
+			break
+		}
+	}
+
+	e.divergences.Add(1)
+	e.logger.Warn("nodes disagree on block",
+		"block", block.Number,
+		"primary_hash", views[0].Hash,
+		"majority_hash", d.Majority.Hash,
+		"majority_node", d.Majority.Node,
+		"replaced", d.Replaced,
+	)
+	if e.onDivergence != nil {
+		e.onDivergence(d)
+	}
+	return chosen
+}
+
+// headView summarizes node's answer to a block request.
+func headView(node string, b *eth.Block, err error) HeadView {
+	switch {
+	case err != nil:
+		return HeadView{Node: node, Err: err.Error()}
+	case b == nil || b.Hash == "":
+		return HeadView{Node: node, Err: "block not found"}
+	}
+	v := HeadView{Node: node, Hash: b.Hash}
+	if b.BaseFee != nil {
+		v.BaseFee = b.BaseFee.Dec()
+	}
+	return v
+}
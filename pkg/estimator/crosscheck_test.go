@@ -0,0 +1,102 @@
+package estimator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+func TestEstimator_CrossCheck(t *testing.T) {
+	block := func(hash string, baseFee uint64) *eth.Block {
+		return &eth.Block{Number: 100, Hash: hash, BaseFee: uint256.NewInt(baseFee)}
+	}
+	witness := func(name string, b *eth.Block, err error) Witness {
+		return Witness{Name: name, Client: &mockBlockReader{
+			blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+				if number.Uint64() != 100 {
+					t.Errorf("%s asked for block %d, want 100", name, number.Uint64())
+				}
+				return b, err
+			},
+		}}
+	}
+
+	tests := []struct {
+		name      string
+		witnesses []Witness
+		wantHash  string
+		diverged  bool
+		replaced  bool
+	}{
+		{
+			name:      "agreement",
+			witnesses: []Witness{witness("a", block("0xaa", 1e9), nil), witness("b", block("0xaa", 1e9), nil)},
+			wantHash:  "0xaa",
+		},
+		{
+			name:      "witnesses without the block abstain",
+			witnesses: []Witness{witness("a", nil, nil), witness("b", nil, errors.New("timeout"))},
+			wantHash:  "0xaa",
+		},
+		{
+			name:      "primary outvoted",
+			witnesses: []Witness{witness("a", block("0xbb", 1e9), nil), witness("b", block("0xbb", 1e9), nil)},
+			wantHash:  "0xbb",
+			diverged:  true,
+			replaced:  true,
+		},
+		{
+			name:      "base fee outvoted",
+			witnesses: []Witness{witness("a", block("0xaa", 2e9), nil), witness("b", block("0xaa", 2e9), nil)},
+			wantHash:  "0xaa",
+			diverged:  true,
+			replaced:  true,
+		},
+		{
+			name:      "no majority keeps the primary",
+			witnesses: []Witness{witness("a", block("0xbb", 1e9), nil), witness("b", block("0xcc", 1e9), nil)},
+			wantHash:  "0xaa",
+			diverged:  true,
+		},
+		{
+			name:      "single witness ties",
+			witnesses: []Witness{witness("a", block("0xbb", 1e9), nil)},
+			wantHash:  "0xaa",
+			diverged:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Divergence
+			e := New(&mockBlockReader{}, nil, nil, NewProvider(), WithCrossCheck(tt.witnesses, func(d Divergence) {
+				got = append(got, d)
+			}))
+			chosen := e.crossCheck(context.Background(), block("0xaa", 1e9))
+			if chosen.Hash != tt.wantHash {
+				t.Errorf("chosen block = %s, want %s", chosen.Hash, tt.wantHash)
+			}
+			if tt.replaced && chosen.BaseFee.Uint64() == 1e9 && tt.wantHash == "0xaa" {
+				t.Errorf("chosen base fee = %s, want the majority's", chosen.BaseFee)
+			}
+			if !tt.diverged {
+				if len(got) != 0 {
+					t.Errorf("divergences = %+v, want none", got)
+				}
+				return
+			}
+			if len(got) != 1 || e.ChainStatus().Divergences != 1 {
+				t.Fatalf("divergences = %+v, want one", got)
+			}
+			d := got[0]
+			if d.BlockNumber != 100 || d.Replaced != tt.replaced || len(d.Views) != len(tt.witnesses)+1 || d.Views[0].Node != primaryNode {
+				t.Errorf("divergence = %+v, want block 100 replaced %v with every view", d, tt.replaced)
+			}
+			if d.Majority.Hash != tt.wantHash {
+				t.Errorf("majority = %+v, want hash %s", d.Majority, tt.wantHash)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package estimator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+// MempoolSource is where the estimator gets pending transactions from.
+type MempoolSource string
+
+const (
+	// MempoolSubscription streams pending transactions over eth_subscribe,
+	// as full bodies when supported and hashes otherwise.
+	MempoolSubscription MempoolSource = "subscription"
+
+	// MempoolTxPool polls txpool_content once per block. Only chosen when
+	// the node has no pending transaction subscription; the payload can be
+	// large on busy chains.
+	MempoolTxPool MempoolSource = "txpool"
+
+	// MempoolNone disables mempool sampling; estimates use history only.
+	MempoolNone MempoolSource = "none"
+)
+
+// HistorySource is how the estimator bootstraps block history.
+type HistorySource string
+
+const (
+	// HistoryBlocks fetches every history block with its transactions.
+	HistoryBlocks HistorySource = "blocks"
+
+	// HistoryFeeHistory bootstraps with a single eth_feeHistory call,
+	// using reward percentiles as each block's priority fee sample. Blocks
+	// arriving afterwards are fetched in full.
+	HistoryFeeHistory HistorySource = "fee_history"
+)
+
+// DataPlan is the set of data sources chosen for the connected node.
+type DataPlan struct {
+	Capabilities eth.Capabilities
+	Mempool      MempoolSource
+	History      HistorySource
+
+	// DevChain is set when the dev-chain profile applies (see WithDevChain).
+	DevChain bool
+}
+
+// feeHistoryPercentiles are the reward percentiles requested when
+// bootstrapping from eth_feeHistory.
+var feeHistoryPercentiles = []float64{5, 10, 15, 20, 25, 30, 35, 40, 45, 50, 55, 60, 65, 70, 75, 80, 85, 90, 95}
+
+// defaultPlan is used when auto-detection is disabled: subscribe to the
+// mempool, and bootstrap from eth_feeHistory if the client can call it and
+// full blocks otherwise.
+func (e *Estimator) defaultPlan() DataPlan {
+	plan := DataPlan{Mempool: MempoolSubscription, History: HistoryBlocks}
+	if _, ok := e.client.(eth.FeeHistoryReader); ok && e.historySource == HistoryFeeHistory {
+		plan.History = HistoryFeeHistory
+	}
+	return plan
+}
+
+// detectPlan probes the node and picks the best available sources. Probes
+// the client or subscriber don't implement are assumed to succeed, which
+// keeps the default plan.
+func (e *Estimator) detectPlan(ctx context.Context) DataPlan {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var caps eth.Capabilities
+	if prober, ok := e.client.(eth.CapabilityProber); ok {
+		var err error
+		caps, err = prober.ProbeCapabilities(ctx)
+		if err != nil {
+			e.logger.Warn("capability probe failed", "error", err)
+		}
+	}
+	caps.PendingSubscription = true
+	if prober, ok := e.subscriber.(eth.SubscriptionProber); ok {
+		caps.PendingSubscription = prober.ProbePendingSubscription(ctx)
+	}
+
+	plan := DataPlan{Capabilities: caps, Mempool: MempoolNone, History: HistoryBlocks}
+	_, hasTxPool := e.client.(eth.TxPoolReader)
+	switch {
+	case caps.PendingSubscription:
+		plan.Mempool = MempoolSubscription
+	case caps.TxPool && hasTxPool:
+		plan.Mempool = MempoolTxPool
+	}
+	if _, ok := e.client.(eth.FeeHistoryReader); ok && caps.FeeHistory && e.historySource == HistoryFeeHistory {
+		plan.History = HistoryFeeHistory
+	}
+	return plan
+}
+
+// loadFeeHistory fills the history from eth_feeHistory, ending with latest.
+// The latest block itself is kept in full so its transactions are known to
+// be mined.
+func (e *Estimator) loadFeeHistory(ctx context.Context, reader eth.FeeHistoryReader, latest *eth.Block) error {
+	if oldest := oldestHistoryBlock(latest.Number, e.historySize); oldest < latest.Number {
+		h, err := reader.FeeHistory(ctx, int(latest.Number-oldest), latest.Number-1, feeHistoryPercentiles)
+		if err != nil {
+			return fmt.Errorf("eth_feeHistory: %w", err)
+		}
+		for i, ratio := range h.GasUsedRatio {
+			bd := &BlockData{
+				Number:   h.OldestBlock + uint64(i),
+				GasLimit: latest.GasLimit,
+				GasUsed:  uint64(ratio * float64(latest.GasLimit)),
+			}
+			if i < len(h.BaseFees) {
+				bd.BaseFee = h.BaseFees[i]
+			}
+			if i < len(h.Rewards) {
+				for _, fee := range h.Rewards[i] {
+					if fee != nil && !fee.IsZero() {
+						bd.PriorityFees = append(bd.PriorityFees, new(uint256.Int).Set(fee))
+					}
+				}
+			}
+			e.state.pushBlock(&eth.Block{Number: bd.Number}, bd)
+		}
+	}
+	e.state.pushBlock(latest, e.minedBlock(ctx, latest))
+	return nil
+}
+
+// pollTxPool samples the node's mempool through txpool_content.
+func (e *Estimator) pollTxPool(ctx context.Context) {
+	reader, ok := e.client.(eth.TxPoolReader)
+	if !ok {
+		return
+	}
+
+	txs, err := reader.PendingTransactions(ctx, max(e.mempoolSamples, 1))
+	if err != nil {
+		e.logger.Warn("txpool poll failed", "error", err)
+		return
+	}
+	for _, tx := range txs {
+		e.addPendingTx(tx)
+	}
+}
@@ -0,0 +1,232 @@
+package estimator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+// probingClient is a block reader that reports fixed capabilities and
+// serves a fixed fee history and txpool.
+type probingClient struct {
+	mockBlockReader
+	caps       eth.Capabilities
+	history    *eth.FeeHistory
+	historyErr error
+
+	// historyCalls records the block count and newest block of each
+	// FeeHistory call
+	historyCalls [][2]uint64
+}
+
+func (c *probingClient) ProbeCapabilities(ctx context.Context) (eth.Capabilities, error) {
+	return c.caps, nil
+}
+
+func (c *probingClient) FeeHistory(ctx context.Context, blocks int, newest uint64, percentiles []float64) (*eth.FeeHistory, error) {
+	c.historyCalls = append(c.historyCalls, [2]uint64{uint64(blocks), newest})
+	return c.history, c.historyErr
+}
+
+func (c *probingClient) PendingTransactions(ctx context.Context, limit int) ([]*eth.Transaction, error) {
+	return nil, nil
+}
+
+type probingSubscriber struct {
+	mockSubscriber
+	pending bool
+}
+
+func (s *probingSubscriber) ProbePendingSubscription(ctx context.Context) bool {
+	return s.pending
+}
+
+func TestEstimator_DetectPlan(t *testing.T) {
+	tests := []struct {
+		name        string
+		caps        eth.Capabilities
+		pendingSub  bool
+		wantMempool MempoolSource
+		wantHistory HistorySource
+	}{
+		{"full support", eth.Capabilities{TxPool: true, FeeHistory: true}, true, MempoolSubscription, HistoryFeeHistory},
+		{"no subscription", eth.Capabilities{TxPool: true}, false, MempoolTxPool, HistoryBlocks},
+		{"nothing optional", eth.Capabilities{}, false, MempoolNone, HistoryBlocks},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &probingClient{caps: tt.caps}
+			sub := &probingSubscriber{pending: tt.pendingSub}
+			e := New(client, nil, sub, NewProvider(), WithAutoDetect(true))
+
+			plan := e.detectPlan(context.Background())
+			if plan.Mempool != tt.wantMempool {
+				t.Errorf("Mempool = %q, want %q", plan.Mempool, tt.wantMempool)
+			}
+			if plan.History != tt.wantHistory {
+				t.Errorf("History = %q, want %q", plan.History, tt.wantHistory)
+			}
+			if plan.Capabilities.PendingSubscription != tt.pendingSub {
+				t.Errorf("PendingSubscription = %v, want %v", plan.Capabilities.PendingSubscription, tt.pendingSub)
+			}
+		})
+	}
+
+	// Without probers the default sources are kept
+	e := New(&mockBlockReader{}, nil, &mockSubscriber{}, NewProvider())
+	if plan := e.detectPlan(context.Background()); plan.Mempool != MempoolSubscription || plan.History != HistoryBlocks {
+		t.Errorf("plan without probers = %+v, want subscription and blocks", plan)
+	}
+}
+
+func TestEstimator_DefaultPlan(t *testing.T) {
+	tests := []struct {
+		name   string
+		client eth.BlockReader
+		opts   []Option
+		want   HistorySource
+	}{
+		{"fee history reader", &probingClient{}, nil, HistoryFeeHistory},
+		{"blocks requested", &probingClient{}, []Option{WithHistorySource(HistoryBlocks)}, HistoryBlocks},
+		{"plain block reader", &mockBlockReader{}, nil, HistoryBlocks},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New(tt.client, nil, &mockSubscriber{}, NewProvider(), tt.opts...)
+			if plan := e.defaultPlan(); plan.History != tt.want || plan.Mempool != MempoolSubscription {
+				t.Errorf("defaultPlan() = %+v, want subscription and %q", plan, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimator_LoadFeeHistoryFallback(t *testing.T) {
+	client := &probingClient{historyErr: errors.New("method not found")}
+	client.latestBlockFunc = func(ctx context.Context) (*eth.Block, error) {
+		return &eth.Block{Number: 100}, nil
+	}
+	var fetched []uint64
+	client.blockByNumberFunc = func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+		fetched = append(fetched, number.Uint64())
+		return &eth.Block{Number: number.Uint64()}, nil
+	}
+
+	e := New(client, nil, nil, NewProvider(), WithHistorySize(3))
+	e.setPlan(e.defaultPlan())
+	if err := e.loadHistory(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(fetched) != 3 || fetched[0] != 98 || fetched[2] != 100 {
+		t.Errorf("fetched blocks %v, want 98..100", fetched)
+	}
+}
+
+func TestEstimator_LoadHistoryYoungChain(t *testing.T) {
+	tests := []struct {
+		latest      uint64
+		wantHistory [][2]uint64 // eth_feeHistory calls, which fail
+		wantFetched []uint64    // blocks fetched in the fallback
+		wantLoaded  int
+	}{
+		{latest: 0, wantLoaded: 1}, // genesis alone needs no history call
+		{latest: 1, wantHistory: [][2]uint64{{1, 0}}, wantFetched: []uint64{0, 1}, wantLoaded: 2},
+		{latest: 3, wantHistory: [][2]uint64{{3, 2}}, wantFetched: []uint64{0, 1, 2, 3}, wantLoaded: 4},
+		{latest: 4, wantHistory: [][2]uint64{{3, 3}}, wantFetched: []uint64{1, 2, 3, 4}, wantLoaded: 4},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.latest), func(t *testing.T) {
+			client := &probingClient{historyErr: errors.New("method not found")}
+			client.latestBlockFunc = func(ctx context.Context) (*eth.Block, error) {
+				return &eth.Block{Number: tt.latest}, nil
+			}
+			var fetched []uint64
+			client.blockByNumberFunc = func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+				fetched = append(fetched, number.Uint64())
+				return &eth.Block{Number: number.Uint64()}, nil
+			}
+
+			e := New(client, nil, nil, NewProvider(), WithHistorySize(4))
+			e.setPlan(DataPlan{History: HistoryFeeHistory})
+			if err := e.loadHistory(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(fetched, tt.wantFetched) {
+				t.Errorf("fetched blocks %v, want %v", fetched, tt.wantFetched)
+			}
+			if !slices.Equal(client.historyCalls, tt.wantHistory) {
+				t.Errorf("eth_feeHistory calls %v, want %v", client.historyCalls, tt.wantHistory)
+			}
+			if got := e.state.history.Len(); got != tt.wantLoaded {
+				t.Errorf("history holds %d blocks, want %d", got, tt.wantLoaded)
+			}
+		})
+	}
+}
+
+func TestEstimator_LoadFeeHistory(t *testing.T) {
+	gwei := func(v uint64) *uint256.Int { return uint256.NewInt(v * 1e9) }
+	latest := &eth.Block{Number: 100, BaseFee: gwei(12), GasLimit: 30e6, GasUsed: 15e6}
+	client := &probingClient{
+		history: &eth.FeeHistory{
+			OldestBlock:  97,
+			BaseFees:     []*uint256.Int{gwei(10), gwei(11), gwei(11), gwei(12)},
+			GasUsedRatio: []float64{0.5, 1, 0.25},
+			Rewards: [][]*uint256.Int{
+				{gwei(1), gwei(2)},
+				{gwei(0), gwei(3)},
+				{gwei(1), gwei(1)},
+			},
+		},
+	}
+	client.latestBlockFunc = func(ctx context.Context) (*eth.Block, error) { return latest, nil }
+
+	e := New(client, nil, nil, NewProvider(), WithHistorySize(4))
+	e.setPlan(DataPlan{History: HistoryFeeHistory})
+	if err := e.loadHistory(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	blocks, _ := e.state.snapshot()
+	if len(blocks) != 4 {
+		t.Fatalf("loaded %d blocks, want 4", len(blocks))
+	}
+	if blocks[0].Number != 100 || blocks[3].Number != 97 {
+		t.Errorf("blocks span %d..%d, want 100..97", blocks[0].Number, blocks[3].Number)
+	}
+	if got := blocks[2]; got.GasUsed != 30e6 || !got.BaseFee.Eq(gwei(11)) || len(got.PriorityFees) != 1 {
+		t.Errorf("block 98 = %+v, want full gas, 11 gwei base fee and one nonzero reward", got)
+	}
+}
+
+func TestEstimator_BootstrapAtGenesis(t *testing.T) {
+	genesis := &eth.Block{Number: 0, BaseFee: uint256.NewInt(1e9), GasLimit: 30e6}
+	var fetched []uint64
+	client := &mockBlockReader{
+		chainIDFunc:     func(ctx context.Context) (uint64, error) { return 1337, nil },
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) { return genesis, nil },
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			fetched = append(fetched, number.Uint64())
+			return genesis, nil
+		},
+	}
+	provider := NewProvider()
+	e := New(client, &mockTxReader{}, nil, provider)
+	if err := e.Bootstrap(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(fetched) != 1 || fetched[0] != 0 {
+		t.Errorf("fetched blocks %v, want only genesis", fetched)
+	}
+	est, err := provider.Current(context.Background())
+	if err != nil {
+		t.Fatalf("no estimate at genesis: %v", err)
+	}
+	if est.BlockNumber != 0 {
+		t.Errorf("estimate for block %d, want 0", est.BlockNumber)
+	}
+}
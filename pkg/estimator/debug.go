@@ -0,0 +1,284 @@
+package estimator
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+// DebugReader exposes estimator internals for troubleshooting.
+// Implemented by Estimator and Service; used by the debug API.
+type DebugReader interface {
+	DebugSnapshot() DebugSnapshot
+}
+
+// DebugSnapshot is a point-in-time view of the estimator's inputs and
+// recent activity, for diagnosing estimates that look wrong without adding
+// log lines and redeploying.
+type DebugSnapshot struct {
+	ChainID  uint64
+	Strategy Strategy
+	Config   DebugConfig
+
+	// History is the block history, newest first.
+	History         []BlockSummary
+	HistoryCapacity int
+
+	Mempool MempoolStats
+
+	// Memory is the approximate memory held by the history and mempool
+	// sample.
+	Memory MemoryStats
+
+	// DataPlan is the data sources in use; zero before Run starts.
+	DataPlan DataPlan
+
+	// NonceTracked is the number of senders with a known confirmed nonce and
+	// NonceExcluded how many sampled transactions the last recalculation
+	// dropped for nonce gaps. Both are zero unless nonce filtering is enabled.
+	NonceTracked  int
+	NonceExcluded int
+
+	// MempoolOutliersRejected is the total number of pending fee samples
+	// rejected as outliers across all recalculations.
+	MempoolOutliersRejected uint64
+
+	// FeeCapHits is the total number of tier fees lowered by the provider's
+	// fee caps.
+	FeeCapHits uint64
+
+	// UpdateConflicts is the total number of estimates the provider
+	// rejected as older than the one it held.
+	UpdateConflicts uint64
+
+	// EstimatesRejected is the total number of estimates the sanity guard
+	// refused to publish (see WithSanityGuard).
+	EstimatesRejected uint64
+
+	// Anomalies is the total number of anomalies detected per kind; nil
+	// unless anomaly detection is enabled.
+	Anomalies map[AnomalyKind]uint64
+
+	// LastRecalc is when the last recalculation started; zero if none has run.
+	LastRecalc         time.Time
+	LastRecalcDuration time.Duration
+	LastRecalcError    string
+
+	// Components are the per-member outputs behind the latest estimate when
+	// the strategy is an EnsembleStrategy.
+	Components []ComponentEstimate
+
+	// Explanation is how the latest estimate's tiers were derived; nil
+	// unless explain mode is enabled (see WithExplain).
+	Explanation *Explanation
+
+	// Connection is the subscriber's WebSocket connection history; nil if
+	// the subscriber does not implement eth.ConnectionStatsReader.
+	Connection *eth.ConnectionStats
+
+	// Subscriptions maps each node subscription to its state, e.g.
+	// "new_heads": "active" or "pending_transactions": "closed".
+	Subscriptions map[string]string
+
+	// Paused is set while estimation is paused (see Estimator.Pause).
+	Paused bool
+}
+
+// DebugConfig is the estimator configuration in effect.
+type DebugConfig struct {
+	HistorySize    int
+	MempoolSamples int
+	RecalcInterval time.Duration
+
+	// RecalcOnBlock and RecalcPendingTxs are the event-driven
+	// recalculation triggers (see WithRecalcTriggers)
+	RecalcOnBlock    bool
+	RecalcPendingTxs int
+
+	FullPendingTxs bool
+	NonceFiltering bool
+	SamplingPolicy SamplingPolicy
+	SamplingWindow time.Duration
+	Network        Network
+}
+
+// BlockSummary describes one block in the history.
+type BlockSummary struct {
+	Number       uint64
+	BaseFee      *uint256.Int
+	GasUsed      uint64
+	GasLimit     uint64
+	Utilization  float64
+	PriorityFees int // number of fee-paying transactions
+}
+
+// MempoolStats summarizes the current mempool sample. Includable counts
+// transactions whose fee cap covers the predicted base fee of the latest
+// estimate (the head block's base fee before the first estimate). Priority
+// fees are effective fees at that base fee; nil when there are none.
+type MempoolStats struct {
+	Samples        int
+	EIP1559        int
+	Legacy         int
+	Includable     int
+	MinPriorityFee *uint256.Int
+	P50PriorityFee *uint256.Int
+	MaxPriorityFee *uint256.Int
+}
+
+// debugState records activity that isn't otherwise retained.
+type debugState struct {
+	mu                 sync.Mutex
+	lastRecalc         time.Time
+	lastRecalcDuration time.Duration
+	lastRecalcError    string
+	subscriptions      map[string]string
+	outliersRejected   uint64
+}
+
+// Subscription names reported in DebugSnapshot.Subscriptions.
+const (
+	subNewHeads   = "new_heads"
+	subPendingTxs = "pending_transactions"
+)
+
+func (d *debugState) recordRecalc(start time.Time, duration time.Duration, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.lastRecalc = start
+	d.lastRecalcDuration = duration
+	d.lastRecalcError = ""
+	if err != nil {
+		d.lastRecalcError = err.Error()
+	}
+}
+
+func (d *debugState) recordOutliers(n int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.outliersRejected += uint64(n)
+}
+
+func (d *debugState) setSubscription(name, state string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.subscriptions == nil {
+		d.subscriptions = make(map[string]string)
+	}
+	d.subscriptions[name] = state
+}
+
+// DebugSnapshot returns the current estimator internals.
+// Safe to call concurrently with Run.
+func (e *Estimator) DebugSnapshot() DebugSnapshot {
+	blocks, pending := e.state.snapshot()
+
+	e.mu.Lock()
+	chainID, network, plan := e.chainID, e.network, e.plan
+	e.mu.Unlock()
+
+	snap := DebugSnapshot{
+		ChainID:  chainID,
+		Strategy: e.strategy,
+		Config: DebugConfig{
+			HistorySize:      e.historySize,
+			MempoolSamples:   e.mempoolSamples,
+			RecalcInterval:   e.recalcInterval,
+			RecalcOnBlock:    e.recalcOnBlock,
+			RecalcPendingTxs: e.recalcTxs,
+			FullPendingTxs:   e.fullPendingTxs,
+			NonceFiltering:   e.nonceFilter,
+			SamplingPolicy:   e.samplingPolicy,
+			SamplingWindow:   e.samplingWindow,
+			Network:          network,
+		},
+		History:           make([]BlockSummary, len(blocks)),
+		HistoryCapacity:   e.state.history.Cap(),
+		DataPlan:          plan,
+		NonceTracked:      e.nonces.tracked(),
+		NonceExcluded:     e.nonces.excluded(),
+		FeeCapHits:        e.provider.CapHitCount(),
+		UpdateConflicts:   e.provider.ConflictCount(),
+		EstimatesRejected: e.rejections.Load(),
+		Subscriptions:     make(map[string]string),
+		Paused:            e.paused.Load(),
+		Memory:            e.memoryStats(blocks, pending),
+	}
+
+	if e.anomalies != nil {
+		snap.Anomalies = e.anomalies.totals()
+	}
+
+	if r, ok := e.subscriber.(eth.ConnectionStatsReader); ok {
+		stats := r.ConnectionStats()
+		snap.Connection = &stats
+	}
+
+	for i, b := range blocks {
+		snap.History[i] = BlockSummary{
+			Number:       b.Number,
+			BaseFee:      b.BaseFee,
+			GasUsed:      b.GasUsed,
+			GasLimit:     b.GasLimit,
+			Utilization:  b.GasUtilization(),
+			PriorityFees: len(b.PriorityFees),
+		}
+	}
+
+	var baseFee *uint256.Int
+	if len(blocks) > 0 {
+		baseFee = blocks[0].BaseFee
+	}
+	if est := e.provider.current.Load(); est != nil {
+		baseFee = est.BaseFee
+		snap.Components = est.Components
+		snap.Explanation = est.Explanation
+	}
+	snap.Mempool = mempoolStats(pending, baseFee)
+
+	e.debug.mu.Lock()
+	snap.LastRecalc = e.debug.lastRecalc
+	snap.LastRecalcDuration = e.debug.lastRecalcDuration
+	snap.LastRecalcError = e.debug.lastRecalcError
+	snap.MempoolOutliersRejected = e.debug.outliersRejected
+	for name, state := range e.debug.subscriptions {
+		snap.Subscriptions[name] = state
+	}
+	e.debug.mu.Unlock()
+
+	return snap
+}
+
+func mempoolStats(txs []*TxData, baseFee *uint256.Int) MempoolStats {
+	stats := MempoolStats{Samples: len(txs)}
+
+	fees := make([]*uint256.Int, 0, len(txs))
+	for _, tx := range txs {
+		if tx.IsEIP1559 {
+			stats.EIP1559++
+		} else {
+			stats.Legacy++
+		}
+		if tx.Includable(baseFee) {
+			stats.Includable++
+		}
+		fees = append(fees, tx.EffectivePriorityFee(baseFee))
+	}
+	if len(fees) == 0 {
+		return stats
+	}
+
+	slices.SortFunc(fees, func(a, b *uint256.Int) int { return a.Cmp(b) })
+	stats.MinPriorityFee = fees[0]
+	stats.P50PriorityFee = fees[(len(fees)-1)/2]
+	stats.MaxPriorityFee = fees[len(fees)-1]
+	return stats
+}
+
+// Verify interface compliance at compile time.
+var _ DebugReader = (*Estimator)(nil)
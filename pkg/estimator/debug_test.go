@@ -0,0 +1,61 @@
+package estimator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+func TestEstimator_DebugSnapshot(t *testing.T) {
+	client := &mockBlockReader{
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) {
+			return &eth.Block{Number: 10, BaseFee: uint256.NewInt(100)}, nil
+		},
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			return &eth.Block{
+				Number:   number.Uint64(),
+				BaseFee:  uint256.NewInt(100),
+				GasUsed:  15_000_000,
+				GasLimit: 30_000_000,
+			}, nil
+		},
+	}
+	e := New(client, &mockTxReader{}, &mockSubscriber{}, NewProvider(), WithHistorySize(3), WithMempoolSamples(10))
+	e.setChainID(1)
+
+	ctx := context.Background()
+	if err := e.loadHistory(ctx); err != nil {
+		t.Fatalf("loadHistory() error = %v", err)
+	}
+	for _, tip := range []uint64{300, 100, 200} {
+		e.state.addTx(&eth.Transaction{Type: 2, MaxPriorityFeePerGas: uint256.NewInt(tip), MaxFeePerGas: uint256.NewInt(1000)})
+	}
+	e.state.addTx(&eth.Transaction{GasPrice: uint256.NewInt(150)})
+	e.Recalculate(ctx)
+
+	snap := e.DebugSnapshot()
+	if snap.ChainID != 1 || snap.Config.Network.Name != "mainnet" {
+		t.Errorf("ChainID = %d, Network = %+v", snap.ChainID, snap.Config.Network)
+	}
+	if len(snap.History) != 3 || snap.HistoryCapacity != 3 {
+		t.Fatalf("History len = %d, cap = %d, want 3/3", len(snap.History), snap.HistoryCapacity)
+	}
+	if snap.History[0].Number != 10 || snap.History[0].Utilization != 0.5 {
+		t.Errorf("History[0] = %+v, want block 10 at 50%% utilization", snap.History[0])
+	}
+
+	m := snap.Mempool
+	if m.Samples != 4 || m.EIP1559 != 3 || m.Legacy != 1 || m.Includable != 4 {
+		t.Errorf("Mempool counts = %+v", m)
+	}
+	// Legacy gas price 150 at base fee 100 pays a 50 wei tip
+	if m.MinPriorityFee.Uint64() != 50 || m.P50PriorityFee.Uint64() != 100 || m.MaxPriorityFee.Uint64() != 300 {
+		t.Errorf("Mempool fees = %v/%v/%v, want 50/100/300", m.MinPriorityFee, m.P50PriorityFee, m.MaxPriorityFee)
+	}
+
+	if snap.LastRecalc.IsZero() || snap.LastRecalcError != "" {
+		t.Errorf("LastRecalc = %v, error = %q", snap.LastRecalc, snap.LastRecalcError)
+	}
+}
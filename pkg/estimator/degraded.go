@@ -0,0 +1,116 @@
+package estimator
+
+import (
+	"context"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+)
+
+// Resubscription backoff while degraded.
+const (
+	resubscribeMinBackoff = time.Second
+	resubscribeMaxBackoff = 30 * time.Second
+)
+
+// Degraded reports whether the estimator lost its new heads subscription
+// and is polling for blocks over HTTP.
+func (e *Estimator) Degraded() bool {
+	return e.degraded.Load()
+}
+
+// enterDegraded switches to polling after the new heads subscription closed.
+// The returned ticker drives pollLatestBlock; resubscribe runs until a new
+// subscription is delivered on resubCh or ctx is canceled.
+func (e *Estimator) enterDegraded(ctx context.Context, resubCh chan<- (<-chan *eth.Block)) Ticker {
+	e.degraded.Store(true)
+	e.debug.setSubscription(subNewHeads, "closed (polling)")
+
+	attrs := []any{"poll_interval", e.degradedPoll}
+	lastClose := e.lastClose()
+	if lastClose != nil {
+		attrs = append(attrs, "close_code", lastClose.Code, "close_reason", lastClose.Reason)
+	}
+	e.logger.Warn("block subscription closed, polling for blocks", attrs...)
+
+	// A server that closed normally (e.g. going away for a restart behind a
+	// load balancer) is retried right away
+	backoff := resubscribeMinBackoff
+	if lastClose != nil && lastClose.Normal() {
+		backoff = 0
+	}
+	go e.resubscribe(ctx, resubCh, backoff)
+	return e.clock.NewTicker(e.degradedPoll)
+}
+
+// lastClose returns why the subscriber's last connection ended, if known.
+func (e *Estimator) lastClose() *eth.CloseError {
+	if r, ok := e.subscriber.(eth.ConnectionStatsReader); ok {
+		return r.ConnectionStats().LastClose
+	}
+	return nil
+}
+
+// leaveDegraded resumes normal operation once resubscribed. The mempool
+// subscription shares the connection, so it is reopened too.
+func (e *Estimator) leaveDegraded(ctx context.Context) {
+	e.degraded.Store(false)
+	e.debug.setSubscription(subNewHeads, "active")
+	e.logger.Info("block subscription restored")
+
+	if e.dataPlan().Mempool == MempoolSubscription {
+		if err := e.subscribePending(ctx); err != nil {
+			e.logger.Warn("resubscribing to pending transactions", "error", err)
+		}
+	}
+}
+
+// resubscribe retries SubscribeNewHeads with exponential backoff, making
+// the first attempt after initial.
+func (e *Estimator) resubscribe(ctx context.Context, resubCh chan<- (<-chan *eth.Block), initial time.Duration) {
+	timer := e.clock.NewTimer(initial)
+	defer timer.Stop()
+	backoff := max(initial, resubscribeMinBackoff)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C():
+		}
+
+		ch, err := e.subscriber.SubscribeNewHeads(ctx)
+		if err == nil {
+			select {
+			case resubCh <- ch:
+			case <-ctx.Done():
+			}
+			return
+		}
+
+		e.logger.Debug("resubscribing to new heads failed", "error", err, "retry_in", backoff)
+		timer.Reset(backoff)
+		backoff = min(backoff*2, resubscribeMaxBackoff)
+	}
+}
+
+// pollLatestBlock fetches the latest block and, if it is newer than the
+// last one polled and the head of the history, queues it for the block
+// worker like a new head notification, so polled and streamed heads are
+// processed one at a time and in order.
+func (e *Estimator) pollLatestBlock(ctx context.Context) {
+	block, err := e.fetchLatestBlock(ctx)
+	if err != nil {
+		e.logger.Warn("polling latest block", "error", err)
+		return
+	}
+
+	if block.Number <= e.polledHead {
+		return
+	}
+	e.polledHead = block.Number
+	if latest := e.state.latest(); latest != nil && block.Number <= latest.Number {
+		return
+	}
+	e.queueBlock(block, true)
+}
@@ -0,0 +1,106 @@
+package estimator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+func TestPollLatestBlock(t *testing.T) {
+	latest := &eth.Block{Number: 11, Hash: "0x0b", BaseFee: uint256.NewInt(1e9), GasLimit: 30_000_000}
+	var fetched int
+	client := &mockBlockReader{
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) { return latest, nil },
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			fetched++
+			return nil, nil
+		},
+	}
+	e := New(client, nil, nil, NewProvider())
+
+	// The polled block waits in the queue for the block worker
+	e.pollLatestBlock(context.Background())
+	head, ok := e.blocks.next(expired())
+	if !ok || head.block != latest || !head.full {
+		t.Fatalf("queued head = %+v, %v; want the polled full block", head, ok)
+	}
+
+	// Polling the same head again queues nothing
+	e.pollLatestBlock(context.Background())
+	if head, ok := e.blocks.next(expired()); ok {
+		t.Errorf("queued head = %d after polling the same block", head.block.Number)
+	}
+
+	// The worker processes it without fetching it again
+	ctx, cancel := context.WithCancel(context.Background())
+	e.queueBlock(&eth.Block{Number: 12, Hash: "0x0c", BaseFee: uint256.NewInt(1e9), GasLimit: 30_000_000}, true)
+	done := make(chan struct{})
+	go func() { e.processBlocks(ctx); close(done) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for e.state.latest() == nil || e.state.latest().Number != 12 {
+		if time.Now().After(deadline) {
+			t.Fatal("polled block not processed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	if fetched != 0 {
+		t.Errorf("block fetched %d times, want 0", fetched)
+	}
+}
+
+// expired returns a context that is already done, so blockQueue.next
+// returns at once when nothing is queued.
+func expired() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+func TestPollLatestBlock_Budget(t *testing.T) {
+	var polls int
+	client := &mockBlockReader{
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) {
+			polls++
+			return &eth.Block{Number: 11, Hash: "0x0b"}, nil
+		},
+	}
+	limiter := newNodeLimiter(1, &manualClock{now: time.Unix(1000, 0)})
+	e := New(client, nil, nil, NewProvider(), WithNodeBudget(NodeBudget{Limiter: limiter}))
+
+	// With the budget spent, polls wait for it rather than call the node
+	if err := limiter.Wait(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	e.pollLatestBlock(ctx)
+	if polls != 0 {
+		t.Errorf("node polled %d times over budget, want 0", polls)
+	}
+}
+
+func TestPollLatestBlock_Timeout(t *testing.T) {
+	client := &mockBlockReader{
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) {
+			<-ctx.Done() // a hung node
+			return nil, ctx.Err()
+		},
+	}
+	e := New(client, nil, nil, NewProvider(), WithFetchTimeouts(FetchTimeouts{Block: 10 * time.Millisecond}))
+
+	done := make(chan struct{})
+	go func() {
+		e.pollLatestBlock(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("poll of a hung node outlasted the block timeout")
+	}
+}
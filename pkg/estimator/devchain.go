@@ -0,0 +1,103 @@
+package estimator
+
+import (
+	"context"
+	"strings"
+
+	"github.com/holiman/uint256"
+)
+
+// DevChainMode controls the dev-chain profile for local chains such as
+// Anvil, Hardhat and Ganache. They mine on demand, often produce blocks
+// without fee-paying transactions and may not support pending transaction
+// subscriptions, so the usual strategies have nothing to price from.
+type DevChainMode string
+
+const (
+	// DevChainAuto enables the profile when the chain ID or client version
+	// identifies a local dev chain (see IsDevChain).
+	DevChainAuto DevChainMode = "auto"
+
+	// DevChainOn always enables the profile.
+	DevChainOn DevChainMode = "on"
+
+	// DevChainOff never enables the profile.
+	DevChainOff DevChainMode = "off"
+)
+
+// DevChainPriorityFee is the tip quoted on dev chains when neither blocks
+// nor the mempool carry fees: 1 gwei, matching what Anvil and Hardhat
+// return for eth_maxPriorityFeePerGas.
+var DevChainPriorityFee = uint256.NewInt(1e9)
+
+// devChainIDs are chain IDs reserved by convention for local dev chains.
+var devChainIDs = map[uint64]bool{
+	1337:  true, // Ganache, geth --dev
+	31337: true, // Anvil, Hardhat
+}
+
+// devClients are web3_clientVersion prefixes of local dev chains,
+// lower-cased.
+var devClients = []string{"anvil/", "hardhatnetwork/", "ganache/", "ethereumjs testrpc/"}
+
+// IsDevChain reports whether chainID or clientVersion identify a local dev
+// chain. clientVersion may be empty when unknown.
+func IsDevChain(chainID uint64, clientVersion string) bool {
+	if devChainIDs[chainID] {
+		return true
+	}
+	v := strings.ToLower(clientVersion)
+	for _, prefix := range devClients {
+		if strings.HasPrefix(v, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// devChain reports whether the dev-chain profile applies to the connected
+// chain under the configured mode.
+func (e *Estimator) devChain(chainID uint64, clientVersion string) bool {
+	switch e.devChainMode {
+	case DevChainOn:
+		return true
+	case DevChainOff:
+		return false
+	default:
+		return IsDevChain(chainID, clientVersion)
+	}
+}
+
+// hasFeeData reports whether input holds any priority fee a strategy could
+// price from.
+func hasFeeData(input *CalculatorInput) bool {
+	if len(input.PendingTxs) > 0 {
+		return true
+	}
+	if pb := input.PendingBlock; pb != nil && len(pb.PriorityFees) > 0 {
+		return true
+	}
+	for _, b := range input.RecentBlocks {
+		if len(b.PriorityFees) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// devChainEstimate quotes DevChainPriorityFee for every tier on top of the
+// predicted base fee, which is often zero on dev chains.
+func devChainEstimate(input *CalculatorInput) *GasEstimate {
+	baseFee := nextBaseFee(input.CurrentBlock, DefaultElasticityMultiplier, DefaultBaseFeeChangeDenominator)
+	tip := func(float64) *uint256.Int { return DevChainPriorityFee }
+	return tieredEstimate(input, DefaultTierPercentiles, baseFee, 2, nil, nil, FeeSourceDefault, tip)
+}
+
+// calculate runs the strategy, except on dev chains with no fee data at
+// all, where strategies would fall back to defaults meant for busy chains.
+func (e *Estimator) calculate(ctx context.Context, input *CalculatorInput) (*GasEstimate, error) {
+	if e.dataPlan().DevChain && !hasFeeData(input) {
+		return devChainEstimate(input), nil
+	}
+	return e.strategy.Calculate(ctx, input)
+}
@@ -0,0 +1,68 @@
+package estimator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+func TestIsDevChain(t *testing.T) {
+	tests := []struct {
+		chainID uint64
+		version string
+		want    bool
+	}{
+		{31337, "", true},
+		{1337, "Geth/v1.14.0", true},
+		{900, "anvil/v0.2.0", true},
+		{900, "HardhatNetwork/2.22.0/@ethereumjs/vm/7.0.0", true},
+		{1, "Geth/v1.14.0-stable/linux-amd64/go1.22.0", false},
+		{1, "", false},
+	}
+	for _, tt := range tests {
+		if got := IsDevChain(tt.chainID, tt.version); got != tt.want {
+			t.Errorf("IsDevChain(%d, %q) = %v, want %v", tt.chainID, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestEstimateOnce_DevChain(t *testing.T) {
+	// Empty automined blocks with a zero base fee, as Anvil produces when idle
+	client := &mockBlockReader{
+		chainIDFunc: func(ctx context.Context) (uint64, error) { return 31337, nil },
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) {
+			return &eth.Block{Number: 5, BaseFee: uint256.NewInt(0), GasLimit: 30_000_000, Timestamp: time.Now().Add(-time.Hour)}, nil
+		},
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			return &eth.Block{Number: number.Uint64(), BaseFee: uint256.NewInt(0), GasLimit: 30_000_000}, nil
+		},
+	}
+
+	est, err := EstimateOnce(context.Background(), client, WithHistorySize(5))
+	if err != nil {
+		t.Fatalf("EstimateOnce() error = %v", err)
+	}
+	for _, tier := range []PriorityEstimate{est.Urgent, est.Fast, est.Standard, est.Slow} {
+		if !tier.MaxPriorityFeePerGas.Eq(DevChainPriorityFee) || !tier.MaxFeePerGas.Eq(DevChainPriorityFee) {
+			t.Errorf("tier %.2f = %v/%v, want the dev chain tip", tier.Confidence, tier.MaxPriorityFeePerGas, tier.MaxFeePerGas)
+		}
+	}
+	if !est.ValidUntil.IsZero() {
+		t.Errorf("ValidUntil = %v, want zero on a dev chain", est.ValidUntil)
+	}
+	if est.Network.Name != "devnet" {
+		t.Errorf("Network.Name = %q, want devnet", est.Network.Name)
+	}
+
+	// Without the profile the strategy falls back to its busy-chain defaults
+	est, err = EstimateOnce(context.Background(), client, WithHistorySize(5), WithDevChain(DevChainOff))
+	if err != nil {
+		t.Fatalf("EstimateOnce() error = %v", err)
+	}
+	if !est.Standard.MaxPriorityFeePerGas.Gt(DevChainPriorityFee) {
+		t.Errorf("standard tip without profile = %v, want the strategy default", est.Standard.MaxPriorityFeePerGas)
+	}
+}
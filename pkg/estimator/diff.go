@@ -0,0 +1,100 @@
+package estimator
+
+import (
+	"math"
+
+	"github.com/holiman/uint256"
+)
+
+// percentScale is the resolution of ChangeThreshold.PriorityFeePercent:
+// percentages are compared in millionths of a percent, rounded.
+const percentScale = 1e6
+
+// ChangeThreshold defines how much an estimate must move before it is
+// considered materially different from a previous one.
+//
+// Zero values mean "any change": a zero PriorityFeePercent triggers on any
+// priority fee difference, a nil or zero BaseFeeDelta on any base fee difference.
+// IgnorePriorityFee and IgnoreBaseFee leave a dimension out entirely.
+type ChangeThreshold struct {
+	// PriorityFeePercent is the relative change (in percent, e.g. 5 = 5%)
+	// in any tier's MaxPriorityFeePerGas that counts as a change. It is
+	// rounded to a millionth of a percent; NaN counts as zero.
+	PriorityFeePercent float64
+
+	// BaseFeeDelta is the absolute change in base fee (in wei) that counts as a change.
+	BaseFeeDelta *uint256.Int
+
+	// IgnorePriorityFee and IgnoreBaseFee disregard changes in the priority
+	// fees or the base fee, e.g. when a client only set a threshold for
+	// the other one.
+	IgnorePriorityFee bool
+	IgnoreBaseFee     bool
+}
+
+// Changed reports whether next differs from prev by more than the threshold.
+// A nil prev always counts as changed.
+func Changed(prev, next *GasEstimate, th ChangeThreshold) bool {
+	if prev == nil {
+		return next != nil
+	}
+	if next == nil {
+		return false
+	}
+
+	if !th.IgnoreBaseFee && baseFeeChanged(prev.BaseFee, next.BaseFee, th.BaseFeeDelta) {
+		return true
+	}
+	if th.IgnorePriorityFee {
+		return false
+	}
+
+	return priorityChanged(prev.Urgent, next.Urgent, th.PriorityFeePercent) ||
+		priorityChanged(prev.Fast, next.Fast, th.PriorityFeePercent) ||
+		priorityChanged(prev.Standard, next.Standard, th.PriorityFeePercent) ||
+		priorityChanged(prev.Slow, next.Slow, th.PriorityFeePercent)
+}
+
+func baseFeeChanged(prev, next, delta *uint256.Int) bool {
+	if prev == nil || next == nil {
+		return prev != next
+	}
+
+	diff := absDiff(prev, next)
+	if delta == nil || delta.IsZero() {
+		return !diff.IsZero()
+	}
+	return diff.Gt(delta)
+}
+
+func priorityChanged(prev, next PriorityEstimate, pct float64) bool {
+	a, b := prev.MaxPriorityFeePerGas, next.MaxPriorityFeePerGas
+	if a == nil || b == nil {
+		return a != b
+	}
+
+	diff := absDiff(a, b)
+	scaled := math.Round(pct * percentScale)
+	if !(scaled > 0) { // also NaN
+		return !diff.IsZero()
+	}
+	if a.IsZero() {
+		return !b.IsZero()
+	}
+	// Thresholds beyond uint64 allow more than any fee can move
+	if scaled >= math.MaxUint64 {
+		return false
+	}
+
+	// diff / a > pct / 100  <=>  diff * 100 * percentScale > a * pct * percentScale
+	lhs := new(uint256.Int).Mul(diff, uint256.NewInt(100*percentScale))
+	rhs := new(uint256.Int).Mul(a, uint256.NewInt(uint64(scaled)))
+	return lhs.Gt(rhs)
+}
+
+func absDiff(a, b *uint256.Int) *uint256.Int {
+	if a.Lt(b) {
+		return new(uint256.Int).Sub(b, a)
+	}
+	return new(uint256.Int).Sub(a, b)
+}
@@ -0,0 +1,144 @@
+package estimator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestChanged(t *testing.T) {
+	u256 := func(v uint64) *uint256.Int { return uint256.NewInt(v) }
+
+	makeEstimate := func(baseFee, standard uint64) *GasEstimate {
+		tier := func(fee uint64) PriorityEstimate {
+			return PriorityEstimate{MaxPriorityFeePerGas: u256(fee), MaxFeePerGas: u256(fee + 2*baseFee)}
+		}
+		return &GasEstimate{
+			BaseFee:  u256(baseFee),
+			Urgent:   tier(300),
+			Fast:     tier(200),
+			Standard: tier(standard),
+			Slow:     tier(50),
+		}
+	}
+
+	tests := []struct {
+		name string
+		prev *GasEstimate
+		next *GasEstimate
+		th   ChangeThreshold
+		want bool
+	}{
+		{
+			name: "No previous estimate",
+			prev: nil,
+			next: makeEstimate(1000, 100),
+			want: true,
+		},
+		{
+			name: "Identical estimates",
+			prev: makeEstimate(1000, 100),
+			next: makeEstimate(1000, 100),
+			want: false,
+		},
+		{
+			name: "Any change with zero threshold",
+			prev: makeEstimate(1000, 100),
+			next: makeEstimate(1000, 101),
+			want: true,
+		},
+		{
+			name: "Priority change below percent threshold",
+			prev: makeEstimate(1000, 100),
+			next: makeEstimate(1000, 104),
+			th:   ChangeThreshold{PriorityFeePercent: 5, BaseFeeDelta: u256(10)},
+			want: false,
+		},
+		{
+			name: "Priority change above percent threshold",
+			prev: makeEstimate(1000, 100),
+			next: makeEstimate(1000, 94),
+			th:   ChangeThreshold{PriorityFeePercent: 5, BaseFeeDelta: u256(10)},
+			want: true,
+		},
+		{
+			name: "Base fee change below delta",
+			prev: makeEstimate(1000, 100),
+			next: makeEstimate(1010, 100),
+			th:   ChangeThreshold{PriorityFeePercent: 5, BaseFeeDelta: u256(10)},
+			want: false,
+		},
+		{
+			name: "Base fee change above delta",
+			prev: makeEstimate(1000, 100),
+			next: makeEstimate(1011, 100),
+			th:   ChangeThreshold{PriorityFeePercent: 5, BaseFeeDelta: u256(10)},
+			want: true,
+		},
+		{
+			name: "Base fee change with only a priority threshold",
+			prev: makeEstimate(1000, 100),
+			next: makeEstimate(2000, 100),
+			th:   ChangeThreshold{PriorityFeePercent: 5, IgnoreBaseFee: true},
+			want: false,
+		},
+		{
+			name: "Priority change below fractional percent threshold",
+			prev: makeEstimate(1000, 1_000_000),
+			next: makeEstimate(1000, 1_000_040),
+			th:   ChangeThreshold{PriorityFeePercent: 0.005, IgnoreBaseFee: true},
+			want: false,
+		},
+		{
+			name: "Priority change above fractional percent threshold",
+			prev: makeEstimate(1000, 1_000_000),
+			next: makeEstimate(1000, 1_000_060),
+			th:   ChangeThreshold{PriorityFeePercent: 0.005, IgnoreBaseFee: true},
+			want: true,
+		},
+		{
+			name: "Priority change with NaN percent threshold",
+			prev: makeEstimate(1000, 100),
+			next: makeEstimate(1000, 101),
+			th:   ChangeThreshold{PriorityFeePercent: math.NaN(), IgnoreBaseFee: true},
+			want: true,
+		},
+		{
+			name: "Priority change with infinite percent threshold",
+			prev: makeEstimate(1000, 100),
+			next: makeEstimate(1000, 1_000_000),
+			th:   ChangeThreshold{PriorityFeePercent: math.Inf(1), IgnoreBaseFee: true},
+			want: false,
+		},
+		{
+			name: "Priority change with only a priority threshold",
+			prev: makeEstimate(1000, 100),
+			next: makeEstimate(1000, 110),
+			th:   ChangeThreshold{PriorityFeePercent: 5, IgnoreBaseFee: true},
+			want: true,
+		},
+		{
+			name: "Priority change with only a base fee threshold",
+			prev: makeEstimate(1000, 100),
+			next: makeEstimate(1000, 200),
+			th:   ChangeThreshold{BaseFeeDelta: u256(10), IgnorePriorityFee: true},
+			want: false,
+		},
+		{
+			name: "Base fee change with only a base fee threshold",
+			prev: makeEstimate(1000, 100),
+			next: makeEstimate(1011, 100),
+			th:   ChangeThreshold{BaseFeeDelta: u256(10), IgnorePriorityFee: true},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Changed(tt.prev, tt.next, tt.th); got != tt.want {
+				t.Errorf("Changed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
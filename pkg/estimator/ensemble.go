@@ -0,0 +1,218 @@
+package estimator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/holiman/uint256"
+)
+
+// CombineMethod selects how EnsembleStrategy merges member estimates.
+type CombineMethod string
+
+const (
+	// CombineMedian takes the median of the members' values per tier.
+	// Robust to a single member producing an outlier; weights are ignored.
+	CombineMedian CombineMethod = "median"
+
+	// CombineWeightedMean takes the weight-averaged value per tier.
+	CombineWeightedMean CombineMethod = "weighted_mean"
+)
+
+// ParseCombineMethod validates a combine method name.
+func ParseCombineMethod(s string) (CombineMethod, error) {
+	switch m := CombineMethod(s); m {
+	case CombineMedian, CombineWeightedMean:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown combine method %q", s)
+	}
+}
+
+// EnsembleMember is one strategy of an ensemble and its relative weight.
+type EnsembleMember struct {
+	Strategy Strategy
+	Weight   float64
+}
+
+// ComponentEstimate is one ensemble member's output for a calculation.
+// Estimate is nil and Err set when the member failed.
+type ComponentEstimate struct {
+	Strategy string
+	Weight   float64
+	Estimate *GasEstimate
+	Err      string
+}
+
+// EnsembleStrategy runs several strategies on the same input and combines
+// their tiers, so one strategy misbehaving under unusual network conditions
+// is outvoted by the others.
+//
+// Members that fail are left out of the combination; the calculation fails
+// only if every member does. Each member's output is attached to the
+// combined estimate as Components for debugging.
+type EnsembleStrategy struct {
+	Members []EnsembleMember
+
+	// Combine selects the combination method.
+	// Default: CombineMedian
+	Combine CombineMethod
+}
+
+// DefaultEnsembleStrategy combines the hybrid, fee history and mempool
+// strategies with their default settings.
+func DefaultEnsembleStrategy() *EnsembleStrategy {
+	return &EnsembleStrategy{
+		Members: []EnsembleMember{
+			{Strategy: DefaultStrategy(), Weight: 0.5},
+			{Strategy: DefaultFeeHistoryStrategy(), Weight: 0.25},
+			{Strategy: DefaultMempoolStrategy(), Weight: 0.25},
+		},
+		Combine: CombineMedian,
+	}
+}
+
+// Name returns the strategy name.
+func (s *EnsembleStrategy) Name() string {
+	return "ensemble"
+}
+
+// Calculate runs every member and combines the successful estimates.
+func (s *EnsembleStrategy) Calculate(ctx context.Context, input *CalculatorInput) (*GasEstimate, error) {
+	if len(s.Members) == 0 {
+		return nil, errors.New("ensemble has no members")
+	}
+
+	components := make([]ComponentEstimate, len(s.Members))
+	var ok []ComponentEstimate
+	var errs []error
+	for i, m := range s.Members {
+		components[i] = ComponentEstimate{Strategy: m.Strategy.Name(), Weight: m.Weight}
+
+		est, err := m.Strategy.Calculate(ctx, input)
+		if err != nil {
+			components[i].Err = err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", m.Strategy.Name(), err))
+			continue
+		}
+		components[i].Estimate = est
+		if m.Weight > 0 {
+			ok = append(ok, components[i])
+		}
+	}
+	if len(ok) == 0 {
+		if len(errs) == 0 {
+			return nil, errors.New("ensemble has no members with positive weight")
+		}
+		return nil, errors.Join(errs...)
+	}
+
+	combine := s.combineMedian
+	if s.Combine == CombineWeightedMean {
+		combine = s.combineWeightedMean
+	}
+	fee := func(pick func(*GasEstimate) *uint256.Int) *uint256.Int {
+		return combine(ok, pick)
+	}
+	tier := func(pick func(*GasEstimate) PriorityEstimate) PriorityEstimate {
+		priority := fee(func(e *GasEstimate) *uint256.Int { return pick(e).MaxPriorityFeePerGas })
+		maxFee := fee(func(e *GasEstimate) *uint256.Int { return pick(e).MaxFeePerGas })
+		var sources []FeeSource
+		for _, c := range ok {
+			sources = withSources(sources, pick(c.Estimate).Sources...)
+		}
+		return PriorityEstimate{
+			MaxPriorityFeePerGas: priority,
+			MaxFeePerGas:         maxFee,
+			Confidence:           pick(ok[0].Estimate).Confidence,
+			Sources:              sources,
+		}
+	}
+
+	first := ok[0].Estimate
+	combined := &GasEstimate{
+		ChainID:       first.ChainID,
+		BlockNumber:   first.BlockNumber,
+		Timestamp:     first.Timestamp,
+		BaseFee:       fee(func(e *GasEstimate) *uint256.Int { return e.BaseFee }),
+		BlockGasLimit: first.BlockGasLimit,
+		Urgent:        tier(func(e *GasEstimate) PriorityEstimate { return e.Urgent }),
+		Fast:          tier(func(e *GasEstimate) PriorityEstimate { return e.Fast }),
+		Standard:      tier(func(e *GasEstimate) PriorityEstimate { return e.Standard }),
+		Slow:          tier(func(e *GasEstimate) PriorityEstimate { return e.Slow }),
+		Components:    components,
+	}
+
+	// Combining per field can leave maxFee below baseFee + tip; restore it
+	for _, p := range []*PriorityEstimate{&combined.Urgent, &combined.Fast, &combined.Standard, &combined.Slow} {
+		floor := new(uint256.Int).Add(combined.BaseFee, p.MaxPriorityFeePerGas)
+		if p.MaxFeePerGas.Lt(floor) {
+			p.MaxFeePerGas = floor
+		}
+	}
+	if !combined.BaseFee.IsZero() {
+		// Effective buffer of the combined Standard tier
+		buffer := new(uint256.Int).Sub(combined.Standard.MaxFeePerGas, combined.Standard.MaxPriorityFeePerGas)
+		combined.BaseFeeMultiplier = math.Round(buffer.Float64()/combined.BaseFee.Float64()*10000) / 10000
+	}
+	for _, c := range ok {
+		if combined.Distribution == nil {
+			combined.Distribution = c.Estimate.Distribution
+		}
+		if combined.Demand == nil {
+			combined.Demand = c.Estimate.Demand
+		}
+		// Members read the same input; report the most any member used
+		combined.Samples = SampleSizes{
+			Blocks:       max(combined.Samples.Blocks, c.Estimate.Samples.Blocks),
+			Historical:   max(combined.Samples.Historical, c.Estimate.Samples.Historical),
+			Mempool:      max(combined.Samples.Mempool, c.Estimate.Samples.Mempool),
+			PendingBlock: max(combined.Samples.PendingBlock, c.Estimate.Samples.PendingBlock),
+		}
+		// Members filter the same mempool sample; report the strictest
+		combined.MempoolOutliers = max(combined.MempoolOutliers, c.Estimate.MempoolOutliers)
+		if c.Estimate.MempoolSampled > combined.MempoolSampled {
+			combined.MempoolSampled = c.Estimate.MempoolSampled
+			combined.MempoolIncludable = c.Estimate.MempoolIncludable
+		}
+	}
+
+	return combined, nil
+}
+
+func (s *EnsembleStrategy) combineMedian(ests []ComponentEstimate, pick func(*GasEstimate) *uint256.Int) *uint256.Int {
+	values := make([]*uint256.Int, 0, len(ests))
+	for _, c := range ests {
+		if v := pick(c.Estimate); v != nil {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return new(uint256.Int)
+	}
+	return medianFee(values)
+}
+
+func (s *EnsembleStrategy) combineWeightedMean(ests []ComponentEstimate, pick func(*GasEstimate) *uint256.Int) *uint256.Int {
+	// Weights in basis points keep the arithmetic in uint256
+	sum := new(uint256.Int)
+	var total uint64
+	for _, c := range ests {
+		v := pick(c.Estimate)
+		if v == nil {
+			continue
+		}
+		w := uint64(math.Round(c.Weight * 10000))
+		sum.Add(sum, new(uint256.Int).Mul(v, uint256.NewInt(w)))
+		total += w
+	}
+	if total == 0 {
+		return sum
+	}
+	return sum.Div(sum, uint256.NewInt(total))
+}
+
+// Verify interface compliance at compile time.
+var _ Strategy = (*EnsembleStrategy)(nil)
@@ -0,0 +1,131 @@
+package estimator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+// staticStrategy returns the same tip for every tier, or err.
+type staticStrategy struct {
+	name string
+	tip  uint64
+	err  error
+}
+
+func (s *staticStrategy) Name() string { return s.name }
+
+func (s *staticStrategy) Calculate(ctx context.Context, input *CalculatorInput) (*GasEstimate, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	level := func() PriorityEstimate {
+		return PriorityEstimate{
+			MaxPriorityFeePerGas: uint256.NewInt(s.tip),
+			MaxFeePerGas:         uint256.NewInt(200 + s.tip),
+		}
+	}
+	return &GasEstimate{
+		BlockNumber: input.CurrentBlock.Number,
+		BaseFee:     uint256.NewInt(100),
+		Urgent:      level(),
+		Fast:        level(),
+		Standard:    level(),
+		Slow:        level(),
+	}, nil
+}
+
+func TestEnsembleStrategy_Combine(t *testing.T) {
+	input := &CalculatorInput{CurrentBlock: &BlockData{Number: 7}}
+	members := func(weights ...float64) []EnsembleMember {
+		tips := []uint64{10, 20, 90}
+		out := make([]EnsembleMember, len(weights))
+		for i, w := range weights {
+			out[i] = EnsembleMember{Strategy: &staticStrategy{name: "s", tip: tips[i]}, Weight: w}
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		strategy *EnsembleStrategy
+		wantTip  uint64
+	}{
+		{"median of three", &EnsembleStrategy{Members: members(1, 1, 1)}, 20},
+		{"median of two averages", &EnsembleStrategy{Members: members(1, 1)}, 15},
+		{"zero weight excluded", &EnsembleStrategy{Members: members(1, 1, 0)}, 15},
+		{"weighted mean", &EnsembleStrategy{Members: members(0.5, 0.25, 0.25), Combine: CombineWeightedMean}, 32},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			est, err := tt.strategy.Calculate(context.Background(), input)
+			if err != nil {
+				t.Fatalf("Calculate() error = %v", err)
+			}
+			if got := est.Standard.MaxPriorityFeePerGas.Uint64(); got != tt.wantTip {
+				t.Errorf("Standard tip = %d, want %d", got, tt.wantTip)
+			}
+			if est.BlockNumber != 7 || len(est.Components) != len(tt.strategy.Members) {
+				t.Errorf("BlockNumber = %d, Components = %d", est.BlockNumber, len(est.Components))
+			}
+		})
+	}
+}
+
+func TestEnsembleStrategy_Failures(t *testing.T) {
+	input := &CalculatorInput{CurrentBlock: &BlockData{Number: 7}}
+
+	s := &EnsembleStrategy{Members: []EnsembleMember{
+		{Strategy: &staticStrategy{name: "ok", tip: 10}, Weight: 1},
+		{Strategy: &staticStrategy{name: "empty", err: ErrNoFeeData}, Weight: 1},
+	}}
+	est, err := s.Calculate(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+	if got := est.Urgent.MaxPriorityFeePerGas.Uint64(); got != 10 {
+		t.Errorf("Urgent tip = %d, want 10 from the surviving member", got)
+	}
+	if c := est.Components[1]; c.Estimate != nil || c.Err == "" {
+		t.Errorf("failed component = %+v, want error recorded", c)
+	}
+
+	s.Members[0].Strategy = &staticStrategy{name: "broken", err: errors.New("boom")}
+	if _, err := s.Calculate(context.Background(), input); !errors.Is(err, ErrNoFeeData) {
+		t.Errorf("Calculate() error = %v, want joined member errors", err)
+	}
+}
+
+func TestFeeHistoryStrategy_MedianAcrossBlocks(t *testing.T) {
+	block := func(n uint64, fees ...uint64) *BlockData {
+		b := &BlockData{Number: n, BaseFee: uint256.NewInt(1e9), GasUsed: 15e6, GasLimit: 30e6}
+		for _, f := range fees {
+			b.PriorityFees = append(b.PriorityFees, uint256.NewInt(f*1e9))
+		}
+		return b
+	}
+	blocks := []*BlockData{block(3, 2, 4), block(2, 6), block(1, 3, 3, 3)}
+	input := &CalculatorInput{CurrentBlock: blocks[0], RecentBlocks: blocks}
+
+	est, err := DefaultFeeHistoryStrategy().Calculate(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+	// Per-block medians are 2, 6 and 3 gwei
+	if got := est.Standard.MaxPriorityFeePerGas.Uint64(); got != 3e9 {
+		t.Errorf("Standard tip = %d, want 3 gwei", got)
+	}
+	if got, want := est.Standard.MaxFeePerGas.Uint64(), uint64(2e9+3e9); got != want {
+		t.Errorf("Standard maxFee = %d, want %d", got, want)
+	}
+
+	empty := &CalculatorInput{CurrentBlock: block(1), RecentBlocks: []*BlockData{block(1)}}
+	if _, err := DefaultFeeHistoryStrategy().Calculate(context.Background(), empty); !errors.Is(err, ErrNoFeeData) {
+		t.Errorf("empty history error = %v, want ErrNoFeeData", err)
+	}
+	if _, err := DefaultMempoolStrategy().Calculate(context.Background(), empty); !errors.Is(err, ErrNoFeeData) {
+		t.Errorf("empty mempool error = %v, want ErrNoFeeData", err)
+	}
+}
@@ -4,13 +4,35 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/branched-services/go-gas/pkg/eth"
 	"github.com/holiman/uint256"
 )
 
+// Runner drives an estimation pipeline until ctx is canceled.
+// Implemented by Estimator.
+//
+// Applications with their own event loop (e.g. an existing block
+// processing service) can skip Run and call the Estimator's stages
+// themselves:
+//
+//	if err := est.Bootstrap(ctx); err != nil { ... }
+//	// for each new head, with full transactions:
+//	est.IngestBlock(ctx, block)
+//	// as pending transactions arrive:
+//	est.IngestPendingTxs(txs...)
+//	// periodically, between blocks:
+//	est.Recalculate(ctx)
+//
+// The subscriber passed to New is then never used and may be nil.
+type Runner interface {
+	Run(ctx context.Context) error
+}
+
 // Estimator orchestrates gas estimation by:
 // 1. Subscribing to new blocks
 // 2. Sampling the mempool
@@ -24,20 +46,108 @@ type Estimator struct {
 	provider   *Provider
 	strategy   Strategy
 	logger     *slog.Logger
+	clock      Clock
 
 	// Configuration
 	historySize    int
+	blockSamples   int
 	mempoolSamples int
+	mempoolBytes   int
+	timeouts       FetchTimeouts
+	budget         *nodeBudget
 	recalcInterval time.Duration
+	recalcOnBlock  bool
+	recalcTxs      int
+	fullPendingTxs bool
+	samplingPolicy SamplingPolicy
+	samplingWindow time.Duration
+	network        Network
+	nonceFilter    bool
+	pendingBlock   bool
+	receipts       bool
+	autoDetect     bool
+	historySource  HistorySource
+	degradedPoll   time.Duration
+	devChainMode   DevChainMode
+	haltThreshold  time.Duration
+
+	// explain attaches an Explanation to every estimate and logs it
+	explain bool
+
+	// Anomaly detection; nil when disabled
+	anomalies *anomalyDetector
+	onAnomaly func(Anomaly)
+
+	// Cross-checking new heads against witness nodes; witnesses is empty
+	// when disabled and divergences counts disagreements
+	witnesses    []Witness
+	onDivergence func(Divergence)
+	divergences  atomic.Uint64
+
+	// Sanity guard; sanity is nil when disabled and rejections counts the
+	// estimates it refused to publish
+	sanity      *SanityLimits
+	onRejection func(Rejection)
+	rejections  atomic.Uint64
+
+	// Resync guard; disabled when both resyncPoll and maxRewind are zero
+	resyncPoll time.Duration
+	maxRewind  uint64
+	resync     resyncState
+
+	// inputLog records the input of published estimates; nil when disabled
+	inputLog *InputLog
+
+	// published is the latest published estimate with its input, for
+	// evaluating other strategies against it
+	published atomic.Pointer[publishedInput]
+
+	// onBlockTiming receives block-to-estimate latencies; nil when unset
+	onBlockTiming func(BlockTiming)
+
+	// events carries pipeline events to the stages that follow them and to
+	// external subscribers (see Events)
+	events *Bus
 
 	// Internal state
-	history   *History
-	localPool *LocalTxPool
-	chainID   uint64
+	state   *chainState
+	nonces  *nonceTracker
+	chainID uint64
+	plan    DataPlan
+	debug   debugState
 
-	// Lifecycle
-	mu      sync.Mutex
-	running bool
+	// Degraded mode: set while polling for blocks after losing the new
+	// heads subscription; polledHead is the newest block polled, only
+	// used by Run
+	degraded   atomic.Bool
+	polledHead uint64
+
+	// head is the newest processed block, for ChainStatus
+	head chainHead
+
+	// feesEvicted counts priority fees dropped by thinning history blocks
+	feesEvicted atomic.Uint64
+
+	// txTypes counts ingested transactions by type, unknown types last
+	txTypes []atomic.Uint64
+
+	// paused is set by Pause; Run then ignores blocks, pending transactions
+	// and recalculation ticks
+	paused atomic.Bool
+
+	// Pending transaction trigger: transactions seen since the last
+	// recalculation, and Run's channel of recalculation requests
+	txsSinceRecalc atomic.Int64
+	recalcReqs     chan struct{}
+
+	// blocks queues new head notifications for Run's block worker
+	blocks *blockQueue
+
+	// Lifecycle; mu also guards chainID, network, plan and resubReqs once
+	// Run has started
+	mu        sync.Mutex
+	running   bool
+	resubReqs chan chan error
 }
 
 // Option configures an Estimator.
@@ -50,6 +160,17 @@ func WithHistorySize(size int) Option {
 	}
 }
 
+// WithBlockFeeSamples caps the priority fees kept per history block at n,
+// thinning larger blocks to evenly spaced percentiles of their fees. This
+// bounds history memory on busy chains at the cost of some precision, and
+// each block then counts at most n times in historical percentiles.
+// Zero, the default, keeps every fee.
+func WithBlockFeeSamples(n int) Option {
+	return func(e *Estimator) {
+		e.blockSamples = n
+	}
+}
+
 // WithMempoolSamples sets the maximum number of pending transactions to sample.
 func WithMempoolSamples(samples int) Option {
 	return func(e *Estimator) {
@@ -57,13 +178,150 @@ func WithMempoolSamples(samples int) Option {
 	}
 }
 
-// WithRecalcInterval sets how often to recalculate estimates.
+// WithRecalcInterval sets how often to recalculate estimates; 0 disables
+// the ticker (see WithRecalcTriggers).
 func WithRecalcInterval(d time.Duration) Option {
 	return func(e *Estimator) {
 		e.recalcInterval = d
 	}
 }
 
+// WithFullPendingTxs controls whether full pending transaction bodies are
+// requested when the subscriber supports it (eth.FullPendingTxSubscriber).
+// Enabled by default; when the node does not support it the estimator falls
+// back to hash subscriptions plus batch fetches.
+func WithFullPendingTxs(enabled bool) Option {
+	return func(e *Estimator) {
+		e.fullPendingTxs = enabled
+	}
+}
+
+// WithSamplingPolicy sets how pending transactions are sampled.
+// window is the observation window for the reservoir and stratified policies.
+// Default: SampleMostRecent.
+func WithSamplingPolicy(policy SamplingPolicy, window time.Duration) Option {
+	return func(e *Estimator) {
+		e.samplingPolicy = policy
+		e.samplingWindow = window
+	}
+}
+
+// WithNonceFiltering excludes pending transactions that can't execute
+// because their sender has a nonce gap. Confirmed nonces of sampled senders
+// are fetched once per block with a batched eth_getTransactionCount, which
+// requires the client to implement eth.NonceReader.
+// Disabled by default.
+func WithNonceFiltering(enabled bool) Option {
+	return func(e *Estimator) {
+		e.nonceFilter = enabled
+	}
+}
+
+// WithPendingBlock fetches the node's pending block once per block with
+// eth_getBlockByNumber("pending") and passes it to the strategy, whose
+// transactions the node has already selected for inclusion. Most useful
+// against a local node; requires the client to implement
+// eth.PendingBlockReader.
+// Disabled by default.
+func WithPendingBlock(enabled bool) Option {
+	return func(e *Estimator) {
+		e.pendingBlock = enabled
+	}
+}
+
+// WithReceipts takes each mined block's priority fees and gas from its
+// transaction receipts (eth_getBlockReceipts, or batched
+// eth_getTransactionReceipt where unsupported) instead of transaction
+// fields: effectiveGasPrice minus the base fee is the tip actually paid,
+// whereas MaxPriorityFeePerGas overstates it for transactions capped by
+// MaxFeePerGas. Costs one or more requests per block; blocks whose
+// receipts can't be fetched fall back to transaction fields. Requires the
+// client to implement eth.ReceiptReader.
+// Disabled by default.
+func WithReceipts(enabled bool) Option {
+	return func(e *Estimator) {
+		e.receipts = enabled
+	}
+}
+
+// WithExplain enables explain mode: every recalculation records how each
+// tier was derived (percentiles per source, blend weights, clamping and
+// smoothing), logs it at info level and keeps it on the estimate as
+// Explanation, where the debug API shows it. Meant for tuning strategies;
+// strategies other than HybridStrategy ignore it.
+// Disabled by default.
+func WithExplain(enabled bool) Option {
+	return func(e *Estimator) {
+		e.explain = enabled
+	}
+}
+
+// WithAnomalyDetection checks every new block for base fee spikes, mempool
+// surges and priority fee spikes (see AnomalyThresholds). Each anomaly is
+// logged, counted in DebugSnapshot.Anomalies and passed to handler, if not
+// nil. handler runs on the block processing path and must not block.
+// Disabled by default.
+func WithAnomalyDetection(th AnomalyThresholds, handler func(Anomaly)) Option {
+	return func(e *Estimator) {
+		e.anomalies = newAnomalyDetector(th)
+		e.onAnomaly = handler
+	}
+}
+
+// WithAutoDetect probes the node at startup and picks mempool and history
+// sources it supports (see DataPlan), instead of assuming pending
+// transaction subscriptions and the bootstrap history source work. Probing needs the
+// client to implement eth.CapabilityProber and the subscriber
+// eth.SubscriptionProber; missing probes assume the default sources.
+// Disabled by default.
+func WithAutoDetect(enabled bool) Option {
+	return func(e *Estimator) {
+		e.autoDetect = enabled
+	}
+}
+
+// WithHistorySource sets how history is bootstrapped. HistoryFeeHistory,
+// the default, seeds it from one eth_feeHistory call when the client
+// implements eth.FeeHistoryReader and falls back to fetching blocks if the
+// call fails; HistoryBlocks always fetches every block in full.
+func WithHistorySource(source HistorySource) Option {
+	return func(e *Estimator) {
+		e.historySource = source
+	}
+}
+
+// WithDegradedMode keeps the estimator running when the new heads
+// subscription closes: the latest block is polled every pollInterval while
+// the subscription is retried in the background, and estimates are flagged
+// Degraded until it is restored. Zero disables it, and Run returns an error
+// when the subscription closes.
+// Disabled by default.
+func WithDegradedMode(pollInterval time.Duration) Option {
+	return func(e *Estimator) {
+		e.degradedPoll = pollInterval
+	}
+}
+
+// WithDevChain sets when the dev-chain profile for local chains such as
+// Anvil and Hardhat applies. Under the profile, estimates quote
+// DevChainPriorityFee when blocks and the mempool carry no fees, carry no
+// ValidUntil since blocks are mined on demand, and a node without pending
+// transaction subscriptions runs without mempool sampling instead of
+// failing. Defaults to DevChainAuto.
+func WithDevChain(mode DevChainMode) Option {
+	return func(e *Estimator) {
+		e.devChainMode = mode
+	}
+}
+
+// WithNetwork overrides the network metadata attached to estimates.
+// Zero fields are filled from built-in metadata for the connected chain.
+func WithNetwork(n Network) Option {
+	return func(e *Estimator) {
+		e.network = n
+	}
+}
+
 // WithStrategy sets the estimation strategy.
 func WithStrategy(s Strategy) Option {
 	return func(e *Estimator) {
@@ -71,6 +329,14 @@ func WithStrategy(s Strategy) Option {
 	}
 }
 
+// WithClock sets the time source. Defaults to SystemClock.
+// Intended for deterministic tests.
+func WithClock(c Clock) Option {
+	return func(e *Estimator) {
+		e.clock = c
+	}
+}
+
 // WithLogger sets the logger.
 func WithLogger(l *slog.Logger) Option {
 	return func(e *Estimator) {
@@ -93,24 +359,49 @@ func New(
 		provider:       provider,
 		strategy:       DefaultStrategy(),
 		logger:         slog.Default(),
+		clock:          SystemClock(),
 		historySize:    20,
 		mempoolSamples: 500,
+		timeouts:       defaultFetchTimeouts,
+		budget:         defaultNodeBudget(),
+		txTypes:        make([]atomic.Uint64, len(eth.TxTypeNames())),
 		recalcInterval: 200 * time.Millisecond,
+		recalcOnBlock:  true,
+		recalcReqs:     make(chan struct{}, 1),
+		fullPendingTxs: true,
+		samplingPolicy: SampleMostRecent,
+		samplingWindow: 30 * time.Second,
+		historySource:  HistoryFeeHistory,
+		devChainMode:   DevChainAuto,
 	}
 
 	for _, opt := range opts {
 		opt(e)
 	}
 
-	e.history = NewHistory(e.historySize)
-	e.localPool = NewLocalTxPool(e.mempoolSamples * 2)
+	e.budget.start(e.clock)
+	e.state = newChainState(
+		NewHistory(e.historySize),
+		e.newSampler(),
+	)
+	e.nonces = newNonceTracker()
+	e.blocks = newBlockQueue()
 	e.logger = e.logger.With("component", "estimator")
+	e.events = &Bus{}
+	e.subscribeStages()
 
 	return e
 }
 
-// Run starts the estimator. Blocks until context is canceled.
+// Run starts the estimator: it bootstraps, subscribes to new heads and the
+// mempool and recalculates on its triggers (see WithRecalcTriggers),
+// running each stage (Bootstrap, IngestBlock, IngestPendingTxs,
+// Recalculate) from its own goroutines. Blocks until context is canceled.
 func (e *Estimator) Run(ctx context.Context) error {
+	if t := e.triggers(); !t.Blocks && t.PendingTxs <= 0 && t.Interval <= 0 {
+		return errNoTriggers
+	}
+
 	e.mu.Lock()
 	if e.running {
 		e.mu.Unlock()
@@ -125,43 +416,104 @@ func (e *Estimator) Run(ctx context.Context) error {
 		e.mu.Unlock()
 	}()
 
-	// Get chain ID
-	chainID, err := e.client.ChainID(ctx)
-	if err != nil {
-		return fmt.Errorf("getting chain ID: %w", err)
+	if err := e.Bootstrap(ctx); err != nil {
+		return err
 	}
-	e.chainID = chainID
-	e.logger.Info("connected to chain", "chain_id", chainID)
+	plan := e.dataPlan()
 
-	// Bootstrap with recent blocks
-	if err := e.bootstrap(ctx); err != nil {
-		return fmt.Errorf("bootstrapping: %w", err)
+	// Subscriptions live in subCtx so Resubscribe can end them and start
+	// over in a new one
+	newSubCtx := func() (context.Context, context.CancelFunc) {
+		return context.WithCancel(ctx)
 	}
+	subCtx, cancelSubs := newSubCtx()
+	defer func() { cancelSubs() }()
 
 	// Subscribe to new blocks
-	blockCh, err := e.subscriber.SubscribeNewHeads(ctx)
+	blockCh, err := e.subscriber.SubscribeNewHeads(subCtx)
 	if err != nil {
 		return fmt.Errorf("subscribing to new heads: %w", err)
 	}
+	e.debug.setSubscription(subNewHeads, "active")
 
-	// Subscribe to pending transactions
-	txHashCh, err := e.subscriber.SubscribeNewPendingTransactions(ctx)
-	if err != nil {
-		return fmt.Errorf("subscribing to pending txs: %w", err)
+	// New heads are fetched and processed in order by a single worker,
+	// which stops before Run returns, also when a subscription fails
+	workerCtx, stopWorker := context.WithCancel(ctx)
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		e.processBlocks(workerCtx)
+	}()
+	defer func() {
+		stopWorker()
+		<-workerDone
+	}()
+
+	// Sample the mempool from the planned source
+	switch plan.Mempool {
+	case MempoolSubscription:
+		if err := e.subscribePending(subCtx); err != nil {
+			if !plan.DevChain {
+				return err
+			}
+			e.logger.Warn("dev chain has no pending transaction subscription, mempool sampling disabled", "error", err)
+			e.debug.setSubscription(subPendingTxs, "disabled")
+		}
+	case MempoolTxPool:
+		e.debug.setSubscription(subPendingTxs, "polling txpool")
+		e.pollTxPool(ctx)
+	default:
+		e.debug.setSubscription(subPendingTxs, "disabled")
 	}
 
-	// Periodic recalculation ticker
-	ticker := time.NewTicker(e.recalcInterval)
-	defer ticker.Stop()
+	// Node sync status polling; syncC is nil when disabled
+	var syncC <-chan time.Time
+	syncReader, _ := e.client.(eth.SyncStatusReader)
+	if e.resyncPoll > 0 && syncReader != nil {
+		ticker := e.clock.NewTicker(e.resyncPoll)
+		defer ticker.Stop()
+		syncC = ticker.C()
+	}
 
-	// Start pending tx processor
-	go e.processPendingTxs(ctx, txHashCh)
+	// Periodic recalculation ticker; tickC is nil when disabled
+	var tickC <-chan time.Time
+	if e.recalcInterval > 0 {
+		ticker := e.clock.NewTicker(e.recalcInterval)
+		defer ticker.Stop()
+		tickC = ticker.C()
+	}
+
+	// Block polling while degraded; pollC is nil otherwise
+	var (
+		poll    Ticker
+		pollC   <-chan time.Time
+		resubCh = make(chan (<-chan *eth.Block))
+	)
+	defer func() {
+		if poll != nil {
+			poll.Stop()
+		}
+	}()
+
+	// Resubscribe requests
+	reqs := make(chan chan error)
+	e.mu.Lock()
+	e.resubReqs = reqs
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		e.resubReqs = nil
+		e.mu.Unlock()
+	}()
 
 	e.logger.Info("estimator running",
 		"strategy", e.strategy.Name(),
 		"history_size", e.historySize,
 		"mempool_samples", e.mempoolSamples,
+		"sampling_policy", e.samplingPolicy,
 		"recalc_interval", e.recalcInterval,
+		"recalc_on_block", e.recalcOnBlock,
+		"recalc_pending_txs", e.recalcTxs,
 	)
 
 	for {
@@ -172,54 +524,273 @@ func (e *Estimator) Run(ctx context.Context) error {
 
 		case block, ok := <-blockCh:
 			if !ok {
-				return fmt.Errorf("block subscription closed")
+				if e.degradedPoll <= 0 {
+					e.debug.setSubscription(subNewHeads, "closed")
+					return fmt.Errorf("block subscription closed")
+				}
+				blockCh = nil
+				poll = e.enterDegraded(subCtx, resubCh)
+				pollC = poll.C()
+				continue
+			}
+			if e.paused.Load() {
+				continue
+			}
+			// Fetched and processed in order by the block worker
+			e.queueBlock(block, false)
+
+		case <-pollC:
+			if !e.paused.Load() {
+				e.pollLatestBlock(ctx)
+			}
+
+		case ch := <-resubCh:
+			if poll != nil {
+				poll.Stop()
+			}
+			poll, pollC = nil, nil
+			blockCh = ch
+			e.leaveDegraded(subCtx)
+
+		case done := <-reqs:
+			// End the current subscriptions and wait for the new heads one
+			// to be released, so the node gets a fresh subscription rather
+			// than sharing the old one
+			cancelSubs()
+			e.awaitClosed(blockCh)
+			subCtx, cancelSubs = newSubCtx()
+
+			ch, err := e.subscriber.SubscribeNewHeads(subCtx)
+			if err != nil {
+				blockCh = nil
+				if poll == nil && e.degradedPoll > 0 {
+					poll = e.enterDegraded(subCtx, resubCh)
+					pollC = poll.C()
+				} else {
+					e.debug.setSubscription(subNewHeads, "resubscribing")
+					go e.resubscribe(subCtx, resubCh, resubscribeMinBackoff)
+				}
+				done <- fmt.Errorf("subscribing to new heads: %w", err)
+				continue
+			}
+			if poll != nil {
+				poll.Stop()
+			}
+			poll, pollC = nil, nil
+			blockCh = ch
+			e.leaveDegraded(subCtx)
+			done <- nil
+
+		case <-syncC:
+			go e.pollSyncing(ctx, syncReader)
+
+		case <-tickC:
+			if !e.paused.Load() {
+				e.Recalculate(ctx)
+			}
+
+		case <-e.recalcReqs:
+			if !e.paused.Load() {
+				e.Recalculate(ctx)
 			}
-			// Handle block in background to avoid blocking main loop
-			go e.handleNewBlock(ctx, block)
+		}
+	}
+}
+
+// Bootstrap is the first pipeline stage: it identifies the chain, picks
+// data sources (probing the node if WithAutoDetect is set), loads recent
+// blocks into the history and computes a first estimate. Run calls it
+// before subscribing; callers driving the stages themselves must call it
+// once before the others.
+func (e *Estimator) Bootstrap(ctx context.Context) error {
+	chainID, err := e.client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("getting chain ID: %w", err)
+	}
+	e.setChainID(chainID)
+	e.logger.Info("connected to chain", "chain_id", chainID, "network", e.network.Name)
+
+	plan := e.defaultPlan()
+	if e.autoDetect {
+		plan = e.detectPlan(ctx)
+	}
+	plan.DevChain = e.devChain(chainID, plan.Capabilities.ClientVersion)
+	e.setPlan(plan)
+	e.logger.Info("data source plan",
+		"mempool", plan.Mempool,
+		"history", plan.History,
+		"client_version", plan.Capabilities.ClientVersion,
+		"txpool", plan.Capabilities.TxPool,
+		"fee_history", plan.Capabilities.FeeHistory,
+		"erigon", plan.Capabilities.Erigon,
+		"pending_subscription", plan.Capabilities.PendingSubscription,
+		"dev_chain", plan.DevChain,
+	)
+
+	// A node that is syncing at startup holds back the first estimate
+	if reader, ok := e.client.(eth.SyncStatusReader); ok && e.resyncPoll > 0 {
+		e.pollSyncing(ctx, reader)
+	}
+
+	if err := e.loadHistory(ctx); err != nil {
+		return fmt.Errorf("bootstrapping: %w", err)
+	}
+
+	// Trigger initial calculation
+	e.Recalculate(ctx)
+
+	return nil
+}
 
-		case <-ticker.C:
-			e.recalculate(ctx)
+// IngestBlock is the block ingestion stage: it adds a new head block, which
+// must include full transactions (eth.BlockReader.BlockByNumber), to the
+// history and recalculates, unless block triggers are off (see
+// WithRecalcTriggers). Blocks are appended in the order given, so each new
+// head should be passed once.
+func (e *Estimator) IngestBlock(ctx context.Context, block *eth.Block) {
+	e.processBlock(ctx, block, e.clock.Now())
+}
+
+// IngestPendingTxs is the mempool ingestion stage: it adds pending
+// transactions to the sample used by the next recalculation.
+func (e *Estimator) IngestPendingTxs(txs ...*eth.Transaction) {
+	for _, tx := range txs {
+		if tx != nil {
+			e.countTxType(tx.Type)
+			e.state.addTx(tx)
+			e.events.MempoolSampled.publish(MempoolSampled{Tx: tx})
 		}
 	}
 }
 
-// bootstrap loads recent blocks to warm up the history.
-func (e *Estimator) bootstrap(ctx context.Context) error {
+// Recalculate is the recalculation stage: it computes an estimate from the
+// current history and mempool sample and publishes it to the provider. Run
+// calls it on every block and every recalculation interval.
+//
+// Failures are logged and recorded for the debug snapshot as well as
+// returned.
+func (e *Estimator) Recalculate(ctx context.Context) error {
+	// Keep the previous estimate rather than publish one from a chain the
+	// node is still rebuilding
+	if e.resync.active.Load() {
+		return ErrResyncing
+	}
+
+	start := e.clock.Now()
+	e.txsSinceRecalc.Store(0)
+	generation := e.provider.NextGeneration()
+
+	// Build calculator input
+	input, err := e.buildInput(ctx)
+	if err != nil {
+		e.debug.recordRecalc(start, e.clock.Now().Sub(start), err)
+		e.logger.Error("failed to build calculator input", "error", err)
+		return fmt.Errorf("building calculator input: %w", err)
+	}
+
+	// Calculate new estimate
+	estimate, err := e.calculate(ctx, input)
+	e.debug.recordRecalc(start, e.clock.Now().Sub(start), err)
+	if err != nil {
+		e.logger.Error("calculation failed", "error", err)
+		return fmt.Errorf("calculating estimate: %w", err)
+	}
+
+	// Keep the previous estimate rather than publish an implausible one
+	if e.sanity != nil {
+		if err := e.rejectImplausible(input, estimate); err != nil {
+			return err
+		}
+	}
+
+	// Update provider, unless a calculation started later already did
+	e.annotate(estimate, input)
+	estimate.Generation = generation
+	if !e.provider.Update(estimate) {
+		e.logger.Debug("discarded stale estimate", "block", estimate.BlockNumber, "generation", generation)
+		return nil
+	}
+	e.debug.recordOutliers(estimate.MempoolOutliers)
+	e.published.Store(&publishedInput{input: input, estimate: estimate})
+	if e.inputLog != nil {
+		if err := e.inputLog.record(input, estimate); err != nil {
+			e.logger.Warn("failed to record estimate input", "block", estimate.BlockNumber, "error", err)
+		}
+	}
+	e.events.EstimateComputed.publish(EstimateComputed{Estimate: estimate, Started: start, Published: e.clock.Now()})
+
+	e.logger.Debug("estimate updated",
+		"block", estimate.BlockNumber,
+		"base_fee_gwei", weiToGwei(estimate.BaseFee),
+		"urgent_priority_gwei", weiToGwei(estimate.Urgent.MaxPriorityFeePerGas),
+		"standard_priority_gwei", weiToGwei(estimate.Standard.MaxPriorityFeePerGas),
+		"mempool_sampled", estimate.MempoolSampled,
+		"mempool_includable", estimate.MempoolIncludable,
+		"mempool_outliers", estimate.MempoolOutliers,
+		"duration_us", e.clock.Now().Sub(start).Microseconds(),
+	)
+	if estimate.Explanation != nil {
+		e.logExplanation(estimate)
+	}
+	return nil
+}
+
+// oldestHistoryBlock returns the first of the size blocks ending at latest
+// that bootstrap the history: genesis on chains younger than that.
+func oldestHistoryBlock(latest uint64, size int) uint64 {
+	if size < 1 {
+		return latest
+	}
+	if latest < uint64(size) {
+		return 0
+	}
+	return latest - uint64(size) + 1
+}
+
+// loadHistory fills the history with the most recent blocks.
+func (e *Estimator) loadHistory(ctx context.Context) error {
 	latest, err := e.client.LatestBlock(ctx)
 	if err != nil {
 		return fmt.Errorf("getting latest block: %w", err)
 	}
 
 	e.logger.Info("bootstrapping history", "latest_block", latest.Number)
+	e.head.observe(latest, e.clock.Now())
 
-	// Load last N blocks
-	for i := 0; i < e.historySize && latest.Number > uint64(i); i++ {
-		blockNum := latest.Number - uint64(i)
-		block, err := e.client.BlockByNumber(ctx, uint256.NewInt(blockNum))
+	if reader, ok := e.client.(eth.FeeHistoryReader); ok && e.dataPlan().History == HistoryFeeHistory {
+		err := e.loadFeeHistory(ctx, reader, latest)
+		if err == nil {
+			e.logger.Info("bootstrap complete", "blocks_loaded", e.state.history.Len(), "source", HistoryFeeHistory)
+			return nil
+		}
+		e.logger.Warn("fee history bootstrap failed, fetching blocks", "error", err)
+	}
+
+	// Load the blocks up to latest, oldest first, so the newest ends up as
+	// History.Latest
+	oldest := oldestHistoryBlock(latest.Number, e.historySize)
+	for i := range latest.Number - oldest + 1 {
+		n := oldest + i
+		block, err := e.fetchBlock(ctx, n)
 		if err != nil {
 			e.logger.Warn("failed to fetch historical block",
-				"block", blockNum,
+				"block", n,
 				"error", err,
 			)
 			continue
 		}
-		e.history.Push(e.convertBlock(block))
+		e.state.pushBlock(block, e.minedBlock(ctx, block))
 	}
 
-	e.logger.Info("bootstrap complete", "blocks_loaded", e.history.Len())
-
-	// Trigger initial calculation
-	e.recalculate(ctx)
+	e.logger.Info("bootstrap complete", "blocks_loaded", e.state.history.Len())
 
 	return nil
 }
 
-// handleNewBlock processes a new block notification.
-func (e *Estimator) handleNewBlock(ctx context.Context, block *eth.Block) {
-	start := time.Now()
-
+// handleNewBlock processes a new block notification that arrived at start.
+func (e *Estimator) handleNewBlock(ctx context.Context, block *eth.Block, start time.Time) {
 	// Fetch full block with transactions
-	fullBlock, err := e.client.BlockByNumber(ctx, uint256.NewInt(block.Number))
+	fullBlock, err := e.fetchBlock(ctx, block.Number)
 	if err != nil {
 		e.logger.Error("failed to fetch full block",
 			"block", block.Number,
@@ -227,75 +798,169 @@ func (e *Estimator) handleNewBlock(ctx context.Context, block *eth.Block) {
 		)
 		return
 	}
+	e.processBlock(ctx, fullBlock, start)
+}
+
+// backfill fetches the blocks numbered numbers into the history, oldest
+// first, stopping at the first failure.
+func (e *Estimator) backfill(ctx context.Context, numbers []uint64) {
+	for _, n := range numbers {
+		block, err := e.fetchBlock(ctx, n)
+		if err != nil {
+			e.logger.Warn("failed to backfill block", "block", n, "error", err)
+			return
+		}
+		e.state.insertBlock(e.minedBlock(ctx, block))
+	}
+	e.logger.Info("backfilled missing blocks", "from", numbers[0], "to", numbers[len(numbers)-1], "count", len(numbers))
+}
+
+// processBlock adds a full block to the history, publishes BlockArrived
+// and recalculates unless block triggers are off. start is when the block
+// was first seen.
+func (e *Estimator) processBlock(ctx context.Context, block *eth.Block, start time.Time) {
+	block = e.crossCheck(ctx, block)
+	depth := e.head.observe(block, start)
+	if depth > 0 {
+		e.logger.Warn("chain reorganization", "block", block.Number, "hash", block.Hash, "depth", depth)
+	}
+	if e.trackResync(ctx, block, depth) {
+		return
+	}
+	data := e.minedBlock(ctx, block)
+	e.state.pushBlock(block, data)
+	e.events.BlockArrived.publish(BlockArrived{Block: data, Arrived: start})
+	if e.recalcOnBlock {
+		e.Recalculate(ctx)
+	}
+
+	// Blocks skipped by a full block queue, a reconnect or a late fetch;
+	// they inform the estimate from the next recalculation on
+	if gaps := e.state.gaps(); len(gaps) > 0 {
+		e.backfill(ctx, gaps)
+	}
 
-	e.history.Push(e.convertBlock(fullBlock))
-	e.recalculate(ctx)
+	// Refreshed after recalculating so the extra requests don't delay the
+	// estimate; nonces and the pending block apply from the next
+	// recalculation on
+	if e.nonceFilter {
+		e.refreshNonces(ctx)
+	}
+	if e.pendingBlock {
+		e.refreshPendingBlock(ctx)
+	}
+	if e.dataPlan().Mempool == MempoolTxPool {
+		e.pollTxPool(ctx)
+	}
 
-	lag := time.Since(block.Timestamp)
+	now := e.clock.Now()
+	lag := now.Sub(block.Timestamp)
 	e.logger.Info("processed new block",
 		"block", block.Number,
 		"base_fee_gwei", weiToGwei(block.BaseFee),
 		"chain_lag_ms", lag.Milliseconds(),
-		"processing_time_ms", time.Since(start).Milliseconds(),
+		"processing_time_ms", now.Sub(start).Milliseconds(),
 	)
 }
 
-// recalculate computes a new estimate and updates the provider.
-func (e *Estimator) recalculate(ctx context.Context) {
-	start := time.Now()
+// setChainID records the connected chain and resolves its network metadata.
+func (e *Estimator) setChainID(chainID uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 
-	// Build calculator input
-	input, err := e.buildInput(ctx)
-	if err != nil {
-		e.logger.Error("failed to build calculator input", "error", err)
-		return
-	}
+	e.chainID = chainID
+	e.network = e.network.withDefaults(chainID)
+}
 
-	// Calculate new estimate
-	estimate, err := e.strategy.Calculate(ctx, input)
-	if err != nil {
-		e.logger.Error("calculation failed", "error", err)
-		return
-	}
+// setPlan records the data sources chosen for this run.
+func (e *Estimator) setPlan(plan DataPlan) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.plan = plan
+}
 
-	// Update provider
-	e.provider.Update(estimate)
+// dataPlan returns the data sources in use.
+func (e *Estimator) dataPlan() DataPlan {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.plan
+}
 
-	e.logger.Debug("estimate updated",
-		"block", estimate.BlockNumber,
-		"base_fee_gwei", weiToGwei(estimate.BaseFee),
-		"urgent_priority_gwei", weiToGwei(estimate.Urgent.MaxPriorityFeePerGas),
-		"standard_priority_gwei", weiToGwei(estimate.Standard.MaxPriorityFeePerGas),
-		"duration_us", time.Since(start).Microseconds(),
-	)
+// annotate attaches network and strategy labels, inclusion statistics and
+// the validity window to an estimate freshly calculated from input.
+func (e *Estimator) annotate(est *GasEstimate, input *CalculatorInput) {
+	est.Network = e.network
+	est.Strategy = e.strategy.Name()
+	est.Degraded = e.degraded.Load()
+	est.BlockTimestamp = input.CurrentBlock.Timestamp
+	est.Inclusion = inclusionCurve(input.RecentBlocks)
+	if e.dataPlan().DevChain {
+		// Dev chains mine on demand, so there is no next block time
+		return
+	}
+	cadence := blockCadence(e.network, input.RecentBlocks)
+	est.ValidUntil = nextBlockTime(input.CurrentBlock.Timestamp, cadence, input.Now)
 }
 
 // buildInput constructs the calculator input from current state.
 func (e *Estimator) buildInput(ctx context.Context) (*CalculatorInput, error) {
-	blocks := e.history.Snapshot()
+	// History and mempool sample as of the same head block
+	blocks, pendingTxs := e.state.snapshot()
 	if len(blocks) == 0 {
 		return nil, fmt.Errorf("no blocks in history")
 	}
-
-	// Sample pending transactions from local pool
-	pendingTxs := e.localPool.Snapshot()
-
-	// Get previous estimate for smoothing
-	var prevEstimate *GasEstimate
-	if est, err := e.provider.Current(ctx); err == nil {
-		prevEstimate = est
+	if e.nonceFilter {
+		pendingTxs = e.nonces.executable(pendingTxs)
 	}
 
+	// Previous estimate for smoothing, even if it has expired
+	prevEstimate := e.provider.latest()
+
 	return &CalculatorInput{
 		ChainID:          e.chainID,
 		CurrentBlock:     blocks[0],
 		RecentBlocks:     blocks,
 		PendingTxs:       pendingTxs,
 		PreviousEstimate: prevEstimate,
+		PendingBlock:     e.state.pendingBlock(),
+		Now:              e.clock.Now(),
+		Explain:          e.explain,
 	}, nil
 }
 
-func (e *Estimator) convertBlock(block *eth.Block) *BlockData {
+// minedBlock converts a mined block, with fees from its receipts under
+// WithReceipts.
+func (e *Estimator) minedBlock(ctx context.Context, block *eth.Block) *BlockData {
+	reader, ok := e.client.(eth.ReceiptReader)
+	if !e.receipts || !ok || len(block.Transactions) == 0 {
+		return e.convertBlock(block, nil)
+	}
+	receipts, err := e.fetchReceipts(ctx, reader, block)
+	if err != nil {
+		e.logger.Warn("failed to fetch receipts, using transaction fees",
+			"block", block.Number,
+			"error", err,
+		)
+	}
+	return e.convertBlock(block, receipts)
+}
+
+// convertBlock converts a mined block with blockData, counting its
+// transactions by type and the fees thinned out of it.
+func (e *Estimator) convertBlock(block *eth.Block, receipts []eth.Receipt) *BlockData {
+	for _, tx := range block.Transactions {
+		e.countTxType(tx.Type)
+	}
+	bd, evicted := e.blockData(block, receipts)
+	e.feesEvicted.Add(uint64(evicted))
+	return bd
+}
+
+// blockData extracts the priority fee and gas of each fee-paying
+// transaction, and reports how many fees thinning dropped. Transactions
+// with a receipt are priced from its effective gas price and gas used, the
+// rest from their fee fields and gas limit.
+func (e *Estimator) blockData(block *eth.Block, receipts []eth.Receipt) (*BlockData, int) {
 	bd := &BlockData{
 		Number:    block.Number,
 		Timestamp: block.Timestamp,
@@ -304,15 +969,32 @@ func (e *Estimator) convertBlock(block *eth.Block) *BlockData {
 		GasLimit:  block.GasLimit,
 	}
 
-	// Extract priority fees from transactions
+	var byHash map[string]*eth.Receipt
+	if len(receipts) > 0 {
+		byHash = make(map[string]*eth.Receipt, len(receipts))
+		for i := range receipts {
+			byHash[receipts[i].TxHash] = &receipts[i]
+		}
+	}
+
+	// Extract priority fees from transactions, with the gas each bought
 	for _, tx := range block.Transactions {
-		fee := tx.EffectivePriorityFee(block.BaseFee)
+		fee, gas := tx.EffectivePriorityFee(block.BaseFee), tx.GasLimit
+		if r := byHash[tx.Hash]; r != nil && r.EffectiveGasPrice != nil && block.BaseFee != nil {
+			fee = new(uint256.Int)
+			if r.EffectiveGasPrice.Gt(block.BaseFee) {
+				fee.Sub(r.EffectiveGasPrice, block.BaseFee)
+			}
+			gas = r.GasUsed
+		}
 		if !fee.IsZero() {
 			bd.PriorityFees = append(bd.PriorityFees, fee)
+			bd.FeeGas = append(bd.FeeGas, gas)
 		}
 	}
-
-	return bd
+	n := len(bd.PriorityFees)
+	bd.PriorityFees, bd.FeeGas = thinFees(bd.PriorityFees, bd.FeeGas, e.blockSamples)
+	return bd, n - len(bd.PriorityFees)
 }
 
 func (e *Estimator) convertTx(tx *eth.Transaction) *TxData {
@@ -321,42 +1003,106 @@ func (e *Estimator) convertTx(tx *eth.Transaction) *TxData {
 		MaxFeePerGas:         tx.MaxFeePerGas,
 		GasPrice:             tx.GasPrice,
 		IsEIP1559:            tx.IsEIP1559(),
+		Hash:                 tx.Hash,
+		From:                 tx.From,
+		Nonce:                tx.Nonce,
+		Gas:                  tx.GasLimit,
 	}
 }
 
-// processPendingTxs batches pending transaction hashes and fetches them efficiently.
+// subscribePending starts mempool ingestion, preferring full transaction
+// bodies over hashes when the subscriber and node support it.
+func (e *Estimator) subscribePending(ctx context.Context) error {
+	if full, ok := e.subscriber.(eth.FullPendingTxSubscriber); ok && e.fullPendingTxs {
+		txCh, err := full.SubscribeFullPendingTransactions(ctx)
+		if err == nil {
+			e.logger.Info("subscribed to full pending transactions")
+			e.debug.setSubscription(subPendingTxs, "active (full)")
+			go e.processFullPendingTxs(ctx, txCh)
+			return nil
+		}
+		e.logger.Warn("full pending transaction subscription unavailable, falling back to hashes",
+			"error", err,
+		)
+	}
+
+	txHashCh, err := e.subscriber.SubscribeNewPendingTransactions(ctx)
+	if err != nil {
+		return fmt.Errorf("subscribing to pending txs: %w", err)
+	}
+
+	e.debug.setSubscription(subPendingTxs, "active (hashes)")
+
+	// Start pending tx processor
+	go e.processPendingTxs(ctx, txHashCh)
+	return nil
+}
+
+// processFullPendingTxs adds streamed transaction bodies directly to the local pool.
+func (e *Estimator) processFullPendingTxs(ctx context.Context, ch <-chan *eth.Transaction) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case tx, ok := <-ch:
+			if !ok {
+				e.debug.setSubscription(subPendingTxs, "closed")
+				return
+			}
+			e.addPendingTx(tx)
+		}
+	}
+}
+
+// processPendingTxs batches pending transaction hashes and fetches them
+// within the node budget, up to its in-flight batch limit at once.
 func (e *Estimator) processPendingTxs(ctx context.Context, ch <-chan string) {
-	const batchSize = 100
 	const batchTimeout = 50 * time.Millisecond
 
-	batch := make([]string, 0, batchSize)
-	timer := time.NewTimer(batchTimeout)
+	var inFlight sync.WaitGroup
+	defer inFlight.Wait()
+
+	batch := make([]string, 0, e.budget.maxBatch)
+	timer := e.clock.NewTimer(batchTimeout)
 	defer timer.Stop()
 
+	flush := func() {
+		if !e.budget.acquireBatch(ctx) {
+			return
+		}
+		hashes := slices.Clone(batch)
+		batch = batch[:0]
+		inFlight.Add(1)
+		go func() {
+			defer inFlight.Done()
+			defer e.budget.releaseBatch()
+			e.fetchAndAddTxs(ctx, hashes)
+		}()
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case hash, ok := <-ch:
 			if !ok {
+				e.debug.setSubscription(subPendingTxs, "closed")
 				return
 			}
 			batch = append(batch, hash)
-			if len(batch) >= batchSize {
-				e.fetchAndAddTxs(ctx, batch)
-				batch = batch[:0]
+			if len(batch) >= e.budget.batchLimit() {
+				flush()
 				if !timer.Stop() {
 					select {
-					case <-timer.C:
+					case <-timer.C():
 					default:
 					}
 				}
 				timer.Reset(batchTimeout)
 			}
-		case <-timer.C:
+		case <-timer.C():
 			if len(batch) > 0 {
-				e.fetchAndAddTxs(ctx, batch)
-				batch = batch[:0]
+				flush()
 			}
 			timer.Reset(batchTimeout)
 		}
@@ -364,21 +1110,96 @@ func (e *Estimator) processPendingTxs(ctx context.Context, ch <-chan string) {
 }
 
 func (e *Estimator) fetchAndAddTxs(ctx context.Context, hashes []string) {
-	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	if e.budget.wait(ctx, len(hashes)) != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeouts.Txs)
 	defer cancel()
 
 	txs, err := e.txReader.TransactionsByHashes(ctx, hashes)
+	e.budget.batchDone(err)
 	if err != nil {
 		return
 	}
 
 	for _, tx := range txs {
-		if tx != nil {
-			e.localPool.Add(tx)
+		e.addPendingTx(tx)
+	}
+}
+
+// refreshNonces fetches the confirmed nonces of the senders in the mempool sample.
+func (e *Estimator) refreshNonces(ctx context.Context) {
+	reader, ok := e.client.(eth.NonceReader)
+	if !ok {
+		if reader, ok = e.txReader.(eth.NonceReader); !ok {
+			return
+		}
+	}
+
+	_, pending := e.state.snapshot()
+	addrs := senders(pending, max(e.mempoolSamples, 1))
+	if len(addrs) == 0 {
+		e.nonces.set(make(map[string]uint64))
+		return
+	}
+
+	if e.budget.wait(ctx, len(addrs)) != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.timeouts.Txs)
+	defer cancel()
+
+	nonces, err := reader.Nonces(ctx, addrs)
+	if err != nil {
+		e.logger.Warn("failed to fetch sender nonces", "senders", len(addrs), "error", err)
+		return
+	}
+	e.nonces.set(nonces)
+}
+
+// detectAnomalies checks a new head block for abnormal conditions and
+// reports any it finds.
+func (e *Estimator) detectAnomalies(block *BlockData) {
+	for _, a := range e.anomalies.observe(block, e.state.takeArrivals()) {
+		a.ChainID = e.chainID
+		a.DetectedAt = e.clock.Now()
+		e.logger.Warn("gas anomaly detected",
+			"kind", a.Kind,
+			"block", a.BlockNumber,
+			"value", a.Value,
+			"baseline", a.Baseline,
+			"ratio", a.Ratio,
+		)
+		if e.onAnomaly != nil {
+			e.onAnomaly(a)
 		}
 	}
 }
 
+// refreshPendingBlock fetches the node's pending block.
+func (e *Estimator) refreshPendingBlock(ctx context.Context) {
+	reader, ok := e.client.(eth.PendingBlockReader)
+	if !ok {
+		return
+	}
+
+	if e.budget.wait(ctx, 1) != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.timeouts.Txs)
+	defer cancel()
+
+	block, err := reader.PendingBlock(ctx)
+	if err != nil {
+		e.logger.Warn("failed to fetch pending block", "error", err)
+		return
+	}
+	// Not counted: its transactions are counted once mined
+	data, _ := e.blockData(block, nil)
+	e.state.setPendingBlock(data)
+}
+
 // Helper functions
 
 func weiToGwei(wei *uint256.Int) float64 {
@@ -388,3 +1209,6 @@ func weiToGwei(wei *uint256.Int) float64 {
 	gwei := new(uint256.Int).Div(wei, uint256.NewInt(1e9))
 	return float64(gwei.Uint64())
 }
+
+// Verify interface compliance at compile time.
+var _ Runner = (*Estimator)(nil)
@@ -2,6 +2,11 @@ package estimator
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -54,3 +59,473 @@ func TestEstimator_Run(t *testing.T) {
 		t.Errorf("Run() error = %v", err)
 	}
 }
+
+func TestEstimator_Stages(t *testing.T) {
+	block := func(n uint64) *eth.Block {
+		return &eth.Block{Number: n, BaseFee: uint256.NewInt(10e9), GasUsed: 15e6, GasLimit: 30e6}
+	}
+	client := &mockBlockReader{
+		chainIDFunc: func(ctx context.Context) (uint64, error) { return 1, nil },
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) {
+			return block(100), nil
+		},
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			return block(number.Uint64()), nil
+		},
+	}
+	provider := NewProvider()
+
+	// Driven without Run, so no subscriber is needed
+	e := New(client, &mockTxReader{}, nil, provider, WithHistorySize(5))
+	ctx := context.Background()
+
+	if err := e.Bootstrap(ctx); err != nil {
+		t.Fatalf("Bootstrap() error = %v", err)
+	}
+	if est, err := provider.Current(ctx); err != nil || est.BlockNumber != 100 || est.ChainID != 1 {
+		t.Fatalf("after Bootstrap: estimate = %+v, error = %v", est, err)
+	}
+
+	e.IngestPendingTxs(&eth.Transaction{
+		Hash:                 "0x1",
+		Type:                 2,
+		MaxFeePerGas:         uint256.NewInt(100e9),
+		MaxPriorityFeePerGas: uint256.NewInt(7e9),
+	}, nil)
+	if err := e.Recalculate(ctx); err != nil {
+		t.Fatalf("Recalculate() error = %v", err)
+	}
+	if est, _ := provider.Current(ctx); est.MempoolSampled != 1 {
+		t.Errorf("MempoolSampled = %d, want 1", est.MempoolSampled)
+	}
+
+	e.IngestBlock(ctx, block(101))
+	if est, _ := provider.Current(ctx); est.BlockNumber != 101 {
+		t.Errorf("after IngestBlock: BlockNumber = %d, want 101", est.BlockNumber)
+	}
+}
+
+func TestEstimator_Control(t *testing.T) {
+	block := func(n uint64) *eth.Block {
+		return &eth.Block{Number: n, BaseFee: uint256.NewInt(1e9), GasUsed: 15e6, GasLimit: 30e6}
+	}
+	client := &mockBlockReader{
+		chainIDFunc:     func(ctx context.Context) (uint64, error) { return 1, nil },
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) { return block(100), nil },
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			return block(number.Uint64()), nil
+		},
+	}
+
+	// Each new heads subscription closes its channel when its context ends
+	subs := make(chan chan *eth.Block, 2)
+	sub := &mockSubscriber{
+		subHeadsFunc: func(ctx context.Context) (<-chan *eth.Block, error) {
+			ch := make(chan *eth.Block)
+			out := make(chan *eth.Block)
+			go func() {
+				defer close(out)
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case b := <-ch:
+						out <- b
+					}
+				}
+			}()
+			subs <- ch
+			return out, nil
+		},
+		subPendingFunc: func(ctx context.Context) (<-chan string, error) {
+			return make(chan string), nil
+		},
+	}
+	provider := NewProvider()
+	e := New(client, &mockTxReader{}, sub, provider, WithHistorySize(5), WithRecalcInterval(time.Hour))
+
+	if err := e.Resubscribe(context.Background()); err != ErrNotRunning {
+		t.Fatalf("Resubscribe() before Run error = %v, want ErrNotRunning", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go e.Run(ctx)
+
+	waitBlock := func(want uint64) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for {
+			if est, err := provider.Current(ctx); err == nil && est.BlockNumber == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("estimate for block %d not published", want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	heads := <-subs
+	waitBlock(100)
+
+	e.Pause()
+	if !e.Paused() || !e.DebugSnapshot().Paused {
+		t.Fatal("Paused() = false after Pause")
+	}
+	heads <- block(101)
+	time.Sleep(20 * time.Millisecond)
+	if est, _ := provider.Current(ctx); est.BlockNumber != 100 {
+		t.Errorf("block %d processed while paused", est.BlockNumber)
+	}
+
+	if err := e.Resume(ctx); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	heads <- block(102)
+	waitBlock(102)
+
+	// Wait for Run to accept requests
+	var err error
+	for i := 0; i < 100; i++ {
+		if err = e.Resubscribe(ctx); err != ErrNotRunning {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Resubscribe() error = %v", err)
+	}
+	select {
+	case heads = <-subs:
+	default:
+		t.Fatal("Resubscribe() did not open a new heads subscription")
+	}
+	heads <- block(103)
+	waitBlock(103)
+}
+
+func TestEstimator_SubscribePending(t *testing.T) {
+	newEstimator := func(sub eth.Subscriber) *Estimator {
+		return New(&mockBlockReader{}, &mockTxReader{}, sub, NewProvider(), WithMempoolSamples(10))
+	}
+
+	t.Run("Full bodies", func(t *testing.T) {
+		txCh := make(chan *eth.Transaction, 1)
+		hashSubscribed := false
+		sub := &mockFullSubscriber{
+			mockSubscriber: mockSubscriber{
+				subPendingFunc: func(ctx context.Context) (<-chan string, error) {
+					hashSubscribed = true
+					return make(chan string), nil
+				},
+			},
+			subFullFunc: func(ctx context.Context) (<-chan *eth.Transaction, error) {
+				return txCh, nil
+			},
+		}
+		e := newEstimator(sub)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		if err := e.subscribePending(ctx); err != nil {
+			t.Fatalf("subscribePending() error = %v", err)
+		}
+		if hashSubscribed {
+			t.Error("hash subscription opened despite full-body support")
+		}
+
+		txCh <- &eth.Transaction{Type: 2, MaxPriorityFeePerGas: uint256.NewInt(1), MaxFeePerGas: uint256.NewInt(2)}
+		deadline := time.Now().Add(time.Second)
+		for len(e.state.pool.Snapshot()) == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("streamed transaction not added to pool")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+
+	t.Run("Fallback to hashes", func(t *testing.T) {
+		hashSubscribed := false
+		sub := &mockFullSubscriber{
+			mockSubscriber: mockSubscriber{
+				subPendingFunc: func(ctx context.Context) (<-chan string, error) {
+					hashSubscribed = true
+					return make(chan string), nil
+				},
+			},
+		}
+		e := newEstimator(sub)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		if err := e.subscribePending(ctx); err != nil {
+			t.Fatalf("subscribePending() error = %v", err)
+		}
+		if !hashSubscribed {
+			t.Error("hash subscription not opened after full-body probe failed")
+		}
+	})
+}
+
+// receiptClient serves receipts for any block.
+type receiptClient struct {
+	mockBlockReader
+	receipts []eth.Receipt
+	err      error
+}
+
+func (c *receiptClient) BlockReceipts(ctx context.Context, block *eth.Block) ([]eth.Receipt, error) {
+	return c.receipts, c.err
+}
+
+func TestEstimator_Receipts(t *testing.T) {
+	// A transaction capped by its max fee pays less than its priority fee
+	block := &eth.Block{
+		Number:  10,
+		BaseFee: uint256.NewInt(90),
+		Transactions: []eth.Transaction{
+			{Hash: "0x1", Type: 2, GasLimit: 100_000, MaxFeePerGas: uint256.NewInt(100), MaxPriorityFeePerGas: uint256.NewInt(10)},
+			{Hash: "0x2", Type: 2, GasLimit: 50_000, MaxFeePerGas: uint256.NewInt(200), MaxPriorityFeePerGas: uint256.NewInt(20)},
+		},
+	}
+	client := &receiptClient{receipts: []eth.Receipt{
+		{TxHash: "0x1", GasUsed: 60_000, EffectiveGasPrice: uint256.NewInt(95)},
+		{TxHash: "0x2", GasUsed: 21_000, EffectiveGasPrice: uint256.NewInt(110)},
+	}}
+
+	e := New(client, nil, nil, NewProvider(), WithReceipts(true))
+	bd := e.minedBlock(context.Background(), block)
+	if len(bd.PriorityFees) != 2 || bd.PriorityFees[0].Uint64() != 5 || bd.PriorityFees[1].Uint64() != 20 {
+		t.Errorf("fees from receipts = %v, want [5 20]", bd.PriorityFees)
+	}
+	if len(bd.FeeGas) != 2 || bd.FeeGas[0] != 60_000 || bd.FeeGas[1] != 21_000 {
+		t.Errorf("gas from receipts = %v, want [60000 21000]", bd.FeeGas)
+	}
+
+	// Failed fetches fall back to transaction fields
+	client.err = errors.New("boom")
+	client.receipts = nil
+	bd = e.minedBlock(context.Background(), block)
+	if len(bd.PriorityFees) != 2 || bd.PriorityFees[0].Uint64() != 10 || bd.FeeGas[0] != 100_000 {
+		t.Errorf("fallback fees = %v gas = %v, want [10 20] and gas limits", bd.PriorityFees, bd.FeeGas)
+	}
+
+	// Disabled by default
+	client.err = nil
+	client.receipts = []eth.Receipt{{TxHash: "0x1", GasUsed: 60_000, EffectiveGasPrice: uint256.NewInt(95)}}
+	bd = New(client, nil, nil, NewProvider()).minedBlock(context.Background(), block)
+	if bd.PriorityFees[0].Uint64() != 10 {
+		t.Errorf("fee without WithReceipts = %v, want 10", bd.PriorityFees[0])
+	}
+}
+
+func TestEstimator_Backfill(t *testing.T) {
+	var fetched []uint64
+	client := &mockBlockReader{
+		chainIDFunc: func(ctx context.Context) (uint64, error) { return 1, nil },
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) {
+			return &eth.Block{Number: 100, BaseFee: uint256.NewInt(1e9)}, nil
+		},
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			fetched = append(fetched, number.Uint64())
+			return &eth.Block{Number: number.Uint64(), BaseFee: uint256.NewInt(1e9)}, nil
+		},
+	}
+	e := New(client, nil, nil, NewProvider(), WithHistorySize(5))
+	if err := e.Bootstrap(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Heads 101 and 102 were never delivered
+	fetched = nil
+	e.IngestBlock(context.Background(), &eth.Block{Number: 103, BaseFee: uint256.NewInt(1e9)})
+	if want := []uint64{101, 102}; !slices.Equal(fetched, want) {
+		t.Errorf("fetched %v, want %v", fetched, want)
+	}
+	blocks, _ := e.state.snapshot()
+	var got []uint64
+	for _, b := range blocks {
+		got = append(got, b.Number)
+	}
+	if want := []uint64{103, 102, 101, 100, 99}; !slices.Equal(got, want) {
+		t.Errorf("history = %v, want %v", got, want)
+	}
+}
+
+func TestEstimator_FetchTimeouts(t *testing.T) {
+	stalled := false
+	var fetchErr error
+	client := &mockBlockReader{
+		chainIDFunc: func(ctx context.Context) (uint64, error) { return 1, nil },
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) {
+			return &eth.Block{Number: 100, BaseFee: uint256.NewInt(1e9)}, nil
+		},
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			if stalled {
+				<-ctx.Done()
+				fetchErr = ctx.Err()
+				return nil, fetchErr
+			}
+			return &eth.Block{Number: number.Uint64(), BaseFee: uint256.NewInt(1e9)}, nil
+		},
+	}
+	e := New(client, nil, nil, NewProvider(), WithHistorySize(5), WithFetchTimeouts(FetchTimeouts{Block: 20 * time.Millisecond}))
+	if e.timeouts.Txs != defaultFetchTimeouts.Txs {
+		t.Errorf("Txs timeout = %v, want default %v", e.timeouts.Txs, defaultFetchTimeouts.Txs)
+	}
+	if err := e.Bootstrap(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The backfill of block 101 must not hang on a stalled node
+	stalled = true
+	start := time.Now()
+	e.IngestBlock(context.Background(), &eth.Block{Number: 102, BaseFee: uint256.NewInt(1e9)})
+	if !errors.Is(fetchErr, context.DeadlineExceeded) {
+		t.Errorf("stalled fetch ended with %v, want deadline exceeded", fetchErr)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("IngestBlock took %v with a 20ms block timeout", elapsed)
+	}
+}
+
+func TestEstimator_BlockTiming(t *testing.T) {
+	client := &mockBlockReader{
+		chainIDFunc: func(ctx context.Context) (uint64, error) { return 1, nil },
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) {
+			return &eth.Block{Number: 100, BaseFee: uint256.NewInt(1e9)}, nil
+		},
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			return &eth.Block{Number: number.Uint64(), BaseFee: uint256.NewInt(1e9)}, nil
+		},
+	}
+	var timings []BlockTiming
+	provider := NewProvider()
+	e := New(client, nil, nil, provider, WithHistorySize(5), WithBlockTiming(func(t BlockTiming) {
+		timings = append(timings, t)
+	}))
+	if err := e.Bootstrap(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	produced := time.Now().Add(-3 * time.Second)
+	e.IngestBlock(context.Background(), &eth.Block{Number: 101, BaseFee: uint256.NewInt(1e9), Timestamp: produced})
+	if len(timings) != 1 {
+		t.Fatalf("timings = %d, want 1", len(timings))
+	}
+	if got := timings[0]; got.Number != 101 || got.ChainLag() < 3*time.Second || got.UpdateLatency() < 0 {
+		t.Errorf("timing = %+v, want block 101 about 3s behind the chain", got)
+	}
+
+	est, _ := provider.Current(context.Background())
+	if !est.BlockTimestamp.Equal(produced) || est.ChainLag(time.Now()) < 3*time.Second {
+		t.Errorf("estimate block time %v, want %v", est.BlockTimestamp, produced)
+	}
+}
+
+func TestEstimator_TxTypes(t *testing.T) {
+	e := New(&mockBlockReader{}, nil, nil, NewProvider())
+	block := &eth.Block{Number: 1, BaseFee: uint256.NewInt(100), Transactions: []eth.Transaction{
+		{Hash: "0x1", Type: eth.DynamicFeeTxType, MaxFeePerGas: uint256.NewInt(200), MaxPriorityFeePerGas: uint256.NewInt(10)},
+		{Hash: "0x2", Type: eth.BlobTxType, MaxFeePerGas: uint256.NewInt(200), MaxPriorityFeePerGas: uint256.NewInt(20)},
+		{Hash: "0x3", Type: eth.SetCodeTxType, MaxFeePerGas: uint256.NewInt(200), MaxPriorityFeePerGas: uint256.NewInt(30)},
+		{Hash: "0x4", Type: 0x7e, MaxFeePerGas: uint256.NewInt(200), MaxPriorityFeePerGas: uint256.NewInt(40)},
+	}}
+	bd := e.convertBlock(block, nil)
+	var fees []uint64
+	for _, fee := range bd.PriorityFees {
+		fees = append(fees, fee.Uint64())
+	}
+	if want := []uint64{10, 20, 30, 40}; !slices.Equal(fees, want) {
+		t.Errorf("priority fees = %v, want %v", fees, want)
+	}
+
+	e.IngestPendingTxs(&eth.Transaction{Hash: "0x5", Type: eth.SetCodeTxType, MaxFeePerGas: uint256.NewInt(200), MaxPriorityFeePerGas: uint256.NewInt(5)})
+	counts := e.TxTypeCounts()
+	want := map[string]uint64{"legacy": 0, "access_list": 0, "dynamic_fee": 1, "blob": 1, "set_code": 2, "unknown": 1}
+	if !maps.Equal(counts, want) {
+		t.Errorf("TxTypeCounts() = %v, want %v", counts, want)
+	}
+}
+
+// pendingBlockClient is a block reader that also serves a pending block.
+type pendingBlockClient struct {
+	mockBlockReader
+	block *eth.Block
+}
+
+func (c *pendingBlockClient) PendingBlock(ctx context.Context) (*eth.Block, error) {
+	return c.block, nil
+}
+
+func TestEstimator_PendingBlockNotCounted(t *testing.T) {
+	block := &eth.Block{Number: 2, BaseFee: uint256.NewInt(100)}
+	for i := range 4 {
+		block.Transactions = append(block.Transactions, eth.Transaction{
+			Hash: fmt.Sprintf("0x%d", i), Type: eth.DynamicFeeTxType,
+			MaxFeePerGas: uint256.NewInt(200), MaxPriorityFeePerGas: uint256.NewInt(uint64(10 + i)),
+		})
+	}
+	e := New(&pendingBlockClient{block: block}, nil, nil, NewProvider(), WithBlockFeeSamples(2))
+	e.state.pushBlock(&eth.Block{Number: 1}, &BlockData{Number: 1})
+
+	// Refreshing the pending block, however often, counts nothing
+	for range 3 {
+		e.refreshPendingBlock(context.Background())
+	}
+	if pending := e.state.pendingBlock(); pending == nil || len(pending.PriorityFees) != 2 {
+		t.Fatalf("pending block = %+v, want 2 thinned fees", pending)
+	}
+	if counts := e.TxTypeCounts(); counts["dynamic_fee"] != 0 {
+		t.Errorf("TxTypeCounts() = %v after pending block refreshes, want none", counts)
+	}
+	if evicted := e.MemoryStats().HistoryFeesEvicted; evicted != 0 {
+		t.Errorf("HistoryFeesEvicted = %d after pending block refreshes, want 0", evicted)
+	}
+
+	// Once mined, its transactions are counted once
+	e.convertBlock(block, nil)
+	if counts := e.TxTypeCounts(); counts["dynamic_fee"] != 4 {
+		t.Errorf("TxTypeCounts()[dynamic_fee] = %d once mined, want 4", counts["dynamic_fee"])
+	}
+	if evicted := e.MemoryStats().HistoryFeesEvicted; evicted != 2 {
+		t.Errorf("HistoryFeesEvicted = %d once mined, want 2", evicted)
+	}
+}
+
+func TestEstimator_RunStopsWorkerOnError(t *testing.T) {
+	var fetches atomic.Int32
+	client := &mockBlockReader{
+		chainIDFunc: func(ctx context.Context) (uint64, error) { return 1, nil },
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) {
+			return &eth.Block{Number: 100, BaseFee: uint256.NewInt(1e9)}, nil
+		},
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			fetches.Add(1)
+			return &eth.Block{Number: number.Uint64(), BaseFee: uint256.NewInt(1e9)}, nil
+		},
+	}
+	sub := &mockSubscriber{
+		subHeadsFunc: func(ctx context.Context) (<-chan *eth.Block, error) {
+			return make(chan *eth.Block), nil
+		},
+		subPendingFunc: func(ctx context.Context) (<-chan string, error) {
+			return nil, errors.New("pending subscriptions not supported")
+		},
+	}
+	e := New(client, &mockTxReader{}, sub, NewProvider(), WithHistorySize(5))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := e.Run(ctx); err == nil {
+		t.Fatal("Run() succeeded without a pending transaction subscription")
+	}
+
+	// The block worker ended with Run, though ctx is still live
+	before := fetches.Load()
+	e.queueBlock(&eth.Block{Number: 101, Hash: "0x65"}, false)
+	time.Sleep(20 * time.Millisecond)
+	if n := fetches.Load() - before; n != 0 {
+		t.Errorf("block worker fetched %d blocks after Run returned", n)
+	}
+}
@@ -0,0 +1,152 @@
+// Package estimatortest provides test doubles and canned data for testing
+// the estimator and custom strategies without a real node or real sleeps.
+package estimatortest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+)
+
+// Clock is a manually advanced estimator.Clock.
+// Tickers and timers fire only when Advance moves time past their deadline.
+type Clock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeTimer
+}
+
+// NewClock creates a fake clock starting at start.
+func NewClock(start time.Time) *Clock {
+	c := &Clock{now: start}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the current fake time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves time forward by d, firing any tickers and timers that come due.
+// Like time.Ticker, a ticker that is not drained drops intermediate ticks.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	for _, w := range c.waiters {
+		w.fire(c.now)
+	}
+}
+
+// BlockUntil waits until at least n tickers or timers are active.
+// Use it to know that a goroutine has reached its select loop before advancing.
+func (c *Clock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.activeLocked() < n {
+		c.cond.Wait()
+	}
+}
+
+// NewTicker creates a fake ticker.
+func (c *Clock) NewTicker(d time.Duration) estimator.Ticker {
+	if d <= 0 {
+		panic("estimatortest: non-positive ticker interval")
+	}
+	return fakeTicker{c.add(d, d)}
+}
+
+// NewTimer creates a fake timer.
+func (c *Clock) NewTimer(d time.Duration) estimator.Timer {
+	return c.add(d, 0)
+}
+
+func (c *Clock) add(d, period time.Duration) *fakeTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{
+		clock:    c,
+		ch:       make(chan time.Time, 1),
+		deadline: c.now.Add(d),
+		period:   period,
+		active:   true,
+	}
+	c.waiters = append(c.waiters, t)
+	c.cond.Broadcast()
+	return t
+}
+
+func (c *Clock) activeLocked() int {
+	n := 0
+	for _, w := range c.waiters {
+		if w.active {
+			n++
+		}
+	}
+	return n
+}
+
+type fakeTimer struct {
+	clock    *Clock
+	ch       chan time.Time
+	deadline time.Time
+	period   time.Duration // 0 for one-shot timers
+	active   bool
+}
+
+// fire delivers due ticks. Caller holds clock.mu.
+func (t *fakeTimer) fire(now time.Time) {
+	for t.active && !t.deadline.After(now) {
+		select {
+		case t.ch <- t.deadline:
+		default:
+		}
+		if t.period == 0 {
+			t.active = false
+			return
+		}
+		t.deadline = t.deadline.Add(t.period)
+	}
+}
+
+// fakeTicker adapts fakeTimer to the estimator.Ticker method set.
+type fakeTicker struct{ t *fakeTimer }
+
+func (t fakeTicker) C() <-chan time.Time { return t.t.ch }
+func (t fakeTicker) Stop()               { t.t.Stop() }
+
+func (t *fakeTimer) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	wasActive := t.active
+	t.active = false
+	return wasActive
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	wasActive := t.active
+	t.active = true
+	t.deadline = t.clock.now.Add(d)
+	if t.period != 0 {
+		t.period = d
+	}
+	t.clock.cond.Broadcast()
+	return wasActive
+}
+
+// Verify interface compliance at compile time.
+var _ estimator.Clock = (*Clock)(nil)
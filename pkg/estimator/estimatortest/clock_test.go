@@ -0,0 +1,62 @@
+package estimatortest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock_Ticker(t *testing.T) {
+	c := NewClock(Epoch)
+	tk := c.NewTicker(time.Second)
+
+	c.Advance(500 * time.Millisecond)
+	select {
+	case <-tk.C():
+		t.Fatal("ticker fired early")
+	default:
+	}
+
+	c.Advance(500 * time.Millisecond)
+	select {
+	case got := <-tk.C():
+		if want := Epoch.Add(time.Second); !got.Equal(want) {
+			t.Errorf("tick = %v, want %v", got, want)
+		}
+	default:
+		t.Fatal("ticker did not fire")
+	}
+
+	tk.Stop()
+	c.Advance(time.Second)
+	select {
+	case <-tk.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+}
+
+func TestClock_Timer(t *testing.T) {
+	c := NewClock(Epoch)
+	tm := c.NewTimer(time.Second)
+
+	c.Advance(2 * time.Second)
+	<-tm.C()
+
+	// One-shot: no second fire
+	c.Advance(2 * time.Second)
+	select {
+	case <-tm.C():
+		t.Fatal("timer fired twice")
+	default:
+	}
+
+	if tm.Reset(time.Second) {
+		t.Error("Reset() on expired timer = true, want false")
+	}
+	c.Advance(time.Second)
+	select {
+	case <-tm.C():
+	default:
+		t.Fatal("reset timer did not fire")
+	}
+}
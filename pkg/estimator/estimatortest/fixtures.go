@@ -0,0 +1,118 @@
+package estimatortest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+// Canned values shared by the fixtures.
+const (
+	ChainID  = 1
+	GasLimit = 30_000_000
+	Gwei     = 1_000_000_000
+)
+
+// Epoch is the timestamp of block 0 in fixtures; block n is at Epoch + 12s*n.
+var Epoch = time.Unix(1_700_000_000, 0).UTC()
+
+// BlockTime returns the fixture timestamp of block number.
+func BlockTime(number uint64) time.Time {
+	return Epoch.Add(time.Duration(number) * 12 * time.Second)
+}
+
+// Block returns a BlockData with the given base fee, gas utilization (0.0 to 1.0)
+// and included priority fees, all in wei.
+func Block(number, baseFee uint64, utilization float64, priorityFees ...uint64) *estimator.BlockData {
+	fees := make([]*uint256.Int, len(priorityFees))
+	for i, f := range priorityFees {
+		fees[i] = uint256.NewInt(f)
+	}
+	return &estimator.BlockData{
+		Number:       number,
+		Timestamp:    BlockTime(number),
+		BaseFee:      uint256.NewInt(baseFee),
+		GasUsed:      uint64(utilization * GasLimit),
+		GasLimit:     GasLimit,
+		PriorityFees: fees,
+	}
+}
+
+// Blocks returns n consecutive blocks ending at head, newest first (the order
+// used by CalculatorInput.RecentBlocks).
+func Blocks(head uint64, n int, baseFee uint64, utilization float64, priorityFees ...uint64) []*estimator.BlockData {
+	blocks := make([]*estimator.BlockData, 0, n)
+	for i := 0; i < n && uint64(i) <= head; i++ {
+		blocks = append(blocks, Block(head-uint64(i), baseFee, utilization, priorityFees...))
+	}
+	return blocks
+}
+
+// EIP1559Tx returns a pending EIP-1559 transaction.
+func EIP1559Tx(maxFee, maxPriorityFee uint64) *estimator.TxData {
+	return &estimator.TxData{
+		MaxFeePerGas:         uint256.NewInt(maxFee),
+		MaxPriorityFeePerGas: uint256.NewInt(maxPriorityFee),
+		IsEIP1559:            true,
+	}
+}
+
+// LegacyTx returns a pending legacy transaction.
+func LegacyTx(gasPrice uint64) *estimator.TxData {
+	return &estimator.TxData{
+		GasPrice: uint256.NewInt(gasPrice),
+	}
+}
+
+// Input builds a CalculatorInput from blocks (newest first) and pending transactions.
+func Input(blocks []*estimator.BlockData, txs ...*estimator.TxData) *estimator.CalculatorInput {
+	in := &estimator.CalculatorInput{
+		ChainID:      ChainID,
+		RecentBlocks: blocks,
+		PendingTxs:   txs,
+	}
+	if len(blocks) > 0 {
+		in.CurrentBlock = blocks[0]
+		in.Now = blocks[0].Timestamp.Add(time.Second)
+	}
+	return in
+}
+
+// EthBlock returns an eth.Block as served by a node, with one EIP-1559
+// transaction per priority fee.
+func EthBlock(number, baseFee uint64, utilization float64, priorityFees ...uint64) *eth.Block {
+	txs := make([]eth.Transaction, len(priorityFees))
+	for i, f := range priorityFees {
+		txs[i] = eth.Transaction{
+			Hash:                 fmt.Sprintf("0x%x%04x", number, i),
+			Type:                 2,
+			GasLimit:             21_000,
+			MaxPriorityFeePerGas: uint256.NewInt(f),
+			MaxFeePerGas:         uint256.NewInt(2*baseFee + f),
+		}
+	}
+	return &eth.Block{
+		Number:       number,
+		Hash:         fmt.Sprintf("0x%064x", number),
+		ParentHash:   fmt.Sprintf("0x%064x", number-1),
+		Timestamp:    BlockTime(number),
+		BaseFee:      uint256.NewInt(baseFee),
+		GasUsed:      uint64(utilization * GasLimit),
+		GasLimit:     GasLimit,
+		Transactions: txs,
+	}
+}
+
+// PendingTx returns an eth.Transaction suitable for Node.AddPendingTx.
+func PendingTx(hash string, maxFee, maxPriorityFee uint64) *eth.Transaction {
+	return &eth.Transaction{
+		Hash:                 hash,
+		Type:                 2,
+		GasLimit:             21_000,
+		MaxFeePerGas:         uint256.NewInt(maxFee),
+		MaxPriorityFeePerGas: uint256.NewInt(maxPriorityFee),
+	}
+}
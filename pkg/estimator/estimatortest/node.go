@@ -0,0 +1,195 @@
+package estimatortest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+// ErrNotFound is returned for unknown blocks.
+var ErrNotFound = errors.New("not found")
+
+// Node is an in-memory Ethereum node implementing eth.BlockReader,
+// eth.TransactionReader and eth.Subscriber.
+//
+// Blocks and pending transactions added after a subscription is opened are
+// delivered to subscribers; the Node does not drop notifications, so tests
+// must keep the consumer running.
+type Node struct {
+	mu      sync.Mutex
+	chainID uint64
+	blocks  map[uint64]*eth.Block
+	head    uint64
+	txs     map[string]*eth.Transaction
+	heads   []chan *eth.Block
+	pending []chan string
+	closed  bool
+}
+
+// NewNode creates an empty node for chainID.
+func NewNode(chainID uint64) *Node {
+	return &Node{
+		chainID: chainID,
+		blocks:  make(map[uint64]*eth.Block),
+		txs:     make(map[string]*eth.Transaction),
+	}
+}
+
+// AddBlock stores b, advances the head if b is newer, and notifies newHeads subscribers.
+func (n *Node) AddBlock(b *eth.Block) {
+	n.mu.Lock()
+	n.blocks[b.Number] = b
+	if b.Number > n.head {
+		n.head = b.Number
+	}
+	subs := append([]chan *eth.Block(nil), n.heads...)
+	n.mu.Unlock()
+
+	for _, ch := range subs {
+		ch <- b
+	}
+}
+
+// AddPendingTx stores tx and notifies newPendingTransactions subscribers.
+func (n *Node) AddPendingTx(tx *eth.Transaction) {
+	n.mu.Lock()
+	n.txs[tx.Hash] = tx
+	subs := append([]chan string(nil), n.pending...)
+	n.mu.Unlock()
+
+	for _, ch := range subs {
+		ch <- tx.Hash
+	}
+}
+
+// ChainID implements eth.BlockReader.
+func (n *Node) ChainID(ctx context.Context) (uint64, error) {
+	return n.chainID, nil
+}
+
+// LatestBlock implements eth.BlockReader.
+func (n *Node) LatestBlock(ctx context.Context) (*eth.Block, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	b, ok := n.blocks[n.head]
+	if !ok {
+		return nil, fmt.Errorf("latest block: %w", ErrNotFound)
+	}
+	return b, nil
+}
+
+// BlockByNumber implements eth.BlockReader.
+func (n *Node) BlockByNumber(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+	if number == nil {
+		return n.LatestBlock(ctx)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	b, ok := n.blocks[number.Uint64()]
+	if !ok {
+		return nil, fmt.Errorf("block %d: %w", number.Uint64(), ErrNotFound)
+	}
+	return b, nil
+}
+
+// TransactionByHash implements eth.TransactionReader.
+func (n *Node) TransactionByHash(ctx context.Context, hash string) (*eth.Transaction, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	tx, ok := n.txs[hash]
+	if !ok {
+		return nil, fmt.Errorf("tx %s: %w", hash, ErrNotFound)
+	}
+	return tx, nil
+}
+
+// TransactionsByHashes implements eth.TransactionReader. Unknown hashes are skipped.
+func (n *Node) TransactionsByHashes(ctx context.Context, hashes []string) ([]*eth.Transaction, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	txs := make([]*eth.Transaction, 0, len(hashes))
+	for _, h := range hashes {
+		if tx, ok := n.txs[h]; ok {
+			txs = append(txs, tx)
+		}
+	}
+	return txs, nil
+}
+
+// SubscribeNewHeads implements eth.Subscriber.
+func (n *Node) SubscribeNewHeads(ctx context.Context) (<-chan *eth.Block, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.closed {
+		return nil, errors.New("node closed")
+	}
+	ch := make(chan *eth.Block, 16)
+	n.heads = append(n.heads, ch)
+	return ch, nil
+}
+
+// SubscribeNewPendingTransactions implements eth.Subscriber.
+func (n *Node) SubscribeNewPendingTransactions(ctx context.Context) (<-chan string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.closed {
+		return nil, errors.New("node closed")
+	}
+	ch := make(chan string, 128)
+	n.pending = append(n.pending, ch)
+	return ch, nil
+}
+
+// DropSubscriptions closes the open subscription channels, as when the
+// WebSocket connection is lost. Later subscriptions succeed.
+// It must not be called concurrently with AddBlock or AddPendingTx.
+func (n *Node) DropSubscriptions() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, ch := range n.heads {
+		close(ch)
+	}
+	for _, ch := range n.pending {
+		close(ch)
+	}
+	n.heads, n.pending = nil, nil
+}
+
+// Close closes all subscription channels.
+// It must not be called concurrently with AddBlock or AddPendingTx.
+func (n *Node) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.closed {
+		return nil
+	}
+	n.closed = true
+	for _, ch := range n.heads {
+		close(ch)
+	}
+	for _, ch := range n.pending {
+		close(ch)
+	}
+	n.heads, n.pending = nil, nil
+	return nil
+}
+
+// Verify interface compliance at compile time.
+var (
+	_ eth.BlockReader       = (*Node)(nil)
+	_ eth.TransactionReader = (*Node)(nil)
+	_ eth.Subscriber        = (*Node)(nil)
+)
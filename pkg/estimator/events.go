@@ -0,0 +1,120 @@
+package estimator
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+)
+
+// BlockArrived is published when a new head block has been added to the
+// history, before the estimate is recalculated from it.
+type BlockArrived struct {
+	Block *BlockData
+
+	// Arrived is when the estimator was notified of the block.
+	Arrived time.Time
+}
+
+// MempoolSampled is published for each pending transaction added to the
+// mempool sample.
+type MempoolSampled struct {
+	Tx *eth.Transaction
+}
+
+// EstimateComputed is published when a recalculated estimate has been
+// published to the provider. Estimates rejected as stale are not.
+type EstimateComputed struct {
+	Estimate *GasEstimate
+
+	// Started is when the recalculation began, Published when the
+	// estimate was published.
+	Started   time.Time
+	Published time.Time
+}
+
+// Bus carries the estimator's pipeline events to subscribers, so stages
+// such as accuracy tracking, persistence or notifications can follow the
+// pipeline without changes to it. Get an Estimator's bus with Events.
+//
+// Handlers run synchronously on the goroutine that published the event,
+// in subscription order, and must not block; hand slow work off to another
+// goroutine. Events and the values they point to must not be modified.
+type Bus struct {
+	BlockArrived     Topic[BlockArrived]
+	MempoolSampled   Topic[MempoolSampled]
+	EstimateComputed Topic[EstimateComputed]
+}
+
+// Topic is one event type's subscriber list. Publishing takes no locks.
+type Topic[T any] struct {
+	mu       sync.Mutex // serializes changes to handlers
+	handlers atomic.Pointer[[]*handler[T]]
+}
+
+type handler[T any] struct {
+	fn func(T)
+}
+
+// Subscribe calls fn for every event published from now on, until the
+// returned function is called.
+func (t *Topic[T]) Subscribe(fn func(T)) (unsubscribe func()) {
+	h := &handler[T]{fn: fn}
+	t.mu.Lock()
+	next := append(slices.Clip(t.load()), h)
+	t.handlers.Store(&next)
+	t.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			next := slices.DeleteFunc(slices.Clone(t.load()), func(x *handler[T]) bool { return x == h })
+			t.handlers.Store(&next)
+			t.mu.Unlock()
+		})
+	}
+}
+
+// publish calls every handler with ev.
+func (t *Topic[T]) publish(ev T) {
+	for _, h := range t.load() {
+		h.fn(ev)
+	}
+}
+
+func (t *Topic[T]) load() []*handler[T] {
+	if p := t.handlers.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// Events returns the estimator's event bus.
+func (e *Estimator) Events() *Bus {
+	return e.events
+}
+
+// subscribeStages connects the estimator's own stages that follow the
+// pipeline to the bus: accuracy scoring, anomaly detection, the pending
+// transaction trigger and block timing.
+func (e *Estimator) subscribeStages() {
+	e.events.BlockArrived.Subscribe(func(ev BlockArrived) {
+		e.provider.scoreBlock(ev.Block)
+	})
+	if e.anomalies != nil {
+		e.events.BlockArrived.Subscribe(func(ev BlockArrived) {
+			e.detectAnomalies(ev.Block)
+		})
+	}
+	if e.recalcTxs > 0 {
+		e.events.MempoolSampled.Subscribe(func(MempoolSampled) {
+			e.countPendingTx()
+		})
+	}
+	if e.onBlockTiming != nil {
+		e.traceBlockTiming(e.onBlockTiming)
+	}
+}
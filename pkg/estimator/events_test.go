@@ -0,0 +1,66 @@
+package estimator
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+func TestTopic_Subscribe(t *testing.T) {
+	var topic Topic[int]
+	var got []int
+	unsubscribe := topic.Subscribe(func(v int) { got = append(got, v) })
+	topic.Subscribe(func(v int) { got = append(got, -v) })
+
+	topic.publish(1)
+	unsubscribe()
+	unsubscribe()
+	topic.publish(2)
+
+	if want := []int{1, -1, -2}; !slices.Equal(got, want) {
+		t.Errorf("handled %v, want %v", got, want)
+	}
+}
+
+func TestEstimator_Events(t *testing.T) {
+	client := &mockBlockReader{
+		chainIDFunc: func(ctx context.Context) (uint64, error) { return 1, nil },
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) {
+			return &eth.Block{Number: 100, BaseFee: uint256.NewInt(1e9)}, nil
+		},
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			return &eth.Block{Number: number.Uint64(), BaseFee: uint256.NewInt(1e9)}, nil
+		},
+	}
+	e := New(client, nil, nil, NewProvider(), WithHistorySize(5))
+	if err := e.Bootstrap(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	e.Events().BlockArrived.Subscribe(func(ev BlockArrived) {
+		got = append(got, "block")
+		if ev.Block.Number != 101 || ev.Arrived.IsZero() {
+			t.Errorf("BlockArrived = %+v", ev)
+		}
+	})
+	e.Events().MempoolSampled.Subscribe(func(ev MempoolSampled) {
+		got = append(got, "tx "+ev.Tx.Hash)
+	})
+	e.Events().EstimateComputed.Subscribe(func(ev EstimateComputed) {
+		got = append(got, "estimate")
+		if ev.Estimate.BlockNumber != 101 || ev.Published.Before(ev.Started) {
+			t.Errorf("EstimateComputed = %+v", ev)
+		}
+	})
+
+	e.IngestPendingTxs(&eth.Transaction{Hash: "0x1", MaxPriorityFeePerGas: uint256.NewInt(1), MaxFeePerGas: uint256.NewInt(2e9), Type: 2})
+	e.IngestBlock(context.Background(), &eth.Block{Number: 101, BaseFee: uint256.NewInt(1e9)})
+
+	if want := []string{"tx 0x1", "block", "estimate"}; !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
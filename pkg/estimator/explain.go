@@ -0,0 +1,123 @@
+package estimator
+
+import (
+	"log/slog"
+
+	"github.com/holiman/uint256"
+)
+
+// Explanation records how a strategy arrived at an estimate's tiers. It is
+// only produced when CalculatorInput.Explain is set (see WithExplain), and
+// only by strategies that support it (HybridStrategy).
+type Explanation struct {
+	// PredictedBaseFee and BaseFeeMultiplier size every tier's MaxFeePerGas.
+	PredictedBaseFee  *uint256.Int
+	BaseFeeMultiplier float64
+
+	// Sample sizes behind the percentiles.
+	HistoricalSamples   int
+	MempoolSamples      int
+	PendingBlockSamples int
+
+	Urgent   TierExplanation
+	Fast     TierExplanation
+	Standard TierExplanation
+	Slow     TierExplanation
+}
+
+// Clamp values of TierExplanation.Clamped.
+const (
+	ClampedMin = "min"
+	ClampedMax = "max"
+)
+
+// TierExplanation records the steps that produced one tier's priority fee.
+// Percentile values are nil when their source had no samples.
+type TierExplanation struct {
+	Percentile float64
+
+	Historical   *uint256.Int
+	Mempool      *uint256.Int // before the pending block is blended in
+	PendingBlock *uint256.Int
+
+	// HistoricalWeight is the weight the historical percentile got in the
+	// blend: the configured weight when both sources had samples, otherwise
+	// 0 or 1.
+	HistoricalWeight float64
+
+	// Default is set when no source had samples and the fee was derived
+	// from the configured bounds.
+	Default bool
+
+	// Blended is the fee before clamping; Clamped names the bound applied,
+	// if any ("min" or "max").
+	Blended *uint256.Int
+	Clamped string
+
+	// Unsmoothed is the fee after clamping and Final the published fee after
+	// smoothing with the previous estimate.
+	Unsmoothed *uint256.Int
+	Final      *uint256.Int
+}
+
+func (x *Explanation) clone() *Explanation {
+	if x == nil {
+		return nil
+	}
+	c := *x
+	c.PredictedBaseFee = cloneInt(x.PredictedBaseFee)
+	c.Urgent = x.Urgent.clone()
+	c.Fast = x.Fast.clone()
+	c.Standard = x.Standard.clone()
+	c.Slow = x.Slow.clone()
+	return &c
+}
+
+func (t TierExplanation) clone() TierExplanation {
+	t.Historical = cloneInt(t.Historical)
+	t.Mempool = cloneInt(t.Mempool)
+	t.PendingBlock = cloneInt(t.PendingBlock)
+	t.Blended = cloneInt(t.Blended)
+	t.Unsmoothed = cloneInt(t.Unsmoothed)
+	t.Final = cloneInt(t.Final)
+	return t
+}
+
+// SmoothingDelta returns Final - Unsmoothed in wei, which is negative when
+// smoothing lowered the fee.
+func (t TierExplanation) SmoothingDelta() float64 {
+	return weiToFloat(t.Final) - weiToFloat(t.Unsmoothed)
+}
+
+// logExplanation logs how each tier of est was derived.
+func (e *Estimator) logExplanation(est *GasEstimate) {
+	// Fractional gwei: tips on L2s are often well below 1 gwei
+	gwei := func(v *uint256.Int) float64 { return weiToFloat(v) / 1e9 }
+	x := est.Explanation
+	tier := func(name string, t TierExplanation) slog.Attr {
+		return slog.Group(name,
+			"percentile", t.Percentile,
+			"historical_gwei", gwei(t.Historical),
+			"mempool_gwei", gwei(t.Mempool),
+			"pending_block_gwei", gwei(t.PendingBlock),
+			"historical_weight", t.HistoricalWeight,
+			"default", t.Default,
+			"blended_gwei", gwei(t.Blended),
+			"clamped", t.Clamped,
+			"smoothing_delta_gwei", t.SmoothingDelta()/1e9,
+			"final_gwei", gwei(t.Final),
+		)
+	}
+	e.logger.Info("estimate explained",
+		"block", est.BlockNumber,
+		"predicted_base_fee_gwei", gwei(x.PredictedBaseFee),
+		"base_fee_multiplier", x.BaseFeeMultiplier,
+		"historical_samples", x.HistoricalSamples,
+		"mempool_samples", x.MempoolSamples,
+		"pending_block_samples", x.PendingBlockSamples,
+		tier("urgent", x.Urgent),
+		tier("fast", x.Fast),
+		tier("standard", x.Standard),
+		tier("slow", x.Slow),
+	)
+}
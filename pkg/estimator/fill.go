@@ -0,0 +1,120 @@
+package estimator
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/holiman/uint256"
+)
+
+// ErrGasAmountTooLarge is returned by ForGasAmount when the requested gas
+// does not fit in a single block.
+var ErrGasAmountTooLarge = errors.New("gas amount exceeds block gas limit")
+
+// DemandPoint is one step of the pending demand curve: Gas is the total gas
+// of sampled transactions paying an effective tip of at least Tip.
+type DemandPoint struct {
+	Tip *uint256.Int
+	Gas uint64
+}
+
+// maxDemandPoints bounds the demand curve kept on each estimate.
+const maxDemandPoints = 64
+
+// tierBlocks are the inclusion horizons, in blocks, of the Urgent, Fast,
+// Standard and Slow tiers.
+var tierBlocks = [4]uint64{1, 3, 6, 12}
+
+// demandCurve orders the includable transactions by effective tip, highest
+// first, and accumulates their gas. Outliers are kept on purpose: a spammer
+// paying an absurd tip still takes block space ahead of everyone else.
+//
+// The curve is thinned to at most maxDemandPoints steps, keeping the last
+// point so the total gas is exact.
+func demandCurve(txs []*TxData, baseFee *uint256.Int) []DemandPoint {
+	type bid struct {
+		tip *uint256.Int
+		gas uint64
+	}
+	bids := make([]bid, 0, len(txs))
+	arena := newFeeArena(len(txs))
+	for _, tx := range txs {
+		if tx.Gas == 0 || !tx.Includable(baseFee) {
+			continue
+		}
+		bids = append(bids, bid{tip: tx.effectivePriorityFee(arena.next(), baseFee), gas: tx.Gas})
+	}
+	if len(bids) == 0 {
+		return nil
+	}
+	slices.SortFunc(bids, func(a, b bid) int { return b.tip.Cmp(a.tip) })
+
+	points := make([]DemandPoint, 0, len(bids))
+	var total uint64
+	for _, b := range bids {
+		total += b.gas
+		if n := len(points); n > 0 && points[n-1].Tip.Eq(b.tip) {
+			points[n-1].Gas = total
+			continue
+		}
+		points = append(points, DemandPoint{Tip: b.tip, Gas: total})
+	}
+
+	if len(points) <= maxDemandPoints {
+		return points
+	}
+	thinned := make([]DemandPoint, 0, maxDemandPoints)
+	for i := 1; i <= maxDemandPoints; i++ {
+		thinned = append(thinned, points[i*len(points)/maxDemandPoints-1])
+	}
+	return thinned
+}
+
+// ForGasAmount returns a copy of the estimate with tier tips raised so a
+// transaction using gas would fit: for a tier expected within n blocks, it
+// must outbid enough pending demand that the gas ahead of it plus its own
+// stays within n block gas limits. Tiers already paying enough are kept.
+//
+// The model treats the mempool sample as the competing demand, so it only
+// reacts once the sample holds more gas than the tier's horizon can fit.
+// Returns a plain copy when the estimate has no demand curve.
+func (e *GasEstimate) ForGasAmount(gas uint64) (*GasEstimate, error) {
+	if e.BlockGasLimit > 0 && gas > e.BlockGasLimit {
+		return nil, ErrGasAmountTooLarge
+	}
+
+	c := *e
+	if len(e.Demand) == 0 || e.BlockGasLimit == 0 {
+		return &c, nil
+	}
+
+	for i, p := range []*PriorityEstimate{&c.Urgent, &c.Fast, &c.Standard, &c.Slow} {
+		capacity := tierBlocks[i] * e.BlockGasLimit
+		required := e.requiredTip(capacity - gas)
+		if required == nil || p.MaxPriorityFeePerGas == nil || !required.Gt(p.MaxPriorityFeePerGas) {
+			continue
+		}
+		// The signature no longer matches the raised tiers
+		c.Signature = nil
+		raise := new(uint256.Int).Sub(required, p.MaxPriorityFeePerGas)
+		*p = PriorityEstimate{
+			MaxPriorityFeePerGas: required,
+			MaxFeePerGas:         new(uint256.Int).Add(p.MaxFeePerGas, raise),
+			Confidence:           p.Confidence,
+			Sources:              withSources(p.Sources, FeeSourceMempool),
+		}
+	}
+	return &c, nil
+}
+
+// requiredTip returns the tip of the first pending step whose cumulative gas
+// exceeds room, i.e. the bid that must be matched to get ahead of it, or nil
+// if all sampled demand fits.
+func (e *GasEstimate) requiredTip(room uint64) *uint256.Int {
+	for _, d := range e.Demand {
+		if d.Gas > room {
+			return d.Tip
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package estimator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestDemandCurve(t *testing.T) {
+	baseFee := uint256.NewInt(10e9)
+	tx := func(tip, gas uint64) *TxData {
+		return &TxData{
+			IsEIP1559:            true,
+			MaxPriorityFeePerGas: uint256.NewInt(tip * 1e9),
+			MaxFeePerGas:         uint256.NewInt(100e9),
+			Gas:                  gas,
+		}
+	}
+	txs := []*TxData{
+		tx(1, 21000),
+		tx(5, 1e6),
+		tx(3, 500000),
+		tx(5, 2e6),
+		// Can't pay the base fee
+		{IsEIP1559: true, MaxPriorityFeePerGas: uint256.NewInt(50e9), MaxFeePerGas: uint256.NewInt(5e9), Gas: 1e6},
+	}
+
+	got := demandCurve(txs, baseFee)
+	want := []DemandPoint{
+		{Tip: uint256.NewInt(5e9), Gas: 3e6},
+		{Tip: uint256.NewInt(3e9), Gas: 3.5e6},
+		{Tip: uint256.NewInt(1e9), Gas: 3521000},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("len = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Tip.Eq(want[i].Tip) || got[i].Gas != want[i].Gas {
+			t.Errorf("point %d = {%s, %d}, want {%s, %d}", i, got[i].Tip, got[i].Gas, want[i].Tip, want[i].Gas)
+		}
+	}
+
+	var many []*TxData
+	for i := uint64(1); i <= 200; i++ {
+		many = append(many, tx(i, 21000))
+	}
+	thinned := demandCurve(many, baseFee)
+	if len(thinned) != maxDemandPoints {
+		t.Fatalf("thinned len = %d, want %d", len(thinned), maxDemandPoints)
+	}
+	if last := thinned[len(thinned)-1]; last.Gas != 200*21000 {
+		t.Errorf("total gas = %d, want %d", last.Gas, 200*21000)
+	}
+}
+
+func TestGasEstimate_ForGasAmount(t *testing.T) {
+	gwei := func(v uint64) *uint256.Int { return uint256.NewInt(v * 1e9) }
+	tier := func(tip uint64) PriorityEstimate {
+		return PriorityEstimate{MaxPriorityFeePerGas: gwei(tip), MaxFeePerGas: gwei(20 + tip)}
+	}
+	est := &GasEstimate{
+		BaseFee:       gwei(10),
+		BlockGasLimit: 10e6,
+		Urgent:        tier(4),
+		Fast:          tier(3),
+		Standard:      tier(2),
+		Slow:          tier(1),
+		// 8M gas at >= 10 gwei, 25M at >= 6 gwei, 65M at >= 2 gwei
+		Demand: []DemandPoint{
+			{Tip: gwei(10), Gas: 8e6},
+			{Tip: gwei(6), Gas: 25e6},
+			{Tip: gwei(2), Gas: 65e6},
+		},
+	}
+
+	tests := []struct {
+		name string
+		gas  uint64
+		want [4]uint64 // urgent, fast, standard, slow tips in gwei
+	}{
+		// Urgent room is 9.98M: the 10 gwei step fits, the 6 gwei one doesn't.
+		// Other tiers already pay enough.
+		{"transfer", 21000, [4]uint64{6, 3, 2, 1}},
+		// Urgent room is 7M, so even the top step must be matched
+		{"medium", 3e6, [4]uint64{10, 3, 2, 1}},
+		// Fast room is 21M, exceeded by the 6 gwei step
+		{"large", 9e6, [4]uint64{10, 6, 2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := est.ForGasAmount(tt.gas)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tiers := [4]PriorityEstimate{got.Urgent, got.Fast, got.Standard, got.Slow}
+			for i, p := range tiers {
+				if !p.MaxPriorityFeePerGas.Eq(gwei(tt.want[i])) {
+					t.Errorf("tier %d tip = %s, want %d gwei", i, p.MaxPriorityFeePerGas, tt.want[i])
+				}
+				// The raise carries over to maxFee
+				want := new(uint256.Int).Add(gwei(20), p.MaxPriorityFeePerGas)
+				if !p.MaxFeePerGas.Eq(want) {
+					t.Errorf("tier %d maxFee = %s, want %s", i, p.MaxFeePerGas, want)
+				}
+			}
+		})
+	}
+
+	// The original estimate is untouched
+	if !est.Urgent.MaxPriorityFeePerGas.Eq(gwei(4)) {
+		t.Errorf("original urgent tip changed to %s", est.Urgent.MaxPriorityFeePerGas)
+	}
+
+	if _, err := est.ForGasAmount(11e6); !errors.Is(err, ErrGasAmountTooLarge) {
+		t.Errorf("err = %v, want ErrGasAmountTooLarge", err)
+	}
+}
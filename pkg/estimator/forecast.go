@@ -0,0 +1,166 @@
+package estimator
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/holiman/uint256"
+)
+
+// ForecastReader predicts fee levels over the coming hours.
+// Implemented by Provider; used by the forecast API.
+type ForecastReader interface {
+	Forecast(hours int) (Forecast, error)
+}
+
+// Forecast predicts the base fee and standard priority fee for each of the
+// coming hours.
+//
+// Predictions combine the current estimate with a seasonal profile of the
+// final estimate of every block, bucketed by hour of week (UTC). An hour's
+// prediction is its seasonal level plus the current deviation from the
+// seasonal level of the present hour, which halves every
+// forecastDeviationHalfLife, so near hours follow current conditions and
+// later hours the usual pattern. Hours without enough seasonal samples
+// repeat the current level.
+type Forecast struct {
+	Generated time.Time
+	Hours     []ForecastHour
+
+	// Cheapest is the index in Hours with the lowest base plus priority fee.
+	Cheapest int
+}
+
+// ForecastHour is the prediction for one hour, starting at Start.
+type ForecastHour struct {
+	Start       time.Time
+	BaseFee     *uint256.Int
+	PriorityFee *uint256.Int // standard tier
+
+	// Samples is the number of blocks behind the hour's seasonal level;
+	// 0 when the prediction only repeats the current level.
+	Samples int
+}
+
+// TotalFee returns BaseFee + PriorityFee.
+func (h ForecastHour) TotalFee() *uint256.Int {
+	return new(uint256.Int).Add(h.BaseFee, h.PriorityFee)
+}
+
+// MaxForecastHours bounds Forecast to one week ahead.
+const MaxForecastHours = 7 * 24
+
+const (
+	// seasonalMaxWeight caps the sample count a bucket's running mean is
+	// weighted by, so old weeks fade once a bucket has seen this many
+	// blocks (about seven weeks of that hour at 12s blocks).
+	seasonalMaxWeight = 2000
+
+	// seasonalMinSamples is the sample count below which a bucket is ignored.
+	seasonalMinSamples = 10
+
+	forecastDeviationHalfLife = 2 * time.Hour
+)
+
+// seasonalBucket is the running mean of fees for one hour of the week.
+type seasonalBucket struct {
+	baseFee     float64
+	priorityFee float64
+	samples     int
+}
+
+// seasonalProfile learns fee levels by hour of week.
+type seasonalProfile struct {
+	mu      sync.RWMutex
+	buckets [MaxForecastHours]seasonalBucket
+}
+
+func hourOfWeek(t time.Time) int {
+	t = t.UTC()
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// add folds the final estimate of a block into its hour's bucket.
+func (p *seasonalProfile) add(est *GasEstimate) {
+	if est == nil || est.BaseFee == nil || est.Standard.MaxPriorityFeePerGas == nil {
+		return
+	}
+	baseFee := est.BaseFee.Float64()
+	priorityFee := est.Standard.MaxPriorityFeePerGas.Float64()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	b := &p.buckets[hourOfWeek(est.Timestamp)]
+	if b.samples < seasonalMaxWeight {
+		b.samples++
+	}
+	n := float64(b.samples)
+	b.baseFee += (baseFee - b.baseFee) / n
+	b.priorityFee += (priorityFee - b.priorityFee) / n
+}
+
+// forecast predicts hours hours starting with the one containing now, from
+// the current estimate.
+func (p *seasonalProfile) forecast(current *GasEstimate, hours int, now time.Time) Forecast {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	baseNow := weiToFloat(current.BaseFee)
+	priorityNow := weiToFloat(current.Standard.MaxPriorityFeePerGas)
+
+	// Current deviation from the present hour's seasonal level
+	var baseDev, priorityDev float64
+	if b := p.buckets[hourOfWeek(now)]; b.samples >= seasonalMinSamples {
+		baseDev = baseNow - b.baseFee
+		priorityDev = priorityNow - b.priorityFee
+	}
+
+	f := Forecast{Generated: now, Hours: make([]ForecastHour, hours)}
+	start := now.Truncate(time.Hour)
+	var cheapest *uint256.Int
+	for i := range f.Hours {
+		at := start.Add(time.Duration(i) * time.Hour)
+		h := ForecastHour{Start: at}
+
+		b := p.buckets[hourOfWeek(at)]
+		if b.samples >= seasonalMinSamples {
+			// Hours ahead of now, measured to the middle of the hour
+			ahead := max(at.Add(30*time.Minute).Sub(now), 0)
+			decay := math.Exp2(-ahead.Hours() / forecastDeviationHalfLife.Hours())
+			h.BaseFee = weiFromFloat(b.baseFee + baseDev*decay)
+			h.PriorityFee = weiFromFloat(b.priorityFee + priorityDev*decay)
+			h.Samples = b.samples
+		} else {
+			h.BaseFee = weiFromFloat(baseNow)
+			h.PriorityFee = weiFromFloat(priorityNow)
+		}
+
+		if total := h.TotalFee(); cheapest == nil || total.Lt(cheapest) {
+			cheapest = total
+			f.Cheapest = i
+		}
+		f.Hours[i] = h
+	}
+	return f
+}
+
+// weiToFloat converts a wei amount to float64; nil is 0.
+func weiToFloat(v *uint256.Int) float64 {
+	if v == nil {
+		return 0
+	}
+	return v.Float64()
+}
+
+// weiFromFloat converts a non-negative wei amount to a uint256, rounding.
+func weiFromFloat(v float64) *uint256.Int {
+	if v <= 0 {
+		return new(uint256.Int)
+	}
+	if v >= math.MaxUint64 {
+		return uint256.NewInt(math.MaxUint64)
+	}
+	return uint256.NewInt(uint64(math.Round(v)))
+}
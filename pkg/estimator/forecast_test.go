@@ -0,0 +1,45 @@
+package estimator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+)
+
+func TestSeasonalProfile_Forecast(t *testing.T) {
+	gwei := func(v uint64) *uint256.Int { return uint256.NewInt(v * 1e9) }
+	est := func(at time.Time, baseFee, tip uint64) *GasEstimate {
+		return &GasEstimate{Timestamp: at, BaseFee: gwei(baseFee), Standard: PriorityEstimate{MaxPriorityFeePerGas: gwei(tip)}}
+	}
+
+	// Monday 10:00 UTC is busy (40 gwei), 14:00 quiet (10 gwei)
+	monday := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var p seasonalProfile
+	for i := 0; i < 20; i++ {
+		p.add(est(monday.Add(10*time.Hour+time.Duration(i)*time.Minute), 40, 2))
+		p.add(est(monday.Add(14*time.Hour+time.Duration(i)*time.Minute), 10, 1))
+	}
+
+	// A week later at 10:00, fees are 10 gwei above the usual level
+	now := monday.Add(7*24*time.Hour + 10*time.Hour)
+	f := p.forecast(est(now, 50, 2), 6, now)
+
+	if len(f.Hours) != 6 || !f.Hours[0].Start.Equal(now) {
+		t.Fatalf("forecast hours = %d starting %v", len(f.Hours), f.Hours[0].Start)
+	}
+	// The deviation decays with a 2h half-life, measured to mid-hour
+	if got := f.Hours[0].BaseFee.Uint64(); got < 48e9 || got > 49e9 {
+		t.Errorf("10:00 base fee = %d, want ~48.4 gwei", got)
+	}
+	if got := f.Hours[4].BaseFee.Uint64(); got < 12e9 || got > 13e9 {
+		t.Errorf("14:00 base fee = %d, want ~12.1 gwei", got)
+	}
+	// Hours without seasonal data repeat the current level
+	if h := f.Hours[1]; h.Samples != 0 || h.BaseFee.Uint64() != 50e9 {
+		t.Errorf("11:00 = %d gwei from %d samples, want current 50 gwei", h.BaseFee.Uint64()/1e9, h.Samples)
+	}
+	if f.Cheapest != 4 {
+		t.Errorf("Cheapest = %d, want 4 (14:00)", f.Cheapest)
+	}
+}
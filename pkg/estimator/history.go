@@ -28,12 +28,26 @@ func NewHistory(size int) *History {
 	}
 }
 
-// Push adds a block to the history.
-// If the buffer is full, the oldest block is overwritten.
+// Push adds a new head block to the history. A head that is not newer than
+// the stored ones comes from a reorg: the blocks it replaces, at its number
+// and above, belong to the abandoned fork and are dropped. If the buffer is
+// full, the oldest block is overwritten.
 func (h *History) Push(block *BlockData) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	if h.count > 0 && block.Number <= h.blocks[h.index(0)].Number {
+		i := 0
+		for i < h.count && h.blocks[h.index(i)].Number >= block.Number {
+			i++
+		}
+		kept := make([]*BlockData, 0, h.count-i)
+		for j := i; j < h.count; j++ {
+			kept = append(kept, h.blocks[h.index(j)])
+		}
+		h.rewrite(kept)
+	}
+
 	h.blocks[h.head] = block
 	h.head = (h.head + 1) % h.size
 	if h.count < h.size {
@@ -41,6 +55,81 @@ func (h *History) Push(block *BlockData) {
 	}
 }
 
+// Insert adds a block fetched behind the head, such as a backfilled one, in
+// block number order. A block with the number of one already stored
+// replaces it. If the buffer is full, a block older than all stored ones is
+// dropped.
+func (h *History) Insert(block *BlockData) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Find the first stored block, newest first, not newer than block
+	i := 0
+	for i < h.count && h.blocks[h.index(i)].Number > block.Number {
+		i++
+	}
+	if i < h.count && h.blocks[h.index(i)].Number == block.Number {
+		h.blocks[h.index(i)] = block
+		return
+	}
+	if i == h.count && h.count == h.size {
+		return
+	}
+
+	ordered := make([]*BlockData, 0, h.count+1)
+	for j := 0; j < h.count; j++ {
+		if j == i {
+			ordered = append(ordered, block)
+		}
+		ordered = append(ordered, h.blocks[h.index(j)])
+	}
+	if i == h.count {
+		ordered = append(ordered, block)
+	}
+	h.rewrite(ordered[:min(len(ordered), h.size)])
+}
+
+// index returns the buffer position of the i-th newest block.
+func (h *History) index(i int) int {
+	return (h.head - 1 - i + 2*h.size) % h.size
+}
+
+// rewrite replaces the contents with blocks, newest first.
+func (h *History) rewrite(blocks []*BlockData) {
+	clear(h.blocks)
+	n := len(blocks)
+	for i, b := range blocks {
+		h.blocks[n-1-i] = b
+	}
+	h.head = n % h.size
+	h.count = n
+}
+
+// Gaps returns the block numbers missing between the stored blocks, oldest
+// first, limited to the window of Cap blocks ending at the newest one.
+func (h *History) Gaps() []uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.count == 0 {
+		return nil
+	}
+	newest := h.blocks[h.index(0)].Number
+	var floor uint64
+	if newest >= uint64(h.size) {
+		floor = newest - uint64(h.size) + 1
+	}
+
+	var gaps []uint64
+	for i := h.count - 1; i > 0; i-- {
+		older, newer := h.blocks[h.index(i)].Number, h.blocks[h.index(i-1)].Number
+		for n := max(older+1, floor); n < newer; n++ {
+			gaps = append(gaps, n)
+		}
+	}
+	return gaps
+}
+
 // Latest returns the most recently added block, or nil if empty.
 func (h *History) Latest() *BlockData {
 	h.mu.RLock()
@@ -50,8 +139,7 @@ func (h *History) Latest() *BlockData {
 		return nil
 	}
 
-	idx := (h.head - 1 + h.size) % h.size
-	return h.blocks[idx]
+	return h.blocks[h.index(0)]
 }
 
 // Snapshot returns a copy of all stored blocks, newest first.
@@ -62,9 +150,7 @@ func (h *History) Snapshot() []*BlockData {
 
 	result := make([]*BlockData, h.count)
 	for i := 0; i < h.count; i++ {
-		// Walk backwards from head
-		idx := (h.head - 1 - i + h.size) % h.size
-		result[i] = h.blocks[idx]
+		result[i] = h.blocks[h.index(i)]
 	}
 	return result
 }
@@ -1,6 +1,7 @@
 package estimator
 
 import (
+	"slices"
 	"testing"
 )
 
@@ -63,3 +64,92 @@ func TestHistory(t *testing.T) {
 		t.Errorf("snap[2] = %d, want 2", snap[2].Number)
 	}
 }
+
+// historyNumbers returns the block numbers in h, newest first.
+func historyNumbers(h *History) []uint64 {
+	var out []uint64
+	for _, b := range h.Snapshot() {
+		out = append(out, b.Number)
+	}
+	return out
+}
+
+func TestHistory_Order(t *testing.T) {
+	numbers := historyNumbers
+
+	h := NewHistory(4)
+	h.Push(&BlockData{Number: 10})
+	h.Push(&BlockData{Number: 13})
+	h.Insert(&BlockData{Number: 11})
+	if got := numbers(h); !slices.Equal(got, []uint64{13, 11, 10}) {
+		t.Errorf("after late block = %v, want [13 11 10]", got)
+	}
+	if gaps := h.Gaps(); !slices.Equal(gaps, []uint64{12}) {
+		t.Errorf("Gaps() = %v, want [12]", gaps)
+	}
+
+	// A replacement block takes its number's place
+	replacement := &BlockData{Number: 11, GasUsed: 1}
+	h.Insert(replacement)
+	if h.Snapshot()[1] != replacement || h.Len() != 3 {
+		t.Errorf("replacement not stored in place: %v", numbers(h))
+	}
+
+	// Filling the gap fills the history; older blocks then fall out
+	h.Insert(&BlockData{Number: 12})
+	h.Insert(&BlockData{Number: 9})
+	if got := numbers(h); !slices.Equal(got, []uint64{13, 12, 11, 10}) {
+		t.Errorf("full history = %v, want [13 12 11 10]", got)
+	}
+	h.Push(&BlockData{Number: 14})
+	if got := numbers(h); !slices.Equal(got, []uint64{14, 13, 12, 11}) || h.Gaps() != nil {
+		t.Errorf("after new head = %v (gaps %v), want [14 13 12 11]", got, h.Gaps())
+	}
+
+	// Gaps older than the window are not reported
+	h.Push(&BlockData{Number: 20})
+	if gaps := h.Gaps(); !slices.Equal(gaps, []uint64{17, 18, 19}) {
+		t.Errorf("Gaps() after jump = %v, want [17 18 19]", gaps)
+	}
+}
+
+func TestHistory_Reorg(t *testing.T) {
+	h := NewHistory(8)
+	for n := uint64(10); n <= 14; n++ {
+		h.Push(&BlockData{Number: n})
+	}
+
+	// The new fork is shorter: its head replaces 12 and 13 and 14 are gone
+	head := &BlockData{Number: 12, GasUsed: 1}
+	h.Push(head)
+	if got := historyNumbers(h); !slices.Equal(got, []uint64{12, 11, 10}) {
+		t.Errorf("after reorg to a shorter chain = %v, want [12 11 10]", got)
+	}
+	if h.Latest() != head {
+		t.Errorf("Latest() = %+v, want the new fork's head", h.Latest())
+	}
+	if gaps := h.Gaps(); gaps != nil {
+		t.Errorf("Gaps() = %v, want none", gaps)
+	}
+
+	// A reorg of the head alone replaces it
+	replacement := &BlockData{Number: 12, GasUsed: 2}
+	h.Push(replacement)
+	if got := historyNumbers(h); !slices.Equal(got, []uint64{12, 11, 10}) || h.Latest() != replacement {
+		t.Errorf("after head reorg = %v, latest %+v", got, h.Latest())
+	}
+
+	// A head below every stored block replaces them all
+	h.Push(&BlockData{Number: 5})
+	if got := historyNumbers(h); !slices.Equal(got, []uint64{5}) {
+		t.Errorf("after deep reorg = %v, want [5]", got)
+	}
+
+	// The new fork grows from there, wrapping around the buffer
+	for n := uint64(6); n <= 20; n++ {
+		h.Push(&BlockData{Number: n})
+	}
+	if got := historyNumbers(h); !slices.Equal(got, []uint64{20, 19, 18, 17, 16, 15, 14, 13}) {
+		t.Errorf("after growth = %v", got)
+	}
+}
@@ -0,0 +1,152 @@
+package estimator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/holiman/uint256"
+)
+
+// ErrBlockTimeUnknown is returned by TierWithin when the estimate's network
+// has no known block time to convert a wait into blocks.
+var ErrBlockTimeUnknown = errors.New("block time unknown")
+
+// TierNames are the names of the estimate tiers, fastest first.
+var TierNames = [4]string{"urgent", "fast", "standard", "slow"}
+
+// Tier returns the named tier ("urgent", "fast", "standard" or "slow");
+// ok is false for an unknown name.
+func (e *GasEstimate) Tier(name string) (p PriorityEstimate, ok bool) {
+	switch name {
+	case "urgent":
+		return e.Urgent, true
+	case "fast":
+		return e.Fast, true
+	case "standard":
+		return e.Standard, true
+	case "slow":
+		return e.Slow, true
+	}
+	return PriorityEstimate{}, false
+}
+
+// TierWithin returns the name of the cheapest tier expected to be included
+// within wait, from the tiers' inclusion horizons (1, 3, 6 and 12 blocks)
+// and the network's block time. Waits shorter than one block get "urgent",
+// the best the estimate offers.
+func (e *GasEstimate) TierWithin(wait time.Duration) (string, error) {
+	blockTime := e.Network.BlockTime
+	if blockTime <= 0 {
+		return "", ErrBlockTimeUnknown
+	}
+	name := TierNames[0]
+	for i, blocks := range tierBlocks {
+		if time.Duration(blocks)*blockTime > wait {
+			break
+		}
+		name = TierNames[i]
+	}
+	return name, nil
+}
+
+// ErrInclusionUnknown is returned by ForDeadline when the estimate has no
+// inclusion statistics, because no recent block had fee-paying
+// transactions.
+var ErrInclusionUnknown = errors.New("no recent inclusion statistics")
+
+// DefaultDeadlineConfidence is the probability of inclusion ForDeadline
+// targets when given none.
+const DefaultDeadlineConfidence = 0.9
+
+// DeadlineEstimate is a fee recommendation for inclusion within a number
+// of blocks.
+type DeadlineEstimate struct {
+	PriorityEstimate
+
+	// Blocks is the deadline; Confidence is the targeted probability of
+	// inclusion by then.
+	Blocks int
+
+	// Percentile is the per-block inclusion probability the fee was read
+	// at: the fraction of recent blocks whose cheapest included tip was at
+	// most MaxPriorityFeePerGas.
+	Percentile float64
+}
+
+// BlocksWithin returns how many blocks are expected within wait, at least
+// one, from the network's block time.
+func (e *GasEstimate) BlocksWithin(wait time.Duration) (int, error) {
+	blockTime := e.Network.BlockTime
+	if blockTime <= 0 {
+		return 0, ErrBlockTimeUnknown
+	}
+	return max(int(wait/blockTime), 1), nil
+}
+
+// ForDeadline returns the priority fee expected to be included within
+// blocks blocks with probability confidence, from the estimate's Inclusion
+// curve. Treating blocks as independent draws, a tip clearing a fraction p
+// of blocks is included within n of them with probability 1-(1-p)^n, so
+// the fee is read at p = 1-(1-confidence)^(1/n). MaxFeePerGas carries the
+// same base fee buffer as the tiers.
+func (e *GasEstimate) ForDeadline(blocks int, confidence float64) (DeadlineEstimate, error) {
+	if blocks < 1 {
+		return DeadlineEstimate{}, fmt.Errorf("deadline must be at least one block, got %d", blocks)
+	}
+	if confidence <= 0 || confidence >= 1 {
+		return DeadlineEstimate{}, fmt.Errorf("confidence must be between 0 and 1, got %v", confidence)
+	}
+	if len(e.Inclusion) == 0 {
+		return DeadlineEstimate{}, ErrInclusionUnknown
+	}
+
+	p := 1 - math.Pow(1-confidence, 1/float64(blocks))
+	fee := curveAt(e.Inclusion, p)
+
+	// The tiers share one buffered base fee; reuse it
+	buffered := scaleFee(e.BaseFee, 2)
+	if s := e.Standard; s.MaxFeePerGas != nil && s.MaxPriorityFeePerGas != nil && !s.MaxFeePerGas.Lt(s.MaxPriorityFeePerGas) {
+		buffered = new(uint256.Int).Sub(s.MaxFeePerGas, s.MaxPriorityFeePerGas)
+	}
+	return DeadlineEstimate{
+		PriorityEstimate: PriorityEstimate{
+			MaxPriorityFeePerGas: fee,
+			MaxFeePerGas:         new(uint256.Int).Add(buffered, fee),
+			Confidence:           confidence,
+			Sources:              []FeeSource{FeeSourceHistory},
+		},
+		Blocks:     blocks,
+		Percentile: p,
+	}, nil
+}
+
+// curveAt interpolates a DistributionStep-spaced percentile curve at p.
+func curveAt(curve []*uint256.Int, p float64) *uint256.Int {
+	pos := p * float64(len(curve)-1)
+	i := int(pos)
+	if i >= len(curve)-1 {
+		return new(uint256.Int).Set(curve[len(curve)-1])
+	}
+	lo, hi := curve[i], curve[i+1]
+	frac := uint64(math.Round((pos - float64(i)) * 10000))
+	// lo + (hi-lo)*frac, with hi >= lo on a sorted curve
+	out := new(uint256.Int).Sub(hi, lo)
+	out.Mul(out, uint256.NewInt(frac))
+	out.Div(out, uint256.NewInt(10000))
+	return out.Add(out, lo)
+}
+
+// inclusionCurve returns the percentile curve of the cheapest priority fee
+// included in each of blocks, over blocks with fee-paying transactions;
+// nil if there are none.
+func inclusionCurve(blocks []*BlockData) []*uint256.Int {
+	var minFees []*uint256.Int
+	for _, b := range blocks {
+		if len(b.PriorityFees) > 0 {
+			minFees = append(minFees, minPriorityFee(b.PriorityFees))
+		}
+	}
+	return curve(newFeeSample(minFees, nil))
+}
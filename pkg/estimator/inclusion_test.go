@@ -0,0 +1,109 @@
+package estimator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+)
+
+func TestGasEstimate_TierWithin(t *testing.T) {
+	est := &GasEstimate{Network: Network{BlockTime: 12 * time.Second}}
+	tests := []struct {
+		wait time.Duration
+		want string
+	}{
+		{time.Second, "urgent"},
+		{12 * time.Second, "urgent"},
+		{36 * time.Second, "fast"},
+		{time.Minute, "fast"},
+		{72 * time.Second, "standard"},
+		{time.Hour, "slow"},
+	}
+	for _, tt := range tests {
+		got, err := est.TierWithin(tt.wait)
+		if err != nil || got != tt.want {
+			t.Errorf("TierWithin(%v) = %q, %v, want %q", tt.wait, got, err, tt.want)
+		}
+	}
+
+	if _, err := (&GasEstimate{}).TierWithin(time.Minute); !errors.Is(err, ErrBlockTimeUnknown) {
+		t.Errorf("TierWithin without a block time: err = %v, want ErrBlockTimeUnknown", err)
+	}
+}
+
+func TestGasEstimate_Tier(t *testing.T) {
+	est := &GasEstimate{Fast: PriorityEstimate{MaxPriorityFeePerGas: uint256.NewInt(7)}}
+	if p, ok := est.Tier("fast"); !ok || p.MaxPriorityFeePerGas.Uint64() != 7 {
+		t.Errorf("Tier(fast) = %v, %v", p, ok)
+	}
+	if _, ok := est.Tier("instant"); ok {
+		t.Error("Tier(instant) found an unknown tier")
+	}
+}
+
+func TestGasEstimate_ForDeadline(t *testing.T) {
+	// 21 blocks whose cheapest tips were 0, 1, ..., 20 gwei: a tip of k gwei
+	// made it into k/20 of them
+	var blocks []*BlockData
+	for i := 20; i >= 0; i-- {
+		blocks = append(blocks, &BlockData{PriorityFees: []*uint256.Int{
+			uint256.NewInt(uint64(i) * 1e9), uint256.NewInt(uint64(i)*1e9 + 5e9),
+		}})
+	}
+	blocks = append(blocks, &BlockData{}) // not scored
+	est := &GasEstimate{
+		BaseFee:   uint256.NewInt(10e9),
+		Standard:  PriorityEstimate{MaxPriorityFeePerGas: uint256.NewInt(1e9), MaxFeePerGas: uint256.NewInt(26e9)},
+		Inclusion: inclusionCurve(blocks),
+		Network:   Network{BlockTime: 12 * time.Second},
+	}
+
+	// Within one block, 90% confidence needs the 90th percentile
+	got, err := est.ForDeadline(1, 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.MaxPriorityFeePerGas.Uint64() != 18e9 || got.MaxFeePerGas.Uint64() != 43e9 || got.Percentile != 0.9 || got.Confidence != 0.9 {
+		t.Errorf("ForDeadline(1, 0.9) = %+v", got)
+	}
+
+	// Within two blocks, each only needs 1-sqrt(0.1) ≈ 68.4%
+	got, err = est.ForDeadline(2, 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee := got.MaxPriorityFeePerGas.Uint64(); fee < 13.6e9 || fee > 13.7e9 || got.Blocks != 2 {
+		t.Errorf("ForDeadline(2, 0.9) fee = %d, want ~13.68 gwei", fee)
+	}
+
+	// Longer deadlines are never more expensive
+	prev := got.MaxPriorityFeePerGas
+	for n := 3; n <= 50; n++ {
+		got, _ := est.ForDeadline(n, 0.9)
+		if got.MaxPriorityFeePerGas.Gt(prev) {
+			t.Errorf("ForDeadline(%d) = %v, above %v for fewer blocks", n, got.MaxPriorityFeePerGas, prev)
+		}
+		prev = got.MaxPriorityFeePerGas
+	}
+
+	if n, err := est.BlocksWithin(30 * time.Second); n != 2 || err != nil {
+		t.Errorf("BlocksWithin(30s) = %d, %v, want 2", n, err)
+	}
+	if n, _ := est.BlocksWithin(time.Second); n != 1 {
+		t.Errorf("BlocksWithin(1s) = %d, want 1", n)
+	}
+
+	for _, tt := range []struct {
+		blocks     int
+		confidence float64
+	}{{0, 0.9}, {1, 0}, {1, 1}} {
+		if _, err := est.ForDeadline(tt.blocks, tt.confidence); err == nil {
+			t.Errorf("ForDeadline(%d, %v) succeeded", tt.blocks, tt.confidence)
+		}
+	}
+	if _, err := (&GasEstimate{}).ForDeadline(1, 0.9); !errors.Is(err, ErrInclusionUnknown) {
+		t.Errorf("ForDeadline without inclusion data: err = %v, want ErrInclusionUnknown", err)
+	}
+}
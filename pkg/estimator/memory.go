@@ -0,0 +1,78 @@
+package estimator
+
+const (
+	// txDataBytes approximates the heap held by one sampled transaction: the
+	// TxData struct (80 bytes), its hash and sender strings (80 and 48
+	// byte allocations), two fee values and the sampler's slot.
+	txDataBytes = 280
+
+	// feeSampleBytes approximates the heap held by one priority fee in the
+	// history: the fee value, its slot and its gas.
+	feeSampleBytes = 48
+)
+
+// WithMempoolMaxBytes caps the memory held by the mempool sample at about
+// n bytes, shrinking the sample if needed. The reservoir policy retains two
+// windows' samples and the stratified policy a sample per fee band, so
+// they are shrunk further. Zero, the default, leaves the sample at its
+// configured size. Together with WithBlockFeeSamples, which bounds the
+// history, this bounds the estimator's memory on busy chains.
+func WithMempoolMaxBytes(n int) Option {
+	return func(e *Estimator) {
+		e.mempoolBytes = n
+	}
+}
+
+// MemoryStats reports the approximate memory held by the estimator's
+// history and mempool sample, and how much data was dropped to keep it
+// bounded, for sizing deployments.
+type MemoryStats struct {
+	// HistoryFeeSamples is the number of priority fees in the history and
+	// HistoryBytes their approximate size.
+	HistoryFeeSamples int
+	HistoryBytes      int
+
+	// HistoryFeesEvicted is the total number of priority fees dropped by
+	// thinning blocks to WithBlockFeeSamples.
+	HistoryFeesEvicted uint64
+
+	// MempoolSamples is the number of transactions in the current sample
+	// and MempoolBytes its approximate size.
+	MempoolSamples int
+	MempoolBytes   int
+
+	// MempoolEvicted is the total number of sampled transactions dropped to
+	// make room for newer ones.
+	MempoolEvicted uint64
+}
+
+// MemoryStats returns the estimator's current memory use.
+func (e *Estimator) MemoryStats() MemoryStats {
+	return e.memoryStats(e.state.snapshot())
+}
+
+// memoryStats computes MemoryStats from a chain state snapshot.
+func (e *Estimator) memoryStats(blocks []*BlockData, pending []*TxData) MemoryStats {
+	stats := MemoryStats{
+		HistoryFeesEvicted: e.feesEvicted.Load(),
+		MempoolSamples:     len(pending),
+		MempoolBytes:       len(pending) * txDataBytes,
+	}
+	for _, b := range blocks {
+		stats.HistoryFeeSamples += len(b.PriorityFees)
+	}
+	stats.HistoryBytes = stats.HistoryFeeSamples * feeSampleBytes
+	if c, ok := e.state.pool.(evictionCounter); ok {
+		stats.MempoolEvicted = c.Evicted()
+	}
+	return stats
+}
+
+// newSampler creates the mempool sampler, sized to fit WithMempoolMaxBytes.
+func (e *Estimator) newSampler() TxSampler {
+	var retained int
+	if e.mempoolBytes > 0 {
+		retained = max(e.mempoolBytes/txDataBytes, 1)
+	}
+	return newBoundedSampler(e.samplingPolicy, e.mempoolSamples*2, retained, e.samplingWindow, e.clock)
+}
@@ -0,0 +1,71 @@
+package estimator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+func TestBoundedSampler(t *testing.T) {
+	clock := &manualClock{now: time.Unix(1000, 0)}
+	for _, tc := range []struct {
+		policy   SamplingPolicy
+		retained int
+		want     int
+	}{
+		{SampleMostRecent, 10, 10},
+		{SampleReservoir, 10, 5},
+		{SampleStratified, 16, 2}, // every transaction lands in one band
+		{SampleMostRecent, 0, 100},
+	} {
+		s := newBoundedSampler(tc.policy, 100, tc.retained, time.Minute, clock)
+		for i := 0; i < 100; i++ {
+			s.Add(makeSampleTx(1))
+		}
+		if n := len(s.Snapshot()); n != tc.want {
+			t.Errorf("%s retaining %d: Snapshot len = %d, want %d", tc.policy, tc.retained, n, tc.want)
+		}
+		if got := s.(evictionCounter).Evicted(); tc.policy != SampleReservoir && got != uint64(100-tc.want) {
+			t.Errorf("%s retaining %d: Evicted() = %d, want %d", tc.policy, tc.retained, got, 100-tc.want)
+		}
+	}
+}
+
+func TestEstimator_MemoryStats(t *testing.T) {
+	fees := make([]eth.Transaction, 10)
+	pending := make([]*eth.Transaction, len(fees))
+	for i := range fees {
+		fees[i] = eth.Transaction{Type: 2, MaxPriorityFeePerGas: uint256.NewInt(uint64(i + 1)), MaxFeePerGas: uint256.NewInt(2e9)}
+		pending[i] = &fees[i]
+	}
+	client := &mockBlockReader{
+		chainIDFunc: func(ctx context.Context) (uint64, error) { return 1, nil },
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) {
+			return &eth.Block{Number: 100, BaseFee: uint256.NewInt(1e9), Transactions: fees}, nil
+		},
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			return &eth.Block{Number: number.Uint64(), BaseFee: uint256.NewInt(1e9), Transactions: fees}, nil
+		},
+	}
+	e := New(client, nil, nil, NewProvider(),
+		WithHistorySize(2),
+		WithHistorySource(HistoryBlocks),
+		WithBlockFeeSamples(4),
+		WithMempoolMaxBytes(3*txDataBytes),
+	)
+	if err := e.Bootstrap(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	e.IngestPendingTxs(pending...)
+
+	stats := e.MemoryStats()
+	if stats.HistoryFeeSamples != 8 || stats.HistoryBytes != 8*feeSampleBytes || stats.HistoryFeesEvicted != 12 {
+		t.Errorf("history stats = %+v, want 8 fees kept and 12 evicted", stats)
+	}
+	if stats.MempoolSamples != 3 || stats.MempoolBytes != 3*txDataBytes || stats.MempoolEvicted != 7 {
+		t.Errorf("mempool stats = %+v, want 3 samples kept and 7 evicted", stats)
+	}
+}
@@ -2,6 +2,7 @@ package estimator
 
 import (
 	"context"
+	"time"
 
 	"github.com/branched-services/go-gas/pkg/eth"
 	"github.com/holiman/uint256"
@@ -86,3 +87,25 @@ func (m *mockSubscriber) Close() error {
 	}
 	return nil
 }
+
+type mockFullSubscriber struct {
+	mockSubscriber
+	subFullFunc func(ctx context.Context) (<-chan *eth.Transaction, error)
+}
+
+func (m *mockFullSubscriber) SubscribeFullPendingTransactions(ctx context.Context) (<-chan *eth.Transaction, error) {
+	if m.subFullFunc != nil {
+		return m.subFullFunc(ctx)
+	}
+	return nil, eth.ErrNotSupported
+}
+
+// manualClock is a Clock whose Now is set by the test. Tickers and timers
+// use real time; use estimatortest.Clock from external tests for those.
+type manualClock struct {
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time                   { return c.now }
+func (c *manualClock) NewTicker(d time.Duration) Ticker { return SystemClock().NewTicker(d) }
+func (c *manualClock) NewTimer(d time.Duration) Timer   { return SystemClock().NewTimer(d) }
@@ -0,0 +1,108 @@
+package estimator
+
+import (
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// Version is the estimator build version reported in API responses.
+// Set at build time with
+//
+//	-ldflags "-X github.com/branched-services/go-gas/pkg/estimator.Version=v1.2.3"
+//
+// and otherwise taken from the module build info, falling back to "dev".
+var Version = buildVersion()
+
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// Network describes the chain an estimate was produced for.
+type Network struct {
+	// Name is a short human-readable network name (e.g. "mainnet", "base").
+	Name string
+
+	// CurrencySymbol is the native token symbol fees are paid in (e.g. "ETH").
+	CurrencySymbol string
+
+	// BlockTime is the target interval between blocks.
+	BlockTime time.Duration
+
+	// FeeCurrencies are the tokens other than the native one that the
+	// chain accepts fees in, such as cUSD on Celo. Estimates are always in
+	// wei of the native token.
+	FeeCurrencies []FeeCurrency
+}
+
+// FeeCurrency is an ERC-20 token a chain accepts fees in.
+type FeeCurrency struct {
+	Symbol string
+
+	// Address is the contract transactions name as their fee currency.
+	Address string
+
+	// Decimals is the number of decimal places of one token, i.e. fee
+	// amounts in the token are in units of 10^-Decimals tokens.
+	Decimals uint8
+}
+
+// LookupFeeCurrency returns the fee currency of n with the given symbol or
+// contract address, ignoring case.
+func (n Network) LookupFeeCurrency(symbolOrAddress string) (FeeCurrency, bool) {
+	for _, c := range n.FeeCurrencies {
+		if strings.EqualFold(c.Symbol, symbolOrAddress) || strings.EqualFold(c.Address, symbolOrAddress) {
+			return c, true
+		}
+	}
+	return FeeCurrency{}, false
+}
+
+// knownNetworks holds metadata for common chains, keyed by chain ID.
+var knownNetworks = map[uint64]Network{
+	1:        {Name: "mainnet", CurrencySymbol: "ETH", BlockTime: 12 * time.Second},
+	17000:    {Name: "holesky", CurrencySymbol: "ETH", BlockTime: 12 * time.Second},
+	11155111: {Name: "sepolia", CurrencySymbol: "ETH", BlockTime: 12 * time.Second},
+	10:       {Name: "optimism", CurrencySymbol: "ETH", BlockTime: 2 * time.Second},
+	8453:     {Name: "base", CurrencySymbol: "ETH", BlockTime: 2 * time.Second},
+	42161:    {Name: "arbitrum", CurrencySymbol: "ETH", BlockTime: 250 * time.Millisecond},
+	137:      {Name: "polygon", CurrencySymbol: "POL", BlockTime: 2 * time.Second},
+	56:       {Name: "bsc", CurrencySymbol: "BNB", BlockTime: 3 * time.Second},
+	43114:    {Name: "avalanche", CurrencySymbol: "AVAX", BlockTime: 2 * time.Second},
+	100:      {Name: "gnosis", CurrencySymbol: "XDAI", BlockTime: 5 * time.Second},
+	42220: {Name: "celo", CurrencySymbol: "CELO", BlockTime: time.Second, FeeCurrencies: []FeeCurrency{
+		{Symbol: "cUSD", Address: "0x765DE816845861e75A25fCA122bb6898B8B1282a", Decimals: 18},
+		{Symbol: "cEUR", Address: "0xD8763CBa276a3738E6DE85b4b3bF5FDed6D6cA73", Decimals: 18},
+	}},
+
+	// Local dev chains mine on demand and have no block time
+	1337:  {Name: "devnet", CurrencySymbol: "ETH"},
+	31337: {Name: "devnet", CurrencySymbol: "ETH"},
+}
+
+// LookupNetwork returns built-in metadata for chainID.
+func LookupNetwork(chainID uint64) (Network, bool) {
+	n, ok := knownNetworks[chainID]
+	return n, ok
+}
+
+// withDefaults fills zero fields of n from the built-in metadata for chainID.
+func (n Network) withDefaults(chainID uint64) Network {
+	known, _ := LookupNetwork(chainID)
+	if n.Name == "" {
+		n.Name = known.Name
+	}
+	if n.CurrencySymbol == "" {
+		n.CurrencySymbol = known.CurrencySymbol
+	}
+	if n.BlockTime == 0 {
+		n.BlockTime = known.BlockTime
+	}
+	if n.FeeCurrencies == nil {
+		n.FeeCurrencies = known.FeeCurrencies
+	}
+	return n
+}
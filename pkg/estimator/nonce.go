@@ -0,0 +1,117 @@
+package estimator
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// nonceTracker holds the confirmed nonces of senders in the mempool sample
+// and filters out transactions that can't execute because an earlier nonce
+// from the same sender is missing.
+//
+// Nonces are refreshed once per block for the senders currently sampled.
+// Senders seen since the last refresh have no known nonce and are kept.
+type nonceTracker struct {
+	mu     sync.RWMutex
+	nonces map[string]uint64
+
+	// Outcome of the last filter call, for debugging
+	lastExcluded int
+}
+
+func newNonceTracker() *nonceTracker {
+	return &nonceTracker{nonces: make(map[string]uint64)}
+}
+
+// set replaces the known nonces.
+func (t *nonceTracker) set(nonces map[string]uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nonces = nonces
+}
+
+// tracked returns the number of senders with a known nonce.
+func (t *nonceTracker) tracked() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.nonces)
+}
+
+// excluded returns how many transactions the last filter call removed.
+func (t *nonceTracker) excluded() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.lastExcluded
+}
+
+// executable returns the transactions that could be mined next: for a sender
+// with confirmed nonce n, a transaction with nonce k is kept only if the
+// sample also holds that sender's transactions for every nonce in [n, k).
+// Transactions below n were already mined or replaced and are dropped too.
+//
+// The sample is partial, so a missing nonce may have been seen by the node
+// but not sampled; this errs on the side of excluding such transactions.
+func (t *nonceTracker) executable(txs []*TxData) []*TxData {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.nonces) == 0 {
+		t.lastExcluded = 0
+		return txs
+	}
+
+	// Group sampled nonces per tracked sender
+	bySender := make(map[string][]uint64)
+	for _, tx := range txs {
+		if _, ok := t.nonces[tx.From]; ok && tx.From != "" {
+			bySender[tx.From] = append(bySender[tx.From], tx.Nonce)
+		}
+	}
+
+	// The highest nonce each sender can reach without a gap
+	reach := make(map[string]uint64, len(bySender))
+	for sender, nonces := range bySender {
+		slices.SortFunc(nonces, cmp.Compare[uint64])
+		next := t.nonces[sender]
+		for _, n := range nonces {
+			if n == next {
+				next++
+			} else if n > next {
+				break
+			}
+		}
+		reach[sender] = next
+	}
+
+	out := make([]*TxData, 0, len(txs))
+	for _, tx := range txs {
+		confirmed, ok := t.nonces[tx.From]
+		if ok && tx.From != "" && (tx.Nonce < confirmed || tx.Nonce >= reach[tx.From]) {
+			continue
+		}
+		out = append(out, tx)
+	}
+	t.lastExcluded = len(txs) - len(out)
+	return out
+}
+
+// senders returns the distinct senders in txs, at most limit.
+func senders(txs []*TxData, limit int) []string {
+	seen := make(map[string]struct{})
+	var out []string
+	for _, tx := range txs {
+		if tx.From == "" {
+			continue
+		}
+		if _, ok := seen[tx.From]; ok {
+			continue
+		}
+		seen[tx.From] = struct{}{}
+		out = append(out, tx.From)
+		if len(out) == limit {
+			break
+		}
+	}
+	return out
+}
@@ -0,0 +1,42 @@
+package estimator
+
+import "testing"
+
+func TestNonceTracker_Executable(t *testing.T) {
+	tx := func(from string, nonce uint64) *TxData {
+		return &TxData{From: from, Nonce: nonce}
+	}
+	txs := []*TxData{
+		tx("a", 5), tx("a", 6), tx("a", 8), // 8 is behind a gap at 7
+		tx("b", 2), // already mined (confirmed nonce 3)
+		tx("c", 1), // gap: confirmed nonce 0
+		tx("d", 0), // unknown sender, kept
+		tx("", 9),  // no sender, kept
+	}
+
+	tr := newNonceTracker()
+	if got := tr.executable(txs); len(got) != len(txs) {
+		t.Errorf("no nonces known: kept %d, want all %d", len(got), len(txs))
+	}
+
+	tr.set(map[string]uint64{"a": 5, "b": 3, "c": 0})
+	got := tr.executable(txs)
+
+	var kept []string
+	for _, tx := range got {
+		kept = append(kept, tx.From+":"+string(rune('0'+tx.Nonce)))
+	}
+	want := []string{"a:5", "a:6", "d:0", ":9"}
+	if len(kept) != len(want) {
+		t.Fatalf("kept %v, want %v", kept, want)
+	}
+	for i := range want {
+		if kept[i] != want[i] {
+			t.Errorf("kept %v, want %v", kept, want)
+			break
+		}
+	}
+	if tr.excluded() != 3 {
+		t.Errorf("excluded = %d, want 3", tr.excluded())
+	}
+}
@@ -0,0 +1,62 @@
+package estimator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+)
+
+// EstimateOnce computes a single gas estimate synchronously, without
+// subscriptions or a long-running Run loop.
+//
+// It loads the most recent blocks (see WithHistorySize) and, if client also
+// implements eth.TxPoolReader and mempool sampling is enabled (see
+// WithMempoolSamples), samples the node's mempool. Intended for scripts and
+// cron jobs that need to price a single transaction.
+func EstimateOnce(ctx context.Context, client eth.BlockReader, opts ...Option) (*GasEstimate, error) {
+	e := New(client, nil, nil, NewProvider(), opts...)
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting chain ID: %w", err)
+	}
+	e.setChainID(chainID)
+	plan := e.dataPlan()
+	plan.DevChain = e.devChain(chainID, "")
+	e.setPlan(plan)
+
+	if err := e.loadHistory(ctx); err != nil {
+		return nil, err
+	}
+
+	if pool, ok := client.(eth.TxPoolReader); ok && e.mempoolSamples > 0 {
+		txs, err := pool.PendingTransactions(ctx, e.mempoolSamples)
+		if err != nil {
+			// Mempool access is optional on many providers; history alone still yields an estimate.
+			e.logger.Warn("mempool sampling failed", "error", err)
+		}
+		for _, tx := range txs {
+			if tx != nil {
+				e.countTxType(tx.Type)
+				e.state.addTx(tx)
+			}
+		}
+	}
+
+	if e.nonceFilter {
+		e.refreshNonces(ctx)
+	}
+
+	input, err := e.buildInput(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("building calculator input: %w", err)
+	}
+
+	estimate, err := e.calculate(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("calculating estimate: %w", err)
+	}
+	e.annotate(estimate, input)
+	return estimate, nil
+}
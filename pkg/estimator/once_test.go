@@ -0,0 +1,73 @@
+package estimator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+func TestEstimateOnce(t *testing.T) {
+	mockClient := &mockBlockReader{
+		chainIDFunc: func(ctx context.Context) (uint64, error) {
+			return 1, nil
+		},
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) {
+			return &eth.Block{Number: 100, BaseFee: uint256.NewInt(1000000000)}, nil
+		},
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			return &eth.Block{
+				Number:   number.Uint64(),
+				BaseFee:  uint256.NewInt(1000000000),
+				GasUsed:  15000000,
+				GasLimit: 30000000,
+			}, nil
+		},
+	}
+
+	est, err := EstimateOnce(context.Background(), mockClient, WithHistorySize(5))
+	if err != nil {
+		t.Fatalf("EstimateOnce() error = %v", err)
+	}
+	if est.ChainID != 1 {
+		t.Errorf("ChainID = %d, want 1", est.ChainID)
+	}
+	if est.BlockNumber != 100 {
+		t.Errorf("BlockNumber = %d, want 100", est.BlockNumber)
+	}
+	if !est.BaseFee.Eq(uint256.NewInt(1000000000)) {
+		t.Errorf("BaseFee = %v, want 1000000000", est.BaseFee)
+	}
+	if est.Network.Name != "mainnet" || est.Network.CurrencySymbol != "ETH" {
+		t.Errorf("Network = %+v, want mainnet/ETH", est.Network)
+	}
+	if est.Strategy != "hybrid" {
+		t.Errorf("Strategy = %q, want hybrid", est.Strategy)
+	}
+}
+
+func TestNetwork_WithDefaults(t *testing.T) {
+	got := Network{Name: "my-fork"}.withDefaults(1)
+	want := Network{Name: "my-fork", CurrencySymbol: "ETH", BlockTime: 12 * time.Second}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("withDefaults = %+v, want %+v", got, want)
+	}
+
+	if got := (Network{}).withDefaults(999999); !reflect.DeepEqual(got, Network{}) {
+		t.Errorf("unknown chain = %+v, want zero", got)
+	}
+
+	celo := Network{}.withDefaults(42220)
+	if c, ok := celo.LookupFeeCurrency("cusd"); !ok || c.Address != "0x765DE816845861e75A25fCA122bb6898B8B1282a" {
+		t.Errorf("LookupFeeCurrency(cusd) = %+v, %v, want the cUSD contract", c, ok)
+	}
+	if c, ok := celo.LookupFeeCurrency("0xd8763cba276a3738e6de85b4b3bf5fded6d6ca73"); !ok || c.Symbol != "cEUR" {
+		t.Errorf("LookupFeeCurrency(address) = %+v, %v, want cEUR", c, ok)
+	}
+	if _, ok := celo.LookupFeeCurrency("USDT"); ok {
+		t.Error("LookupFeeCurrency(USDT) found an unlisted currency")
+	}
+}
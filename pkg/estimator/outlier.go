@@ -0,0 +1,131 @@
+package estimator
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/holiman/uint256"
+)
+
+// OutlierMethod selects how outlying fee samples are detected.
+type OutlierMethod string
+
+const (
+	// OutlierNone keeps every sample.
+	OutlierNone OutlierMethod = ""
+
+	// OutlierIQR rejects samples more than Threshold interquartile ranges
+	// below the first or above the third quartile (Tukey's fences).
+	OutlierIQR OutlierMethod = "iqr"
+
+	// OutlierMAD rejects samples whose modified z-score, based on the median
+	// absolute deviation, exceeds Threshold.
+	OutlierMAD OutlierMethod = "mad"
+)
+
+// Default thresholds for each method.
+const (
+	DefaultIQRThreshold = 1.5
+	DefaultMADThreshold = 3.5
+)
+
+// minOutlierSamples is the smallest sample the filter applies to; quartiles
+// of a handful of values say little about what is unusual.
+const minOutlierSamples = 8
+
+// ParseOutlierMethod validates a method name; "none" and "" disable filtering.
+func ParseOutlierMethod(s string) (OutlierMethod, error) {
+	switch s {
+	case "", "none":
+		return OutlierNone, nil
+	case string(OutlierIQR), string(OutlierMAD):
+		return OutlierMethod(s), nil
+	default:
+		return "", fmt.Errorf("unknown outlier method %q", s)
+	}
+}
+
+// OutlierFilter rejects outlying fees before percentiles are computed, so
+// spam with absurd tips doesn't inflate the high tiers.
+//
+// Samples smaller than 8 values, or whose spread (IQR or MAD) is zero, are
+// left untouched: with most fees identical any deviation would look extreme.
+type OutlierFilter struct {
+	Method OutlierMethod
+
+	// Threshold is the fence multiplier for IQR or the modified z-score
+	// cutoff for MAD. 0 selects DefaultIQRThreshold or DefaultMADThreshold.
+	Threshold float64
+}
+
+// apply returns the fees within the fences and the number rejected.
+// fees is not modified; kept may alias its elements.
+func (f OutlierFilter) apply(fees []*uint256.Int) (kept []*uint256.Int, rejected int) {
+	if f.Method == OutlierNone || len(fees) < minOutlierSamples {
+		return fees, 0
+	}
+
+	values := make([]float64, len(fees))
+	for i, fee := range fees {
+		values[i] = fee.Float64()
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	var lo, hi float64
+	switch f.Method {
+	case OutlierIQR:
+		k := f.Threshold
+		if k <= 0 {
+			k = DefaultIQRThreshold
+		}
+		q1, q3 := quantile(sorted, 0.25), quantile(sorted, 0.75)
+		iqr := q3 - q1
+		if iqr == 0 {
+			return fees, 0
+		}
+		lo, hi = q1-k*iqr, q3+k*iqr
+	case OutlierMAD:
+		z := f.Threshold
+		if z <= 0 {
+			z = DefaultMADThreshold
+		}
+		median := quantile(sorted, 0.5)
+		deviations := make([]float64, len(sorted))
+		for i, v := range sorted {
+			deviations[i] = math.Abs(v - median)
+		}
+		slices.Sort(deviations)
+		mad := quantile(deviations, 0.5)
+		if mad == 0 {
+			return fees, 0
+		}
+		// Modified z-score: 0.6745 * (x - median) / MAD
+		spread := z * mad / 0.6745
+		lo, hi = median-spread, median+spread
+	default:
+		return fees, 0
+	}
+
+	kept = make([]*uint256.Int, 0, len(fees))
+	for i, v := range values {
+		if v < lo || v > hi {
+			rejected++
+			continue
+		}
+		kept = append(kept, fees[i])
+	}
+	return kept, rejected
+}
+
+// quantile returns the linearly interpolated q-th quantile of sorted values.
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	i := int(pos)
+	if i >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	frac := pos - float64(i)
+	return sorted[i] + frac*(sorted[i+1]-sorted[i])
+}
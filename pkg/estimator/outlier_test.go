@@ -0,0 +1,76 @@
+package estimator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestOutlierFilter(t *testing.T) {
+	gwei := func(vs ...uint64) []*uint256.Int {
+		out := make([]*uint256.Int, len(vs))
+		for i, v := range vs {
+			out[i] = uint256.NewInt(v * 1e9)
+		}
+		return out
+	}
+	// Ten ordinary tips plus one spam tip and one near-zero tip
+	fees := gwei(1, 2, 2, 3, 3, 3, 4, 4, 5, 6, 5000)
+
+	tests := []struct {
+		name         string
+		filter       OutlierFilter
+		fees         []*uint256.Int
+		wantRejected int
+	}{
+		{"disabled", OutlierFilter{}, fees, 0},
+		{"iqr rejects spam", OutlierFilter{Method: OutlierIQR}, fees, 1},
+		{"mad rejects spam", OutlierFilter{Method: OutlierMAD}, fees, 1},
+		{"wide iqr fences keep all", OutlierFilter{Method: OutlierIQR, Threshold: 10000}, fees, 0},
+		{"small sample untouched", OutlierFilter{Method: OutlierIQR}, gwei(1, 2, 5000), 0},
+		{"zero spread untouched", OutlierFilter{Method: OutlierMAD}, gwei(1, 1, 1, 1, 1, 1, 1, 1, 1, 50), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, rejected := tt.filter.apply(tt.fees)
+			if rejected != tt.wantRejected {
+				t.Errorf("rejected = %d, want %d", rejected, tt.wantRejected)
+			}
+			if len(kept)+rejected != len(tt.fees) {
+				t.Errorf("kept %d + rejected %d != %d", len(kept), rejected, len(tt.fees))
+			}
+			for _, fee := range kept {
+				if tt.wantRejected > 0 && fee.Uint64() == 5000e9 {
+					t.Error("spam fee kept")
+				}
+			}
+		})
+	}
+}
+
+func TestHybridStrategy_MempoolOutliers(t *testing.T) {
+	block := &BlockData{Number: 1, BaseFee: uint256.NewInt(1e9), GasUsed: 15e6, GasLimit: 30e6}
+	var pending []*TxData
+	for _, tip := range []uint64{1, 2, 2, 3, 3, 3, 4, 4, 5, 6, 5000} {
+		pending = append(pending, &TxData{
+			IsEIP1559:            true,
+			MaxPriorityFeePerGas: uint256.NewInt(tip * 1e9),
+			MaxFeePerGas:         uint256.NewInt(10000e9),
+		})
+	}
+	input := &CalculatorInput{CurrentBlock: block, RecentBlocks: []*BlockData{block}, PendingTxs: pending}
+
+	s := DefaultStrategy()
+	s.MempoolOutliers = OutlierFilter{Method: OutlierIQR}
+	est, err := s.Calculate(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+	if est.MempoolOutliers != 1 {
+		t.Errorf("MempoolOutliers = %d, want 1", est.MempoolOutliers)
+	}
+	if got := est.Urgent.MaxPriorityFeePerGas.Uint64(); got > 6e9 {
+		t.Errorf("Urgent tip = %d, spam still dominates", got)
+	}
+}
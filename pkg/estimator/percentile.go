@@ -0,0 +1,219 @@
+package estimator
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/holiman/uint256"
+)
+
+// feeSample is a sorted set of fees with optional per-value weights.
+type feeSample struct {
+	values []*uint256.Int
+	// cum[i] is the total weight of values[:i]; nil when every value counts equally
+	cum []float64
+}
+
+// feeArena allocates the temporary fees of one calculation from a shared
+// backing array rather than one allocation each. Slots are never reused,
+// so fees that end up in an estimate stay immutable.
+type feeArena []uint256.Int
+
+func newFeeArena(n int) feeArena {
+	return make(feeArena, 0, n)
+}
+
+// next returns a zero fee from the arena, growing it when full.
+func (a *feeArena) next() *uint256.Int {
+	if len(*a) == cap(*a) {
+		*a = make(feeArena, 0, max(2*cap(*a), 16))
+	}
+	*a = (*a)[:len(*a)+1]
+	return &(*a)[len(*a)-1]
+}
+
+// thinFees reduces fees to n evenly spaced order statistics, keeping the
+// smallest and largest, so percentiles over the result approximate those
+// over fees. gas, when not nil, holds the gas of each fee's transaction;
+// each kept fee is credited with the gas of the fees nearest to it in
+// order, so gas totals are preserved. fees and gas are returned as is
+// when n <= 0 or there are at most n fees.
+func thinFees(fees []*uint256.Int, gas []uint64, n int) ([]*uint256.Int, []uint64) {
+	if n <= 0 || len(fees) <= n {
+		return fees, gas
+	}
+	idx := make([]int, len(fees))
+	for i := range idx {
+		idx[i] = i
+	}
+	slices.SortStableFunc(idx, func(a, b int) int { return fees[a].Cmp(fees[b]) })
+
+	// pos[j] is the sorted index of the j-th kept fee
+	pos := make([]int, n)
+	if n == 1 {
+		pos[0] = len(idx) / 2
+	} else {
+		for j := range pos {
+			pos[j] = j * (len(idx) - 1) / (n - 1)
+		}
+	}
+
+	out := make([]*uint256.Int, n)
+	for j, k := range pos {
+		out[j] = fees[idx[k]]
+	}
+	if gas == nil {
+		return out, nil
+	}
+
+	// Fees between two kept ones are split at the midpoint
+	outGas := make([]uint64, n)
+	j := 0
+	for k, i := range idx {
+		for j < n-1 && k > (pos[j]+pos[j+1])/2 {
+			j++
+		}
+		outGas[j] += gas[i]
+	}
+	return out, outGas
+}
+
+// newFeeSample sorts fees ascending together with their weights.
+// weights may be nil; otherwise it must have the same length as fees.
+func newFeeSample(fees []*uint256.Int, weights []float64) feeSample {
+	idx := make([]int, len(fees))
+	for i := range idx {
+		idx[i] = i
+	}
+	slices.SortStableFunc(idx, func(a, b int) int {
+		return fees[a].Cmp(fees[b])
+	})
+
+	s := feeSample{values: make([]*uint256.Int, len(fees))}
+	for i, j := range idx {
+		s.values[i] = fees[j]
+	}
+	if weights == nil {
+		return s
+	}
+
+	s.cum = make([]float64, len(fees))
+	total := 0.0
+	for i, j := range idx {
+		s.cum[i] = total
+		total += weights[j]
+	}
+	return s
+}
+
+func (s feeSample) len() int {
+	return len(s.values)
+}
+
+// PercentileMethod selects how a percentile is read from a fee sample.
+type PercentileMethod string
+
+const (
+	// PercentileNearestRank picks the value at the percentile's rank
+	// rounded down. Small samples make tiers move in steps as values enter
+	// and leave the sample.
+	PercentileNearestRank PercentileMethod = ""
+
+	// PercentileLinear interpolates linearly between the two values around
+	// the percentile's rank, so tiers move smoothly with thin history.
+	PercentileLinear PercentileMethod = "linear"
+)
+
+// ParsePercentileMethod validates a method name; "nearest_rank" and ""
+// select PercentileNearestRank.
+func ParsePercentileMethod(s string) (PercentileMethod, error) {
+	switch s {
+	case "", "nearest_rank":
+		return PercentileNearestRank, nil
+	case string(PercentileLinear):
+		return PercentileLinear, nil
+	default:
+		return "", fmt.Errorf("unknown percentile method %q", s)
+	}
+}
+
+// at returns the value at percentile p (0.0 to 1.0), or nil if empty.
+// The result aliases the sample and must not be modified.
+//
+// Unweighted samples use the nearest-rank-below index (n-1)*p. Weighted
+// samples generalize it: each value is positioned at the cumulative weight
+// before it, and the last value whose position is within p of the span is
+// chosen, so equal weights give the same result as the unweighted form.
+func (s feeSample) at(p float64) *uint256.Int {
+	if len(s.values) == 0 {
+		return nil
+	}
+	i, _ := s.rank(p)
+	return s.values[i]
+}
+
+// interpolate returns the value at percentile p (0.0 to 1.0) interpolated
+// linearly between the values around its rank, or nil if empty. Ranks are
+// positioned as in at, which it matches whenever p falls on a value.
+// The result is a new value.
+func (s feeSample) interpolate(p float64) *uint256.Int {
+	if len(s.values) == 0 {
+		return nil
+	}
+	i, frac := s.rank(p)
+	lo := new(uint256.Int).Set(s.values[i])
+	if frac <= 0 || i == len(s.values)-1 {
+		return lo
+	}
+	// lo + (hi-lo)*frac, in millionths; values are sorted so hi >= lo
+	delta := new(uint256.Int).Sub(s.values[i+1], lo)
+	delta.MulDivOverflow(delta, uint256.NewInt(uint64(math.Round(frac*1e6))), uint256.NewInt(1e6))
+	return lo.Add(lo, delta)
+}
+
+// value reads percentile p with method. The result must not be modified.
+func (s feeSample) value(p float64, method PercentileMethod) *uint256.Int {
+	if method == PercentileLinear {
+		return s.interpolate(p)
+	}
+	return s.at(p)
+}
+
+// rank locates percentile p in a non-empty sample: the index of the value
+// at or below it and the fraction of the way to the next value.
+func (s feeSample) rank(p float64) (int, float64) {
+	n := len(s.values)
+	if s.cum == nil {
+		r := float64(n-1) * p
+		i := min(int(r), n-1)
+		return i, r - float64(i)
+	}
+
+	span := s.cum[n-1]
+	if span <= 0 {
+		return n - 1, 0
+	}
+	target := p * span
+	// Largest i with cum[i] <= target, tolerating float rounding
+	i, _ := slices.BinarySearchFunc(s.cum, target, func(c, t float64) int {
+		if c <= t+1e-9*span {
+			return -1
+		}
+		return 1
+	})
+	i = max(i-1, 0)
+	if i == n-1 {
+		return i, 0
+	}
+	frac := (target - s.cum[i]) / (s.cum[i+1] - s.cum[i])
+	return i, min(max(frac, 0), 1)
+}
+
+// decayWeight returns 0.5^(age/halfLife), or 1 when halfLife is not positive.
+func decayWeight(age uint64, halfLife float64) float64 {
+	if halfLife <= 0 {
+		return 1
+	}
+	return math.Exp2(-float64(age) / halfLife)
+}
@@ -2,22 +2,38 @@ package estimator
 
 import (
 	"sync"
+	"sync/atomic"
 
 	"github.com/branched-services/go-gas/pkg/eth"
 )
 
+// TxSampler maintains a bounded sample of pending transactions.
+// Implementations must be safe for concurrent use.
+type TxSampler interface {
+	// Add offers a pending transaction to the sample.
+	Add(tx *eth.Transaction)
+
+	// Snapshot returns the current sample. The slice is owned by the caller.
+	Snapshot() []*TxData
+}
+
 // LocalTxPool maintains a ring buffer of recent pending transactions.
 // It provides a low-latency view of the mempool without polling full content.
+// This is the SampleMostRecent policy.
 type LocalTxPool struct {
 	mu    sync.RWMutex
 	txs   []*TxData
 	size  int
 	pos   int
 	count int
+
+	evicted atomic.Uint64
 }
 
 // NewLocalTxPool creates a new local transaction pool.
+// A size of 0 or less disables sampling.
 func NewLocalTxPool(size int) *LocalTxPool {
+	size = max(size, 0)
 	return &LocalTxPool{
 		txs:  make([]*TxData, size),
 		size: size,
@@ -26,8 +42,37 @@ func NewLocalTxPool(size int) *LocalTxPool {
 
 // Add adds a transaction to the pool.
 func (p *LocalTxPool) Add(tx *eth.Transaction) {
+	if p.size == 0 {
+		return
+	}
+	data := newTxData(tx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.txs[p.pos] = data
+	p.pos = (p.pos + 1) % p.size
+	if p.count < p.size {
+		p.count++
+	} else {
+		p.evicted.Add(1)
+	}
+}
+
+// Evicted returns the number of transactions dropped to make room for
+// newer ones.
+func (p *LocalTxPool) Evicted() uint64 {
+	return p.evicted.Load()
+}
+
+// newTxData extracts the fee fields relevant for estimation.
+func newTxData(tx *eth.Transaction) *TxData {
 	// Only track EIP-1559 or legacy txs with gas price
 	data := &TxData{
+		Hash:      tx.Hash,
+		From:      tx.From,
+		Nonce:     tx.Nonce,
+		Gas:       tx.GasLimit,
 		IsEIP1559: tx.IsEIP1559(),
 	}
 
@@ -44,14 +89,7 @@ func (p *LocalTxPool) Add(tx *eth.Transaction) {
 		}
 	}
 
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	p.txs[p.pos] = data
-	p.pos = (p.pos + 1) % p.size
-	if p.count < p.size {
-		p.count++
-	}
+	return data
 }
 
 // Snapshot returns a copy of all transactions in the pool.
@@ -69,3 +107,6 @@ func (p *LocalTxPool) Snapshot() []*TxData {
 	}
 	return res
 }
+
+// Verify interface compliance at compile time.
+var _ TxSampler = (*LocalTxPool)(nil)
@@ -3,7 +3,9 @@ package estimator
 import (
 	"context"
 	"errors"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // ErrNotReady indicates the estimator has not produced its first estimate.
@@ -15,6 +17,50 @@ type EstimateReader interface {
 	Current(ctx context.Context) (*GasEstimate, error)
 }
 
+// HistoryReader provides access to recently published estimates.
+// Implemented by Provider; used by the history API.
+type HistoryReader interface {
+	// Recent returns the final estimate for each block published at or after since,
+	// oldest first.
+	Recent(since time.Time) []*GasEstimate
+}
+
+// SnapshotReader looks up the estimate published for a recent block.
+// Implemented by Provider; used by the estimate API.
+type SnapshotReader interface {
+	// AtBlock returns the final estimate published for block number, or
+	// the latest one if the block is still the head. ok is false when the
+	// block is older than the retained history or was never priced.
+	AtBlock(number uint64) (est *GasEstimate, ok bool)
+}
+
+// VersionReader looks up an exact published estimate, so that several reads
+// can be made against the same snapshot. Implemented by Provider; used by
+// the estimate API for If-Generation-Match.
+type VersionReader interface {
+	// AtVersion returns the estimate published for block number with
+	// provider version version. ok is false once a later estimate has
+	// replaced it within its block or it has left the retained history.
+	AtVersion(number, version uint64) (est *GasEstimate, ok bool)
+}
+
+// RenderedReader provides the current estimate pre-encoded for serving.
+// Implemented by Provider; used by the estimate API.
+type RenderedReader interface {
+	// Rendered returns the current estimate and its encoding, which is nil
+	// when no renderer is configured. The bytes are shared and must not be
+	// modified.
+	Rendered(ctx context.Context) (*GasEstimate, []byte, error)
+}
+
+// ChangeNotifier signals published estimates to waiting readers.
+// Implemented by Provider; used by the long-poll API.
+type ChangeNotifier interface {
+	// Changed returns a channel that is closed when the next estimate is
+	// published.
+	Changed() <-chan struct{}
+}
+
 // ReadinessChecker provides health check functionality.
 // Implemented by Provider; used by health probes.
 type ReadinessChecker interface {
@@ -30,27 +76,335 @@ type ReadinessChecker interface {
 //
 // Thread safety: All methods are safe for concurrent use.
 type Provider struct {
-	current atomic.Pointer[GasEstimate]
-	updates atomic.Uint64 // total number of updates (for metrics)
+	current    atomic.Pointer[GasEstimate]
+	updates    atomic.Uint64 // total number of updates (for metrics)
+	copyOnRead bool
+
+	// updateMu serializes Update so the staleness check and the swap are
+	// atomic. generations hands out NextGeneration; conflicts counts
+	// rejected stale updates (for metrics).
+	updateMu    sync.Mutex
+	generations atomic.Uint64
+	conflicts   atomic.Uint64
+
+	// changed is closed and replaced by each Update (see Changed)
+	changed atomic.Pointer[chan struct{}]
+
+	// expire makes reads fail with ErrExpired once the current estimate is
+	// more than expiryGrace past its ValidUntil
+	expire      bool
+	expiryGrace time.Duration
+
+	caps    FeeCaps
+	capHits atomic.Uint64 // total number of capped fee values (for metrics)
+
+	// render encodes each estimate once on Update, so readers serving the
+	// same bytes to every client don't re-encode it per request
+	render   func(*GasEstimate) []byte
+	rendered atomic.Pointer[renderedEstimate]
+
+	// signer, if set, replaces each published estimate's signature
+	signer Signer
+
+	// clock stamps UpdatedAt and dates expiry, accuracy and forecasts
+	clock Clock
+
+	// accuracy scores each new block against the estimate served before it
+	accuracy *accuracyLog
+
+	// seasons learns fee levels by hour of week from each block's final estimate
+	seasons *seasonalProfile
+
+	// log keeps the last estimate of each recent block.
+	// Only touched on the write path and by history readers.
+	logMu sync.RWMutex
+	log   []*GasEstimate
+	head  int
+	count int
+}
+
+// renderedEstimate pairs an estimate with its encoding.
+type renderedEstimate struct {
+	est  *GasEstimate
+	body []byte
+}
+
+// defaultHistoryCapacity is the number of per-block estimates retained (~3.4h on mainnet).
+const defaultHistoryCapacity = 1024
+
+// ProviderOption configures a Provider.
+type ProviderOption func(*Provider)
+
+// WithCopyOnRead makes Current and Recent return deep copies, so callers may
+// modify returned estimates without affecting other readers. This costs an
+// allocation per read; by default the shared estimate is returned.
+func WithCopyOnRead() ProviderOption {
+	return func(p *Provider) {
+		p.copyOnRead = true
+	}
+}
+
+// WithHistoryCapacity sets how many blocks' estimates are retained for
+// Recent and AtBlock. Defaults to 1024 (~3.4h on mainnet); zero disables
+// the history.
+func WithHistoryCapacity(blocks int) ProviderOption {
+	return func(p *Provider) {
+		if blocks < 0 {
+			blocks = 0
+		}
+		p.log = make([]*GasEstimate, blocks)
+	}
+}
+
+// WithExpiry makes Current and Rendered refuse to serve an estimate more
+// than grace past its ValidUntil, returning ErrExpired, e.g. while the
+// estimator is stalled. Estimates without a ValidUntil are always served.
+// By default the latest estimate is served however old it is.
+func WithExpiry(grace time.Duration) ProviderOption {
+	return func(p *Provider) {
+		p.expire = true
+		p.expiryGrace = grace
+	}
+}
+
+// WithFeeCaps caps every published tier's MaxPriorityFeePerGas and
+// MaxFeePerGas, as a safety net against strategy bugs or fee spikes. Each
+// capped value is counted in CapHitCount.
+func WithFeeCaps(caps FeeCaps) ProviderOption {
+	return func(p *Provider) {
+		p.caps = caps
+	}
+}
+
+// WithRenderer encodes every estimate with render as it is published, after
+// fee caps are applied and Version is set. The result is served by Rendered.
+func WithRenderer(render func(*GasEstimate) []byte) ProviderOption {
+	return func(p *Provider) {
+		p.render = render
+	}
+}
+
+// WithSigner signs every published estimate with signer, after fee caps
+// are applied and before it is rendered (see GasEstimate.Signature).
+// Estimates are published unsigned if signing fails.
+func WithSigner(signer Signer) ProviderOption {
+	return func(p *Provider) {
+		p.signer = signer
+	}
+}
+
+// WithProviderClock sets the time source for publish times, expiry,
+// accuracy windows and forecasts. Defaults to SystemClock; pass the
+// Estimator's clock (see WithClock) to drive both in tests.
+func WithProviderClock(c Clock) ProviderOption {
+	return func(p *Provider) {
+		p.clock = c
+	}
 }
 
 // NewProvider creates a new Provider.
-func NewProvider() *Provider {
-	return &Provider{}
+func NewProvider(opts ...ProviderOption) *Provider {
+	p := &Provider{
+		log:      make([]*GasEstimate, defaultHistoryCapacity),
+		accuracy: newAccuracyLog(defaultAccuracyCapacity),
+		seasons:  &seasonalProfile{},
+		clock:    SystemClock(),
+	}
+	changed := make(chan struct{})
+	p.changed.Store(&changed)
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// EnforceCaps lowers est's tier fees to the configured caps. Update applies
+// it to every published estimate.
+func (p *Provider) EnforceCaps(est *GasEstimate) {
+	if !p.caps.enabled() {
+		return
+	}
+	if hits := p.caps.apply(est); hits > 0 {
+		p.capHits.Add(uint64(hits))
+	}
 }
 
-// Update atomically replaces the current estimate.
+// Update atomically replaces the current estimate and stamps its Version
+// and UpdatedAt. Fee caps, if configured, are applied to est first, then it
+// is signed if a signer is set and encoded if a renderer is set.
 // The provided estimate should be treated as immutable after this call.
-func (p *Provider) Update(est *GasEstimate) {
+//
+// Update rejects est, reporting false, if its calculation started before
+// the current estimate's: both have a Generation and est's is lower. A slow
+// calculation then cannot overwrite a newer one published while it ran.
+// Block numbers alone are not compared, since after a reorg the newest
+// estimate can be for a lower block. Rejections are counted in
+// ConflictCount.
+func (p *Provider) Update(est *GasEstimate) bool {
+	p.updateMu.Lock()
+	defer p.updateMu.Unlock()
+
+	if cur := p.current.Load(); cur != nil && supersedes(cur, est) {
+		p.conflicts.Add(1)
+		return false
+	}
+
+	p.EnforceCaps(est)
+	est.Version = p.updates.Add(1)
+	est.UpdatedAt = p.clock.Now()
+	if p.signer != nil {
+		est.Signature, _ = SignEstimate(p.signer, est)
+	}
+	p.publish(est)
+	return true
+}
+
+// Mirror publishes est as another Provider published it, keeping its
+// Version, UpdatedAt and Signature, so a replica serves the same ETags,
+// generation tokens and publish times as the leader it follows. Fee caps
+// are not applied again. Later Updates continue from est's Version.
+func (p *Provider) Mirror(est *GasEstimate) {
+	p.updateMu.Lock()
+	defer p.updateMu.Unlock()
+
+	if est.Version > p.updates.Load() {
+		p.updates.Store(est.Version)
+	}
+	p.publish(est)
+}
+
+// publish makes est the current estimate and wakes waiters. The caller
+// holds updateMu.
+func (p *Provider) publish(est *GasEstimate) {
+	if p.render != nil {
+		p.rendered.Store(&renderedEstimate{est: est, body: p.render(est)})
+	}
 	p.current.Store(est)
-	p.updates.Add(1)
+	p.record(est)
+
+	next := make(chan struct{})
+	close(*p.changed.Swap(&next))
+}
+
+// Changed returns a channel that is closed when the next estimate is
+// published. Waiters should call it before reading the current estimate,
+// so an update in between is not missed.
+func (p *Provider) Changed() <-chan struct{} {
+	return *p.changed.Load()
+}
+
+// supersedes reports whether cur comes from a later calculation than est.
+// Estimates without a Generation are never stale.
+func supersedes(cur, est *GasEstimate) bool {
+	return est.Generation != 0 && est.Generation < cur.Generation
+}
+
+// NextGeneration returns a new Generation for a calculation about to
+// start. Generations increase monotonically.
+func (p *Provider) NextGeneration() uint64 {
+	return p.generations.Add(1)
+}
+
+// ConflictCount returns the total number of updates rejected as stale.
+func (p *Provider) ConflictCount() uint64 {
+	return p.conflicts.Load()
+}
+
+// record stores est in the per-block log, replacing the entry for the
+// same block if one exists.
+func (p *Provider) record(est *GasEstimate) {
+	p.logMu.Lock()
+	defer p.logMu.Unlock()
+
+	size := len(p.log)
+	if size == 0 {
+		return
+	}
+
+	if p.count > 0 {
+		last := (p.head - 1 + size) % size
+		if p.log[last].BlockNumber == est.BlockNumber {
+			p.log[last] = est
+			return
+		}
+		// The previous block's estimate is now final
+		p.seasons.add(p.log[last])
+	}
+
+	p.log[p.head] = est
+	p.head = (p.head + 1) % size
+	if p.count < size {
+		p.count++
+	}
+}
+
+// Recent returns the final estimate of each block published at or after since,
+// oldest first. The returned slice is owned by the caller.
+func (p *Provider) Recent(since time.Time) []*GasEstimate {
+	p.logMu.RLock()
+	defer p.logMu.RUnlock()
+
+	size := len(p.log)
+	result := make([]*GasEstimate, 0, p.count)
+	for i := 0; i < p.count; i++ {
+		// Walk forward from the oldest entry
+		est := p.log[(p.head-p.count+i+size)%size]
+		if !est.Timestamp.Before(since) {
+			if p.copyOnRead {
+				est = est.Clone()
+			}
+			result = append(result, est)
+		}
+	}
+	return result
+}
+
+// AtBlock returns the last estimate published for block number. When a
+// reorg priced the same height twice, the most recent estimate wins.
+func (p *Provider) AtBlock(number uint64) (*GasEstimate, bool) {
+	p.logMu.RLock()
+	defer p.logMu.RUnlock()
+
+	size := len(p.log)
+	for i := 1; i <= p.count; i++ {
+		// Walk backwards from the newest entry
+		est := p.log[(p.head-i+size)%size]
+		if est.BlockNumber == number {
+			if p.copyOnRead {
+				est = est.Clone()
+			}
+			return est, true
+		}
+	}
+	return nil, false
+}
+
+// AtVersion returns the estimate published for block number with version
+// version, whether or not it has expired: the current estimate, or a
+// block's final estimate while it is retained.
+func (p *Provider) AtVersion(number, version uint64) (*GasEstimate, bool) {
+	est := p.current.Load()
+	if est == nil || est.BlockNumber != number || est.Version != version {
+		var ok bool
+		if est, ok = p.AtBlock(number); !ok || est.Version != version {
+			return nil, false
+		}
+		return est, true
+	}
+	if p.copyOnRead {
+		est = est.Clone()
+	}
+	return est, true
 }
 
 // Current returns the latest gas estimate.
-// Returns ErrNotReady if no estimate has been computed yet.
+// Returns ErrNotReady if no estimate has been computed yet, and ErrExpired
+// if WithExpiry is set and the estimate is past its validity window.
 //
 // This is the hot path - must be as fast as possible.
-// Single atomic load, no allocations, no locks.
+// Single atomic load, no allocations, no locks (unless WithCopyOnRead is set).
 func (p *Provider) Current(ctx context.Context) (*GasEstimate, error) {
 	// Check context first to support request cancellation
 	if err := ctx.Err(); err != nil {
@@ -61,9 +415,55 @@ func (p *Provider) Current(ctx context.Context) (*GasEstimate, error) {
 	if est == nil {
 		return nil, ErrNotReady
 	}
+	if p.expire && est.Expired(p.clock.Now(), p.expiryGrace) {
+		return nil, ErrExpired
+	}
+	if p.copyOnRead {
+		return est.Clone(), nil
+	}
 	return est, nil
 }
 
+// ChainLag reports how far the latest estimate, expired or not, trails the
+// chain at now (see GasEstimate.ChainLag); zero before the first estimate.
+func (p *Provider) ChainLag(now time.Time) time.Duration {
+	if est := p.current.Load(); est != nil {
+		return est.ChainLag(now)
+	}
+	return 0
+}
+
+// latest returns the latest estimate, or nil, whether or not it has expired.
+func (p *Provider) latest() *GasEstimate {
+	return p.current.Load()
+}
+
+// Rendered returns the current estimate together with its encoding from the
+// renderer set by WithRenderer, or a nil encoding if there is none. Like
+// Current it makes no allocations unless WithCopyOnRead is set, in which
+// case only the estimate is copied.
+func (p *Provider) Rendered(ctx context.Context) (*GasEstimate, []byte, error) {
+	if p.render == nil {
+		est, err := p.Current(ctx)
+		return est, nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	r := p.rendered.Load()
+	if r == nil {
+		return nil, nil, ErrNotReady
+	}
+	if p.expire && r.est.Expired(p.clock.Now(), p.expiryGrace) {
+		return nil, nil, ErrExpired
+	}
+	if p.copyOnRead {
+		return r.est.Clone(), r.body, nil
+	}
+	return r.est, r.body, nil
+}
+
 // Ready returns true if at least one estimate has been computed.
 // Used for health/readiness checks.
 func (p *Provider) Ready() bool {
@@ -76,8 +476,46 @@ func (p *Provider) UpdateCount() uint64 {
 	return p.updates.Load()
 }
 
+// scoreBlock records whether the current estimate's tiers would have been
+// included in block, which must be newer than the estimate.
+func (p *Provider) scoreBlock(block *BlockData) {
+	p.accuracy.score(p.current.Load(), block, p.clock.Now())
+}
+
+// Accuracy summarizes the inclusion rate of each tier over blocks mined
+// within window of now. At most the last defaultAccuracyCapacity blocks
+// are retained.
+func (p *Provider) Accuracy(window time.Duration) Accuracy {
+	return p.accuracy.summarize(window, p.clock.Now())
+}
+
+// Forecast predicts fee levels for the given number of hours, starting
+// with the current one, from the current estimate and the seasonal profile
+// learned from published estimates (see Forecast). hours is clamped to
+// [1, MaxForecastHours].
+func (p *Provider) Forecast(hours int) (Forecast, error) {
+	est := p.current.Load()
+	if est == nil {
+		return Forecast{}, ErrNotReady
+	}
+	hours = min(max(hours, 1), MaxForecastHours)
+	return p.seasons.forecast(est, hours, p.clock.Now()), nil
+}
+
+// CapHitCount returns the total number of tier fees lowered by fee caps.
+func (p *Provider) CapHitCount() uint64 {
+	return p.capHits.Load()
+}
+
 // Verify interface compliance at compile time.
 var (
 	_ EstimateReader   = (*Provider)(nil)
+	_ FeeCapEnforcer   = (*Provider)(nil)
+	_ HistoryReader    = (*Provider)(nil)
+	_ SnapshotReader   = (*Provider)(nil)
+	_ AccuracyReader   = (*Provider)(nil)
+	_ ForecastReader   = (*Provider)(nil)
+	_ RenderedReader   = (*Provider)(nil)
+	_ ChangeNotifier   = (*Provider)(nil)
 	_ ReadinessChecker = (*Provider)(nil)
 )
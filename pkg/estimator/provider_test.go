@@ -3,6 +3,9 @@ package estimator
 import (
 	"context"
 	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
 )
 
 func TestProvider(t *testing.T) {
@@ -44,4 +47,298 @@ func TestProvider(t *testing.T) {
 	if got != est2 {
 		t.Error("Current() returned different pointer")
 	}
+	if est.Version != 1 || est2.Version != 2 {
+		t.Errorf("Versions = %d, %d; want 1, 2", est.Version, est2.Version)
+	}
+	if est.UpdatedAt.IsZero() || est2.UpdatedAt.Before(est.UpdatedAt) {
+		t.Errorf("UpdatedAt = %v, %v; want set and ordered", est.UpdatedAt, est2.UpdatedAt)
+	}
+}
+
+func TestProvider_Recent(t *testing.T) {
+	p := NewProvider()
+	start := time.Now()
+
+	p.Update(&GasEstimate{BlockNumber: 1, Timestamp: start})
+	p.Update(&GasEstimate{BlockNumber: 2, Timestamp: start.Add(time.Second)})
+	// Same block: replaces the previous entry
+	final := &GasEstimate{BlockNumber: 2, Timestamp: start.Add(2 * time.Second)}
+	p.Update(final)
+	p.Update(&GasEstimate{BlockNumber: 3, Timestamp: start.Add(3 * time.Second)})
+
+	got := p.Recent(time.Time{})
+	if len(got) != 3 {
+		t.Fatalf("Recent() len = %d, want 3", len(got))
+	}
+	if got[0].BlockNumber != 1 || got[2].BlockNumber != 3 {
+		t.Errorf("Recent() order = %d..%d, want 1..3", got[0].BlockNumber, got[2].BlockNumber)
+	}
+	if got[1] != final {
+		t.Error("Recent() did not keep the final estimate for block 2")
+	}
+
+	got = p.Recent(start.Add(1500 * time.Millisecond))
+	if len(got) != 2 {
+		t.Errorf("Recent(since) len = %d, want 2", len(got))
+	}
+}
+
+func TestProvider_AtBlock(t *testing.T) {
+	p := NewProvider(WithHistoryCapacity(3))
+	for n := uint64(1); n <= 4; n++ {
+		p.Update(&GasEstimate{BlockNumber: n})
+	}
+	final := &GasEstimate{BlockNumber: 4}
+	p.Update(final)
+
+	if got, ok := p.AtBlock(4); !ok || got != final {
+		t.Errorf("AtBlock(4) = %v, %v, want the final estimate", got, ok)
+	}
+	if got, ok := p.AtBlock(2); !ok || got.BlockNumber != 2 {
+		t.Errorf("AtBlock(2) = %v, %v, want block 2", got, ok)
+	}
+	// Evicted by capacity
+	if _, ok := p.AtBlock(1); ok {
+		t.Error("AtBlock(1) found an evicted block")
+	}
+	if _, ok := p.AtBlock(5); ok {
+		t.Error("AtBlock(5) found a future block")
+	}
+
+	// After a reorg the replacement estimate wins
+	replacement := &GasEstimate{BlockNumber: 3}
+	p.Update(replacement)
+	if got, _ := p.AtBlock(3); got != replacement {
+		t.Error("AtBlock(3) did not return the estimate after the reorg")
+	}
+}
+
+func TestProvider_AtVersion(t *testing.T) {
+	p := NewProvider(WithHistoryCapacity(2))
+	first := &GasEstimate{BlockNumber: 1}
+	p.Update(first)
+	if got, ok := p.AtVersion(1, first.Version); !ok || got != first {
+		t.Errorf("AtVersion(current) = %v, %v, want the current estimate", got, ok)
+	}
+
+	// A newer estimate for the same block supersedes the earlier version
+	final := &GasEstimate{BlockNumber: 1}
+	p.Update(final)
+	p.Update(&GasEstimate{BlockNumber: 2})
+	if _, ok := p.AtVersion(1, first.Version); ok {
+		t.Error("AtVersion found a superseded version")
+	}
+	if got, ok := p.AtVersion(1, final.Version); !ok || got != final {
+		t.Errorf("AtVersion(final) = %v, %v, want the block's final estimate", got, ok)
+	}
+
+	// Evicted by capacity
+	p.Update(&GasEstimate{BlockNumber: 3})
+	if _, ok := p.AtVersion(1, final.Version); ok {
+		t.Error("AtVersion found an evicted estimate")
+	}
+}
+
+func TestProvider_StaleUpdate(t *testing.T) {
+	p := NewProvider()
+	slow, fast := p.NextGeneration(), p.NextGeneration()
+
+	newer := &GasEstimate{BlockNumber: 10, Generation: fast}
+	if !p.Update(newer) {
+		t.Fatal("Update rejected the first estimate")
+	}
+	// The slower calculation finishes last, even for a later block
+	if p.Update(&GasEstimate{BlockNumber: 11, Generation: slow}) {
+		t.Error("Update accepted an estimate from an earlier generation")
+	}
+	if got, _ := p.Current(context.Background()); got != newer {
+		t.Errorf("Current() = block %d, want the newer estimate", got.BlockNumber)
+	}
+	if n := p.ConflictCount(); n != 1 {
+		t.Errorf("ConflictCount() = %d, want 1", n)
+	}
+	if n := p.UpdateCount(); n != 1 {
+		t.Errorf("UpdateCount() = %d, want 1", n)
+	}
+
+	// A reorg to a lower block from a later calculation is accepted
+	if !p.Update(&GasEstimate{BlockNumber: 9, Generation: p.NextGeneration()}) {
+		t.Error("Update rejected a newer generation for a lower block")
+	}
+	// Unordered estimates, e.g. replicated ones, are always accepted
+	if !p.Update(&GasEstimate{BlockNumber: 8}) {
+		t.Error("Update rejected an estimate without a generation")
+	}
+}
+
+func TestProvider_Changed(t *testing.T) {
+	p := NewProvider()
+	changed := p.Changed()
+	select {
+	case <-changed:
+		t.Fatal("Changed() closed before an update")
+	default:
+	}
+
+	p.Update(&GasEstimate{BlockNumber: 1})
+	select {
+	case <-changed:
+	default:
+		t.Fatal("Changed() not closed by Update")
+	}
+	if p.Changed() == changed {
+		t.Error("Changed() returned the closed channel after the update")
+	}
+}
+
+func TestProvider_CopyOnRead(t *testing.T) {
+	p := NewProvider(WithCopyOnRead())
+	est := &GasEstimate{
+		BlockNumber: 1,
+		BaseFee:     uint256.NewInt(100),
+		Urgent:      PriorityEstimate{MaxPriorityFeePerGas: uint256.NewInt(5)},
+	}
+	p.Update(est)
+
+	got, err := p.Current(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == est {
+		t.Fatal("Current() returned the shared estimate")
+	}
+
+	// Mutating the copy must not leak into the published estimate
+	got.BaseFee.SetUint64(1)
+	got.Urgent.MaxPriorityFeePerGas.SetUint64(1)
+
+	again, _ := p.Current(context.Background())
+	if again.BaseFee.Uint64() != 100 || again.Urgent.MaxPriorityFeePerGas.Uint64() != 5 {
+		t.Errorf("published estimate mutated: base fee %s, urgent %s",
+			again.BaseFee, again.Urgent.MaxPriorityFeePerGas)
+	}
+}
+
+func TestGasEstimate_Clone(t *testing.T) {
+	est := &GasEstimate{
+		BlockNumber:  7,
+		BaseFee:      uint256.NewInt(10),
+		Distribution: &FeeDistribution{Historical: []*uint256.Int{uint256.NewInt(3)}},
+	}
+
+	c := est.Clone()
+	if c.BlockNumber != 7 || !c.BaseFee.Eq(est.BaseFee) || c.Slow.MaxFeePerGas != nil {
+		t.Fatalf("Clone() = %+v", c)
+	}
+	if c.BaseFee == est.BaseFee || c.Distribution == est.Distribution ||
+		c.Distribution.Historical[0] == est.Distribution.Historical[0] {
+		t.Error("Clone() shares memory with the original")
+	}
+	if (*GasEstimate)(nil).Clone() != nil {
+		t.Error("nil Clone() != nil")
+	}
+}
+
+func TestProvider_FeeCaps(t *testing.T) {
+	gwei := func(v uint64) *uint256.Int { return uint256.NewInt(v * 1e9) }
+	tier := func(tip, maxFee uint64) PriorityEstimate {
+		return PriorityEstimate{MaxPriorityFeePerGas: gwei(tip), MaxFeePerGas: gwei(maxFee)}
+	}
+
+	p := NewProvider(WithFeeCaps(FeeCaps{MaxPriorityFeePerGas: gwei(300), MaxFeePerGas: gwei(2000)}))
+	shared := gwei(500)
+	est := &GasEstimate{
+		Urgent:   PriorityEstimate{MaxPriorityFeePerGas: shared, MaxFeePerGas: gwei(2500)},
+		Fast:     tier(100, 2100),
+		Standard: tier(50, 150),
+		Slow:     tier(1, 21),
+	}
+	p.Update(est)
+
+	want := []struct {
+		name        string
+		got         PriorityEstimate
+		tip, maxFee uint64
+	}{
+		{"urgent", est.Urgent, 300, 2000},
+		{"fast", est.Fast, 100, 2000},
+		{"standard", est.Standard, 50, 150},
+		{"slow", est.Slow, 1, 21},
+	}
+	for _, w := range want {
+		if !w.got.MaxPriorityFeePerGas.Eq(gwei(w.tip)) || !w.got.MaxFeePerGas.Eq(gwei(w.maxFee)) {
+			t.Errorf("%s = %v/%v, want %d/%d gwei", w.name,
+				w.got.MaxPriorityFeePerGas, w.got.MaxFeePerGas, w.tip, w.maxFee)
+		}
+	}
+	if !shared.Eq(gwei(500)) {
+		t.Error("capping modified a shared value in place")
+	}
+	if got := p.CapHitCount(); got != 3 {
+		t.Errorf("CapHitCount() = %d, want 3", got)
+	}
+
+	// The tip never exceeds a capped max fee
+	p = NewProvider(WithFeeCaps(FeeCaps{MaxFeePerGas: gwei(100)}))
+	est = &GasEstimate{Urgent: tier(150, 400)}
+	p.Update(est)
+	if !est.Urgent.MaxPriorityFeePerGas.Eq(gwei(100)) {
+		t.Errorf("tip = %v, want capped to max fee", est.Urgent.MaxPriorityFeePerGas)
+	}
+}
+
+func TestProvider_Rendered(t *testing.T) {
+	ctx := context.Background()
+
+	// Without a renderer the estimate is returned unencoded
+	plain := NewProvider()
+	plain.Update(&GasEstimate{BlockNumber: 1})
+	if est, body, err := plain.Rendered(ctx); err != nil || est == nil || body != nil {
+		t.Errorf("Rendered() without renderer = %v, %q, %v", est, body, err)
+	}
+
+	renders := 0
+	p := NewProvider(WithRenderer(func(est *GasEstimate) []byte {
+		renders++
+		return []byte{byte(est.BlockNumber), byte(est.Version)}
+	}))
+	if _, _, err := p.Rendered(ctx); err != ErrNotReady {
+		t.Errorf("Rendered() before Update error = %v, want ErrNotReady", err)
+	}
+
+	est := &GasEstimate{BlockNumber: 7}
+	p.Update(est)
+	for i := 0; i < 3; i++ {
+		got, body, err := p.Rendered(ctx)
+		if err != nil || got != est || string(body) != "\x07\x01" {
+			t.Fatalf("Rendered() = %v, %q, %v; want the published estimate and its encoding", got, body, err)
+		}
+	}
+	if renders != 1 {
+		t.Errorf("renderer called %d times, want once per update", renders)
+	}
+}
+
+func TestProvider_Mirror(t *testing.T) {
+	ctx := context.Background()
+	p := NewProvider()
+	published := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	p.Mirror(&GasEstimate{BlockNumber: 7, Version: 42, UpdatedAt: published})
+
+	est, err := p.Current(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.Version != 42 || !est.UpdatedAt.Equal(published) {
+		t.Errorf("mirrored Version, UpdatedAt = %d, %v; want 42, %v kept", est.Version, est.UpdatedAt, published)
+	}
+	if _, ok := p.AtVersion(7, 42); !ok {
+		t.Error("AtVersion(7, 42) not found after Mirror")
+	}
+
+	// Publishing locally after a promotion continues past the leader's versions
+	p.Update(&GasEstimate{BlockNumber: 8})
+	if est, _ := p.Current(ctx); est.Version != 43 {
+		t.Errorf("Version after Update = %d, want 43", est.Version)
+	}
 }
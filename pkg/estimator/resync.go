@@ -0,0 +1,134 @@
+package estimator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+)
+
+// ErrResyncing is returned by Recalculate while the node re-syncs (see
+// WithResyncGuard); the previous estimate stays published.
+var ErrResyncing = errors.New("node resyncing")
+
+// WithResyncGuard stops publishing estimates while the node re-syncs, since
+// estimates from a chain it is still rebuilding are meaningless. A re-sync
+// is detected when eth_syncing, polled every pollInterval, reports progress
+// (the client must implement eth.SyncStatusReader) or when a new head
+// replaces more than maxRewind blocks. Meanwhile new blocks are dropped,
+// Recalculate returns ErrResyncing and ChainStatus reports Resyncing. Once
+// the node no longer reports syncing and its head is back at the one before
+// the rewind, the history is reloaded and publishing resumes. Zero disables
+// either check. Disabled by default.
+func WithResyncGuard(pollInterval time.Duration, maxRewind uint64) Option {
+	return func(e *Estimator) {
+		e.resyncPoll = pollInterval
+		e.maxRewind = maxRewind
+	}
+}
+
+// resyncState tracks a node re-sync in progress.
+type resyncState struct {
+	active atomic.Bool
+	count  atomic.Uint64
+
+	mu      sync.Mutex
+	since   time.Time
+	syncing bool   // the node's last eth_syncing answer
+	target  uint64 // head to reach before resuming after a rewind
+}
+
+// caughtUp reports whether a head numbered head ends the re-sync.
+func (r *resyncState) caughtUp(head uint64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.syncing && head >= r.target
+}
+
+// status reports whether a re-sync is in progress and since when.
+func (r *resyncState) status() (bool, time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.active.Load() {
+		return false, time.Time{}
+	}
+	return true, r.since
+}
+
+// enterResync stops publishing until the node is synced and its head
+// reaches target.
+func (e *Estimator) enterResync(target uint64, attrs ...any) {
+	r := &e.resync
+	r.mu.Lock()
+	r.target = max(r.target, target)
+	entered := !r.active.Load()
+	if entered {
+		r.since = e.clock.Now()
+		r.count.Add(1)
+		r.active.Store(true)
+	}
+	r.mu.Unlock()
+
+	if entered {
+		e.logger.Warn("node resyncing, estimates paused", attrs...)
+	}
+}
+
+// leaveResync reloads the history the re-sync invalidated and resumes
+// publishing. If the history cannot be reloaded, the re-sync goes on and
+// the next head retries.
+func (e *Estimator) leaveResync(ctx context.Context) {
+	e.state.reset()
+	if err := e.loadHistory(ctx); err != nil {
+		e.logger.Warn("reloading history after node resync", "error", err)
+		return
+	}
+
+	r := &e.resync
+	r.mu.Lock()
+	since := r.since
+	r.target = 0
+	r.active.Store(false)
+	r.mu.Unlock()
+
+	e.logger.Info("node caught up, estimates resumed", "resyncing_for", e.clock.Now().Sub(since))
+	e.Recalculate(ctx)
+}
+
+// trackResync checks a new head that replaced depth blocks for a rewind and
+// reports whether it must be dropped because the node is re-syncing. The
+// head that ends a re-sync is dropped too, as it is part of the reloaded
+// history.
+func (e *Estimator) trackResync(ctx context.Context, block *eth.Block, depth uint64) bool {
+	if e.maxRewind > 0 && depth > e.maxRewind {
+		// The previous head was depth-1 blocks above this one
+		e.enterResync(block.Number+depth-1, "reason", "head rewound", "block", block.Number, "depth", depth)
+	}
+	if !e.resync.active.Load() {
+		return false
+	}
+	if e.resync.caughtUp(block.Number) {
+		e.leaveResync(ctx)
+	}
+	return true
+}
+
+// pollSyncing asks the node whether it is syncing. Publishing resumes on
+// the first head after it stops (see trackResync).
+func (e *Estimator) pollSyncing(ctx context.Context, reader eth.SyncStatusReader) {
+	st, err := reader.Syncing(ctx)
+	if err != nil {
+		e.logger.Debug("polling node sync status", "error", err)
+		return
+	}
+
+	e.resync.mu.Lock()
+	e.resync.syncing = st.Syncing
+	e.resync.mu.Unlock()
+	if st.Syncing {
+		e.enterResync(0, "reason", "node syncing", "current_block", st.CurrentBlock, "highest_block", st.HighestBlock)
+	}
+}
@@ -0,0 +1,123 @@
+package estimator
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+// syncingClient is a chain whose head is at head and that answers
+// eth_syncing with syncing.
+type syncingClient struct {
+	mockBlockReader
+	head    atomic.Uint64
+	syncing atomic.Bool
+}
+
+func newSyncingClient(head uint64) *syncingClient {
+	c := &syncingClient{}
+	c.head.Store(head)
+	block := func(number uint64) *eth.Block {
+		return &eth.Block{Number: number, BaseFee: uint256.NewInt(10e9), GasUsed: 15e6, GasLimit: 30e6}
+	}
+	c.chainIDFunc = func(ctx context.Context) (uint64, error) { return 1, nil }
+	c.latestBlockFunc = func(ctx context.Context) (*eth.Block, error) { return block(c.head.Load()), nil }
+	c.blockByNumberFunc = func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+		return block(number.Uint64()), nil
+	}
+	return c
+}
+
+func (c *syncingClient) Syncing(ctx context.Context) (eth.SyncStatus, error) {
+	if !c.syncing.Load() {
+		return eth.SyncStatus{}, nil
+	}
+	return eth.SyncStatus{Syncing: true, CurrentBlock: c.head.Load(), HighestBlock: c.head.Load() + 1000}, nil
+}
+
+func TestEstimator_ResyncOnRewind(t *testing.T) {
+	ctx := context.Background()
+	client := newSyncingClient(100)
+	provider := NewProvider()
+	e := New(client, nil, nil, provider, WithHistorySize(5), WithResyncGuard(0, 5))
+	if err := e.Bootstrap(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// A reorg within the limit is no re-sync
+	e.IngestBlock(ctx, &eth.Block{Number: 97, BaseFee: uint256.NewInt(10e9)})
+	if st := e.ChainStatus(); st.Resyncing {
+		t.Fatal("resyncing after a 4 block reorg, want only past 5 blocks")
+	}
+	e.IngestBlock(ctx, &eth.Block{Number: 100, BaseFee: uint256.NewInt(10e9)})
+
+	e.IngestBlock(ctx, &eth.Block{Number: 90, BaseFee: uint256.NewInt(10e9)})
+	if st := e.ChainStatus(); !st.Resyncing || st.Resyncs != 1 {
+		t.Fatalf("status after rewinding 11 blocks = %+v, want resyncing", st)
+	}
+	if err := e.Recalculate(ctx); !errors.Is(err, ErrResyncing) {
+		t.Errorf("Recalculate() error = %v, want ErrResyncing", err)
+	}
+
+	// Heads below the one before the rewind stay out of the history
+	e.IngestBlock(ctx, &eth.Block{Number: 99, BaseFee: uint256.NewInt(10e9)})
+	if st := e.ChainStatus(); !st.Resyncing {
+		t.Fatal("resumed before the head caught up")
+	}
+	if est, _ := provider.Current(ctx); est.BlockNumber != 100 {
+		t.Errorf("published block %d while resyncing, want 100 kept", est.BlockNumber)
+	}
+
+	client.head.Store(101)
+	e.IngestBlock(ctx, &eth.Block{Number: 101, BaseFee: uint256.NewInt(10e9)})
+	if st := e.ChainStatus(); st.Resyncing || st.Resyncs != 1 {
+		t.Fatalf("status after catching up = %+v, want resumed", st)
+	}
+	if est, _ := provider.Current(ctx); est.BlockNumber != 101 {
+		t.Errorf("published block %d after catching up, want 101", est.BlockNumber)
+	}
+	if blocks, _ := e.state.snapshot(); len(blocks) != 5 || blocks[0].Number != 101 || blocks[4].Number != 97 {
+		t.Errorf("history after catching up has %d blocks, want 97-101 reloaded", len(blocks))
+	}
+}
+
+func TestEstimator_ResyncWhileSyncing(t *testing.T) {
+	ctx := context.Background()
+	client := newSyncingClient(100)
+	client.syncing.Store(true)
+	provider := NewProvider()
+	e := New(client, nil, nil, provider, WithHistorySize(5), WithResyncGuard(time.Minute, 0))
+
+	// A node syncing at startup holds back the first estimate
+	if err := e.Bootstrap(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if provider.Ready() {
+		t.Error("published an estimate while the node is syncing")
+	}
+	if st := e.ChainStatus(); !st.Resyncing || st.ResyncingSince.IsZero() {
+		t.Fatalf("status = %+v, want resyncing", st)
+	}
+
+	client.head.Store(101)
+	e.IngestBlock(ctx, &eth.Block{Number: 101, BaseFee: uint256.NewInt(10e9)})
+	if !e.ChainStatus().Resyncing {
+		t.Fatal("resumed while the node still reports syncing")
+	}
+
+	client.syncing.Store(false)
+	e.pollSyncing(ctx, client)
+	client.head.Store(102)
+	e.IngestBlock(ctx, &eth.Block{Number: 102, BaseFee: uint256.NewInt(10e9)})
+	if st := e.ChainStatus(); st.Resyncing {
+		t.Fatalf("status after the node synced = %+v, want resumed", st)
+	}
+	if est, err := provider.Current(ctx); err != nil || est.BlockNumber != 102 {
+		t.Errorf("Current() = %v, %v, want block 102", est, err)
+	}
+}
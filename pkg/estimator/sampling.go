@@ -0,0 +1,314 @@
+package estimator
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+// SamplingPolicy selects how pending transactions are sampled from the mempool.
+type SamplingPolicy string
+
+const (
+	// SampleMostRecent keeps the most recently seen transactions (LocalTxPool).
+	// Cheapest, but a burst of similar transactions can dominate the sample.
+	SampleMostRecent SamplingPolicy = "recent"
+
+	// SampleReservoir keeps a uniform random sample of all transactions seen
+	// in the current time window (ReservoirPool).
+	SampleReservoir SamplingPolicy = "reservoir"
+
+	// SampleStratified keeps recent transactions per priority fee band and
+	// returns them in proportion to each band's share of the window (StratifiedPool).
+	SampleStratified SamplingPolicy = "stratified"
+)
+
+// ParseSamplingPolicy validates a policy name.
+func ParseSamplingPolicy(s string) (SamplingPolicy, error) {
+	switch p := SamplingPolicy(s); p {
+	case SampleMostRecent, SampleReservoir, SampleStratified:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown sampling policy %q", s)
+	}
+}
+
+// NewTxSampler creates a sampler for policy holding up to size transactions.
+// window is the observation window for reservoir and stratified policies.
+func NewTxSampler(policy SamplingPolicy, size int, window time.Duration, clock Clock) TxSampler {
+	return newBoundedSampler(policy, size, 0, window, clock)
+}
+
+// newBoundedSampler is NewTxSampler with the transactions the sampler
+// retains in total capped at retained, if positive. The reservoir policy
+// keeps two windows' samples and the stratified policy one sample per fee
+// band, so they get smaller samples or bands to fit.
+func newBoundedSampler(policy SamplingPolicy, size, retained int, window time.Duration, clock Clock) TxSampler {
+	switch policy {
+	case SampleReservoir:
+		if retained > 0 {
+			size = min(size, retained/2)
+		}
+		return NewReservoirPool(size, window, clock)
+	case SampleStratified:
+		bandSize := size
+		if retained > 0 {
+			size = min(size, retained)
+			bandSize = min(size, max(retained/len(feeBandEdgesGwei), 1))
+		}
+		return newStratifiedPool(size, bandSize, window, clock)
+	default:
+		if retained > 0 {
+			size = min(size, retained)
+		}
+		return NewLocalTxPool(size)
+	}
+}
+
+// evictionCounter is implemented by samplers that count the transactions
+// they dropped to stay within their size.
+type evictionCounter interface {
+	Evicted() uint64
+}
+
+// ReservoirPool keeps a uniform random sample (Algorithm R) of the
+// transactions seen in the current window. When a window ends its sample is
+// retained to pad the next window's sample until that fills up, so the
+// snapshot never collapses to a handful of transactions at a window boundary.
+type ReservoirPool struct {
+	mu     sync.Mutex
+	size   int
+	window time.Duration
+	clock  Clock
+	rng    *rand.Rand
+
+	start    time.Time
+	seen     uint64
+	current  []*TxData
+	previous []*TxData
+
+	evicted atomic.Uint64
+}
+
+// NewReservoirPool creates a reservoir sampler. A size of 0 or less disables sampling.
+func NewReservoirPool(size int, window time.Duration, clock Clock) *ReservoirPool {
+	size = max(size, 0)
+	return &ReservoirPool{
+		size:    size,
+		window:  window,
+		clock:   clock,
+		rng:     rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+		start:   clock.Now(),
+		current: make([]*TxData, 0, size),
+	}
+}
+
+// Add offers tx to the reservoir.
+func (p *ReservoirPool) Add(tx *eth.Transaction) {
+	if p.size == 0 {
+		return
+	}
+	data := newTxData(tx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rollLocked()
+
+	p.seen++
+	if len(p.current) < p.size {
+		p.current = append(p.current, data)
+		return
+	}
+	if j := p.rng.Uint64N(p.seen); j < uint64(p.size) {
+		p.current[j] = data
+		p.evicted.Add(1)
+	}
+}
+
+// Evicted returns the number of sampled transactions replaced by later ones.
+func (p *ReservoirPool) Evicted() uint64 {
+	return p.evicted.Load()
+}
+
+// Snapshot returns the current window's sample, padded from the previous window.
+func (p *ReservoirPool) Snapshot() []*TxData {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rollLocked()
+
+	res := make([]*TxData, 0, p.size)
+	res = append(res, p.current...)
+	for i := 0; len(res) < p.size && i < len(p.previous); i++ {
+		res = append(res, p.previous[i])
+	}
+	return res
+}
+
+// rollLocked starts a new window if the current one has elapsed.
+func (p *ReservoirPool) rollLocked() {
+	now := p.clock.Now()
+	if p.window <= 0 || now.Sub(p.start) < p.window {
+		return
+	}
+	if len(p.current) > 0 {
+		p.previous = p.current
+	}
+	p.current = make([]*TxData, 0, p.size)
+	p.seen = 0
+	p.start = now
+}
+
+// feeBandEdgesGwei are the lower edges of the priority fee bands used by StratifiedPool.
+var feeBandEdgesGwei = []uint64{0, 1, 2, 5, 10, 20, 50, 100}
+
+// StratifiedPool keeps the most recent transactions of each priority fee band
+// and counts how many transactions each band received during the window.
+// Snapshot allocates the sample across bands in proportion to those counts,
+// so the fee distribution reflects the whole window rather than the last burst.
+type StratifiedPool struct {
+	mu     sync.Mutex
+	size   int
+	window time.Duration
+	clock  Clock
+
+	bands []*LocalTxPool
+	start time.Time
+	cur   []uint64 // arrivals per band in the current window
+	prev  []uint64 // arrivals per band in the previous window
+}
+
+// NewStratifiedPool creates a stratified sampler. A size of 0 or less disables sampling.
+func NewStratifiedPool(size int, window time.Duration, clock Clock) *StratifiedPool {
+	// Each band can hold the whole sample in case one band dominates
+	return newStratifiedPool(size, size, window, clock)
+}
+
+// newStratifiedPool creates a stratified sampler whose bands hold up to
+// bandSize transactions each.
+func newStratifiedPool(size, bandSize int, window time.Duration, clock Clock) *StratifiedPool {
+	size = max(size, 0)
+	n := len(feeBandEdgesGwei)
+	bands := make([]*LocalTxPool, n)
+	for i := range bands {
+		bands[i] = NewLocalTxPool(bandSize)
+	}
+	return &StratifiedPool{
+		size:   size,
+		window: window,
+		clock:  clock,
+		bands:  bands,
+		start:  clock.Now(),
+		cur:    make([]uint64, n),
+		prev:   make([]uint64, n),
+	}
+}
+
+// Add records tx in its fee band.
+func (p *StratifiedPool) Add(tx *eth.Transaction) {
+	if p.size == 0 {
+		return
+	}
+	band := feeBand(tx)
+
+	p.mu.Lock()
+	p.rollLocked()
+	p.cur[band]++
+	p.mu.Unlock()
+
+	p.bands[band].Add(tx)
+}
+
+// Snapshot returns a sample whose band mix matches the window's arrivals.
+func (p *StratifiedPool) Snapshot() []*TxData {
+	p.mu.Lock()
+	p.rollLocked()
+	counts := make([]uint64, len(p.cur))
+	var total uint64
+	for i := range counts {
+		// Blend in the previous window so allocations don't reset abruptly
+		counts[i] = p.cur[i] + p.prev[i]
+		total += counts[i]
+	}
+	p.mu.Unlock()
+
+	if total == 0 {
+		return nil
+	}
+
+	res := make([]*TxData, 0, p.size)
+	for i, band := range p.bands {
+		quota := int((counts[i]*uint64(p.size) + total/2) / total)
+		if quota == 0 {
+			continue
+		}
+		txs := band.Snapshot()
+		// Most recent entries are at the end
+		if len(txs) > quota {
+			txs = txs[len(txs)-quota:]
+		}
+		res = append(res, txs...)
+	}
+	return res
+}
+
+// Evicted returns the number of transactions dropped from full bands.
+func (p *StratifiedPool) Evicted() uint64 {
+	var n uint64
+	for _, band := range p.bands {
+		n += band.Evicted()
+	}
+	return n
+}
+
+func (p *StratifiedPool) rollLocked() {
+	now := p.clock.Now()
+	if p.window <= 0 || now.Sub(p.start) < p.window {
+		return
+	}
+	copy(p.prev, p.cur)
+	clear(p.cur)
+	p.start = now
+}
+
+// feeBand returns the band index for a transaction's priority fee
+// (GasPrice for legacy transactions, which bounds the tip from above).
+func feeBand(tx *eth.Transaction) int {
+	fee := tx.MaxPriorityFeePerGas
+	if !tx.IsEIP1559() || fee == nil {
+		fee = tx.GasPrice
+	}
+	if fee == nil {
+		return 0
+	}
+
+	gwei := new(uint256.Int).Div(fee, uint256.NewInt(1e9))
+	if !gwei.IsUint64() {
+		return len(feeBandEdgesGwei) - 1
+	}
+	g := gwei.Uint64()
+
+	band := 0
+	for i, edge := range feeBandEdgesGwei {
+		if g >= edge {
+			band = i
+		}
+	}
+	return band
+}
+
+// Verify interface compliance at compile time.
+var (
+	_ TxSampler = (*ReservoirPool)(nil)
+	_ TxSampler = (*StratifiedPool)(nil)
+
+	_ evictionCounter = (*LocalTxPool)(nil)
+	_ evictionCounter = (*ReservoirPool)(nil)
+	_ evictionCounter = (*StratifiedPool)(nil)
+)
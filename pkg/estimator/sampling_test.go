@@ -0,0 +1,88 @@
+package estimator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+func makeSampleTx(feeGwei uint64) *eth.Transaction {
+	return &eth.Transaction{
+		Type:                 2,
+		MaxPriorityFeePerGas: uint256.NewInt(feeGwei * 1e9),
+		MaxFeePerGas:         uint256.NewInt(feeGwei * 2e9),
+	}
+}
+
+func TestReservoirPool(t *testing.T) {
+	clock := &manualClock{now: time.Unix(1000, 0)}
+	pool := NewReservoirPool(10, time.Minute, clock)
+
+	for i := 0; i < 1000; i++ {
+		pool.Add(makeSampleTx(uint64(i)))
+	}
+	snap := pool.Snapshot()
+	if len(snap) != 10 {
+		t.Fatalf("Snapshot len = %d, want 10", len(snap))
+	}
+
+	// A uniform sample of 0..999 should not be just the last 10 transactions
+	recent := 0
+	for _, tx := range snap {
+		if tx.MaxPriorityFeePerGas.Uint64() >= 990e9 {
+			recent++
+		}
+	}
+	if recent == 10 {
+		t.Error("reservoir sample contains only the most recent transactions")
+	}
+
+	// New window: previous sample pads the new one
+	clock.now = clock.now.Add(2 * time.Minute)
+	pool.Add(makeSampleTx(5000))
+	snap = pool.Snapshot()
+	if len(snap) != 10 {
+		t.Fatalf("Snapshot len after roll = %d, want 10", len(snap))
+	}
+	if snap[0].MaxPriorityFeePerGas.Uint64() != 5000e9 {
+		t.Errorf("snap[0] = %v, want the new window's transaction first", snap[0].MaxPriorityFeePerGas)
+	}
+}
+
+func TestStratifiedPool(t *testing.T) {
+	clock := &manualClock{now: time.Unix(1000, 0)}
+	pool := NewStratifiedPool(10, time.Minute, clock)
+
+	// 50% high fee (>=100 gwei) early in the window, then a burst of low-fee txs
+	for i := 0; i < 100; i++ {
+		pool.Add(makeSampleTx(150))
+	}
+	for i := 0; i < 100; i++ {
+		pool.Add(makeSampleTx(1))
+	}
+
+	snap := pool.Snapshot()
+	high := 0
+	for _, tx := range snap {
+		if tx.MaxPriorityFeePerGas.Uint64() >= 100e9 {
+			high++
+		}
+	}
+	// Most-recent sampling would return only the 1 gwei burst
+	if high != 5 {
+		t.Errorf("high-fee txs in sample = %d, want 5 (of %d)", high, len(snap))
+	}
+}
+
+func TestZeroSizeSamplers(t *testing.T) {
+	clock := &manualClock{now: time.Unix(1000, 0)}
+	for _, policy := range []SamplingPolicy{SampleMostRecent, SampleReservoir, SampleStratified} {
+		s := NewTxSampler(policy, 0, time.Minute, clock)
+		s.Add(makeSampleTx(1)) // must not panic
+		if n := len(s.Snapshot()); n != 0 {
+			t.Errorf("%s: Snapshot len = %d, want 0", policy, n)
+		}
+	}
+}
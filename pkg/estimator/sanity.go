@@ -0,0 +1,169 @@
+package estimator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/holiman/uint256"
+)
+
+// ErrImplausibleEstimate is returned by Recalculate when the sanity guard
+// rejects the calculated estimate; the previous estimate stays published.
+var ErrImplausibleEstimate = errors.New("implausible estimate")
+
+// SanityCheck identifies an invariant published estimates must hold.
+type SanityCheck string
+
+const (
+	// SanityPriorityAboveMaxFee: a tier's priority fee exceeds its max
+	// fee, which nodes reject.
+	SanityPriorityAboveMaxFee SanityCheck = "priority_above_max_fee"
+
+	// SanityBaseFeeJump: the predicted base fee differs from the head
+	// block's by more than SanityLimits.MaxBaseFeeChange.
+	SanityBaseFeeJump SanityCheck = "base_fee_jump"
+
+	// SanityZeroTier: a tier's priority or max fee is zero while the
+	// mempool is congested (see SanityLimits.CongestedMempool).
+	SanityZeroTier SanityCheck = "zero_tier"
+)
+
+// SanityViolation is one broken invariant. Tier is empty for checks of the
+// estimate as a whole.
+type SanityViolation struct {
+	Check  SanityCheck
+	Tier   string
+	Detail string
+}
+
+// Rejection is a calculated estimate the sanity guard refused to publish.
+type Rejection struct {
+	ChainID     uint64
+	BlockNumber uint64
+	DetectedAt  time.Time
+	Violations  []SanityViolation
+	Estimate    *GasEstimate
+}
+
+// SanityLimits configures the sanity guard. A zero limit disables that
+// check; priority fees above max fees are always rejected.
+type SanityLimits struct {
+	// MaxBaseFeeChange is the largest relative difference allowed between
+	// the predicted base fee and the head block's.
+	// Default: 0.125, the most EIP-1559 moves it per block on mainnet
+	MaxBaseFeeChange float64
+
+	// CongestedMempool is the number of includable pending transactions
+	// at which the mempool counts as congested and zero tiers are
+	// rejected.
+	// Default: 100
+	CongestedMempool int
+}
+
+// DefaultSanityLimits returns the default sanity limits.
+func DefaultSanityLimits() SanityLimits {
+	return SanityLimits{
+		MaxBaseFeeChange: 0.125,
+		CongestedMempool: 100,
+	}
+}
+
+// WithSanityGuard checks every calculated estimate against invariants (see
+// SanityCheck) before publishing it. An estimate that breaks one is not
+// published, so the previous estimate stays current; the rejection is
+// logged, counted in DebugSnapshot.EstimatesRejected and passed to handler,
+// if not nil. handler runs on the recalculation path and must not block.
+// Disabled by default.
+func WithSanityGuard(limits SanityLimits, handler func(Rejection)) Option {
+	return func(e *Estimator) {
+		e.sanity = &limits
+		e.onRejection = handler
+	}
+}
+
+// EstimatesRejected returns the number of estimates the sanity guard has
+// refused to publish (see WithSanityGuard).
+func (e *Estimator) EstimatesRejected() uint64 {
+	return e.rejections.Load()
+}
+
+// checkSanity returns the invariants est, calculated from input, breaks.
+func checkSanity(limits SanityLimits, input *CalculatorInput, est *GasEstimate) []SanityViolation {
+	var found []SanityViolation
+	congested := limits.CongestedMempool > 0 && est.MempoolIncludable >= limits.CongestedMempool
+	for _, tier := range []struct {
+		name string
+		p    *PriorityEstimate
+	}{
+		{"urgent", &est.Urgent}, {"fast", &est.Fast}, {"standard", &est.Standard}, {"slow", &est.Slow},
+	} {
+		tip, maxFee := tier.p.MaxPriorityFeePerGas, tier.p.MaxFeePerGas
+		if tip != nil && maxFee != nil && tip.Gt(maxFee) {
+			found = append(found, SanityViolation{
+				Check:  SanityPriorityAboveMaxFee,
+				Tier:   tier.name,
+				Detail: fmt.Sprintf("priority fee %s wei above max fee %s wei", tip, maxFee),
+			})
+		}
+		if congested && (tip == nil || tip.IsZero() || maxFee == nil || maxFee.IsZero()) {
+			found = append(found, SanityViolation{
+				Check:  SanityZeroTier,
+				Tier:   tier.name,
+				Detail: fmt.Sprintf("zero fee with %d includable pending transactions", est.MempoolIncludable),
+			})
+		}
+	}
+
+	if parent := input.CurrentBlock.BaseFee; limits.MaxBaseFeeChange > 0 && parent != nil && !parent.IsZero() && est.BaseFee != nil {
+		if change := baseFeeChange(parent, est.BaseFee); change > limits.MaxBaseFeeChange {
+			found = append(found, SanityViolation{
+				Check:  SanityBaseFeeJump,
+				Detail: fmt.Sprintf("base fee %s wei is %.1f%% away from block %d's %s wei", est.BaseFee, change*100, input.CurrentBlock.Number, parent),
+			})
+		}
+	}
+	return found
+}
+
+// baseFeeChange is the relative difference between next and parent.
+func baseFeeChange(parent, next *uint256.Int) float64 {
+	diff := new(uint256.Int)
+	if next.Gt(parent) {
+		diff.Sub(next, parent)
+	} else {
+		diff.Sub(parent, next)
+	}
+	return weiToFloat(diff) / weiToFloat(parent)
+}
+
+// rejectImplausible returns an ErrImplausibleEstimate error if est breaks
+// an invariant, after reporting the rejection.
+func (e *Estimator) rejectImplausible(input *CalculatorInput, est *GasEstimate) error {
+	violations := checkSanity(*e.sanity, input, est)
+	if len(violations) == 0 {
+		return nil
+	}
+	e.rejections.Add(1)
+	checks := make([]string, len(violations))
+	for i, v := range violations {
+		checks[i] = string(v.Check)
+		e.logger.Warn("implausible estimate",
+			"block", est.BlockNumber,
+			"check", v.Check,
+			"tier", v.Tier,
+			"detail", v.Detail,
+		)
+	}
+	if e.onRejection != nil {
+		e.onRejection(Rejection{
+			ChainID:     input.ChainID,
+			BlockNumber: est.BlockNumber,
+			DetectedAt:  e.clock.Now(),
+			Violations:  violations,
+			Estimate:    est,
+		})
+	}
+	return fmt.Errorf("%w for block %d: %s", ErrImplausibleEstimate, est.BlockNumber, strings.Join(checks, ", "))
+}
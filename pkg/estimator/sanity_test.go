@@ -0,0 +1,115 @@
+package estimator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+func TestCheckSanity(t *testing.T) {
+	input := &CalculatorInput{CurrentBlock: &BlockData{Number: 100, BaseFee: uint256.NewInt(10e9)}}
+	tier := func(tip, maxFee uint64) PriorityEstimate {
+		return PriorityEstimate{MaxPriorityFeePerGas: uint256.NewInt(tip), MaxFeePerGas: uint256.NewInt(maxFee)}
+	}
+	estimate := func(baseFee uint64, includable int, slow PriorityEstimate) *GasEstimate {
+		return &GasEstimate{
+			BaseFee:           uint256.NewInt(baseFee),
+			MempoolIncludable: includable,
+			Urgent:            tier(3e9, 23e9),
+			Fast:              tier(2e9, 22e9),
+			Standard:          tier(1e9, 21e9),
+			Slow:              slow,
+		}
+	}
+
+	tests := []struct {
+		name string
+		est  *GasEstimate
+		want []SanityCheck
+	}{
+		{"sane", estimate(11e9, 500, tier(5e8, 205e8)), nil},
+		{"largest EIP-1559 step", estimate(11.25e9, 0, tier(5e8, 205e8)), nil},
+		{"zero tier on a quiet mempool", estimate(10e9, 10, tier(0, 20e9)), nil},
+		{"priority above max fee", estimate(10e9, 0, tier(5e9, 4e9)), []SanityCheck{SanityPriorityAboveMaxFee}},
+		{"base fee jump", estimate(12e9, 0, tier(5e8, 205e8)), []SanityCheck{SanityBaseFeeJump}},
+		{"base fee drop", estimate(8e9, 0, tier(5e8, 205e8)), []SanityCheck{SanityBaseFeeJump}},
+		{"zero tier when congested", estimate(10e9, 500, tier(0, 20e9)), []SanityCheck{SanityZeroTier}},
+		{"missing tier when congested", estimate(10e9, 500, PriorityEstimate{}), []SanityCheck{SanityZeroTier}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkSanity(DefaultSanityLimits(), input, tt.est)
+			if len(got) != len(tt.want) {
+				t.Fatalf("violations = %+v, want %v", got, tt.want)
+			}
+			for i, v := range got {
+				if v.Check != tt.want[i] {
+					t.Errorf("violation %d = %s, want %s", i, v.Check, tt.want[i])
+				}
+			}
+		})
+	}
+
+	if got := checkSanity(SanityLimits{}, input, estimate(20e9, 500, tier(0, 20e9))); len(got) != 0 {
+		t.Errorf("zero limits reported %+v", got)
+	}
+}
+
+// baseFeeStrategy is a staticStrategy predicting baseFee.
+type baseFeeStrategy struct {
+	staticStrategy
+	baseFee uint64
+}
+
+func (s *baseFeeStrategy) Calculate(ctx context.Context, input *CalculatorInput) (*GasEstimate, error) {
+	est, err := s.staticStrategy.Calculate(ctx, input)
+	if err == nil {
+		est.BaseFee = uint256.NewInt(s.baseFee)
+	}
+	return est, err
+}
+
+func TestEstimator_SanityGuard(t *testing.T) {
+	client := &mockBlockReader{
+		chainIDFunc: func(ctx context.Context) (uint64, error) { return 1, nil },
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) {
+			return &eth.Block{Number: 100, BaseFee: uint256.NewInt(100), GasUsed: 15e6, GasLimit: 30e6}, nil
+		},
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			return &eth.Block{Number: number.Uint64(), BaseFee: uint256.NewInt(100), GasUsed: 15e6, GasLimit: 30e6}, nil
+		},
+	}
+	strategy := &baseFeeStrategy{staticStrategy: staticStrategy{name: "static", tip: 10}, baseFee: 100}
+	provider := NewProvider()
+	var rejections []Rejection
+	e := New(client, &mockTxReader{}, nil, provider,
+		WithHistorySize(5),
+		WithStrategy(strategy),
+		WithSanityGuard(DefaultSanityLimits(), func(r Rejection) { rejections = append(rejections, r) }),
+	)
+	ctx := context.Background()
+	if err := e.Bootstrap(ctx); err != nil {
+		t.Fatal(err)
+	}
+	published, err := provider.Current(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	strategy.baseFee = 200
+	if err := e.Recalculate(ctx); !errors.Is(err, ErrImplausibleEstimate) {
+		t.Fatalf("Recalculate() error = %v, want ErrImplausibleEstimate", err)
+	}
+	if current, _ := provider.Current(ctx); current != published {
+		t.Error("rejected estimate replaced the published one")
+	}
+	if len(rejections) != 1 || rejections[0].Violations[0].Check != SanityBaseFeeJump || rejections[0].ChainID != 1 {
+		t.Errorf("rejections = %+v, want one base fee jump", rejections)
+	}
+	if e.EstimatesRejected() != 1 || e.DebugSnapshot().EstimatesRejected != 1 {
+		t.Errorf("rejected = %d, want 1", e.EstimatesRejected())
+	}
+}
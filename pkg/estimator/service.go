@@ -0,0 +1,216 @@
+package estimator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+)
+
+// Options configures a Service.
+// Zero values fall back to the same defaults used by New.
+type Options struct {
+	// NodeHTTPURL is the JSON-RPC endpoint used for block and transaction fetches. Required.
+	NodeHTTPURL string
+
+	// NodeWSURL is the WebSocket endpoint used for subscriptions. Required.
+	NodeWSURL string
+
+	// NodeAuth holds credentials sent to both endpoints. Optional.
+	NodeAuth eth.Auth
+
+	HistorySize    int
+	MempoolSamples int
+	RecalcInterval time.Duration
+	Strategy       Strategy
+	Logger         *slog.Logger
+
+	// CopyOnRead makes Current return deep copies (see WithCopyOnRead).
+	CopyOnRead bool
+
+	// FeeCaps bounds published fees (see WithFeeCaps). Optional.
+	FeeCaps FeeCaps
+}
+
+// Service wires an eth.Client, eth.WSSubscriber, Provider and Estimator
+// together for in-process embedding.
+//
+// Typical usage:
+//
+//	svc, err := estimator.NewService(estimator.Options{NodeHTTPURL: httpURL, NodeWSURL: wsURL})
+//	if err != nil { ... }
+//	if err := svc.Start(ctx); err != nil { ... }
+//	defer svc.Stop(context.Background())
+//	est, err := svc.Current(ctx)
+type Service struct {
+	client     *eth.Client
+	subscriber *eth.WSSubscriber
+	provider   *Provider
+	estimator  *Estimator
+	logger     *slog.Logger
+
+	mu      sync.Mutex
+	started bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+	err     error
+}
+
+// NewService validates opts and builds all components. Nothing is started
+// and no connections are made until Start is called.
+func NewService(opts Options) (*Service, error) {
+	if opts.NodeHTTPURL == "" {
+		return nil, errors.New("NodeHTTPURL is required")
+	}
+	if opts.NodeWSURL == "" {
+		return nil, errors.New("NodeWSURL is required")
+	}
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	var estOpts []Option
+	if opts.HistorySize > 0 {
+		estOpts = append(estOpts, WithHistorySize(opts.HistorySize))
+	}
+	if opts.MempoolSamples > 0 {
+		estOpts = append(estOpts, WithMempoolSamples(opts.MempoolSamples))
+	}
+	if opts.RecalcInterval > 0 {
+		estOpts = append(estOpts, WithRecalcInterval(opts.RecalcInterval))
+	}
+	if opts.Strategy != nil {
+		estOpts = append(estOpts, WithStrategy(opts.Strategy))
+	}
+	estOpts = append(estOpts, WithLogger(logger))
+
+	client := eth.NewClient(opts.NodeHTTPURL, eth.WithClientAuth(opts.NodeAuth))
+	subscriber := eth.NewWSSubscriber(opts.NodeWSURL, logger, eth.WithSubscriberAuth(opts.NodeAuth))
+	var providerOpts []ProviderOption
+	if opts.CopyOnRead {
+		providerOpts = append(providerOpts, WithCopyOnRead())
+	}
+	if opts.FeeCaps.enabled() {
+		providerOpts = append(providerOpts, WithFeeCaps(opts.FeeCaps))
+	}
+	provider := NewProvider(providerOpts...)
+
+	return &Service{
+		client:     client,
+		subscriber: subscriber,
+		provider:   provider,
+		estimator:  New(client, client, subscriber, provider, estOpts...),
+		logger:     logger.With("component", "service"),
+		done:       make(chan struct{}),
+	}, nil
+}
+
+// Start runs the estimator in the background. It returns immediately;
+// use Ready, Done and Err to observe progress. A Service can only be started once.
+func (s *Service) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return errors.New("service already started")
+	}
+	s.started = true
+
+	runCtx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+
+	go func() {
+		defer close(s.done)
+		err := s.estimator.Run(runCtx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			s.logger.Error("estimator stopped", "error", err)
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+		}
+	}()
+
+	return nil
+}
+
+// Stop cancels the estimator, waits for it to exit (or ctx to expire),
+// and releases node connections. Safe to call multiple times.
+func (s *Service) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	started, cancel := s.started, s.cancel
+	s.mu.Unlock()
+
+	if started {
+		cancel()
+		select {
+		case <-s.done:
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for estimator: %w", ctx.Err())
+		}
+	}
+
+	subErr := s.subscriber.Close()
+	clientErr := s.client.Close()
+	return errors.Join(subErr, clientErr)
+}
+
+// Current returns the latest estimate. See Provider.Current.
+func (s *Service) Current(ctx context.Context) (*GasEstimate, error) {
+	return s.provider.Current(ctx)
+}
+
+// Ready reports whether the first estimate has been produced.
+func (s *Service) Ready() bool {
+	return s.provider.Ready()
+}
+
+// Provider returns the underlying Provider, e.g. for wiring into an API server.
+func (s *Service) Provider() *Provider {
+	return s.provider
+}
+
+// DebugSnapshot returns estimator internals. See Estimator.DebugSnapshot.
+func (s *Service) DebugSnapshot() DebugSnapshot {
+	return s.estimator.DebugSnapshot()
+}
+
+// Strategy returns the strategy estimates are calculated with.
+func (s *Service) Strategy() Strategy {
+	return s.estimator.Strategy()
+}
+
+// Evaluate calculates an estimate with strategy from the input of the
+// latest published estimate. See Estimator.Evaluate.
+func (s *Service) Evaluate(ctx context.Context, strategy Strategy) (Evaluation, error) {
+	return s.estimator.Evaluate(ctx, strategy)
+}
+
+// Events returns the estimator's event bus. See Estimator.Events.
+func (s *Service) Events() *Bus {
+	return s.estimator.Events()
+}
+
+// Done is closed when the estimator exits.
+func (s *Service) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the error that caused the estimator to exit, if any.
+func (s *Service) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Verify interface compliance at compile time.
+var (
+	_ EstimateReader   = (*Service)(nil)
+	_ ReadinessChecker = (*Service)(nil)
+	_ DebugReader      = (*Service)(nil)
+)
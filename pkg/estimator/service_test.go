@@ -0,0 +1,54 @@
+package estimator
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestNewService_Validation(t *testing.T) {
+	if _, err := NewService(Options{NodeWSURL: "ws://localhost:8546"}); err == nil {
+		t.Error("NewService() without NodeHTTPURL: want error")
+	}
+	if _, err := NewService(Options{NodeHTTPURL: "http://localhost:8545"}); err == nil {
+		t.Error("NewService() without NodeWSURL: want error")
+	}
+}
+
+func TestService_StartStop(t *testing.T) {
+	svc, err := NewService(Options{
+		NodeHTTPURL: "http://127.0.0.1:1", // nothing listening
+		NodeWSURL:   "ws://127.0.0.1:1",
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	if err := svc.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := svc.Start(context.Background()); err == nil {
+		t.Error("second Start(): want error")
+	}
+
+	select {
+	case <-svc.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("estimator did not exit on unreachable node")
+	}
+	if svc.Err() == nil {
+		t.Error("Err() = nil, want connection error")
+	}
+	if svc.Ready() {
+		t.Error("Ready() = true, want false")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := svc.Stop(ctx); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package estimator
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/holiman/uint256"
+)
+
+// Signature algorithms.
+const (
+	// SigEd25519 is Ed25519 over the signing message.
+	SigEd25519 = "ed25519"
+
+	// SigP256SHA256 is ECDSA on P-256 (secp256r1) over the SHA-256 of the
+	// signing message, encoded as r || s (32 bytes each). This is the form
+	// taken by the P256VERIFY precompile (RIP-7212) on chains that have it.
+	SigP256SHA256 = "ecdsa-p256-sha256"
+)
+
+// signingDomain is the first word of every signing message, so signatures
+// can't be replayed as signatures over anything else.
+var signingDomain = [32]byte{'g', 'o', '-', 'g', 'a', 's', ' ', 'e', 's', 't', 'i', 'm', 'a', 't', 'e', ' ', 'v', '1'}
+
+// signingWords is the length of the signing message in 32-byte words.
+const signingWords = 13
+
+// Signature attests that an estimate was published by the holder of a key.
+// See SigningMessage for what it covers.
+type Signature struct {
+	Algorithm string
+	PublicKey []byte // Ed25519: 32 bytes; P-256: 65-byte uncompressed point
+	Value     []byte
+}
+
+// Signer signs estimates, see WithSigner.
+type Signer interface {
+	Algorithm() string
+	PublicKey() []byte
+	Sign(message []byte) ([]byte, error)
+}
+
+// SigningMessage returns the canonical serialization of est that signatures
+// cover: 13 big-endian 32-byte words, equal to Solidity's
+//
+//	abi.encode(bytes32("go-gas estimate v1"), chainId, blockNumber,
+//	    timestamp, baseFee,
+//	    urgentMaxPriorityFee, urgentMaxFee, fastMaxPriorityFee, fastMaxFee,
+//	    standardMaxPriorityFee, standardMaxFee, slowMaxPriorityFee, slowMaxFee)
+//
+// with all values in wei except timestamp, the estimate's calculation time
+// in Unix seconds. Nil fees encode as zero.
+func SigningMessage(est *GasEstimate) []byte {
+	msg := make([]byte, 0, signingWords*32)
+	msg = append(msg, signingDomain[:]...)
+	word := func(v *uint256.Int) {
+		var b [32]byte
+		if v != nil {
+			b = v.Bytes32()
+		}
+		msg = append(msg, b[:]...)
+	}
+	word(uint256.NewInt(est.ChainID))
+	word(uint256.NewInt(est.BlockNumber))
+	word(uint256.NewInt(uint64(max(est.Timestamp.Unix(), 0))))
+	word(est.BaseFee)
+	for _, p := range []PriorityEstimate{est.Urgent, est.Fast, est.Standard, est.Slow} {
+		word(p.MaxPriorityFeePerGas)
+		word(p.MaxFeePerGas)
+	}
+	return msg
+}
+
+// SignEstimate returns signer's signature over SigningMessage(est).
+func SignEstimate(signer Signer, est *GasEstimate) (*Signature, error) {
+	sig, err := signer.Sign(SigningMessage(est))
+	if err != nil {
+		return nil, err
+	}
+	return &Signature{
+		Algorithm: signer.Algorithm(),
+		PublicKey: signer.PublicKey(),
+		Value:     sig,
+	}, nil
+}
+
+// VerifyEstimate checks est.Signature against est's fields. It only proves
+// the estimate was signed by est.Signature.PublicKey; callers must also
+// check that key is the one they trust.
+func VerifyEstimate(est *GasEstimate) error {
+	sig := est.Signature
+	if sig == nil {
+		return errors.New("estimate is not signed")
+	}
+	msg := SigningMessage(est)
+
+	switch sig.Algorithm {
+	case SigEd25519:
+		if len(sig.PublicKey) != ed25519.PublicKeySize {
+			return errors.New("invalid ed25519 public key")
+		}
+		if !ed25519.Verify(ed25519.PublicKey(sig.PublicKey), msg, sig.Value) {
+			return errors.New("invalid signature")
+		}
+		return nil
+
+	case SigP256SHA256:
+		x, y := elliptic.Unmarshal(elliptic.P256(), sig.PublicKey)
+		if x == nil || len(sig.Value) != 64 {
+			return errors.New("invalid P-256 public key or signature")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		r := new(big.Int).SetBytes(sig.Value[:32])
+		s := new(big.Int).SetBytes(sig.Value[32:])
+		digest := sha256.Sum256(msg)
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown signature algorithm %q", sig.Algorithm)
+	}
+}
+
+// NewSigner returns a Signer for an Ed25519 or P-256 ECDSA private key.
+func NewSigner(key crypto.Signer) (Signer, error) {
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		return ed25519Signer{k}, nil
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("unsupported ECDSA curve %s (want P-256)", k.Curve.Params().Name)
+		}
+		return p256Signer{k}, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T (want Ed25519 or ECDSA P-256)", key)
+	}
+}
+
+// LoadSigner reads a PEM-encoded Ed25519 or P-256 private key (PKCS #8, or
+// SEC 1 "EC PRIVATE KEY"), as generated by e.g.
+//
+//	openssl genpkey -algorithm ed25519 -out signing.pem
+//	openssl genpkey -algorithm EC -pkeyopt ec_paramgen_curve:P-256 -out signing.pem
+func LoadSigner(path string) (Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading signing key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("signing key is not PEM encoded")
+	}
+
+	var key any
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing signing key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing key type %T", key)
+	}
+	return NewSigner(signer)
+}
+
+type ed25519Signer struct {
+	key ed25519.PrivateKey
+}
+
+func (s ed25519Signer) Algorithm() string { return SigEd25519 }
+
+func (s ed25519Signer) PublicKey() []byte {
+	return []byte(s.key.Public().(ed25519.PublicKey))
+}
+
+func (s ed25519Signer) Sign(message []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, message), nil
+}
+
+type p256Signer struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s p256Signer) Algorithm() string { return SigP256SHA256 }
+
+func (s p256Signer) PublicKey() []byte {
+	return elliptic.Marshal(elliptic.P256(), s.key.X, s.key.Y)
+}
+
+func (s p256Signer) Sign(message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+	r, sv, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	sv.FillBytes(sig[32:])
+	return sig, nil
+}
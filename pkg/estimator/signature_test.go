@@ -0,0 +1,109 @@
+package estimator
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+)
+
+func TestSigningMessage(t *testing.T) {
+	est := testSignedEstimate()
+	est.ChainID = 10
+	est.Timestamp = time.Unix(1700000000, 500)
+
+	msg := SigningMessage(est)
+	if len(msg) != 13*32 {
+		t.Fatalf("len = %d, want %d", len(msg), 13*32)
+	}
+	if got := string(msg[:18]); got != "go-gas estimate v1" {
+		t.Errorf("domain = %q", got)
+	}
+	word := func(i int) uint64 { return new(uint256.Int).SetBytes(msg[i*32 : (i+1)*32]).Uint64() }
+	if word(1) != 10 || word(2) != est.BlockNumber || word(3) != 1700000000 {
+		t.Errorf("chain, block, timestamp = %d, %d, %d", word(1), word(2), word(3))
+	}
+	if word(12) != est.Slow.MaxFeePerGas.Uint64() {
+		t.Errorf("slow max fee = %d, want %d", word(12), est.Slow.MaxFeePerGas.Uint64())
+	}
+}
+
+func TestSignEstimate(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	tests := []struct {
+		name string
+		key  crypto.Signer
+		alg  string
+	}{
+		{"Ed25519", edKey, SigEd25519},
+		{"P-256", ecKey, SigP256SHA256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Round-trip the key through a PEM file
+			der, err := x509.MarshalPKCS8PrivateKey(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "key.pem")
+			if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			signer, err := LoadSigner(path)
+			if err != nil {
+				t.Fatalf("LoadSigner() error = %v", err)
+			}
+
+			p := NewProvider(WithSigner(signer))
+			p.Update(testSignedEstimate())
+			est, _ := p.Current(context.Background())
+			if est.Signature == nil || est.Signature.Algorithm != tt.alg {
+				t.Fatalf("Signature = %+v, want algorithm %s", est.Signature, tt.alg)
+			}
+			if err := VerifyEstimate(est); err != nil {
+				t.Errorf("VerifyEstimate() error = %v", err)
+			}
+
+			tampered := est.Clone()
+			tampered.Standard.MaxPriorityFeePerGas = uint256.NewInt(1)
+			if err := VerifyEstimate(tampered); err == nil {
+				t.Error("VerifyEstimate() accepted a tampered estimate")
+			}
+		})
+	}
+
+	t.Run("Unsupported curve", func(t *testing.T) {
+		key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		if _, err := NewSigner(key); err == nil {
+			t.Error("NewSigner() accepted a P-384 key")
+		}
+	})
+}
+
+func testSignedEstimate() *GasEstimate {
+	tier := func(tip, maxFee uint64) PriorityEstimate {
+		return PriorityEstimate{MaxPriorityFeePerGas: uint256.NewInt(tip), MaxFeePerGas: uint256.NewInt(maxFee)}
+	}
+	return &GasEstimate{
+		ChainID:     1,
+		BlockNumber: 19000000,
+		Timestamp:   time.Unix(1700000000, 0),
+		BaseFee:     uint256.NewInt(20e9),
+		Urgent:      tier(3e9, 43e9),
+		Fast:        tier(2e9, 42e9),
+		Standard:    tier(1e9, 41e9),
+		Slow:        tier(5e8, 405e8),
+	}
+}
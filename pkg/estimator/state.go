@@ -0,0 +1,139 @@
+package estimator
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+)
+
+// chainState holds block history and the mempool sample behind a single
+// synchronization point, so a snapshot never pairs a new block with mempool
+// state from before that block.
+//
+// Adding a block takes the write lock; adding transactions and taking
+// snapshots share the read lock. A snapshot therefore observes the history
+// and the pool at the same block boundary. Transactions included in the head
+// block are excluded from snapshots, since they are no longer pending.
+type chainState struct {
+	mu      sync.RWMutex
+	history *History
+	pool    TxSampler
+	mined   map[string]struct{} // hashes of transactions in the head block
+	pending *BlockData          // node's pending block, if fetched
+
+	arrivals atomic.Uint64 // pending transactions added since takeArrivals
+}
+
+func newChainState(history *History, pool TxSampler) *chainState {
+	return &chainState{
+		history: history,
+		pool:    pool,
+		mined:   make(map[string]struct{}),
+	}
+}
+
+// pushBlock adds the head block to history and marks its transactions as mined.
+func (s *chainState) pushBlock(block *eth.Block, data *BlockData) {
+	mined := make(map[string]struct{}, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		if tx.Hash != "" {
+			mined[tx.Hash] = struct{}{}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.history.Push(data)
+	s.mined = mined
+}
+
+// insertBlock adds a block backfilled behind the head to history. Unlike
+// pushBlock it leaves the head's mined transactions alone.
+func (s *chainState) insertBlock(data *BlockData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history.Insert(data)
+}
+
+// reset empties history and forgets the head's mined transactions and the
+// pending block, e.g. before reloading history after the node re-synced.
+func (s *chainState) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history.Clear()
+	s.mined = make(map[string]struct{})
+	s.pending = nil
+}
+
+// gaps returns the block numbers missing from history (see History.Gaps).
+func (s *chainState) gaps() []uint64 {
+	return s.history.Gaps()
+}
+
+// addTx adds a pending transaction to the sample unless it was already mined.
+func (s *chainState) addTx(tx *eth.Transaction) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.mined[tx.Hash]; ok && tx.Hash != "" {
+		return
+	}
+	s.pool.Add(tx)
+	s.arrivals.Add(1)
+}
+
+// takeArrivals returns the number of pending transactions added since the
+// last call.
+func (s *chainState) takeArrivals() uint64 {
+	return s.arrivals.Swap(0)
+}
+
+// setPendingBlock records the node's pending block.
+func (s *chainState) setPendingBlock(data *BlockData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = data
+}
+
+// pendingBlock returns the recorded pending block if it builds on the
+// current head, or nil.
+func (s *chainState) pendingBlock() *BlockData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	head := s.history.Latest()
+	if s.pending == nil || head == nil || s.pending.Number != head.Number+1 {
+		return nil
+	}
+	return s.pending
+}
+
+// latest returns the head block of the history, or nil if it is empty.
+func (s *chainState) latest() *BlockData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.history.Latest()
+}
+
+// snapshot returns blocks (newest first) and pending transactions as of the same head block.
+func (s *chainState) snapshot() ([]*BlockData, []*TxData) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	blocks := s.history.Snapshot()
+	sampled := s.pool.Snapshot()
+	if len(s.mined) == 0 {
+		return blocks, sampled
+	}
+
+	pending := sampled[:0]
+	for _, tx := range sampled {
+		if _, ok := s.mined[tx.Hash]; ok && tx.Hash != "" {
+			continue
+		}
+		pending = append(pending, tx)
+	}
+	return blocks, pending
+}
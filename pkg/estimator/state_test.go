@@ -0,0 +1,38 @@
+package estimator
+
+import (
+	"testing"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+func TestChainState_ExcludesMinedTxs(t *testing.T) {
+	s := newChainState(NewHistory(4), NewLocalTxPool(10))
+
+	pending := func(hash string) *eth.Transaction {
+		return &eth.Transaction{Hash: hash, Type: 2, MaxPriorityFeePerGas: uint256.NewInt(1), MaxFeePerGas: uint256.NewInt(2)}
+	}
+	s.addTx(pending("0xa"))
+	s.addTx(pending("0xb"))
+
+	block := &eth.Block{Number: 1, BaseFee: uint256.NewInt(1), Transactions: []eth.Transaction{{Hash: "0xa"}}}
+	s.pushBlock(block, &BlockData{Number: 1})
+
+	// Already mined, must not re-enter the sample
+	s.addTx(pending("0xa"))
+	s.addTx(pending("0xc"))
+
+	blocks, txs := s.snapshot()
+	if len(blocks) != 1 || blocks[0].Number != 1 {
+		t.Fatalf("blocks = %v, want head 1", blocks)
+	}
+
+	got := make(map[string]bool)
+	for _, tx := range txs {
+		got[tx.Hash] = true
+	}
+	if got["0xa"] || !got["0xb"] || !got["0xc"] || len(txs) != 2 {
+		t.Errorf("pending hashes = %v, want 0xb and 0xc", got)
+	}
+}
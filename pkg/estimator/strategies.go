@@ -0,0 +1,265 @@
+package estimator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/holiman/uint256"
+)
+
+// ErrNoFeeData is returned by single-source strategies when their source has
+// no fee samples. EnsembleStrategy skips members that fail this way.
+var ErrNoFeeData = errors.New("no fee data")
+
+// TierPercentiles are the confidence levels (0.0 to 1.0) of the Urgent,
+// Fast, Standard and Slow tiers, in that order. The zero value selects
+// DefaultTierPercentiles.
+type TierPercentiles [4]float64
+
+// DefaultTierPercentiles are the tier confidence levels used unless a
+// strategy is configured otherwise.
+var DefaultTierPercentiles = TierPercentiles{0.99, 0.90, 0.50, 0.25}
+
+// orDefault returns t, or DefaultTierPercentiles if t is zero.
+func (t TierPercentiles) orDefault() TierPercentiles {
+	if t == (TierPercentiles{}) {
+		return DefaultTierPercentiles
+	}
+	return t
+}
+
+// FeeHistoryStrategy mirrors the eth_feeHistory approach used by many
+// wallets: each tier's tip is the median, across recent blocks, of that
+// block's priority fee at the tier's percentile. It ignores the mempool,
+// which makes it steady but slow to react to sudden demand.
+type FeeHistoryStrategy struct {
+	// Blocks is how many of the most recent blocks are considered.
+	// 0 uses the whole history window.
+	// Default: 10
+	Blocks int
+
+	// MinPriorityFee and MaxPriorityFee bound the tips (in wei).
+	// Default: 1 gwei and 500 gwei
+	MinPriorityFee *uint256.Int
+	MaxPriorityFee *uint256.Int
+
+	// BaseFeeMultiplier buffers the predicted base fee in maxFeePerGas.
+	// Default: 2
+	BaseFeeMultiplier float64
+
+	// Tiers are the tier confidence levels.
+	// Default: DefaultTierPercentiles
+	Tiers TierPercentiles
+
+	// Percentiles selects how percentiles are read from fee samples.
+	// Default: PercentileLinear
+	Percentiles PercentileMethod
+
+	// EIP-1559 parameters; zero selects the mainnet values.
+	ElasticityMultiplier     uint64
+	BaseFeeChangeDenominator uint64
+}
+
+// DefaultFeeHistoryStrategy returns a FeeHistoryStrategy with sensible defaults.
+func DefaultFeeHistoryStrategy() *FeeHistoryStrategy {
+	return &FeeHistoryStrategy{
+		Blocks:            10,
+		MinPriorityFee:    uint256.NewInt(1e9),
+		MaxPriorityFee:    uint256.NewInt(500e9),
+		BaseFeeMultiplier: 2,
+		Percentiles:       PercentileLinear,
+	}
+}
+
+// Name returns the strategy name.
+func (s *FeeHistoryStrategy) Name() string {
+	return "fee_history"
+}
+
+// Calculate computes a gas estimate from per-block fee percentiles.
+// Returns ErrNoFeeData when no recent block paid a priority fee.
+func (s *FeeHistoryStrategy) Calculate(ctx context.Context, input *CalculatorInput) (*GasEstimate, error) {
+	if input.CurrentBlock == nil {
+		return nil, ErrNotReady
+	}
+
+	blocks := input.RecentBlocks
+	if s.Blocks > 0 && len(blocks) > s.Blocks {
+		blocks = blocks[:s.Blocks]
+	}
+	samples := make([]feeSample, 0, len(blocks))
+	fees := 0
+	for _, b := range blocks {
+		if len(b.PriorityFees) > 0 {
+			samples = append(samples, newFeeSample(b.PriorityFees, nil))
+			fees += len(b.PriorityFees)
+		}
+	}
+	if len(samples) == 0 {
+		return nil, ErrNoFeeData
+	}
+
+	tip := func(p float64) *uint256.Int {
+		rewards := make([]*uint256.Int, len(samples))
+		for i, sample := range samples {
+			rewards[i] = sample.value(p, s.Percentiles)
+		}
+		return medianFee(rewards)
+	}
+
+	baseFee := nextBaseFee(input.CurrentBlock, s.ElasticityMultiplier, s.BaseFeeChangeDenominator)
+	est := tieredEstimate(input, s.Tiers, baseFee, s.BaseFeeMultiplier, s.MinPriorityFee, s.MaxPriorityFee, FeeSourceHistory, tip)
+	est.Samples = SampleSizes{Blocks: len(samples), Historical: fees}
+	return est, nil
+}
+
+// MempoolStrategy prices tiers purely from the pending transaction sample:
+// each tier's tip is the sample's effective priority fee at the tier's
+// percentile. It reacts immediately to demand but is noisy when the sample
+// is small.
+type MempoolStrategy struct {
+	// MinPriorityFee and MaxPriorityFee bound the tips (in wei).
+	// Default: 1 gwei and 500 gwei
+	MinPriorityFee *uint256.Int
+	MaxPriorityFee *uint256.Int
+
+	// BaseFeeMultiplier buffers the predicted base fee in maxFeePerGas.
+	// Default: 2
+	BaseFeeMultiplier float64
+
+	// Outliers rejects outlying fees before percentiles are computed.
+	// Default: disabled
+	Outliers OutlierFilter
+
+	// Tiers are the tier confidence levels.
+	// Default: DefaultTierPercentiles
+	Tiers TierPercentiles
+
+	// Percentiles selects how percentiles are read from fee samples.
+	// Default: PercentileLinear
+	Percentiles PercentileMethod
+
+	// EIP-1559 parameters; zero selects the mainnet values.
+	ElasticityMultiplier     uint64
+	BaseFeeChangeDenominator uint64
+}
+
+// DefaultMempoolStrategy returns a MempoolStrategy with sensible defaults.
+func DefaultMempoolStrategy() *MempoolStrategy {
+	return &MempoolStrategy{
+		MinPriorityFee:    uint256.NewInt(1e9),
+		MaxPriorityFee:    uint256.NewInt(500e9),
+		BaseFeeMultiplier: 2,
+		Percentiles:       PercentileLinear,
+	}
+}
+
+// Name returns the strategy name.
+func (s *MempoolStrategy) Name() string {
+	return "mempool"
+}
+
+// Calculate computes a gas estimate from pending transactions.
+// Returns ErrNoFeeData when the sample has no fee-paying transactions.
+func (s *MempoolStrategy) Calculate(ctx context.Context, input *CalculatorInput) (*GasEstimate, error) {
+	if input.CurrentBlock == nil {
+		return nil, ErrNotReady
+	}
+
+	baseFee := nextBaseFee(input.CurrentBlock, s.ElasticityMultiplier, s.BaseFeeChangeDenominator)
+
+	fees := make([]*uint256.Int, 0, len(input.PendingTxs))
+	arena := newFeeArena(len(input.PendingTxs))
+	includable := 0
+	for _, tx := range input.PendingTxs {
+		if !tx.Includable(baseFee) {
+			continue
+		}
+		includable++
+		if fee := tx.effectivePriorityFee(arena.next(), baseFee); !fee.IsZero() {
+			fees = append(fees, fee)
+		}
+	}
+	fees, rejected := s.Outliers.apply(fees)
+	if len(fees) == 0 {
+		return nil, ErrNoFeeData
+	}
+	sample := newFeeSample(fees, nil)
+
+	tip := func(p float64) *uint256.Int { return sample.value(p, s.Percentiles) }
+	est := tieredEstimate(input, s.Tiers, baseFee, s.BaseFeeMultiplier, s.MinPriorityFee, s.MaxPriorityFee, FeeSourceMempool, tip)
+	est.Distribution = &FeeDistribution{Mempool: curve(sample)}
+	est.Samples = SampleSizes{Mempool: len(fees)}
+	est.MempoolSampled = len(input.PendingTxs)
+	est.MempoolIncludable = includable
+	est.MempoolOutliers = rejected
+	est.Demand = demandCurve(input.PendingTxs, baseFee)
+	return est, nil
+}
+
+// tieredEstimate builds an estimate whose tier tips come from tip at
+// percentiles, clamped to [lo, hi], with
+// maxFeePerGas = baseFee * multiplier + tip. source is the tips' source.
+func tieredEstimate(
+	input *CalculatorInput,
+	percentiles TierPercentiles,
+	baseFee *uint256.Int,
+	multiplier float64,
+	lo, hi *uint256.Int,
+	source FeeSource,
+	tip func(p float64) *uint256.Int,
+) *GasEstimate {
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	buffered := scaleFee(baseFee, multiplier)
+
+	now := input.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var tiers [4]PriorityEstimate
+	for i, p := range percentiles.orDefault() {
+		fee := clampFee(new(uint256.Int).Set(tip(p)), lo, hi)
+		tiers[i] = PriorityEstimate{
+			MaxPriorityFeePerGas: fee,
+			MaxFeePerGas:         new(uint256.Int).Add(buffered, fee),
+			Confidence:           p,
+			Sources:              []FeeSource{source},
+		}
+	}
+
+	return &GasEstimate{
+		ChainID:           input.ChainID,
+		BlockNumber:       input.CurrentBlock.Number,
+		Timestamp:         now,
+		BaseFee:           baseFee,
+		BaseFeeMultiplier: multiplier,
+		BlockGasLimit:     input.CurrentBlock.GasLimit,
+		Urgent:            tiers[0],
+		Fast:              tiers[1],
+		Standard:          tiers[2],
+		Slow:              tiers[3],
+	}
+}
+
+// medianFee returns the median of fees, averaging the two middle values for
+// an even count. fees is reordered.
+func medianFee(fees []*uint256.Int) *uint256.Int {
+	slices.SortFunc(fees, func(a, b *uint256.Int) int { return a.Cmp(b) })
+	n := len(fees)
+	if n%2 == 1 {
+		return new(uint256.Int).Set(fees[n/2])
+	}
+	sum := new(uint256.Int).Add(fees[n/2-1], fees[n/2])
+	return sum.Rsh(sum, 1)
+}
+
+// Verify interface compliance at compile time.
+var (
+	_ Strategy = (*FeeHistoryStrategy)(nil)
+	_ Strategy = (*MempoolStrategy)(nil)
+)
@@ -0,0 +1,73 @@
+package estimator
+
+import (
+	"context"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+// FetchTimeouts bounds the estimator's node calls. Zero fields keep their
+// defaults.
+type FetchTimeouts struct {
+	// Block bounds fetching one full block and its receipts, for new heads,
+	// degraded-mode polls, backfills and bootstrap. Default 5s.
+	Block time.Duration
+
+	// Txs bounds one batch of pending transaction lookups, and the nonce
+	// and pending block refreshes. Default 2s.
+	Txs time.Duration
+}
+
+// defaultFetchTimeouts are the FetchTimeouts used for fields left zero.
+var defaultFetchTimeouts = FetchTimeouts{
+	Block: 5 * time.Second,
+	Txs:   2 * time.Second,
+}
+
+// WithFetchTimeouts sets the timeouts of the estimator's node calls, so a
+// stalled node cannot hold up the pipeline for longer than the run lasts.
+func WithFetchTimeouts(t FetchTimeouts) Option {
+	return func(e *Estimator) {
+		if t.Block > 0 {
+			e.timeouts.Block = t.Block
+		}
+		if t.Txs > 0 {
+			e.timeouts.Txs = t.Txs
+		}
+	}
+}
+
+// fetchBlock fetches the full block numbered number within the node
+// budget, waiting at most the block timeout.
+func (e *Estimator) fetchBlock(ctx context.Context, number uint64) (*eth.Block, error) {
+	if err := e.budget.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.timeouts.Block)
+	defer cancel()
+	return e.client.BlockByNumber(ctx, uint256.NewInt(number))
+}
+
+// fetchLatestBlock fetches the latest full block within the node budget,
+// waiting at most the block timeout.
+func (e *Estimator) fetchLatestBlock(ctx context.Context) (*eth.Block, error) {
+	if err := e.budget.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.timeouts.Block)
+	defer cancel()
+	return e.client.LatestBlock(ctx)
+}
+
+// fetchReceipts fetches block's receipts within the node budget, waiting
+// at most the block timeout.
+func (e *Estimator) fetchReceipts(ctx context.Context, reader eth.ReceiptReader, block *eth.Block) ([]eth.Receipt, error) {
+	if err := e.budget.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.timeouts.Block)
+	defer cancel()
+	return reader.BlockReceipts(ctx, block)
+}
@@ -0,0 +1,69 @@
+package estimator
+
+import (
+	"sync"
+	"time"
+)
+
+// BlockTiming traces one new head from the chain to a published estimate.
+type BlockTiming struct {
+	// Number and Timestamp identify the block and when it was produced
+	Number    uint64
+	Timestamp time.Time
+
+	// Arrived is when the estimator was notified of the block, and
+	// Published when the estimate computed from it was published.
+	Arrived   time.Time
+	Published time.Time
+}
+
+// UpdateLatency is the time from the block's arrival to the estimate update.
+func (t BlockTiming) UpdateLatency() time.Duration {
+	return t.Published.Sub(t.Arrived)
+}
+
+// ChainLag is the time from the block's production to the estimate update.
+func (t BlockTiming) ChainLag() time.Duration {
+	return t.Published.Sub(t.Timestamp)
+}
+
+// WithBlockTiming calls fn for every new head once an estimate computed
+// from it is published, from the goroutine that published it, for latency
+// metrics. fn must not block.
+func WithBlockTiming(fn func(BlockTiming)) Option {
+	return func(e *Estimator) {
+		e.onBlockTiming = fn
+	}
+}
+
+// traceBlockTiming pairs each BlockArrived event with the first estimate
+// published for that block and reports the timing to fn. A head superseded
+// before it was priced is not reported.
+func (e *Estimator) traceBlockTiming(fn func(BlockTiming)) {
+	var (
+		mu      sync.Mutex
+		pending *BlockArrived
+	)
+	e.events.BlockArrived.Subscribe(func(ev BlockArrived) {
+		mu.Lock()
+		pending = &ev
+		mu.Unlock()
+	})
+	e.events.EstimateComputed.Subscribe(func(ev EstimateComputed) {
+		mu.Lock()
+		arrived := pending
+		if arrived == nil || arrived.Block.Number != ev.Estimate.BlockNumber {
+			mu.Unlock()
+			return
+		}
+		pending = nil
+		mu.Unlock()
+
+		fn(BlockTiming{
+			Number:    arrived.Block.Number,
+			Timestamp: arrived.Block.Timestamp,
+			Arrived:   arrived.Arrived,
+			Published: ev.Published,
+		})
+	})
+}
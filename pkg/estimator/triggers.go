@@ -0,0 +1,54 @@
+package estimator
+
+import (
+	"errors"
+	"time"
+)
+
+// RecalcTriggers selects what makes Run recalculate the estimate. Triggers
+// combine: any one firing recalculates. The default is every block plus a
+// 200ms ticker.
+type RecalcTriggers struct {
+	// Blocks recalculates as soon as each new head block is ingested.
+	Blocks bool
+
+	// PendingTxs recalculates once this many pending transactions have
+	// arrived since the last recalculation; 0 disables.
+	PendingTxs int
+
+	// Interval recalculates on a ticker; 0 disables.
+	Interval time.Duration
+}
+
+// errNoTriggers is returned by Run when every recalculation trigger is off.
+var errNoTriggers = errors.New("no recalculation triggers enabled")
+
+// WithRecalcTriggers sets what makes Run recalculate. Event-driven triggers
+// avoid a ticker's work during quiet periods and its delay after a busy
+// one; Interval still bounds staleness when events are rare. With Blocks
+// off, IngestBlock does not recalculate either.
+func WithRecalcTriggers(t RecalcTriggers) Option {
+	return func(e *Estimator) {
+		e.recalcOnBlock = t.Blocks
+		e.recalcTxs = t.PendingTxs
+		e.recalcInterval = t.Interval
+	}
+}
+
+// triggers returns the recalculation triggers in effect.
+func (e *Estimator) triggers() RecalcTriggers {
+	return RecalcTriggers{Blocks: e.recalcOnBlock, PendingTxs: e.recalcTxs, Interval: e.recalcInterval}
+}
+
+// countPendingTx counts a pending transaction towards the PendingTxs
+// trigger and asks Run to recalculate when it is reached. Requests made
+// while one is outstanding coalesce.
+func (e *Estimator) countPendingTx() {
+	if e.recalcTxs <= 0 || e.txsSinceRecalc.Add(1) != int64(e.recalcTxs) {
+		return
+	}
+	select {
+	case e.recalcReqs <- struct{}{}:
+	default:
+	}
+}
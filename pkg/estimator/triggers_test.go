@@ -0,0 +1,93 @@
+package estimator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+func TestEstimator_RecalcTriggers(t *testing.T) {
+	client := &mockBlockReader{
+		chainIDFunc: func(ctx context.Context) (uint64, error) { return 1, nil },
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) {
+			return &eth.Block{Number: 100, BaseFee: uint256.NewInt(1e9)}, nil
+		},
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			return &eth.Block{Number: number.Uint64(), BaseFee: uint256.NewInt(1e9)}, nil
+		},
+	}
+
+	t.Run("Pending transactions", func(t *testing.T) {
+		txCh := make(chan *eth.Transaction, 10)
+		sub := &mockFullSubscriber{
+			mockSubscriber: mockSubscriber{
+				subHeadsFunc: func(ctx context.Context) (<-chan *eth.Block, error) {
+					return make(chan *eth.Block), nil
+				},
+			},
+			subFullFunc: func(ctx context.Context) (<-chan *eth.Transaction, error) { return txCh, nil },
+		}
+		provider := NewProvider()
+		e := New(client, &mockTxReader{}, sub, provider,
+			WithHistorySize(5),
+			WithRecalcTriggers(RecalcTriggers{PendingTxs: 3}),
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go e.Run(ctx)
+
+		waitFor := func(what string, cond func() bool) {
+			t.Helper()
+			deadline := time.Now().Add(time.Second)
+			for !cond() {
+				if time.Now().After(deadline) {
+					t.Fatalf("timed out waiting for %s", what)
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}
+		waitFor("bootstrap", func() bool { return provider.UpdateCount() == 1 })
+
+		tx := func(n int) *eth.Transaction {
+			return &eth.Transaction{Hash: fmt.Sprintf("0x%x", n), Type: 2, MaxPriorityFeePerGas: uint256.NewInt(1), MaxFeePerGas: uint256.NewInt(2e9)}
+		}
+		txCh <- tx(0)
+		txCh <- tx(1)
+		waitFor("transactions sampled", func() bool { return len(e.state.pool.Snapshot()) == 2 })
+		time.Sleep(20 * time.Millisecond)
+		if n := provider.UpdateCount(); n != 1 {
+			t.Fatalf("updates after 2 transactions = %d, want 1", n)
+		}
+
+		txCh <- tx(2)
+		waitFor("recalculation after 3 transactions", func() bool { return provider.UpdateCount() == 2 })
+	})
+
+	t.Run("No block trigger", func(t *testing.T) {
+		provider := NewProvider()
+		e := New(client, nil, nil, provider,
+			WithHistorySize(5),
+			WithRecalcTriggers(RecalcTriggers{Interval: time.Second}),
+		)
+		if err := e.Bootstrap(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		e.IngestBlock(context.Background(), &eth.Block{Number: 101, BaseFee: uint256.NewInt(1e9)})
+		if n := provider.UpdateCount(); n != 1 {
+			t.Errorf("updates = %d, want only the bootstrap estimate", n)
+		}
+	})
+
+	t.Run("None enabled", func(t *testing.T) {
+		e := New(client, nil, nil, NewProvider(), WithRecalcTriggers(RecalcTriggers{}))
+		if err := e.Run(context.Background()); !errors.Is(err, errNoTriggers) {
+			t.Errorf("Run() error = %v, want %v", err, errNoTriggers)
+		}
+	})
+}
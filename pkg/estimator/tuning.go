@@ -0,0 +1,364 @@
+package estimator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/holiman/uint256"
+)
+
+// TunableStrategy is a Strategy whose parameters can be listed and
+// overridden by name, e.g. "historical_weight", so other settings can be
+// tried against live data without redeploying. The built-in strategies
+// implement it.
+type TunableStrategy interface {
+	Strategy
+
+	// Params returns the strategy's parameters by name.
+	Params() map[string]any
+
+	// WithParams returns a copy of the strategy with the named parameters
+	// set from their string forms. The strategy itself is not modified.
+	WithParams(values map[string]string) (Strategy, error)
+}
+
+// StrategyEvaluator exposes the estimator's strategy and evaluates other
+// strategies against the input of the latest published estimate.
+// Implemented by Estimator and Service; used by the strategy API.
+type StrategyEvaluator interface {
+	Strategy() Strategy
+	Evaluate(ctx context.Context, strategy Strategy) (Evaluation, error)
+}
+
+// Evaluation is an estimate calculated by another strategy from the same
+// input as the latest published estimate.
+type Evaluation struct {
+	Published *GasEstimate
+	Evaluated *GasEstimate
+}
+
+// publishedInput is the latest published estimate and the input it was
+// calculated from.
+type publishedInput struct {
+	input    *CalculatorInput
+	estimate *GasEstimate
+}
+
+// Strategy returns the strategy estimates are calculated with.
+func (e *Estimator) Strategy() Strategy {
+	return e.strategy
+}
+
+// Evaluate calculates an estimate with strategy from the input of the
+// latest published estimate, labeled like a published one. It returns
+// ErrNotReady before the first estimate is published.
+func (e *Estimator) Evaluate(ctx context.Context, strategy Strategy) (Evaluation, error) {
+	last := e.published.Load()
+	if last == nil {
+		return Evaluation{}, ErrNotReady
+	}
+	est, err := strategy.Calculate(ctx, last.input)
+	if err != nil {
+		return Evaluation{}, err
+	}
+	e.annotate(est, last.input)
+	est.Strategy = strategy.Name()
+	return Evaluation{Published: last.estimate, Evaluated: est}, nil
+}
+
+// param is one named strategy parameter.
+type param struct {
+	name  string
+	value any
+	set   func(string) error
+}
+
+func paramValues(params []param) map[string]any {
+	values := make(map[string]any, len(params))
+	for _, p := range params {
+		values[p.name] = p.value
+	}
+	return values
+}
+
+// setParams sets the named params from values, in name order so the first
+// error reported is stable.
+func setParams(strategy string, params []param, values map[string]string) error {
+	for _, name := range sortedNames(values) {
+		i := slices.IndexFunc(params, func(p param) bool { return p.name == name })
+		if i < 0 {
+			return fmt.Errorf("unknown %s strategy parameter %q", strategy, name)
+		}
+		if err := params[i].set(values[name]); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func sortedNames(values map[string]string) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func weiParam(name string, p **uint256.Int) param {
+	return param{name: name, value: *p, set: func(s string) error {
+		v, err := uint256.FromDecimal(s)
+		if err != nil {
+			return fmt.Errorf("%q is not an amount of wei", s)
+		}
+		*p = v
+		return nil
+	}}
+}
+
+// fractionParam is a float between 0 and 1.
+func fractionParam(name string, p *float64) param {
+	return param{name: name, value: *p, set: func(s string) error {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < 0 || v > 1 {
+			return fmt.Errorf("%q is not between 0 and 1", s)
+		}
+		*p = v
+		return nil
+	}}
+}
+
+// floatParam is a non-negative float.
+func floatParam(name string, p *float64) param {
+	return param{name: name, value: *p, set: func(s string) error {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < 0 {
+			return fmt.Errorf("%q is not a non-negative number", s)
+		}
+		*p = v
+		return nil
+	}}
+}
+
+// intParam is a non-negative int.
+func intParam(name string, p *int) param {
+	return param{name: name, value: *p, set: func(s string) error {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			return fmt.Errorf("%q is not a non-negative integer", s)
+		}
+		*p = v
+		return nil
+	}}
+}
+
+// eip1559Param is an EIP-1559 parameter; zero selects the mainnet value.
+func eip1559Param(name string, p *uint64) param {
+	return param{name: name, value: *p, set: func(s string) error {
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a non-negative integer", s)
+		}
+		*p = v
+		return nil
+	}}
+}
+
+func boolParam(name string, p *bool) param {
+	return param{name: name, value: *p, set: func(s string) error {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", s)
+		}
+		*p = v
+		return nil
+	}}
+}
+
+// tiersParam takes the four tier percentiles (0 to 100), comma-separated,
+// as GAS_TIER_PERCENTILES does.
+func tiersParam(name string, p *TierPercentiles) param {
+	var value [4]float64
+	for i, t := range p.orDefault() {
+		value[i] = math.Round(t*1e6) / 1e4
+	}
+	return param{name: name, value: value, set: func(s string) error {
+		parts := strings.Split(s, ",")
+		if len(parts) != len(p) {
+			return fmt.Errorf("%q is not %d comma-separated percentiles", s, len(p))
+		}
+		var tiers TierPercentiles
+		for i, part := range parts {
+			v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil || v <= 0 || v > 100 || (i > 0 && v/100 > tiers[i-1]) {
+				return fmt.Errorf("%q is not non-increasing percentiles between 0 and 100", s)
+			}
+			tiers[i] = v / 100
+		}
+		*p = tiers
+		return nil
+	}}
+}
+
+func percentilesParam(name string, p *PercentileMethod) param {
+	value := string(*p)
+	if *p == PercentileNearestRank {
+		value = "nearest_rank"
+	}
+	return param{name: name, value: value, set: func(s string) error {
+		m, err := ParsePercentileMethod(s)
+		if err != nil {
+			return err
+		}
+		*p = m
+		return nil
+	}}
+}
+
+func (s *HybridStrategy) params() []param {
+	return []param{
+		weiParam("min_priority_fee", &s.MinPriorityFee),
+		weiParam("max_priority_fee", &s.MaxPriorityFee),
+		fractionParam("historical_weight", &s.HistoricalWeight),
+		fractionParam("pending_block_weight", &s.PendingBlockWeight),
+		fractionParam("smoothing_factor", &s.SmoothingFactor),
+		floatParam("historical_half_life", &s.HistoricalHalfLife),
+		boolParam("gas_weighted", &s.GasWeighted),
+		intParam("base_fee_horizon", &s.BaseFeeHorizon),
+		intParam("trend_window", &s.TrendWindow),
+		floatParam("base_fee_multiplier", &s.BaseFeeMultiplier),
+		tiersParam("tiers", &s.Tiers),
+		percentilesParam("percentiles", &s.Percentiles),
+		eip1559Param("elasticity_multiplier", &s.ElasticityMultiplier),
+		eip1559Param("base_fee_change_denominator", &s.BaseFeeChangeDenominator),
+	}
+}
+
+// Params implements TunableStrategy.
+func (s *HybridStrategy) Params() map[string]any {
+	return paramValues(s.params())
+}
+
+// WithParams implements TunableStrategy.
+func (s *HybridStrategy) WithParams(values map[string]string) (Strategy, error) {
+	c := *s
+	if err := setParams(c.Name(), c.params(), values); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (s *FeeHistoryStrategy) params() []param {
+	return []param{
+		intParam("blocks", &s.Blocks),
+		weiParam("min_priority_fee", &s.MinPriorityFee),
+		weiParam("max_priority_fee", &s.MaxPriorityFee),
+		floatParam("base_fee_multiplier", &s.BaseFeeMultiplier),
+		tiersParam("tiers", &s.Tiers),
+		percentilesParam("percentiles", &s.Percentiles),
+		eip1559Param("elasticity_multiplier", &s.ElasticityMultiplier),
+		eip1559Param("base_fee_change_denominator", &s.BaseFeeChangeDenominator),
+	}
+}
+
+// Params implements TunableStrategy.
+func (s *FeeHistoryStrategy) Params() map[string]any {
+	return paramValues(s.params())
+}
+
+// WithParams implements TunableStrategy.
+func (s *FeeHistoryStrategy) WithParams(values map[string]string) (Strategy, error) {
+	c := *s
+	if err := setParams(c.Name(), c.params(), values); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (s *MempoolStrategy) params() []param {
+	return []param{
+		weiParam("min_priority_fee", &s.MinPriorityFee),
+		weiParam("max_priority_fee", &s.MaxPriorityFee),
+		floatParam("base_fee_multiplier", &s.BaseFeeMultiplier),
+		tiersParam("tiers", &s.Tiers),
+		percentilesParam("percentiles", &s.Percentiles),
+		eip1559Param("elasticity_multiplier", &s.ElasticityMultiplier),
+		eip1559Param("base_fee_change_denominator", &s.BaseFeeChangeDenominator),
+	}
+}
+
+// Params implements TunableStrategy.
+func (s *MempoolStrategy) Params() map[string]any {
+	return paramValues(s.params())
+}
+
+// WithParams implements TunableStrategy.
+func (s *MempoolStrategy) WithParams(values map[string]string) (Strategy, error) {
+	c := *s
+	if err := setParams(c.Name(), c.params(), values); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Params implements TunableStrategy: the combine method and each member's
+// strategy, weight and parameters.
+func (s *EnsembleStrategy) Params() map[string]any {
+	members := make([]map[string]any, len(s.Members))
+	for i, m := range s.Members {
+		members[i] = map[string]any{"strategy": m.Strategy.Name(), "weight": m.Weight}
+		if t, ok := m.Strategy.(TunableStrategy); ok {
+			members[i]["params"] = t.Params()
+		}
+	}
+	return map[string]any{"combine": string(s.Combine), "members": members}
+}
+
+// WithParams implements TunableStrategy. "combine" sets the combine
+// method; other parameters are set on every member that has them, and a
+// parameter no member has is an error.
+func (s *EnsembleStrategy) WithParams(values map[string]string) (Strategy, error) {
+	c := *s
+	c.Members = slices.Clone(s.Members)
+	used := make(map[string]bool)
+	if v, ok := values["combine"]; ok {
+		m, err := ParseCombineMethod(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid combine: %w", err)
+		}
+		c.Combine = m
+		used["combine"] = true
+	}
+	for i, m := range c.Members {
+		t, ok := m.Strategy.(TunableStrategy)
+		if !ok {
+			continue
+		}
+		known := t.Params()
+		own := make(map[string]string)
+		for name, v := range values {
+			if _, ok := known[name]; ok {
+				own[name] = v
+				used[name] = true
+			}
+		}
+		if len(own) == 0 {
+			continue
+		}
+		tuned, err := t.WithParams(own)
+		if err != nil {
+			return nil, err
+		}
+		c.Members[i].Strategy = tuned
+	}
+	for _, name := range sortedNames(values) {
+		if !used[name] {
+			return nil, fmt.Errorf("unknown %s strategy parameter %q", c.Name(), name)
+		}
+	}
+	return &c, nil
+}
@@ -0,0 +1,114 @@
+package estimator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+func TestHybridStrategy_WithParams(t *testing.T) {
+	s := DefaultStrategy()
+	tuned, err := s.WithParams(map[string]string{
+		"historical_weight": "0.7",
+		"min_priority_fee":  "2000000000",
+		"tiers":             "95,80,50,10",
+		"percentiles":       "nearest_rank",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := tuned.(*HybridStrategy)
+	if h.HistoricalWeight != 0.7 || h.MinPriorityFee.Uint64() != 2e9 || h.Tiers != (TierPercentiles{0.95, 0.8, 0.5, 0.1}) || h.Percentiles != PercentileNearestRank {
+		t.Errorf("tuned = %+v", h)
+	}
+	if s.HistoricalWeight != 0.3 || s.MinPriorityFee.Uint64() != 1e9 {
+		t.Errorf("WithParams modified the original: %+v", s)
+	}
+	if got := tuned.(TunableStrategy).Params()["historical_weight"]; got != 0.7 {
+		t.Errorf("Params()[historical_weight] = %v, want 0.7", got)
+	}
+
+	for _, tc := range []struct {
+		values map[string]string
+		want   string
+	}{
+		{map[string]string{"historical_wieght": "0.7"}, `unknown hybrid strategy parameter "historical_wieght"`},
+		{map[string]string{"historical_weight": "1.5"}, "invalid historical_weight"},
+		{map[string]string{"tiers": "50,90,95,99"}, "invalid tiers"},
+		{map[string]string{"gas_weighted": "maybe"}, "invalid gas_weighted"},
+	} {
+		if _, err := s.WithParams(tc.values); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("WithParams(%v) error = %v, want %q", tc.values, err, tc.want)
+		}
+	}
+}
+
+func TestEnsembleStrategy_WithParams(t *testing.T) {
+	s := DefaultEnsembleStrategy()
+	tuned, err := s.WithParams(map[string]string{"combine": "weighted_mean", "blocks": "20", "base_fee_multiplier": "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := tuned.(*EnsembleStrategy)
+	if e.Combine != CombineWeightedMean {
+		t.Errorf("combine = %s, want weighted_mean", e.Combine)
+	}
+	if e.Members[1].Strategy.(*FeeHistoryStrategy).Blocks != 20 {
+		t.Error("blocks not set on the fee history member")
+	}
+	for _, m := range e.Members {
+		if got := m.Strategy.(TunableStrategy).Params()["base_fee_multiplier"]; got != 3.0 {
+			t.Errorf("%s base_fee_multiplier = %v, want 3", m.Strategy.Name(), got)
+		}
+	}
+	if s.Members[1].Strategy.(*FeeHistoryStrategy).Blocks != 10 || s.Combine != CombineMedian {
+		t.Error("WithParams modified the original")
+	}
+
+	if _, err := s.WithParams(map[string]string{"nonsense": "1"}); err == nil {
+		t.Error("WithParams accepted a parameter no member has")
+	}
+}
+
+func TestEstimator_Evaluate(t *testing.T) {
+	client := &mockBlockReader{
+		chainIDFunc: func(ctx context.Context) (uint64, error) { return 1, nil },
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) {
+			return &eth.Block{Number: 100, BaseFee: uint256.NewInt(10e9), GasUsed: 15e6, GasLimit: 30e6}, nil
+		},
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			return &eth.Block{Number: number.Uint64(), BaseFee: uint256.NewInt(10e9), GasUsed: 15e6, GasLimit: 30e6}, nil
+		},
+	}
+	e := New(client, &mockTxReader{}, nil, NewProvider(), WithHistorySize(5))
+	ctx := context.Background()
+
+	if _, err := e.Evaluate(ctx, DefaultStrategy()); !errors.Is(err, ErrNotReady) {
+		t.Fatalf("Evaluate() before the first estimate error = %v, want ErrNotReady", err)
+	}
+	if err := e.Bootstrap(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ceiling, _ := DefaultStrategy().WithParams(map[string]string{"max_priority_fee": "3000000000"})
+	ev, err := e.Evaluate(ctx, ceiling)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Published.BlockNumber != 100 || ev.Evaluated.BlockNumber != 100 {
+		t.Errorf("evaluated block %d, published %d, want both 100", ev.Evaluated.BlockNumber, ev.Published.BlockNumber)
+	}
+	if tip := ev.Evaluated.Urgent.MaxPriorityFeePerGas.Uint64(); tip > 3e9 {
+		t.Errorf("evaluated urgent tip = %d, want at most the 3 gwei ceiling", tip)
+	}
+	if tip := ev.Published.Urgent.MaxPriorityFeePerGas.Uint64(); tip <= 3e9 {
+		t.Errorf("published urgent tip = %d, want above the evaluated ceiling", tip)
+	}
+	if ev.Evaluated.Network.Name != "mainnet" || ev.Evaluated.Strategy != "hybrid" {
+		t.Errorf("evaluated labels = %q %q, want mainnet hybrid", ev.Evaluated.Network.Name, ev.Evaluated.Strategy)
+	}
+}
@@ -0,0 +1,21 @@
+package estimator
+
+import "github.com/branched-services/go-gas/pkg/eth"
+
+// TxTypeCounts returns the number of transactions ingested from blocks and
+// the mempool by type name (see eth.TxTypeName). A rising "unknown" count
+// means the chain has adopted a transaction type this version predates;
+// such transactions are priced by their fee fields where present.
+func (e *Estimator) TxTypeCounts() map[string]uint64 {
+	names := eth.TxTypeNames()
+	counts := make(map[string]uint64, len(names))
+	for i, name := range names {
+		counts[name] = e.txTypes[i].Load()
+	}
+	return counts
+}
+
+// countTxType counts one ingested transaction of type t.
+func (e *Estimator) countTxType(t uint8) {
+	e.txTypes[min(int(t), len(e.txTypes)-1)].Add(1)
+}
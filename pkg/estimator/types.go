@@ -1,6 +1,7 @@
 package estimator
 
 import (
+	"slices"
 	"time"
 
 	"github.com/holiman/uint256"
@@ -9,21 +10,202 @@ import (
 // GasEstimate represents a point-in-time gas price estimate.
 // This struct is immutable - all fields are either value types or
 // treated as read-only. Safe to share across goroutines.
+//
+// The uint256.Int fields are pointers shared by every reader of a published
+// estimate. Callers that need to modify values must work on a Clone, or
+// create the Provider with WithCopyOnRead.
 type GasEstimate struct {
 	// Chain and block context
 	ChainID     uint64
 	BlockNumber uint64
 	Timestamp   time.Time
 
+	// BlockTimestamp is when block BlockNumber was produced; zero if
+	// unknown.
+	BlockTimestamp time.Time
+
+	// Version is assigned by Provider.Update and increases with every
+	// published estimate. Zero for estimates that were never published.
+	Version uint64
+
+	// Generation orders calculations by when their input was assembled
+	// (see Provider.NextGeneration), so Provider.Update can reject one that
+	// finished after a newer one. Zero if unordered, e.g. for estimates
+	// replicated from another instance.
+	Generation uint64
+
+	// UpdatedAt is the wall time Provider.Update published the estimate,
+	// unlike Timestamp, which is when it was calculated. Zero for estimates
+	// that were never published.
+	UpdatedAt time.Time
+
+	// ValidUntil is when the next block is expected, after which the base
+	// fee and competing demand the estimate was computed for have moved on.
+	// Derived from the network's block time, or the recent block cadence
+	// when it is unknown. Zero if the cadence is unknown.
+	ValidUntil time.Time
+
 	// Predicted base fee for next block (EIP-1559)
 	BaseFee *uint256.Int
 
+	// BaseFeeMultiplier is the factor applied to BaseFee when computing each
+	// tier's MaxFeePerGas (2 for the classic fixed buffer).
+	BaseFeeMultiplier float64
+
 	// Priority fee estimates at different confidence levels
 	// Higher confidence = faster inclusion, higher price
 	Urgent   PriorityEstimate // 99th percentile, ~1 block inclusion
 	Fast     PriorityEstimate // 90th percentile, ~3 blocks
 	Standard PriorityEstimate // 50th percentile, ~6 blocks
 	Slow     PriorityEstimate // 25th percentile, ~12+ blocks
+
+	// MempoolSampled is the number of pending transactions the strategy
+	// considered, and MempoolIncludable how many of them could pay the
+	// predicted base fee. Only includable transactions inform the estimate.
+	MempoolSampled    int
+	MempoolIncludable int
+
+	// MempoolOutliers is the number of pending transaction fees the
+	// strategy rejected as outliers for this estimate.
+	MempoolOutliers int
+
+	// Samples is how much data the tiers were derived from; zero for
+	// sources the strategy does not use.
+	Samples SampleSizes
+
+	// Distribution is the raw priority fee percentile curve the tiers were
+	// derived from. Nil if the strategy does not report it.
+	Distribution *FeeDistribution
+
+	// Inclusion is the percentile curve, spaced by DistributionStep, of the
+	// cheapest priority fee included in each recent block with fee-paying
+	// transactions: a tip at the p-th point would have made it into a
+	// fraction p of them. Set by the Estimator for ForDeadline; nil when no
+	// recent block had such transactions.
+	Inclusion []*uint256.Int
+
+	// BlockGasLimit is the gas limit of the block the estimate was computed
+	// at, and Demand the pending gas ordered by tip. Together they let
+	// ForGasAmount price transactions by size; Demand is nil if the strategy
+	// does not report it.
+	BlockGasLimit uint64
+	Demand        []DemandPoint
+
+	// Components holds each member's output when produced by an
+	// EnsembleStrategy; nil otherwise.
+	Components []ComponentEstimate
+
+	// Explanation records how the tiers were derived when the estimate was
+	// calculated in explain mode (see WithExplain); nil otherwise.
+	Explanation *Explanation
+
+	// Labels for downstream aggregation, set by the Estimator.
+	Network  Network
+	Strategy string
+
+	// Degraded is set when the estimator lost its block subscription and
+	// is polling for blocks, so estimates may lag the chain.
+	Degraded bool
+
+	// Signature is set by a Provider with a signer (see WithSigner) and
+	// covers the fields in SigningMessage.
+	Signature *Signature
+}
+
+// Clone returns a deep copy of the estimate that shares no memory with e.
+func (e *GasEstimate) Clone() *GasEstimate {
+	if e == nil {
+		return nil
+	}
+
+	c := *e
+	c.BaseFee = cloneInt(e.BaseFee)
+	c.Urgent = e.Urgent.clone()
+	c.Fast = e.Fast.clone()
+	c.Standard = e.Standard.clone()
+	c.Slow = e.Slow.clone()
+	if e.Distribution != nil {
+		c.Distribution = &FeeDistribution{
+			Historical: cloneInts(e.Distribution.Historical),
+			Mempool:    cloneInts(e.Distribution.Mempool),
+		}
+	}
+	c.Inclusion = cloneInts(e.Inclusion)
+	if e.Demand != nil {
+		c.Demand = make([]DemandPoint, len(e.Demand))
+		for i, d := range e.Demand {
+			c.Demand[i] = DemandPoint{Tip: cloneInt(d.Tip), Gas: d.Gas}
+		}
+	}
+	c.Explanation = e.Explanation.clone()
+	if e.Signature != nil {
+		c.Signature = &Signature{
+			Algorithm: e.Signature.Algorithm,
+			PublicKey: slices.Clone(e.Signature.PublicKey),
+			Value:     slices.Clone(e.Signature.Value),
+		}
+	}
+	if e.Components != nil {
+		c.Components = make([]ComponentEstimate, len(e.Components))
+		for i, comp := range e.Components {
+			comp.Estimate = comp.Estimate.Clone()
+			c.Components[i] = comp
+		}
+	}
+	return &c
+}
+
+// DistributionStep is the percentile spacing of FeeDistribution curves (5%).
+const DistributionStep = 0.05
+
+// FeeDistribution holds sorted priority fee percentile curves, one value per
+// DistributionStep from the 0th to the 100th percentile (21 points).
+// A curve is empty when its source had no samples.
+type FeeDistribution struct {
+	Historical []*uint256.Int
+	Mempool    []*uint256.Int
+}
+
+// SampleSizes counts the data behind an estimate, so consumers can tell a
+// tier backed by 3 samples from one backed by 30,000.
+type SampleSizes struct {
+	// Blocks is the number of recent blocks that contributed priority fees.
+	Blocks int
+
+	// Historical, Mempool and PendingBlock are the priority fees sampled
+	// from recent blocks, the includable pending transactions (after
+	// outliers were rejected) and the node's pending block.
+	Historical   int
+	Mempool      int
+	PendingBlock int
+}
+
+// FeeSource names a source of priority fees.
+type FeeSource string
+
+// Sources of PriorityEstimate.Sources, in the order they are listed.
+const (
+	FeeSourceHistory      FeeSource = "history"
+	FeeSourceMempool      FeeSource = "mempool"
+	FeeSourcePendingBlock FeeSource = "pending_block"
+
+	// FeeSourceDefault: no source had samples and the fee was derived from
+	// the strategy's bounds.
+	FeeSourceDefault FeeSource = "default"
+)
+
+var feeSourceOrder = []FeeSource{FeeSourceHistory, FeeSourceMempool, FeeSourcePendingBlock, FeeSourceDefault}
+
+// withSources returns sources with add merged in, in feeSourceOrder. The
+// result shares no memory with sources.
+func withSources(sources []FeeSource, add ...FeeSource) []FeeSource {
+	var out []FeeSource
+	for _, s := range feeSourceOrder {
+		if slices.Contains(sources, s) || slices.Contains(add, s) {
+			out = append(out, s)
+		}
+	}
+	return out
 }
 
 // PriorityEstimate represents a gas estimate at a specific confidence level.
@@ -37,6 +219,51 @@ type PriorityEstimate struct {
 
 	// Confidence is the probability of inclusion (0.0 to 1.0)
 	Confidence float64
+
+	// Sources are where the priority fee came from, e.g. history and
+	// mempool when both were blended. Nil if the strategy does not report
+	// them.
+	Sources []FeeSource
+}
+
+// GasPrice is the tier's legacy (type-0) gas price: baseFee, the estimate's
+// predicted base fee, plus the priority fee, but no more than MaxFeePerGas.
+// Legacy transactions pay their whole gas price with no refund, so there is
+// no buffer for base fee increases as MaxFeePerGas has.
+func (p PriorityEstimate) GasPrice(baseFee *uint256.Int) *uint256.Int {
+	price := new(uint256.Int).Set(p.MaxPriorityFeePerGas)
+	if baseFee != nil {
+		price.Add(price, baseFee)
+	}
+	if p.MaxFeePerGas != nil && price.Gt(p.MaxFeePerGas) {
+		price.Set(p.MaxFeePerGas)
+	}
+	return price
+}
+
+func (p PriorityEstimate) clone() PriorityEstimate {
+	p.MaxPriorityFeePerGas = cloneInt(p.MaxPriorityFeePerGas)
+	p.MaxFeePerGas = cloneInt(p.MaxFeePerGas)
+	p.Sources = slices.Clone(p.Sources)
+	return p
+}
+
+func cloneInt(v *uint256.Int) *uint256.Int {
+	if v == nil {
+		return nil
+	}
+	return new(uint256.Int).Set(v)
+}
+
+func cloneInts(vs []*uint256.Int) []*uint256.Int {
+	if vs == nil {
+		return nil
+	}
+	out := make([]*uint256.Int, len(vs))
+	for i, v := range vs {
+		out[i] = cloneInt(v)
+	}
+	return out
 }
 
 // CalculatorInput contains all data needed to compute a gas estimate.
@@ -47,6 +274,17 @@ type CalculatorInput struct {
 	RecentBlocks     []*BlockData
 	PendingTxs       []*TxData
 	PreviousEstimate *GasEstimate
+
+	// PendingBlock is the block the node is building on CurrentBlock, when
+	// available. Its transactions were already selected for the next block.
+	PendingBlock *BlockData
+
+	// Now is the calculation time, used as the estimate timestamp.
+	// Zero means time.Now().
+	Now time.Time
+
+	// Explain asks the strategy to attach an Explanation to the estimate.
+	Explain bool
 }
 
 // BlockData is a simplified view of block data for calculations.
@@ -57,6 +295,11 @@ type BlockData struct {
 	GasUsed      uint64
 	GasLimit     uint64
 	PriorityFees []*uint256.Int // priority fees from included transactions
+
+	// FeeGas is the gas limit of the transaction paying each of
+	// PriorityFees, a proxy for the blockspace it bought. Nil when unknown,
+	// e.g. for blocks seeded from eth_feeHistory.
+	FeeGas []uint64
 }
 
 // GasUtilization returns the ratio of gas used to gas limit.
@@ -69,42 +312,66 @@ func (b *BlockData) GasUtilization() float64 {
 
 // TxData is a simplified view of pending transaction data.
 type TxData struct {
+	Hash                 string
+	From                 string
+	Nonce                uint64
+	Gas                  uint64 // gas limit
 	MaxPriorityFeePerGas *uint256.Int
 	MaxFeePerGas         *uint256.Int
 	GasPrice             *uint256.Int // for legacy transactions
 	IsEIP1559            bool
 }
 
+// Includable reports whether the transaction's fee cap covers baseFee, i.e.
+// whether it could be mined in a block with that base fee.
+func (t *TxData) Includable(baseFee *uint256.Int) bool {
+	if baseFee == nil {
+		return true
+	}
+	if t.IsEIP1559 && t.MaxFeePerGas != nil {
+		return !t.MaxFeePerGas.Lt(baseFee)
+	}
+	if t.GasPrice != nil {
+		return !t.GasPrice.Lt(baseFee)
+	}
+	return false
+}
+
 // EffectivePriorityFee returns the priority fee that would be paid given a base fee.
 func (t *TxData) EffectivePriorityFee(baseFee *uint256.Int) *uint256.Int {
+	return t.effectivePriorityFee(new(uint256.Int), baseFee)
+}
+
+// effectivePriorityFee is EffectivePriorityFee storing the result in z,
+// which it returns.
+func (t *TxData) effectivePriorityFee(z, baseFee *uint256.Int) *uint256.Int {
 	if baseFee == nil || baseFee.IsZero() {
 		if t.IsEIP1559 && t.MaxPriorityFeePerGas != nil {
-			return new(uint256.Int).Set(t.MaxPriorityFeePerGas)
+			return z.Set(t.MaxPriorityFeePerGas)
 		}
 		if t.GasPrice != nil {
-			return new(uint256.Int).Set(t.GasPrice)
+			return z.Set(t.GasPrice)
 		}
-		return uint256.NewInt(0)
+		return z.Clear()
 	}
 
 	if t.IsEIP1559 && t.MaxFeePerGas != nil && t.MaxPriorityFeePerGas != nil {
 		if t.MaxFeePerGas.Lt(baseFee) {
-			return uint256.NewInt(0)
+			return z.Clear()
 		}
-		maxMinusBase := new(uint256.Int).Sub(t.MaxFeePerGas, baseFee)
-
-		if t.MaxPriorityFeePerGas.Lt(maxMinusBase) {
-			return new(uint256.Int).Set(t.MaxPriorityFeePerGas)
+		z.Sub(t.MaxFeePerGas, baseFee)
+		if t.MaxPriorityFeePerGas.Lt(z) {
+			return z.Set(t.MaxPriorityFeePerGas)
 		}
-		return maxMinusBase
+		return z
 	}
 
 	if t.GasPrice != nil {
 		if t.GasPrice.Lt(baseFee) {
-			return uint256.NewInt(0)
+			return z.Clear()
 		}
-		return new(uint256.Int).Sub(t.GasPrice, baseFee)
+		return z.Sub(t.GasPrice, baseFee)
 	}
 
-	return uint256.NewInt(0)
+	return z.Clear()
 }
@@ -0,0 +1,57 @@
+package estimator
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrExpired is returned by a Provider created with WithExpiry when the
+// current estimate is past its validity window.
+var ErrExpired = errors.New("estimate expired")
+
+// blockCadence returns the expected interval between blocks: the network's
+// BlockTime when known, otherwise the mean interval between the given
+// blocks (newest first). Zero when neither is available.
+func blockCadence(network Network, blocks []*BlockData) time.Duration {
+	if network.BlockTime > 0 {
+		return network.BlockTime
+	}
+	if len(blocks) < 2 {
+		return 0
+	}
+	newest, oldest := blocks[0], blocks[len(blocks)-1]
+	if newest.Number <= oldest.Number || !newest.Timestamp.After(oldest.Timestamp) {
+		return 0
+	}
+	return newest.Timestamp.Sub(oldest.Timestamp) / time.Duration(newest.Number-oldest.Number)
+}
+
+// nextBlockTime returns when the block after head is expected: the first
+// multiple of cadence after head's timestamp that is later than now, so
+// missed slots push it forward instead of leaving it in the past.
+// Zero when head's timestamp or the cadence is unknown.
+func nextBlockTime(head time.Time, cadence time.Duration, now time.Time) time.Time {
+	if head.IsZero() || cadence <= 0 {
+		return time.Time{}
+	}
+	if now.Before(head) {
+		return head.Add(cadence)
+	}
+	n := now.Sub(head)/cadence + 1
+	return head.Add(n * cadence)
+}
+
+// Expired reports whether the estimate's validity window ended more than
+// grace before now. Estimates without a ValidUntil never expire.
+func (e *GasEstimate) Expired(now time.Time, grace time.Duration) bool {
+	return !e.ValidUntil.IsZero() && now.After(e.ValidUntil.Add(grace))
+}
+
+// ChainLag is how far the estimate trails the chain at now: the time since
+// its block was produced. Zero when the block time is unknown.
+func (e *GasEstimate) ChainLag(now time.Time) time.Duration {
+	if e.BlockTimestamp.IsZero() {
+		return 0
+	}
+	return max(now.Sub(e.BlockTimestamp), 0)
+}
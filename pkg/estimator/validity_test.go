@@ -0,0 +1,108 @@
+package estimator
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNextBlockTime(t *testing.T) {
+	head := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		now     time.Time
+		cadence time.Duration
+		want    time.Time
+	}{
+		{"within slot", head.Add(3 * time.Second), 12 * time.Second, head.Add(12 * time.Second)},
+		{"missed slot", head.Add(15 * time.Second), 12 * time.Second, head.Add(24 * time.Second)},
+		{"on slot boundary", head.Add(12 * time.Second), 12 * time.Second, head.Add(24 * time.Second)},
+		{"clock behind head", head.Add(-time.Second), 12 * time.Second, head.Add(12 * time.Second)},
+		{"unknown cadence", head, 0, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextBlockTime(head, tt.cadence, tt.now); !got.Equal(tt.want) {
+				t.Errorf("nextBlockTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockCadence(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	blocks := []*BlockData{
+		{Number: 13, Timestamp: start.Add(6 * time.Second)},
+		{Number: 12, Timestamp: start.Add(4 * time.Second)},
+		{Number: 10, Timestamp: start},
+	}
+
+	if got := blockCadence(Network{}, blocks); got != 2*time.Second {
+		t.Errorf("observed cadence = %v, want 2s", got)
+	}
+	if got := blockCadence(Network{BlockTime: 12 * time.Second}, blocks); got != 12*time.Second {
+		t.Errorf("configured cadence = %v, want 12s", got)
+	}
+	if got := blockCadence(Network{}, blocks[:1]); got != 0 {
+		t.Errorf("single block cadence = %v, want 0", got)
+	}
+}
+
+func TestProvider_WithExpiry(t *testing.T) {
+	ctx := context.Background()
+	p := NewProvider(WithExpiry(time.Minute), WithRenderer(func(*GasEstimate) []byte { return []byte("{}\n") }))
+
+	p.Update(&GasEstimate{BlockNumber: 1, ValidUntil: time.Now().Add(-30 * time.Second)})
+	if _, err := p.Current(ctx); err != nil {
+		t.Errorf("Current() within grace error = %v", err)
+	}
+
+	p.Update(&GasEstimate{BlockNumber: 2, ValidUntil: time.Now().Add(-2 * time.Minute)})
+	if _, err := p.Current(ctx); err != ErrExpired {
+		t.Errorf("Current() error = %v, want ErrExpired", err)
+	}
+	if _, _, err := p.Rendered(ctx); err != ErrExpired {
+		t.Errorf("Rendered() error = %v, want ErrExpired", err)
+	}
+	if p.latest() == nil {
+		t.Error("latest() = nil, want the expired estimate")
+	}
+
+	// Without a validity window estimates never expire
+	p.Update(&GasEstimate{BlockNumber: 3})
+	if _, err := p.Current(ctx); err != nil {
+		t.Errorf("Current() without ValidUntil error = %v", err)
+	}
+
+	// Expiry is opt-in
+	p = NewProvider()
+	p.Update(&GasEstimate{BlockNumber: 1, ValidUntil: time.Now().Add(-time.Hour)})
+	if _, err := p.Current(ctx); err != nil {
+		t.Errorf("Current() without WithExpiry error = %v", err)
+	}
+}
+
+func TestProvider_Clock(t *testing.T) {
+	ctx := context.Background()
+	clock := &manualClock{now: time.Unix(1000, 0)}
+	p := NewProvider(WithProviderClock(clock), WithExpiry(time.Minute))
+
+	p.Update(&GasEstimate{BlockNumber: 1, ValidUntil: clock.now.Add(12 * time.Second)})
+	est, err := p.Current(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !est.UpdatedAt.Equal(clock.now) {
+		t.Errorf("UpdatedAt = %v, want the clock's %v", est.UpdatedAt, clock.now)
+	}
+
+	// Expiry follows the clock, not the wall time
+	clock.now = clock.now.Add(72 * time.Second)
+	if _, err := p.Current(ctx); err != nil {
+		t.Errorf("Current() at the end of the grace period error = %v", err)
+	}
+	clock.now = clock.now.Add(time.Second)
+	if _, err := p.Current(ctx); err != ErrExpired {
+		t.Errorf("Current() past the grace period error = %v, want ErrExpired", err)
+	}
+}
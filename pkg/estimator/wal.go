@@ -0,0 +1,311 @@
+package estimator
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/holiman/uint256"
+)
+
+// The input log is JSON lines. A block line holds a history block the
+// first time a calculation uses it, or again when a reorg replaced it; a
+// calculation line holds the rest of a published estimate's input and the
+// tiers that were published. Replaying a calculation reads its history
+// from the newest block lines for its range of heights.
+
+// logRecord is one line of the input log; exactly one field is set.
+type logRecord struct {
+	Block       *logBlock       `json:"block,omitempty"`
+	Calculation *logCalculation `json:"calculation,omitempty"`
+}
+
+type logBlock struct {
+	Number       uint64         `json:"number"`
+	Timestamp    time.Time      `json:"timestamp"`
+	BaseFee      *uint256.Int   `json:"base_fee"`
+	GasUsed      uint64         `json:"gas_used"`
+	GasLimit     uint64         `json:"gas_limit"`
+	PriorityFees []*uint256.Int `json:"priority_fees,omitempty"`
+	FeeGas       []uint64       `json:"fee_gas,omitempty"`
+}
+
+type logTx struct {
+	Hash                 string       `json:"hash"`
+	From                 string       `json:"from,omitempty"`
+	Nonce                uint64       `json:"nonce"`
+	Gas                  uint64       `json:"gas"`
+	MaxPriorityFeePerGas *uint256.Int `json:"max_priority_fee_per_gas,omitempty"`
+	MaxFeePerGas         *uint256.Int `json:"max_fee_per_gas,omitempty"`
+	GasPrice             *uint256.Int `json:"gas_price,omitempty"`
+	IsEIP1559            bool         `json:"eip1559,omitempty"`
+}
+
+type logCalculation struct {
+	Time         time.Time `json:"time"`
+	ChainID      uint64    `json:"chain_id"`
+	BlockNumber  uint64    `json:"block_number"`
+	OldestBlock  uint64    `json:"oldest_block"`
+	PendingTxs   []logTx   `json:"pending_txs"`
+	PendingBlock *logBlock `json:"pending_block,omitempty"`
+
+	// The published estimate
+	BaseFee  *uint256.Int  `json:"base_fee"`
+	Urgent   logTierValues `json:"urgent"`
+	Fast     logTierValues `json:"fast"`
+	Standard logTierValues `json:"standard"`
+	Slow     logTierValues `json:"slow"`
+}
+
+type logTierValues struct {
+	MaxPriorityFeePerGas *uint256.Int `json:"max_priority_fee_per_gas"`
+	MaxFeePerGas         *uint256.Int `json:"max_fee_per_gas"`
+}
+
+// InputLog appends the input of every published estimate to an
+// append-only log: the history blocks it used and the sampled mempool, so
+// the estimate can later be rebuilt with Replay, e.g. to find out why a
+// fee was quoted. Write errors are returned to the estimator, which logs
+// them and carries on.
+type InputLog struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+
+	// logged is the version of each height last written, so unchanged
+	// history blocks are written once
+	logged map[uint64]*BlockData
+}
+
+// NewInputLog returns an InputLog appending to w. Each calculation is
+// flushed to w as a whole.
+func NewInputLog(w io.Writer) *InputLog {
+	return &InputLog{w: bufio.NewWriter(w), logged: make(map[uint64]*BlockData)}
+}
+
+// WithInputLog appends the input of every published estimate to log.
+// Disabled by default.
+func WithInputLog(log *InputLog) Option {
+	return func(e *Estimator) {
+		e.inputLog = log
+	}
+}
+
+// record appends the blocks input uses that are not logged yet and the
+// calculation that published est.
+func (l *InputLog) record(input *CalculatorInput, est *GasEstimate) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	oldest := input.CurrentBlock.Number
+	for _, b := range input.RecentBlocks {
+		oldest = min(oldest, b.Number)
+		if l.logged[b.Number] == b {
+			continue
+		}
+		if err := l.write(logRecord{Block: newLogBlock(b)}); err != nil {
+			return err
+		}
+		l.logged[b.Number] = b
+	}
+	for n := range l.logged {
+		if n < oldest {
+			delete(l.logged, n)
+		}
+	}
+
+	calc := &logCalculation{
+		Time:        input.Now,
+		ChainID:     input.ChainID,
+		BlockNumber: input.CurrentBlock.Number,
+		OldestBlock: oldest,
+		PendingTxs:  make([]logTx, len(input.PendingTxs)),
+		BaseFee:     est.BaseFee,
+		Urgent:      newLogTierValues(est.Urgent),
+		Fast:        newLogTierValues(est.Fast),
+		Standard:    newLogTierValues(est.Standard),
+		Slow:        newLogTierValues(est.Slow),
+	}
+	for i, tx := range input.PendingTxs {
+		calc.PendingTxs[i] = logTx(*tx)
+	}
+	if input.PendingBlock != nil {
+		calc.PendingBlock = newLogBlock(input.PendingBlock)
+	}
+	if err := l.write(logRecord{Calculation: calc}); err != nil {
+		return err
+	}
+	return l.w.Flush()
+}
+
+func (l *InputLog) write(rec logRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encoding input log record: %w", err)
+	}
+	b = append(b, '\n')
+	_, err = l.w.Write(b)
+	return err
+}
+
+func newLogBlock(b *BlockData) *logBlock {
+	lb := logBlock(*b)
+	return &lb
+}
+
+func newLogTierValues(t PriorityEstimate) logTierValues {
+	return logTierValues{MaxPriorityFeePerGas: t.MaxPriorityFeePerGas, MaxFeePerGas: t.MaxFeePerGas}
+}
+
+// ReplayedEstimate is one calculation rebuilt from an input log: the
+// input as recorded, the estimate that was published from it then, and
+// the estimate the replaying strategy computes from it now.
+type ReplayedEstimate struct {
+	Input *CalculatorInput
+
+	// Recorded holds the published base fee and tier fees only
+	Recorded *GasEstimate
+	Replayed *GasEstimate
+}
+
+// Replay rebuilds every calculation in the input log read from r with
+// strategy, oldest first, and passes each to fn. Each replayed estimate is
+// the next calculation's previous estimate, as it was when recording.
+// Replay stops at the first error from the log, strategy or fn, and at a
+// truncated last record.
+func Replay(ctx context.Context, r io.Reader, strategy Strategy, fn func(ReplayedEstimate) error) error {
+	dec := json.NewDecoder(r)
+	blocks := make(map[uint64]*BlockData)
+	var prev *GasEstimate
+	for {
+		var rec logRecord
+		err := dec.Decode(&rec)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			// A truncated last record is still being written, or was cut
+			// off by a crash
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading input log: %w", err)
+		}
+
+		switch {
+		case rec.Block != nil:
+			b := BlockData(*rec.Block)
+			blocks[b.Number] = &b
+		case rec.Calculation != nil:
+			input, err := replayInput(rec.Calculation, blocks)
+			if err != nil {
+				return err
+			}
+			input.PreviousEstimate = prev
+			est, err := strategy.Calculate(ctx, input)
+			if err != nil {
+				return fmt.Errorf("replaying block %d at %s: %w", input.CurrentBlock.Number, input.Now.Format(time.RFC3339), err)
+			}
+			if est.ChainID == 0 {
+				est.ChainID = input.ChainID
+			}
+			if err := fn(ReplayedEstimate{Input: input, Recorded: rec.Calculation.estimate(), Replayed: est}); err != nil {
+				return err
+			}
+			prev = est
+			for n := range blocks {
+				if n < rec.Calculation.OldestBlock {
+					delete(blocks, n)
+				}
+			}
+		}
+	}
+}
+
+// replayInput rebuilds a calculation's input from the logged blocks.
+func replayInput(calc *logCalculation, blocks map[uint64]*BlockData) (*CalculatorInput, error) {
+	input := &CalculatorInput{
+		ChainID:    calc.ChainID,
+		PendingTxs: make([]*TxData, len(calc.PendingTxs)),
+		Now:        calc.Time,
+	}
+	for n, b := range blocks {
+		if n >= calc.OldestBlock && n <= calc.BlockNumber {
+			input.RecentBlocks = append(input.RecentBlocks, b)
+		}
+	}
+	sort.Slice(input.RecentBlocks, func(i, j int) bool {
+		return input.RecentBlocks[i].Number > input.RecentBlocks[j].Number
+	})
+	if len(input.RecentBlocks) == 0 || input.RecentBlocks[0].Number != calc.BlockNumber {
+		return nil, fmt.Errorf("input log: block %d not logged before its calculation", calc.BlockNumber)
+	}
+	input.CurrentBlock = input.RecentBlocks[0]
+	for i := range calc.PendingTxs {
+		tx := TxData(calc.PendingTxs[i])
+		input.PendingTxs[i] = &tx
+	}
+	if calc.PendingBlock != nil {
+		b := BlockData(*calc.PendingBlock)
+		input.PendingBlock = &b
+	}
+	return input, nil
+}
+
+// estimate returns the published estimate the calculation recorded.
+func (c *logCalculation) estimate() *GasEstimate {
+	tier := func(t logTierValues) PriorityEstimate {
+		return PriorityEstimate{MaxPriorityFeePerGas: t.MaxPriorityFeePerGas, MaxFeePerGas: t.MaxFeePerGas}
+	}
+	return &GasEstimate{
+		ChainID:     c.ChainID,
+		BlockNumber: c.BlockNumber,
+		Timestamp:   c.Time,
+		BaseFee:     c.BaseFee,
+		Urgent:      tier(c.Urgent),
+		Fast:        tier(c.Fast),
+		Standard:    tier(c.Standard),
+		Slow:        tier(c.Slow),
+	}
+}
+
+// Playback publishes the estimates Replay rebuilds from the input log read
+// from r to provider as if they were being calculated now, so the API can
+// be served without a node. Recorded intervals between calculations are
+// divided by speed; zero or less publishes them back to back. Timestamps
+// are moved to playback time, keeping each estimate's chain lag. Playback
+// returns nil at the end of the log.
+func Playback(ctx context.Context, r io.Reader, strategy Strategy, provider *Provider, speed float64) error {
+	var first, start time.Time
+	return Replay(ctx, r, strategy, func(re ReplayedEstimate) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if first.IsZero() {
+			first, start = re.Input.Now, time.Now()
+		}
+		var elapsed time.Duration
+		if speed > 0 {
+			elapsed = time.Duration(float64(re.Input.Now.Sub(first)) / speed)
+		}
+		at := start.Add(elapsed)
+		if wait := time.Until(at); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		est := re.Replayed.Clone()
+		est.Timestamp = at
+		est.BlockTimestamp = at.Add(-re.Input.Now.Sub(re.Input.CurrentBlock.Timestamp))
+		est.Generation = provider.NextGeneration()
+		provider.Update(est)
+		return nil
+	})
+}
@@ -0,0 +1,159 @@
+package estimator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+	"github.com/holiman/uint256"
+)
+
+func TestInputLog_Replay(t *testing.T) {
+	block := func(n, fork uint64) *eth.Block {
+		b := &eth.Block{
+			Number:   n,
+			Hash:     fmt.Sprintf("0x%x-%d", n, fork),
+			BaseFee:  uint256.NewInt(10e9 + fork*1e9),
+			GasUsed:  15e6,
+			GasLimit: 30e6,
+		}
+		for i := uint64(0); i < 3; i++ {
+			b.Transactions = append(b.Transactions, eth.Transaction{
+				Hash:                 fmt.Sprintf("0x%x-%d-%d", n, fork, i),
+				Type:                 2,
+				GasLimit:             21000,
+				MaxFeePerGas:         uint256.NewInt(100e9),
+				MaxPriorityFeePerGas: uint256.NewInt((n%7 + i + fork) * 1e9),
+			})
+		}
+		return b
+	}
+	client := &mockBlockReader{
+		chainIDFunc: func(ctx context.Context) (uint64, error) { return 1, nil },
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) {
+			return block(100, 0), nil
+		},
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			return block(number.Uint64(), 0), nil
+		},
+	}
+	var buf bytes.Buffer
+	provider := NewProvider()
+	e := New(client, &mockTxReader{}, nil, provider, WithHistorySize(5), WithInputLog(NewInputLog(&buf)))
+	ctx := context.Background()
+
+	if err := e.Bootstrap(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var published []*GasEstimate
+	e.Events().EstimateComputed.Subscribe(func(ev EstimateComputed) {
+		published = append(published, ev.Estimate.Clone())
+	})
+	e.IngestPendingTxs(&eth.Transaction{Hash: "0x1", Type: 2, MaxFeePerGas: uint256.NewInt(100e9), MaxPriorityFeePerGas: uint256.NewInt(9e9)})
+	e.IngestBlock(ctx, block(101, 0))
+	e.IngestBlock(ctx, block(101, 1)) // replaces 101
+	e.IngestPendingTxs(&eth.Transaction{Hash: "0x2", GasLimit: 21000, GasPrice: uint256.NewInt(50e9)})
+	e.Recalculate(ctx)
+	e.IngestBlock(ctx, block(102, 1))
+
+	// History blocks are logged once per version
+	if n := strings.Count(buf.String(), `{"block":`); n != 8 {
+		t.Errorf("logged %d blocks, want 8 (bootstrap 96-100, 101 twice, 102)", n)
+	}
+
+	var replayed []ReplayedEstimate
+	err := Replay(ctx, bytes.NewReader(buf.Bytes()), DefaultStrategy(), func(r ReplayedEstimate) error {
+		replayed = append(replayed, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The bootstrap estimate was published before the first subscription
+	if len(replayed) != len(published)+1 {
+		t.Fatalf("replayed %d estimates, want %d", len(replayed), len(published)+1)
+	}
+	for i, r := range replayed[1:] {
+		want := published[i]
+		if r.Input.CurrentBlock.Number != want.BlockNumber || r.Recorded.BlockNumber != want.BlockNumber {
+			t.Errorf("replay %d: block %d, want %d", i, r.Input.CurrentBlock.Number, want.BlockNumber)
+		}
+		if !r.Replayed.BaseFee.Eq(want.BaseFee) || !r.Recorded.BaseFee.Eq(want.BaseFee) {
+			t.Errorf("replay %d: base fee %s (recorded %s), want %s", i, r.Replayed.BaseFee, r.Recorded.BaseFee, want.BaseFee)
+		}
+		for _, tier := range []struct {
+			name           string
+			got, rec, want PriorityEstimate
+		}{
+			{"urgent", r.Replayed.Urgent, r.Recorded.Urgent, want.Urgent},
+			{"standard", r.Replayed.Standard, r.Recorded.Standard, want.Standard},
+			{"slow", r.Replayed.Slow, r.Recorded.Slow, want.Slow},
+		} {
+			if !tier.got.MaxPriorityFeePerGas.Eq(tier.want.MaxPriorityFeePerGas) || !tier.rec.MaxPriorityFeePerGas.Eq(tier.want.MaxPriorityFeePerGas) {
+				t.Errorf("replay %d: %s tip %s (recorded %s), want %s", i, tier.name,
+					tier.got.MaxPriorityFeePerGas, tier.rec.MaxPriorityFeePerGas, tier.want.MaxPriorityFeePerGas)
+			}
+		}
+	}
+	if last := replayed[len(replayed)-1]; len(last.Input.RecentBlocks) != 5 || last.Input.RecentBlocks[1].BaseFee.Uint64() != 11e9 {
+		t.Errorf("last replayed history = %d blocks, want 5 with the replacement 101", len(last.Input.RecentBlocks))
+	}
+
+	stop := errors.New("stop")
+	err = Replay(ctx, bytes.NewReader(buf.Bytes()), DefaultStrategy(), func(ReplayedEstimate) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("Replay() error = %v, want the callback's", err)
+	}
+}
+
+func TestPlayback(t *testing.T) {
+	client := &mockBlockReader{
+		chainIDFunc: func(ctx context.Context) (uint64, error) { return 1, nil },
+		latestBlockFunc: func(ctx context.Context) (*eth.Block, error) {
+			return &eth.Block{Number: 100, BaseFee: uint256.NewInt(10e9), GasUsed: 15e6, GasLimit: 30e6}, nil
+		},
+		blockByNumberFunc: func(ctx context.Context, number *uint256.Int) (*eth.Block, error) {
+			return &eth.Block{Number: number.Uint64(), BaseFee: uint256.NewInt(10e9), GasUsed: 15e6, GasLimit: 30e6}, nil
+		},
+	}
+	var buf bytes.Buffer
+	e := New(client, &mockTxReader{}, nil, NewProvider(), WithHistorySize(5), WithInputLog(NewInputLog(&buf)))
+	ctx := context.Background()
+	if err := e.Bootstrap(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for n := uint64(101); n <= 103; n++ {
+		e.IngestBlock(ctx, &eth.Block{Number: n, BaseFee: uint256.NewInt(n * 1e8), GasUsed: 15e6, GasLimit: 30e6})
+	}
+
+	provider := NewProvider()
+	start := time.Now()
+	if err := Playback(ctx, bytes.NewReader(buf.Bytes()), DefaultStrategy(), provider, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n := provider.UpdateCount(); n != 4 {
+		t.Errorf("published %d estimates, want 4", n)
+	}
+	est, err := provider.Current(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.BlockNumber != 103 {
+		t.Errorf("current estimate at block %d, want 103", est.BlockNumber)
+	}
+	if est.Timestamp.Before(start) {
+		t.Errorf("estimate timestamp %s is the recorded time, want playback time", est.Timestamp)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = Playback(ctx, bytes.NewReader(buf.Bytes()), DefaultStrategy(), NewProvider(), 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Playback() error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,51 @@
+package eth
+
+import "context"
+
+// Gas charged up front per access list entry (EIP-2930).
+const (
+	TxAccessListAddressGas    = 2400
+	TxAccessListStorageKeyGas = 1900
+)
+
+// AccessTuple is an address and the storage slots of it that a transaction
+// declares it will access.
+type AccessTuple struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storageKeys"`
+}
+
+// AccessList is an EIP-2930 access list.
+type AccessList []AccessTuple
+
+// IntrinsicGas returns the gas charged for carrying l.
+func (l AccessList) IntrinsicGas() uint64 {
+	var gas uint64
+	for _, t := range l {
+		gas += TxAccessListAddressGas + uint64(len(t.StorageKeys))*TxAccessListStorageKeyGas
+	}
+	return gas
+}
+
+// CreateAccessList returns the access list msg would use if sent now, and
+// the gas it would use with that list (eth_createAccessList against the
+// pending state). Reverting calls fail with the node's error, like
+// EstimateGas.
+func (c *Client) CreateAccessList(ctx context.Context, msg CallMsg) (AccessList, uint64, error) {
+	var result struct {
+		AccessList AccessList `json:"accessList"`
+		GasUsed    hexUint64  `json:"gasUsed"`
+		Error      string     `json:"error"`
+	}
+	if err := c.call(ctx, "eth_createAccessList", []any{msg.args(), "pending"}, &result); err != nil {
+		return nil, 0, err
+	}
+	// Geth reports execution failures in the result rather than as an error
+	if result.Error != "" {
+		return nil, 0, &rpcError{Code: -32000, Message: result.Error}
+	}
+	if result.AccessList == nil {
+		result.AccessList = AccessList{}
+	}
+	return result.AccessList, uint64(result.GasUsed), nil
+}
@@ -0,0 +1,104 @@
+package eth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Auth holds credentials attached to outbound JSON-RPC requests and to the
+// WebSocket handshake. All fields are optional; when several are set the
+// Authorization header is taken from, in order of precedence, JWTSecret,
+// BearerToken, then Username/Password. Headers are applied first and may
+// carry provider-specific keys (e.g. an API key header).
+type Auth struct {
+	// Headers are added to every request.
+	Headers map[string]string
+
+	// BearerToken is sent as "Authorization: Bearer <token>".
+	BearerToken string
+
+	// Username and Password are sent as HTTP basic auth
+	// (Infura project secrets use an empty username).
+	Username string
+	Password string
+
+	// JWTSecret signs a fresh HS256 token with an "iat" claim per request,
+	// as required by the Engine API and nodes started with --authrpc.jwtsecret.
+	JWTSecret []byte
+}
+
+// IsZero reports whether no credentials are configured.
+func (a Auth) IsZero() bool {
+	return len(a.Headers) == 0 && a.BearerToken == "" &&
+		a.Username == "" && a.Password == "" && len(a.JWTSecret) == 0
+}
+
+// apply sets the configured headers on h.
+func (a Auth) apply(h http.Header, now time.Time) {
+	for k, v := range a.Headers {
+		h.Set(k, v)
+	}
+
+	switch {
+	case len(a.JWTSecret) > 0:
+		h.Set("Authorization", "Bearer "+signJWT(a.JWTSecret, now))
+	case a.BearerToken != "":
+		h.Set("Authorization", "Bearer "+a.BearerToken)
+	case a.Username != "" || a.Password != "":
+		creds := base64.StdEncoding.EncodeToString([]byte(a.Username + ":" + a.Password))
+		h.Set("Authorization", "Basic "+creds)
+	}
+}
+
+// jwtHeader is the pre-encoded {"alg":"HS256","typ":"JWT"} header.
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// signJWT returns an HS256 JWT whose only claim is the issued-at time.
+func signJWT(secret []byte, now time.Time) string {
+	claims := base64.RawURLEncoding.EncodeToString(
+		[]byte(`{"iat":` + strconv.FormatInt(now.Unix(), 10) + `}`))
+	unsigned := jwtHeader + "." + claims
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ParseJWTSecret decodes a hex-encoded 32-byte JWT secret, with or without a
+// 0x prefix, as written to the jwtsecret file by execution clients.
+func ParseJWTSecret(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "0x")
+	secret, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("decoding JWT secret: %w", err)
+	}
+	if len(secret) != 32 {
+		return nil, fmt.Errorf("JWT secret must be 32 bytes, got %d", len(secret))
+	}
+	return secret, nil
+}
+
+// ParseHeaders parses "Name: value" pairs separated by newlines or semicolons.
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, line := range strings.FieldsFunc(s, func(r rune) bool { return r == '\n' || r == ';' }) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q: expected \"Name: value\"", line)
+		}
+		headers[http.CanonicalHeaderKey(name)] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
@@ -0,0 +1,113 @@
+package eth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuth_Apply(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	secret := make([]byte, 32)
+
+	tests := []struct {
+		name string
+		auth Auth
+		want string
+	}{
+		{name: "none", auth: Auth{}, want: ""},
+		{name: "bearer", auth: Auth{BearerToken: "tok"}, want: "Bearer tok"},
+		{name: "basic", auth: Auth{Username: "", Password: "secret"}, want: "Basic OnNlY3JldA=="},
+		{
+			name: "jwt wins over bearer",
+			auth: Auth{BearerToken: "tok", JWTSecret: secret},
+			want: "Bearer " + signJWT(secret, now),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := make(http.Header)
+			tt.auth.apply(h, now)
+			if got := h.Get("Authorization"); got != tt.want {
+				t.Errorf("Authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignJWT(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	token := signJWT(secret, time.Unix(1_700_000_000, 0))
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token has %d parts, want 3", len(parts))
+	}
+
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(claims) != `{"iat":1700000000}` {
+		t.Errorf("claims = %s", claims)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if want := base64.RawURLEncoding.EncodeToString(mac.Sum(nil)); parts[2] != want {
+		t.Errorf("signature = %s, want %s", parts[2], want)
+	}
+}
+
+func TestParseJWTSecret(t *testing.T) {
+	hex := strings.Repeat("ab", 32)
+	for _, in := range []string{hex, "0x" + hex, hex + "\n"} {
+		if secret, err := ParseJWTSecret(in); err != nil || len(secret) != 32 {
+			t.Errorf("ParseJWTSecret(%q) = %d bytes, %v", in, len(secret), err)
+		}
+	}
+	if _, err := ParseJWTSecret("abcd"); err == nil {
+		t.Error("expected error for short secret")
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	got, err := ParseHeaders("x-api-key: abc; X-Other:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["X-Api-Key"] != "abc" || got["X-Other"] != "1" {
+		t.Errorf("ParseHeaders = %v", got)
+	}
+	if _, err := ParseHeaders("missing-colon"); err == nil {
+		t.Error("expected error for header without colon")
+	}
+}
+
+func TestClient_SendsAuth(t *testing.T) {
+	var gotAuth, gotKey string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotKey = r.Header.Get("X-Api-Key")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, WithClientAuth(Auth{
+		Headers:     map[string]string{"X-Api-Key": "key"},
+		BearerToken: "tok",
+	}))
+	if _, err := c.ChainID(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if gotAuth != "Bearer tok" || gotKey != "key" {
+		t.Errorf("headers = %q, %q", gotAuth, gotKey)
+	}
+}
@@ -0,0 +1,144 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/holiman/uint256"
+)
+
+// Capabilities describes which optional RPC methods a node supports.
+// Managed providers often disable the txpool namespace or pending
+// transaction subscriptions depending on the plan.
+type Capabilities struct {
+	// ClientVersion is the web3_clientVersion string; empty if unavailable.
+	ClientVersion string
+
+	// TxPool is set when the txpool namespace (txpool_content) is enabled.
+	TxPool bool
+
+	// FeeHistory is set when eth_feeHistory with reward percentiles works.
+	FeeHistory bool
+
+	// Erigon is set when Erigon's erigon_* namespace is enabled.
+	Erigon bool
+
+	// PendingSubscription is set when eth_subscribe accepts
+	// newPendingTransactions. Probed by SubscriptionProber.
+	PendingSubscription bool
+}
+
+// CapabilityProber detects optional RPC support over HTTP.
+type CapabilityProber interface {
+	// ProbeCapabilities calls each optional method once. Methods the node
+	// rejects are reported unsupported; the error joins probe failures that
+	// had other causes (e.g. timeouts), whose methods are also reported
+	// unsupported.
+	ProbeCapabilities(ctx context.Context) (Capabilities, error)
+}
+
+// SubscriptionProber detects pending transaction subscription support.
+type SubscriptionProber interface {
+	ProbePendingSubscription(ctx context.Context) bool
+}
+
+// FeeHistoryReader abstracts eth_feeHistory.
+type FeeHistoryReader interface {
+	FeeHistory(ctx context.Context, blocks int, newest uint64, percentiles []float64) (*FeeHistory, error)
+}
+
+// FeeHistory is the result of eth_feeHistory.
+type FeeHistory struct {
+	OldestBlock uint64
+
+	// BaseFees has one entry per block plus the base fee of the block after
+	// the newest one.
+	BaseFees     []*uint256.Int
+	GasUsedRatio []float64
+
+	// Rewards holds, per block, the priority fee at each requested percentile
+	// weighted by gas used.
+	Rewards [][]*uint256.Int
+}
+
+// FeeHistory returns fee data for blocks blocks ending at newest, with
+// priority fees at the given percentiles (0-100, ascending).
+func (c *Client) FeeHistory(ctx context.Context, blocks int, newest uint64, percentiles []float64) (*FeeHistory, error) {
+	var raw struct {
+		OldestBlock   hexUint64  `json:"oldestBlock"`
+		BaseFeePerGas []*hexBig  `json:"baseFeePerGas"`
+		GasUsedRatio  []float64  `json:"gasUsedRatio"`
+		Reward        [][]hexBig `json:"reward"`
+	}
+	params := []any{fmt.Sprintf("0x%x", blocks), fmt.Sprintf("0x%x", newest), percentiles}
+	if err := c.call(ctx, "eth_feeHistory", params, &raw); err != nil {
+		return nil, err
+	}
+
+	h := &FeeHistory{
+		OldestBlock:  uint64(raw.OldestBlock),
+		BaseFees:     make([]*uint256.Int, len(raw.BaseFeePerGas)),
+		GasUsedRatio: raw.GasUsedRatio,
+		Rewards:      make([][]*uint256.Int, len(raw.Reward)),
+	}
+	for i, fee := range raw.BaseFeePerGas {
+		if fee != nil {
+			h.BaseFees[i] = fee.Int()
+		}
+	}
+	for i, rewards := range raw.Reward {
+		h.Rewards[i] = make([]*uint256.Int, len(rewards))
+		for j := range rewards {
+			h.Rewards[i][j] = rewards[j].Int()
+		}
+	}
+	return h, nil
+}
+
+// ProbeCapabilities detects the txpool, eth_feeHistory and erigon
+// namespaces and reads the client version.
+func (c *Client) ProbeCapabilities(ctx context.Context) (Capabilities, error) {
+	var caps Capabilities
+	var errs []error
+
+	supported := func(method string, params []any, result any) bool {
+		err := c.call(ctx, method, params, result)
+		var rpcErr *rpcError
+		if err != nil && !errors.As(err, &rpcErr) {
+			errs = append(errs, fmt.Errorf("%s: %w", method, err))
+		}
+		return err == nil
+	}
+
+	var version string
+	if supported("web3_clientVersion", nil, &version) {
+		caps.ClientVersion = version
+	}
+	caps.TxPool = supported("txpool_status", nil, nil)
+	caps.FeeHistory = supported("eth_feeHistory", []any{"0x1", "latest", []float64{50}}, nil)
+	caps.Erigon = supported("erigon_blockNumber", nil, nil) ||
+		strings.HasPrefix(strings.ToLower(caps.ClientVersion), "erigon")
+
+	return caps, errors.Join(errs...)
+}
+
+// ProbePendingSubscription subscribes to pending transaction hashes and
+// immediately unsubscribes, reporting whether the node accepted it.
+func (s *WSSubscriber) ProbePendingSubscription(ctx context.Context) bool {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // ends the probe subscription
+
+	_, err := s.SubscribeNewPendingTransactions(ctx)
+	return err == nil
+}
+
+// Verify interface compliance at compile time.
+var (
+	_ CapabilityProber   = (*Client)(nil)
+	_ FeeHistoryReader   = (*Client)(nil)
+	_ PendingBlockReader = (*Client)(nil)
+	_ SubscriptionProber = (*WSSubscriber)(nil)
+	_ SubscriptionProber = (*PollingSubscriber)(nil)
+)
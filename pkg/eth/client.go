@@ -3,9 +3,14 @@ package eth
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -25,34 +30,163 @@ type TxPoolReader interface {
 	PendingTransactions(ctx context.Context, limit int) ([]*Transaction, error)
 }
 
+// ContractCaller abstracts read-only contract calls.
+type ContractCaller interface {
+	Call(ctx context.Context, to string, data []byte) ([]byte, error)
+}
+
 // TransactionReader abstracts transaction fetching.
 type TransactionReader interface {
 	TransactionByHash(ctx context.Context, hash string) (*Transaction, error)
 	TransactionsByHashes(ctx context.Context, hashes []string) ([]*Transaction, error)
 }
 
-// Client provides access to an Ethereum node via JSON-RPC.
+// NonceReader abstracts account nonce lookups.
+type NonceReader interface {
+	// Nonces returns the transaction count of each address as of the latest
+	// block, i.e. the nonce its next transaction must use. Addresses whose
+	// lookup failed are omitted.
+	Nonces(ctx context.Context, addresses []string) (map[string]uint64, error)
+}
+
+// PendingBlockReader abstracts access to the block the node is building on
+// top of the head.
+type PendingBlockReader interface {
+	// PendingBlock returns the node's pending block with full transactions.
+	// Nodes that don't build one may return an empty block or the head.
+	PendingBlock(ctx context.Context) (*Block, error)
+}
+
+// Client provides access to an Ethereum node via JSON-RPC over HTTP, or
+// over IPC when created with an IPC endpoint (see IsIPCEndpoint).
 type Client struct {
 	httpURL    string
 	httpClient *http.Client
+	ipc        *ipcTransport // nil for HTTP endpoints
+	auth       Auth
 	requestID  atomic.Uint64
+
+	// Transport settings, applied by NewClient after options
+	proxy          func(*http.Request) (*url.URL, error)
+	tlsConfig      *tls.Config // nil uses the system roots
+	dialTimeout    time.Duration
+	http2          bool
+	requestTimeout time.Duration
+	sem            chan struct{} // nil when concurrency is unlimited
+
+	// noBlockReceipts is set once the node rejects eth_getBlockReceipts
+	noBlockReceipts atomic.Bool
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// Default transport settings.
+const (
+	DefaultDialTimeout    = 10 * time.Second
+	DefaultRequestTimeout = 30 * time.Second
+)
+
+// WithProxy routes requests through proxyURL instead of the proxy taken
+// from HTTP_PROXY/HTTPS_PROXY/NO_PROXY. A nil URL disables proxying.
+func WithProxy(proxyURL *url.URL) ClientOption {
+	return func(c *Client) {
+		if proxyURL == nil {
+			c.proxy = nil
+			return
+		}
+		c.proxy = http.ProxyURL(proxyURL)
+	}
+}
+
+// WithTLSConfig sets the TLS configuration for https endpoints, e.g. a
+// private CA, a client certificate or a server name that differs from the
+// URL's host. The config is copied.
+func WithTLSConfig(config *tls.Config) ClientOption {
+	return func(c *Client) {
+		c.tlsConfig = config.Clone()
+	}
+}
+
+// WithDialTimeout bounds TCP connection establishment.
+func WithDialTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.dialTimeout = d
+	}
+}
+
+// WithHTTP2 enables or disables HTTP/2 negotiation for https endpoints.
+// Enabled by default; disable for proxies or load balancers with broken h2 support.
+func WithHTTP2(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.http2 = enabled
+	}
+}
+
+// WithMaxConcurrentRequests limits in-flight requests; further calls wait
+// for a slot or for their context to end. Zero means unlimited.
+func WithMaxConcurrentRequests(n int) ClientOption {
+	return func(c *Client) {
+		if n <= 0 {
+			c.sem = nil
+			return
+		}
+		c.sem = make(chan struct{}, n)
+	}
+}
+
+// WithRequestTimeout sets the deadline applied to requests whose context has
+// none. Zero disables the default deadline.
+func WithRequestTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.requestTimeout = d
+	}
 }
 
-// NewClient creates a new Ethereum RPC client.
-func NewClient(httpURL string) *Client {
-	return &Client{
-		httpURL: httpURL,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:        1000,
-				MaxIdleConnsPerHost: 1000,
-				IdleConnTimeout:     90 * time.Second,
-			},
-		},
+// WithClientAuth attaches credentials to every JSON-RPC request.
+func WithClientAuth(auth Auth) ClientOption {
+	return func(c *Client) {
+		c.auth = auth
 	}
 }
 
+// NewClient creates a new Ethereum RPC client. httpURL may also be an IPC
+// endpoint, in which case auth, proxy and HTTP/2 settings don't apply.
+func NewClient(httpURL string, opts ...ClientOption) *Client {
+	c := &Client{
+		httpURL:        httpURL,
+		proxy:          http.ProxyFromEnvironment,
+		dialTimeout:    DefaultDialTimeout,
+		http2:          true,
+		requestTimeout: DefaultRequestTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	transport := &http.Transport{
+		Proxy:               c.proxy,
+		DialContext:         (&net.Dialer{Timeout: c.dialTimeout, KeepAlive: 30 * time.Second}).DialContext,
+		ForceAttemptHTTP2:   c.http2,
+		TLSClientConfig:     c.tlsConfig,
+		TLSHandshakeTimeout: c.dialTimeout,
+		MaxIdleConns:        1000,
+		MaxIdleConnsPerHost: 1000,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	if !c.http2 {
+		// A non-nil empty map disables the transport's automatic h2 upgrade
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	c.httpClient = &http.Client{Transport: transport}
+	if IsIPCEndpoint(httpURL) {
+		c.ipc = newIPCTransport(httpURL, c.dialTimeout)
+	}
+
+	return c
+}
+
 // ChainID returns the chain ID of the connected network.
 func (c *Client) ChainID(ctx context.Context) (uint64, error) {
 	var result hexUint64
@@ -77,6 +211,11 @@ func (c *Client) BlockByNumber(ctx context.Context, number *uint256.Int) (*Block
 	return c.blockByTag(ctx, tag, true)
 }
 
+// PendingBlock returns the block the node is assembling for the next height.
+func (c *Client) PendingBlock(ctx context.Context) (*Block, error) {
+	return c.blockByTag(ctx, "pending", true)
+}
+
 func (c *Client) blockByTag(ctx context.Context, tag string, includeTxs bool) (*Block, error) {
 	var raw rpcBlock
 	if err := c.call(ctx, "eth_getBlockByNumber", []any{tag, includeTxs}, &raw); err != nil {
@@ -85,6 +224,26 @@ func (c *Client) blockByTag(ctx context.Context, tag string, includeTxs bool) (*
 	return raw.toBlock(includeTxs)
 }
 
+// Call executes a read-only contract call (eth_call) against the latest block
+// and returns the raw return data.
+func (c *Client) Call(ctx context.Context, to string, data []byte) ([]byte, error) {
+	msg := map[string]string{
+		"to":   to,
+		"data": "0x" + hex.EncodeToString(data),
+	}
+
+	var result string
+	if err := c.call(ctx, "eth_call", []any{msg, "latest"}, &result); err != nil {
+		return nil, err
+	}
+
+	out, err := hex.DecodeString(strings.TrimPrefix(result, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("decoding call result: %w", err)
+	}
+	return out, nil
+}
+
 // TransactionByHash returns the transaction with the given hash.
 func (c *Client) TransactionByHash(ctx context.Context, hash string) (*Transaction, error) {
 	var raw rpcTransaction
@@ -137,6 +296,47 @@ func (c *Client) TransactionsByHashes(ctx context.Context, hashes []string) ([]*
 	return txs, nil
 }
 
+// Nonces fetches eth_getTransactionCount for many addresses in a single batch request.
+func (c *Client) Nonces(ctx context.Context, addresses []string) (map[string]uint64, error) {
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+
+	reqs := make([]rpcRequest, len(addresses))
+	byID := make(map[uint64]string, len(addresses))
+	for i, addr := range addresses {
+		id := c.requestID.Add(1)
+		byID[id] = addr
+		reqs[i] = rpcRequest{
+			JSONRPC: "2.0",
+			ID:      id,
+			Method:  "eth_getTransactionCount",
+			Params:  []any{addr, "latest"},
+		}
+	}
+
+	responses, err := c.batchCall(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Batch responses may arrive in any order; match them by ID
+	nonces := make(map[string]uint64, len(responses))
+	for _, resp := range responses {
+		addr, ok := byID[resp.ID]
+		if !ok || resp.Error != nil {
+			continue
+		}
+		var n hexUint64
+		if err := json.Unmarshal(resp.Result, &n); err != nil {
+			continue
+		}
+		nonces[addr] = uint64(n)
+	}
+
+	return nonces, nil
+}
+
 // PendingTransactions returns pending transactions from the mempool.
 // Uses txpool_content and samples up to limit transactions.
 //
@@ -188,9 +388,12 @@ func (c *Client) pendingTransactionsFallback(ctx context.Context, limit int) ([]
 	return txs, nil
 }
 
-// Close releases resources. Currently a no-op for HTTP client.
+// Close releases idle connections.
 func (c *Client) Close() error {
 	c.httpClient.CloseIdleConnections()
+	if c.ipc != nil {
+		c.ipc.closeIdle()
+	}
 	return nil
 }
 
@@ -232,26 +435,9 @@ func (c *Client) call(ctx context.Context, method string, params []any, result a
 		return fmt.Errorf("marshaling request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.httpURL, bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return fmt.Errorf("sending request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
-	}
-
 	var rpcResp rpcResponse
-	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
-		return fmt.Errorf("decoding response: %w", err)
+	if err := c.post(ctx, body, &rpcResp); err != nil {
+		return err
 	}
 
 	if rpcResp.Error != nil {
@@ -273,27 +459,56 @@ func (c *Client) batchCall(ctx context.Context, reqs []rpcRequest) ([]rpcRespons
 		return nil, fmt.Errorf("marshaling batch request: %w", err)
 	}
 
+	var rpcResps []rpcResponse
+	if err := c.post(ctx, body, &rpcResps); err != nil {
+		return nil, err
+	}
+
+	return rpcResps, nil
+}
+
+// post sends a JSON-RPC payload and decodes the response body into out,
+// applying the default deadline and concurrency limit.
+func (c *Client) post(ctx context.Context, body []byte, out any) error {
+	if _, ok := ctx.Deadline(); !ok && c.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.requestTimeout)
+		defer cancel()
+	}
+
+	if c.sem != nil {
+		select {
+		case c.sem <- struct{}{}:
+			defer func() { <-c.sem }()
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for request slot: %w", ctx.Err())
+		}
+	}
+
+	if c.ipc != nil {
+		return c.ipc.roundTrip(ctx, body, out)
+	}
+
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.httpURL, bytes.NewReader(body))
 	if err != nil {
-		return nil, fmt.Errorf("creating batch request: %w", err)
+		return fmt.Errorf("creating request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
+	c.auth.apply(httpReq.Header, time.Now())
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("sending batch request: %w", err)
+		return fmt.Errorf("sending request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
 	}
 
-	var rpcResps []rpcResponse
-	if err := json.NewDecoder(resp.Body).Decode(&rpcResps); err != nil {
-		return nil, fmt.Errorf("decoding batch response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
 	}
-
-	return rpcResps, nil
+	return nil
 }
@@ -0,0 +1,398 @@
+package eth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/holiman/uint256"
+)
+
+func TestClient_MaxConcurrentRequests(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, WithMaxConcurrentRequests(2))
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.ChainID(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got > 2 {
+		t.Errorf("peak concurrency = %d, want <= 2", got)
+	}
+}
+
+func TestClient_RequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c := NewClient(srv.URL, WithRequestTimeout(50*time.Millisecond))
+	defer c.Close()
+
+	_, err := c.ChainID(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want deadline exceeded", err)
+	}
+}
+
+func TestClient_Proxy(t *testing.T) {
+	var proxied atomic.Bool
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A forward proxy receives the absolute target URL
+		proxied.Store(r.URL.Host == "node.invalid")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	c := NewClient("http://node.invalid", WithProxy(proxyURL))
+	defer c.Close()
+
+	if _, err := c.ChainID(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !proxied.Load() {
+		t.Error("request did not go through the proxy")
+	}
+}
+
+func TestClient_TLSConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	if _, err := c.ChainID(context.Background()); err == nil {
+		t.Error("ChainID() trusted a certificate outside the system roots")
+	}
+	c.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	c = NewClient(srv.URL, WithTLSConfig(&tls.Config{RootCAs: roots}))
+	defer c.Close()
+	if _, err := c.ChainID(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_Nonces(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqs []rpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			t.Error(err)
+			return
+		}
+		// Reply in reverse order, with an error for the last address
+		resps := make([]map[string]any, 0, len(reqs))
+		for i := len(reqs) - 1; i >= 0; i-- {
+			resp := map[string]any{"jsonrpc": "2.0", "id": reqs[i].ID}
+			if i == len(reqs)-1 {
+				resp["error"] = map[string]any{"code": -32000, "message": "unknown account"}
+			} else {
+				resp["result"] = fmt.Sprintf("0x%x", 10+i)
+			}
+			resps = append(resps, resp)
+		}
+		json.NewEncoder(w).Encode(resps)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	defer c.Close()
+
+	nonces, err := c.Nonces(context.Background(), []string{"0xa", "0xb", "0xc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]uint64{"0xa": 10, "0xb": 11}
+	if len(nonces) != len(want) || nonces["0xa"] != 10 || nonces["0xb"] != 11 {
+		t.Errorf("Nonces() = %v, want %v", nonces, want)
+	}
+}
+
+func TestClient_EstimateGas(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+		if req.Method != "eth_estimateGas" || len(req.Params) != 2 || req.Params[1] != "pending" {
+			t.Errorf("request = %s %v", req.Method, req.Params)
+			return
+		}
+		got, _ = req.Params[0].(map[string]any)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"0xb411"}`, req.ID)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	defer c.Close()
+
+	gas, err := c.EstimateGas(context.Background(), CallMsg{
+		From:  "0xa",
+		To:    "0xb",
+		Data:  []byte{0xde, 0xad},
+		Value: uint256.NewInt(255),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gas != 46097 {
+		t.Errorf("EstimateGas() = %d, want 46097", gas)
+	}
+	want := map[string]any{"from": "0xa", "to": "0xb", "data": "0xdead", "value": "0xff"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("call args = %v, want %v", got, want)
+	}
+}
+
+func TestClient_CreateAccessList(t *testing.T) {
+	var (
+		got    map[string]any
+		result string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+		if req.Method != "eth_createAccessList" || len(req.Params) != 2 || req.Params[1] != "pending" {
+			t.Errorf("request = %s %v", req.Method, req.Params)
+			return
+		}
+		got, _ = req.Params[0].(map[string]any)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":%s}`, req.ID, result)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	defer c.Close()
+
+	result = `{"accessList":[{"address":"0xb","storageKeys":["0x01","0x02"]}],"gasUsed":"0x7530"}`
+	list, gas, err := c.CreateAccessList(context.Background(), CallMsg{To: "0xb", Data: []byte{0x01}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gas != 30000 || len(list) != 1 || list[0].Address != "0xb" || len(list[0].StorageKeys) != 2 {
+		t.Errorf("CreateAccessList() = %v, %d", list, gas)
+	}
+	if list.IntrinsicGas() != 2400+2*1900 {
+		t.Errorf("IntrinsicGas() = %d", list.IntrinsicGas())
+	}
+	if got["to"] != "0xb" || got["data"] != "0x01" {
+		t.Errorf("call args = %v", got)
+	}
+
+	// The list can be sent with later calls
+	if args := (CallMsg{To: "0xb", AccessList: list}).args(); fmt.Sprint(args["accessList"]) != fmt.Sprint(list) {
+		t.Errorf("accessList arg = %v, want %v", args["accessList"], list)
+	}
+
+	// Geth reports reverts in the result
+	result = `{"accessList":[],"gasUsed":"0x0","error":"execution reverted"}`
+	if _, _, err := c.CreateAccessList(context.Background(), CallMsg{To: "0xb"}); !IsNodeError(err) {
+		t.Errorf("CreateAccessList() error = %v, want a node error", err)
+	}
+}
+
+func TestClient_GasPrice(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+		price := "0x2540be400" // 10 gwei
+		if len(req.Params) == 1 && req.Params[0] == "0xcusd" {
+			price = "0x12a05f200" // 5 gwei
+		} else if len(req.Params) != 0 {
+			t.Errorf("params = %v", req.Params)
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"%s"}`, req.ID, price)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	defer c.Close()
+
+	if p, err := c.GasPrice(context.Background(), ""); err != nil || p.Uint64() != 10e9 {
+		t.Errorf("GasPrice() = %v, %v, want 10 gwei", p, err)
+	}
+	if p, err := c.GasPrice(context.Background(), "0xcusd"); err != nil || p.Uint64() != 5e9 {
+		t.Errorf("GasPrice(cUSD) = %v, %v, want 5e9", p, err)
+	}
+}
+
+func TestClient_NodeStatus(t *testing.T) {
+	var syncing string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+		switch req.Method {
+		case "eth_syncing":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":%s}`, req.ID, syncing)
+		case "net_peerCount":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"0x19"}`, req.ID)
+		default:
+			t.Errorf("unexpected method %s", req.Method)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	defer c.Close()
+
+	syncing = "false"
+	if st, err := c.Syncing(context.Background()); err != nil || st != (SyncStatus{}) {
+		t.Errorf("Syncing() = %+v, %v, want not syncing", st, err)
+	}
+	syncing = `{"startingBlock":"0x0","currentBlock":"0x64","highestBlock":"0xc8"}`
+	st, err := c.Syncing(context.Background())
+	if err != nil || st != (SyncStatus{Syncing: true, CurrentBlock: 100, HighestBlock: 200}) {
+		t.Errorf("Syncing() = %+v, %v, want syncing 100 of 200", st, err)
+	}
+	if peers, err := c.PeerCount(context.Background()); err != nil || peers != 25 {
+		t.Errorf("PeerCount() = %d, %v, want 25", peers, err)
+	}
+}
+
+func TestClient_ProbeCapabilities(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+		resp := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+		switch req.Method {
+		case "web3_clientVersion":
+			resp["result"] = "Geth/v1.14.0-stable/linux-amd64/go1.22"
+		case "eth_feeHistory":
+			resp["result"] = map[string]any{
+				"oldestBlock":   "0x10",
+				"baseFeePerGas": []string{"0x3b9aca00", "0x3b9aca01"},
+				"gasUsedRatio":  []float64{0.5},
+				"reward":        [][]string{{"0x5f5e100"}},
+			}
+		default:
+			resp["error"] = map[string]any{"code": -32601, "message": "the method does not exist"}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	defer c.Close()
+
+	caps, err := c.ProbeCapabilities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Capabilities{ClientVersion: "Geth/v1.14.0-stable/linux-amd64/go1.22", FeeHistory: true}
+	if caps != want {
+		t.Errorf("ProbeCapabilities() = %+v, want %+v", caps, want)
+	}
+
+	h, err := c.FeeHistory(context.Background(), 1, 0x10, []float64{50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.OldestBlock != 0x10 || len(h.BaseFees) != 2 || h.Rewards[0][0].Uint64() != 1e8 {
+		t.Errorf("FeeHistory() = %+v", h)
+	}
+}
+
+func TestClient_BlockReceipts(t *testing.T) {
+	var blockReceiptCalls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var raw json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+			t.Error(err)
+			return
+		}
+		if raw[0] != '[' {
+			// eth_getBlockReceipts is not supported
+			var req rpcRequest
+			json.Unmarshal(raw, &req)
+			blockReceiptCalls.Add(1)
+			json.NewEncoder(w).Encode(map[string]any{
+				"jsonrpc": "2.0", "id": req.ID,
+				"error": map[string]any{"code": -32601, "message": "the method eth_getBlockReceipts does not exist"},
+			})
+			return
+		}
+		var reqs []rpcRequest
+		json.Unmarshal(raw, &reqs)
+		resps := make([]map[string]any, len(reqs))
+		for i, req := range reqs {
+			resps[i] = map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": map[string]any{
+				"transactionHash":   req.Params[0],
+				"gasUsed":           "0x5208",
+				"effectiveGasPrice": "0x3b9aca00",
+			}}
+		}
+		json.NewEncoder(w).Encode(resps)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	defer c.Close()
+
+	block := &Block{Hash: "0xb", Transactions: []Transaction{{Hash: "0x1"}, {Hash: "0x2"}}}
+	for i := 0; i < 2; i++ {
+		receipts, err := c.BlockReceipts(context.Background(), block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(receipts) != 2 || receipts[1].TxHash != "0x2" || receipts[1].GasUsed != 21000 || receipts[1].EffectiveGasPrice.Uint64() != 1e9 {
+			t.Errorf("BlockReceipts() = %+v", receipts)
+		}
+	}
+	if n := blockReceiptCalls.Load(); n != 1 {
+		t.Errorf("eth_getBlockReceipts called %d times, want once", n)
+	}
+}
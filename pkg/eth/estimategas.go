@@ -0,0 +1,71 @@
+package eth
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+
+	"github.com/holiman/uint256"
+)
+
+// CallMsg is an unsigned transaction to simulate. To is empty for contract
+// creation; zero Value and Gas and a nil AccessList are omitted.
+type CallMsg struct {
+	From       string
+	To         string
+	Data       []byte
+	Value      *uint256.Int
+	Gas        uint64
+	AccessList AccessList
+}
+
+// args encodes m as JSON-RPC transaction call arguments.
+func (m CallMsg) args() map[string]any {
+	args := make(map[string]any, 6)
+	if m.From != "" {
+		args["from"] = m.From
+	}
+	if m.To != "" {
+		args["to"] = m.To
+	}
+	if len(m.Data) > 0 {
+		args["data"] = "0x" + hex.EncodeToString(m.Data)
+	}
+	if m.Value != nil && !m.Value.IsZero() {
+		args["value"] = m.Value.Hex()
+	}
+	if m.Gas > 0 {
+		args["gas"] = new(uint256.Int).SetUint64(m.Gas).Hex()
+	}
+	if m.AccessList != nil {
+		args["accessList"] = m.AccessList
+	}
+	return args
+}
+
+// EstimateGas returns the gas msg would use if sent now (eth_estimateGas
+// against the pending state). Reverting calls fail with the node's error.
+func (c *Client) EstimateGas(ctx context.Context, msg CallMsg) (uint64, error) {
+	var result hexUint64
+	if err := c.call(ctx, "eth_estimateGas", []any{msg.args(), "pending"}, &result); err != nil {
+		return 0, err
+	}
+	return uint64(result), nil
+}
+
+// PendingNonce returns the next nonce for address, counting its pending
+// transactions (eth_getTransactionCount at "pending").
+func (c *Client) PendingNonce(ctx context.Context, address string) (uint64, error) {
+	var result hexUint64
+	if err := c.call(ctx, "eth_getTransactionCount", []any{address, "pending"}, &result); err != nil {
+		return 0, err
+	}
+	return uint64(result), nil
+}
+
+// IsNodeError reports whether err is an error returned by the node for the
+// request, such as a reverting call, rather than a failure to reach it.
+func IsNodeError(err error) bool {
+	var rpcErr *rpcError
+	return errors.As(err, &rpcErr)
+}
@@ -0,0 +1,23 @@
+package eth
+
+import (
+	"context"
+
+	"github.com/holiman/uint256"
+)
+
+// GasPrice returns the node's suggested legacy gas price (eth_gasPrice).
+// With a feeCurrency contract address it is quoted in units of that token
+// instead of wei, on chains that accept fees in ERC-20 tokens (e.g. Celo);
+// other nodes reject the extra parameter with a node error.
+func (c *Client) GasPrice(ctx context.Context, feeCurrency string) (*uint256.Int, error) {
+	var params []any
+	if feeCurrency != "" {
+		params = []any{feeCurrency}
+	}
+	var result hexBig
+	if err := c.call(ctx, "eth_gasPrice", params, &result); err != nil {
+		return nil, err
+	}
+	return result.Int(), nil
+}
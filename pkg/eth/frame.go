@@ -0,0 +1,206 @@
+package eth
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// WebSocket frame opcodes (RFC 6455, section 5.2).
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// Close status codes (RFC 6455, section 7.4.1).
+const (
+	CloseNormalClosure = 1000
+	CloseGoingAway     = 1001
+	CloseProtocolError = 1002
+	CloseNoStatus      = 1005 // no code in the close frame; never sent
+	CloseAbnormal      = 1006 // connection dropped without a close frame; never sent
+	CloseMessageTooBig = 1009
+	CloseInternalError = 1011
+)
+
+// DefaultMaxMessageSize bounds a WebSocket message, whether sent in one
+// frame or reassembled from fragments. Full pending transaction bodies with
+// large calldata are the biggest messages the subscriber expects.
+const DefaultMaxMessageSize = 16 << 20
+
+// maxControlPayload is the largest payload a control frame may carry, and
+// closeReasonMaxLength the longest reason that fits after a close code.
+const (
+	maxControlPayload    = 125
+	closeReasonMaxLength = maxControlPayload - 2
+)
+
+var (
+	// ErrMessageTooLarge is returned when the server declares a frame or
+	// sends a fragmented message larger than the configured limit. The
+	// payload is not read, so the connection must be dropped.
+	ErrMessageTooLarge = errors.New("websocket message exceeds size limit")
+
+	// errProtocol reports a frame that violates RFC 6455.
+	errProtocol = errors.New("websocket protocol error")
+)
+
+// frame is a single WebSocket frame with its payload unmasked.
+type frame struct {
+	fin     bool
+	opcode  byte
+	payload []byte
+}
+
+// readFrame reads one frame from r. The declared payload length is checked
+// against maxSize before anything is allocated.
+func readFrame(r io.Reader, maxSize int64) (frame, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return frame{}, err
+	}
+
+	f := frame{fin: header[0]&0x80 != 0, opcode: header[0] & 0x0F}
+	if header[0]&0x70 != 0 {
+		// No extensions are negotiated, so reserved bits must be clear
+		return frame{}, fmt.Errorf("%w: reserved bits set", errProtocol)
+	}
+	switch f.opcode {
+	case opContinuation, opText, opBinary, opClose, opPing, opPong:
+	default:
+		return frame{}, fmt.Errorf("%w: reserved opcode %#x", errProtocol, f.opcode)
+	}
+	control := f.opcode&0x8 != 0
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return frame{}, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return frame{}, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+		if length>>63 != 0 {
+			return frame{}, fmt.Errorf("%w: invalid payload length", errProtocol)
+		}
+	}
+	if control && (!f.fin || length > maxControlPayload) {
+		return frame{}, fmt.Errorf("%w: fragmented or oversized control frame", errProtocol)
+	}
+	if length > uint64(maxSize) {
+		return frame{}, fmt.Errorf("%w: frame of %d bytes", ErrMessageTooLarge, length)
+	}
+
+	// Servers must not mask, but unmasking costs nothing and is more
+	// forgiving than dropping the connection
+	var mask [4]byte
+	masked := header[1]&0x80 != 0
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return frame{}, err
+		}
+	}
+
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return frame{}, err
+	}
+	if masked {
+		for i := range f.payload {
+			f.payload[i] ^= mask[i%4]
+		}
+	}
+	return f, nil
+}
+
+// readMessage reads the next text or binary message from r, reassembling
+// fragmented messages up to maxSize bytes in total. Control frames, which
+// may arrive between fragments, are passed to control; an error from
+// control ends the read.
+func readMessage(r io.Reader, maxSize int64, control func(frame) error) ([]byte, error) {
+	var msg []byte
+	fragmented := false
+
+	for {
+		f, err := readFrame(r, maxSize)
+		if err != nil {
+			return nil, err
+		}
+
+		switch f.opcode {
+		case opClose, opPing, opPong:
+			if err := control(f); err != nil {
+				return nil, err
+			}
+			continue
+
+		case opText, opBinary:
+			if fragmented {
+				return nil, fmt.Errorf("%w: new message before previous one finished", errProtocol)
+			}
+			if f.fin {
+				return f.payload, nil
+			}
+			msg, fragmented = f.payload, true
+
+		case opContinuation:
+			if !fragmented {
+				return nil, fmt.Errorf("%w: continuation without a message", errProtocol)
+			}
+			if int64(len(msg))+int64(len(f.payload)) > maxSize {
+				return nil, fmt.Errorf("%w: fragmented message over %d bytes", ErrMessageTooLarge, maxSize)
+			}
+			msg = append(msg, f.payload...)
+			if f.fin {
+				return msg, nil
+			}
+		}
+	}
+}
+
+// parseClose decodes a close frame payload into its status code and reason.
+// An empty payload carries no status and yields CloseNoStatus.
+func parseClose(payload []byte) (*CloseError, error) {
+	switch {
+	case len(payload) == 0:
+		return &CloseError{Code: CloseNoStatus}, nil
+	case len(payload) == 1:
+		return nil, fmt.Errorf("%w: truncated close code", errProtocol)
+	}
+
+	code := int(binary.BigEndian.Uint16(payload))
+	if code < 1000 || code == CloseNoStatus || code == CloseAbnormal || code == 1015 || code >= 5000 {
+		return nil, fmt.Errorf("%w: invalid close code %d", errProtocol, code)
+	}
+	reason := payload[2:]
+	if !utf8.Valid(reason) {
+		return nil, fmt.Errorf("%w: close reason is not UTF-8", errProtocol)
+	}
+	return &CloseError{Code: code, Reason: string(reason)}, nil
+}
+
+// closePayload encodes a close frame payload. CloseNoStatus encodes as an
+// empty payload, and reasons are truncated to fit a control frame.
+func closePayload(code int, reason string) []byte {
+	if code == CloseNoStatus {
+		return nil
+	}
+	if len(reason) > closeReasonMaxLength {
+		reason = strings.ToValidUTF8(reason[:closeReasonMaxLength], "")
+	}
+	payload := binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(reason)), uint16(code))
+	return append(payload, reason...)
+}
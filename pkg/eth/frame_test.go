@@ -0,0 +1,178 @@
+package eth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+// rawFrame encodes an unmasked server frame.
+func rawFrame(fin bool, opcode byte, payload []byte) []byte {
+	b := opcode
+	if fin {
+		b |= 0x80
+	}
+	out := []byte{b}
+	switch n := len(payload); {
+	case n < 126:
+		out = append(out, byte(n))
+	case n < 65536:
+		out = append(out, 126, byte(n>>8), byte(n))
+	default:
+		out = append(out, 127)
+		out = binary.BigEndian.AppendUint64(out, uint64(n))
+	}
+	return append(out, payload...)
+}
+
+func concat(frames ...[]byte) []byte {
+	return bytes.Join(frames, nil)
+}
+
+func TestReadMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		maxSize  int64
+		want     string
+		wantErr  error
+		controls int
+	}{
+		{
+			name:  "single frame",
+			input: rawFrame(true, opText, []byte("hello")),
+			want:  "hello",
+		},
+		{
+			name: "fragmented with interleaved ping",
+			input: concat(
+				rawFrame(false, opText, []byte("hel")),
+				rawFrame(true, opPing, []byte("p")),
+				rawFrame(false, opContinuation, []byte("lo ")),
+				rawFrame(true, opContinuation, []byte("world")),
+			),
+			want:     "hello world",
+			controls: 1,
+		},
+		{
+			name:  "16-bit length",
+			input: rawFrame(true, opBinary, bytes.Repeat([]byte("x"), 300)),
+			want:  string(bytes.Repeat([]byte("x"), 300)),
+		},
+		{
+			name:  "masked frame",
+			input: []byte{0x81, 0x82, 1, 2, 3, 4, 'h' ^ 1, 'i' ^ 2},
+			want:  "hi",
+		},
+		{
+			name:    "declared length over limit",
+			input:   []byte{0x81, 127, 0, 0, 0, 1, 0, 0, 0, 0}, // 4 GiB, no payload
+			maxSize: 1024,
+			wantErr: ErrMessageTooLarge,
+		},
+		{
+			name:    "invalid 64-bit length",
+			input:   []byte{0x81, 127, 0x80, 0, 0, 0, 0, 0, 0, 1},
+			wantErr: errProtocol,
+		},
+		{
+			name: "fragments over limit",
+			input: concat(
+				rawFrame(false, opText, []byte("0123456789")),
+				rawFrame(true, opContinuation, []byte("0123456789")),
+			),
+			maxSize: 16,
+			wantErr: ErrMessageTooLarge,
+		},
+		{
+			name:    "continuation without message",
+			input:   rawFrame(true, opContinuation, []byte("x")),
+			wantErr: errProtocol,
+		},
+		{
+			name: "new message inside fragmented one",
+			input: concat(
+				rawFrame(false, opText, []byte("a")),
+				rawFrame(true, opText, []byte("b")),
+			),
+			wantErr: errProtocol,
+		},
+		{
+			name:    "fragmented control frame",
+			input:   rawFrame(false, opPing, nil),
+			wantErr: errProtocol,
+		},
+		{
+			name:    "oversized control frame",
+			input:   rawFrame(true, opPing, make([]byte, 126)),
+			wantErr: errProtocol,
+		},
+		{
+			name:    "reserved bits",
+			input:   []byte{0xC1, 0},
+			wantErr: errProtocol,
+		},
+		{
+			name:    "reserved opcode",
+			input:   []byte{0x83, 0},
+			wantErr: errProtocol,
+		},
+		{
+			name:    "truncated payload",
+			input:   []byte{0x81, 5, 'a', 'b'},
+			wantErr: io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxSize := tt.maxSize
+			if maxSize == 0 {
+				maxSize = DefaultMaxMessageSize
+			}
+			controls := 0
+			got, err := readMessage(bytes.NewReader(tt.input), maxSize, func(frame) error {
+				controls++
+				return nil
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("readMessage() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readMessage() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("readMessage() = %q, want %q", got, tt.want)
+			}
+			if controls != tt.controls {
+				t.Errorf("control frames = %d, want %d", controls, tt.controls)
+			}
+		})
+	}
+}
+
+func FuzzReadMessage(f *testing.F) {
+	f.Add(rawFrame(true, opText, []byte(`{"jsonrpc":"2.0"}`)))
+	f.Add(concat(rawFrame(false, opText, []byte("a")), rawFrame(true, opPing, nil), rawFrame(true, opContinuation, []byte("b"))))
+	f.Add([]byte{0x81, 127, 0x7F, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})
+	f.Add([]byte{0x81, 126, 0xFF, 0xFF})
+	f.Add([]byte{0x88, 0x02, 0x03, 0xE8})
+
+	const maxSize = 4096
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg, err := readMessage(bytes.NewReader(data), maxSize, func(f frame) error {
+			if len(f.payload) > maxControlPayload {
+				t.Fatalf("control frame with %d byte payload", len(f.payload))
+			}
+			return nil
+		})
+		if err == nil && len(msg) > maxSize {
+			t.Fatalf("message of %d bytes exceeds limit", len(msg))
+		}
+	})
+}
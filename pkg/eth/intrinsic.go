@@ -0,0 +1,38 @@
+package eth
+
+// Gas charged for a transaction before any EVM execution.
+const (
+	TxGas            = 21000 // every transaction
+	TxCreateGas      = 32000 // extra for contract creation
+	TxDataZeroGas    = 4     // per zero calldata byte
+	TxDataNonZeroGas = 16    // per non-zero calldata byte (EIP-2028)
+	InitCodeWordGas  = 2     // per 32-byte word of init code (EIP-3860)
+)
+
+// MaxInitCodeSize is the largest init code a contract creation may carry
+// (EIP-3860).
+const MaxInitCodeSize = 49152
+
+// IntrinsicGas returns the gas a transaction with calldata data is charged
+// up front: the base cost, the calldata cost and, for contract creation,
+// the creation surcharge and init code word cost.
+func IntrinsicGas(data []byte, creation bool) uint64 {
+	gas := uint64(TxGas)
+	if creation {
+		gas += TxCreateGas
+		words := (uint64(len(data)) + 31) / 32
+		gas += words * InitCodeWordGas
+	}
+	zeros, nonZeros := countZeros(data)
+	return gas + zeros*TxDataZeroGas + nonZeros*TxDataNonZeroGas
+}
+
+// countZeros counts the zero and non-zero bytes of data.
+func countZeros(data []byte) (zeros, nonZeros uint64) {
+	for _, b := range data {
+		if b == 0 {
+			zeros++
+		}
+	}
+	return zeros, uint64(len(data)) - zeros
+}
@@ -0,0 +1,117 @@
+package eth
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// maxIdleIPCConns bounds the IPC connections a Client keeps open between
+// requests. Each carries one request at a time.
+const maxIdleIPCConns = 16
+
+// IsIPCEndpoint reports whether endpoint names a local IPC socket rather
+// than a URL: a Windows named pipe (\\.\pipe\...) or a path ending in
+// ".ipc", such as geth's datadir/geth.ipc.
+func IsIPCEndpoint(endpoint string) bool {
+	if strings.HasPrefix(endpoint, `\\.\pipe\`) {
+		return true
+	}
+	return !strings.Contains(endpoint, "://") && strings.HasSuffix(endpoint, ".ipc")
+}
+
+// ipcConn is a connection to the node's IPC endpoint. JSON-RPC messages
+// are written and read as a stream of JSON values without framing.
+type ipcConn struct {
+	net.Conn
+	dec *json.Decoder
+}
+
+func newIPCConn(conn net.Conn) *ipcConn {
+	return &ipcConn{Conn: conn, dec: json.NewDecoder(bufio.NewReader(conn))}
+}
+
+// ipcTransport sends Client requests over IPC, keeping idle connections
+// for reuse.
+type ipcTransport struct {
+	path        string
+	dialTimeout time.Duration
+	idle        chan *ipcConn
+}
+
+func newIPCTransport(path string, dialTimeout time.Duration) *ipcTransport {
+	return &ipcTransport{
+		path:        path,
+		dialTimeout: dialTimeout,
+		idle:        make(chan *ipcConn, maxIdleIPCConns),
+	}
+}
+
+// roundTrip writes body and decodes the response into out. The connection
+// is dropped if anything fails or ctx ends mid-request, since a late
+// response would be read by the next request.
+func (t *ipcTransport) roundTrip(ctx context.Context, body []byte, out any) error {
+	conn, err := t.get(ctx)
+	if err != nil {
+		return fmt.Errorf("dialing ipc: %w", err)
+	}
+
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+
+	if _, err = conn.Write(body); err != nil {
+		err = fmt.Errorf("sending request: %w", err)
+	} else if err = conn.dec.Decode(out); err != nil {
+		err = fmt.Errorf("decoding response: %w", err)
+	}
+	if !stop() || err != nil {
+		conn.Close()
+		if ctxErr := ctx.Err(); ctxErr != nil && err != nil {
+			return fmt.Errorf("%w: %w", ctxErr, err)
+		}
+		return err
+	}
+
+	select {
+	case t.idle <- conn:
+	default:
+		conn.Close()
+	}
+	return nil
+}
+
+// get returns an idle connection or dials a new one.
+func (t *ipcTransport) get(ctx context.Context) (*ipcConn, error) {
+	select {
+	case conn := <-t.idle:
+		return conn, nil
+	default:
+	}
+	ctx, cancel := context.WithTimeout(ctx, t.dialTimeout)
+	defer cancel()
+	conn, err := dialIPC(ctx, t.path)
+	if err != nil {
+		return nil, err
+	}
+	return newIPCConn(conn), nil
+}
+
+// closeIdle closes every idle connection.
+func (t *ipcTransport) closeIdle() {
+	for {
+		select {
+		case conn := <-t.idle:
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,180 @@
+//go:build !windows
+
+package eth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+func TestIsIPCEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     bool
+	}{
+		{"/home/eth/.ethereum/geth.ipc", true},
+		{"geth.ipc", true},
+		{`\\.\pipe\geth.ipc`, true},
+		{"http://localhost:8545", false},
+		{"ws://localhost:8546/geth.ipc", false},
+		{"/var/run/geth.sock", false},
+	}
+	for _, tt := range tests {
+		if got := IsIPCEndpoint(tt.endpoint); got != tt.want {
+			t.Errorf("IsIPCEndpoint(%q) = %v, want %v", tt.endpoint, got, tt.want)
+		}
+	}
+}
+
+// ipcServer listens on a Unix socket and passes each decoded request, a
+// single message or a batch, to handle, writing back what it returns.
+// It returns the socket path and a count of accepted connections.
+func ipcServer(t *testing.T, handle func(conn net.Conn, req json.RawMessage) any) (string, *atomic.Int32) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "geth.ipc")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	var conns atomic.Int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns.Add(1)
+			go func() {
+				defer conn.Close()
+				dec := json.NewDecoder(conn)
+				for {
+					var req json.RawMessage
+					if err := dec.Decode(&req); err != nil {
+						return
+					}
+					if resp := handle(conn, req); resp != nil {
+						out, _ := json.Marshal(resp)
+						conn.Write(append(out, '\n'))
+					}
+				}
+			}()
+		}
+	}()
+	return path, &conns
+}
+
+func TestClient_IPC(t *testing.T) {
+	path, conns := ipcServer(t, func(conn net.Conn, raw json.RawMessage) any {
+		if raw[0] == '[' {
+			var reqs []rpcRequest
+			json.Unmarshal(raw, &reqs)
+			resps := make([]map[string]any, len(reqs))
+			for i, req := range reqs {
+				resps[i] = map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": map[string]any{"hash": req.Params[0]}}
+			}
+			return resps
+		}
+		var req rpcRequest
+		json.Unmarshal(raw, &req)
+		return map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": "0x5"}
+	})
+
+	c := NewClient(path)
+	defer c.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		id, err := c.ChainID(ctx)
+		if err != nil || id != 5 {
+			t.Fatalf("ChainID() = %d, %v; want 5", id, err)
+		}
+	}
+	txs, err := c.TransactionsByHashes(ctx, []string{"0x1", "0x2"})
+	if err != nil || len(txs) != 2 || txs[1].Hash != "0x2" {
+		t.Fatalf("TransactionsByHashes() = %v, %v", txs, err)
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("opened %d connections for sequential requests, want 1", n)
+	}
+}
+
+func TestClient_IPCCanceled(t *testing.T) {
+	path, conns := ipcServer(t, func(conn net.Conn, raw json.RawMessage) any {
+		return nil // never answers
+	})
+
+	c := NewClient(path)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.ChainID(ctx); err == nil {
+		t.Fatal("ChainID() succeeded without a response")
+	}
+
+	// The abandoned connection must not be reused
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel2()
+	c.ChainID(ctx2)
+	if n := conns.Load(); n != 2 {
+		t.Errorf("opened %d connections, want 2", n)
+	}
+}
+
+func TestWSSubscriber_IPC(t *testing.T) {
+	path, _ := ipcServer(t, func(conn net.Conn, raw json.RawMessage) any {
+		var req rpcRequest
+		json.Unmarshal(raw, &req)
+		if req.Method != "eth_subscribe" {
+			return nil
+		}
+		// Notify until the connection closes; the first may arrive before
+		// the subscriber has registered the subscription
+		go func() {
+			for {
+				time.Sleep(10 * time.Millisecond)
+				if _, err := fmt.Fprint(conn, `{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0xabc","result":{"number":"0x10"}}}`); err != nil {
+					return
+				}
+			}
+		}()
+		return map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": "0xabc"}
+	})
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewWSSubscriber(path, logger)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	heads, err := s.SubscribeNewHeads(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case block := <-heads:
+		if block.Number != 16 {
+			t.Errorf("block number = %d, want 16", block.Number)
+		}
+	case <-ctx.Done():
+		t.Fatal("no block received")
+	}
+
+	start := time.Now()
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("Close() took %v; IPC has no closing handshake to wait for", d)
+	}
+}
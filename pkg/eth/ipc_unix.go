@@ -0,0 +1,14 @@
+//go:build !windows
+
+package eth
+
+import (
+	"context"
+	"net"
+)
+
+// dialIPC connects to the Unix domain socket at path.
+func dialIPC(ctx context.Context, path string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", path)
+}
@@ -0,0 +1,42 @@
+//go:build windows
+
+package eth
+
+import (
+	"context"
+	"net"
+	"os"
+	"time"
+)
+
+// dialIPC opens the named pipe at path. Pipes opened this way don't support
+// deadlines, so a request blocked on an unresponsive node is only
+// interrupted by the node closing the pipe.
+func dialIPC(ctx context.Context, path string) (net.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return pipeConn{f}, nil
+}
+
+// pipeConn adapts a named pipe to net.Conn.
+type pipeConn struct {
+	*os.File
+}
+
+func (c pipeConn) LocalAddr() net.Addr  { return pipeAddr(c.Name()) }
+func (c pipeConn) RemoteAddr() net.Addr { return pipeAddr(c.Name()) }
+
+// Deadlines are best effort; see dialIPC.
+func (c pipeConn) SetDeadline(t time.Time) error      { c.File.SetDeadline(t); return nil }
+func (c pipeConn) SetReadDeadline(t time.Time) error  { c.File.SetReadDeadline(t); return nil }
+func (c pipeConn) SetWriteDeadline(t time.Time) error { c.File.SetWriteDeadline(t); return nil }
+
+type pipeAddr string
+
+func (a pipeAddr) Network() string { return "pipe" }
+func (a pipeAddr) String() string  { return string(a) }
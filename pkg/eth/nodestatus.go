@@ -0,0 +1,57 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/goccy/go-json"
+)
+
+// SyncStatus is the node's sync progress (eth_syncing). CurrentBlock and
+// HighestBlock are zero when the node is not syncing.
+type SyncStatus struct {
+	Syncing      bool
+	CurrentBlock uint64
+	HighestBlock uint64
+}
+
+// SyncStatusReader abstracts access to the node's sync progress.
+type SyncStatusReader interface {
+	Syncing(ctx context.Context) (SyncStatus, error)
+}
+
+// Syncing reports whether the node is still catching up with the network
+// (eth_syncing), which answers false or an object with its progress.
+func (c *Client) Syncing(ctx context.Context) (SyncStatus, error) {
+	var raw json.RawMessage
+	if err := c.call(ctx, "eth_syncing", nil, &raw); err != nil {
+		return SyncStatus{}, err
+	}
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("false")) {
+		return SyncStatus{}, nil
+	}
+	var progress struct {
+		CurrentBlock hexUint64 `json:"currentBlock"`
+		HighestBlock hexUint64 `json:"highestBlock"`
+	}
+	if err := json.Unmarshal(raw, &progress); err != nil {
+		return SyncStatus{}, fmt.Errorf("unmarshaling sync status: %w", err)
+	}
+	return SyncStatus{
+		Syncing:      true,
+		CurrentBlock: uint64(progress.CurrentBlock),
+		HighestBlock: uint64(progress.HighestBlock),
+	}, nil
+}
+
+// PeerCount returns the number of peers the node is connected to
+// (net_peerCount). Providers that hide the net namespace fail with a node
+// error.
+func (c *Client) PeerCount(ctx context.Context) (uint64, error) {
+	var result hexUint64
+	if err := c.call(ctx, "net_peerCount", nil, &result); err != nil {
+		return 0, err
+	}
+	return uint64(result), nil
+}
@@ -0,0 +1,243 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/holiman/uint256"
+)
+
+// DefaultPollInterval is how often a PollingSubscriber polls the node.
+const DefaultPollInterval = time.Second
+
+// maxPollCatchUp bounds the blocks a PollingSubscriber delivers after
+// falling behind: only the newest ones are sent, like a WebSocket
+// subscription that doesn't replay missed heads.
+const maxPollCatchUp = 16
+
+// PollingSubscriber implements Subscriber over plain JSON-RPC, for nodes or
+// RPC plans without a WebSocket endpoint. New heads are found by polling
+// eth_blockNumber, and pending transaction hashes by polling a filter from
+// eth_newPendingTransactionFilter with eth_getFilterChanges.
+//
+// Poll errors are logged and retried on the next tick; subscription
+// channels close only when their context is canceled or the subscriber is
+// closed.
+type PollingSubscriber struct {
+	client   *Client
+	logger   *slog.Logger
+	interval time.Duration
+
+	closed atomic.Bool
+	done   chan struct{}
+}
+
+// PollingOption configures a PollingSubscriber.
+type PollingOption func(*PollingSubscriber)
+
+// WithPollInterval sets how often the node is polled for new blocks and
+// pending transactions. Default: DefaultPollInterval.
+func WithPollInterval(d time.Duration) PollingOption {
+	return func(s *PollingSubscriber) {
+		s.interval = d
+	}
+}
+
+// NewPollingSubscriber creates a subscriber that polls the node through client.
+func NewPollingSubscriber(client *Client, logger *slog.Logger, opts ...PollingOption) *PollingSubscriber {
+	s := &PollingSubscriber{
+		client:   client,
+		logger:   logger,
+		interval: DefaultPollInterval,
+		done:     make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// SubscribeNewHeads delivers block headers as eth_blockNumber advances.
+func (s *PollingSubscriber) SubscribeNewHeads(ctx context.Context) (<-chan *Block, error) {
+	if s.closed.Load() {
+		return nil, errors.New("subscriber closed")
+	}
+
+	// Start from the current head so only new blocks are delivered
+	last, err := s.blockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("polling block number: %w", err)
+	}
+
+	blockCh := make(chan *Block, 16)
+
+	go func() {
+		defer close(blockCh)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.done:
+				return
+			case <-ticker.C:
+			}
+
+			head, err := s.blockNumber(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					s.logger.Warn("polling block number", "error", err)
+				}
+				continue
+			}
+			if head <= last {
+				continue
+			}
+
+			from := max(last+1, head-min(head, maxPollCatchUp-1))
+			for n := from; n <= head; n++ {
+				block, err := s.client.blockByTag(ctx, uint256.NewInt(n).Hex(), false)
+				if err != nil {
+					if ctx.Err() == nil {
+						s.logger.Warn("fetching polled block header", "block", n, "error", err)
+					}
+					break
+				}
+				select {
+				case blockCh <- block:
+				case <-ctx.Done():
+					return
+				case <-s.done:
+					return
+				}
+				last = n
+			}
+		}
+	}()
+
+	return blockCh, nil
+}
+
+// SubscribeNewPendingTransactions delivers pending transaction hashes from a
+// pending transaction filter. The filter is uninstalled when ctx is
+// canceled, and reinstalled if the node drops it (filters expire when not
+// polled for a while, typically 5 minutes).
+func (s *PollingSubscriber) SubscribeNewPendingTransactions(ctx context.Context) (<-chan string, error) {
+	if s.closed.Load() {
+		return nil, errors.New("subscriber closed")
+	}
+
+	id, err := s.newPendingFilter(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating pending transaction filter: %w", err)
+	}
+
+	hashCh := make(chan string, 1024)
+
+	go func() {
+		defer close(hashCh)
+		defer func() {
+			// The subscription context is done; use a fresh one to clean up
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			s.client.call(ctx, "eth_uninstallFilter", []any{id}, nil)
+		}()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.done:
+				return
+			case <-ticker.C:
+			}
+
+			var hashes []string
+			err := s.client.call(ctx, "eth_getFilterChanges", []any{id}, &hashes)
+			if err != nil && isFilterNotFound(err) {
+				s.logger.Info("pending transaction filter expired, reinstalling")
+				if id, err = s.newPendingFilter(ctx); err == nil {
+					continue
+				}
+			}
+			if err != nil {
+				if ctx.Err() == nil {
+					s.logger.Warn("polling pending transaction filter", "error", err)
+				}
+				continue
+			}
+
+			for _, hash := range hashes {
+				select {
+				case hashCh <- hash:
+				case <-ctx.Done():
+					return
+				case <-s.done:
+					return
+				}
+			}
+		}
+	}()
+
+	return hashCh, nil
+}
+
+// ProbePendingSubscription installs and uninstalls a pending transaction
+// filter, reporting whether the node supports them.
+func (s *PollingSubscriber) ProbePendingSubscription(ctx context.Context) bool {
+	id, err := s.newPendingFilter(ctx)
+	if err != nil {
+		return false
+	}
+	s.client.call(ctx, "eth_uninstallFilter", []any{id}, nil)
+	return true
+}
+
+// Close stops all subscriptions. Pending transaction filters are
+// uninstalled as their subscriptions end.
+func (s *PollingSubscriber) Close() error {
+	if s.closed.Swap(true) {
+		return nil
+	}
+	close(s.done)
+	return nil
+}
+
+func (s *PollingSubscriber) blockNumber(ctx context.Context) (uint64, error) {
+	var result hexUint64
+	if err := s.client.call(ctx, "eth_blockNumber", nil, &result); err != nil {
+		return 0, err
+	}
+	return uint64(result), nil
+}
+
+func (s *PollingSubscriber) newPendingFilter(ctx context.Context) (string, error) {
+	var id string
+	if err := s.client.call(ctx, "eth_newPendingTransactionFilter", nil, &id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// isFilterNotFound reports whether err is a node's response to polling an
+// expired or unknown filter.
+func isFilterNotFound(err error) bool {
+	var rpcErr *rpcError
+	return errors.As(err, &rpcErr) && strings.Contains(strings.ToLower(rpcErr.Message), "filter not found")
+}
+
+// Verify interface compliance at compile time.
+var _ Subscriber = (*PollingSubscriber)(nil)
@@ -0,0 +1,175 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// pollingNode is a fake HTTP-only node for PollingSubscriber tests.
+type pollingNode struct {
+	head atomic.Uint64
+
+	mu          sync.Mutex
+	filters     int
+	changes     map[string][][]string // queued eth_getFilterChanges results per filter
+	uninstalled []string
+}
+
+func (n *pollingNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     uint64            `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	resp := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	switch req.Method {
+	case "eth_blockNumber":
+		resp["result"] = fmt.Sprintf("0x%x", n.head.Load())
+	case "eth_getBlockByNumber":
+		var tag string
+		json.Unmarshal(req.Params[0], &tag)
+		resp["result"] = map[string]any{"number": tag, "hash": "0xh" + tag, "gasLimit": "0x1c9c380", "transactions": []string{}}
+	case "eth_newPendingTransactionFilter":
+		n.filters++
+		resp["result"] = "0x" + strconv.Itoa(n.filters)
+	case "eth_getFilterChanges":
+		var id string
+		json.Unmarshal(req.Params[0], &id)
+		queued, ok := n.changes[id]
+		switch {
+		case !ok:
+			resp["error"] = map[string]any{"code": -32000, "message": "filter not found"}
+		case len(queued) == 0:
+			resp["result"] = []string{}
+		default:
+			resp["result"] = queued[0]
+			n.changes[id] = queued[1:]
+		}
+	case "eth_uninstallFilter":
+		var id string
+		json.Unmarshal(req.Params[0], &id)
+		n.uninstalled = append(n.uninstalled, id)
+		resp["result"] = true
+	default:
+		resp["error"] = map[string]any{"code": -32601, "message": "the method does not exist"}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func TestPollingSubscriber_NewHeads(t *testing.T) {
+	node := &pollingNode{}
+	node.head.Store(100)
+	srv := httptest.NewServer(node)
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewPollingSubscriber(NewClient(srv.URL), logger, WithPollInterval(5*time.Millisecond))
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ch, err := s.SubscribeNewHeads(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two blocks at once are both delivered, in order; the head at
+	// subscription time is not
+	node.head.Store(102)
+	for _, want := range []uint64{101, 102} {
+		select {
+		case b := <-ch:
+			if b.Number != want {
+				t.Fatalf("block = %d, want %d", b.Number, want)
+			}
+		case <-ctx.Done():
+			t.Fatalf("no block %d", want)
+		}
+	}
+
+	// Far behind: only the newest maxPollCatchUp blocks
+	node.head.Store(200)
+	select {
+	case b := <-ch:
+		if want := uint64(200 - maxPollCatchUp + 1); b.Number != want {
+			t.Errorf("block after gap = %d, want %d", b.Number, want)
+		}
+	case <-ctx.Done():
+		t.Fatal("no block after gap")
+	}
+
+	s.Close()
+	for range ch {
+	}
+}
+
+func TestPollingSubscriber_PendingTransactions(t *testing.T) {
+	node := &pollingNode{changes: map[string][][]string{
+		"0x1": {{"0xa", "0xb"}},
+	}}
+	srv := httptest.NewServer(node)
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewPollingSubscriber(NewClient(srv.URL), logger, WithPollInterval(5*time.Millisecond))
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	subCtx, unsubscribe := context.WithCancel(ctx)
+	ch, err := s.SubscribeNewPendingTransactions(subCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for len(got) < 2 {
+		select {
+		case h := <-ch:
+			got = append(got, h)
+		case <-ctx.Done():
+			t.Fatalf("received %v", got)
+		}
+	}
+
+	// Filter 0x1 expires once drained; the subscription reinstalls it as 0x2
+	node.mu.Lock()
+	delete(node.changes, "0x1")
+	node.changes["0x2"] = [][]string{{"0xc"}}
+	node.mu.Unlock()
+	select {
+	case h := <-ch:
+		if h != "0xc" {
+			t.Errorf("hash after reinstall = %s, want 0xc", h)
+		}
+	case <-ctx.Done():
+		t.Fatal("no hash after filter expired")
+	}
+
+	unsubscribe()
+	for range ch {
+	}
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	if len(node.uninstalled) != 1 || node.uninstalled[0] != "0x2" {
+		t.Errorf("uninstalled filters = %v, want [0x2]", node.uninstalled)
+	}
+	if fmt.Sprint(got) != "[0xa 0xb]" {
+		t.Errorf("hashes = %v", got)
+	}
+}
@@ -0,0 +1,111 @@
+package eth
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// WithSubscriberProxy tunnels WebSocket connections through the HTTP proxy
+// at proxyURL instead of the one taken from HTTPS_PROXY/HTTP_PROXY/NO_PROXY
+// (HTTPS_PROXY for wss:// endpoints). A nil URL disables proxying. IPC
+// endpoints are never proxied.
+func WithSubscriberProxy(proxyURL *url.URL) SubscriberOption {
+	return func(s *WSSubscriber) {
+		if proxyURL == nil {
+			s.proxy = nil
+			return
+		}
+		s.proxy = http.ProxyURL(proxyURL)
+	}
+}
+
+// proxyFor returns the proxy to reach the WebSocket endpoint u through, or
+// nil to connect directly. ws and wss are looked up as http and https, the
+// schemes the proxy environment variables are keyed by.
+func (s *WSSubscriber) proxyFor(u *url.URL) (*url.URL, error) {
+	if s.proxy == nil {
+		return nil, nil
+	}
+	target := *u
+	target.Scheme = "http"
+	if u.Scheme == "wss" {
+		target.Scheme = "https"
+	}
+	return s.proxy(&http.Request{URL: &target})
+}
+
+// dialProxy connects to proxyURL and asks it to open a tunnel to host
+// (host:port) with an HTTP CONNECT request. The returned connection carries
+// the raw stream to host; TLS and the WebSocket handshake run inside it.
+func dialProxy(ctx context.Context, dialer *net.Dialer, proxyURL *url.URL, host string) (net.Conn, error) {
+	proxyHost := proxyURL.Host
+	if proxyURL.Port() == "" {
+		if proxyURL.Scheme == "https" {
+			proxyHost += ":443"
+		} else {
+			proxyHost += ":80"
+		}
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", proxyHost)
+	if err != nil {
+		return nil, fmt.Errorf("dialing proxy: %w", err)
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("proxy tls handshake: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	// Bound the CONNECT exchange like the dial; the tunnel itself has no
+	// deadline
+	deadline := time.Now().Add(dialer.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	defer conn.SetDeadline(time.Time{})
+
+	var req strings.Builder
+	fmt.Fprintf(&req, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n", host, host)
+	if u := proxyURL.User; u != nil {
+		password, _ := u.Password()
+		creds := base64.StdEncoding.EncodeToString([]byte(u.Username() + ":" + password))
+		fmt.Fprintf(&req, "Proxy-Authorization: Basic %s\r\n", creds)
+	}
+	req.WriteString("\r\n")
+	if _, err := conn.Write([]byte(req.String())); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("sending proxy CONNECT: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("reading proxy CONNECT response: %w", err)
+	}
+	// A successful CONNECT response has no body; the tunnel follows it
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT to %s: %s", host, resp.Status)
+	}
+	// The server speaks first in neither TLS nor the WebSocket handshake,
+	// so nothing of the tunnel can have been read along with the response
+	if reader.Buffered() > 0 {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT to %s: unexpected data after response", host)
+	}
+	return conn, nil
+}
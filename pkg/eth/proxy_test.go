@@ -0,0 +1,112 @@
+package eth
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// connectProxy is an HTTP proxy that tunnels CONNECT requests carrying
+// auth as their Proxy-Authorization and answers others with 407. It
+// reports each tunnel's target on targets.
+func connectProxy(t *testing.T, auth string) (proxyURL *url.URL, targets <-chan string) {
+	t.Helper()
+	hosts := make(chan string, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect || r.Header.Get("Proxy-Authorization") != auth {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		hosts <- r.Host
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			upstream.Close()
+			return
+		}
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go func() {
+			io.Copy(upstream, rw)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	t.Cleanup(srv.Close)
+
+	u, _ := url.Parse(srv.URL)
+	return u, hosts
+}
+
+func TestWSSubscriber_Proxy(t *testing.T) {
+	wsURL := wsServer(t, func(conn net.Conn, r *bufio.Reader) {
+		readClientFrame(r) // hold the connection until the client closes
+	})
+	target := strings.TrimPrefix(wsURL, "ws://")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("tunnel", func(t *testing.T) {
+		proxyURL, targets := connectProxy(t, "Basic dXNlcjpzZWNyZXQ=") // user:secret
+		proxyURL.User = url.UserPassword("user", "secret")
+		s := NewWSSubscriber(wsURL, logger, WithSubscriberProxy(proxyURL))
+		defer s.Close()
+
+		if err := s.Connect(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case host := <-targets:
+			if host != target {
+				t.Errorf("CONNECT %s, want %s", host, target)
+			}
+		default:
+			t.Error("connected without tunneling through the proxy")
+		}
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		proxyURL, _ := connectProxy(t, "Basic dXNlcjpzZWNyZXQ=")
+		s := NewWSSubscriber(wsURL, logger, WithSubscriberProxy(proxyURL))
+		defer s.Close()
+
+		err := s.Connect(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "407") {
+			t.Errorf("Connect() error = %v, want the proxy's 407", err)
+		}
+	})
+}
+
+func TestWSSubscriber_ProxyFor(t *testing.T) {
+	proxy := func(req *http.Request) (*url.URL, error) {
+		return &url.URL{Scheme: "http", Host: req.URL.Scheme + ".proxy:3128"}, nil
+	}
+	s := NewWSSubscriber("wss://node.example/ws", slog.Default())
+	s.proxy = proxy
+	for endpoint, want := range map[string]string{
+		"ws://node.example":  "http.proxy:3128",
+		"wss://node.example": "https.proxy:3128",
+	} {
+		u, _ := url.Parse(endpoint)
+		got, err := s.proxyFor(u)
+		if err != nil || got.Host != want {
+			t.Errorf("proxyFor(%s) = %v, %v, want %s", endpoint, got, err, want)
+		}
+	}
+
+	s = NewWSSubscriber("ws://node.example", slog.Default(), WithSubscriberProxy(nil))
+	if got, err := s.proxyFor(&url.URL{Scheme: "ws", Host: "node.example"}); got != nil || err != nil {
+		t.Errorf("proxyFor() with proxying disabled = %v, %v", got, err)
+	}
+}
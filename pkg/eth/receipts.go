@@ -0,0 +1,107 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/goccy/go-json"
+	"github.com/holiman/uint256"
+)
+
+// Receipt holds the fee-relevant fields of a transaction receipt.
+type Receipt struct {
+	TxHash  string
+	GasUsed uint64
+
+	// EffectiveGasPrice is the price per gas actually paid: base fee plus
+	// the tip, which for EIP-1559 transactions may be less than
+	// MaxPriorityFeePerGas when MaxFeePerGas caps it. Nil if the node
+	// omits it (pre-London receipts).
+	EffectiveGasPrice *uint256.Int
+}
+
+// ReceiptReader abstracts receipt fetching for mined blocks.
+type ReceiptReader interface {
+	// BlockReceipts returns the receipts of block's transactions. block
+	// must carry its hash and full transactions.
+	BlockReceipts(ctx context.Context, block *Block) ([]Receipt, error)
+}
+
+// rpcReceipt is the JSON-RPC representation of a receipt.
+type rpcReceipt struct {
+	TransactionHash   string    `json:"transactionHash"`
+	GasUsed           hexUint64 `json:"gasUsed"`
+	EffectiveGasPrice *hexBig   `json:"effectiveGasPrice"`
+}
+
+func (r *rpcReceipt) toReceipt() Receipt {
+	rc := Receipt{TxHash: r.TransactionHash, GasUsed: uint64(r.GasUsed)}
+	if r.EffectiveGasPrice != nil {
+		rc.EffectiveGasPrice = r.EffectiveGasPrice.Int()
+	}
+	return rc
+}
+
+// BlockReceipts fetches a block's receipts with eth_getBlockReceipts. Nodes
+// without it (the method is recent in some clients) get one batch of
+// eth_getTransactionReceipt calls instead, and are not asked again.
+func (c *Client) BlockReceipts(ctx context.Context, block *Block) ([]Receipt, error) {
+	if !c.noBlockReceipts.Load() {
+		var raw []rpcReceipt
+		err := c.call(ctx, "eth_getBlockReceipts", []any{block.Hash}, &raw)
+		var rpcErr *rpcError
+		switch {
+		case err == nil:
+			receipts := make([]Receipt, len(raw))
+			for i := range raw {
+				receipts[i] = raw[i].toReceipt()
+			}
+			return receipts, nil
+		case errors.As(err, &rpcErr):
+			c.noBlockReceipts.Store(true)
+		default:
+			return nil, fmt.Errorf("eth_getBlockReceipts: %w", err)
+		}
+	}
+	return c.transactionReceipts(ctx, block.Transactions)
+}
+
+// transactionReceipts fetches receipts for txs in a single batch request.
+// Receipts that fail or are missing are skipped.
+func (c *Client) transactionReceipts(ctx context.Context, txs []Transaction) ([]Receipt, error) {
+	if len(txs) == 0 {
+		return nil, nil
+	}
+
+	reqs := make([]rpcRequest, len(txs))
+	for i, tx := range txs {
+		reqs[i] = rpcRequest{
+			JSONRPC: "2.0",
+			ID:      c.requestID.Add(1),
+			Method:  "eth_getTransactionReceipt",
+			Params:  []any{tx.Hash},
+		}
+	}
+
+	responses, err := c.batchCall(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	receipts := make([]Receipt, 0, len(responses))
+	for _, resp := range responses {
+		if resp.Error != nil || len(resp.Result) == 0 || string(resp.Result) == "null" {
+			continue
+		}
+		var raw rpcReceipt
+		if err := json.Unmarshal(resp.Result, &raw); err != nil {
+			continue
+		}
+		receipts = append(receipts, raw.toReceipt())
+	}
+	return receipts, nil
+}
+
+// Verify interface compliance at compile time.
+var _ ReceiptReader = (*Client)(nil)
@@ -0,0 +1,242 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+)
+
+// Dedup windows of a ShardedSubscriber: how many recent heads and pending
+// transactions it remembers to drop copies delivered by other shards. A
+// copy arriving after this many newer items is delivered again.
+const (
+	shardHeadWindow = 64
+	shardTxWindow   = 8192
+)
+
+// ShardedSubscriber merges the streams of several subscribers, typically
+// WSSubscribers on separate connections to one or more endpoints, so that a
+// burst of pending transactions too large for one connection is spread over
+// many. Heads and transactions delivered by more than one shard are passed
+// on once.
+//
+// A subscription succeeds if any shard's does, and ends when any shard's
+// stream ends, so the caller's resubscription reconnects the lost shard.
+type ShardedSubscriber struct {
+	shards []Subscriber
+	logger *slog.Logger
+}
+
+// NewShardedSubscriber creates a subscriber merging shards. It takes
+// ownership of the shards and closes them on Close.
+func NewShardedSubscriber(logger *slog.Logger, shards ...Subscriber) *ShardedSubscriber {
+	return &ShardedSubscriber{shards: shards, logger: logger}
+}
+
+// SubscribeNewHeads subscribes every shard to new block headers.
+func (s *ShardedSubscriber) SubscribeNewHeads(ctx context.Context) (<-chan *Block, error) {
+	return mergeShards(ctx, s, "newHeads", 16, shardHeadWindow, true,
+		func(ctx context.Context, sub Subscriber) (<-chan *Block, error) {
+			return sub.SubscribeNewHeads(ctx)
+		},
+		func(b *Block) string {
+			if b.Hash != "" {
+				return b.Hash
+			}
+			return strconv.FormatUint(b.Number, 10)
+		})
+}
+
+// SubscribeNewPendingTransactions subscribes every shard to new pending
+// transaction hashes.
+func (s *ShardedSubscriber) SubscribeNewPendingTransactions(ctx context.Context) (<-chan string, error) {
+	return mergeShards(ctx, s, "newPendingTransactions", 128, shardTxWindow, false,
+		func(ctx context.Context, sub Subscriber) (<-chan string, error) {
+			return sub.SubscribeNewPendingTransactions(ctx)
+		},
+		func(hash string) string { return hash })
+}
+
+// SubscribeFullPendingTransactions subscribes every shard that supports it
+// to full pending transaction bodies. It returns ErrNotSupported if no
+// shard does.
+func (s *ShardedSubscriber) SubscribeFullPendingTransactions(ctx context.Context) (<-chan *Transaction, error) {
+	return mergeShards(ctx, s, "full newPendingTransactions", 128, shardTxWindow, false,
+		func(ctx context.Context, sub Subscriber) (<-chan *Transaction, error) {
+			full, ok := sub.(FullPendingTxSubscriber)
+			if !ok {
+				return nil, ErrNotSupported
+			}
+			return full.SubscribeFullPendingTransactions(ctx)
+		},
+		func(tx *Transaction) string { return tx.Hash })
+}
+
+// ProbePendingSubscription reports whether any shard supports pending
+// transaction subscriptions.
+func (s *ShardedSubscriber) ProbePendingSubscription(ctx context.Context) bool {
+	for _, sub := range s.shards {
+		if prober, ok := sub.(SubscriptionProber); ok && prober.ProbePendingSubscription(ctx) {
+			return true
+		}
+	}
+	return false
+}
+
+// ConnectionStats sums the shards' connection statistics. LastClose is the
+// first shard's with a close.
+func (s *ShardedSubscriber) ConnectionStats() ConnectionStats {
+	var total ConnectionStats
+	for _, sub := range s.shards {
+		r, ok := sub.(ConnectionStatsReader)
+		if !ok {
+			continue
+		}
+		stats := r.ConnectionStats()
+		total.Connects += stats.Connects
+		total.NormalCloses += stats.NormalCloses
+		total.AbnormalCloses += stats.AbnormalCloses
+		if total.LastClose == nil {
+			total.LastClose = stats.LastClose
+		}
+	}
+	return total
+}
+
+// Close closes every shard.
+func (s *ShardedSubscriber) Close() error {
+	var errs []error
+	for _, sub := range s.shards {
+		errs = append(errs, sub.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// mergeShards subscribes every shard concurrently with subscribe and
+// forwards their items to one channel of size buffer, dropping items whose
+// key was among the last window forwarded. Heads (block true) wait for the
+// consumer; transactions are dropped when it falls behind, as a single
+// subscriber drops them.
+func mergeShards[T any](
+	ctx context.Context,
+	s *ShardedSubscriber,
+	event string,
+	buffer, window int,
+	block bool,
+	subscribe func(context.Context, Subscriber) (<-chan T, error),
+	key func(T) string,
+) (<-chan T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	chans := make([]<-chan T, len(s.shards))
+	errs := make([]error, len(s.shards))
+	var wg sync.WaitGroup
+	for i, sub := range s.shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			chans[i], errs[i] = subscribe(ctx, sub)
+		}()
+	}
+	wg.Wait()
+
+	var live []<-chan T
+	for i, ch := range chans {
+		if errs[i] != nil {
+			s.logger.Warn("shard subscription failed", "event", event, "shard", i, "error", errs[i])
+			continue
+		}
+		live = append(live, ch)
+	}
+	if len(live) == 0 {
+		cancel()
+		return nil, errors.Join(errs...)
+	}
+
+	out := make(chan T, buffer)
+	seen := newRecentSet(window)
+	var forwarders sync.WaitGroup
+	for _, ch := range live {
+		forwarders.Add(1)
+		go func() {
+			defer forwarders.Done()
+			// One shard's stream ending ends the merged stream
+			defer cancel()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case v, ok := <-ch:
+					if !ok {
+						return
+					}
+					if !seen.add(key(v)) {
+						continue
+					}
+					if block {
+						select {
+						case out <- v:
+						case <-ctx.Done():
+							return
+						}
+					} else {
+						select {
+						case out <- v:
+						default:
+							// Drop if buffer full - we only need a sample
+						}
+					}
+				}
+			}
+		}()
+	}
+	go func() {
+		forwarders.Wait()
+		cancel()
+		close(out)
+	}()
+
+	return out, nil
+}
+
+// recentSet remembers the last n keys added to it.
+type recentSet struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+	ring []string
+	next int
+}
+
+func newRecentSet(n int) *recentSet {
+	return &recentSet{keys: make(map[string]struct{}, n), ring: make([]string, n)}
+}
+
+// add records key and reports whether it was not already among the
+// remembered keys.
+func (r *recentSet) add(key string) bool {
+	if key == "" {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.keys[key]; ok {
+		return false
+	}
+	if old := r.ring[r.next]; old != "" {
+		delete(r.keys, old)
+	}
+	r.ring[r.next] = key
+	r.next = (r.next + 1) % len(r.ring)
+	r.keys[key] = struct{}{}
+	return true
+}
+
+// Verify interface compliance at compile time.
+var (
+	_ Subscriber              = (*ShardedSubscriber)(nil)
+	_ FullPendingTxSubscriber = (*ShardedSubscriber)(nil)
+	_ ConnectionStatsReader   = (*ShardedSubscriber)(nil)
+	_ SubscriptionProber      = (*ShardedSubscriber)(nil)
+)
@@ -0,0 +1,117 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+// chanSubscriber is a fake shard whose streams are fed by the test.
+type chanSubscriber struct {
+	heads  chan *Block
+	hashes chan string
+	err    error
+}
+
+func newChanSubscriber() *chanSubscriber {
+	return &chanSubscriber{heads: make(chan *Block, 8), hashes: make(chan string, 8)}
+}
+
+func (s *chanSubscriber) SubscribeNewHeads(ctx context.Context) (<-chan *Block, error) {
+	return s.heads, s.err
+}
+
+func (s *chanSubscriber) SubscribeNewPendingTransactions(ctx context.Context) (<-chan string, error) {
+	return s.hashes, s.err
+}
+
+func (s *chanSubscriber) Close() error { return nil }
+
+func TestShardedSubscriber_Dedup(t *testing.T) {
+	a, b := newChanSubscriber(), newChanSubscriber()
+	s := NewShardedSubscriber(slog.New(slog.NewTextHandler(io.Discard, nil)), a, b)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hashes, err := s.SubscribeNewPendingTransactions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.hashes <- "0x1"
+	b.hashes <- "0x1"
+	b.hashes <- "0x2"
+	a.hashes <- "0x2"
+	a.hashes <- "0x3"
+
+	var got []string
+	timeout := time.After(time.Second)
+	for len(got) < 3 {
+		select {
+		case h := <-hashes:
+			got = append(got, h)
+		case <-timeout:
+			t.Fatalf("received %v, want 3 hashes", got)
+		}
+	}
+	select {
+	case h := <-hashes:
+		t.Errorf("duplicate %s delivered", h)
+	case <-time.After(20 * time.Millisecond):
+	}
+	slices.Sort(got)
+	if want := []string{"0x1", "0x2", "0x3"}; !slices.Equal(got, want) {
+		t.Errorf("received %v, want %v", got, want)
+	}
+
+	// A shard's stream ending ends the merged one
+	close(b.hashes)
+	select {
+	case _, ok := <-hashes:
+		if ok {
+			t.Error("merged stream still open after a shard closed")
+		}
+	case <-time.After(time.Second):
+		t.Error("merged stream still open after a shard closed")
+	}
+}
+
+func TestShardedSubscriber_PartialFailure(t *testing.T) {
+	a, b := newChanSubscriber(), newChanSubscriber()
+	b.err = errors.New("dial failed")
+	s := NewShardedSubscriber(slog.New(slog.NewTextHandler(io.Discard, nil)), a, b)
+
+	heads, err := s.SubscribeNewHeads(context.Background())
+	if err != nil {
+		t.Fatalf("SubscribeNewHeads with one shard up: %v", err)
+	}
+	a.heads <- &Block{Number: 7, Hash: "0x7"}
+	if head := <-heads; head.Number != 7 {
+		t.Errorf("head = %d, want 7", head.Number)
+	}
+
+	a.err = errors.New("dial failed")
+	if _, err := s.SubscribeNewHeads(context.Background()); err == nil {
+		t.Error("SubscribeNewHeads with every shard down: want error")
+	}
+	if _, err := s.SubscribeFullPendingTransactions(context.Background()); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("SubscribeFullPendingTransactions without support = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestRecentSet(t *testing.T) {
+	r := newRecentSet(2)
+	for _, tc := range []struct {
+		key  string
+		want bool
+	}{
+		{"a", true}, {"a", false}, {"b", true}, {"c", true}, {"a", true}, {"c", false},
+	} {
+		if got := r.add(tc.key); got != tc.want {
+			t.Errorf("add(%q) = %v, want %v", tc.key, got, tc.want)
+		}
+	}
+}
@@ -10,11 +10,12 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -29,29 +30,184 @@ type Subscriber interface {
 	Close() error
 }
 
-// WSSubscriber implements Subscriber using WebSocket connections.
+// FullPendingTxSubscriber is implemented by subscribers that can stream full
+// pending transaction objects instead of hashes, using
+// eth_subscribe("newPendingTransactions", true) (Geth >= 1.11 and compatible nodes).
+type FullPendingTxSubscriber interface {
+	SubscribeFullPendingTransactions(ctx context.Context) (<-chan *Transaction, error)
+}
+
+// ErrNotSupported indicates the node does not support the requested subscription.
+var ErrNotSupported = errors.New("not supported by node")
+
+// CloseError reports why a WebSocket connection ended: the status code and
+// reason from the server's close frame, or CloseAbnormal with the read error
+// if the connection dropped without one.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket closed with code %d", e.Code)
+	}
+	return fmt.Sprintf("websocket closed with code %d: %s", e.Code, e.Reason)
+}
+
+// Normal reports whether the server closed the connection deliberately
+// (normal closure or going away, e.g. for maintenance).
+func (e *CloseError) Normal() bool {
+	return e.Code == CloseNormalClosure || e.Code == CloseGoingAway
+}
+
+// ConnectionStats counts WebSocket connections and how they ended.
+type ConnectionStats struct {
+	Connects uint64
+
+	// NormalCloses counts connections the server closed with a normal
+	// closure or going away code; AbnormalCloses all others, including
+	// protocol errors and connections that dropped without a close frame.
+	// Connections closed by Close are not counted.
+	NormalCloses   uint64
+	AbnormalCloses uint64
+
+	// LastClose is why the most recent connection ended; nil if none has.
+	LastClose *CloseError
+}
+
+// ConnectionStatsReader exposes WebSocket connection statistics.
+// Implemented by WSSubscriber and ShardedSubscriber.
+type ConnectionStatsReader interface {
+	ConnectionStats() ConnectionStats
+}
+
+// closeHandshakeTimeout bounds how long Close waits for the server to
+// answer its close frame.
+const closeHandshakeTimeout = time.Second
+
+// fullTxProbeTimeout bounds how long SubscribeFullPendingTransactions waits for
+// the first notification to verify the node sends full transaction bodies.
+const fullTxProbeTimeout = 5 * time.Second
+
+// WSSubscriber implements Subscriber using WebSocket connections, or an IPC
+// connection when created with an IPC endpoint (see IsIPCEndpoint). IPC
+// carries the same JSON-RPC messages without WebSocket framing, so pings,
+// the closing handshake and the message size limit don't apply.
+//
+// Subscriptions to the same event share one upstream eth_subscribe: every
+// caller gets its own channel, fed from a single node subscription that is
+// closed when the last caller's context is canceled.
 type WSSubscriber struct {
 	wsURL  string
+	ipc    bool
 	logger *slog.Logger
+	auth   Auth
+
+	// proxy selects the HTTP proxy to tunnel through; nil connects directly
+	proxy func(*http.Request) (*url.URL, error)
+
+	// tlsConfig is the base TLS configuration for wss endpoints; nil uses
+	// the system roots
+	tlsConfig *tls.Config
 
 	mu       sync.Mutex
 	conn     net.Conn
+	connDone chan struct{} // closed when the connection's read loop exits
 	reader   *bufio.Reader
-	subs     map[string]chan json.RawMessage
+	dec      *json.Decoder                   // reads messages from IPC connections
+	subs     map[string]chan json.RawMessage // pending RPC responses by "temp_<id>"
 	closed   atomic.Bool
 	done     chan struct{}
 	subCount atomic.Uint64
 	writeMu  sync.Mutex
+
+	// Upstream subscriptions by subscription ID and by event; guarded by mu.
+	// streamMu serializes their creation.
+	streams      map[string]*stream
+	streamsByKey map[string]*stream
+	streamMu     sync.Mutex
+
+	pingInterval time.Duration // 0 disables client pings
+	pongTimeout  time.Duration
+	lastPong     atomic.Int64 // unix nanos of the last pong received
+
+	maxMessageSize int64
+
+	connects       atomic.Uint64
+	normalCloses   atomic.Uint64
+	abnormalCloses atomic.Uint64
+	lastClose      atomic.Pointer[CloseError]
+}
+
+// Default client keepalive settings.
+const (
+	DefaultPingInterval = 15 * time.Second
+	DefaultPongTimeout  = 10 * time.Second
+)
+
+// SubscriberOption configures a WSSubscriber.
+type SubscriberOption func(*WSSubscriber)
+
+// WithSubscriberAuth attaches credentials to the WebSocket handshake.
+func WithSubscriberAuth(auth Auth) SubscriberOption {
+	return func(s *WSSubscriber) {
+		s.auth = auth
+	}
+}
+
+// WithSubscriberTLSConfig sets the TLS configuration for wss endpoints,
+// e.g. a private CA, a client certificate or a server name that differs
+// from the URL's host. The config is copied.
+func WithSubscriberTLSConfig(config *tls.Config) SubscriberOption {
+	return func(s *WSSubscriber) {
+		s.tlsConfig = config.Clone()
+	}
+}
+
+// WithPing sets how often the subscriber pings the server and how long it
+// waits for the pong before treating the connection as dead. An interval of
+// zero disables client-initiated pings.
+func WithPing(interval, timeout time.Duration) SubscriberOption {
+	return func(s *WSSubscriber) {
+		s.pingInterval = interval
+		s.pongTimeout = timeout
+	}
+}
+
+// WithMaxMessageSize limits the size of messages read from the server,
+// including reassembled fragmented messages. A server exceeding it is
+// disconnected. Default: DefaultMaxMessageSize.
+func WithMaxMessageSize(n int64) SubscriberOption {
+	return func(s *WSSubscriber) {
+		s.maxMessageSize = n
+	}
 }
 
 // NewWSSubscriber creates a new WebSocket subscriber.
-func NewWSSubscriber(wsURL string, logger *slog.Logger) *WSSubscriber {
-	return &WSSubscriber{
+func NewWSSubscriber(wsURL string, logger *slog.Logger, opts ...SubscriberOption) *WSSubscriber {
+	s := &WSSubscriber{
 		wsURL:  wsURL,
+		ipc:    IsIPCEndpoint(wsURL),
 		logger: logger,
+		proxy:  http.ProxyFromEnvironment,
 		subs:   make(map[string]chan json.RawMessage),
 		done:   make(chan struct{}),
+
+		streams:      make(map[string]*stream),
+		streamsByKey: make(map[string]*stream),
+
+		pingInterval: DefaultPingInterval,
+		pongTimeout:  DefaultPongTimeout,
+
+		maxMessageSize: DefaultMaxMessageSize,
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
 
 // Connect establishes the WebSocket connection.
@@ -63,6 +219,20 @@ func (s *WSSubscriber) Connect(ctx context.Context) error {
 		return errors.New("subscriber closed")
 	}
 
+	if s.ipc {
+		dialCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		conn, err := dialIPC(dialCtx, s.wsURL)
+		if err != nil {
+			return fmt.Errorf("dialing ipc: %w", err)
+		}
+		reader := bufio.NewReader(conn)
+		s.dec = json.NewDecoder(reader)
+		s.attach(conn, reader)
+		s.logger.Info("ipc connected", "path", s.wsURL)
+		return nil
+	}
+
 	u, err := url.Parse(s.wsURL)
 	if err != nil {
 		return fmt.Errorf("parsing URL: %w", err)
@@ -77,17 +247,30 @@ func (s *WSSubscriber) Connect(ctx context.Context) error {
 		}
 	}
 
+	proxyURL, err := s.proxyFor(u)
+	if err != nil {
+		return fmt.Errorf("resolving proxy: %w", err)
+	}
+
 	var conn net.Conn
 	dialer := &net.Dialer{Timeout: 10 * time.Second}
-	conn, err = dialer.DialContext(ctx, "tcp", host)
+	if proxyURL != nil {
+		conn, err = dialProxy(ctx, dialer, proxyURL, host)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", host)
+	}
 	if err != nil {
 		return fmt.Errorf("dialing: %w", err)
 	}
 
 	// Handle WSS (TLS)
 	if u.Scheme == "wss" {
-		tlsConfig := &tls.Config{
-			ServerName: u.Hostname(),
+		tlsConfig := s.tlsConfig.Clone()
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = u.Hostname()
 		}
 		tlsConn := tls.Client(conn, tlsConfig)
 		if err := tlsConn.HandshakeContext(ctx); err != nil {
@@ -113,15 +296,26 @@ func (s *WSSubscriber) Connect(ctx context.Context) error {
 		path += "?" + u.RawQuery
 	}
 
-	req := fmt.Sprintf("GET %s HTTP/1.1\r\n"+
+	// Credentials embedded in the URL are used when no explicit auth is set
+	auth := s.auth
+	if auth.IsZero() && u.User != nil {
+		auth.Username = u.User.Username()
+		auth.Password, _ = u.User.Password()
+	}
+	extra := make(http.Header)
+	auth.apply(extra, time.Now())
+
+	var req strings.Builder
+	fmt.Fprintf(&req, "GET %s HTTP/1.1\r\n"+
 		"Host: %s\r\n"+
 		"Upgrade: websocket\r\n"+
 		"Connection: Upgrade\r\n"+
 		"Sec-WebSocket-Key: %s\r\n"+
-		"Sec-WebSocket-Version: 13\r\n"+
-		"\r\n", path, u.Host, wsKey)
+		"Sec-WebSocket-Version: 13\r\n", path, u.Host, wsKey)
+	extra.Write(&req)
+	req.WriteString("\r\n")
 
-	if _, err := conn.Write([]byte(req)); err != nil {
+	if _, err := conn.Write([]byte(req.String())); err != nil {
 		conn.Close()
 		return fmt.Errorf("sending handshake: %w", err)
 	}
@@ -148,13 +342,24 @@ func (s *WSSubscriber) Connect(ctx context.Context) error {
 		return errors.New("invalid Sec-WebSocket-Accept")
 	}
 
+	s.attach(conn, reader)
+	s.logger.Info("websocket connected", "url", s.wsURL)
+	return nil
+}
+
+// attach starts reading from a freshly established connection. s.mu must
+// be held.
+func (s *WSSubscriber) attach(conn net.Conn, reader *bufio.Reader) {
 	s.conn = conn
 	s.reader = reader
+	s.connects.Add(1)
 
-	go s.readLoop()
-
-	s.logger.Info("websocket connected", "url", s.wsURL)
-	return nil
+	connDone := make(chan struct{})
+	s.connDone = connDone
+	go s.readLoop(connDone)
+	if s.pingInterval > 0 && !s.ipc {
+		go s.pingLoop(conn, connDone)
+	}
 }
 
 // SubscribeNewPendingTransactions subscribes to new pending transaction hashes.
@@ -169,7 +374,7 @@ func (s *WSSubscriber) SubscribeNewPendingTransactions(ctx context.Context) (<-c
 		}
 	}
 
-	subID, rawCh, err := s.subscribe(ctx, "newPendingTransactions")
+	rawCh, release, err := s.subscribe(ctx, "newPendingTransactions")
 	if err != nil {
 		return nil, fmt.Errorf("subscribing to newPendingTransactions: %w", err)
 	}
@@ -178,7 +383,7 @@ func (s *WSSubscriber) SubscribeNewPendingTransactions(ctx context.Context) (<-c
 
 	go func() {
 		defer close(txHashCh)
-		defer s.unsubscribe(subID)
+		defer release()
 
 		for {
 			select {
@@ -207,6 +412,88 @@ func (s *WSSubscriber) SubscribeNewPendingTransactions(ctx context.Context) (<-c
 	return txHashCh, nil
 }
 
+// SubscribeFullPendingTransactions subscribes to full pending transaction bodies.
+//
+// The subscription is probed before returning: if the node rejects the
+// request, or ignores the flag and sends hashes, ErrNotSupported is returned
+// and callers should fall back to SubscribeNewPendingTransactions.
+// If no transaction arrives within the probe window the node is assumed to support it.
+func (s *WSSubscriber) SubscribeFullPendingTransactions(ctx context.Context) (<-chan *Transaction, error) {
+	s.mu.Lock()
+	needsConnect := s.conn == nil
+	s.mu.Unlock()
+
+	if needsConnect {
+		if err := s.Connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	rawCh, release, err := s.subscribe(ctx, "newPendingTransactions", true)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotSupported, err)
+	}
+
+	// Probe: the first notification must be an object, not a hash string
+	var first json.RawMessage
+	select {
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	case raw, ok := <-rawCh:
+		if !ok {
+			return nil, errors.New("connection closed during probe")
+		}
+		if len(raw) == 0 || raw[0] != '{' {
+			release()
+			return nil, fmt.Errorf("%w: node sent transaction hashes", ErrNotSupported)
+		}
+		first = raw
+	case <-time.After(fullTxProbeTimeout):
+	}
+
+	txCh := make(chan *Transaction, 128)
+
+	go func() {
+		defer close(txCh)
+		defer release()
+
+		forward := func(raw json.RawMessage) {
+			var rtx rpcTransaction
+			if err := json.Unmarshal(raw, &rtx); err != nil {
+				s.logger.Error("parsing pending tx", "error", err)
+				return
+			}
+			tx := rtx.toTransaction()
+			select {
+			case txCh <- &tx:
+			default:
+				// Drop if buffer full - we only need a sample
+			}
+		}
+
+		if first != nil {
+			forward(first)
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.done:
+				return
+			case raw, ok := <-rawCh:
+				if !ok {
+					return
+				}
+				forward(raw)
+			}
+		}
+	}()
+
+	return txCh, nil
+}
+
 // SubscribeNewHeads subscribes to new block headers.
 func (s *WSSubscriber) SubscribeNewHeads(ctx context.Context) (<-chan *Block, error) {
 	s.mu.Lock()
@@ -219,7 +506,7 @@ func (s *WSSubscriber) SubscribeNewHeads(ctx context.Context) (<-chan *Block, er
 		}
 	}
 
-	subID, rawCh, err := s.subscribe(ctx, "newHeads")
+	rawCh, release, err := s.subscribe(ctx, "newHeads")
 	if err != nil {
 		return nil, fmt.Errorf("subscribing to newHeads: %w", err)
 	}
@@ -228,7 +515,7 @@ func (s *WSSubscriber) SubscribeNewHeads(ctx context.Context) (<-chan *Block, er
 
 	go func() {
 		defer close(blockCh)
-		defer s.unsubscribe(subID)
+		defer release()
 
 		for {
 			select {
@@ -257,14 +544,99 @@ func (s *WSSubscriber) SubscribeNewHeads(ctx context.Context) (<-chan *Block, er
 	return blockCh, nil
 }
 
-func (s *WSSubscriber) subscribe(ctx context.Context, event string) (string, chan json.RawMessage, error) {
+// stream is one upstream eth_subscribe shared by every local subscriber of
+// the same event, so embedding applications and the estimator don't open
+// duplicate subscriptions on the node.
+type stream struct {
+	id        string
+	key       string
+	consumers map[chan json.RawMessage]struct{}
+}
+
+// subscribe returns a channel of notifications for event, joining the
+// upstream subscription if one is open and creating it otherwise. release
+// must be called when the caller is done; the upstream subscription is
+// closed when its last consumer releases. The channel is closed by release
+// or when the connection drops.
+func (s *WSSubscriber) subscribe(ctx context.Context, event string, args ...any) (ch chan json.RawMessage, release func(), err error) {
+	params := append([]any{event}, args...)
+	keyBytes, err := json.Marshal(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := string(keyBytes)
+
+	// Serializes upstream subscription creation so concurrent callers of
+	// the same event share one
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+
+	s.mu.Lock()
+	st, ok := s.streamsByKey[key]
+	s.mu.Unlock()
+
+	if !ok {
+		subID, err := s.rpcSubscribe(ctx, params)
+		if err != nil {
+			return nil, nil, err
+		}
+		st = &stream{id: subID, key: key, consumers: make(map[chan json.RawMessage]struct{})}
+		s.mu.Lock()
+		s.streams[subID] = st
+		s.streamsByKey[key] = st
+		s.mu.Unlock()
+		s.logger.Debug("subscribed", "event", event, "subscription_id", subID)
+	}
+
+	ch = make(chan json.RawMessage, 64)
+	s.mu.Lock()
+	if s.streams[st.id] != st {
+		// The connection dropped since the upstream subscription was opened
+		s.mu.Unlock()
+		return nil, nil, errors.New("connection closed")
+	}
+	st.consumers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() { s.release(st, ch) })
+	}
+	return ch, release, nil
+}
+
+// release removes a consumer from st, closing the upstream subscription if
+// it was the last one.
+func (s *WSSubscriber) release(st *stream, ch chan json.RawMessage) {
+	s.mu.Lock()
+	if _, ok := st.consumers[ch]; !ok {
+		// Already closed with the connection
+		s.mu.Unlock()
+		return
+	}
+	close(ch)
+	delete(st.consumers, ch)
+	last := len(st.consumers) == 0
+	if last {
+		delete(s.streams, st.id)
+		delete(s.streamsByKey, st.key)
+	}
+	s.mu.Unlock()
+
+	if last {
+		s.unsubscribe(st.id)
+	}
+}
+
+// rpcSubscribe sends eth_subscribe and returns the subscription ID.
+func (s *WSSubscriber) rpcSubscribe(ctx context.Context, params []any) (string, error) {
 	id := s.subCount.Add(1)
 
 	req := map[string]any{
 		"jsonrpc": "2.0",
 		"id":      id,
 		"method":  "eth_subscribe",
-		"params":  []string{event},
+		"params":  params,
 	}
 
 	respCh := make(chan json.RawMessage, 1)
@@ -275,29 +647,26 @@ func (s *WSSubscriber) subscribe(ctx context.Context, event string) (string, cha
 	s.subs[tempID] = respCh
 	s.mu.Unlock()
 
-	if err := s.writeJSON(req); err != nil {
+	defer func() {
 		s.mu.Lock()
 		delete(s.subs, tempID)
 		s.mu.Unlock()
-		return "", nil, fmt.Errorf("sending subscribe request: %w", err)
+	}()
+
+	if err := s.writeJSON(req); err != nil {
+		return "", fmt.Errorf("sending subscribe request: %w", err)
 	}
 
 	// Wait for response with timeout
 	select {
 	case <-ctx.Done():
-		s.mu.Lock()
-		delete(s.subs, tempID)
-		s.mu.Unlock()
-		return "", nil, ctx.Err()
+		return "", ctx.Err()
 	case <-time.After(10 * time.Second):
-		s.mu.Lock()
-		delete(s.subs, tempID)
-		s.mu.Unlock()
-		return "", nil, errors.New("subscription timeout")
-	case raw := <-respCh:
-		s.mu.Lock()
-		delete(s.subs, tempID)
-		s.mu.Unlock()
+		return "", errors.New("subscription timeout")
+	case raw, ok := <-respCh:
+		if !ok {
+			return "", errors.New("connection closed")
+		}
 
 		var resp struct {
 			Result string `json:"result"`
@@ -307,31 +676,17 @@ func (s *WSSubscriber) subscribe(ctx context.Context, event string) (string, cha
 			} `json:"error"`
 		}
 		if err := json.Unmarshal(raw, &resp); err != nil {
-			return "", nil, fmt.Errorf("parsing subscribe response: %w", err)
+			return "", fmt.Errorf("parsing subscribe response: %w", err)
 		}
 		if resp.Error != nil {
-			return "", nil, fmt.Errorf("subscription error: %s", resp.Error.Message)
+			return "", fmt.Errorf("subscription error: %s", resp.Error.Message)
 		}
-
-		subID := resp.Result
-		ch := make(chan json.RawMessage, 64)
-		s.mu.Lock()
-		s.subs[subID] = ch
-		s.mu.Unlock()
-
-		s.logger.Debug("subscribed", "event", event, "subscription_id", subID)
-		return subID, ch, nil
+		return resp.Result, nil
 	}
 }
 
+// unsubscribe sends eth_unsubscribe for subID.
 func (s *WSSubscriber) unsubscribe(subID string) {
-	s.mu.Lock()
-	if ch, ok := s.subs[subID]; ok {
-		close(ch)
-		delete(s.subs, subID)
-	}
-	s.mu.Unlock()
-
 	req := map[string]any{
 		"jsonrpc": "2.0",
 		"id":      s.subCount.Add(1),
@@ -341,13 +696,29 @@ func (s *WSSubscriber) unsubscribe(subID string) {
 	_ = s.writeJSON(req)
 }
 
-func (s *WSSubscriber) readLoop() {
+// Verify interface compliance at compile time.
+var (
+	_ Subscriber              = (*WSSubscriber)(nil)
+	_ FullPendingTxSubscriber = (*WSSubscriber)(nil)
+	_ ConnectionStatsReader   = (*WSSubscriber)(nil)
+)
+
+func (s *WSSubscriber) readLoop(connDone chan struct{}) {
+	defer close(connDone)
 	defer func() {
 		s.mu.Lock()
 		for _, ch := range s.subs {
 			close(ch)
 		}
 		s.subs = make(map[string]chan json.RawMessage)
+		for _, st := range s.streams {
+			for ch := range st.consumers {
+				close(ch)
+			}
+			st.consumers = nil
+		}
+		s.streams = make(map[string]*stream)
+		s.streamsByKey = make(map[string]*stream)
 		if s.conn != nil {
 			s.conn.Close()
 			s.conn = nil
@@ -363,10 +734,10 @@ func (s *WSSubscriber) readLoop() {
 		}
 
 		s.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-		data, err := s.readFrame()
+		data, err := s.readMessage()
 		if err != nil {
 			if !s.closed.Load() {
-				s.logger.Error("websocket read error", "error", err)
+				s.connectionLost(err)
 			}
 			return
 		}
@@ -390,13 +761,15 @@ func (s *WSSubscriber) readLoop() {
 
 		s.mu.Lock()
 		if notification.Method == "eth_subscription" {
-			// Subscription notification
-			if ch, ok := s.subs[notification.Params.Subscription]; ok {
-				select {
-				case ch <- notification.Params.Result:
-				default:
-					s.logger.Warn("subscription channel full, dropping message",
-						"subscription_id", notification.Params.Subscription)
+			// Subscription notification, fanned out to every consumer
+			if st, ok := s.streams[notification.Params.Subscription]; ok {
+				for ch := range st.consumers {
+					select {
+					case ch <- notification.Params.Result:
+					default:
+						s.logger.Warn("subscription channel full, dropping message",
+							"subscription_id", notification.Params.Subscription)
+					}
 				}
 			}
 		} else if notification.ID > 0 {
@@ -422,6 +795,11 @@ func (s *WSSubscriber) writeJSON(v any) error {
 }
 
 func (s *WSSubscriber) writeFrame(data []byte) error {
+	return s.writeFrameOp(opText, data)
+}
+
+// writeFrameOp writes a single masked frame with the given opcode.
+func (s *WSSubscriber) writeFrameOp(opcode byte, data []byte) error {
 	s.writeMu.Lock()
 	defer s.writeMu.Unlock()
 
@@ -433,9 +811,18 @@ func (s *WSSubscriber) writeFrame(data []byte) error {
 		return fmt.Errorf("connection closed")
 	}
 
-	// WebSocket frame: FIN=1, opcode=1 (text), mask=1 (client must mask)
+	if s.ipc {
+		// Messages are written unframed; there are no control frames
+		if opcode != opText {
+			return nil
+		}
+		_, err := conn.Write(data)
+		return err
+	}
+
+	// WebSocket frame: FIN=1, opcode, mask=1 (client must mask)
 	frame := make([]byte, 0, 14+len(data))
-	frame = append(frame, 0x81) // FIN + text frame
+	frame = append(frame, 0x80|opcode)
 
 	// Payload length
 	if len(data) < 126 {
@@ -467,113 +854,111 @@ func (s *WSSubscriber) writeFrame(data []byte) error {
 	return err
 }
 
-func (s *WSSubscriber) readFrame() ([]byte, error) {
-	for {
-		// Read first 2 bytes
-		header := make([]byte, 2)
-		if _, err := io.ReadFull(s.reader, header); err != nil {
-			return nil, err
-		}
-
-		// Check opcode
-		opcode := header[0] & 0x0F
-
-		// Payload length
-		payloadLen := int64(header[1] & 0x7F)
-		if payloadLen == 126 {
-			ext := make([]byte, 2)
-			if _, err := io.ReadFull(s.reader, ext); err != nil {
-				return nil, err
-			}
-			payloadLen = int64(binary.BigEndian.Uint16(ext))
-		} else if payloadLen == 127 {
-			ext := make([]byte, 8)
-			if _, err := io.ReadFull(s.reader, ext); err != nil {
-				return nil, err
+// readMessage reads the next data message, answering pings and recording
+// pongs that arrive in between. A close frame from the server is echoed to
+// complete the closing handshake and returned as a *CloseError.
+func (s *WSSubscriber) readMessage() ([]byte, error) {
+	if s.ipc {
+		var msg json.RawMessage
+		err := s.dec.Decode(&msg)
+		return msg, err
+	}
+	return readMessage(s.reader, s.maxMessageSize, func(f frame) error {
+		switch f.opcode {
+		case opClose:
+			closeErr, err := parseClose(f.payload)
+			if err != nil {
+				return err
 			}
-			payloadLen = int64(binary.BigEndian.Uint64(ext))
-		}
-
-		// Check for mask (server should not mask, but we should handle skipping it if present)
-		if header[1]&0x80 != 0 {
-			// Skip mask key
-			mask := make([]byte, 4)
-			if _, err := io.ReadFull(s.reader, mask); err != nil {
-				return nil, err
+			// If Close started the handshake this is the server's answer
+			if !s.closed.Load() {
+				_ = s.writeFrameOp(opClose, closePayload(closeErr.Code, ""))
 			}
-		}
-
-		// Read payload
-		payload := make([]byte, payloadLen)
-		if _, err := io.ReadFull(s.reader, payload); err != nil {
-			return nil, err
-		}
-
-		switch opcode {
-		case 0x01, 0x02: // Text or Binary
-			return payload, nil
-		case 0x08: // Close
-			return nil, errors.New("connection closed by server")
-		case 0x09: // Ping
+			return closeErr
+		case opPing:
 			s.logger.Debug("received ping, sending pong")
-			if err := s.writePong(payload); err != nil {
-				return nil, fmt.Errorf("sending pong: %w", err)
+			if err := s.writePong(f.payload); err != nil {
+				return fmt.Errorf("sending pong: %w", err)
 			}
-			continue // Read next frame
-		case 0x0A: // Pong
+		case opPong:
 			s.logger.Debug("received pong")
-			continue // Read next frame
-		default:
-			// Ignore unknown opcodes
-			continue
+			s.lastPong.Store(time.Now().UnixNano())
 		}
-	}
+		return nil
+	})
 }
 
-func (s *WSSubscriber) writePong(data []byte) error {
-	s.writeMu.Lock()
-	defer s.writeMu.Unlock()
+// connectionLost records why the connection ended. Violations of the
+// protocol by the server are answered with a close frame first.
+func (s *WSSubscriber) connectionLost(err error) {
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) {
+		closeErr = &CloseError{Code: CloseAbnormal, Reason: err.Error()}
+		switch {
+		case errors.Is(err, errProtocol):
+			_ = s.writeFrameOp(opClose, closePayload(CloseProtocolError, err.Error()))
+		case errors.Is(err, ErrMessageTooLarge):
+			_ = s.writeFrameOp(opClose, closePayload(CloseMessageTooBig, ""))
+		}
+	}
+	s.lastClose.Store(closeErr)
 
-	s.mu.Lock()
-	conn := s.conn
-	s.mu.Unlock()
+	if closeErr.Normal() {
+		s.normalCloses.Add(1)
+		s.logger.Info("websocket closed by server", "code", closeErr.Code, "reason", closeErr.Reason)
+		return
+	}
+	s.abnormalCloses.Add(1)
+	s.logger.Error("websocket connection lost", "code", closeErr.Code, "reason", closeErr.Reason)
+}
 
-	if conn == nil {
-		return fmt.Errorf("connection closed")
+// ConnectionStats returns connection counts and the last close reason.
+func (s *WSSubscriber) ConnectionStats() ConnectionStats {
+	return ConnectionStats{
+		Connects:       s.connects.Load(),
+		NormalCloses:   s.normalCloses.Load(),
+		AbnormalCloses: s.abnormalCloses.Load(),
+		LastClose:      s.lastClose.Load(),
 	}
+}
 
-	// WebSocket frame: FIN=1, opcode=0xA (Pong), mask=1
-	frame := make([]byte, 0, 14+len(data))
-	frame = append(frame, 0x8A) // FIN + Pong
+func (s *WSSubscriber) writePong(data []byte) error {
+	return s.writeFrameOp(opPong, data)
+}
 
-	// Payload length
-	if len(data) < 126 {
-		frame = append(frame, byte(len(data))|0x80) // Set mask bit
-	} else if len(data) < 65536 {
-		frame = append(frame, 126|0x80)
-		frame = append(frame, byte(len(data)>>8), byte(len(data)))
-	} else {
-		frame = append(frame, 127|0x80)
-		frame = append(frame, make([]byte, 8)...)
-		binary.BigEndian.PutUint64(frame[len(frame)-8:], uint64(len(data)))
-	}
+// pingLoop sends a ping every pingInterval on conn and closes conn if no pong
+// arrives within pongTimeout, so a silently dropped connection is noticed
+// quickly instead of at the read deadline. It exits when stop is closed.
+func (s *WSSubscriber) pingLoop(conn net.Conn, stop <-chan struct{}) {
+	ticker := time.NewTicker(s.pingInterval)
+	defer ticker.Stop()
 
-	// Masking key
-	mask := make([]byte, 4)
-	if _, err := rand.Read(mask); err != nil {
-		return err
-	}
-	frame = append(frame, mask...)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
 
-	// Masked payload
-	masked := make([]byte, len(data))
-	for i, b := range data {
-		masked[i] = b ^ mask[i%4]
-	}
-	frame = append(frame, masked...)
+		sent := time.Now()
+		if err := s.writeFrameOp(opPing, []byte(strconv.FormatInt(sent.UnixNano(), 10))); err != nil {
+			s.logger.Warn("websocket ping failed", "error", err)
+			conn.Close()
+			return
+		}
 
-	_, err := conn.Write(frame)
-	return err
+		select {
+		case <-stop:
+			return
+		case <-time.After(s.pongTimeout):
+			if s.lastPong.Load() < sent.UnixNano() {
+				s.logger.Warn("websocket pong timeout, closing connection",
+					"timeout", s.pongTimeout)
+				conn.Close()
+				return
+			}
+		}
+	}
 }
 
 func (s *WSSubscriber) parseBlockHeader(raw json.RawMessage) (*Block, error) {
@@ -584,7 +969,9 @@ func (s *WSSubscriber) parseBlockHeader(raw json.RawMessage) (*Block, error) {
 	return header.toBlock(false)
 }
 
-// Close shuts down the subscriber and all active subscriptions.
+// Close shuts down the subscriber and all active subscriptions. It starts
+// the closing handshake and waits briefly for the server to answer before
+// closing the connection.
 func (s *WSSubscriber) Close() error {
 	if s.closed.Swap(true) {
 		return nil
@@ -593,15 +980,21 @@ func (s *WSSubscriber) Close() error {
 	close(s.done)
 
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	conn, connDone := s.conn, s.connDone
+	s.mu.Unlock()
 
-	if s.conn != nil {
-		// Send close frame
-		s.writeMu.Lock()
-		closeFrame := []byte{0x88, 0x02, 0x03, 0xe8} // Close with 1000 (normal closure)
-		s.conn.Write(closeFrame)
-		s.writeMu.Unlock()
-		return s.conn.Close()
+	if conn == nil {
+		return nil
+	}
+	// IPC has no closing handshake
+	if !s.ipc && s.writeFrameOp(opClose, closePayload(CloseNormalClosure, "")) == nil {
+		select {
+		case <-connDone:
+		case <-time.After(closeHandshakeTimeout):
+		}
+	}
+	if err := conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
 	}
 	return nil
 }
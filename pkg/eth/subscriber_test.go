@@ -0,0 +1,426 @@
+package eth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsTestServer accepts one WebSocket connection and reports client frames.
+// When answerPings is set it replies to pings with pongs.
+func wsTestServer(t *testing.T, answerPings bool) (url string, opcodes <-chan byte, closed <-chan struct{}) {
+	t.Helper()
+	ops := make(chan byte, 16)
+	done := make(chan struct{})
+
+	url = wsServer(t, func(conn net.Conn, r *bufio.Reader) {
+		defer close(done)
+		for {
+			op, payload, err := readClientFrame(r)
+			if err != nil {
+				return
+			}
+			ops <- op
+			if op == opClose {
+				writeServerFrame(conn, opClose, payload)
+				return
+			}
+			if op == 0x9 && answerPings {
+				writeServerFrame(conn, 0xA, payload)
+			}
+		}
+	})
+	return url, ops, done
+}
+
+// wsServer completes the WebSocket handshake and hands the connection to
+// handle, closing it when handle returns.
+func wsServer(t *testing.T, handle func(conn net.Conn, r *bufio.Reader)) string {
+	t.Helper()
+	srv := httptest.NewServer(wsHandler(t, handle))
+	t.Cleanup(srv.Close)
+
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// wsHandler is wsServer's handler.
+func wsHandler(t *testing.T, handle func(conn net.Conn, r *bufio.Reader)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := sha1.New()
+		h.Write([]byte(r.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+		w.Header().Set("Upgrade", "websocket")
+		w.Header().Set("Connection", "Upgrade")
+		w.Header().Set("Sec-WebSocket-Accept", base64.StdEncoding.EncodeToString(h.Sum(nil)))
+		w.WriteHeader(http.StatusSwitchingProtocols)
+
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		handle(conn, rw.Reader)
+	})
+}
+
+func readClientFrame(r *bufio.Reader) (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	n := int(header[1] & 0x7F)
+	switch n {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return 0, nil, err
+		}
+		n = int(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return 0, nil, err
+		}
+		n = int(binary.BigEndian.Uint64(ext))
+	}
+	mask := make([]byte, 4)
+	if _, err := io.ReadFull(r, mask); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return header[0] & 0x0F, payload, nil
+}
+
+func writeServerFrame(conn net.Conn, opcode byte, payload []byte) {
+	conn.Write(append([]byte{0x80 | opcode, byte(len(payload))}, payload...))
+}
+
+func TestWSSubscriber_TLSConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(wsHandler(t, func(conn net.Conn, r *bufio.Reader) {
+		readClientFrame(r) // hold the connection until the client closes
+	}))
+	defer srv.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// The test certificate is issued for example.com and 127.0.0.1, not
+	// localhost
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	wssURL := "wss://localhost:" + port
+
+	for _, tc := range []struct {
+		name   string
+		config *tls.Config
+		ok     bool
+	}{
+		{"system roots", nil, false},
+		{"name mismatch", &tls.Config{RootCAs: roots}, false},
+		{"server name override", &tls.Config{RootCAs: roots, ServerName: "example.com"}, true},
+		{"insecure", &tls.Config{InsecureSkipVerify: true}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var opts []SubscriberOption
+			if tc.config != nil {
+				opts = append(opts, WithSubscriberTLSConfig(tc.config))
+			}
+			s := NewWSSubscriber(wssURL, logger, opts...)
+			defer s.Close()
+
+			err := s.Connect(context.Background())
+			if tc.ok && err != nil {
+				t.Errorf("Connect() error = %v", err)
+			}
+			if !tc.ok && err == nil {
+				t.Error("Connect() succeeded, want certificate error")
+			}
+		})
+	}
+}
+
+func TestWSSubscriber_PingKeepsConnection(t *testing.T) {
+	url, ops, closed := wsTestServer(t, true)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewWSSubscriber(url, logger, WithPing(20*time.Millisecond, 50*time.Millisecond))
+	defer s.Close()
+
+	if err := s.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	pings := 0
+	deadline := time.After(time.Second)
+	for pings < 3 {
+		select {
+		case op := <-ops:
+			if op == 0x9 {
+				pings++
+			}
+		case <-closed:
+			t.Fatal("connection closed despite pongs")
+		case <-deadline:
+			t.Fatalf("saw %d pings, want 3", pings)
+		}
+	}
+}
+
+func TestWSSubscriber_PongTimeoutClosesConnection(t *testing.T) {
+	url, _, closed := wsTestServer(t, false)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewWSSubscriber(url, logger, WithPing(20*time.Millisecond, 50*time.Millisecond))
+	defer s.Close()
+
+	if err := s.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("connection not closed after pong timeout")
+	}
+}
+
+func TestWSSubscriber_CloseHandshake(t *testing.T) {
+	tests := []struct {
+		name       string
+		serverSend []byte // frame sent by the server; nil drops the connection
+		wantCode   int
+		wantEcho   []byte
+		wantNormal bool
+	}{
+		{
+			name:       "going away",
+			serverSend: rawFrame(true, opClose, closePayload(CloseGoingAway, "maintenance")),
+			wantCode:   CloseGoingAway,
+			wantEcho:   closePayload(CloseGoingAway, ""),
+			wantNormal: true,
+		},
+		{
+			name:       "internal error",
+			serverSend: rawFrame(true, opClose, closePayload(CloseInternalError, "")),
+			wantCode:   CloseInternalError,
+			wantEcho:   closePayload(CloseInternalError, ""),
+		},
+		{
+			name:       "protocol violation",
+			serverSend: rawFrame(true, opContinuation, []byte("x")),
+			wantCode:   CloseAbnormal,
+			wantEcho:   []byte{0x03, 0xEA}, // 1002, reason follows
+		},
+		{
+			name:     "dropped connection",
+			wantCode: CloseAbnormal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			echo := make(chan []byte, 1)
+			url := wsServer(t, func(conn net.Conn, r *bufio.Reader) {
+				if tt.serverSend == nil {
+					return
+				}
+				conn.Write(tt.serverSend)
+				op, payload, err := readClientFrame(r)
+				if err == nil && op == opClose {
+					echo <- payload
+				}
+			})
+
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			s := NewWSSubscriber(url, logger, WithPing(0, 0))
+			defer s.Close()
+
+			heads, err := connectDone(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			<-heads
+
+			stats := s.ConnectionStats()
+			if stats.LastClose == nil || stats.LastClose.Code != tt.wantCode {
+				t.Fatalf("LastClose = %v, want code %d", stats.LastClose, tt.wantCode)
+			}
+			if tt.wantNormal && stats.NormalCloses != 1 || !tt.wantNormal && stats.AbnormalCloses != 1 {
+				t.Errorf("stats = %+v, want one normal=%v close", stats, tt.wantNormal)
+			}
+			if tt.wantEcho != nil {
+				select {
+				case got := <-echo:
+					if !bytes.HasPrefix(got, tt.wantEcho) {
+						t.Errorf("client close payload = %x, want prefix %x", got, tt.wantEcho)
+					}
+				case <-time.After(time.Second):
+					t.Error("client did not answer with a close frame")
+				}
+			}
+		})
+	}
+}
+
+// connectDone connects s and returns a channel closed when the
+// connection's read loop exits.
+func connectDone(s *WSSubscriber) (<-chan struct{}, error) {
+	if err := s.Connect(context.Background()); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connDone, nil
+}
+
+func TestParseClose(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		want    *CloseError
+		wantErr bool
+	}{
+		{"empty", nil, &CloseError{Code: CloseNoStatus}, false},
+		{"code only", []byte{0x03, 0xE8}, &CloseError{Code: CloseNormalClosure}, false},
+		{"code and reason", append([]byte{0x03, 0xE9}, "bye"...), &CloseError{Code: CloseGoingAway, Reason: "bye"}, false},
+		{"truncated code", []byte{0x03}, nil, true},
+		{"reserved code", []byte{0x03, 0xED}, nil, true}, // 1005
+		{"invalid reason", []byte{0x03, 0xE8, 0xFF}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseClose(tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseClose() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && *got != *tt.want {
+				t.Errorf("parseClose() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	long := string(bytes.Repeat([]byte("é"), 100))
+	if p := closePayload(CloseGoingAway, long); len(p) > maxControlPayload {
+		t.Errorf("closePayload() length = %d, want at most %d", len(p), maxControlPayload)
+	}
+}
+
+func TestWSSubscriber_SharedSubscription(t *testing.T) {
+	type request struct {
+		ID     uint64 `json:"id"`
+		Method string `json:"method"`
+	}
+	requests := make(chan request, 16)
+	notify := make(chan struct{})
+
+	url := wsServer(t, func(conn net.Conn, r *bufio.Reader) {
+		frames := make(chan []byte)
+		go func() {
+			defer close(frames)
+			for {
+				op, payload, err := readClientFrame(r)
+				if err != nil || op == opClose {
+					return
+				}
+				if op == opText {
+					frames <- payload
+				}
+			}
+		}()
+		for {
+			select {
+			case payload, ok := <-frames:
+				if !ok {
+					return
+				}
+				var req request
+				json.Unmarshal(payload, &req)
+				requests <- req
+				if req.Method == "eth_subscribe" {
+					conn.Write(rawFrame(true, opText, []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":"0xabc"}`, req.ID))))
+				}
+			case <-notify:
+				conn.Write(rawFrame(true, opText, []byte(`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0xabc","result":{"number":"0x10"}}}`)))
+			}
+		}
+	})
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewWSSubscriber(url, logger, WithPing(0, 0))
+	defer s.Close()
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	defer cancel1()
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+
+	heads1, err := s.SubscribeNewHeads(ctx1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	heads2, err := s.SubscribeNewHeads(ctx2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req := <-requests; req.Method != "eth_subscribe" {
+		t.Fatalf("first request = %s, want eth_subscribe", req.Method)
+	}
+
+	// One notification reaches both consumers
+	notify <- struct{}{}
+	for i, ch := range []<-chan *Block{heads1, heads2} {
+		select {
+		case b := <-ch:
+			if b.Number != 16 {
+				t.Errorf("consumer %d got block %d, want 16", i+1, b.Number)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("consumer %d got no notification", i+1)
+		}
+	}
+
+	// The upstream subscription outlives the first consumer
+	cancel1()
+	for range heads1 {
+	}
+	select {
+	case req := <-requests:
+		t.Fatalf("request %s while a consumer remains", req.Method)
+	case <-time.After(50 * time.Millisecond):
+	}
+	cancel2()
+	for range heads2 {
+	}
+
+	select {
+	case req := <-requests:
+		if req.Method != "eth_unsubscribe" {
+			t.Errorf("request after last consumer left = %s, want eth_unsubscribe", req.Method)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no eth_unsubscribe after last consumer left")
+	}
+	select {
+	case req := <-requests:
+		t.Errorf("unexpected request %s", req.Method)
+	default:
+	}
+}
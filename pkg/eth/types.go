@@ -2,8 +2,10 @@
 package eth
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/holiman/uint256"
@@ -39,18 +41,43 @@ type Transaction struct {
 	GasPrice             *uint256.Int // legacy transactions
 	MaxFeePerGas         *uint256.Int // EIP-1559 transactions
 	MaxPriorityFeePerGas *uint256.Int // EIP-1559 transactions
-	Type                 uint8        // 0 = legacy, 2 = EIP-1559
+	Type                 uint8        // one of the *TxType constants, or a future type
+}
+
+// Transaction types (EIP-2718).
+const (
+	LegacyTxType     = 0x00
+	AccessListTxType = 0x01 // EIP-2930
+	DynamicFeeTxType = 0x02 // EIP-1559
+	BlobTxType       = 0x03 // EIP-4844
+	SetCodeTxType    = 0x04 // EIP-7702
+)
+
+// txTypeNames names the known transaction types, indexed by type, followed
+// by the name of all others.
+var txTypeNames = []string{"legacy", "access_list", "dynamic_fee", "blob", "set_code", "unknown"}
+
+// TxTypeName returns the name of transaction type t: "legacy",
+// "access_list", "dynamic_fee", "blob", "set_code", or "unknown" for types
+// introduced after set-code transactions.
+func TxTypeName(t uint8) string {
+	return txTypeNames[min(int(t), len(txTypeNames)-1)]
+}
+
+// TxTypeNames returns every name TxTypeName can return, "unknown" last.
+func TxTypeNames() []string {
+	return slices.Clone(txTypeNames)
 }
 
 // EffectivePriorityFee returns the priority fee that would be paid given a base fee.
-// For legacy transactions, this is gasPrice - baseFee.
-// For EIP-1559, this is min(maxPriorityFeePerGas, maxFeePerGas - baseFee).
+// For legacy and access list transactions, this is gasPrice - baseFee.
+// For EIP-1559 fee fields, this is min(maxPriorityFeePerGas, maxFeePerGas - baseFee).
 func (t *Transaction) EffectivePriorityFee(baseFee *uint256.Int) *uint256.Int {
 	if baseFee == nil {
 		return uint256.NewInt(0)
 	}
 
-	if t.Type == 2 && t.MaxFeePerGas != nil && t.MaxPriorityFeePerGas != nil {
+	if t.IsEIP1559() && t.MaxFeePerGas != nil && t.MaxPriorityFeePerGas != nil {
 		// EIP-1559 transaction
 		if t.MaxFeePerGas.Lt(baseFee) {
 			return uint256.NewInt(0)
@@ -77,9 +104,19 @@ func (t *Transaction) EffectivePriorityFee(baseFee *uint256.Int) *uint256.Int {
 	return new(uint256.Int).Sub(t.GasPrice, baseFee)
 }
 
-// IsEIP1559 returns true if this is an EIP-1559 transaction.
+// IsEIP1559 returns true if the transaction is priced with EIP-1559 fee
+// fields: dynamic fee, blob and set-code transactions, and transactions of
+// unknown future types that carry both fields. Unknown types without them
+// are priced by GasPrice if set, and otherwise have no priority fee.
 func (t *Transaction) IsEIP1559() bool {
-	return t.Type == 2
+	switch t.Type {
+	case LegacyTxType, AccessListTxType:
+		return false
+	case DynamicFeeTxType, BlobTxType, SetCodeTxType:
+		return true
+	default:
+		return t.MaxFeePerGas != nil && t.MaxPriorityFeePerGas != nil
+	}
 }
 
 // rpcBlock is the JSON-RPC representation of a block.
@@ -121,7 +158,8 @@ func (r *rpcBlock) toBlock(includeTxs bool) (*Block, error) {
 		block.BaseFee = r.BaseFee.Int()
 	}
 
-	if includeTxs && len(r.Transactions) > 0 && r.Transactions[0] == '{' {
+	// Hash-only blocks list strings; only decode transaction objects
+	if includeTxs && bytes.HasPrefix(bytes.TrimLeft(bytes.TrimPrefix(r.Transactions, []byte("[")), " \t\r\n"), []byte("{")) {
 		var txs []rpcTransaction
 		if err := json.Unmarshal(r.Transactions, &txs); err != nil {
 			return nil, fmt.Errorf("unmarshaling transactions: %w", err)
@@ -1,6 +1,7 @@
 package eth
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/holiman/uint256"
@@ -66,6 +67,60 @@ func TestTransaction_EffectivePriorityFee(t *testing.T) {
 			baseFee: u256(50),
 			want:    u256(0),
 		},
+		{
+			name: "Access list: GasPrice > BaseFee",
+			tx: &Transaction{
+				Type:     AccessListTxType,
+				GasPrice: u256(70),
+			},
+			baseFee: u256(50),
+			want:    u256(20),
+		},
+		{
+			name: "Blob: priced by fee fields",
+			tx: &Transaction{
+				Type:                 BlobTxType,
+				MaxFeePerGas:         u256(100),
+				MaxPriorityFeePerGas: u256(10),
+			},
+			baseFee: u256(50),
+			want:    u256(10),
+		},
+		{
+			name: "Set code: priced by fee fields",
+			tx: &Transaction{
+				Type:                 SetCodeTxType,
+				MaxFeePerGas:         u256(55),
+				MaxPriorityFeePerGas: u256(10),
+			},
+			baseFee: u256(50),
+			want:    u256(5),
+		},
+		{
+			name: "Unknown type with fee fields",
+			tx: &Transaction{
+				Type:                 0x7e,
+				MaxFeePerGas:         u256(100),
+				MaxPriorityFeePerGas: u256(3),
+			},
+			baseFee: u256(50),
+			want:    u256(3),
+		},
+		{
+			name: "Unknown type with GasPrice",
+			tx: &Transaction{
+				Type:     0x7e,
+				GasPrice: u256(60),
+			},
+			baseFee: u256(50),
+			want:    u256(10),
+		},
+		{
+			name:    "Unknown type without fees",
+			tx:      &Transaction{Type: 0x7e},
+			baseFee: u256(50),
+			want:    u256(0),
+		},
 	}
 
 	for _, tt := range tests {
@@ -77,3 +132,69 @@ func TestTransaction_EffectivePriorityFee(t *testing.T) {
 		})
 	}
 }
+
+func TestTxTypeName(t *testing.T) {
+	for typ, want := range map[uint8]string{
+		LegacyTxType:  "legacy",
+		BlobTxType:    "blob",
+		SetCodeTxType: "set_code",
+		5:             "unknown",
+		0x7e:          "unknown",
+	} {
+		if got := TxTypeName(typ); got != want {
+			t.Errorf("TxTypeName(%d) = %q, want %q", typ, got, want)
+		}
+	}
+}
+
+func TestRPCBlock_ToBlock(t *testing.T) {
+	tests := []struct {
+		name   string
+		txs    string
+		wantTx int
+	}{
+		{"full", `[{"hash":"0x1","type":"0x2","maxFeePerGas":"0x64","maxPriorityFeePerGas":"0xa"}]`, 1},
+		{"full with whitespace", `[ {"hash":"0x1"}, {"hash":"0x2"} ]`, 2},
+		{"hashes", `["0x1","0x2"]`, 0},
+		{"empty", `[]`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var raw rpcBlock
+			if err := json.Unmarshal([]byte(`{"number":"0x1","transactions":`+tt.txs+`}`), &raw); err != nil {
+				t.Fatal(err)
+			}
+			b, err := raw.toBlock(true)
+			if err != nil {
+				t.Fatalf("toBlock() error = %v", err)
+			}
+			if len(b.Transactions) != tt.wantTx {
+				t.Errorf("len(Transactions) = %d, want %d", len(b.Transactions), tt.wantTx)
+			}
+		})
+	}
+}
+
+func TestIntrinsicGas(t *testing.T) {
+	// 40 bytes: 10 zero, 30 non-zero, so two init code words
+	data := make([]byte, 40)
+	for i := 10; i < len(data); i++ {
+		data[i] = 0xff
+	}
+	tests := []struct {
+		name     string
+		data     []byte
+		creation bool
+		want     uint64
+	}{
+		{"transfer", nil, false, 21000},
+		{"call", data, false, 21000 + 10*4 + 30*16},
+		{"empty creation", nil, true, 53000},
+		{"creation", data, true, 53000 + 2*2 + 10*4 + 30*16},
+	}
+	for _, tt := range tests {
+		if got := IntrinsicGas(tt.data, tt.creation); got != tt.want {
+			t.Errorf("%s: IntrinsicGas() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
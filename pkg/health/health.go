@@ -22,6 +22,7 @@ type Server struct {
 	addr    string
 	checker ReadinessChecker
 	logger  *slog.Logger
+	mux     *http.ServeMux
 	server  *http.Server
 	ready   atomic.Bool
 }
@@ -35,6 +36,7 @@ func NewServer(addr string, checker ReadinessChecker, logger *slog.Logger) *Serv
 	}
 
 	mux := http.NewServeMux()
+	s.mux = mux
 	mux.HandleFunc("/healthz", s.handleLiveness)
 	mux.HandleFunc("/readyz", s.handleReadiness)
 	mux.HandleFunc("/", s.handleRoot)
@@ -57,6 +59,12 @@ func NewServer(addr string, checker ReadinessChecker, logger *slog.Logger) *Serv
 	return s
 }
 
+// Handle registers an additional handler, such as a metrics endpoint.
+// Call it before Run.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
 // Run starts the health server. Blocks until context is canceled.
 func (s *Server) Run(ctx context.Context) error {
 	s.ready.Store(true)
@@ -128,6 +136,7 @@ func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
 		"endpoints": map[string]string{
 			"/healthz": "Liveness probe",
 			"/readyz":  "Readiness probe",
+			"/metrics": "Prometheus metrics, if registered",
 		},
 	})
 }
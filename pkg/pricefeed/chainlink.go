@@ -0,0 +1,92 @@
+package pricefeed
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/eth"
+)
+
+// Chainlink aggregator function selectors.
+var (
+	selectorLatestRoundData = []byte{0xfe, 0xaf, 0x96, 0x8c} // latestRoundData()
+	selectorDecimals        = []byte{0x31, 0x3c, 0xe5, 0x67} // decimals()
+)
+
+// ChainlinkFeed reads a price from a Chainlink aggregator contract
+// (e.g. the ETH/USD feed) via eth_call.
+type ChainlinkFeed struct {
+	caller  eth.ContractCaller
+	address string
+
+	mu          sync.Mutex
+	decimals    uint8
+	hasDecimals bool
+}
+
+// NewChainlinkFeed creates a feed reading the aggregator at address.
+func NewChainlinkFeed(caller eth.ContractCaller, address string) *ChainlinkFeed {
+	return &ChainlinkFeed{
+		caller:  caller,
+		address: address,
+	}
+}
+
+// Price reads latestRoundData from the aggregator.
+func (f *ChainlinkFeed) Price(ctx context.Context) (Quote, error) {
+	decimals, err := f.loadDecimals(ctx)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	out, err := f.caller.Call(ctx, f.address, selectorLatestRoundData)
+	if err != nil {
+		return Quote{}, fmt.Errorf("calling latestRoundData: %w", err)
+	}
+	// (uint80 roundId, int256 answer, uint256 startedAt, uint256 updatedAt, uint80 answeredInRound)
+	if len(out) < 5*32 {
+		return Quote{}, fmt.Errorf("latestRoundData: short return data (%d bytes)", len(out))
+	}
+
+	answer := new(big.Int).SetBytes(out[32:64])
+	if out[32]&0x80 != 0 || answer.Sign() == 0 {
+		return Quote{}, fmt.Errorf("%w: non-positive answer", ErrNoPrice)
+	}
+	updatedAt := new(big.Int).SetBytes(out[96:128])
+
+	scale := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+	usd, _ := new(big.Float).Quo(new(big.Float).SetInt(answer), scale).Float64()
+
+	return Quote{
+		USD:       usd,
+		UpdatedAt: time.Unix(updatedAt.Int64(), 0),
+	}, nil
+}
+
+// loadDecimals reads and caches the aggregator's decimals.
+// Failures are not cached so a later call can retry.
+func (f *ChainlinkFeed) loadDecimals(ctx context.Context) (uint8, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.hasDecimals {
+		return f.decimals, nil
+	}
+
+	out, err := f.caller.Call(ctx, f.address, selectorDecimals)
+	if err != nil {
+		return 0, fmt.Errorf("calling decimals: %w", err)
+	}
+	if len(out) < 32 {
+		return 0, fmt.Errorf("decimals: short return data (%d bytes)", len(out))
+	}
+
+	f.decimals, f.hasDecimals = out[31], true
+	return f.decimals, nil
+}
+
+// Verify interface compliance at compile time.
+var _ Feed = (*ChainlinkFeed)(nil)
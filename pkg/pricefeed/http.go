@@ -0,0 +1,91 @@
+package pricefeed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPFeed reads a price from a JSON HTTP endpoint.
+//
+// Path is a dot-separated list of object keys leading to the price, e.g.
+// "ethereum.usd" for https://api.coingecko.com/api/v3/simple/price?ids=ethereum&vs_currencies=usd.
+// The value may be a JSON number or a numeric string.
+type HTTPFeed struct {
+	url        string
+	path       []string
+	httpClient *http.Client
+}
+
+// NewHTTPFeed creates a feed reading the value at path from url.
+func NewHTTPFeed(url, path string) *HTTPFeed {
+	var keys []string
+	if path != "" {
+		keys = strings.Split(path, ".")
+	}
+	return &HTTPFeed{
+		url:        url,
+		path:       keys,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Price fetches the current price.
+func (f *HTTPFeed) Price(ctx context.Context) (Quote, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return Quote{}, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return Quote{}, fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Quote{}, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var doc any
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return Quote{}, fmt.Errorf("decoding response: %w", err)
+	}
+
+	for _, key := range f.path {
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			return Quote{}, fmt.Errorf("%w: %q is not an object", ErrNoPrice, key)
+		}
+		doc = obj[key]
+	}
+
+	var usd float64
+	switch v := doc.(type) {
+	case float64:
+		usd = v
+	case string:
+		usd, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return Quote{}, fmt.Errorf("%w: invalid number %q", ErrNoPrice, v)
+		}
+	default:
+		return Quote{}, fmt.Errorf("%w: no numeric value at %q", ErrNoPrice, strings.Join(f.path, "."))
+	}
+
+	if usd <= 0 {
+		return Quote{}, fmt.Errorf("%w: non-positive price %v", ErrNoPrice, usd)
+	}
+
+	return Quote{USD: usd, UpdatedAt: time.Now()}, nil
+}
+
+// Verify interface compliance at compile time.
+var _ Feed = (*HTTPFeed)(nil)
@@ -0,0 +1,93 @@
+// Package pricefeed provides native token to fiat price quotes used to
+// express gas costs in USD.
+package pricefeed
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/holiman/uint256"
+)
+
+// ErrNoPrice indicates the feed could not produce a price.
+var ErrNoPrice = errors.New("price unavailable")
+
+// Feed provides the USD price of the chain's native token.
+type Feed interface {
+	Price(ctx context.Context) (Quote, error)
+}
+
+// Quote is the USD price of one whole native token (1e18 wei).
+type Quote struct {
+	USD       float64
+	UpdatedAt time.Time
+}
+
+// weiPerToken is 1e18 as a big.Float for fiat conversion.
+var weiPerToken = new(big.Float).SetFloat64(1e18)
+
+// ValueOf converts an amount in wei to USD at this quote's price.
+func (q Quote) ValueOf(wei *uint256.Int) float64 {
+	if wei == nil {
+		return 0
+	}
+	tokens := new(big.Float).SetInt(wei.ToBig())
+	tokens.Quo(tokens, weiPerToken)
+	usd, _ := tokens.Mul(tokens, big.NewFloat(q.USD)).Float64()
+	return usd
+}
+
+// Cached wraps a Feed and reuses the last quote for a fixed TTL, so API
+// requests don't hit the upstream source on every call.
+//
+// If a refresh fails, the previous quote keeps being served until it is
+// older than MaxAge (when MaxAge > 0).
+type Cached struct {
+	feed   Feed
+	ttl    time.Duration
+	maxAge time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	last      Quote
+	fetchedAt time.Time
+	ok        bool
+}
+
+// NewCached creates a cached feed. maxAge of 0 serves stale quotes indefinitely.
+func NewCached(feed Feed, ttl, maxAge time.Duration) *Cached {
+	return &Cached{
+		feed:   feed,
+		ttl:    ttl,
+		maxAge: maxAge,
+		now:    time.Now,
+	}
+}
+
+// Price returns the cached quote, refreshing it when older than the TTL.
+func (c *Cached) Price(ctx context.Context) (Quote, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if c.ok && now.Sub(c.fetchedAt) < c.ttl {
+		return c.last, nil
+	}
+
+	q, err := c.feed.Price(ctx)
+	if err == nil {
+		c.last, c.fetchedAt, c.ok = q, now, true
+		return q, nil
+	}
+
+	if c.ok && (c.maxAge == 0 || now.Sub(c.fetchedAt) < c.maxAge) {
+		return c.last, nil
+	}
+	return Quote{}, err
+}
+
+// Verify interface compliance at compile time.
+var _ Feed = (*Cached)(nil)
@@ -0,0 +1,146 @@
+package pricefeed
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+)
+
+func TestQuote_ValueOf(t *testing.T) {
+	q := Quote{USD: 2000}
+	// 21000 gas * 50 gwei = 0.00105 ETH = $2.10
+	got := q.ValueOf(uint256.NewInt(21000 * 50e9))
+	if math.Abs(got-2.10) > 1e-9 {
+		t.Errorf("ValueOf() = %v, want 2.10", got)
+	}
+}
+
+func TestHTTPFeed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ethereum":{"usd":3456.78},"str":{"usd":"12.5"}}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		want    float64
+		wantErr bool
+	}{
+		{name: "Number", path: "ethereum.usd", want: 3456.78},
+		{name: "String", path: "str.usd", want: 12.5},
+		{name: "Missing", path: "bitcoin.usd", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := NewHTTPFeed(srv.URL, tt.path).Price(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Price() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && q.USD != tt.want {
+				t.Errorf("Price() = %v, want %v", q.USD, tt.want)
+			}
+		})
+	}
+}
+
+type mockCaller struct {
+	calls int
+	fn    func(data []byte) ([]byte, error)
+}
+
+func (m *mockCaller) Call(ctx context.Context, to string, data []byte) ([]byte, error) {
+	m.calls++
+	return m.fn(data)
+}
+
+func TestChainlinkFeed(t *testing.T) {
+	word := func(v uint64) []byte {
+		b := make([]byte, 32)
+		uint256.NewInt(v).WriteToSlice(b)
+		return b
+	}
+
+	caller := &mockCaller{fn: func(data []byte) ([]byte, error) {
+		switch string(data) {
+		case string(selectorDecimals):
+			return word(8), nil
+		case string(selectorLatestRoundData):
+			var out []byte
+			out = append(out, word(1)...)            // roundId
+			out = append(out, word(345678000000)...) // answer: 3456.78 with 8 decimals
+			out = append(out, word(1700000000)...)   // startedAt
+			out = append(out, word(1700000012)...)   // updatedAt
+			out = append(out, word(1)...)            // answeredInRound
+			return out, nil
+		}
+		return nil, errors.New("unexpected call")
+	}}
+
+	feed := NewChainlinkFeed(caller, "0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419")
+	q, err := feed.Price(context.Background())
+	if err != nil {
+		t.Fatalf("Price() error = %v", err)
+	}
+	if math.Abs(q.USD-3456.78) > 1e-9 {
+		t.Errorf("Price() = %v, want 3456.78", q.USD)
+	}
+	if q.UpdatedAt.Unix() != 1700000012 {
+		t.Errorf("UpdatedAt = %d, want 1700000012", q.UpdatedAt.Unix())
+	}
+
+	// Decimals are only read once
+	if _, err := feed.Price(context.Background()); err != nil {
+		t.Fatalf("Price() error = %v", err)
+	}
+	if caller.calls != 3 {
+		t.Errorf("calls = %d, want 3", caller.calls)
+	}
+}
+
+type stubFeed struct {
+	quote Quote
+	err   error
+	calls int
+}
+
+func (s *stubFeed) Price(ctx context.Context) (Quote, error) {
+	s.calls++
+	return s.quote, s.err
+}
+
+func TestCached(t *testing.T) {
+	stub := &stubFeed{quote: Quote{USD: 100}}
+	c := NewCached(stub, time.Minute, 5*time.Minute)
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Price(context.Background()); err != nil {
+			t.Fatalf("Price() error = %v", err)
+		}
+	}
+	if stub.calls != 1 {
+		t.Errorf("calls within TTL = %d, want 1", stub.calls)
+	}
+
+	// Refresh fails: stale quote is served until maxAge
+	stub.err = errors.New("upstream down")
+	now = now.Add(2 * time.Minute)
+	q, err := c.Price(context.Background())
+	if err != nil || q.USD != 100 {
+		t.Errorf("stale Price() = %v, %v; want 100, nil", q.USD, err)
+	}
+
+	now = now.Add(10 * time.Minute)
+	if _, err := c.Price(context.Background()); err == nil {
+		t.Error("Price() past maxAge: want error")
+	}
+}
@@ -0,0 +1,318 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+)
+
+// Delivery headers. The signature is "sha256=" followed by the hex HMAC of
+// the timestamp header, a period and the body (see Sign).
+const (
+	SignatureHeader = "X-Gas-Signature"
+	TimestampHeader = "X-Gas-Timestamp"
+)
+
+// ErrLimitReached is returned by Register when the webhook limit is reached.
+var ErrLimitReached = errors.New("webhook limit reached")
+
+// Default dispatcher settings.
+const (
+	DefaultInterval    = time.Second
+	DefaultMaxAttempts = 5
+	DefaultMaxWebhooks = 100
+
+	defaultBackoff = time.Second
+	maxBackoff     = time.Minute
+)
+
+// Dispatcher holds registered webhooks, checks their conditions against
+// each new estimate and delivers triggered notifications.
+//
+// Thread safety: All methods are safe for concurrent use.
+type Dispatcher struct {
+	reader      estimator.EstimateReader
+	client      *http.Client
+	logger      *slog.Logger
+	interval    time.Duration
+	maxAttempts int
+	maxWebhooks int
+	backoff     time.Duration
+
+	mu    sync.Mutex
+	hooks map[string]*entry
+
+	deliveries sync.WaitGroup
+}
+
+// entry is a registered webhook and whether its condition held at the
+// last check.
+type entry struct {
+	Webhook
+	triggered bool
+}
+
+// Option configures a Dispatcher.
+type Option func(*Dispatcher)
+
+// WithInterval sets how often the current estimate is checked.
+// Default: 1s
+func WithInterval(interval time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.interval = interval
+	}
+}
+
+// WithMaxAttempts sets how many times a delivery is attempted before it is
+// dropped. Retries back off exponentially from 1s up to 1m.
+// Default: 5
+func WithMaxAttempts(n int) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = n
+	}
+}
+
+// WithMaxWebhooks bounds the number of registered webhooks.
+// Default: 100
+func WithMaxWebhooks(n int) Option {
+	return func(d *Dispatcher) {
+		d.maxWebhooks = n
+	}
+}
+
+// WithHTTPClient sets the client used for deliveries.
+func WithHTTPClient(c *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = c
+	}
+}
+
+// NewDispatcher creates a Dispatcher watching estimates from reader.
+func NewDispatcher(reader estimator.EstimateReader, logger *slog.Logger, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		reader:      reader,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		logger:      logger.With("component", "webhook"),
+		interval:    DefaultInterval,
+		maxAttempts: DefaultMaxAttempts,
+		maxWebhooks: DefaultMaxWebhooks,
+		backoff:     defaultBackoff,
+		hooks:       make(map[string]*entry),
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// Register adds a webhook for url and cond, generating its ID and signing
+// secret.
+func (d *Dispatcher) Register(url string, cond Condition) (Webhook, error) {
+	if err := ValidateURL(url); err != nil {
+		return Webhook{}, err
+	}
+	if err := cond.Validate(); err != nil {
+		return Webhook{}, err
+	}
+	if cond.Field == "" {
+		cond.Field = MaxFeePerGas
+	}
+
+	hook := Webhook{
+		ID:        randomHex(16),
+		URL:       url,
+		Secret:    randomHex(32),
+		Condition: cond,
+		CreatedAt: time.Now(),
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.hooks) >= d.maxWebhooks {
+		return Webhook{}, ErrLimitReached
+	}
+	d.hooks[hook.ID] = &entry{Webhook: hook}
+	return hook, nil
+}
+
+// Remove deletes the webhook with the given ID, reporting whether it existed.
+// Deliveries already in progress are completed.
+func (d *Dispatcher) Remove(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, ok := d.hooks[id]
+	delete(d.hooks, id)
+	return ok
+}
+
+// Get returns the webhook with the given ID.
+func (d *Dispatcher) Get(id string) (Webhook, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	e, ok := d.hooks[id]
+	if !ok {
+		return Webhook{}, false
+	}
+	return e.Webhook, true
+}
+
+// List returns the registered webhooks, oldest first.
+func (d *Dispatcher) List() []Webhook {
+	d.mu.Lock()
+	hooks := make([]Webhook, 0, len(d.hooks))
+	for _, e := range d.hooks {
+		hooks = append(hooks, e.Webhook)
+	}
+	d.mu.Unlock()
+
+	slices.SortFunc(hooks, func(a, b Webhook) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return hooks
+}
+
+// Run checks each new estimate until ctx is canceled, then waits for
+// deliveries in progress to give up.
+func (d *Dispatcher) Run(ctx context.Context) {
+	defer d.deliveries.Wait()
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	var version uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		est, err := d.reader.Current(ctx)
+		if err != nil || est.Version == version {
+			continue
+		}
+		version = est.Version
+		d.check(ctx, est)
+	}
+}
+
+// check evaluates every webhook against est and starts a delivery for each
+// condition that has just become true.
+func (d *Dispatcher) check(ctx context.Context, est *estimator.GasEstimate) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, e := range d.hooks {
+		met := e.Condition.Met(est)
+		if met && !e.triggered {
+			d.Send(ctx, e.URL, e.Secret, newPayload(&e.Webhook, est))
+		}
+		e.triggered = met
+	}
+}
+
+// Send delivers payload as a signed JSON POST to url in the background,
+// retrying like webhook notifications. Run waits for sends in progress when
+// it returns.
+func (d *Dispatcher) Send(ctx context.Context, url, secret string, payload any) {
+	d.deliveries.Add(1)
+	go func() {
+		defer d.deliveries.Done()
+		d.deliver(ctx, url, secret, payload)
+	}()
+}
+
+// deliver POSTs payload to url, retrying with exponential backoff.
+func (d *Dispatcher) deliver(ctx context.Context, url, secret string, payload any) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		d.logger.Error("encoding webhook payload", "url", url, "error", err)
+		return
+	}
+	logger := d.logger.With("url", url)
+
+	backoff := d.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := d.post(ctx, url, secret, body)
+		if err == nil {
+			logger.Debug("webhook delivered", "attempts", attempt)
+			return
+		}
+		if !retry || attempt >= d.maxAttempts {
+			logger.Warn("webhook delivery failed", "attempts", attempt, "error", err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// post sends one delivery attempt. The boolean result reports whether a
+// failure is worth retrying.
+func (d *Dispatcher) post(ctx context.Context, url, secret string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("creating request: %w", err)
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+	req.Header.Set(SignatureHeader, Sign(secret, ts, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+// Sign returns the signature header value for a delivery body sent at
+// timestamp (Unix seconds): "sha256=" and the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with secret. Receivers should recompute it,
+// compare in constant time and reject stale timestamps.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// randomHex returns n random bytes, hex encoded.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
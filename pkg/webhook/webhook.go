@@ -0,0 +1,272 @@
+// Package webhook notifies registered consumers when gas estimates cross
+// fee thresholds, e.g. "standard drops below 10 gwei".
+//
+// Deliveries are JSON POSTs signed with a per-webhook secret (see Sign) and
+// retried with exponential backoff on network errors, 429 and 5xx responses.
+// Registrations are held in memory and do not survive a restart.
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+	"github.com/holiman/uint256"
+)
+
+// Direction is the way a fee must cross a threshold to trigger a webhook.
+type Direction string
+
+const (
+	Below Direction = "below"
+	Above Direction = "above"
+)
+
+// Field selects which fee of a tier a condition compares.
+type Field string
+
+const (
+	MaxFeePerGas         Field = "max_fee_per_gas"
+	MaxPriorityFeePerGas Field = "max_priority_fee_per_gas"
+)
+
+// Tiers that conditions can watch.
+var Tiers = []string{"urgent", "fast", "standard", "slow"}
+
+// Condition describes when a webhook fires: when Field of Tier moves below
+// or above Threshold. It fires once per crossing, including when it already
+// holds at registration, and re-arms when the fee moves back.
+type Condition struct {
+	Tier      string
+	Field     Field // empty means MaxFeePerGas
+	Direction Direction
+	Threshold *uint256.Int // wei
+}
+
+// Validate reports whether the condition is complete and well-formed.
+func (c Condition) Validate() error {
+	if !slices.Contains(Tiers, c.Tier) {
+		return fmt.Errorf("unknown tier %q", c.Tier)
+	}
+	switch c.Field {
+	case "", MaxFeePerGas, MaxPriorityFeePerGas:
+	default:
+		return fmt.Errorf("unknown field %q", c.Field)
+	}
+	switch c.Direction {
+	case Below, Above:
+	default:
+		return fmt.Errorf("direction must be %q or %q", Below, Above)
+	}
+	if c.Threshold == nil {
+		return errors.New("threshold is required")
+	}
+	return nil
+}
+
+// value returns the fee the condition compares, or nil if est lacks it.
+func (c Condition) value(est *estimator.GasEstimate) *uint256.Int {
+	level := tier(est, c.Tier)
+	if level == nil {
+		return nil
+	}
+	if c.Field == MaxPriorityFeePerGas {
+		return level.MaxPriorityFeePerGas
+	}
+	return level.MaxFeePerGas
+}
+
+// Met reports whether est satisfies the condition.
+func (c Condition) Met(est *estimator.GasEstimate) bool {
+	v := c.value(est)
+	if v == nil {
+		return false
+	}
+	if c.Direction == Below {
+		return v.Lt(c.Threshold)
+	}
+	return v.Gt(c.Threshold)
+}
+
+// tier returns the named tier of est, or nil for an unknown name.
+func tier(est *estimator.GasEstimate, name string) *estimator.PriorityEstimate {
+	switch name {
+	case "urgent":
+		return &est.Urgent
+	case "fast":
+		return &est.Fast
+	case "standard":
+		return &est.Standard
+	case "slow":
+		return &est.Slow
+	}
+	return nil
+}
+
+// Webhook is a registered consumer endpoint.
+type Webhook struct {
+	ID        string
+	URL       string
+	Secret    string // HMAC key for delivery signatures
+	Condition Condition
+	CreatedAt time.Time
+}
+
+// ValidateURL checks that rawURL is an absolute http or https URL.
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// Payload is the JSON body POSTed when a condition triggers.
+type Payload struct {
+	WebhookID   string    `json:"webhook_id"`
+	Event       string    `json:"event"`
+	Tier        string    `json:"tier"`
+	Field       Field     `json:"field"`
+	Direction   Direction `json:"direction"`
+	Threshold   string    `json:"threshold"`
+	Value       string    `json:"value"`
+	ChainID     uint64    `json:"chain_id"`
+	BlockNumber uint64    `json:"block_number"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// EventThresholdCrossed is the Payload event for a triggered condition.
+const EventThresholdCrossed = "threshold_crossed"
+
+func newPayload(hook *Webhook, est *estimator.GasEstimate) Payload {
+	field := hook.Condition.Field
+	if field == "" {
+		field = MaxFeePerGas
+	}
+	return Payload{
+		WebhookID:   hook.ID,
+		Event:       EventThresholdCrossed,
+		Tier:        hook.Condition.Tier,
+		Field:       field,
+		Direction:   hook.Condition.Direction,
+		Threshold:   hook.Condition.Threshold.Dec(),
+		Value:       hook.Condition.value(est).Dec(),
+		ChainID:     est.ChainID,
+		BlockNumber: est.BlockNumber,
+		Timestamp:   est.Timestamp,
+	}
+}
+
+// AnomalyPayload is the JSON body POSTed for an estimator anomaly.
+type AnomalyPayload struct {
+	Event       string    `json:"event"`
+	Kind        string    `json:"kind"`
+	ChainID     uint64    `json:"chain_id"`
+	BlockNumber uint64    `json:"block_number"`
+	Value       float64   `json:"value"`
+	Baseline    float64   `json:"baseline"`
+	Ratio       float64   `json:"ratio"`
+	DetectedAt  time.Time `json:"detected_at"`
+}
+
+// EventAnomaly is the AnomalyPayload event.
+const EventAnomaly = "anomaly"
+
+// NewAnomalyPayload builds the delivery body for a.
+func NewAnomalyPayload(a estimator.Anomaly) AnomalyPayload {
+	return AnomalyPayload{
+		Event:       EventAnomaly,
+		Kind:        string(a.Kind),
+		ChainID:     a.ChainID,
+		BlockNumber: a.BlockNumber,
+		Value:       a.Value,
+		Baseline:    a.Baseline,
+		Ratio:       a.Ratio,
+		DetectedAt:  a.DetectedAt,
+	}
+}
+
+// DivergencePayload is the JSON body POSTed when the estimator's nodes
+// disagree on a new head.
+type DivergencePayload struct {
+	Event       string     `json:"event"`
+	ChainID     uint64     `json:"chain_id"`
+	BlockNumber uint64     `json:"block_number"`
+	Views       []HeadView `json:"views"`
+	Majority    HeadView   `json:"majority"`
+	Replaced    bool       `json:"replaced"`
+	DetectedAt  time.Time  `json:"detected_at"`
+}
+
+// HeadView is one node's version of the diverging block.
+type HeadView struct {
+	Node    string `json:"node"`
+	Hash    string `json:"hash,omitempty"`
+	BaseFee string `json:"base_fee,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// EventDivergence is the DivergencePayload event.
+const EventDivergence = "node_divergence"
+
+// NewDivergencePayload builds the delivery body for d.
+func NewDivergencePayload(d estimator.Divergence) DivergencePayload {
+	views := make([]HeadView, len(d.Views))
+	for i, v := range d.Views {
+		views[i] = newHeadView(v)
+	}
+	return DivergencePayload{
+		Event:       EventDivergence,
+		ChainID:     d.ChainID,
+		BlockNumber: d.BlockNumber,
+		Views:       views,
+		Majority:    newHeadView(d.Majority),
+		Replaced:    d.Replaced,
+		DetectedAt:  d.DetectedAt,
+	}
+}
+
+func newHeadView(v estimator.HeadView) HeadView {
+	return HeadView{Node: v.Node, Hash: v.Hash, BaseFee: v.BaseFee, Error: v.Err}
+}
+
+// RejectionPayload is the JSON body POSTed when the sanity guard refuses
+// to publish an estimate.
+type RejectionPayload struct {
+	Event       string      `json:"event"`
+	ChainID     uint64      `json:"chain_id"`
+	BlockNumber uint64      `json:"block_number"`
+	Violations  []Violation `json:"violations"`
+	DetectedAt  time.Time   `json:"detected_at"`
+}
+
+// Violation is one invariant the rejected estimate broke.
+type Violation struct {
+	Check  string `json:"check"`
+	Tier   string `json:"tier,omitempty"`
+	Detail string `json:"detail"`
+}
+
+// EventRejection is the RejectionPayload event.
+const EventRejection = "estimate_rejected"
+
+// NewRejectionPayload builds the delivery body for r.
+func NewRejectionPayload(r estimator.Rejection) RejectionPayload {
+	violations := make([]Violation, len(r.Violations))
+	for i, v := range r.Violations {
+		violations[i] = Violation{Check: string(v.Check), Tier: v.Tier, Detail: v.Detail}
+	}
+	return RejectionPayload{
+		Event:       EventRejection,
+		ChainID:     r.ChainID,
+		BlockNumber: r.BlockNumber,
+		Violations:  violations,
+		DetectedAt:  r.DetectedAt,
+	}
+}
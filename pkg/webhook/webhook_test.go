@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/branched-services/go-gas/pkg/estimator"
+	"github.com/holiman/uint256"
+)
+
+func estimateWithStandard(maxFee, tip uint64) *estimator.GasEstimate {
+	return &estimator.GasEstimate{
+		ChainID:     1,
+		BlockNumber: 100,
+		Standard: estimator.PriorityEstimate{
+			MaxFeePerGas:         uint256.NewInt(maxFee),
+			MaxPriorityFeePerGas: uint256.NewInt(tip),
+		},
+	}
+}
+
+func TestCondition_Met(t *testing.T) {
+	est := estimateWithStandard(12e9, 2e9)
+	tests := []struct {
+		name string
+		cond Condition
+		want bool
+	}{
+		{"max fee below", Condition{Tier: "standard", Direction: Below, Threshold: uint256.NewInt(15e9)}, true},
+		{"max fee not below", Condition{Tier: "standard", Direction: Below, Threshold: uint256.NewInt(10e9)}, false},
+		{"max fee above", Condition{Tier: "standard", Direction: Above, Threshold: uint256.NewInt(10e9)}, true},
+		{"equal is not a crossing", Condition{Tier: "standard", Direction: Above, Threshold: uint256.NewInt(12e9)}, false},
+		{"priority fee", Condition{Tier: "standard", Field: MaxPriorityFeePerGas, Direction: Below, Threshold: uint256.NewInt(3e9)}, true},
+		{"missing tier", Condition{Tier: "fast", Direction: Below, Threshold: uint256.NewInt(3e9)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cond.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if got := tt.cond.Met(est); got != tt.want {
+				t.Errorf("Met() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCondition_Validate(t *testing.T) {
+	bad := []Condition{
+		{Tier: "cheap", Direction: Below, Threshold: uint256.NewInt(1)},
+		{Tier: "slow", Field: "gas_price", Direction: Below, Threshold: uint256.NewInt(1)},
+		{Tier: "slow", Direction: "sideways", Threshold: uint256.NewInt(1)},
+		{Tier: "slow", Direction: Below},
+	}
+	for _, cond := range bad {
+		if err := cond.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded", cond)
+		}
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	var (
+		calls    atomic.Int32
+		received = make(chan Payload, 10)
+		secret   atomic.Value
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first attempt fails and must be retried
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		if got, want := r.Header.Get(SignatureHeader), Sign(secret.Load().(string), ts, body); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		var p Payload
+		json.Unmarshal(body, &p)
+		received <- p
+	}))
+	defer srv.Close()
+
+	provider := estimator.NewProvider()
+	provider.Update(estimateWithStandard(20e9, 2e9))
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	d := NewDispatcher(provider, logger, WithInterval(5*time.Millisecond))
+	d.backoff = time.Millisecond
+
+	hook, err := d.Register(srv.URL, Condition{Tier: "standard", Direction: Below, Threshold: uint256.NewInt(10e9)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret.Store(hook.Secret)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go d.Run(ctx)
+
+	// Not met yet; then crosses below the threshold twice in a row, which
+	// must notify once
+	time.Sleep(20 * time.Millisecond)
+	provider.Update(estimateWithStandard(8e9, 2e9))
+	time.Sleep(20 * time.Millisecond)
+	provider.Update(estimateWithStandard(7e9, 2e9))
+
+	select {
+	case p := <-received:
+		if p.WebhookID != hook.ID || p.Value != "8000000000" || p.Threshold != "10000000000" || p.Field != MaxFeePerGas {
+			t.Errorf("payload = %+v", p)
+		}
+	case <-ctx.Done():
+		t.Fatal("no delivery")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("server called %d times, want 2 (one failure, one delivery)", n)
+	}
+
+	if !d.Remove(hook.ID) || len(d.List()) != 0 {
+		t.Error("webhook not removed")
+	}
+}
+
+func TestDispatcher_Limit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	d := NewDispatcher(estimator.NewProvider(), logger, WithMaxWebhooks(1))
+	cond := Condition{Tier: "fast", Direction: Above, Threshold: uint256.NewInt(1)}
+
+	if _, err := d.Register("https://example.com/hook", cond); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Register("https://example.com/hook", cond); err != ErrLimitReached {
+		t.Errorf("second Register() error = %v, want ErrLimitReached", err)
+	}
+	if _, err := d.Register("ftp://example.com", cond); err == nil {
+		t.Error("Register() accepted a non-HTTP URL")
+	}
+}